@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+// Actions of entries. A request entry is recorded for every request, the other
+// actions for every change to a post
+const (
+	ActionRequest         = "request"
+	ActionCreated         = "post.created"
+	ActionUpdated         = "post.updated"
+	ActionPatched         = "post.patched"
+	ActionRestored        = "post.restored"
+	ActionDeleted         = "post.deleted"
+	ActionScheduled       = "post.scheduled"
+	ActionPublishClaimed  = "post.publish_claimed"
+	ActionPublishComplete = "post.publish_completed"
+)
+
+// Entry records a request of a customer or a change to one of their posts.
+// Sequence orders the entries of a customer starting at 1, and every entry is
+// chained to the one before it by PrevHash
+type Entry struct {
+	Sequence   int64     `json:"sequence"`
+	CustomerID string    `json:"customer_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Actor      string    `json:"actor,omitempty"`
+	Action     string    `json:"action"`
+	PostID     string    `json:"post_id,omitempty"`
+	// IP, Method, Path and Status are set for requests
+	IP     string `json:"ip,omitempty"`
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Status int    `json:"status,omitempty"`
+	// Before and After are the json of the post around a change, Before is not
+	// set for a created post and After is not set for a deleted one. They are
+	// kept as written, so entries do not change with the representation of posts
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+	// PrevHash is the Hash of the customer's previous entry, empty for the first
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// Sum returns the hash of the entry, the hex sha256 of its json without Hash.
+// Every other field, including PrevHash, is covered. Before and After are hashed
+// as they were written
+func Sum(e *Entry) (string, error) {
+	unhashed := *e
+	unhashed.Hash = ""
+	data, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Query selects a page of a customer's entries, oldest first. Zero fields
+// match every entry
+type Query struct {
+	// After is the sequence of the last entry of the previous page
+	After  int64
+	Limit  int
+	Actor  string
+	Action string
+	PostID string
+	// Since and Until select the entries that occurred in [Since, Until)
+	Since time.Time
+	Until time.Time
+}
+
+// Match returns true if the entry passes every set field of the query
+func (q Query) Match(e *Entry) bool {
+	switch {
+	case e.Sequence <= q.After:
+		return false
+	case q.Actor != "" && e.Actor != q.Actor:
+		return false
+	case q.Action != "" && e.Action != q.Action:
+		return false
+	case q.PostID != "" && e.PostID != q.PostID:
+		return false
+	case !q.Since.IsZero() && e.OccurredAt.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !e.OccurredAt.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Appender receives the entries of the audit log
+type Appender interface {
+	Append(*Entry) (*Entry, error)
+}
+
+// Store defines the interface for an append only log of entries, chained per
+// customer
+type Store interface {
+	Appender
+	Query(string, Query) ([]*Entry, error)
+}
+
+// Snapshot returns the json of the post for the Before or After of an entry, it
+// is nil for a nil post
+func Snapshot(post *dao.Post) (json.RawMessage, error) {
+	if post == nil {
+		return nil, nil
+	}
+	return json.Marshal(post)
+}
+
+// DecodePost reads the post of a snapshot, it is nil for an empty one.
+// Snapshots written before captions were objects have caption texts, which are
+// read as manual captions without ids
+func DecodePost(snapshot json.RawMessage) (*dao.Post, error) {
+	if len(snapshot) == 0 {
+		return nil, nil
+	}
+	post := &dao.Post{}
+	err := json.Unmarshal(snapshot, post)
+	if err == nil {
+		return post, nil
+	}
+	legacy := &struct {
+		*dao.Post
+		Captions []string `json:"captions"`
+	}{Post: &dao.Post{}}
+	if json.Unmarshal(snapshot, legacy) != nil {
+		return nil, err
+	}
+	legacy.Post.Captions = dao.ManualCaptions(legacy.Captions)
+	return legacy.Post, nil
+}
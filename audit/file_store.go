@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// FileStore implements the Store interface. Entries are queried from memory and
+// every entry is appended to a file as a json line, which is verified and
+// replayed when the file is opened. The file is only ever appended to, and only
+// one process may write it
+type FileStore struct {
+	*InMemoryStore
+	logger *log.Logger
+	path   string
+	file   *os.File
+}
+
+// OpenFileStore opens the file at path, creating it if it does not exist. It
+// returns a *ChainError if the entries of the file have been changed
+func OpenFileStore(logger *log.Logger, path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		InMemoryStore: NewInMemoryStore(),
+		logger:        logger,
+		path:          path,
+		file:          f,
+	}
+	if err := s.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.InMemoryStore.write = s.writeEntry
+	return s, nil
+}
+
+// replay loads every entry of the file. A last line that was only partly
+// written is removed, it was never acknowledged
+func (s *FileStore) replay() error {
+	offset, partial, err := replay(s.file, s.chain, func(e *Entry) {
+		s.byCustomer[e.CustomerID] = append(s.byCustomer[e.CustomerID], e)
+	})
+	if err != nil {
+		return err
+	}
+	if partial {
+		s.logger.WithFields(log.Fields{
+			"path": s.path,
+		}).Warn("removing partly written audit entry")
+		if err := s.file.Truncate(offset); err != nil {
+			return err
+		}
+	}
+	_, err = s.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// writeEntry appends the entry to the file, it is called with the lock held
+func (s *FileStore) writeEntry(e *Entry) error {
+	line, err := json.Marshal(e)
+	if err == nil {
+		_, err = s.file.Write(append(line, '\n'))
+	}
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		s.logger.WithFields(log.Fields{
+			"path":  s.path,
+			"error": err.Error(),
+		}).Error("failed to write audit entry")
+		return datastore.NewUnavailableError("audit log")
+	}
+	return nil
+}
+
+// Close closes the file
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
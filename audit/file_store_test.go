@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+var errTest = errors.New("test-error")
+
+var _ = Describe("FileStore", func() {
+	var (
+		logger *log.Logger
+		dir    string
+		path   string
+		s      *FileStore
+	)
+
+	reopen := func() {
+		ExpectWithOffset(1, s.Close()).To(Succeed())
+		var err error
+		s, err = OpenFileStore(logger, path)
+		ExpectWithOffset(1, err).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		var err error
+		dir, err = ioutil.TempDir("", "audit-file-store")
+		Expect(err).To(BeNil())
+		path = filepath.Join(dir, "audit.jsonl")
+		s, err = OpenFileStore(logger, path)
+		Expect(err).To(BeNil())
+
+		for _, customerID := range []string{"customer", "other-customer", "customer"} {
+			_, err := s.Append(&Entry{CustomerID: customerID, Action: ActionUpdated, After: json.RawMessage(`{"url":"https://example.com","captions":[{"text":"caption","source":"manual"}]}`)})
+			Expect(err).To(BeNil())
+		}
+	})
+
+	AfterEach(func() {
+		if s != nil {
+			s.Close()
+		}
+		os.RemoveAll(dir)
+	})
+
+	It("should keep the entries when reopened", func() {
+		before, err := s.Query("customer", Query{})
+		Expect(err).To(BeNil())
+		reopen()
+
+		after, err := s.Query("customer", Query{})
+		Expect(err).To(BeNil())
+		Expect(after).To(Equal(before))
+
+		e, err := s.Append(&Entry{CustomerID: "customer", Action: ActionDeleted})
+		Expect(err).To(BeNil())
+		Expect(e.Sequence).To(Equal(int64(3)))
+		Expect(e.PrevHash).To(Equal(before[1].Hash))
+	})
+
+	It("should write a file that verifies", func() {
+		Expect(s.Close()).To(Succeed())
+		data, err := ioutil.ReadFile(path)
+		Expect(err).To(BeNil())
+		s = nil
+
+		heads, err := Verify(bytes.NewReader(data))
+		Expect(err).To(BeNil())
+		Expect(heads).To(HaveLen(2))
+		Expect(heads[0].CustomerID).To(Equal("customer"))
+		Expect(heads[0].Sequence).To(Equal(int64(2)))
+	})
+
+	It("should open a file written before captions were objects", func() {
+		Expect(s.Close()).To(Succeed())
+		legacy := `{"sequence":1,"customer_id":"customer","occurred_at":"2026-01-02T03:04:05Z","actor":"alice","action":"post.created","post_id":"5e154899cb80cb0001000003","after":{"id":"5e154899cb80cb0001000003","url":"https://example.com/a","captions":["caption \u003c1\u003e"],"status":"draft"},"prev_hash":"","hash":"558f3ecc39e82ef9f61de6cf8135e05f467d992d011ca21a310bba56702635e1"}`
+		Expect(ioutil.WriteFile(path, []byte(legacy+"\n"), 0600)).To(Succeed())
+
+		var err error
+		s, err = OpenFileStore(logger, path)
+		Expect(err).To(BeNil())
+		entries, err := s.Query("customer", Query{})
+		Expect(err).To(BeNil())
+		Expect(entries).To(HaveLen(1))
+		post, err := DecodePost(entries[0].After)
+		Expect(err).To(BeNil())
+		Expect(post.URL).To(Equal("https://example.com/a"))
+		Expect(post.Captions).To(Equal(dao.ManualCaptions([]string{"caption <1>"})))
+
+		e, err := s.Append(&Entry{CustomerID: "customer", Action: ActionDeleted})
+		Expect(err).To(BeNil())
+		Expect(e.PrevHash).To(Equal(entries[0].Hash))
+		reopen()
+	})
+
+	It("should NOT open a file that was changed", func() {
+		Expect(s.Close()).To(Succeed())
+		data, err := ioutil.ReadFile(path)
+		Expect(err).To(BeNil())
+		changed := strings.Replace(string(data), `"caption"`, `"changed"`, 1)
+		Expect(ioutil.WriteFile(path, []byte(changed), 0600)).To(Succeed())
+
+		s, err = OpenFileStore(logger, path)
+		Expect(s).To(BeNil())
+		Expect(err).To(Equal(&ChainError{Line: 1, CustomerID: "customer", Sequence: 1, Message: "hash does not match the entry"}))
+	})
+
+	It("should remove a partly written last entry", func() {
+		Expect(s.Close()).To(Succeed())
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		Expect(err).To(BeNil())
+		_, err = f.WriteString(`{"sequence":3,"customer_id":"cust`)
+		Expect(err).To(BeNil())
+		Expect(f.Close()).To(Succeed())
+
+		s, err = OpenFileStore(logger, path)
+		Expect(err).To(BeNil())
+		e, err := s.Append(&Entry{CustomerID: "customer", Action: ActionDeleted})
+		Expect(err).To(BeNil())
+		Expect(e.Sequence).To(Equal(int64(3)))
+		reopen()
+	})
+})
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// InMemoryStore implements the Store interface for in memory storage
+type InMemoryStore struct {
+	mu         sync.Mutex
+	byCustomer map[string][]*Entry
+	chain      chain
+	// write is called with every entry before it is stored, it is how a
+	// FileStore persists them. The entry is not stored if it fails
+	write func(*Entry) error
+	now   func() time.Time
+}
+
+// NewInMemoryStore creates an empty InMemoryStore
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		byCustomer: make(map[string][]*Entry),
+		chain:      chain{},
+		now:        time.Now,
+	}
+}
+
+// Append assigns the entry the customer's next sequence, chains it to the
+// customer's last entry and stores it
+func (s *InMemoryStore) Append(e *Entry) (*Entry, error) {
+	if e == nil {
+		return nil, datastore.NewInvalidArugmentError("must provide entry")
+	}
+	if e.CustomerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *e
+	stored.Before = append(json.RawMessage(nil), e.Before...)
+	stored.After = append(json.RawMessage(nil), e.After...)
+	stored.Sequence, stored.PrevHash = 1, ""
+	if last, ok := s.chain[e.CustomerID]; ok {
+		stored.Sequence, stored.PrevHash = last.Sequence+1, last.Hash
+	}
+	if stored.OccurredAt.IsZero() {
+		stored.OccurredAt = s.now()
+	}
+	stored.OccurredAt = stored.OccurredAt.UTC()
+	hash, err := Sum(&stored)
+	if err != nil {
+		return nil, err
+	}
+	stored.Hash = hash
+
+	if s.write != nil {
+		if err := s.write(&stored); err != nil {
+			return nil, err
+		}
+	}
+	s.add(&stored)
+	copied := stored
+	return &copied, nil
+}
+
+// Query returns up to q.Limit of the customer's entries that match the query,
+// oldest first
+func (s *InMemoryStore) Query(customerID string, q Query) ([]*Entry, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []*Entry{}
+	for _, e := range s.byCustomer[customerID] {
+		if !q.Match(e) {
+			continue
+		}
+		copied := *e
+		entries = append(entries, &copied)
+		if q.Limit > 0 && len(entries) == q.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// add stores an entry that has been checked, it must be called with the lock held
+func (s *InMemoryStore) add(e *Entry) {
+	s.byCustomer[e.CustomerID] = append(s.byCustomer[e.CustomerID], e)
+	s.chain.add(e)
+}
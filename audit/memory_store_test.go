@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+var _ = Describe("InMemoryStore", func() {
+	var (
+		s      *InMemoryStore
+		now    time.Time
+		postID bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		s = NewInMemoryStore()
+		now = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		s.now = func() time.Time { return now }
+		postID = bson.NewObjectId()
+	})
+
+	appendEntry := func(customerID, actor, action string) *Entry {
+		e, err := s.Append(&Entry{CustomerID: customerID, Actor: actor, Action: action, PostID: postID.Hex()})
+		ExpectWithOffset(1, err).To(BeNil())
+		return e
+	}
+
+	Describe("Append", func() {
+		It("should chain the entries of each customer", func() {
+			first := appendEntry("customer", "alice", ActionCreated)
+			other := appendEntry("other-customer", "bob", ActionCreated)
+			second := appendEntry("customer", "alice", ActionUpdated)
+
+			Expect(first.Sequence).To(Equal(int64(1)))
+			Expect(first.PrevHash).To(BeEmpty())
+			Expect(first.OccurredAt).To(Equal(now))
+			Expect(other.Sequence).To(Equal(int64(1)))
+			Expect(other.PrevHash).To(BeEmpty())
+			Expect(second.Sequence).To(Equal(int64(2)))
+			Expect(second.PrevHash).To(Equal(first.Hash))
+
+			for _, e := range []*Entry{first, other, second} {
+				sum, err := Sum(e)
+				Expect(err).To(BeNil())
+				Expect(e.Hash).To(Equal(sum))
+			}
+		})
+
+		It("should copy the snapshots", func() {
+			after, err := Snapshot(&dao.Post{ID: &postID, Captions: dao.ManualCaptions([]string{"caption1"})})
+			Expect(err).To(BeNil())
+			e, err := s.Append(&Entry{CustomerID: "customer", Action: ActionCreated, After: after})
+			Expect(err).To(BeNil())
+			copy(after, `{"id":"changed"`)
+			post, err := DecodePost(e.After)
+			Expect(err).To(BeNil())
+			Expect(*post.ID).To(Equal(postID))
+			Expect(dao.CaptionTexts(post.Captions)).To(Equal([]string{"caption1"}))
+		})
+
+		It("should NOT store an entry that can not be written", func() {
+			s.write = func(*Entry) error {
+				return errTest
+			}
+			_, err := s.Append(&Entry{CustomerID: "customer", Action: ActionCreated})
+			Expect(err).To(Equal(errTest))
+
+			s.write = nil
+			e := appendEntry("customer", "alice", ActionCreated)
+			Expect(e.Sequence).To(Equal(int64(1)))
+		})
+
+		It("should return an error without customerID", func() {
+			_, err := s.Append(&Entry{Action: ActionCreated})
+			Expect(err.Error()).To(Equal("invalid customerID"))
+		})
+	})
+
+	Describe("Query", func() {
+		BeforeEach(func() {
+			appendEntry("customer", "alice", ActionRequest)
+			now = now.Add(time.Hour)
+			appendEntry("customer", "bob", ActionUpdated)
+			now = now.Add(time.Hour)
+			appendEntry("customer", "alice", ActionUpdated)
+			appendEntry("other-customer", "alice", ActionUpdated)
+		})
+
+		It("should return the customer's entries that match", func() {
+			start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+			cases := []struct {
+				query     Query
+				sequences []int64
+			}{
+				{Query{}, []int64{1, 2, 3}},
+				{Query{After: 1, Limit: 1}, []int64{2}},
+				{Query{Actor: "alice"}, []int64{1, 3}},
+				{Query{Action: ActionUpdated}, []int64{2, 3}},
+				{Query{PostID: bson.NewObjectId().Hex()}, []int64{}},
+				{Query{Since: start.Add(time.Hour)}, []int64{2, 3}},
+				{Query{Until: start.Add(time.Hour)}, []int64{1}},
+			}
+			for i, c := range cases {
+				entries, err := s.Query("customer", c.query)
+				Expect(err).To(BeNil())
+				sequences := []int64{}
+				for _, e := range entries {
+					sequences = append(sequences, e.Sequence)
+				}
+				Expect(sequences).To(Equal(c.sequences), "case %d", i)
+			}
+		})
+	})
+})
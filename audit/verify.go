@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ChainError describes where the chain of a customer's entries is broken
+type ChainError struct {
+	// Line is the line of the file, it is 0 when the entries were not read from
+	// one
+	Line       int
+	CustomerID string
+	Sequence   int64
+	Message    string
+}
+
+func (e *ChainError) Error() string {
+	msg := e.Message
+	if e.CustomerID != "" {
+		msg = fmt.Sprintf("customer %q entry %d: %s", e.CustomerID, e.Sequence, msg)
+	}
+	if e.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", e.Line, msg)
+	}
+	return msg
+}
+
+// chain tracks the last entry of every customer to check the entries that
+// follow it
+type chain map[string]*Entry
+
+// check returns an error if the entry does not follow the customer's last
+// entry, or its hash does not match its contents
+func (c chain) check(e *Entry) *ChainError {
+	broken := func(format string, args ...interface{}) *ChainError {
+		return &ChainError{CustomerID: e.CustomerID, Sequence: e.Sequence, Message: fmt.Sprintf(format, args...)}
+	}
+
+	var sequence int64 = 1
+	prevHash := ""
+	if last, ok := c[e.CustomerID]; ok {
+		sequence, prevHash = last.Sequence+1, last.Hash
+	}
+	if e.Sequence != sequence {
+		return broken("expected entry %d", sequence)
+	}
+	if e.PrevHash != prevHash {
+		return broken("prev_hash does not match the hash of the previous entry")
+	}
+	sum, err := Sum(e)
+	if err != nil {
+		return broken("%v", err)
+	}
+	if e.Hash != sum {
+		return broken("hash does not match the entry")
+	}
+	return nil
+}
+
+// add makes the entry the customer's last
+func (c chain) add(e *Entry) {
+	c[e.CustomerID] = e
+}
+
+// Head is the last entry of a customer's chain. Comparing it with a head
+// recorded earlier shows whether entries were removed from the end
+type Head struct {
+	CustomerID string `json:"customer_id"`
+	Sequence   int64  `json:"sequence"`
+	Hash       string `json:"hash"`
+}
+
+// Verify reads the entries of an audit file, a json line each, and checks that
+// every customer's entries are numbered from 1 without gaps and each is chained
+// to the one before it. It returns the head of every customer's chain, by
+// customer, and a *ChainError for the first entry that breaks its chain
+func Verify(r io.Reader) ([]Head, error) {
+	entries := chain{}
+	_, partial, err := replay(r, entries, nil)
+	if err != nil {
+		return nil, err
+	}
+	if partial {
+		return nil, &ChainError{Message: "the last entry is only partly written"}
+	}
+	heads := make([]Head, 0, len(entries))
+	for customerID, last := range entries {
+		heads = append(heads, Head{CustomerID: customerID, Sequence: last.Sequence, Hash: last.Hash})
+	}
+	sort.Slice(heads, func(i, j int) bool {
+		return heads[i].CustomerID < heads[j].CustomerID
+	})
+	return heads, nil
+}
+
+// replay verifies the entries of r against the chain, calling fn with every
+// entry that is valid. It returns the offset after the last complete line, and
+// whether there is a last line without a newline after it, which is not read
+func replay(r io.Reader, entries chain, fn func(*Entry)) (offset int64, partial bool, err error) {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, len(data) > 0, nil
+		}
+		if err != nil {
+			return offset, false, err
+		}
+
+		e := &Entry{}
+		if err := json.Unmarshal(data, e); err != nil {
+			return offset, false, &ChainError{Line: line, Message: "invalid entry: " + err.Error()}
+		}
+		if cerr := entries.check(e); cerr != nil {
+			cerr.Line = line
+			return offset, false, cerr
+		}
+		entries.add(e)
+		if fn != nil {
+			fn(e)
+		}
+		offset += int64(len(data))
+	}
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verify", func() {
+	var entries []*Entry
+
+	BeforeEach(func() {
+		s := NewInMemoryStore()
+		entries = []*Entry{}
+		for _, customerID := range []string{"customer", "other-customer", "customer", "customer"} {
+			e, err := s.Append(&Entry{CustomerID: customerID, Actor: "alice", Action: ActionRequest, IP: "10.0.0.1"})
+			Expect(err).To(BeNil())
+			entries = append(entries, e)
+		}
+	})
+
+	lines := func(entries []*Entry) string {
+		var b strings.Builder
+		for _, e := range entries {
+			data, err := json.Marshal(e)
+			ExpectWithOffset(1, err).To(BeNil())
+			b.Write(append(data, '\n'))
+		}
+		return b.String()
+	}
+
+	It("should return the head of every chain", func() {
+		heads, err := Verify(strings.NewReader(lines(entries)))
+		Expect(err).To(BeNil())
+		Expect(heads).To(Equal([]Head{
+			{CustomerID: "customer", Sequence: 3, Hash: entries[3].Hash},
+			{CustomerID: "other-customer", Sequence: 1, Hash: entries[1].Hash},
+		}))
+	})
+
+	It("should find the entry that breaks a chain", func() {
+		cases := []struct {
+			name    string
+			change  func([]*Entry) []*Entry
+			line    int
+			message string
+		}{
+			{"a changed entry", func(entries []*Entry) []*Entry {
+				entries[2].IP = "10.0.0.2"
+				return entries
+			}, 3, "hash does not match the entry"},
+			{"a changed snapshot", func(entries []*Entry) []*Entry {
+				entries[2].After = json.RawMessage(`{"url":"https://example.com/changed"}`)
+				return entries
+			}, 3, "hash does not match the entry"},
+			{"a removed entry", func(entries []*Entry) []*Entry {
+				return append(entries[:2], entries[3])
+			}, 3, "expected entry 2"},
+			{"a rehashed entry", func(entries []*Entry) []*Entry {
+				entries[2].IP = "10.0.0.2"
+				entries[2].Hash, _ = Sum(entries[2])
+				return entries
+			}, 4, "prev_hash does not match the hash of the previous entry"},
+			{"reordered entries", func(entries []*Entry) []*Entry {
+				return []*Entry{entries[0], entries[1], entries[3], entries[2]}
+			}, 3, "expected entry 2"},
+		}
+		for _, c := range cases {
+			copied := make([]*Entry, len(entries))
+			for i, e := range entries {
+				e := *e
+				copied[i] = &e
+			}
+			_, err := Verify(strings.NewReader(lines(c.change(copied))))
+			Expect(err).To(HaveOccurred(), c.name)
+			chainErr, ok := err.(*ChainError)
+			Expect(ok).To(BeTrue(), c.name)
+			Expect(chainErr.Line).To(Equal(c.line), c.name)
+			Expect(chainErr.Message).To(Equal(c.message), c.name)
+		}
+	})
+
+	It("should verify snapshots of posts the current version can not read", func() {
+		s := NewInMemoryStore()
+		e, err := s.Append(&Entry{CustomerID: "customer", Action: ActionCreated, After: json.RawMessage(`{"url":"https://example.com","retired":["a", "b"]}`)})
+		Expect(err).To(BeNil())
+		heads, err := Verify(strings.NewReader(lines([]*Entry{e})))
+		Expect(err).To(BeNil())
+		Expect(heads).To(Equal([]Head{{CustomerID: "customer", Sequence: 1, Hash: e.Hash}}))
+	})
+
+	It("should reject a partly written last entry", func() {
+		_, err := Verify(strings.NewReader(lines(entries) + `{"sequence":`))
+		Expect(err).To(Equal(&ChainError{Message: "the last entry is only partly written"}))
+	})
+
+	It("should reject a line that is not an entry", func() {
+		_, err := Verify(strings.NewReader("not json\n"))
+		Expect(err.Error()).To(HavePrefix("line 1: "))
+	})
+})
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"fmt"
+)
+
+// Stable, machine readable codes for each error type
+const (
+	CodeUnauthenticated = "unauthenticated"
+	CodeForbidden       = "forbidden"
+)
+
+// AuthError is the base error
+type AuthError struct {
+	msg string
+}
+
+// Unauthorized represents an error where the caller could not be authenticated
+type Unauthorized AuthError
+
+// Forbidden represents an error where the caller is authenticated, but not allowed
+type Forbidden AuthError
+
+// NewUnauthorizedError returns an Unauthorized error with the supplied options
+func NewUnauthorizedError(msg string) *Unauthorized {
+	return &Unauthorized{
+		msg: msg,
+	}
+}
+
+// Error implements the Error interface
+func (e *Unauthorized) Error() string {
+	return fmt.Sprintf("unauthorized: %s", e.msg)
+}
+
+// Code implements the datastore.Coder interface
+func (e *Unauthorized) Code() string {
+	return CodeUnauthenticated
+}
+
+// NewForbiddenError returns a Forbidden error with the supplied options
+func NewForbiddenError(msg string) *Forbidden {
+	return &Forbidden{
+		msg: msg,
+	}
+}
+
+// Error implements the Error interface
+func (e *Forbidden) Error() string {
+	return fmt.Sprintf("forbidden: %s", e.msg)
+}
+
+// Code implements the datastore.Coder interface
+func (e *Forbidden) Code() string {
+	return CodeForbidden
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// Scopes that can be granted to a caller
+const (
+	ScopePostsRead    = "posts:read"
+	ScopePostsWrite   = "posts:write"
+	ScopePostsApprove = "posts:approve"
+	ScopeKeysManage   = "keys:manage"
+	ScopeWebhooks     = "webhooks:manage"
+	ScopeAuditRead    = "audit:read"
+	ScopeQuality      = "quality:manage"
+	ScopeTemplates    = "templates:manage"
+)
+
+// AllScopes is every scope a caller can be granted
+var AllScopes = []string{
+	ScopePostsRead,
+	ScopePostsWrite,
+	ScopePostsApprove,
+	ScopeKeysManage,
+	ScopeWebhooks,
+	ScopeAuditRead,
+	ScopeQuality,
+	ScopeTemplates,
+}
+
+// Identity describes the authenticated caller of a request
+type Identity struct {
+	CustomerID string
+	KeyID      string
+	Subject    string
+	Scopes     []string
+}
+
+// HasScope returns true if the identity was granted the scope
+func (i *Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator defines the interface for deriving an identity from a request.
+// If the request does not carry the kind of credential the Authenticator handles,
+// it returns a nil Identity and a nil error so the next Authenticator can be tried
+type Authenticator interface {
+	Authenticate(*http.Request) (*Identity, error)
+}
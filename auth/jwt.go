@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	bearerPrefix = "Bearer "
+	clockLeeway  = time.Minute
+)
+
+// Claims holds the decoded payload of a verified token
+type Claims map[string]interface{}
+
+// String returns the claim if it is a string
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Scopes returns the scopes from either the space delimited "scope" claim or
+// the "scp" list claim
+func (c Claims) Scopes() []string {
+	if scope := c.String("scope"); scope != "" {
+		return strings.Fields(scope)
+	}
+	scopes := []string{}
+	if list, ok := c["scp"].([]interface{}); ok {
+		for _, s := range list {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+	}
+	return scopes
+}
+
+// hasAudience handles "aud" being either a single string or a list of strings
+func (c Claims) hasAudience(audience string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// JWTVerifier verifies the signature and registered claims of compact JWTs
+type JWTVerifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewJWTVerifier creates a JWTVerifier with the provided options. An empty issuer
+// or audience is not checked
+func NewJWTVerifier(keys *KeySet, issuer, audience string) *JWTVerifier {
+	return &JWTVerifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		now:      time.Now,
+	}
+}
+
+// Verify checks the token and returns its claims. Tokens must expire, so one
+// without an exp claim is rejected
+func (v *JWTVerifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, NewUnauthorizedError("malformed token")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, NewUnauthorizedError("malformed token header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, NewUnauthorizedError("malformed token signature")
+	}
+
+	key, ok := v.keys.lookup(header.Kid)
+	if !ok {
+		return nil, NewUnauthorizedError("unknown signing key")
+	}
+	if !verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, NewUnauthorizedError("invalid token signature")
+	}
+
+	claims := Claims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, NewUnauthorizedError("malformed token claims")
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *JWTVerifier) validateClaims(claims Claims) error {
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return NewUnauthorizedError("token is missing exp claim")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockLeeway)) {
+		return NewUnauthorizedError("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Add(clockLeeway).Before(time.Unix(int64(nbf), 0)) {
+			return NewUnauthorizedError("token not yet valid")
+		}
+	}
+	if v.issuer != "" && claims.String("iss") != v.issuer {
+		return NewUnauthorizedError("invalid token issuer")
+	}
+	if v.audience != "" && !claims.hasAudience(v.audience) {
+		return NewUnauthorizedError("invalid token audience")
+	}
+	return nil
+}
+
+// verifySignature checks the signature for the algorithm. The key type must match
+// the algorithm, so an RSA public key can never be used as an HMAC secret
+func verifySignature(alg string, key interface{}, signed, sig []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// JWTAuthenticator implements the Authenticator interface for bearer tokens
+type JWTAuthenticator struct {
+	verifier      *JWTVerifier
+	customerClaim string
+}
+
+// NewJWTAuthenticator returns a JWTAuthenticator that reads the customerID from
+// the supplied claim
+func NewJWTAuthenticator(verifier *JWTVerifier, customerClaim string) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		verifier:      verifier,
+		customerClaim: customerClaim,
+	}
+}
+
+// Authenticate verifies the bearer token in the Authorization header
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return nil, nil
+	}
+
+	claims, err := a.verifier.Verify(strings.TrimPrefix(header, bearerPrefix))
+	if err != nil {
+		return nil, err
+	}
+	customerID := claims.String(a.customerClaim)
+	if customerID == "" {
+		return nil, NewUnauthorizedError("token is missing " + a.customerClaim + " claim")
+	}
+	return &Identity{
+		CustomerID: customerID,
+		Subject:    claims.String("sub"),
+		Scopes:     claims.Scopes(),
+	}, nil
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func encodeSegment(v interface{}) string {
+	b, err := json.Marshal(v)
+	Expect(err).To(BeNil())
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signToken(alg, kid string, key interface{}, claims Claims) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signed := encodeSegment(header) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		Expect(err).To(BeNil())
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		Expect(err).To(BeNil())
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func b64Int(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+var _ = Describe("JWTVerifier", func() {
+	var (
+		secret   []byte
+		verifier *JWTVerifier
+		now      time.Time
+		claims   Claims
+		token    string
+		result   Claims
+		err      error
+	)
+
+	BeforeEach(func() {
+		secret = []byte("test-secret")
+		now = time.Unix(1577836800, 0)
+		verifier = NewJWTVerifier(NewSecretKeySet(secret), "test-issuer", "test-audience")
+		verifier.now = func() time.Time { return now }
+		claims = Claims{
+			"sub":         "user-1",
+			"customer_id": "test-customer",
+			"iss":         "test-issuer",
+			"aud":         []string{"other", "test-audience"},
+			"exp":         now.Add(time.Hour).Unix(),
+			"scope":       "posts:read posts:write",
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, err = verifier.Verify(token)
+	})
+
+	Context("with HS256 token", func() {
+		BeforeEach(func() {
+			token = signToken("HS256", "", secret, claims)
+		})
+
+		It("should NOT return an error", func() {
+			Expect(err).To(BeNil())
+		})
+
+		It("should return the claims", func() {
+			Expect(result.String("customer_id")).To(Equal("test-customer"))
+			Expect(result.Scopes()).To(Equal([]string{"posts:read", "posts:write"}))
+		})
+	})
+
+	Context("with wrong secret", func() {
+		BeforeEach(func() {
+			token = signToken("HS256", "", []byte("other-secret"), claims)
+		})
+
+		It("should return an Unauthorized error", func() {
+			Expect(err).To(BeAssignableToTypeOf(&Unauthorized{}))
+			Expect(err.Error()).To(Equal("unauthorized: invalid token signature"))
+		})
+	})
+
+	Context("with alg none", func() {
+		BeforeEach(func() {
+			token = encodeSegment(map[string]string{"alg": "none"}) + "." + encodeSegment(claims) + "."
+		})
+
+		It("should return an Unauthorized error", func() {
+			Expect(err).To(BeAssignableToTypeOf(&Unauthorized{}))
+		})
+	})
+
+	Context("with expired token", func() {
+		BeforeEach(func() {
+			claims["exp"] = now.Add(-time.Hour).Unix()
+			token = signToken("HS256", "", secret, claims)
+		})
+
+		It("should return an Unauthorized error", func() {
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(Equal("unauthorized: token expired"))
+		})
+	})
+
+	Context("without exp claim", func() {
+		BeforeEach(func() {
+			delete(claims, "exp")
+			token = signToken("HS256", "", secret, claims)
+		})
+
+		It("should return an Unauthorized error", func() {
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(Equal("unauthorized: token is missing exp claim"))
+		})
+	})
+
+	Context("with wrong audience", func() {
+		BeforeEach(func() {
+			claims["aud"] = "other"
+			token = signToken("HS256", "", secret, claims)
+		})
+
+		It("should return an Unauthorized error", func() {
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(Equal("unauthorized: invalid token audience"))
+		})
+	})
+
+	Context("with wrong issuer", func() {
+		BeforeEach(func() {
+			claims["iss"] = "other"
+			token = signToken("HS256", "", secret, claims)
+		})
+
+		It("should return an Unauthorized error", func() {
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(Equal("unauthorized: invalid token issuer"))
+		})
+	})
+
+	Context("with JWKS", func() {
+		var (
+			rsaKey *rsa.PrivateKey
+			ecKey  *ecdsa.PrivateKey
+		)
+
+		BeforeEach(func() {
+			var keyErr error
+			rsaKey, keyErr = rsa.GenerateKey(rand.Reader, 2048)
+			Expect(keyErr).To(BeNil())
+			ecKey, keyErr = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(keyErr).To(BeNil())
+
+			jwks := fmt.Sprintf(`{"keys": [
+				{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": %q, "e": %q},
+				{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": %q, "y": %q},
+				{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"}
+			]}`, b64Int(rsaKey.N), b64Int(big.NewInt(int64(rsaKey.E))), b64Int(ecKey.X), b64Int(ecKey.Y))
+			keys, keyErr := ParseJWKS([]byte(jwks))
+			Expect(keyErr).To(BeNil())
+			verifier = NewJWTVerifier(keys, "", "")
+			verifier.now = func() time.Time { return now }
+		})
+
+		Context("with RS256 token", func() {
+			BeforeEach(func() {
+				token = signToken("RS256", "rsa-1", rsaKey, claims)
+			})
+
+			It("should NOT return an error", func() {
+				Expect(err).To(BeNil())
+				Expect(result.String("sub")).To(Equal("user-1"))
+			})
+		})
+
+		Context("with ES256 token", func() {
+			BeforeEach(func() {
+				token = signToken("ES256", "ec-1", ecKey, claims)
+			})
+
+			It("should NOT return an error", func() {
+				Expect(err).To(BeNil())
+			})
+		})
+
+		Context("with unknown kid", func() {
+			BeforeEach(func() {
+				token = signToken("RS256", "enc-1", rsaKey, claims)
+			})
+
+			It("should return an Unauthorized error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("unauthorized: unknown signing key"))
+			})
+		})
+
+		Context("with alg not matching the key", func() {
+			BeforeEach(func() {
+				token = signToken("ES256", "rsa-1", ecKey, claims)
+			})
+
+			It("should return an Unauthorized error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("unauthorized: invalid token signature"))
+			})
+		})
+	})
+})
+
+var _ = Describe("JWTAuthenticator", func() {
+	var (
+		secret        []byte
+		authenticator *JWTAuthenticator
+		claims        Claims
+		header        string
+		identity      *Identity
+		err           error
+	)
+
+	BeforeEach(func() {
+		secret = []byte("test-secret")
+		authenticator = NewJWTAuthenticator(NewJWTVerifier(NewSecretKeySet(secret), "", ""), "customer_id")
+		claims = Claims{
+			"sub":         "user-1",
+			"customer_id": "test-customer",
+			"scp":         []string{"posts:read", "posts:approve"},
+			"exp":         time.Now().Add(time.Hour).Unix(),
+		}
+	})
+
+	JustBeforeEach(func() {
+		req := httptest.NewRequest("GET", "/", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		identity, err = authenticator.Authenticate(req)
+	})
+
+	Context("without bearer token", func() {
+		BeforeEach(func() {
+			header = ""
+		})
+
+		It("should skip the request", func() {
+			Expect(err).To(BeNil())
+			Expect(identity).To(BeNil())
+		})
+	})
+
+	Context("without customer claim", func() {
+		BeforeEach(func() {
+			delete(claims, "customer_id")
+			header = "Bearer " + signToken("HS256", "", secret, claims)
+		})
+
+		It("should return an Unauthorized error", func() {
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(Equal("unauthorized: token is missing customer_id claim"))
+		})
+	})
+
+	Context("with valid token", func() {
+		BeforeEach(func() {
+			header = "Bearer " + signToken("HS256", "", secret, claims)
+		})
+
+		It("should derive the identity from the claims", func() {
+			Expect(err).To(BeNil())
+			Expect(identity.CustomerID).To(Equal("test-customer"))
+			Expect(identity.Subject).To(Equal("user-1"))
+			Expect(identity.HasScope(ScopePostsApprove)).To(BeTrue())
+			Expect(identity.HasScope(ScopePostsWrite)).To(BeFalse())
+		})
+	})
+})
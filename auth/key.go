@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	keyPrefix      = "cck_"
+	keySecretBytes = 24
+	keyDisplayLen  = 8
+)
+
+// Key stores the information about an API key. The secret itself is never stored,
+// only its hash
+type Key struct {
+	ID        string     `json:"id"`
+	CustID    string     `json:"-"` // do not return when we marshal to json
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"` // do not return when we marshal to json
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Revoked returns true if the key has been revoked
+func (k *Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// KeyStore defines the interface for creating, looking up and revoking API keys
+type KeyStore interface {
+	Create(string) (*Key, string, error)
+	Lookup(string) (*Key, error)
+	List(string) ([]*Key, error)
+	Rotate(string, string) (*Key, string, error)
+	Revoke(string, string) error
+}
+
+// APIKeyHeader is the header callers put their api key in
+const APIKeyHeader = "x-api-key"
+
+// APIKeyAuthenticator implements the Authenticator interface using a KeyStore
+type APIKeyAuthenticator struct {
+	keys KeyStore
+}
+
+// NewAPIKeyAuthenticator returns an APIKeyAuthenticator with the provided options
+func NewAPIKeyAuthenticator(keys KeyStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{
+		keys: keys,
+	}
+}
+
+// Authenticate looks up the api key in the request header. Keys are not scoped,
+// so they are granted every scope for their customer
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	secret := r.Header.Get(APIKeyHeader)
+	if secret == "" {
+		return nil, nil
+	}
+
+	key, err := a.keys.Lookup(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		CustomerID: key.CustID,
+		KeyID:      key.ID,
+		Scopes:     AllScopes,
+	}, nil
+}
+
+// HashKey returns the hex encoded sha256 of the supplied secret
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateSecret returns a new random secret and the prefix used to identify it
+func generateSecret() (string, string, error) {
+	b := make([]byte, keySecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	random := hex.EncodeToString(b)
+	return keyPrefix + random, random[:keyDisplayLen], nil
+}
+
+// looksLikeKey does a cheap sanity check before hashing a caller supplied secret
+func looksLikeKey(secret string) bool {
+	return strings.HasPrefix(secret, keyPrefix) && len(secret) == len(keyPrefix)+2*keySecretBytes
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// InMemoryKeyStore implements the KeyStore interface for in memory storage
+type InMemoryKeyStore struct {
+	logger *log.Logger
+	mu     sync.RWMutex
+	byHash map[string]*Key
+	byID   map[string]*Key
+}
+
+// NewInMemoryKeyStore creates a new InMemoryKeyStore with the provided options
+func NewInMemoryKeyStore(logger *log.Logger) *InMemoryKeyStore {
+	return &InMemoryKeyStore{
+		logger: logger,
+		byHash: make(map[string]*Key),
+		byID:   make(map[string]*Key),
+	}
+}
+
+// Create generates a new key for the customer. The plain text secret is only
+// returned here and can not be recovered later
+func (s *InMemoryKeyStore) Create(customerID string) (*Key, string, error) {
+	if customerID == "" {
+		return nil, "", datastore.NewInvalidArugmentError("customerID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create(customerID)
+}
+
+// Lookup finds the key matching the supplied secret. Unknown and revoked keys
+// return an Unauthorized error
+func (s *InMemoryKeyStore) Lookup(secret string) (*Key, error) {
+	if !looksLikeKey(secret) {
+		return nil, NewUnauthorizedError("invalid api key")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.byHash[HashKey(secret)]
+	if !ok {
+		return nil, NewUnauthorizedError("invalid api key")
+	}
+	if key.Revoked() {
+		return nil, NewUnauthorizedError("api key revoked")
+	}
+	return key, nil
+}
+
+// List returns all of the keys, including revoked ones, for the customer
+func (s *InMemoryKeyStore) List(customerID string) ([]*Key, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := []*Key{}
+	for _, key := range s.byID {
+		if key.CustID == customerID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Rotate revokes the key and creates a new one for the same customer
+func (s *InMemoryKeyStore) Rotate(customerID, keyID string) (*Key, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.revoke(customerID, keyID); err != nil {
+		return nil, "", err
+	}
+	return s.create(customerID)
+}
+
+// Revoke marks the key as revoked, it will no longer authenticate requests
+func (s *InMemoryKeyStore) Revoke(customerID, keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revoke(customerID, keyID)
+}
+
+func (s *InMemoryKeyStore) create(customerID string) (*Key, string, error) {
+	secret, prefix, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &Key{
+		ID:        bson.NewObjectId().Hex(),
+		CustID:    customerID,
+		Prefix:    prefix,
+		Hash:      HashKey(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+	s.byHash[key.Hash] = key
+	s.byID[key.ID] = key
+
+	s.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"key_id":     key.ID,
+	}).Info("created api key")
+	return key, secret, nil
+}
+
+func (s *InMemoryKeyStore) revoke(customerID, keyID string) error {
+	if customerID == "" {
+		return datastore.NewInvalidArugmentError("customerID")
+	}
+
+	// Another customer's key is not found, so key ids can not be probed
+	key, ok := s.byID[keyID]
+	if !ok || key.CustID != customerID {
+		return datastore.NewNotFoundError("key")
+	}
+	if key.Revoked() {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+
+	s.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"key_id":     key.ID,
+	}).Info("revoked api key")
+	return nil
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("InMemoryKeyStore", func() {
+	var (
+		logger     *log.Logger
+		store      *InMemoryKeyStore
+		customerID string
+	)
+
+	BeforeEach(func() {
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		store = NewInMemoryKeyStore(logger)
+		customerID = "test-customer"
+	})
+
+	Describe("Create", func() {
+		var (
+			key    *Key
+			secret string
+			err    error
+		)
+
+		JustBeforeEach(func() {
+			key, secret, err = store.Create(customerID)
+		})
+
+		Context("without customerID", func() {
+			BeforeEach(func() {
+				customerID = ""
+			})
+
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("invalid customerID"))
+			})
+
+			It("should NOT return a key", func() {
+				Expect(key).To(BeNil())
+				Expect(secret).To(BeEmpty())
+			})
+		})
+
+		Context("with customerID", func() {
+			It("should NOT return an error", func() {
+				Expect(err).To(BeNil())
+			})
+
+			It("should return a key for the customer", func() {
+				Expect(key.ID).NotTo(BeEmpty())
+				Expect(key.CustID).To(Equal(customerID))
+				Expect(key.Revoked()).To(BeFalse())
+			})
+
+			It("should only store the hash of the secret", func() {
+				Expect(key.Hash).To(Equal(HashKey(secret)))
+				Expect(key.Hash).NotTo(ContainSubstring(secret))
+				Expect(store.byHash).To(HaveKeyWithValue(key.Hash, key))
+			})
+		})
+	})
+
+	Describe("Lookup", func() {
+		var (
+			key    *Key
+			secret string
+			err    error
+		)
+
+		BeforeEach(func() {
+			_, secret, err = store.Create(customerID)
+			Expect(err).To(BeNil())
+		})
+
+		JustBeforeEach(func() {
+			key, err = store.Lookup(secret)
+		})
+
+		Context("with malformed secret", func() {
+			BeforeEach(func() {
+				secret = "blah"
+			})
+
+			It("should return an Unauthorized error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&Unauthorized{}))
+				Expect(err.Error()).To(Equal("unauthorized: invalid api key"))
+			})
+		})
+
+		Context("with unknown secret", func() {
+			BeforeEach(func() {
+				other := NewInMemoryKeyStore(logger)
+				_, secret, err = other.Create(customerID)
+				Expect(err).To(BeNil())
+			})
+
+			It("should return an Unauthorized error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&Unauthorized{}))
+				Expect(key).To(BeNil())
+			})
+		})
+
+		Context("with revoked key", func() {
+			BeforeEach(func() {
+				k, err := store.Lookup(secret)
+				Expect(err).To(BeNil())
+				Expect(store.Revoke(customerID, k.ID)).To(Succeed())
+			})
+
+			It("should return an Unauthorized error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&Unauthorized{}))
+				Expect(err.Error()).To(Equal("unauthorized: api key revoked"))
+			})
+		})
+
+		Context("with valid secret", func() {
+			It("should return the customer's key", func() {
+				Expect(err).To(BeNil())
+				Expect(key.CustID).To(Equal(customerID))
+			})
+		})
+	})
+
+	Describe("Rotate", func() {
+		var (
+			oldKey    *Key
+			oldSecret string
+			newKey    *Key
+			newSecret string
+			keyID     string
+			rotator   string
+			err       error
+		)
+
+		BeforeEach(func() {
+			oldKey, oldSecret, err = store.Create(customerID)
+			Expect(err).To(BeNil())
+			keyID = oldKey.ID
+			rotator = customerID
+		})
+
+		JustBeforeEach(func() {
+			newKey, newSecret, err = store.Rotate(rotator, keyID)
+		})
+
+		Context("with unknown key", func() {
+			BeforeEach(func() {
+				keyID = "blah"
+			})
+
+			It("should return a NotFound error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("key not found"))
+			})
+		})
+
+		Context("with another customer's key", func() {
+			BeforeEach(func() {
+				rotator = "other-customer"
+			})
+
+			It("should return a NotFound error like an unknown key", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("key not found"))
+			})
+
+			It("should NOT revoke the key", func() {
+				Expect(oldKey.Revoked()).To(BeFalse())
+			})
+		})
+
+		Context("with customer's key", func() {
+			It("should return a new key", func() {
+				Expect(err).To(BeNil())
+				Expect(newKey.ID).NotTo(Equal(oldKey.ID))
+				Expect(newSecret).NotTo(Equal(oldSecret))
+			})
+
+			It("should revoke the old key", func() {
+				_, err := store.Lookup(oldSecret)
+				Expect(err).NotTo(BeNil())
+			})
+
+			It("should authenticate with the new key", func() {
+				key, err := store.Lookup(newSecret)
+				Expect(err).To(BeNil())
+				Expect(key.CustID).To(Equal(customerID))
+			})
+		})
+	})
+
+	Describe("List", func() {
+		It("should only return the customer's keys", func() {
+			_, _, err := store.Create(customerID)
+			Expect(err).To(BeNil())
+			_, _, err = store.Create("other-customer")
+			Expect(err).To(BeNil())
+
+			keys, err := store.List(customerID)
+			Expect(err).To(BeNil())
+			Expect(keys).To(HaveLen(1))
+			Expect(keys[0].CustID).To(Equal(customerID))
+		})
+	})
+})
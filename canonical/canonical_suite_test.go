@@ -0,0 +1,13 @@
+package canonical_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCanonical(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Canonical Suite")
+}
@@ -0,0 +1,118 @@
+package canonical
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters that never change the document
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"amp":     true,
+}
+
+// ErrInvalidURL is returned for urls that can not be canonicalized
+var ErrInvalidURL = errors.New("url must be an absolute http or https url")
+
+// URL returns the canonical form of an article url, so every variant of the same
+// article maps to the same string. http and https are treated as the same and the
+// result is always https. The host is lower cased without "www.", "amp." or a
+// default port. AMP pages and Google AMP cache urls are mapped to the article.
+// Tracking parameters (utm_*, fbclid, ...) and the fragment are removed, the
+// remaining parameters are sorted and a trailing slash is removed from the path
+func URL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "", ErrInvalidURL
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", ErrInvalidURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if target, ok := unwrapAMPCache(host, u.Path); ok {
+		return URL(target)
+	}
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "amp.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 literals keep their brackets
+		host = "[" + host + "]"
+	}
+
+	return (&url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     canonicalPath(u.Path),
+		RawQuery: canonicalQuery(u.Query()),
+	}).String(), nil
+}
+
+// unwrapAMPCache returns the article url for urls served from the Google AMP
+// caches, e.g. https://www.google.com/amp/s/example.com/post
+func unwrapAMPCache(host, path string) (string, bool) {
+	var rest string
+	switch {
+	case host == "google.com" || host == "www.google.com":
+		if !strings.HasPrefix(path, "/amp/") {
+			return "", false
+		}
+		rest = strings.TrimPrefix(path, "/amp/")
+	case strings.HasSuffix(host, ".cdn.ampproject.org"):
+		// /c/ is a document, /c/s/ a document served over https
+		if !strings.HasPrefix(path, "/c/") {
+			return "", false
+		}
+		rest = strings.TrimPrefix(path, "/c/")
+	default:
+		return "", false
+	}
+	rest = strings.TrimPrefix(rest, "s/")
+	if rest == "" {
+		return "", false
+	}
+	return "https://" + rest, true
+}
+
+func canonicalPath(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	path = strings.TrimSuffix(path, "/amp")
+	if strings.HasSuffix(path, ".amp.html") {
+		path = strings.TrimSuffix(path, ".amp.html") + ".html"
+	}
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// canonicalQuery drops tracking parameters. Encode sorts by key
+func canonicalQuery(query url.Values) string {
+	for key, values := range query {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			delete(query, key)
+			continue
+		}
+		if lower == "outputtype" && len(values) == 1 && strings.EqualFold(values[0], "amp") {
+			delete(query, key)
+		}
+	}
+	return query.Encode()
+}
@@ -0,0 +1,73 @@
+package canonical
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("URL", func() {
+	It("should map every variant of an article to the same url", func() {
+		expected := "https://example.com/2019/12/04/post"
+		variants := []string{
+			"https://example.com/2019/12/04/post",
+			"http://example.com/2019/12/04/post",
+			"https://example.com/2019/12/04/post/",
+			"HTTPS://WWW.Example.COM/2019/12/04/post",
+			"https://example.com:443/2019/12/04/post",
+			"http://example.com:80/2019/12/04/post",
+			"https://example.com/2019/12/04/post#comments",
+			"https://example.com/2019/12/04/post?utm_source=twitter&utm_medium=social",
+			"https://example.com/2019/12/04/post?fbclid=abc&gclid=def",
+			"https://example.com/2019/12/04/post/amp",
+			"https://example.com/2019/12/04/post/amp/",
+			"https://example.com/2019/12/04/post?amp=1",
+			"https://example.com/2019/12/04/post?outputType=amp",
+			"https://amp.example.com/2019/12/04/post",
+			"https://www.google.com/amp/s/example.com/2019/12/04/post",
+			"https://example-com.cdn.ampproject.org/c/s/example.com/2019/12/04/post/amp/",
+			"  https://example.com/2019/12/04/post  ",
+		}
+		for _, v := range variants {
+			actual, err := URL(v)
+			Expect(err).To(BeNil(), v)
+			Expect(actual).To(Equal(expected), v)
+		}
+	})
+
+	It("should map amp html pages to the article", func() {
+		actual, err := URL("https://example.com/news/story.amp.html")
+		Expect(err).To(BeNil())
+		Expect(actual).To(Equal("https://example.com/news/story.html"))
+	})
+
+	It("should keep and sort parameters that identify the document", func() {
+		actual, err := URL("https://example.com/article?page=2&id=10&utm_campaign=x")
+		Expect(err).To(BeNil())
+		Expect(actual).To(Equal("https://example.com/article?id=10&page=2"))
+	})
+
+	It("should keep non default ports", func() {
+		actual, err := URL("http://example.com:8080/post/")
+		Expect(err).To(BeNil())
+		Expect(actual).To(Equal("https://example.com:8080/post"))
+	})
+
+	It("should use / for an empty path", func() {
+		actual, err := URL("https://example.com")
+		Expect(err).To(BeNil())
+		Expect(actual).To(Equal("https://example.com/"))
+	})
+
+	It("should keep ipv6 hosts valid", func() {
+		actual, err := URL("http://[2001:DB8::1]:80/post")
+		Expect(err).To(BeNil())
+		Expect(actual).To(Equal("https://[2001:db8::1]/post"))
+	})
+
+	It("should reject urls that are not absolute http(s) urls", func() {
+		for _, v := range []string{"", "example.com/post", "/post", "ftp://example.com/post", "https://"} {
+			_, err := URL(v)
+			Expect(err).To(Equal(ErrInvalidURL), v)
+		}
+	})
+})
@@ -2,18 +2,41 @@ package caption
 
 import (
 	"crypto/sha1"
+	"sync"
 
 	textapi "github.com/AYLIEN/aylien_textapi_go"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/canonical"
+	"github.com/bpross/cc-hw/datastore"
 )
 
-// SummarizeFunc defines the function used by AylienGenerator to request captions
+// SummarizeFunc defines the function used by AylienGenerator to request captions.
+// It is called concurrently
 type SummarizeFunc func(*textapi.SummarizeParams) (*textapi.SummarizeResponse, error)
 
-// AylienGenerator implements the generator interface and uses Aylien API to do so
+// NewAylienSummarizeFunc returns a SummarizeFunc that calls Aylien with the auth.
+// A textapi.Client records the rate limits of every response on itself, so it
+// can not be shared by concurrent calls and every call gets its own
+func NewAylienSummarizeFunc(auth textapi.Auth) (SummarizeFunc, error) {
+	if _, err := textapi.NewClient(auth, true); err != nil {
+		return nil, err
+	}
+	return func(params *textapi.SummarizeParams) (*textapi.SummarizeResponse, error) {
+		client, err := textapi.NewClient(auth, true)
+		if err != nil {
+			return nil, err
+		}
+		return client.Summarize(params)
+	}, nil
+}
+
+// AylienGenerator implements the generator interface and uses Aylien API to do so.
+// It is safe for concurrent use
 type AylienGenerator struct {
 	logger        *log.Logger
 	summarizeFunc SummarizeFunc
+	mu            sync.RWMutex
 	cache         map[string][]string // TODO make this access a datastore
 }
 
@@ -31,16 +54,18 @@ func NewAylienGenerator(logger *log.Logger, summarizeFunc SummarizeFunc) *Aylien
 func (g *AylienGenerator) Create(url string, numCaptions int) ([]string, error) {
 	logger := g.logger.WithFields(log.Fields{
 		"url":         url,
-		"numCaptions": string(numCaptions),
+		"numCaptions": numCaptions,
 	})
 	logger.Info("generating captions")
-	// First check if we have already summarized this url
-	h := sha1.New()
-	h.Write([]byte(url))
-	id := string(h.Sum(nil))
-	if captions, ok := g.cache[id]; ok {
+	// First check if we have already summarized this url, any variant of the
+	// article shares the same cache entry
+	id := cacheKey(url)
+	g.mu.RLock()
+	captions, ok := g.cache[id]
+	g.mu.RUnlock()
+	if ok {
 		logger.Debug("cache hit")
-		return captions, nil
+		return copyStrings(captions), nil
 	}
 	logger.Debug("cache miss")
 	// Create request
@@ -54,11 +79,30 @@ func (g *AylienGenerator) Create(url string, numCaptions int) ([]string, error)
 	resp, err := g.summarizeFunc(req)
 	if err != nil {
 		logger.Error(err)
-		return nil, err
+		return nil, datastore.NewUnavailableError("caption generator")
 	}
 
 	logger.Debug("request successfull, adding captions to cache")
-	// insert into cache
-	g.cache[id] = resp.Sentences
+	// insert into cache, callers get their own copy so they can not change it
+	g.mu.Lock()
+	g.cache[id] = copyStrings(resp.Sentences)
+	g.mu.Unlock()
 	return resp.Sentences, nil
 }
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+// cacheKey hashes the canonical url, falling back to the url as given
+func cacheKey(url string) string {
+	if canonicalURL, err := canonical.URL(url); err == nil {
+		url = canonicalURL
+	}
+	h := sha1.New()
+	h.Write([]byte(url))
+	return string(h.Sum(nil))
+}
@@ -44,9 +44,11 @@ var _ = Describe("InMemoryDatastore", func() {
 				return nil, nil
 			}
 			g = NewAylienGenerator(logger, mockSummarize)
+			// a variant of the url shares the cache entry
 			h := sha1.New()
-			h.Write([]byte(url))
+			h.Write([]byte("https://test-url.com/"))
 			id := string(h.Sum(nil))
+			url = "http://www.test-url.com/?utm_source=twitter"
 			cachedCaptions = []string{
 				"test4",
 				"test5",
@@ -80,7 +82,7 @@ var _ = Describe("InMemoryDatastore", func() {
 
 		It("should return an error", func() {
 			Expect(err).NotTo(BeNil())
-			Expect(err.Error()).To(Equal("summarize error"))
+			Expect(err.Error()).To(Equal("caption generator unavailable"))
 		})
 
 		It("should NOT return captions", func() {
@@ -117,7 +119,7 @@ var _ = Describe("InMemoryDatastore", func() {
 
 		It("should put captions in the cache", func() {
 			h := sha1.New()
-			h.Write([]byte(url))
+			h.Write([]byte("https://test-url.com/"))
 			id := string(h.Sum(nil))
 			Expect(g.cache).To(HaveKeyWithValue(id, sentences))
 		})
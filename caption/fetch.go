@@ -0,0 +1,315 @@
+package caption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// Default limits used by DefaultFetchPolicy
+const (
+	DefaultMaxBodyBytes = 5 << 20
+	DefaultMaxRedirects = 5
+	DefaultFetchTimeout = 10 * time.Second
+)
+
+// blockedNetworks are never fetched: loopback, private, link-local (including
+// cloud metadata endpoints), carrier-grade nat and other special use ranges
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// FetchPolicy configures what a SafeClient is allowed to fetch
+type FetchPolicy struct {
+	AllowedPorts []int
+	MaxBodyBytes int64
+	MaxRedirects int
+	Timeout      time.Duration
+}
+
+// DefaultFetchPolicy only allows the default http and https ports
+func DefaultFetchPolicy() FetchPolicy {
+	return FetchPolicy{
+		AllowedPorts: []int{80, 443},
+		MaxBodyBytes: DefaultMaxBodyBytes,
+		MaxRedirects: DefaultMaxRedirects,
+		Timeout:      DefaultFetchTimeout,
+	}
+}
+
+// FetchResult is a fetched document
+type FetchResult struct {
+	URL         string
+	ContentType string
+	Body        []byte
+}
+
+// SafeClient fetches tenant supplied urls without letting them reach internal
+// hosts. Every url, including each redirect, is resolved and checked before the
+// request is sent, and the address is checked again when the connection is made
+// so a DNS answer that changes between the two can not be used to get around it
+type SafeClient struct {
+	logger  *log.Logger
+	policy  FetchPolicy
+	client  *http.Client
+	lookup  func(context.Context, string) ([]net.IPAddr, error)
+	blocked func(net.IP) bool
+}
+
+// NewSafeClient returns a SafeClient with the provided policy
+func NewSafeClient(logger *log.Logger, policy FetchPolicy) *SafeClient {
+	c := &SafeClient{
+		logger:  logger,
+		policy:  policy,
+		lookup:  net.DefaultResolver.LookupIPAddr,
+		blocked: isBlockedIP,
+	}
+
+	dialer := &net.Dialer{
+		Timeout: policy.Timeout,
+		Control: c.control,
+	}
+	transport := &http.Transport{
+		// Never use a proxy from the environment, it would be the one dialed
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   policy.Timeout,
+		ResponseHeaderTimeout: policy.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	c.client = &http.Client{
+		Transport:     transport,
+		Timeout:       policy.Timeout,
+		CheckRedirect: c.checkRedirect,
+	}
+	return c
+}
+
+// Fetch gets the url and returns its body. Urls that are not allowed are
+// returned as a *datastore.Validation on the url field, a url that can not be
+// reached is returned as *datastore.Unavailable
+func (c *SafeClient) Fetch(ctx context.Context, rawURL string) (*FetchResult, error) {
+	logger := c.logger.WithFields(log.Fields{
+		"url": rawURL,
+	})
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, invalidURL("must be an absolute url")
+	}
+	if err := c.checkURL(ctx, u); err != nil {
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Warn("blocked fetch")
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, invalidURL("must be an absolute url")
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		var verr *datastore.Validation
+		if errors.As(err, &verr) {
+			logger.WithFields(log.Fields{
+				"error": verr.Error(),
+			}).Warn("blocked fetch")
+			return nil, verr
+		}
+		logger.Error(err)
+		return nil, datastore.NewUnavailableError("url")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, invalidURL(fmt.Sprintf("returned status %d", resp.StatusCode))
+	}
+	if resp.ContentLength > c.policy.MaxBodyBytes {
+		return nil, invalidURL(fmt.Sprintf("response is larger than %d bytes", c.policy.MaxBodyBytes))
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, c.policy.MaxBodyBytes+1))
+	if err != nil {
+		logger.Error(err)
+		return nil, datastore.NewUnavailableError("url")
+	}
+	if int64(len(body)) > c.policy.MaxBodyBytes {
+		return nil, invalidURL(fmt.Sprintf("response is larger than %d bytes", c.policy.MaxBodyBytes))
+	}
+
+	return &FetchResult{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, nil
+}
+
+// Do sends the request with the same checks as Fetch, for callers that need a
+// method other than GET. The caller must close the response body
+func (c *SafeClient) Do(req *http.Request) (*http.Response, error) {
+	logger := c.logger.WithFields(log.Fields{
+		"url": req.URL.String(),
+	})
+
+	if err := c.checkURL(req.Context(), req.URL); err != nil {
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Warn("blocked request")
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		var verr *datastore.Validation
+		if errors.As(err, &verr) {
+			logger.WithFields(log.Fields{
+				"error": verr.Error(),
+			}).Warn("blocked request")
+			return nil, verr
+		}
+		logger.Error(err)
+		return nil, datastore.NewUnavailableError("url")
+	}
+	return resp, nil
+}
+
+func (c *SafeClient) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > c.policy.MaxRedirects {
+		return invalidURL(fmt.Sprintf("redirected more than %d times", c.policy.MaxRedirects))
+	}
+	return c.checkURL(req.Context(), req.URL)
+}
+
+// checkURL checks the scheme and port, and that every address the host resolves
+// to is allowed
+func (c *SafeClient) checkURL(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return invalidURL("must be http or https")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return invalidURL("must have a host")
+	}
+	if u.User != nil {
+		return invalidURL("must not contain credentials")
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if !c.portAllowed(port) {
+		return invalidURL(fmt.Sprintf("port %s is not allowed", port))
+	}
+
+	var addrs []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IPAddr{{IP: ip}}
+	} else {
+		var err error
+		addrs, err = c.lookup(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			return invalidURL(fmt.Sprintf("host %s could not be resolved", host))
+		}
+	}
+	for _, addr := range addrs {
+		if c.blocked(addr.IP) {
+			return invalidURL(fmt.Sprintf("host %s resolves to a blocked address", host))
+		}
+	}
+	return nil
+}
+
+// control runs after the address has been resolved and right before the
+// connection is made, so it sees the address that is actually dialed
+func (c *SafeClient) control(network, address string, conn syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || c.blocked(ip) {
+		return invalidURL("connected to a blocked address")
+	}
+	if !c.portAllowed(port) {
+		return invalidURL(fmt.Sprintf("port %s is not allowed", port))
+	}
+	return nil
+}
+
+func (c *SafeClient) portAllowed(port string) bool {
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range c.policy.AllowedPorts {
+		if p == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlockedIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, n := range blockedNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func invalidURL(msg string) *datastore.Validation {
+	return datastore.NewValidationError(datastore.FieldError{Field: "url", Message: msg})
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = n
+	}
+	return networks
+}
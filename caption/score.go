@@ -0,0 +1,210 @@
+package caption
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+// DefaultIdealLength is the length, in characters, of a caption that fits best
+const DefaultIdealLength = 100
+
+// Scorer defines the interface for scoring a candidate caption of a url, from 0
+// for the worst fit to 1 for the best
+type Scorer interface {
+	Score(url, caption string) float64
+}
+
+// ScorerFunc lets a function be used as a Scorer
+type ScorerFunc func(url, caption string) float64
+
+// Score calls the function
+func (f ScorerFunc) Score(url, caption string) float64 {
+	return f(url, caption)
+}
+
+// LengthScorer scores a caption by how close its length is to Ideal. A caption
+// twice as long as Ideal, or empty, scores 0
+type LengthScorer struct {
+	Ideal int
+}
+
+// Score implements the Scorer interface
+func (s LengthScorer) Score(_, caption string) float64 {
+	if s.Ideal <= 0 {
+		return 0
+	}
+	length := float64(utf8.RuneCountInString(caption))
+	return clamp(1 - math.Abs(length-float64(s.Ideal))/float64(s.Ideal))
+}
+
+// ReadabilityScorer scores a caption by its Flesch reading ease, easier to read
+// captions score higher
+type ReadabilityScorer struct{}
+
+// Score implements the Scorer interface
+func (ReadabilityScorer) Score(_, caption string) float64 {
+	words := splitWords(caption)
+	if len(words) == 0 {
+		return 0
+	}
+	sentences := 0
+	for _, field := range strings.FieldsFunc(caption, func(r rune) bool { return r == '.' || r == '!' || r == '?' }) {
+		if len(splitWords(field)) > 0 {
+			sentences++
+		}
+	}
+	syllables := 0
+	for _, word := range words {
+		syllables += countSyllables(word)
+	}
+	ease := 206.835 - 1.015*float64(len(words))/float64(sentences) - 84.6*float64(syllables)/float64(len(words))
+	return clamp(ease / 100)
+}
+
+// KeywordScorer scores a caption by the share of the keywords in the url's path
+// that it contains. A url without keywords scores 0 for every caption
+type KeywordScorer struct{}
+
+// Score implements the Scorer interface
+func (KeywordScorer) Score(rawURL, caption string) float64 {
+	keywords := urlKeywords(rawURL)
+	if len(keywords) == 0 {
+		return 0
+	}
+	words := map[string]bool{}
+	for _, word := range splitWords(caption) {
+		words[word] = true
+	}
+	covered := 0
+	for _, keyword := range keywords {
+		if words[keyword] {
+			covered++
+		}
+	}
+	return float64(covered) / float64(len(keywords))
+}
+
+// Weighted is a scorer and how much it counts in a WeightedScorer
+type Weighted struct {
+	Scorer Scorer
+	Weight float64
+}
+
+// WeightedScorer scores a caption by the weighted mean of the scores of its
+// scorers
+type WeightedScorer []Weighted
+
+// Score implements the Scorer interface
+func (s WeightedScorer) Score(url, caption string) float64 {
+	total, weights := 0.0, 0.0
+	for _, w := range s {
+		total += w.Weight * w.Scorer.Score(url, caption)
+		weights += w.Weight
+	}
+	if weights <= 0 {
+		return 0
+	}
+	return total / weights
+}
+
+// DefaultScorer returns a scorer that weighs length fit, readability and
+// keyword coverage equally
+func DefaultScorer() Scorer {
+	return WeightedScorer{
+		{Scorer: LengthScorer{Ideal: DefaultIdealLength}, Weight: 1},
+		{Scorer: ReadabilityScorer{}, Weight: 1},
+		{Scorer: KeywordScorer{}, Weight: 1},
+	}
+}
+
+// Rank returns the candidates as generated captions of the url, best scored
+// first. Candidates with the same score keep the order they were generated in
+func Rank(scorer Scorer, url string, candidates []string) []*dao.Caption {
+	captions := make([]*dao.Caption, len(candidates))
+	for i, text := range candidates {
+		captions[i] = &dao.Caption{Text: text, Source: dao.SourceGenerated}
+	}
+	return RankCaptions(scorer, url, captions)
+}
+
+// RankCaptions scores the captions of the url and sorts them in place, best
+// scored first. Captions with the same score keep their order
+func RankCaptions(scorer Scorer, url string, captions []*dao.Caption) []*dao.Caption {
+	for _, caption := range captions {
+		caption.Score = math.Round(scorer.Score(url, caption.Text)*1000) / 1000
+	}
+	sort.SliceStable(captions, func(i, j int) bool {
+		return captions[i].Score > captions[j].Score
+	})
+	return captions
+}
+
+// stopWords are left out of the keywords of a url
+var stopWords = map[string]bool{
+	"and": true, "are": true, "for": true, "from": true, "has": true, "have": true,
+	"how": true, "htm": true, "html": true, "index": true, "its": true, "not": true,
+	"php": true, "that": true, "the": true, "this": true, "was": true, "what": true,
+	"when": true, "why": true, "will": true, "with": true, "you": true, "your": true,
+}
+
+// Keywords returns the keywords of the url's path that KeywordScorer looks for,
+// in the order they appear
+func Keywords(rawURL string) []string {
+	return urlKeywords(rawURL)
+}
+
+// urlKeywords returns the distinct words of the url's path that are at least
+// three letters long and not stop words
+func urlKeywords(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	keywords := []string{}
+	for _, word := range splitWords(u.Path) {
+		if utf8.RuneCountInString(word) < 3 || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+	}
+	return keywords
+}
+
+// splitWords returns the lower cased runs of letters of s
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// countSyllables estimates the syllables of a word as its groups of vowels, not
+// counting a silent e at the end
+func countSyllables(word string) int {
+	count, vowel := 0, false
+	for _, r := range word {
+		isVowel := strings.ContainsRune("aeiouy", r)
+		if isVowel && !vowel {
+			count++
+		}
+		vowel = isVowel
+	}
+	if count > 1 && strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") {
+		count--
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+func clamp(score float64) float64 {
+	return math.Max(0, math.Min(1, score))
+}
@@ -0,0 +1,95 @@
+package caption
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+var _ = Describe("Scorers", func() {
+	const url = "https://example.com/2020/01/baking-sourdough-bread-at-home.html"
+
+	Describe("LengthScorer", func() {
+		It("should score by how close the length is to the ideal", func() {
+			s := LengthScorer{Ideal: 10}
+			cases := []struct {
+				caption string
+				score   float64
+			}{
+				{strings.Repeat("a", 10), 1},
+				{strings.Repeat("é", 5), 0.5},
+				{strings.Repeat("a", 15), 0.5},
+				{strings.Repeat("a", 25), 0},
+				{"", 0},
+			}
+			for _, c := range cases {
+				Expect(s.Score(url, c.caption)).To(BeNumerically("~", c.score, 0.001), c.caption)
+			}
+		})
+	})
+
+	Describe("ReadabilityScorer", func() {
+		It("should score short words and sentences higher", func() {
+			s := ReadabilityScorer{}
+			easy := s.Score(url, "The cat sat on the mat. It was warm.")
+			hard := s.Score(url, "Characteristically, institutionalized organizations underestimate unprecedented considerations")
+			Expect(easy).To(BeNumerically(">", hard))
+			Expect(easy).To(BeNumerically("<=", 1))
+			Expect(hard).To(BeNumerically(">=", 0))
+		})
+
+		It("should score a caption without words 0", func() {
+			Expect(ReadabilityScorer{}.Score(url, "?!")).To(BeZero())
+		})
+	})
+
+	Describe("KeywordScorer", func() {
+		It("should score the share of the url's keywords in the caption", func() {
+			s := KeywordScorer{}
+			Expect(s.Score(url, "Baking sourdough bread at home, step by step")).To(BeNumerically("~", 1, 0.001))
+			Expect(s.Score(url, "How to bake Sourdough")).To(BeNumerically("~", 0.25, 0.001))
+			Expect(s.Score(url, "Nothing in common")).To(BeZero())
+		})
+
+		It("should score every caption 0 for a url without keywords", func() {
+			Expect(KeywordScorer{}.Score("https://example.com/", "anything")).To(BeZero())
+		})
+	})
+
+	Describe("WeightedScorer", func() {
+		It("should return the weighted mean", func() {
+			s := WeightedScorer{
+				{Scorer: ScorerFunc(func(string, string) float64 { return 1 }), Weight: 3},
+				{Scorer: ScorerFunc(func(string, string) float64 { return 0 }), Weight: 1},
+			}
+			Expect(s.Score(url, "caption")).To(BeNumerically("~", 0.75, 0.001))
+		})
+
+		It("should score 0 without weights", func() {
+			Expect(WeightedScorer{}.Score(url, "caption")).To(BeZero())
+		})
+	})
+})
+
+var _ = Describe("Rank", func() {
+	It("should order the candidates by score as generated captions", func() {
+		scores := map[string]float64{"low": 0.1, "high": 0.9, "tie1": 0.5, "tie2": 0.5}
+		scorer := ScorerFunc(func(_, caption string) float64 { return scores[caption] })
+
+		captions := Rank(scorer, "https://example.com", []string{"tie1", "low", "high", "tie2"})
+		Expect(dao.CaptionTexts(captions)).To(Equal([]string{"high", "tie1", "tie2", "low"}))
+		Expect(captions[0]).To(Equal(&dao.Caption{Text: "high", Source: dao.SourceGenerated, Score: 0.9}))
+	})
+
+	It("should rank with the default scorer", func() {
+		captions := Rank(DefaultScorer(), "https://example.com/sourdough-bread", []string{
+			"Zymological considerations notwithstanding, fermentation characteristics vary",
+			"Sourdough bread is easy to bake at home with a simple starter and some time.",
+		})
+		Expect(captions[0].Text).To(HavePrefix("Sourdough"))
+		Expect(captions[0].Score).To(BeNumerically(">", captions[1].Score))
+	})
+})
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults used by DefaultRetryPolicy
+const (
+	DefaultMaxAttempts    = 4
+	DefaultInitialBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff     = 10 * time.Second
+
+	// apiPrefix is the version of the api the client calls
+	apiPrefix            = "/v2"
+	apiKeyHeader         = "x-api-key"
+	idempotencyKeyHeader = "Idempotency-Key"
+	userAgent            = "cc-hw-client/1"
+)
+
+// RetryPolicy configures how often a call is tried. After a failed attempt the
+// next one waits InitialBackoff, doubling every time up to MaxBackoff, unless
+// the server asks for longer with Retry-After
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy tries a call for a few seconds
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    DefaultMaxAttempts,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+	}
+}
+
+// backoff returns how long to wait after the number of failed attempts
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// Doer sends http requests, it is satisfied by *http.Client
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Client calls the post api as the customer of its api key. It is safe for
+// concurrent use
+type Client struct {
+	baseURL string
+	apiKey  string
+	doer    Doer
+	policy  RetryPolicy
+}
+
+// NewClient returns a Client for the server at baseURL, e.g.
+// http://localhost:8080. A nil doer uses http.DefaultClient
+func NewClient(baseURL, apiKey string, doer Doer, policy RetryPolicy) *Client {
+	if doer == nil {
+		doer = http.DefaultClient
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		doer:    doer,
+		policy:  policy,
+	}
+}
+
+// call is one api call
+type call struct {
+	method string
+	path   string
+	body   interface{}
+	// idempotent calls are retried. POSTs are made idempotent with an
+	// Idempotency-Key, so the server never applies them twice
+	idempotent bool
+}
+
+// do makes the call, retrying idempotent calls that failed on the network or
+// with a status worth retrying, and decodes a 2xx response into out when it is
+// not nil. Other responses are returned as an *Error
+func (c *Client) do(ctx context.Context, cl call, out interface{}) (*http.Response, error) {
+	var body []byte
+	if cl.body != nil {
+		var err error
+		if body, err = json.Marshal(cl.body); err != nil {
+			return nil, err
+		}
+	}
+	key := ""
+	if cl.idempotent && cl.method == http.MethodPost {
+		key = newIdempotencyKey()
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, cl, body, key)
+		if err == nil {
+			if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+				defer resp.Body.Close()
+				defer drain(resp.Body)
+				if out == nil || resp.StatusCode == http.StatusNoContent {
+					return resp, nil
+				}
+				return resp, json.NewDecoder(resp.Body).Decode(out)
+			}
+			err = decodeError(resp)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !cl.idempotent || attempt >= c.policy.MaxAttempts || !retryable(err) {
+			return nil, err
+		}
+
+		wait := c.policy.backoff(attempt)
+		if apiErr, ok := err.(*Error); ok && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, cl call, body []byte, key string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(cl.method, c.baseURL+apiPrefix+cl.path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set(apiKeyHeader, c.apiKey)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	return c.doer.Do(req)
+}
+
+// retryable returns true if the call may succeed when made again. Network
+// errors, rate limits and unavailable servers are retried
+func retryable(err error) bool {
+	apiErr, ok := err.(*Error)
+	if !ok {
+		return true
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		// A spent monthly quota does not come back by retrying
+		return apiErr.Code != CodeQuotaExceeded
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the Retry-After header in seconds as a duration
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// drain reads what is left of a body so the connection can be reused
+func drain(r io.Reader) {
+	io.Copy(ioutil.Discard, io.LimitReader(r, 64<<10))
+}
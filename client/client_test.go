@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// response is what the fake server answers a request with
+type response struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// fakeServer answers requests with its responses in order, repeating the last one
+type fakeServer struct {
+	mu        sync.Mutex
+	responses []response
+	requests  []*http.Request
+	bodies    []string
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	s.bodies = append(s.bodies, string(body))
+	resp := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	for name, value := range resp.headers {
+		w.Header().Set(name, value)
+	}
+	w.WriteHeader(resp.status)
+	w.Write([]byte(resp.body))
+}
+
+func (s *fakeServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func testPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+var _ = Describe("Client", func() {
+	var (
+		fake   *fakeServer
+		server *httptest.Server
+		client *Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		fake = &fakeServer{}
+		server = httptest.NewServer(fake)
+		client = NewClient(server.URL+"/", "test-key", nil, testPolicy())
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("authentication", func() {
+		BeforeEach(func() {
+			fake.responses = []response{{status: http.StatusOK, body: `{"id":"5e154899cb80cb0001000003"}`}}
+		})
+
+		It("should send the api key", func() {
+			_, err := client.Get(ctx, "5e154899cb80cb0001000003")
+			Expect(err).To(BeNil())
+			Expect(fake.requests[0].Header.Get("x-api-key")).To(Equal("test-key"))
+			Expect(fake.requests[0].URL.Path).To(Equal("/v2/post/5e154899cb80cb0001000003"))
+		})
+	})
+
+	Describe("errors", func() {
+		Context("with a problem", func() {
+			BeforeEach(func() {
+				fake.responses = []response{{
+					status:  http.StatusBadRequest,
+					headers: map[string]string{"Content-Type": "application/problem+json"},
+					body:    `{"type":"about:blank","title":"Bad Request","status":400,"code":"validation_failed","detail":"validation failed: url: must be http or https","errors":[{"field":"url","message":"must be http or https"}]}`,
+				}}
+			})
+
+			It("should return an *Error", func() {
+				_, err := client.Generate(ctx, "ftp://example.com")
+				Expect(errors.Is(err, ErrValidationFailed)).To(BeTrue())
+				Expect(errors.Is(err, ErrNotFound)).To(BeFalse())
+				apiErr, ok := err.(*Error)
+				Expect(ok).To(BeTrue())
+				Expect(apiErr.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(apiErr.Errors[0].Field).To(Equal("url"))
+				Expect(err.Error()).To(Equal("400 validation_failed: validation failed: url: must be http or https"))
+			})
+
+			It("should NOT retry", func() {
+				client.Get(ctx, "5e154899cb80cb0001000003")
+				Expect(fake.count()).To(Equal(1))
+			})
+		})
+
+		Context("without a problem", func() {
+			BeforeEach(func() {
+				fake.responses = []response{{status: http.StatusNotFound, body: "404 page not found"}}
+			})
+
+			It("should use the code of the status", func() {
+				_, err := client.Get(ctx, "5e154899cb80cb0001000003")
+				Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("retries", func() {
+		Context("with an unavailable server", func() {
+			BeforeEach(func() {
+				fake.responses = []response{
+					{status: http.StatusServiceUnavailable, body: `{"status":503,"code":"unavailable"}`},
+					{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}, body: `{"status":429,"code":"rate_limited"}`},
+					{status: http.StatusOK, body: `{"id":"5e154899cb80cb0001000003","url":"https://example.com"}`},
+				}
+			})
+
+			It("should retry idempotent calls", func() {
+				post, err := client.Get(ctx, "5e154899cb80cb0001000003")
+				Expect(err).To(BeNil())
+				Expect(post.URL).To(Equal("https://example.com"))
+				Expect(fake.count()).To(Equal(3))
+			})
+
+			It("should retry a POST with the same idempotency key", func() {
+				_, err := client.Generate(ctx, "https://example.com")
+				Expect(err).To(BeNil())
+				key := fake.requests[0].Header.Get("Idempotency-Key")
+				Expect(key).NotTo(BeEmpty())
+				for i, req := range fake.requests {
+					Expect(req.Header.Get("Idempotency-Key")).To(Equal(key))
+					Expect(fake.bodies[i]).To(Equal(`{"url":"https://example.com"}`))
+				}
+			})
+
+			It("should stop after MaxAttempts", func() {
+				client = NewClient(server.URL, "test-key", nil, RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+				_, err := client.Get(ctx, "5e154899cb80cb0001000003")
+				Expect(errors.Is(err, ErrRateLimited)).To(BeTrue())
+				Expect(err.(*Error).RetryAfter).To(Equal(time.Duration(0)))
+				Expect(fake.count()).To(Equal(2))
+			})
+		})
+
+		Context("with an exceeded quota", func() {
+			BeforeEach(func() {
+				fake.responses = []response{{status: http.StatusTooManyRequests, body: `{"status":429,"code":"quota_exceeded"}`}}
+			})
+
+			It("should NOT retry", func() {
+				_, err := client.Generate(ctx, "https://example.com")
+				Expect(errors.Is(err, ErrQuotaExceeded)).To(BeTrue())
+				Expect(fake.count()).To(Equal(1))
+			})
+		})
+
+		Context("with a cancelled context", func() {
+			BeforeEach(func() {
+				fake.responses = []response{{status: http.StatusServiceUnavailable, headers: map[string]string{"Retry-After": "60"}}}
+			})
+
+			It("should stop waiting", func() {
+				ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+				defer cancel()
+				_, err := client.Get(ctx, "5e154899cb80cb0001000003")
+				Expect(err).To(Equal(context.DeadlineExceeded))
+				Expect(fake.count()).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("backoff", func() {
+		It("should double up to MaxBackoff", func() {
+			policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second}
+			Expect(policy.backoff(1)).To(Equal(time.Second))
+			Expect(policy.backoff(2)).To(Equal(2 * time.Second))
+			Expect(policy.backoff(3)).To(Equal(3 * time.Second))
+		})
+	})
+})
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Codes of the problems returned by the server
+const (
+	CodeNotFound             = "not_found"
+	CodeInvalidArgument      = "invalid_argument"
+	CodeValidationFailed     = "validation_failed"
+	CodeConflict             = "conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodeUnavailable          = "unavailable"
+	CodeQuotaExceeded        = "quota_exceeded"
+	CodeUnauthenticated      = "unauthenticated"
+	CodeForbidden            = "forbidden"
+	CodeRateLimited          = "rate_limited"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeInternal             = "internal"
+)
+
+// maxErrorBytes is the most of an error response that is read
+const maxErrorBytes = 64 << 10
+
+// Errors to compare against with errors.Is, they match any *Error with the
+// same code
+var (
+	ErrNotFound           = &Error{Code: CodeNotFound}
+	ErrInvalidArgument    = &Error{Code: CodeInvalidArgument}
+	ErrValidationFailed   = &Error{Code: CodeValidationFailed}
+	ErrConflict           = &Error{Code: CodeConflict}
+	ErrPreconditionFailed = &Error{Code: CodePreconditionFailed}
+	ErrUnavailable        = &Error{Code: CodeUnavailable}
+	ErrQuotaExceeded      = &Error{Code: CodeQuotaExceeded}
+	ErrUnauthenticated    = &Error{Code: CodeUnauthenticated}
+	ErrForbidden          = &Error{Code: CodeForbidden}
+	ErrRateLimited        = &Error{Code: CodeRateLimited}
+)
+
+// FieldError is a field the server rejected and why
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a problem returned by the server. Code is stable and can be switched
+// on, Detail is meant for people
+type Error struct {
+	StatusCode int          `json:"status"`
+	Code       string       `json:"code"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	Errors     []FieldError `json:"errors"`
+	// RetryAfter is how long the server asked to wait before trying again
+	RetryAfter time.Duration `json:"-"`
+}
+
+// Error implements the Error interface
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Detail)
+}
+
+// Is returns true if the target is an *Error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// decodeError reads the problem of a failed response and closes it. Responses
+// that are not problems, like those of a proxy, get a code from their status
+func decodeError(resp *http.Response) error {
+	defer resp.Body.Close()
+	defer drain(resp.Body)
+
+	apiErr := &Error{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBytes)).Decode(apiErr); err != nil || apiErr.Code == "" {
+		apiErr = &Error{Code: statusCode(resp.StatusCode), Title: http.StatusText(resp.StatusCode)}
+	}
+	apiErr.StatusCode = resp.StatusCode
+	apiErr.RetryAfter = retryAfter(resp)
+	return apiErr
+}
+
+// statusCode returns the code the server uses for a status
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
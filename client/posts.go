@@ -0,0 +1,259 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+type postRequest struct {
+	URL      string   `json:"url"`
+	Captions []string `json:"captions,omitempty"`
+}
+
+type putRequest struct {
+	Captions []string `json:"captions"`
+}
+
+type reorderRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type batchRequest struct {
+	Items []postRequest `json:"items"`
+}
+
+type batchResponse struct {
+	Results []struct {
+		Post  *dao.Post `json:"post"`
+		Error *Error    `json:"error"`
+	} `json:"results"`
+}
+
+// ListOptions selects a page of posts. Posts are listed oldest first unless Sort
+// is set, a page starts after the Next of the previous one in After. Limit is at
+// most 100, the server default is used when it is 0
+type ListOptions struct {
+	After string
+	Limit int
+	// Sort is created_at or updated_at, with a leading - for newest first
+	Sort string
+	// Filters are left out when they are zero
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	CreatedBy     string
+	UpdatedBy     string
+}
+
+// PostPage is a page of posts. Next is the After of the next page, it is empty
+// on the last page
+type PostPage struct {
+	Posts []*dao.Post `json:"posts"`
+	Next  string      `json:"next"`
+}
+
+// Generate creates a post for the url with generated captions. Generating counts
+// against the customer's quota
+func (c *Client) Generate(ctx context.Context, postURL string) (*dao.Post, error) {
+	post := &dao.Post{}
+	if _, err := c.do(ctx, call{method: http.MethodPost, path: "/post", body: postRequest{URL: postURL}, idempotent: true}, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Create creates a post for the url with the captions. Captions are generated if
+// there are none
+func (c *Client) Create(ctx context.Context, postURL string, captions []string) (*dao.Post, error) {
+	resp := &batchResponse{}
+	req := batchRequest{Items: []postRequest{{URL: postURL, Captions: captions}}}
+	if _, err := c.do(ctx, call{method: http.MethodPost, path: "/posts:batch", body: req, idempotent: true}, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != 1 {
+		return nil, fmt.Errorf("expected 1 result for the post, got %d", len(resp.Results))
+	}
+	result := resp.Results[0]
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return result.Post, nil
+}
+
+// Get returns the post
+func (c *Client) Get(ctx context.Context, id string) (*dao.Post, error) {
+	post := &dao.Post{}
+	if _, err := c.do(ctx, call{method: http.MethodGet, path: postPath(id), idempotent: true}, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Update replaces the captions of the post and returns it
+func (c *Client) Update(ctx context.Context, id string, captions []string) (*dao.Post, error) {
+	post := &dao.Post{}
+	if _, err := c.do(ctx, call{method: http.MethodPut, path: postPath(id), body: putRequest{Captions: captions}, idempotent: true}, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// SelectCaption selects the caption that is published with the post, any other
+// caption is unselected
+func (c *Client) SelectCaption(ctx context.Context, id, captionID string) (*dao.Post, error) {
+	return c.postCall(ctx, call{method: http.MethodPost, path: captionPath(id, captionID) + "/select", idempotent: true})
+}
+
+// PinCaption pins the caption ahead of the others
+func (c *Client) PinCaption(ctx context.Context, id, captionID string) (*dao.Post, error) {
+	return c.postCall(ctx, call{method: http.MethodPost, path: captionPath(id, captionID) + "/pin", idempotent: true})
+}
+
+// UnpinCaption unpins the caption
+func (c *Client) UnpinCaption(ctx context.Context, id, captionID string) (*dao.Post, error) {
+	return c.postCall(ctx, call{method: http.MethodPost, path: captionPath(id, captionID) + "/unpin", idempotent: true})
+}
+
+// DiscardCaption removes the caption from the post. Pinned captions have to be
+// unpinned first
+func (c *Client) DiscardCaption(ctx context.Context, id, captionID string) (*dao.Post, error) {
+	return c.postCall(ctx, call{method: http.MethodDelete, path: captionPath(id, captionID), idempotent: true})
+}
+
+// ReorderCaptions puts the captions of the post in the order of the ids, which
+// must list every caption once
+func (c *Client) ReorderCaptions(ctx context.Context, id string, captionIDs []string) (*dao.Post, error) {
+	return c.postCall(ctx, call{method: http.MethodPut, path: postPath(id) + "/captions/order", body: reorderRequest{IDs: captionIDs}, idempotent: true})
+}
+
+// postCall makes a call that responds with a post
+func (c *Client) postCall(ctx context.Context, cl call) (*dao.Post, error) {
+	post := &dao.Post{}
+	if _, err := c.do(ctx, cl, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Delete deletes the post and its revisions
+func (c *Client) Delete(ctx context.Context, id string) error {
+	_, err := c.do(ctx, call{method: http.MethodDelete, path: postPath(id), idempotent: true}, nil)
+	return err
+}
+
+// List returns a page of the customer's posts
+func (c *Client) List(ctx context.Context, opts ListOptions) (*PostPage, error) {
+	query := url.Values{}
+	if opts.After != "" {
+		query.Set("after", opts.After)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	setTime(query, "created_after", opts.CreatedAfter)
+	setTime(query, "created_before", opts.CreatedBefore)
+	setTime(query, "updated_after", opts.UpdatedAfter)
+	setTime(query, "updated_before", opts.UpdatedBefore)
+	if opts.CreatedBy != "" {
+		query.Set("created_by", opts.CreatedBy)
+	}
+	if opts.UpdatedBy != "" {
+		query.Set("updated_by", opts.UpdatedBy)
+	}
+	path := "/posts"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	page := &PostPage{}
+	if _, err := c.do(ctx, call{method: http.MethodGet, path: path, idempotent: true}, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Posts returns an iterator over all of the customer's posts, reading pageSize
+// posts at a time
+func (c *Client) Posts(ctx context.Context, pageSize int) *PostIterator {
+	return c.PostsWith(ctx, ListOptions{Limit: pageSize})
+}
+
+// PostsWith returns an iterator over the customer's posts selected by opts,
+// starting at opts.After
+func (c *Client) PostsWith(ctx context.Context, opts ListOptions) *PostIterator {
+	return &PostIterator{client: c, ctx: ctx, opts: opts}
+}
+
+// PostIterator reads the customer's posts a page at a time:
+//
+//	it := c.Posts(ctx, 100)
+//	for it.Next() {
+//		post := it.Post()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type PostIterator struct {
+	client *Client
+	ctx    context.Context
+	opts   ListOptions
+	page   []*dao.Post
+	post   *dao.Post
+	done   bool
+	err    error
+}
+
+// Next moves to the next post, reading the next page when needed. It returns
+// false when there are no more posts or a page could not be read
+func (it *PostIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			it.post = nil
+			return false
+		}
+		page, err := it.client.List(it.ctx, it.opts)
+		if err != nil {
+			it.err = err
+			continue
+		}
+		it.page = page.Posts
+		it.opts.After = page.Next
+		it.done = page.Next == ""
+	}
+	it.post, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Post returns the current post
+func (it *PostIterator) Post() *dao.Post {
+	return it.post
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *PostIterator) Err() error {
+	return it.err
+}
+
+func postPath(id string) string {
+	return "/post/" + url.PathEscape(id)
+}
+
+func captionPath(id, captionID string) string {
+	return postPath(id) + "/captions/" + url.PathEscape(captionID)
+}
+
+// setTime sets the query parameter to the time, unless it is zero
+func setTime(query url.Values, name string, t time.Time) {
+	if !t.IsZero() {
+		query.Set(name, t.UTC().Format(time.RFC3339Nano))
+	}
+}
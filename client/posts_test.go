@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+var _ = Describe("Posts", func() {
+	var (
+		fake   *fakeServer
+		server *httptest.Server
+		client *Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		fake = &fakeServer{}
+		server = httptest.NewServer(fake)
+		client = NewClient(server.URL, "test-key", nil, testPolicy())
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Create", func() {
+		It("should create the post through a batch", func() {
+			fake.responses = []response{{status: http.StatusOK, body: `{"results":[{"index":0,"status":200,"post":{"id":"5e154899cb80cb0001000003","url":"https://example.com","captions":[{"id":"c1","text":"caption1","source":"manual"}]}}]}`}}
+			post, err := client.Create(ctx, "https://example.com", []string{"caption1"})
+			Expect(err).To(BeNil())
+			Expect(post.ID.Hex()).To(Equal("5e154899cb80cb0001000003"))
+			Expect(fake.requests[0].Method).To(Equal("POST"))
+			Expect(fake.requests[0].URL.Path).To(Equal("/v2/posts:batch"))
+			Expect(fake.bodies[0]).To(Equal(`{"items":[{"url":"https://example.com","captions":["caption1"]}]}`))
+		})
+
+		It("should return the error of the item", func() {
+			fake.responses = []response{{status: http.StatusOK, body: `{"results":[{"index":0,"status":400,"error":{"status":400,"code":"validation_failed","detail":"validation failed"}}]}`}}
+			_, err := client.Create(ctx, "ftp://example.com", nil)
+			Expect(errors.Is(err, ErrValidationFailed)).To(BeTrue())
+		})
+
+		It("should return an error without a result", func() {
+			fake.responses = []response{{status: http.StatusOK, body: `{"results":[]}`}}
+			post, err := client.Create(ctx, "https://example.com", []string{"caption1"})
+			Expect(err).To(MatchError("expected 1 result for the post, got 0"))
+			Expect(post).To(BeNil())
+		})
+	})
+
+	Describe("Update", func() {
+		It("should put the captions", func() {
+			fake.responses = []response{{status: http.StatusOK, body: `{"id":"5e154899cb80cb0001000003","captions":[{"id":"c2","text":"caption2","source":"manual"}]}`}}
+			post, err := client.Update(ctx, "5e154899cb80cb0001000003", []string{"caption2"})
+			Expect(err).To(BeNil())
+			Expect(dao.CaptionTexts(post.Captions)).To(Equal([]string{"caption2"}))
+			Expect(fake.requests[0].Method).To(Equal("PUT"))
+			Expect(fake.bodies[0]).To(Equal(`{"captions":["caption2"]}`))
+		})
+	})
+
+	Describe("Captions", func() {
+		const post = `{"id":"5e154899cb80cb0001000003","captions":[{"id":"c1","text":"caption1","source":"manual","selected":true}]}`
+
+		It("should send every caption action to its route", func() {
+			cases := []struct {
+				do     func() (*dao.Post, error)
+				method string
+				path   string
+				body   string
+			}{
+				{func() (*dao.Post, error) { return client.SelectCaption(ctx, "5e154899cb80cb0001000003", "c1") }, "POST", "/v2/post/5e154899cb80cb0001000003/captions/c1/select", ""},
+				{func() (*dao.Post, error) { return client.PinCaption(ctx, "5e154899cb80cb0001000003", "c1") }, "POST", "/v2/post/5e154899cb80cb0001000003/captions/c1/pin", ""},
+				{func() (*dao.Post, error) { return client.UnpinCaption(ctx, "5e154899cb80cb0001000003", "c1") }, "POST", "/v2/post/5e154899cb80cb0001000003/captions/c1/unpin", ""},
+				{func() (*dao.Post, error) { return client.DiscardCaption(ctx, "5e154899cb80cb0001000003", "c1") }, "DELETE", "/v2/post/5e154899cb80cb0001000003/captions/c1", ""},
+				{func() (*dao.Post, error) {
+					return client.ReorderCaptions(ctx, "5e154899cb80cb0001000003", []string{"c1"})
+				}, "PUT", "/v2/post/5e154899cb80cb0001000003/captions/order", `{"ids":["c1"]}`},
+			}
+			fake.responses = []response{{status: http.StatusOK, body: post}}
+			for i, c := range cases {
+				got, err := c.do()
+				Expect(err).To(BeNil())
+				Expect(got.Captions[0].Selected).To(BeTrue())
+				Expect(fake.requests[i].Method).To(Equal(c.method))
+				Expect(fake.requests[i].URL.Path).To(Equal(c.path))
+				Expect(fake.bodies[i]).To(Equal(c.body))
+			}
+		})
+
+		It("should return the conflict of a pinned caption", func() {
+			fake.responses = []response{{status: http.StatusConflict, body: `{"status":409,"code":"conflict","detail":"conflict: caption is pinned"}`}}
+			_, err := client.DiscardCaption(ctx, "5e154899cb80cb0001000003", "c1")
+			Expect(errors.Is(err, ErrConflict)).To(BeTrue())
+		})
+	})
+
+	Describe("Delete", func() {
+		It("should delete the post", func() {
+			fake.responses = []response{{status: http.StatusNoContent}}
+			Expect(client.Delete(ctx, "5e154899cb80cb0001000003")).To(Succeed())
+			Expect(fake.requests[0].Method).To(Equal("DELETE"))
+		})
+	})
+
+	Describe("Posts", func() {
+		BeforeEach(func() {
+			fake.responses = []response{
+				{status: http.StatusOK, body: `{"posts":[{"url":"https://example.com/0"},{"url":"https://example.com/1"}],"next":"5e154899cb80cb0001000003"}`},
+				{status: http.StatusOK, body: `{"posts":[{"url":"https://example.com/2"}]}`},
+			}
+		})
+
+		It("should read every page", func() {
+			it := client.Posts(ctx, 2)
+			urls := []string{}
+			for it.Next() {
+				urls = append(urls, it.Post().URL)
+			}
+			Expect(it.Err()).To(BeNil())
+			Expect(urls).To(Equal([]string{"https://example.com/0", "https://example.com/1", "https://example.com/2"}))
+			Expect(fake.requests[0].URL.RawQuery).To(Equal("limit=2"))
+			Expect(fake.requests[1].URL.RawQuery).To(Equal("after=5e154899cb80cb0001000003&limit=2"))
+		})
+
+		It("should send the sort and filters with every page", func() {
+			it := client.PostsWith(ctx, ListOptions{
+				Sort:         "-updated_at",
+				CreatedAfter: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				UpdatedBy:    "editor",
+			})
+			for it.Next() {
+			}
+			Expect(it.Err()).To(BeNil())
+			Expect(fake.requests[0].URL.RawQuery).To(Equal("created_after=2026-01-02T03%3A04%3A05Z&sort=-updated_at&updated_by=editor"))
+			Expect(fake.requests[1].URL.RawQuery).To(Equal("after=5e154899cb80cb0001000003&created_after=2026-01-02T03%3A04%3A05Z&sort=-updated_at&updated_by=editor"))
+		})
+
+		It("should stop on an error", func() {
+			fake.responses[1] = response{status: http.StatusBadRequest, body: `{"status":400,"code":"invalid_argument","detail":"invalid after"}`}
+			it := client.Posts(ctx, 2)
+			count := 0
+			for it.Next() {
+				count++
+			}
+			Expect(count).To(Equal(2))
+			Expect(fmt.Sprint(it.Err())).To(Equal("400 invalid_argument: invalid after"))
+			Expect(it.Next()).To(BeFalse())
+		})
+	})
+})
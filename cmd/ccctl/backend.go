@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/dao"
+)
+
+const (
+	// listPageSize is how many posts are read at a time
+	listPageSize = 100
+	// requestTimeout bounds every call to the server
+	requestTimeout = 2 * time.Minute
+	// apiPrefix is the version of the api ccctl calls
+	apiPrefix = "/v2"
+)
+
+// postBackend reads and deletes a customer's posts, from a data file or a server
+type postBackend interface {
+	// List returns a page of posts after the given id and the after of the next
+	// page, which is empty on the last page
+	List(customerID, after string) ([]*dao.Post, string, error)
+	Get(customerID, postID string) (*dao.Post, error)
+	Delete(customerID, postID string) error
+}
+
+// fileBackend uses the Poster of a data file
+type fileBackend struct {
+	posts dao.Poster
+	// actor is who the changes are recorded as made by
+	actor string
+}
+
+func (b *fileBackend) List(customerID, after string) ([]*dao.Post, string, error) {
+	opts := dao.ListOptions{Limit: listPageSize}
+	if after != "" {
+		opts.After = bson.ObjectIdHex(after)
+	}
+	posts, err := b.posts.List(customerID, opts)
+	if err != nil || len(posts) < listPageSize {
+		return posts, "", err
+	}
+	return posts, posts[len(posts)-1].ID.Hex(), nil
+}
+
+func (b *fileBackend) Get(customerID, postID string) (*dao.Post, error) {
+	id, err := parseID(postID)
+	if err != nil {
+		return nil, err
+	}
+	return b.posts.Get(customerID, id)
+}
+
+func (b *fileBackend) Delete(customerID, postID string) error {
+	id, err := parseID(postID)
+	if err != nil {
+		return err
+	}
+	return b.posts.Delete(customerID, id, b.actor)
+}
+
+func parseID(postID string) (bson.ObjectId, error) {
+	if !bson.IsObjectIdHex(postID) {
+		return "", errors.New("invalid post id")
+	}
+	return bson.ObjectIdHex(postID), nil
+}
+
+// apiBackend uses the admin routes of a server
+type apiBackend struct {
+	client *apiClient
+}
+
+// listResponse is a page of GET /admin/customers/:customer_id/posts
+type listResponse struct {
+	Posts []*dao.Post `json:"posts"`
+	Next  string      `json:"next"`
+}
+
+func (b *apiBackend) List(customerID, after string) ([]*dao.Post, string, error) {
+	query := url.Values{"limit": {fmt.Sprint(listPageSize)}}
+	if after != "" {
+		query.Set("after", after)
+	}
+	resp := &listResponse{}
+	if err := b.client.do("GET", customerPath(customerID, "posts")+"?"+query.Encode(), nil, resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Posts, resp.Next, nil
+}
+
+func (b *apiBackend) Get(customerID, postID string) (*dao.Post, error) {
+	post := &dao.Post{}
+	if err := b.client.do("GET", customerPath(customerID, "posts", postID), nil, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (b *apiBackend) Delete(customerID, postID string) error {
+	return b.client.do("DELETE", customerPath(customerID, "posts", postID), nil, nil)
+}
+
+func customerPath(customerID string, parts ...string) string {
+	escaped := []string{"/admin/customers", url.PathEscape(customerID)}
+	for _, part := range parts {
+		escaped = append(escaped, url.PathEscape(part))
+	}
+	return strings.Join(escaped, "/")
+}
+
+// problem is the error body returned by the server
+type problem struct {
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// warmResponse is the response of POST /admin/captions/warm
+type warmResponse struct {
+	Results []struct {
+		URL    string   `json:"url"`
+		Status int      `json:"status"`
+		Error  *problem `json:"error"`
+	} `json:"results"`
+}
+
+// apiClient calls the admin routes of a server with the admin key
+type apiClient struct {
+	server   string
+	adminKey string
+	http     *http.Client
+}
+
+func newAPIClient(cfg *config) *apiClient {
+	return &apiClient{
+		server:   strings.TrimRight(cfg.server, "/"),
+		adminKey: cfg.adminKey,
+		http:     &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// do sends body as json and decodes the response into out, if it is not nil.
+// Responses other than 2xx are returned as errors
+func (c *apiClient) do(method, path string, body, out interface{}) error {
+	if c.adminKey == "" {
+		return errors.New("an admin key is needed to use the server, set -admin-key or $" + envAdminKey)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, c.server+apiPrefix+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(auth.APIKeyHeader, c.adminKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		p := &problem{}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(data, p) != nil || p.Detail == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return fmt.Errorf("%s %s: %d %s: %s", method, path, resp.StatusCode, p.Code, p.Detail)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/dao/decorated"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+	"github.com/bpross/cc-hw/validate"
+)
+
+const (
+	envServer    = "CCCTL_SERVER"
+	envAdminKey  = "ADMIN_API_KEY"
+	envDataFile  = "DATA_FILE"
+	envAuditFile = "AUDIT_FILE"
+
+	defaultServer = "http://localhost:8080"
+	// warmBatchSize is how many urls are sent in a warm request
+	warmBatchSize = 100
+)
+
+const usage = `ccctl operates the caption service, either through the admin api of a
+running server or directly on a data file while the server is stopped.
+
+Usage:
+  ccctl [-server url -admin-key key | -data-file path [-audit-file path]] <command> [flags]
+
+Commands:
+  posts list -customer id [-limit n]      list a customer's posts as json lines
+  posts get -customer id -id post_id      print a post
+  posts delete -customer id -id post_id   delete a post and its revisions
+  keys rotate -customer id -id key_id     revoke a key and print its replacement (server)
+  cache warm [-file urls.txt]             generate captions for a url per line (server)
+  migrate                                 upgrade the data file to the current version (data file)
+  compact                                 rewrite the data file without old versions
+  dump [-out file]                        write every post as json lines (data file)
+  restore [-in file]                      load the posts of a dump (data file)
+  audit verify [-file path]               check the hash chains of an audit log file
+
+The server and admin key default to $CCCTL_SERVER and $ADMIN_API_KEY, the data
+file to $DATA_FILE and the audit log file to $AUDIT_FILE. A data file is used
+when it is set. Posts deleted from a data file are recorded in the audit log
+file, but no webhooks are sent for them since the server is stopped.
+`
+
+// errUsage is returned for a command line that can not be run
+var errUsage = errors.New("invalid usage")
+
+type config struct {
+	server    string
+	adminKey  string
+	dataFile  string
+	auditFile string
+	// out and errOut receive the output of the commands
+	out    io.Writer
+	errOut io.Writer
+}
+
+func main() {
+	cfg := &config{out: os.Stdout, errOut: os.Stderr}
+	flags := flag.NewFlagSet("ccctl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.StringVar(&cfg.server, "server", envDefault(envServer, defaultServer), "url of the server")
+	flags.StringVar(&cfg.adminKey, "admin-key", os.Getenv(envAdminKey), "admin api key of the server")
+	flags.StringVar(&cfg.dataFile, "data-file", os.Getenv(envDataFile), "data file to use instead of the server")
+	flags.StringVar(&cfg.auditFile, "audit-file", os.Getenv(envAuditFile), "audit log file the changes to the data file are recorded in")
+	flags.Parse(os.Args[1:])
+
+	if err := run(cfg, flags.Args()); err != nil {
+		if err == errUsage {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "ccctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg *config, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "posts":
+		if len(args) < 2 {
+			return errUsage
+		}
+		return runPosts(cfg, args[1], args[2:])
+	case "keys":
+		if len(args) < 2 || args[1] != "rotate" {
+			return errUsage
+		}
+		return rotateKey(cfg, args[2:])
+	case "cache":
+		if len(args) < 2 || args[1] != "warm" {
+			return errUsage
+		}
+		return warmCache(cfg, args[2:])
+	case "migrate":
+		return migrate(cfg)
+	case "compact":
+		return compact(cfg)
+	case "dump":
+		return dump(cfg, args[1:])
+	case "restore":
+		return restore(cfg, args[1:])
+	case "audit":
+		if len(args) < 2 || args[1] != "verify" {
+			return errUsage
+		}
+		return verifyAudit(cfg, args[2:])
+	default:
+		return errUsage
+	}
+}
+
+func runPosts(cfg *config, command string, args []string) error {
+	flags := flag.NewFlagSet("posts "+command, flag.ContinueOnError)
+	customerID := flags.String("customer", "", "customer id")
+	postID := flags.String("id", "", "post id")
+	limit := flags.Int("limit", 0, "most posts to list, all when 0")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *customerID == "" || (command != "list" && *postID == "") {
+		return errUsage
+	}
+
+	posts, closeFn, err := openBackend(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	switch command {
+	case "list":
+		return listPosts(cfg.out, posts, *customerID, *limit)
+	case "get":
+		post, err := posts.Get(*customerID, *postID)
+		if err != nil {
+			return err
+		}
+		return printJSON(cfg.out, post)
+	case "delete":
+		if err := posts.Delete(*customerID, *postID); err != nil {
+			return err
+		}
+		fmt.Fprintln(cfg.out, "deleted", *postID)
+		return nil
+	default:
+		return errUsage
+	}
+}
+
+// listPosts prints the customer's posts a page at a time
+func listPosts(out io.Writer, posts postBackend, customerID string, limit int) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	after := ""
+	listed := 0
+	for {
+		page, next, err := posts.List(customerID, after)
+		if err != nil {
+			return err
+		}
+		for _, post := range page {
+			if limit > 0 && listed == limit {
+				return nil
+			}
+			if err := encoder.Encode(post); err != nil {
+				return err
+			}
+			listed++
+		}
+		if next == "" {
+			return nil
+		}
+		after = next
+	}
+}
+
+func rotateKey(cfg *config, args []string) error {
+	flags := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+	customerID := flags.String("customer", "", "customer id")
+	keyID := flags.String("id", "", "key id")
+	if err := flags.Parse(args); err != nil || *customerID == "" || *keyID == "" {
+		return errUsage
+	}
+
+	key := map[string]interface{}{}
+	if err := newAPIClient(cfg).do("POST", customerPath(*customerID, "keys", *keyID, "rotate"), nil, &key); err != nil {
+		return err
+	}
+	return printJSON(cfg.out, key)
+}
+
+// warmCache sends the urls of the file, or stdin, to the server warmBatchSize at
+// a time and prints the urls that failed
+func warmCache(cfg *config, args []string) error {
+	flags := flag.NewFlagSet("cache warm", flag.ContinueOnError)
+	path := flags.String("file", "-", "file with a url per line, - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	in, err := openInput(*path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	client := newAPIClient(cfg)
+	warmed, failed := 0, 0
+	send := func(urls []string) error {
+		resp := &warmResponse{}
+		if err := client.do("POST", "/admin/captions/warm", map[string][]string{"urls": urls}, resp); err != nil {
+			return err
+		}
+		for _, result := range resp.Results {
+			if result.Error != nil {
+				failed++
+				fmt.Fprintf(cfg.errOut, "%s: %d %s\n", result.URL, result.Status, result.Error.Detail)
+				continue
+			}
+			warmed++
+		}
+		return nil
+	}
+
+	urls := []string{}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		url := strings.TrimSpace(scanner.Text())
+		if url == "" || strings.HasPrefix(url, "#") {
+			continue
+		}
+		urls = append(urls, url)
+		if len(urls) == warmBatchSize {
+			if err := send(urls); err != nil {
+				return err
+			}
+			urls = urls[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(urls) > 0 {
+		if err := send(urls); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(cfg.out, "warmed %d urls, %d failed\n", warmed, failed)
+	return nil
+}
+
+func migrate(cfg *config) error {
+	if cfg.dataFile == "" {
+		return errors.New("migrate needs a data file")
+	}
+	from, err := datastore.MigrateFile(cfg.dataFile)
+	if err != nil {
+		return err
+	}
+	if from == datastore.FileVersion {
+		fmt.Fprintf(cfg.out, "%s is already at version %d\n", cfg.dataFile, from)
+		return nil
+	}
+	fmt.Fprintf(cfg.out, "migrated %s from version %d to %d\n", cfg.dataFile, from, datastore.FileVersion)
+	return nil
+}
+
+func compact(cfg *config) error {
+	if cfg.dataFile == "" {
+		if err := newAPIClient(cfg).do("POST", "/admin/datastore/compact", nil, nil); err != nil {
+			return err
+		}
+		fmt.Fprintln(cfg.out, "compacted")
+		return nil
+	}
+
+	ds, err := openDataFile(cfg)
+	if err != nil {
+		return err
+	}
+	defer ds.Close()
+	before := fileSize(cfg.dataFile)
+	if err := ds.Compact(); err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.out, "compacted %s from %d to %d bytes\n", cfg.dataFile, before, fileSize(cfg.dataFile))
+	return nil
+}
+
+func dump(cfg *config, args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	path := flags.String("out", "-", "file to write, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if cfg.dataFile == "" {
+		return errors.New("dump needs a data file")
+	}
+
+	ds, err := openDataFile(cfg)
+	if err != nil {
+		return err
+	}
+	defer ds.Close()
+
+	if *path == "-" {
+		return ds.Dump(cfg.out)
+	}
+	out, err := os.OpenFile(*path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return ds.Dump(out)
+}
+
+func restore(cfg *config, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	path := flags.String("in", "-", "dump to load, - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if cfg.dataFile == "" {
+		return errors.New("restore needs a data file")
+	}
+
+	in, err := openInput(*path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	ds, err := openDataFile(cfg)
+	if err != nil {
+		return err
+	}
+	defer ds.Close()
+	loaded, err := ds.Load(in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.out, "restored %d posts\n", loaded)
+	return nil
+}
+
+// verifyAudit checks every chain of the audit log and prints the head of each.
+// Comparing the heads with ones recorded earlier shows entries that were cut
+// from the end of a chain
+func verifyAudit(cfg *config, args []string) error {
+	flags := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	path := flags.String("file", cfg.auditFile, "audit log file to verify")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *path == "" {
+		return errors.New("audit verify needs an audit log file")
+	}
+
+	in, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	heads, err := audit.Verify(in)
+	if err != nil {
+		return err
+	}
+	for _, head := range heads {
+		fmt.Fprintf(cfg.out, "%s\t%d\t%s\n", head.CustomerID, head.Sequence, head.Hash)
+	}
+	fmt.Fprintf(cfg.out, "verified %d chains\n", len(heads))
+	return nil
+}
+
+// openDataFile opens the data file for the commands that work on the whole file
+func openDataFile(cfg *config) (*datastore.FileDatastore, error) {
+	return datastore.OpenFileDatastore(newLogger(cfg), cfg.dataFile)
+}
+
+// openBackend returns the posts of the data file if it is set, or of the server.
+// The posts of a data file go through the same Posters as the server's, so
+// changes are validated and recorded in the audit log file if it is set
+func openBackend(cfg *config) (postBackend, func(), error) {
+	if cfg.dataFile == "" {
+		return &apiBackend{newAPIClient(cfg)}, func() {}, nil
+	}
+
+	logger := newLogger(cfg)
+	ds, err := datastore.OpenFileDatastore(logger, cfg.dataFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	var auditStore audit.Store = audit.NewInMemoryStore()
+	closeFn := func() { ds.Close() }
+	if cfg.auditFile != "" {
+		fileStore, err := audit.OpenFileStore(logger, cfg.auditFile)
+		if err != nil {
+			ds.Close()
+			return nil, nil, err
+		}
+		auditStore = fileStore
+		closeFn = func() {
+			fileStore.Close()
+			ds.Close()
+		}
+	}
+
+	validator := validate.NewValidator(validate.DefaultRules())
+	posts := decorated.NewPoster(logger, datastore.NewNoOpCache(logger), ds, validator, events.NewInMemoryLog(logger), auditStore)
+	return &fileBackend{posts, fileActor()}, closeFn, nil
+}
+
+// fileActor is the audit actor of changes made to a data file, the user running
+// ccctl when it is known
+func fileActor() string {
+	current, err := user.Current()
+	if err != nil || current.Username == "" {
+		return "ccctl"
+	}
+	return "ccctl:" + current.Username
+}
+
+// newLogger logs only warnings so the output of the commands stays readable
+func newLogger(cfg *config) *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = cfg.errOut
+	logger.Level = logrus.WarnLevel
+	return logger
+}
+
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func envDefault(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("ccctl", func() {
+	var (
+		cfg    *config
+		out    *bytes.Buffer
+		errOut *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		errOut = &bytes.Buffer{}
+		cfg = &config{out: out, errOut: errOut}
+	})
+
+	Context("an invalid command line", func() {
+		cases := []struct {
+			name string
+			args []string
+		}{
+			{"no command", nil},
+			{"an unknown command", []string{"unknown"}},
+			{"posts without a command", []string{"posts"}},
+			{"an unknown posts command", []string{"posts", "unknown", "-customer", "1", "-id", "2"}},
+			{"posts without a customer", []string{"posts", "list"}},
+			{"posts get without an id", []string{"posts", "get", "-customer", "1"}},
+			{"keys without rotate", []string{"keys", "create"}},
+			{"keys rotate without an id", []string{"keys", "rotate", "-customer", "1"}},
+			{"cache without warm", []string{"cache"}},
+			{"audit without verify", []string{"audit", "list"}},
+			{"an unknown flag", []string{"dump", "-unknown"}},
+		}
+		for _, tc := range cases {
+			tc := tc
+			It("should return a usage error for "+tc.name, func() {
+				Expect(run(cfg, tc.args)).To(Equal(errUsage))
+			})
+		}
+	})
+
+	Context("a data file", func() {
+		var (
+			dir    string
+			postID string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "ccctl")
+			Expect(err).To(BeNil())
+			cfg.dataFile = filepath.Join(dir, "posts.jsonl")
+			cfg.auditFile = filepath.Join(dir, "audit.jsonl")
+
+			logger := log.New()
+			logger.Out = ioutil.Discard
+			ds, err := datastore.OpenFileDatastore(logger, cfg.dataFile)
+			Expect(err).To(BeNil())
+			post, err := ds.Insert("1", &dao.Post{
+				URL:      "https://example.com/post",
+				Captions: dao.ManualCaptions([]string{"caption"}),
+			})
+			Expect(err).To(BeNil())
+			Expect(ds.Close()).To(BeNil())
+			postID = post.ID.Hex()
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		listed := func(customerID string) []*dao.Post {
+			out.Reset()
+			Expect(run(cfg, []string{"posts", "list", "-customer", customerID})).To(BeNil())
+			posts := []*dao.Post{}
+			decoder := json.NewDecoder(out)
+			for decoder.More() {
+				post := &dao.Post{}
+				Expect(decoder.Decode(post)).To(BeNil())
+				posts = append(posts, post)
+			}
+			return posts
+		}
+
+		It("should list and get the customer's posts", func() {
+			posts := listed("1")
+			Expect(posts).To(HaveLen(1))
+			Expect(posts[0].ID.Hex()).To(Equal(postID))
+			Expect(listed("2")).To(BeEmpty())
+
+			out.Reset()
+			Expect(run(cfg, []string{"posts", "get", "-customer", "1", "-id", postID})).To(BeNil())
+			post := &dao.Post{}
+			Expect(json.Unmarshal(out.Bytes(), post)).To(BeNil())
+			Expect(post.URL).To(Equal("https://example.com/post"))
+		})
+
+		It("should return an error for a post id that is not valid", func() {
+			Expect(run(cfg, []string{"posts", "get", "-customer", "1", "-id", "not-an-id"})).ToNot(BeNil())
+		})
+
+		It("should record a delete in the audit log file", func() {
+			Expect(run(cfg, []string{"posts", "delete", "-customer", "1", "-id", postID})).To(BeNil())
+			Expect(out.String()).To(Equal("deleted " + postID + "\n"))
+			Expect(listed("1")).To(BeEmpty())
+
+			logger := log.New()
+			logger.Out = ioutil.Discard
+			store, err := audit.OpenFileStore(logger, cfg.auditFile)
+			Expect(err).To(BeNil())
+			defer store.Close()
+			entries, err := store.Query("1", audit.Query{})
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Action).To(Equal(audit.ActionDeleted))
+			Expect(entries[0].PostID).To(Equal(postID))
+			Expect(entries[0].Actor).To(HavePrefix("ccctl"))
+
+			out.Reset()
+			Expect(run(cfg, []string{"audit", "verify"})).To(BeNil())
+			Expect(out.String()).To(HaveSuffix("verified 1 chains\n"))
+		})
+
+		It("should restore a dump into another data file", func() {
+			Expect(run(cfg, []string{"dump"})).To(BeNil())
+			dumped := filepath.Join(dir, "dump.jsonl")
+			Expect(ioutil.WriteFile(dumped, out.Bytes(), 0600)).To(BeNil())
+
+			cfg.dataFile = filepath.Join(dir, "restored.jsonl")
+			out.Reset()
+			Expect(run(cfg, []string{"restore", "-in", dumped})).To(BeNil())
+			Expect(out.String()).To(Equal("restored 1 posts\n"))
+			posts := listed("1")
+			Expect(posts).To(HaveLen(1))
+			Expect(posts[0].ID.Hex()).To(Equal(postID))
+		})
+
+		It("should compact and migrate the data file", func() {
+			Expect(run(cfg, []string{"compact"})).To(BeNil())
+			Expect(out.String()).To(HavePrefix("compacted " + cfg.dataFile))
+
+			out.Reset()
+			Expect(run(cfg, []string{"migrate"})).To(BeNil())
+			Expect(out.String()).To(ContainSubstring("is already at version"))
+		})
+	})
+
+	Context("the commands that need a data file", func() {
+		It("should return an error without one", func() {
+			for _, args := range [][]string{{"migrate"}, {"dump"}, {"restore"}} {
+				err := run(cfg, args)
+				Expect(err).ToNot(BeNil())
+				Expect(err).ToNot(Equal(errUsage))
+			}
+		})
+	})
+
+	Context("audit verify without an audit log file", func() {
+		It("should return an error", func() {
+			Expect(run(cfg, []string{"audit", "verify"})).ToNot(BeNil())
+		})
+	})
+
+	Context("a server", func() {
+		var (
+			server   *httptest.Server
+			requests []string
+			posts    []*dao.Post
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			posts = nil
+			for i := 0; i < listPageSize+1; i++ {
+				id := bson.NewObjectId()
+				posts = append(posts, &dao.Post{ID: &id, URL: "https://example.com/post"})
+			}
+			writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(v)
+			}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.RequestURI())
+				if r.Header.Get(auth.APIKeyHeader) != "admin-key" {
+					writeJSON(w, http.StatusUnauthorized, &problem{Status: http.StatusUnauthorized, Code: "unauthorized", Detail: "invalid admin key"})
+					return
+				}
+				switch {
+				case r.Method == "GET" && r.URL.Path == "/v2/admin/customers/1/posts":
+					if r.URL.Query().Get("after") == "" {
+						writeJSON(w, http.StatusOK, &listResponse{Posts: posts[:listPageSize], Next: posts[listPageSize-1].ID.Hex()})
+						return
+					}
+					writeJSON(w, http.StatusOK, &listResponse{Posts: posts[listPageSize:]})
+				case r.Method == "GET" && r.URL.Path == "/v2/admin/customers/1/posts/"+posts[0].ID.Hex():
+					writeJSON(w, http.StatusOK, posts[0])
+				case r.Method == "DELETE", r.URL.Path == "/v2/admin/datastore/compact":
+					w.WriteHeader(http.StatusNoContent)
+				case r.Method == "POST" && r.URL.Path == "/v2/admin/customers/1/keys/key-1/rotate":
+					writeJSON(w, http.StatusCreated, map[string]string{"id": "key-2"})
+				case r.Method == "POST" && r.URL.Path == "/v2/admin/captions/warm":
+					body := map[string][]string{}
+					json.NewDecoder(r.Body).Decode(&body)
+					resp := &warmResponse{}
+					for _, url := range body["urls"] {
+						result := struct {
+							URL    string   `json:"url"`
+							Status int      `json:"status"`
+							Error  *problem `json:"error"`
+						}{URL: url, Status: http.StatusOK}
+						if strings.Contains(url, "bad") {
+							result.Status = http.StatusBadRequest
+							result.Error = &problem{Status: http.StatusBadRequest, Code: "invalid_argument", Detail: "invalid url"}
+						}
+						resp.Results = append(resp.Results, result)
+					}
+					writeJSON(w, http.StatusOK, resp)
+				default:
+					writeJSON(w, http.StatusNotFound, &problem{Status: http.StatusNotFound, Code: "not_found", Detail: "post not found"})
+				}
+			}))
+			cfg.server = server.URL
+			cfg.adminKey = "admin-key"
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should list every page of posts", func() {
+			Expect(run(cfg, []string{"posts", "list", "-customer", "1"})).To(BeNil())
+			Expect(strings.Count(out.String(), "\n")).To(Equal(listPageSize + 1))
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[1]).To(ContainSubstring("after=" + posts[listPageSize-1].ID.Hex()))
+		})
+
+		It("should stop listing at the limit", func() {
+			Expect(run(cfg, []string{"posts", "list", "-customer", "1", "-limit", "3"})).To(BeNil())
+			Expect(strings.Count(out.String(), "\n")).To(Equal(3))
+			Expect(requests).To(HaveLen(1))
+		})
+
+		It("should get and delete a post", func() {
+			Expect(run(cfg, []string{"posts", "get", "-customer", "1", "-id", posts[0].ID.Hex()})).To(BeNil())
+			Expect(out.String()).To(ContainSubstring(posts[0].ID.Hex()))
+
+			out.Reset()
+			Expect(run(cfg, []string{"posts", "delete", "-customer", "1", "-id", posts[1].ID.Hex()})).To(BeNil())
+			Expect(out.String()).To(Equal("deleted " + posts[1].ID.Hex() + "\n"))
+			Expect(requests[1]).To(Equal("DELETE /v2/admin/customers/1/posts/" + posts[1].ID.Hex()))
+		})
+
+		It("should return the problem of a failed request", func() {
+			err := run(cfg, []string{"posts", "get", "-customer", "1", "-id", "missing"})
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("GET /admin/customers/1/posts/missing: 404 not_found: post not found"))
+		})
+
+		It("should need an admin key", func() {
+			cfg.adminKey = ""
+			Expect(run(cfg, []string{"posts", "list", "-customer", "1"})).ToNot(BeNil())
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("should rotate a key", func() {
+			Expect(run(cfg, []string{"keys", "rotate", "-customer", "1", "-id", "key-1"})).To(BeNil())
+			Expect(out.String()).To(ContainSubstring(`"id": "key-2"`))
+		})
+
+		It("should warm the urls of a file and print the ones that failed", func() {
+			path := filepath.Join(os.TempDir(), "ccctl-urls.txt")
+			Expect(ioutil.WriteFile(path, []byte("# urls\nhttps://example.com/1\n\nhttps://bad.example.com\n"), 0600)).To(BeNil())
+			defer os.Remove(path)
+
+			Expect(run(cfg, []string{"cache", "warm", "-file", path})).To(BeNil())
+			Expect(out.String()).To(Equal("warmed 1 urls, 1 failed\n"))
+			Expect(errOut.String()).To(Equal("https://bad.example.com: 400 invalid url\n"))
+		})
+
+		It("should compact through the server", func() {
+			Expect(run(cfg, []string{"compact"})).To(BeNil())
+			Expect(requests).To(Equal([]string{"POST /v2/admin/datastore/compact"}))
+		})
+	})
+})
@@ -1,25 +1,80 @@
 package main
 
 import (
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	textapi "github.com/AYLIEN/aylien_textapi_go"
 	ginlogrus "github.com/Bose/go-gin-logrus"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/auth"
 	"github.com/bpross/cc-hw/caption"
-	"github.com/bpross/cc-hw/dao/combined"
+	"github.com/bpross/cc-hw/dao/decorated"
 	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
 	"github.com/bpross/cc-hw/handler"
+	"github.com/bpross/cc-hw/idempotency"
+	"github.com/bpross/cc-hw/openapi"
+	"github.com/bpross/cc-hw/postpb"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/rpc"
+	"github.com/bpross/cc-hw/schedule"
+	"github.com/bpross/cc-hw/templates"
+	"github.com/bpross/cc-hw/validate"
+	"github.com/bpross/cc-hw/webhook"
 )
 
 const (
 	envApiKey       = "AYLIEN_API_KEY"
 	envAppID        = "AYLIEN_APP_ID"
 	envCaptionCount = "AYLIEN_CAPTION_COUNT"
+	envAdminKey     = "ADMIN_API_KEY"
+	envJWTSecret    = "JWT_SECRET"
+	envJWKSFile     = "JWT_JWKS_FILE"
+	envJWTIssuer    = "JWT_ISSUER"
+	envJWTAudience  = "JWT_AUDIENCE"
+	envJWTCustomer  = "JWT_CUSTOMER_CLAIM"
+
+	envRateLimit    = "RATE_LIMIT_PER_SECOND"
+	envRateBurst    = "RATE_LIMIT_BURST"
+	envMonthlyQuota = "GENERATION_MONTHLY_QUOTA"
+
+	envAllowedHosts     = "URL_ALLOWED_HOSTS"
+	envDeniedHosts      = "URL_DENIED_HOSTS"
+	envMaxCaptions      = "MAX_CAPTIONS"
+	envMaxCaptionLength = "MAX_CAPTION_LENGTH"
+
+	envDataFile  = "DATA_FILE"
+	envAuditFile = "AUDIT_FILE"
+	envGRPCPort  = "GRPC_PORT"
+
+	envValidateResponses = "OPENAPI_VALIDATE_RESPONSES"
+	envLegacySunset      = "LEGACY_ROUTES_SUNSET"
+
+	envPublishURL    = "PUBLISH_WEBHOOK_URL"
+	envPublishSecret = "PUBLISH_WEBHOOK_SECRET"
+
+	defaultCustomerClaim = "customer_id"
+	defaultRateLimit     = 5
+	defaultRateBurst     = 10
+	defaultMonthlyQuota  = 1000
+	defaultGRPCPort      = 9090
+	defaultLegacySunset  = "2027-04-01"
+
+	idempotencyTTL  = 24 * time.Hour
+	idempotencyWait = 10 * time.Second
+	eventWait       = 30 * time.Second
+	publishTimeout  = 10 * time.Second
 )
 
 func main() {
@@ -63,26 +118,214 @@ func main() {
 		[]byte{}, // where the trace ID might already be populated in the headers
 		ginlogrus.WithAggregateLogging(true)))
 
-	// Setup datastores
-	memDS := datastore.NewInMemoryDatastore(logger)
+	// Setup datastores, posts are kept in a file when one is configured
+	var persistentDS datastore.Datastore = datastore.NewInMemoryDatastore(logger)
+	var fileDS *datastore.FileDatastore
+	if path, present := os.LookupEnv(envDataFile); present && path != "" {
+		fileDS, err = datastore.OpenFileDatastore(logger, path)
+		if err != nil {
+			panic(err.Error())
+		}
+		persistentDS = fileDS
+	}
 	cacheDS := datastore.NewNoOpCache(logger)
 
-	// Setup DAO
-	combinedPoster := combined.NewPoster(logger, cacheDS, memDS)
+	// Setup DAO, every write is validated no matter who calls it
+	validator := validate.NewValidator(validate.Rules{
+		MaxURLLength:     validate.DefaultMaxURLLength,
+		MaxCaptions:      envInt(envMaxCaptions, validate.DefaultMaxCaptions),
+		MaxCaptionLength: envInt(envMaxCaptionLength, validate.DefaultMaxCaptionLength),
+		AllowedHosts:     envList(envAllowedHosts),
+		DeniedHosts:      envList(envDeniedHosts),
+	})
+	// Every successful write is appended to the event log
+	eventLog := events.NewInMemoryLog(logger)
+	// Every request of a customer and every change to a post is recorded in the
+	// audit log, which is kept in a file when one is configured
+	var auditStore audit.Store = audit.NewInMemoryStore()
+	if path, present := os.LookupEnv(envAuditFile); present && path != "" {
+		auditStore, err = audit.OpenFileStore(logger, path)
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+	combinedPoster := decorated.NewPoster(logger, cacheDS, persistentDS, validator, eventLog, auditStore)
+
+	// Setup webhooks, events are sent to customer urls so they go through the
+	// client that can not reach internal hosts
+	webhookStore := webhook.NewInMemoryStore(logger)
+	webhookClient := caption.NewSafeClient(logger, caption.DefaultFetchPolicy())
+	dispatcher := webhook.NewDispatcher(logger, webhookStore, webhookClient, webhook.DefaultRetryPolicy())
+	eventLog.Subscribe("webhooks", dispatcher.Handle)
+	dispatcher.Start()
+
+	// Setup scheduled publishing, posts are claimed in the datastore so every
+	// instance sharing it can run a scheduler
+	scheduler := schedule.NewScheduler(logger, combinedPoster, loadPublisher(logger), schedule.DefaultInterval)
+	scheduler.Start()
 
 	// Setup generator
-	auth := textapi.Auth{appID, apiKey}
-	client, err := textapi.NewClient(auth, true)
+	textAuth := textapi.Auth{
+		ApplicationID:  appID,
+		ApplicationKey: apiKey,
+	}
+	summarize, err := caption.NewAylienSummarizeFunc(textAuth)
 	if err != nil {
 		panic(err)
 	}
-	captionGenerator := caption.NewAylienGenerator(logger, client.Summarize)
+	captionGenerator := caption.NewAylienGenerator(logger, summarize)
+	// Generated captions are ranked by length fit, readability and keyword coverage
+	captionScorer := caption.DefaultScorer()
+	// Generated captions are checked against the customer's quality rules before
+	// they are ranked, customers without rules get the default ones
+	qualityStore := quality.NewInMemoryStore(logger)
+	qualityChecker := quality.NewChecker(qualityStore, quality.DefaultConfig())
+	// Requests can render the generated captions with one of the customer's templates
+	templateStore := templates.NewInMemoryStore(logger)
+
+	// Setup authentication, api keys are always accepted and JWTs are accepted
+	// when a secret or key set is configured
+	keyStore := auth.NewInMemoryKeyStore(logger)
+	authenticators := []auth.Authenticator{auth.NewAPIKeyAuthenticator(keyStore)}
+	if jwtAuthenticator := loadJWTAuthenticator(); jwtAuthenticator != nil {
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+	// Setup rate limiting and quotas, every route is limited per customer
+	limiter := ratelimit.NewTokenBucketLimiter(envFloat(envRateLimit, defaultRateLimit), envInt(envRateBurst, defaultRateBurst))
+	quota := ratelimit.NewMonthlyQuota(envInt(envMonthlyQuota, defaultMonthlyQuota))
+
+	// Setup handlers and routes, requests are checked against the OpenAPI
+	// document before they reach a handler
+	var compacter handler.Compacter
+	if fileDS != nil {
+		compacter = fileDS
+	}
+	baseHandler := handler.NewDefaultPoster(combinedPoster, validator)
+	api := &routes{
+		posts:             handler.NewCaptionGeneratorPoster(baseHandler, combinedPoster, captionGenerator, captionScorer, qualityChecker, templateStore, captionCount, quota, validator),
+		events:            handler.NewDefaultEventFeed(eventLog, eventWait),
+		keys:              handler.NewDefaultKeyer(keyStore),
+		webhooks:          handler.NewDefaultWebhooker(webhookStore),
+		usage:             handler.NewDefaultUsageReporter(quota),
+		audit:             handler.NewDefaultAuditor(auditStore),
+		quality:           handler.NewDefaultQualityRuler(qualityChecker, qualityStore),
+		templates:         handler.NewDefaultTemplater(templateStore),
+		auditLog:          auditStore,
+		authenticators:    authenticators,
+		limiter:           limiter,
+		idempotency:       idempotency.NewInMemoryStore(idempotencyTTL),
+		openAPI:           openapi.Default(),
+		validateResponses: os.Getenv(envValidateResponses) == "true",
+		adminKey:          os.Getenv(envAdminKey),
+		compacter:         compacter,
+		sunset:            envDate(envLegacySunset, defaultLegacySunset),
+	}
+	api.register(r)
+
+	// The gRPC api shares the posts, keys, rate limits and quota of the REST api
+	grpcAuthenticator := rpc.NewAuthenticator(logger, limiter, authenticators...)
+	grpcRecorder := rpc.NewAuditRecorder(logger, auditStore)
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(rpc.ChainUnary(grpcAuthenticator.Unary, grpcRecorder.Unary)),
+		grpc.StreamInterceptor(rpc.ChainStream(grpcAuthenticator.Stream, grpcRecorder.Stream)))
+	postpb.RegisterPostServiceServer(grpcServer, rpc.NewPostServer(logger, combinedPoster, captionGenerator, captionScorer, qualityChecker, captionCount, quota, validator, eventLog))
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", envInt(envGRPCPort, defaultGRPCPort)))
+	if err != nil {
+		panic(err.Error())
+	}
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			logger.WithError(err).Fatal("grpc server stopped")
+		}
+	}()
 
-	// Setup handler and routes
-	baseHandler := handler.NewDefaultPoster(combinedPoster)
-	generateHandler := handler.NewCaptionGeneratorPoster(baseHandler, combinedPoster, captionGenerator, captionCount)
-	r.GET("/post/:id", generateHandler.Get)
-	r.POST("/post", generateHandler.Post)
-	r.PUT("/post/:id", generateHandler.Put)
 	r.Run() // listen and serve on 0.0.0.0:8080 (for windows "localhost:8080")
 }
+
+// loadJWTAuthenticator builds the bearer token authenticator from env. It returns
+// nil when neither a shared secret nor a JWKS file is configured
+func loadJWTAuthenticator() auth.Authenticator {
+	var keys *auth.KeySet
+	if path, present := os.LookupEnv(envJWKSFile); present && path != "" {
+		var err error
+		keys, err = auth.LoadJWKS(path)
+		if err != nil {
+			panic(err.Error())
+		}
+	} else if secret, present := os.LookupEnv(envJWTSecret); present && secret != "" {
+		keys = auth.NewSecretKeySet([]byte(secret))
+	} else {
+		return nil
+	}
+
+	customerClaim := os.Getenv(envJWTCustomer)
+	if customerClaim == "" {
+		customerClaim = defaultCustomerClaim
+	}
+	verifier := auth.NewJWTVerifier(keys, os.Getenv(envJWTIssuer), os.Getenv(envJWTAudience))
+	return auth.NewJWTAuthenticator(verifier, customerClaim)
+}
+
+// loadPublisher posts scheduled posts to PUBLISH_WEBHOOK_URL when it is set, and
+// only logs them otherwise
+func loadPublisher(logger *logrus.Logger) schedule.Publisher {
+	url, present := os.LookupEnv(envPublishURL)
+	if !present || url == "" {
+		return schedule.NewLogPublisher(logger)
+	}
+	secret := os.Getenv(envPublishSecret)
+	if secret == "" {
+		panic("PUBLISH_WEBHOOK_SECRET must be set in env when PUBLISH_WEBHOOK_URL is set")
+	}
+	return schedule.NewWebhookPublisher(logger, url, secret, &http.Client{Timeout: publishTimeout})
+}
+
+// envInt returns the env variable as an int, or the default when it is not set
+func envInt(name string, def int) int {
+	v, present := os.LookupEnv(name)
+	if !present || v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		panic(err.Error())
+	}
+	return i
+}
+
+// envFloat returns the env variable as a float, or the default when it is not set
+func envFloat(name string, def float64) float64 {
+	v, present := os.LookupEnv(name)
+	if !present || v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		panic(err.Error())
+	}
+	return f
+}
+
+// envDate returns the env variable as a date, or the default when it is not set
+func envDate(name, def string) time.Time {
+	v, present := os.LookupEnv(name)
+	if !present || v == "" {
+		v = def
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		panic(err.Error())
+	}
+	return t
+}
+
+// envList returns the comma separated env variable as a list
+func envList(name string) []string {
+	list := []string{}
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
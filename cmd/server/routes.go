@@ -0,0 +1,145 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/handler"
+	"github.com/bpross/cc-hw/idempotency"
+	"github.com/bpross/cc-hw/openapi"
+	"github.com/bpross/cc-hw/ratelimit"
+)
+
+// routes are the handlers and middleware the api is served with
+type routes struct {
+	posts     *handler.CaptionGeneratorPoster
+	events    *handler.DefaultEventFeed
+	keys      *handler.DefaultKeyer
+	webhooks  *handler.DefaultWebhooker
+	usage     *handler.DefaultUsageReporter
+	audit     *handler.DefaultAuditor
+	quality   *handler.DefaultQualityRuler
+	templates *handler.DefaultTemplater
+
+	authenticators []auth.Authenticator
+	limiter        ratelimit.Limiter
+	idempotency    idempotency.Store
+	// auditLog records every request of a customer
+	auditLog audit.Appender
+
+	// openAPI is the document requests are validated against, responses are
+	// only validated when validateResponses is set
+	openAPI           *openapi.Document
+	validateResponses bool
+
+	// Admin routes are only registered when adminKey is set, compacter is nil
+	// when the datastore can not be compacted
+	adminKey  string
+	compacter handler.Compacter
+
+	// sunset is when the unversioned aliases of the /v1 routes are removed
+	sunset time.Time
+}
+
+// register adds every route of the api under /v1 and /v2, and again at its old
+// unversioned path as a deprecated alias of /v1. The versions only differ in
+// how captions are represented, /v1 responds with their texts
+func (rt *routes) register(r *gin.Engine) {
+	rt.registerVersion(r.Group("/v1", handler.NewVersion(handler.V1Post, handler.V1Revision)))
+	rt.registerVersion(r.Group("/v2", handler.NewVersion(handler.V2Post, handler.V2Revision)))
+	rt.registerVersion(r.Group("/", handler.NewDeprecation(rt.sunset, "/v1"), handler.NewVersion(handler.V1Post, handler.V1Revision)))
+}
+
+// registerVersion adds every route of a version of the api to r
+func (rt *routes) registerVersion(r *gin.RouterGroup) {
+	validator := handler.NewOpenAPIValidator(rt.openAPI, rt.validateResponses)
+	r.GET("/openapi.json", handler.NewOpenAPIHandler(rt.openAPI))
+
+	authenticated := r.Group("/", handler.NewAuthenticator(rt.authenticators...), handler.NewRateLimiter(rt.limiter), handler.NewAuditRecorder(rt.auditLog), validator)
+	read := handler.RequireScope(auth.ScopePostsRead)
+	write := handler.RequireScope(auth.ScopePostsWrite)
+	approve := handler.RequireScope(auth.ScopePostsApprove)
+	manageKeys := handler.RequireScope(auth.ScopeKeysManage)
+	manageWebhooks := handler.RequireScope(auth.ScopeWebhooks)
+	readAudit := handler.RequireScope(auth.ScopeAuditRead)
+	manageQuality := handler.RequireScope(auth.ScopeQuality)
+	manageTemplates := handler.RequireScope(auth.ScopeTemplates)
+
+	authenticated.GET("/posts", read, rt.posts.List)
+	authenticated.GET("/post/:id", read, rt.posts.Get)
+	authenticated.POST("/post", write, handler.NewIdempotency(rt.idempotency, idempotencyWait), rt.posts.Post)
+	authenticated.PUT("/post/:id", write, rt.posts.Put)
+	authenticated.PATCH("/post/:id", write, rt.posts.Patch)
+	authenticated.POST("/post/:id/captions/:caption_id/select", write, rt.posts.SelectCaption)
+	authenticated.POST("/post/:id/captions/:caption_id/pin", write, rt.posts.PinCaption)
+	authenticated.POST("/post/:id/captions/:caption_id/unpin", write, rt.posts.UnpinCaption)
+	authenticated.DELETE("/post/:id/captions/:caption_id", write, rt.posts.DiscardCaption)
+	authenticated.PUT("/post/:id/captions/order", write, rt.posts.ReorderCaptions)
+	authenticated.POST("/post/:id/approve", approve, rt.posts.Approve)
+	authenticated.GET("/post/:id/revisions", read, rt.posts.Revisions)
+	authenticated.POST("/post/:id/revisions/:rev/restore", write, rt.posts.Restore)
+	authenticated.DELETE("/post/:id", write, rt.posts.Delete)
+	authenticated.PUT("/post/:id/schedule", approve, rt.posts.Schedule)
+	authenticated.DELETE("/post/:id/schedule", approve, rt.posts.Unschedule)
+	authenticated.POST("/posts:action", write, handler.NewIdempotency(rt.idempotency, idempotencyWait), handler.Actions(map[string]gin.HandlerFunc{
+		"batch": rt.posts.BatchCreate,
+	}))
+	authenticated.PUT("/posts:action", write, handler.Actions(map[string]gin.HandlerFunc{
+		"batch": rt.posts.BatchUpdate,
+	}))
+	authenticated.GET("/export", read, rt.posts.Export)
+	authenticated.POST("/import", write, rt.posts.Import)
+
+	authenticated.GET("/events", read, rt.events.Poll)
+
+	authenticated.GET("/keys", manageKeys, rt.keys.List)
+	authenticated.POST("/keys", manageKeys, rt.keys.Create)
+	authenticated.POST("/keys/:id/rotate", manageKeys, rt.keys.Rotate)
+	authenticated.DELETE("/keys/:id", manageKeys, rt.keys.Revoke)
+
+	authenticated.GET("/webhooks", manageWebhooks, rt.webhooks.List)
+	authenticated.POST("/webhooks", manageWebhooks, rt.webhooks.Create)
+	authenticated.GET("/webhooks/:id", manageWebhooks, rt.webhooks.Get)
+	authenticated.PUT("/webhooks/:id", manageWebhooks, rt.webhooks.Update)
+	authenticated.DELETE("/webhooks/:id", manageWebhooks, rt.webhooks.Delete)
+	authenticated.GET("/webhooks/:id/deliveries", manageWebhooks, rt.webhooks.Deliveries)
+	authenticated.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", manageWebhooks, rt.webhooks.Redeliver)
+
+	authenticated.GET("/usage", read, rt.usage.Get)
+
+	authenticated.GET("/audit", readAudit, rt.audit.List)
+
+	authenticated.GET("/quality/rules", manageQuality, rt.quality.Get)
+	authenticated.PUT("/quality/rules", manageQuality, rt.quality.Put)
+	authenticated.DELETE("/quality/rules", manageQuality, rt.quality.Delete)
+
+	authenticated.GET("/templates", manageTemplates, rt.templates.List)
+	authenticated.POST("/templates", manageTemplates, rt.templates.Create)
+	authenticated.POST("/templates/preview", manageTemplates, rt.posts.PreviewTemplate)
+	authenticated.GET("/templates/:id", manageTemplates, rt.templates.Get)
+	authenticated.PUT("/templates/:id", manageTemplates, rt.templates.Update)
+	authenticated.DELETE("/templates/:id", manageTemplates, rt.templates.Delete)
+
+	if rt.adminKey == "" {
+		return
+	}
+	admin := r.Group("/admin", handler.NewAdminAuthenticator(rt.adminKey), validator)
+	admin.POST("/customers/:customer_id/keys", rt.keys.CreateForCustomer)
+	admin.GET("/usage", rt.usage.List)
+	admin.POST("/captions/warm", rt.posts.Warm)
+
+	// The customer routes act as the customer in the path
+	customer := admin.Group("/customers/:customer_id", handler.NewAdminCustomer(), handler.NewAuditRecorder(rt.auditLog))
+	customer.POST("/keys/:id/rotate", rt.keys.Rotate)
+	customer.GET("/posts", rt.posts.List)
+	customer.GET("/posts/:id", rt.posts.Get)
+	customer.DELETE("/posts/:id", rt.posts.Delete)
+	customer.GET("/audit", rt.audit.List)
+
+	if rt.compacter != nil {
+		admin.POST("/datastore/compact", handler.NewCompactHandler(rt.compacter))
+	}
+}
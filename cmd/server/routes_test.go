@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/caption"
+	"github.com/bpross/cc-hw/dao/decorated"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+	"github.com/bpross/cc-hw/handler"
+	"github.com/bpross/cc-hw/idempotency"
+	"github.com/bpross/cc-hw/openapi"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/templates"
+	"github.com/bpross/cc-hw/validate"
+	"github.com/bpross/cc-hw/webhook"
+)
+
+const adminKey = "test-admin-key"
+
+// fakeGenerator returns numbered captions without calling out
+type fakeGenerator struct{}
+
+func (fakeGenerator) Create(url string, n int) ([]string, error) {
+	captions := make([]string, n)
+	for i := range captions {
+		captions[i] = fmt.Sprintf("caption %d", i+1)
+	}
+	return captions, nil
+}
+
+type fakeCompacter struct{}
+
+func (fakeCompacter) Compact() error {
+	return nil
+}
+
+// routeParam matches the gin path parameter segments
+var routeParam = regexp.MustCompile(`/:([a-z_]+)`)
+
+var _ = Describe("Routes", func() {
+	var (
+		router *gin.Engine
+		errs   []string
+	)
+
+	BeforeEach(func() {
+		logger := log.New()
+		logger.Out = ioutil.Discard
+		validator := validate.NewValidator(validate.DefaultRules())
+		eventLog := events.NewInMemoryLog(logger)
+		auditStore := audit.NewInMemoryStore()
+		poster := decorated.NewPoster(logger, datastore.NewNoOpCache(logger), datastore.NewInMemoryDatastore(logger), validator, eventLog, auditStore)
+		quota := ratelimit.NewMonthlyQuota(100)
+		keyStore := auth.NewInMemoryKeyStore(logger)
+		qualityStore := quality.NewInMemoryStore(logger)
+		qualityChecker := quality.NewChecker(qualityStore, quality.DefaultConfig())
+		templateStore := templates.NewInMemoryStore(logger)
+
+		api := &routes{
+			posts:             handler.NewCaptionGeneratorPoster(handler.NewDefaultPoster(poster, validator), poster, fakeGenerator{}, caption.DefaultScorer(), qualityChecker, templateStore, 2, quota, validator),
+			events:            handler.NewDefaultEventFeed(eventLog, time.Second),
+			keys:              handler.NewDefaultKeyer(keyStore),
+			webhooks:          handler.NewDefaultWebhooker(webhook.NewInMemoryStore(logger)),
+			usage:             handler.NewDefaultUsageReporter(quota),
+			audit:             handler.NewDefaultAuditor(auditStore),
+			quality:           handler.NewDefaultQualityRuler(qualityChecker, qualityStore),
+			templates:         handler.NewDefaultTemplater(templateStore),
+			auditLog:          auditStore,
+			authenticators:    []auth.Authenticator{auth.NewAPIKeyAuthenticator(keyStore)},
+			limiter:           ratelimit.NewTokenBucketLimiter(1000, 1000),
+			idempotency:       idempotency.NewInMemoryStore(time.Hour),
+			openAPI:           openapi.Default(),
+			validateResponses: true,
+			adminKey:          adminKey,
+			compacter:         fakeCompacter{},
+			sunset:            time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC),
+		}
+
+		gin.SetMode(gin.TestMode)
+		router = gin.New()
+		errs = nil
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			for _, err := range c.Errors {
+				errs = append(errs, err.Error())
+			}
+		})
+		api.register(router)
+	})
+
+	It("should document every route and route every documented operation", func() {
+		versioned := []string{}
+		v2 := []string{}
+		aliases := []string{}
+		for _, route := range router.Routes() {
+			// /posts:action serves the actions of the collection
+			path := strings.Replace(route.Path, "/posts:action", "/posts:batch", 1)
+			path = routeParam.ReplaceAllString(path, "/{$1}")
+			switch {
+			case strings.HasPrefix(path, "/v1/"):
+				versioned = append(versioned, route.Method+" "+strings.TrimPrefix(path, "/v1"))
+			case strings.HasPrefix(path, "/v2/"):
+				v2 = append(v2, route.Method+" "+strings.TrimPrefix(path, "/v2"))
+			default:
+				aliases = append(aliases, route.Method+" "+path)
+			}
+		}
+		sort.Strings(versioned)
+		sort.Strings(v2)
+		sort.Strings(aliases)
+		Expect(versioned).To(Equal(openapi.Default().Operations()))
+		Expect(v2).To(Equal(versioned))
+		Expect(aliases).To(Equal(versioned))
+	})
+
+	Describe("responses", func() {
+		var key string
+
+		send := func(method, path, apiKey, contentType, body string) *httptest.ResponseRecorder {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+			req.Header.Set(auth.APIKeyHeader, apiKey)
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
+			router.ServeHTTP(recorder, req)
+			return recorder
+		}
+
+		// call sends a json request as the customer, checks the status and that
+		// the response matched the document, and decodes the response into out
+		call := func(method, path, body string, status int, out interface{}) {
+			recorder := send(method, path, key, "application/json", body)
+			ExpectWithOffset(1, recorder.Code).To(Equal(status), "%s %s: %s", method, path, recorder.Body.String())
+			ExpectWithOffset(1, errs).To(BeEmpty())
+			if out != nil {
+				ExpectWithOffset(1, json.Unmarshal(recorder.Body.Bytes(), out)).To(Succeed())
+			}
+		}
+
+		admin := func(method, path, body string, status int, out interface{}) {
+			recorder := send(method, path, adminKey, "application/json", body)
+			ExpectWithOffset(1, recorder.Code).To(Equal(status), "%s %s: %s", method, path, recorder.Body.String())
+			ExpectWithOffset(1, errs).To(BeEmpty())
+			if out != nil {
+				ExpectWithOffset(1, json.Unmarshal(recorder.Body.Bytes(), out)).To(Succeed())
+			}
+		}
+
+		BeforeEach(func() {
+			created := struct {
+				Secret string `json:"secret"`
+			}{}
+			admin(http.MethodPost, "/v1/admin/customers/customer-1/keys", "", http.StatusCreated, &created)
+			key = created.Secret
+		})
+
+		It("should match the document for the post routes", func() {
+			post := struct {
+				ID string `json:"id"`
+			}{}
+			call(http.MethodPost, "/v1/post", `{"url": "https://example.com/a"}`, http.StatusOK, &post)
+			call(http.MethodGet, "/v1/post/"+post.ID, "", http.StatusOK, nil)
+			call(http.MethodGet, "/v1/posts?limit=1", "", http.StatusOK, nil)
+			call(http.MethodGet, "/v1/posts?sort=-updated_at&created_after=2026-01-01T00:00:00Z&created_by=customer-1", "", http.StatusOK, nil)
+			call(http.MethodPut, "/v1/post/"+post.ID, `{"captions": ["updated"]}`, http.StatusOK, nil)
+			recorder := send(http.MethodPatch, "/v1/post/"+post.ID, key, "application/json-patch+json", `[{"op": "add", "path": "/captions/-", "value": "patched"}]`)
+			Expect(recorder.Code).To(Equal(http.StatusOK), recorder.Body.String())
+			recorder = send(http.MethodPatch, "/v1/post/"+post.ID, key, "application/merge-patch+json", `{"captions": ["merged"]}`)
+			Expect(recorder.Code).To(Equal(http.StatusOK), recorder.Body.String())
+			Expect(errs).To(BeEmpty())
+			withCaptions := struct {
+				Captions []struct {
+					ID string `json:"id"`
+				} `json:"captions"`
+			}{}
+			// only /v2 responds with the ids of the captions
+			call(http.MethodPut, "/v2/post/"+post.ID, `{"captions": ["first", "second"]}`, http.StatusOK, &withCaptions)
+			first, second := withCaptions.Captions[0].ID, withCaptions.Captions[1].ID
+			call(http.MethodPost, "/v2/post/"+post.ID+"/captions/"+second+"/select", "", http.StatusOK, nil)
+			call(http.MethodPost, "/v2/post/"+post.ID+"/captions/"+second+"/pin", "", http.StatusOK, nil)
+			call(http.MethodPost, "/v1/post/"+post.ID+"/captions/"+second+"/unpin", "", http.StatusOK, nil)
+			call(http.MethodPut, "/v2/post/"+post.ID+"/captions/order", `{"ids": ["`+first+`", "`+second+`"]}`, http.StatusOK, nil)
+			call(http.MethodDelete, "/v2/post/"+post.ID+"/captions/"+first, "", http.StatusOK, nil)
+			call(http.MethodDelete, "/v2/post/"+post.ID+"/captions/"+first, "", http.StatusNotFound, nil)
+			call(http.MethodGet, "/v1/post/"+post.ID+"/revisions", "", http.StatusOK, nil)
+			call(http.MethodGet, "/v2/post/"+post.ID+"/revisions", "", http.StatusOK, nil)
+			call(http.MethodPost, "/v1/post/"+post.ID+"/revisions/1/restore", "", http.StatusOK, nil)
+			call(http.MethodPost, "/v1/post/"+post.ID+"/approve", "", http.StatusOK, nil)
+			scheduledAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+			call(http.MethodPut, "/v1/post/"+post.ID+"/schedule", `{"scheduled_at": "`+scheduledAt+`", "channels": ["twitter"]}`, http.StatusOK, nil)
+			call(http.MethodDelete, "/v1/post/"+post.ID+"/schedule", "", http.StatusOK, nil)
+			call(http.MethodPost, "/v1/posts:batch", `{"items": [{"url": "https://example.com/b"}, {"url": "ftp://example.com"}]}`, http.StatusOK, nil)
+			call(http.MethodPut, "/v1/posts:batch", `{"items": [{"id": "`+post.ID+`", "captions": ["batch"]}]}`, http.StatusOK, nil)
+			call(http.MethodGet, "/v1/events?wait=0", "", http.StatusOK, nil)
+			call(http.MethodDelete, "/v1/post/"+post.ID, "", http.StatusNoContent, nil)
+			call(http.MethodGet, "/v1/post/"+post.ID, "", http.StatusNotFound, nil)
+		})
+
+		It("should match the document for export and import", func() {
+			call(http.MethodPost, "/v1/post", `{"url": "https://example.com/a"}`, http.StatusOK, nil)
+			recorder := send(http.MethodGet, "/v1/export?format=jsonl", key, "", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(errs).To(BeEmpty())
+
+			recorder = send(http.MethodPost, "/v1/import?format=jsonl", key, "application/x-ndjson", recorder.Body.String()+"{\n")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("should match the document for the key routes", func() {
+			created := struct {
+				ID string `json:"id"`
+			}{}
+			call(http.MethodPost, "/v1/keys", "", http.StatusCreated, &created)
+			call(http.MethodGet, "/v1/keys", "", http.StatusOK, nil)
+			call(http.MethodPost, "/v1/keys/"+created.ID+"/rotate", "", http.StatusOK, &created)
+			call(http.MethodDelete, "/v1/keys/"+created.ID, "", http.StatusNoContent, nil)
+			call(http.MethodGet, "/v1/usage", "", http.StatusOK, nil)
+			call(http.MethodGet, "/v1/audit?action=request&limit=10", "", http.StatusOK, nil)
+		})
+
+		It("should match the document for the webhook routes", func() {
+			sub := struct {
+				ID string `json:"id"`
+			}{}
+			call(http.MethodPost, "/v1/webhooks", `{"url": "https://example.com/hook", "event_types": ["created"]}`, http.StatusCreated, &sub)
+			call(http.MethodGet, "/v1/webhooks", "", http.StatusOK, nil)
+			call(http.MethodGet, "/v1/webhooks/"+sub.ID, "", http.StatusOK, nil)
+			call(http.MethodPut, "/v1/webhooks/"+sub.ID, `{"url": "https://example.com/hook2", "event_types": ["deleted"]}`, http.StatusOK, nil)
+			call(http.MethodGet, "/v1/webhooks/"+sub.ID+"/deliveries?status=dead", "", http.StatusOK, nil)
+			call(http.MethodPost, "/v1/webhooks/"+sub.ID+"/deliveries/5e154899cb80cb0001000003/redeliver", "", http.StatusNotFound, nil)
+			call(http.MethodDelete, "/v1/webhooks/"+sub.ID, "", http.StatusNoContent, nil)
+		})
+
+		It("should match the document for the quality rules routes", func() {
+			call(http.MethodGet, "/v1/quality/rules", "", http.StatusOK, nil)
+			call(http.MethodPut, "/v1/quality/rules", `{"rules": [{"type": "blocklist", "action": "reject", "terms": ["caption 1"]}, {"type": "readability", "action": "flag", "min_score": 0.9}]}`, http.StatusOK, nil)
+			post := struct {
+				Captions         []interface{} `json:"captions"`
+				RejectedCaptions []interface{} `json:"rejected_captions"`
+			}{}
+			call(http.MethodPost, "/v1/post", `{"url": "https://example.com/a"}`, http.StatusOK, &post)
+			Expect(post.Captions).To(HaveLen(1))
+			Expect(post.RejectedCaptions).To(HaveLen(1))
+			call(http.MethodPut, "/v1/quality/rules", `{"rules": [{"type": "fragment", "action": "warn"}]}`, http.StatusBadRequest, nil)
+			call(http.MethodDelete, "/v1/quality/rules", "", http.StatusNoContent, nil)
+		})
+
+		It("should match the document for the template routes", func() {
+			tmpl := struct {
+				ID string `json:"id"`
+			}{}
+			call(http.MethodPost, "/v1/templates", `{"name": "house", "text": "📣 {{summary}} Read more: {{short_url}} {{hashtags}}"}`, http.StatusCreated, &tmpl)
+			call(http.MethodGet, "/v1/templates", "", http.StatusOK, nil)
+			call(http.MethodGet, "/v1/templates/"+tmpl.ID, "", http.StatusOK, nil)
+			call(http.MethodPut, "/v1/templates/"+tmpl.ID, `{"name": "house", "text": "{{summary}} {{short_url}}"}`, http.StatusOK, nil)
+			call(http.MethodPost, "/v1/templates/preview", `{"url": "https://example.com/agency-domains", "text": "{{summary}} {{hashtags}}", "captions": ["A caption."]}`, http.StatusOK, nil)
+			call(http.MethodPost, "/v1/templates/preview", `{"url": "https://example.com/a", "template": "`+tmpl.ID+`"}`, http.StatusOK, nil)
+			post := struct {
+				Captions []string `json:"captions"`
+			}{}
+			call(http.MethodPost, "/v1/post", `{"url": "https://example.com/a", "template": "`+tmpl.ID+`"}`, http.StatusOK, &post)
+			Expect(post.Captions[0]).To(HaveSuffix(" example.com/a"))
+			call(http.MethodDelete, "/v1/templates/"+tmpl.ID, "", http.StatusNoContent, nil)
+			call(http.MethodPost, "/v1/post", `{"url": "https://example.com/b", "template": "`+tmpl.ID+`"}`, http.StatusBadRequest, nil)
+		})
+
+		It("should match the document for the admin routes", func() {
+			post := struct {
+				ID string `json:"id"`
+			}{}
+			call(http.MethodPost, "/v1/post", `{"url": "https://example.com/a"}`, http.StatusOK, &post)
+			admin(http.MethodGet, "/v1/admin/usage", "", http.StatusOK, nil)
+			admin(http.MethodPost, "/v1/admin/captions/warm", `{"urls": ["https://example.com/a"]}`, http.StatusOK, nil)
+			admin(http.MethodGet, "/v1/admin/customers/customer-1/posts", "", http.StatusOK, nil)
+			admin(http.MethodGet, "/v1/admin/customers/customer-1/posts/"+post.ID, "", http.StatusOK, nil)
+			admin(http.MethodDelete, "/v1/admin/customers/customer-1/posts/"+post.ID, "", http.StatusNoContent, nil)
+			admin(http.MethodGet, "/v1/admin/customers/customer-1/audit?post_id="+post.ID, "", http.StatusOK, nil)
+			admin(http.MethodPost, "/v1/admin/datastore/compact", "", http.StatusNoContent, nil)
+		})
+
+		It("should serve the deprecated aliases with the same responses", func() {
+			post := struct {
+				ID string `json:"id"`
+			}{}
+			call(http.MethodPost, "/v1/post", `{"url": "https://example.com/a"}`, http.StatusOK, &post)
+			versioned := send(http.MethodGet, "/v1/post/"+post.ID, key, "", "")
+			Expect(versioned.Header().Get("Deprecation")).To(BeEmpty())
+
+			alias := send(http.MethodGet, "/post/"+post.ID, key, "", "")
+			Expect(alias.Code).To(Equal(http.StatusOK))
+			Expect(alias.Body.String()).To(MatchJSON(versioned.Body.String()))
+			Expect(alias.Header().Get("Deprecation")).To(Equal("true"))
+			Expect(alias.Header().Get("Sunset")).To(Equal("Thu, 01 Apr 2027 00:00:00 GMT"))
+			Expect(alias.Header().Get("Link")).To(Equal("</v1/post/" + post.ID + `>; rel="successor-version"`))
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("should serve the document without authentication", func() {
+			recorder := send(http.MethodGet, "/v1/openapi.json", "", "", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(MatchJSON(openapi.Spec))
+		})
+
+		It("should reject requests that do not match the document", func() {
+			recorder := send(http.MethodPost, "/v1/posts:batch", key, "application/json", `{"items": [{"captions": ["a"]}]}`)
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			Expect(recorder.Body.String()).To(ContainSubstring(`"field":"items[0].url"`))
+			Expect(errs).To(BeEmpty())
+
+			recorder = send(http.MethodGet, "/v1/posts?limit=1000", key, "", "")
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			Expect(errs).To(BeEmpty())
+		})
+	})
+})
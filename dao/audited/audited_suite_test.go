@@ -0,0 +1,13 @@
+package audited
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAudited(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dao Audited Suite")
+}
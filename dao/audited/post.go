@@ -0,0 +1,233 @@
+package audited
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/dao"
+)
+
+// Poster implements the Poster interface by recording every change the
+// underlying Poster makes in the audit log, with the post before and after it.
+// A change that can not be recorded returns the error of the audit log, even
+// though the change was made
+type Poster struct {
+	logger *log.Logger
+	next   dao.Poster
+	log    audit.Appender
+}
+
+// NewPoster creates a new Poster with the supplied options
+func NewPoster(logger *log.Logger, next dao.Poster, log audit.Appender) *Poster {
+	return &Poster{
+		logger: logger,
+		next:   next,
+		log:    log,
+	}
+}
+
+// Insert records the created post
+func (d *Poster) Insert(customerID string, post *dao.Post) (*dao.Post, error) {
+	inserted, err := d.next.Insert(customerID, post)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.record(customerID, audit.ActionCreated, inserted.UpdatedBy, *inserted.ID, nil, inserted); err != nil {
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// InsertBatch records every created post, a post that can not be recorded has
+// the error of the audit log in its result
+func (d *Poster) InsertBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	results, err := d.next.InsertBatch(customerID, posts)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Err == nil {
+			result.Err = d.record(customerID, audit.ActionCreated, result.Post.UpdatedBy, *result.Post.ID, nil, result.Post)
+		}
+	}
+	return results, nil
+}
+
+// Get calls the underlying Poster, reads are recorded with their request
+func (d *Poster) Get(customerID string, postID bson.ObjectId) (*dao.Post, error) {
+	return d.next.Get(customerID, postID)
+}
+
+// GetByURL calls the underlying Poster, reads are recorded with their request
+func (d *Poster) GetByURL(customerID string, canonicalURL string) (*dao.Post, error) {
+	return d.next.GetByURL(customerID, canonicalURL)
+}
+
+// List calls the underlying Poster, reads are recorded with their request
+func (d *Poster) List(customerID string, opts dao.ListOptions) ([]*dao.Post, error) {
+	return d.next.List(customerID, opts)
+}
+
+// Revisions calls the underlying Poster, reads are recorded with their request
+func (d *Poster) Revisions(customerID string, postID bson.ObjectId) ([]*dao.Revision, error) {
+	return d.next.Revisions(customerID, postID)
+}
+
+// Update records the post before and after the update
+func (d *Poster) Update(customerID string, post *dao.Post) (*dao.Post, error) {
+	if post == nil || post.ID == nil {
+		return d.next.Update(customerID, post)
+	}
+
+	before := d.snapshot(customerID, *post.ID)
+	updated, err := d.next.Update(customerID, post)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.record(customerID, audit.ActionUpdated, updated.UpdatedBy, *updated.ID, before, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// UpdateBatch records every updated post like Update
+func (d *Poster) UpdateBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	before := make([]json.RawMessage, len(posts))
+	for i, post := range posts {
+		if post != nil && post.ID != nil {
+			before[i] = d.snapshot(customerID, *post.ID)
+		}
+	}
+
+	results, err := d.next.UpdateBatch(customerID, posts)
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		if result.Err == nil {
+			result.Err = d.record(customerID, audit.ActionUpdated, result.Post.UpdatedBy, *result.Post.ID, before[i], result.Post)
+		}
+	}
+	return results, nil
+}
+
+// Restore records the post before and after the restore
+func (d *Poster) Restore(customerID string, postID bson.ObjectId, number int, author string) (*dao.Post, error) {
+	before := d.snapshot(customerID, postID)
+	restored, err := d.next.Restore(customerID, postID, number, author)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.record(customerID, audit.ActionRestored, author, postID, before, restored); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// Patch records the post before and after the patch
+func (d *Poster) Patch(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+	before := d.snapshot(customerID, postID)
+	patched, err := d.next.Patch(customerID, postID, patch, author)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.record(customerID, audit.ActionPatched, author, postID, before, patched); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+// Delete records the post as it was before it was deleted
+func (d *Poster) Delete(customerID string, postID bson.ObjectId, author string) error {
+	before := d.snapshot(customerID, postID)
+	if err := d.next.Delete(customerID, postID, author); err != nil {
+		return err
+	}
+	return d.record(customerID, audit.ActionDeleted, author, postID, before, nil)
+}
+
+// Schedule records the post before and after it was scheduled
+func (d *Poster) Schedule(customerID string, post *dao.Post) (*dao.Post, error) {
+	if post == nil || post.ID == nil {
+		return d.next.Schedule(customerID, post)
+	}
+
+	before := d.snapshot(customerID, *post.ID)
+	scheduled, err := d.next.Schedule(customerID, post)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.record(customerID, audit.ActionScheduled, scheduled.UpdatedBy, *scheduled.ID, before, scheduled); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// ClaimDue records every claimed post. The post was approved, or its earlier
+// claim ran out, before it was claimed, which is not read again. The claimed posts are returned with the
+// first error recording them, they are publishing and must still be published
+func (d *Poster) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*dao.Post, error) {
+	posts, err := d.next.ClaimDue(now, lease, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		if recordErr := d.record(post.CustID, audit.ActionPublishClaimed, dao.ActorScheduler, *post.ID, nil, post); err == nil {
+			err = recordErr
+		}
+	}
+	return posts, err
+}
+
+// CompletePublish records the post before and after it was published
+func (d *Poster) CompletePublish(customerID string, postID bson.ObjectId, publications []*dao.Publication) (*dao.Post, error) {
+	before := d.snapshot(customerID, postID)
+	post, err := d.next.CompletePublish(customerID, postID, publications)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.record(customerID, audit.ActionPublishComplete, dao.ActorScheduler, postID, before, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// snapshot reads the json of the post as it is before a change, it is nil if
+// it can not be read
+func (d *Poster) snapshot(customerID string, postID bson.ObjectId) json.RawMessage {
+	post, err := d.next.Get(customerID, postID)
+	if err != nil || post == nil {
+		return nil
+	}
+	before, err := audit.Snapshot(post)
+	if err != nil {
+		return nil
+	}
+	return before
+}
+
+func (d *Poster) record(customerID, action, actor string, postID bson.ObjectId, before json.RawMessage, post *dao.Post) error {
+	after, err := audit.Snapshot(post)
+	if err == nil {
+		_, err = d.log.Append(&audit.Entry{
+			CustomerID: customerID,
+			Actor:      actor,
+			Action:     action,
+			PostID:     postID.Hex(),
+			Before:     before,
+			After:      after,
+		})
+	}
+	if err != nil {
+		d.logger.WithFields(log.Fields{
+			"post_id": postID.Hex(),
+			"action":  action,
+			"error":   err.Error(),
+		}).Error("failed to append audit entry")
+	}
+	return err
+}
@@ -0,0 +1,231 @@
+package audited
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/dao"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+)
+
+// failingLog fails every append
+type failingLog struct {
+	err error
+}
+
+func (l failingLog) Append(*audit.Entry) (*audit.Entry, error) {
+	return nil, l.err
+}
+
+var _ = Describe("Poster", func() {
+	var (
+		logger   *log.Logger
+		p        *Poster
+		mockNext *mock_dao.MockPoster
+		mockCtrl *gomock.Controller
+		store    *audit.InMemoryStore
+
+		customerID string
+		post       *dao.Post
+		postID     bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		mockNext = mock_dao.NewMockPoster(mockCtrl)
+		store = audit.NewInMemoryStore()
+		p = NewPoster(logger, mockNext, store)
+
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+		post = &dao.Post{
+			ID:        &postID,
+			URL:       "https://example.com/post",
+			Captions:  dao.ManualCaptions([]string{"caption1"}),
+			Status:    dao.StatusDraft,
+			UpdatedBy: "alice",
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	decoded := func(snapshot json.RawMessage) *dao.Post {
+		post, err := audit.DecodePost(snapshot)
+		ExpectWithOffset(1, err).To(BeNil())
+		return post
+	}
+
+	recorded := func() []*audit.Entry {
+		entries, err := store.Query(customerID, audit.Query{})
+		ExpectWithOffset(1, err).To(BeNil())
+		return entries
+	}
+
+	Describe("Insert", func() {
+		It("should record the created post", func() {
+			mockNext.EXPECT().Insert(customerID, post).Return(post, nil)
+			_, err := p.Insert(customerID, post)
+			Expect(err).To(BeNil())
+
+			entries := recorded()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Action).To(Equal(audit.ActionCreated))
+			Expect(entries[0].Actor).To(Equal("alice"))
+			Expect(entries[0].PostID).To(Equal(postID.Hex()))
+			Expect(entries[0].Before).To(BeNil())
+			Expect(decoded(entries[0].After)).To(Equal(post))
+		})
+
+		It("should NOT record a failed insert", func() {
+			mockNext.EXPECT().Insert(customerID, post).Return(nil, errors.New("test-error"))
+			_, err := p.Insert(customerID, post)
+			Expect(err).NotTo(BeNil())
+			Expect(recorded()).To(BeEmpty())
+		})
+	})
+
+	Describe("Update", func() {
+		It("should record the post before and after", func() {
+			stored := *post
+			updated := *post
+			updated.Captions = dao.ManualCaptions([]string{"caption2"})
+			updated.UpdatedBy = "bob"
+			mockNext.EXPECT().Get(customerID, postID).Return(&stored, nil)
+			mockNext.EXPECT().Update(customerID, post).Return(&updated, nil)
+			_, err := p.Update(customerID, post)
+			Expect(err).To(BeNil())
+
+			entries := recorded()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Action).To(Equal(audit.ActionUpdated))
+			Expect(entries[0].Actor).To(Equal("bob"))
+			Expect(dao.CaptionTexts(decoded(entries[0].Before).Captions)).To(Equal([]string{"caption1"}))
+			Expect(dao.CaptionTexts(decoded(entries[0].After).Captions)).To(Equal([]string{"caption2"}))
+		})
+	})
+
+	Describe("UpdateBatch", func() {
+		It("should only record the updated posts", func() {
+			missingID := bson.NewObjectId()
+			missing := &dao.Post{ID: &missingID}
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil)
+			mockNext.EXPECT().Get(customerID, missingID).Return(nil, errors.New("not found"))
+			mockNext.EXPECT().UpdateBatch(customerID, []*dao.Post{post, missing}).Return([]*dao.BatchResult{
+				{Post: post},
+				{Err: errors.New("not found")},
+			}, nil)
+			_, err := p.UpdateBatch(customerID, []*dao.Post{post, missing})
+			Expect(err).To(BeNil())
+			Expect(recorded()).To(HaveLen(1))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("should record the deleted post", func() {
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil)
+			mockNext.EXPECT().Delete(customerID, postID, "alice").Return(nil)
+			Expect(p.Delete(customerID, postID, "alice")).To(Succeed())
+
+			entries := recorded()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Action).To(Equal(audit.ActionDeleted))
+			Expect(entries[0].Actor).To(Equal("alice"))
+			Expect(decoded(entries[0].Before)).To(Equal(post))
+			Expect(entries[0].After).To(BeNil())
+		})
+	})
+
+	Describe("Restore and Patch", func() {
+		It("should record the author", func() {
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil).Times(2)
+			mockNext.EXPECT().Restore(customerID, postID, 1, "carol").Return(post, nil)
+			mockNext.EXPECT().Patch(customerID, postID, gomock.Any(), "dave").Return(post, nil)
+			_, err := p.Restore(customerID, postID, 1, "carol")
+			Expect(err).To(BeNil())
+			_, err = p.Patch(customerID, postID, func(p *dao.Post) (*dao.Post, error) { return p, nil }, "dave")
+			Expect(err).To(BeNil())
+
+			entries := recorded()
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Action).To(Equal(audit.ActionRestored))
+			Expect(entries[0].Actor).To(Equal("carol"))
+			Expect(entries[1].Action).To(Equal(audit.ActionPatched))
+			Expect(entries[1].Actor).To(Equal("dave"))
+		})
+	})
+
+	Describe("ClaimDue", func() {
+		It("should record every claimed post as the scheduler", func() {
+			claimed := *post
+			claimed.CustID = customerID
+			claimed.Status = dao.StatusPublishing
+			now := time.Now()
+			mockNext.EXPECT().ClaimDue(now, time.Minute, 10).Return([]*dao.Post{&claimed}, nil)
+			_, err := p.ClaimDue(now, time.Minute, 10)
+			Expect(err).To(BeNil())
+
+			entries := recorded()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Action).To(Equal(audit.ActionPublishClaimed))
+			Expect(entries[0].Actor).To(Equal(dao.ActorScheduler))
+		})
+	})
+
+	Describe("a change that can not be recorded", func() {
+		var logErr error
+
+		BeforeEach(func() {
+			logErr = errors.New("test-error")
+			p = NewPoster(logger, mockNext, failingLog{logErr})
+		})
+
+		It("should return the error of the audit log", func() {
+			mockNext.EXPECT().Insert(customerID, post).Return(post, nil)
+			_, err := p.Insert(customerID, post)
+			Expect(err).To(Equal(logErr))
+
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil)
+			mockNext.EXPECT().Delete(customerID, postID, "alice").Return(nil)
+			Expect(p.Delete(customerID, postID, "alice")).To(Equal(logErr))
+		})
+
+		It("should return the error in the result of a batch", func() {
+			mockNext.EXPECT().InsertBatch(customerID, []*dao.Post{post}).Return([]*dao.BatchResult{{Post: post}}, nil)
+			results, err := p.InsertBatch(customerID, []*dao.Post{post})
+			Expect(err).To(BeNil())
+			Expect(results[0].Err).To(Equal(logErr))
+		})
+
+		It("should return the claimed posts with the error", func() {
+			claimed := *post
+			claimed.CustID = customerID
+			now := time.Now()
+			mockNext.EXPECT().ClaimDue(now, time.Minute, 10).Return([]*dao.Post{&claimed}, nil)
+			posts, err := p.ClaimDue(now, time.Minute, 10)
+			Expect(err).To(Equal(logErr))
+			Expect(posts).To(Equal([]*dao.Post{&claimed}))
+		})
+	})
+
+	Describe("Get", func() {
+		It("should NOT record reads", func() {
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil)
+			_, err := p.Get(customerID, postID)
+			Expect(err).To(BeNil())
+			Expect(recorded()).To(BeEmpty())
+		})
+	})
+})
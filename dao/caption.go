@@ -1,6 +1,219 @@
 package dao
 
+import (
+	"reflect"
+
+	"labix.org/v2/mgo/bson"
+)
+
+// Where a caption came from
+const (
+	SourceGenerated = "generated"
+	SourceManual    = "manual"
+)
+
 // Captioner defines the interface for generating captions
 type Captioner interface {
 	Generate(string) ([]string, error)
 }
+
+// Caption is one of the candidate captions of a post. Score and Findings are
+// only set for generated captions, and at most one caption of a post is selected
+type Caption struct {
+	ID       string     `json:"id"`
+	Text     string     `json:"text"`
+	Source   string     `json:"source"`
+	Score    float64    `json:"score,omitempty"`
+	Selected bool       `json:"selected"`
+	Pinned   bool       `json:"pinned"`
+	Findings []*Finding `json:"findings,omitempty"`
+}
+
+// Finding is a quality rule a generated caption broke, and what the rule did
+// about it
+type Finding struct {
+	Rule    string `json:"rule"`
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+// Rejection is a generated caption a quality rule kept out of a post
+type Rejection struct {
+	Text     string     `json:"text"`
+	Findings []*Finding `json:"findings"`
+}
+
+// ValidSource returns true if a caption can come from the source
+func ValidSource(source string) bool {
+	switch source {
+	case SourceGenerated, SourceManual:
+		return true
+	default:
+		return false
+	}
+}
+
+// ManualCaptions returns a manual caption for every text
+func ManualCaptions(texts []string) []*Caption {
+	if texts == nil {
+		return nil
+	}
+	captions := make([]*Caption, len(texts))
+	for i, text := range texts {
+		captions[i] = &Caption{Text: text, Source: SourceManual}
+	}
+	return captions
+}
+
+// CaptionTexts returns the text of every caption
+func CaptionTexts(captions []*Caption) []string {
+	if captions == nil {
+		return nil
+	}
+	texts := make([]string, len(captions))
+	for i, caption := range captions {
+		texts[i] = caption.Text
+	}
+	return texts
+}
+
+// CopyCaptions copies the captions so they do not change with the originals
+func CopyCaptions(captions []*Caption) []*Caption {
+	if captions == nil {
+		return nil
+	}
+	copied := make([]*Caption, len(captions))
+	for i, caption := range captions {
+		c := *caption
+		c.Findings = copyFindings(caption.Findings)
+		copied[i] = &c
+	}
+	return copied
+}
+
+// CopyRejections copies the rejections so they do not change with the originals
+func CopyRejections(rejections []*Rejection) []*Rejection {
+	if rejections == nil {
+		return nil
+	}
+	copied := make([]*Rejection, len(rejections))
+	for i, rejection := range rejections {
+		copied[i] = &Rejection{Text: rejection.Text, Findings: copyFindings(rejection.Findings)}
+	}
+	return copied
+}
+
+// copyFindings copies the findings of a caption or rejection
+func copyFindings(findings []*Finding) []*Finding {
+	if findings == nil {
+		return nil
+	}
+	copied := make([]*Finding, len(findings))
+	for i, finding := range findings {
+		f := *finding
+		copied[i] = &f
+	}
+	return copied
+}
+
+// FindCaption returns the index of the caption with the id, or -1
+func FindCaption(captions []*Caption, id string) int {
+	for i, caption := range captions {
+		if caption.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// EqualCaptions returns true if both have the same captions in the same order,
+// selected and pinned the same way
+func EqualCaptions(a, b []*Caption) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectedCaption returns the selected caption, or the first one when none is
+// selected, since generated captions are ranked best first. It is nil when
+// there are no captions
+func SelectedCaption(captions []*Caption) *Caption {
+	for _, caption := range captions {
+		if caption.Selected {
+			return caption
+		}
+	}
+	if len(captions) == 0 {
+		return nil
+	}
+	return captions[0]
+}
+
+// MergeCaptions returns the captions of next as they are stored in place of
+// stored. A caption without an id names the first stored caption with the same
+// text that no other caption named, and is kept as that caption. A caption with
+// an id keeps the source, score and findings of the stored caption with it,
+// unless its text changed, which makes it manual. Captions without an id, or
+// with one used earlier in the list, get a new id, and pinned captions are moved
+// ahead of the others
+func MergeCaptions(stored, next []*Caption) []*Caption {
+	if next == nil {
+		return nil
+	}
+
+	byID := map[string]*Caption{}
+	for _, caption := range stored {
+		byID[caption.ID] = caption
+	}
+	named := map[string]bool{}
+	for _, caption := range next {
+		if caption.ID != "" {
+			named[caption.ID] = true
+		}
+	}
+
+	merged := make([]*Caption, 0, len(next))
+	used := map[string]bool{}
+	for _, caption := range next {
+		c := *caption
+		if c.ID == "" {
+			for _, s := range stored {
+				if s.Text == c.Text && !named[s.ID] && !used[s.ID] {
+					c = *s
+					break
+				}
+			}
+		} else if s, ok := byID[c.ID]; ok {
+			c.Source, c.Score, c.Findings = s.Source, s.Score, s.Findings
+			if c.Text != s.Text {
+				c.Source, c.Score, c.Findings = SourceManual, 0, nil
+			}
+		}
+		if c.ID == "" || used[c.ID] {
+			c.ID = bson.NewObjectId().Hex()
+		}
+		if c.Source == "" {
+			c.Source = SourceManual
+		}
+		used[c.ID] = true
+		c.Findings = copyFindings(c.Findings)
+		merged = append(merged, &c)
+	}
+
+	// Pinned captions come first, both keep their order
+	ordered := make([]*Caption, 0, len(merged))
+	for _, pinned := range []bool{true, false} {
+		for _, caption := range merged {
+			if caption.Pinned == pinned {
+				ordered = append(ordered, caption)
+			}
+		}
+	}
+	return ordered
+}
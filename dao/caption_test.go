@@ -0,0 +1,123 @@
+package dao
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergeCaptions", func() {
+	var stored []*Caption
+
+	BeforeEach(func() {
+		stored = []*Caption{
+			{ID: "a", Text: "caption a", Source: SourceGenerated, Score: 0.9, Selected: true},
+			{ID: "b", Text: "caption b", Source: SourceGenerated, Score: 0.5},
+			{ID: "c", Text: "caption c", Source: SourceManual, Pinned: true},
+		}
+	})
+
+	It("should keep nil captions nil", func() {
+		Expect(MergeCaptions(stored, nil)).To(BeNil())
+	})
+
+	It("should keep the captions named by text", func() {
+		merged := MergeCaptions(stored, ManualCaptions([]string{"caption b", "caption a", "new"}))
+		Expect(merged).To(HaveLen(3))
+		Expect(merged[0]).To(Equal(stored[1]))
+		Expect(merged[1]).To(Equal(stored[0]))
+		Expect(merged[2].ID).NotTo(BeEmpty())
+		Expect(merged[2].Text).To(Equal("new"))
+		Expect(merged[2].Source).To(Equal(SourceManual))
+	})
+
+	It("should take the text, selection and pin of the captions named by id", func() {
+		merged := MergeCaptions(stored, []*Caption{
+			{ID: "a", Text: "caption a"},
+			{ID: "b", Text: "edited", Selected: true, Source: SourceGenerated, Score: 1},
+		})
+		Expect(merged).To(Equal([]*Caption{
+			{ID: "a", Text: "caption a", Source: SourceGenerated, Score: 0.9},
+			{ID: "b", Text: "edited", Source: SourceManual, Selected: true},
+		}))
+	})
+
+	It("should keep the findings of a caption until its text changes", func() {
+		stored[0].Findings = []*Finding{{Rule: "fragment", Action: "flag", Message: "does not end a sentence"}}
+		stored[1].Findings = []*Finding{{Rule: "fragment", Action: "flag", Message: "does not end a sentence"}}
+		merged := MergeCaptions(stored, []*Caption{
+			{ID: "a", Text: "caption a"},
+			{ID: "b", Text: "edited"},
+			{Text: "new", Findings: []*Finding{{Rule: "profanity", Action: "fix", Message: "contains profanity"}}},
+		})
+		Expect(merged[0].Findings).To(Equal(stored[0].Findings))
+		Expect(merged[1].Findings).To(BeNil())
+		Expect(merged[2].Findings).To(HaveLen(1))
+	})
+
+	It("should NOT let a text take a caption named by id", func() {
+		merged := MergeCaptions(stored, []*Caption{{Text: "caption a"}, {ID: "a", Text: "caption a"}})
+		Expect(merged[0].ID).NotTo(Equal("a"))
+		Expect(merged[1].ID).To(Equal("a"))
+	})
+
+	It("should give repeated and missing ids a new id", func() {
+		merged := MergeCaptions(nil, []*Caption{{ID: "x", Text: "1"}, {ID: "x", Text: "2"}, {Text: "3"}})
+		Expect(merged[0].ID).To(Equal("x"))
+		Expect(merged[1].ID).NotTo(Equal("x"))
+		Expect(merged[2].ID).NotTo(BeEmpty())
+		Expect(merged[1].ID).NotTo(Equal(merged[2].ID))
+	})
+
+	It("should move pinned captions first", func() {
+		merged := MergeCaptions(stored, []*Caption{
+			{ID: "a", Text: "caption a"},
+			{ID: "b", Text: "caption b", Pinned: true},
+			{ID: "c", Text: "caption c", Pinned: true},
+		})
+		Expect(CaptionTexts(merged)).To(Equal([]string{"caption b", "caption c", "caption a"}))
+	})
+
+	It("should NOT change the captions it was given", func() {
+		next := []*Caption{{Text: "caption a"}}
+		MergeCaptions(stored, next)
+		Expect(next[0]).To(Equal(&Caption{Text: "caption a"}))
+	})
+})
+
+var _ = Describe("SelectedCaption", func() {
+	It("should return the selected caption", func() {
+		captions := ManualCaptions([]string{"a", "b"})
+		captions[1].Selected = true
+		Expect(SelectedCaption(captions)).To(Equal(captions[1]))
+	})
+
+	It("should return the first caption when none is selected", func() {
+		captions := ManualCaptions([]string{"a", "b"})
+		Expect(SelectedCaption(captions)).To(Equal(captions[0]))
+	})
+
+	It("should return nil without captions", func() {
+		Expect(SelectedCaption(nil)).To(BeNil())
+	})
+})
+
+var _ = Describe("EqualCaptions", func() {
+	It("should compare the captions in order", func() {
+		captions := []*Caption{{ID: "a", Text: "caption1"}, {ID: "b", Text: "caption2"}}
+		cases := []struct {
+			name  string
+			other []*Caption
+			equal bool
+		}{
+			{"copies", CopyCaptions(captions), true},
+			{"reordered", []*Caption{captions[1], captions[0]}, false},
+			{"changed text", []*Caption{captions[0], {ID: "b", Text: "changed"}}, false},
+			{"selected", []*Caption{captions[0], {ID: "b", Text: "caption2", Selected: true}}, false},
+			{"fewer", captions[:1], false},
+		}
+		for _, c := range cases {
+			Expect(EqualCaptions(captions, c.other)).To(Equal(c.equal), c.name)
+		}
+		Expect(EqualCaptions(nil, []*Caption{})).To(BeTrue())
+	})
+})
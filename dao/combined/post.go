@@ -2,6 +2,7 @@ package combined
 
 import (
 	"errors"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"labix.org/v2/mgo/bson"
@@ -49,6 +50,69 @@ func (d *Poster) Insert(customerID string, post *dao.Post) (*dao.Post, error) {
 	return dsPost, nil
 }
 
+// InsertBatch inserts into the persistent store, then the cache. Like Insert, a
+// cache failure is only logged
+func (d *Poster) InsertBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	logger := d.logger.WithFields(log.Fields{
+		"posts": len(posts),
+	})
+
+	logger.Info("batch inserting")
+	results, err := d.persistent.InsertBatch(customerID, posts)
+	if err != nil {
+		logger.Warn("failed to batch insert into persistent")
+		return nil, err
+	}
+
+	if _, err := d.cache.InsertBatch(customerID, written(results)); err != nil {
+		logger.Warn("failed to batch insert into cache")
+	}
+
+	logger.Debug("successfully batch inserted")
+	return results, nil
+}
+
+// UpdateBatch updates the persistent store, then the cache. Posts that fail to
+// update in the cache are deleted from it the same way Update does, and a post
+// that can not be deleted from the cache gets the error
+func (d *Poster) UpdateBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	logger := d.logger.WithFields(log.Fields{
+		"posts": len(posts),
+	})
+
+	logger.Info("batch updating")
+	results, err := d.persistent.UpdateBatch(customerID, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := written(results)
+	cacheResults, err := d.cache.UpdateBatch(customerID, updated)
+	stale := map[bson.ObjectId]bool{}
+	for i, post := range updated {
+		if err != nil || (i < len(cacheResults) && cacheResults[i] != nil && cacheResults[i].Err != nil) {
+			stale[*post.ID] = true
+		}
+	}
+
+	for _, result := range results {
+		if result.Err != nil || !stale[*result.Post.ID] {
+			continue
+		}
+		postLogger := logger.WithFields(log.Fields{
+			"post_id": result.Post.ID.Hex(),
+		})
+		postLogger.Warn("failed to update into cache")
+		if err := d.cache.Delete(customerID, *result.Post.ID); err != nil {
+			postLogger.Error("failed to delete into cache")
+			result.Post, result.Err = nil, err
+		}
+	}
+
+	logger.Debug("successfully batch updated")
+	return results, nil
+}
+
 // Get tries the cache first and then the persistent store, on any cache error
 // the code will try to read from the persistent storage
 func (d *Poster) Get(customerID string, postID bson.ObjectId) (*dao.Post, error) {
@@ -79,6 +143,35 @@ func (d *Poster) Get(customerID string, postID bson.ObjectId) (*dao.Post, error)
 	return post, nil
 }
 
+// GetByURL tries the cache first and then the persistent store, like Get
+func (d *Poster) GetByURL(customerID string, canonicalURL string) (*dao.Post, error) {
+	logger := d.logger.WithFields(log.Fields{
+		"url": canonicalURL,
+	})
+
+	logger.Info("retrieving by url")
+	post, err := d.cache.GetByURL(customerID, canonicalURL)
+	if err != nil || post == nil {
+		if err == nil {
+			err = errors.New("cache miss")
+		}
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Info("failed to retrieve by url from cache")
+
+		post, err = d.persistent.GetByURL(customerID, canonicalURL)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				"error": err.Error(),
+			}).Warn("failed to retrieve by url from persistent")
+			return nil, err
+		}
+	}
+
+	logger.Debug("successfully retrieved by url")
+	return post, nil
+}
+
 // Update calls the persistent store first. On success, the cache is called. If the
 // cache call fails, the value will be deleted from the cache
 func (d *Poster) Update(customerID string, post *dao.Post) (*dao.Post, error) {
@@ -107,3 +200,182 @@ func (d *Poster) Update(customerID string, post *dao.Post) (*dao.Post, error) {
 	logger.Debug("successfully updated")
 	return post, nil
 }
+
+// List reads from the persistent store, the cache only holds single posts
+func (d *Poster) List(customerID string, opts dao.ListOptions) ([]*dao.Post, error) {
+	d.logger.Info("listing")
+	return d.persistent.List(customerID, opts)
+}
+
+// Revisions are only kept by the persistent store
+func (d *Poster) Revisions(customerID string, postID bson.ObjectId) ([]*dao.Revision, error) {
+	logger := d.logger.WithFields(log.Fields{
+		"post_id": postID.Hex(),
+	})
+
+	logger.Info("retrieving revisions")
+	revisions, err := d.persistent.Revisions(customerID, postID)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Warn("failed to retrieve revisions from persistent")
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Restore calls the persistent store first, then updates the cache with the
+// restored post the same way Update does
+func (d *Poster) Restore(customerID string, postID bson.ObjectId, number int, author string) (*dao.Post, error) {
+	logger := d.logger.WithFields(log.Fields{
+		"post_id":  postID.Hex(),
+		"revision": number,
+	})
+
+	logger.Info("restoring")
+	post, err := d.persistent.Restore(customerID, postID, number, author)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = d.cache.Update(customerID, post)
+	if err != nil {
+		logger.Warn("failed to update into cache")
+
+		err = d.cache.Delete(customerID, postID)
+		if err != nil {
+			logger.Error("failed to delete into cache")
+			return nil, err
+		}
+	}
+
+	logger.Debug("successfully restored")
+	return post, nil
+}
+
+// Patch calls the persistent store first, then updates the cache with the
+// patched post the same way Update does
+func (d *Poster) Patch(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+	logger := d.logger.WithFields(log.Fields{
+		"post_id": postID.Hex(),
+	})
+
+	logger.Info("patching")
+	patched, err := d.persistent.Patch(customerID, postID, patch, author)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.updateCache(logger, customerID, patched); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("successfully patched")
+	return patched, nil
+}
+
+// Delete calls the persistent store first, then removes the post from the cache.
+// A cache failure is returned, since the cache would still serve the post
+func (d *Poster) Delete(customerID string, postID bson.ObjectId, author string) error {
+	logger := d.logger.WithFields(log.Fields{
+		"post_id": postID.Hex(),
+	})
+
+	logger.Info("deleting")
+	if err := d.persistent.Delete(customerID, postID); err != nil {
+		return err
+	}
+
+	if err := d.cache.Delete(customerID, postID); err != nil {
+		logger.Error("failed to delete from cache")
+		return err
+	}
+
+	logger.Debug("successfully deleted")
+	return nil
+}
+
+// Schedule calls the persistent store first, then updates the cache with the
+// scheduled post the same way Update does
+func (d *Poster) Schedule(customerID string, post *dao.Post) (*dao.Post, error) {
+	logger := d.logger.WithFields(log.Fields{
+		"post_id": post.ID.Hex(),
+	})
+
+	logger.Info("scheduling")
+	scheduled, err := d.persistent.Schedule(customerID, post)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.updateCache(logger, customerID, scheduled); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("successfully scheduled")
+	return scheduled, nil
+}
+
+// ClaimDue claims from the persistent store, which is the only one that sees
+// every customer's posts. The claimed posts are updated in the cache
+func (d *Poster) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*dao.Post, error) {
+	d.logger.Info("claiming due posts")
+	posts, err := d.persistent.ClaimDue(now, lease, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range posts {
+		logger := d.logger.WithFields(log.Fields{
+			"post_id": post.ID.Hex(),
+		})
+		if err := d.updateCache(logger, post.CustID, post); err != nil {
+			// The claim is already stored, the post has to be published
+			logger.Error("claimed post may be stale in cache")
+		}
+	}
+	return posts, nil
+}
+
+// CompletePublish calls the persistent store first, then updates the cache with
+// the published post the same way Update does
+func (d *Poster) CompletePublish(customerID string, postID bson.ObjectId, publications []*dao.Publication) (*dao.Post, error) {
+	logger := d.logger.WithFields(log.Fields{
+		"post_id": postID.Hex(),
+	})
+
+	logger.Info("completing publish")
+	post, err := d.persistent.CompletePublish(customerID, postID, publications)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.updateCache(logger, customerID, post); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("successfully completed publish")
+	return post, nil
+}
+
+// updateCache stores the post in the cache, or removes it if that fails so a
+// stale post is never served
+func (d *Poster) updateCache(logger *log.Entry, customerID string, post *dao.Post) error {
+	if _, err := d.cache.Update(customerID, post); err != nil {
+		logger.Warn("failed to update into cache")
+
+		if err := d.cache.Delete(customerID, *post.ID); err != nil {
+			logger.Error("failed to delete into cache")
+			return err
+		}
+	}
+	return nil
+}
+
+// written returns the posts of the results that were written
+func written(results []*dao.BatchResult) []*dao.Post {
+	posts := []*dao.Post{}
+	for _, result := range results {
+		if result.Err == nil && result.Post != nil {
+			posts = append(posts, result.Post)
+		}
+	}
+	return posts
+}
@@ -3,6 +3,7 @@ package combined
 import (
 	"errors"
 	"io/ioutil"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
@@ -41,11 +42,11 @@ var _ = Describe("Poster", func() {
 			ID:     &postID,
 			CustID: customerID,
 			URL:    "test-url",
-			Captions: []string{
+			Captions: dao.ManualCaptions([]string{
 				"caption1",
 				"caption2",
 				"caption3",
-			},
+			}),
 		}
 	})
 
@@ -225,6 +226,65 @@ var _ = Describe("Poster", func() {
 		})
 	})
 
+	Describe("GetByURL", func() {
+		var (
+			canonicalURL string
+			retPost      *dao.Post
+			err          error
+		)
+
+		BeforeEach(func() {
+			canonicalURL = "https://example.com/post"
+		})
+
+		JustBeforeEach(func() {
+			retPost, err = p.GetByURL(customerID, canonicalURL)
+		})
+
+		Context("with cache datastore success", func() {
+			BeforeEach(func() {
+				mockCache.EXPECT().GetByURL(customerID, canonicalURL).Return(post, nil)
+			})
+
+			It("should return a post", func() {
+				Expect(err).To(BeNil())
+				Expect(retPost).To(Equal(post))
+			})
+		})
+
+		Context("with cache datastore miss", func() {
+			BeforeEach(func() {
+				mockCache.EXPECT().GetByURL(customerID, canonicalURL).Return(nil, nil)
+			})
+
+			Context("with persistent datastore error", func() {
+				var (
+					dsErr error
+				)
+				BeforeEach(func() {
+					dsErr = errors.New("test-error")
+					mockPersistent.EXPECT().GetByURL(customerID, canonicalURL).Return(nil, dsErr)
+				})
+
+				It("should return an error", func() {
+					Expect(err).To(Equal(dsErr))
+					Expect(retPost).To(BeNil())
+				})
+			})
+
+			Context("without persistent datastore error", func() {
+				BeforeEach(func() {
+					mockPersistent.EXPECT().GetByURL(customerID, canonicalURL).Return(post, nil)
+				})
+
+				It("should return a post", func() {
+					Expect(err).To(BeNil())
+					Expect(retPost).To(Equal(post))
+				})
+			})
+		})
+	})
+
 	Describe("Update", func() {
 		var (
 			retPost *dao.Post
@@ -310,4 +370,420 @@ var _ = Describe("Poster", func() {
 			})
 		})
 	})
+
+	Describe("Revisions", func() {
+		It("should only use the persistent datastore", func() {
+			revisions := []*dao.Revision{{Number: 1, PostID: postID}}
+			mockPersistent.EXPECT().Revisions(customerID, postID).Return(revisions, nil)
+			retRevisions, err := p.Revisions(customerID, postID)
+			Expect(err).To(BeNil())
+			Expect(retRevisions).To(Equal(revisions))
+		})
+	})
+
+	Describe("Restore", func() {
+		var (
+			retPost *dao.Post
+			err     error
+		)
+
+		JustBeforeEach(func() {
+			retPost, err = p.Restore(customerID, postID, 1, "author")
+		})
+
+		Context("with persistent datastore error", func() {
+			var dsErr error
+			BeforeEach(func() {
+				dsErr = errors.New("test-error")
+				mockPersistent.EXPECT().Restore(customerID, postID, 1, "author").Return(nil, dsErr)
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(Equal(dsErr))
+				Expect(retPost).To(BeNil())
+			})
+		})
+
+		Context("without persistent datastore error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().Restore(customerID, postID, 1, "author").Return(post, nil)
+			})
+
+			Context("with cache update error", func() {
+				BeforeEach(func() {
+					mockCache.EXPECT().Update(customerID, post).Return(nil, errors.New("test-error"))
+					mockCache.EXPECT().Delete(customerID, postID).Return(nil)
+				})
+
+				It("should return the post", func() {
+					Expect(err).To(BeNil())
+					Expect(retPost).To(Equal(post))
+				})
+			})
+
+			Context("with cache update success", func() {
+				BeforeEach(func() {
+					mockCache.EXPECT().Update(customerID, post).Return(post, nil)
+				})
+
+				It("should return the post", func() {
+					Expect(err).To(BeNil())
+					Expect(retPost).To(Equal(post))
+				})
+			})
+		})
+	})
+
+	Describe("Patch", func() {
+		var (
+			retPost *dao.Post
+			err     error
+		)
+
+		JustBeforeEach(func() {
+			retPost, err = p.Patch(customerID, postID, func(post *dao.Post) (*dao.Post, error) { return post, nil }, "author")
+		})
+
+		Context("with persistent datastore error", func() {
+			var dsErr error
+			BeforeEach(func() {
+				dsErr = errors.New("test-error")
+				mockPersistent.EXPECT().Patch(customerID, postID, gomock.Any(), "author").Return(nil, dsErr)
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(Equal(dsErr))
+				Expect(retPost).To(BeNil())
+			})
+		})
+
+		Context("without persistent datastore error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().Patch(customerID, postID, gomock.Any(), "author").Return(post, nil)
+			})
+
+			Context("with cache update and delete errors", func() {
+				var cacheErr error
+				BeforeEach(func() {
+					cacheErr = errors.New("test-error")
+					mockCache.EXPECT().Update(customerID, post).Return(nil, errors.New("test-error"))
+					mockCache.EXPECT().Delete(customerID, postID).Return(cacheErr)
+				})
+
+				It("should return the error", func() {
+					Expect(err).To(Equal(cacheErr))
+					Expect(retPost).To(BeNil())
+				})
+			})
+
+			Context("with cache update success", func() {
+				BeforeEach(func() {
+					mockCache.EXPECT().Update(customerID, post).Return(post, nil)
+				})
+
+				It("should return the post", func() {
+					Expect(err).To(BeNil())
+					Expect(retPost).To(Equal(post))
+				})
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		var err error
+
+		JustBeforeEach(func() {
+			err = p.Delete(customerID, postID, "alice")
+		})
+
+		Context("with persistent datastore error", func() {
+			var dsErr error
+			BeforeEach(func() {
+				dsErr = errors.New("test-error")
+				mockPersistent.EXPECT().Delete(customerID, postID).Return(dsErr)
+			})
+
+			It("should NOT delete from the cache", func() {
+				Expect(err).To(Equal(dsErr))
+			})
+		})
+
+		Context("without persistent datastore error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().Delete(customerID, postID).Return(nil)
+			})
+
+			Context("with cache error", func() {
+				var cacheErr error
+				BeforeEach(func() {
+					cacheErr = errors.New("test-error")
+					mockCache.EXPECT().Delete(customerID, postID).Return(cacheErr)
+				})
+
+				It("should return the error", func() {
+					Expect(err).To(Equal(cacheErr))
+				})
+			})
+
+			Context("without cache error", func() {
+				BeforeEach(func() {
+					mockCache.EXPECT().Delete(customerID, postID).Return(nil)
+				})
+
+				It("should NOT return an error", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+	})
+
+	Describe("Schedule", func() {
+		var (
+			retPost *dao.Post
+			err     error
+		)
+
+		JustBeforeEach(func() {
+			retPost, err = p.Schedule(customerID, post)
+		})
+
+		Context("with persistent datastore error", func() {
+			var dsErr error
+			BeforeEach(func() {
+				dsErr = errors.New("test-error")
+				mockPersistent.EXPECT().Schedule(customerID, post).Return(nil, dsErr)
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(Equal(dsErr))
+				Expect(retPost).To(BeNil())
+			})
+		})
+
+		Context("without persistent datastore error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().Schedule(customerID, post).Return(post, nil)
+			})
+
+			Context("with cache update and delete errors", func() {
+				var cacheErr error
+				BeforeEach(func() {
+					cacheErr = errors.New("test-error")
+					mockCache.EXPECT().Update(customerID, post).Return(nil, errors.New("test-error"))
+					mockCache.EXPECT().Delete(customerID, postID).Return(cacheErr)
+				})
+
+				It("should return the error", func() {
+					Expect(err).To(Equal(cacheErr))
+					Expect(retPost).To(BeNil())
+				})
+			})
+
+			Context("with cache update success", func() {
+				BeforeEach(func() {
+					mockCache.EXPECT().Update(customerID, post).Return(post, nil)
+				})
+
+				It("should return the post", func() {
+					Expect(err).To(BeNil())
+					Expect(retPost).To(Equal(post))
+				})
+			})
+		})
+	})
+
+	Describe("ClaimDue", func() {
+		var (
+			now   time.Time
+			posts []*dao.Post
+			err   error
+		)
+
+		BeforeEach(func() {
+			now = time.Now()
+		})
+
+		JustBeforeEach(func() {
+			posts, err = p.ClaimDue(now, time.Minute, 10)
+		})
+
+		Context("with persistent datastore error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().ClaimDue(now, time.Minute, 10).Return(nil, errors.New("test-error"))
+			})
+
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(posts).To(BeNil())
+			})
+		})
+
+		Context("with cache errors", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().ClaimDue(now, time.Minute, 10).Return([]*dao.Post{post}, nil)
+				mockCache.EXPECT().Update(customerID, post).Return(nil, errors.New("test-error"))
+				mockCache.EXPECT().Delete(customerID, postID).Return(errors.New("test-error"))
+			})
+
+			It("should still return the claimed posts", func() {
+				Expect(err).To(BeNil())
+				Expect(posts).To(Equal([]*dao.Post{post}))
+			})
+		})
+	})
+
+	Describe("CompletePublish", func() {
+		var publications []*dao.Publication
+
+		BeforeEach(func() {
+			publications = []*dao.Publication{{Channel: "twitter"}}
+		})
+
+		Context("with persistent datastore error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().CompletePublish(customerID, postID, publications).Return(nil, errors.New("test-error"))
+			})
+
+			It("should return an error", func() {
+				retPost, err := p.CompletePublish(customerID, postID, publications)
+				Expect(err).NotTo(BeNil())
+				Expect(retPost).To(BeNil())
+			})
+		})
+
+		Context("without persistent datastore error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().CompletePublish(customerID, postID, publications).Return(post, nil)
+				mockCache.EXPECT().Update(customerID, post).Return(post, nil)
+			})
+
+			It("should return the post", func() {
+				retPost, err := p.CompletePublish(customerID, postID, publications)
+				Expect(err).To(BeNil())
+				Expect(retPost).To(Equal(post))
+			})
+		})
+	})
+
+	Describe("InsertBatch", func() {
+		var (
+			results    []*dao.BatchResult
+			retResults []*dao.BatchResult
+			err        error
+		)
+
+		BeforeEach(func() {
+			results = []*dao.BatchResult{{Post: post}, {Err: errors.New("test-error")}}
+		})
+
+		JustBeforeEach(func() {
+			retResults, err = p.InsertBatch(customerID, []*dao.Post{post, nil})
+		})
+
+		Context("with persistent datastore error", func() {
+			var dsErr error
+			BeforeEach(func() {
+				dsErr = errors.New("test-error")
+				mockPersistent.EXPECT().InsertBatch(customerID, []*dao.Post{post, nil}).Return(nil, dsErr)
+			})
+
+			It("should NOT insert into the cache", func() {
+				Expect(err).To(Equal(dsErr))
+				Expect(retResults).To(BeNil())
+			})
+		})
+
+		Context("with cache error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().InsertBatch(customerID, []*dao.Post{post, nil}).Return(results, nil)
+				mockCache.EXPECT().InsertBatch(customerID, []*dao.Post{post}).Return(nil, errors.New("test-error"))
+			})
+
+			It("should return the persistent results", func() {
+				Expect(err).To(BeNil())
+				Expect(retResults).To(Equal(results))
+			})
+		})
+	})
+
+	Describe("UpdateBatch", func() {
+		var (
+			retResults []*dao.BatchResult
+			err        error
+		)
+
+		JustBeforeEach(func() {
+			retResults, err = p.UpdateBatch(customerID, []*dao.Post{post})
+		})
+
+		Context("with persistent datastore error", func() {
+			var dsErr error
+			BeforeEach(func() {
+				dsErr = errors.New("test-error")
+				mockPersistent.EXPECT().UpdateBatch(customerID, []*dao.Post{post}).Return(nil, dsErr)
+			})
+
+			It("should NOT update the cache", func() {
+				Expect(err).To(Equal(dsErr))
+				Expect(retResults).To(BeNil())
+			})
+		})
+
+		Context("without persistent datastore error", func() {
+			BeforeEach(func() {
+				mockPersistent.EXPECT().UpdateBatch(customerID, []*dao.Post{post}).Return([]*dao.BatchResult{{Post: post}}, nil)
+			})
+
+			Context("without cache error", func() {
+				BeforeEach(func() {
+					mockCache.EXPECT().UpdateBatch(customerID, []*dao.Post{post}).Return([]*dao.BatchResult{{Post: post}}, nil)
+				})
+
+				It("should return the updated post", func() {
+					Expect(err).To(BeNil())
+					Expect(retResults).To(Equal([]*dao.BatchResult{{Post: post}}))
+				})
+			})
+
+			Context("with a cache item error", func() {
+				BeforeEach(func() {
+					mockCache.EXPECT().UpdateBatch(customerID, []*dao.Post{post}).Return([]*dao.BatchResult{{Err: errors.New("test-error")}}, nil)
+				})
+
+				Context("with cache delete error", func() {
+					var cacheErr error
+					BeforeEach(func() {
+						cacheErr = errors.New("test-error")
+						mockCache.EXPECT().Delete(customerID, postID).Return(cacheErr)
+					})
+
+					It("should return the error for the item", func() {
+						Expect(err).To(BeNil())
+						Expect(retResults).To(Equal([]*dao.BatchResult{{Err: cacheErr}}))
+					})
+				})
+
+				Context("without cache delete error", func() {
+					BeforeEach(func() {
+						mockCache.EXPECT().Delete(customerID, postID).Return(nil)
+					})
+
+					It("should return the updated post", func() {
+						Expect(err).To(BeNil())
+						Expect(retResults).To(Equal([]*dao.BatchResult{{Post: post}}))
+					})
+				})
+			})
+		})
+	})
+
+	Describe("List", func() {
+		It("should only read the persistent store", func() {
+			opts := dao.ListOptions{Limit: 10}
+			mockPersistent.EXPECT().List(customerID, opts).Return([]*dao.Post{post}, nil)
+			posts, err := p.List(customerID, opts)
+			Expect(err).To(BeNil())
+			Expect(posts).To(Equal([]*dao.Post{post}))
+		})
+	})
 })
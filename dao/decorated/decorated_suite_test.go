@@ -0,0 +1,13 @@
+package decorated
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDecorated(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dao Decorated Suite")
+}
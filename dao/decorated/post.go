@@ -0,0 +1,22 @@
+package decorated
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/dao/audited"
+	"github.com/bpross/cc-hw/dao/combined"
+	"github.com/bpross/cc-hw/dao/evented"
+	"github.com/bpross/cc-hw/dao/validated"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+	"github.com/bpross/cc-hw/validate"
+)
+
+// NewPoster creates the Poster every writer of posts goes through. Writes are
+// validated, then stored in the cache and the persistent datastore, then
+// appended to the outbox and recorded in the audit log
+func NewPoster(logger *log.Logger, cache, persistent datastore.Datastore, validator *validate.Validator, outbox events.Outbox, auditLog audit.Appender) dao.Poster {
+	return audited.NewPoster(logger, evented.NewPoster(logger, validated.NewPoster(logger, combined.NewPoster(logger, cache, persistent), validator), outbox), auditLog)
+}
@@ -0,0 +1,84 @@
+package decorated
+
+import (
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+	"github.com/bpross/cc-hw/validate"
+)
+
+var _ = Describe("Poster", func() {
+	var (
+		p          dao.Poster
+		outbox     *events.InMemoryLog
+		auditLog   *audit.InMemoryStore
+		customerID string
+	)
+
+	BeforeEach(func() {
+		logger := log.New()
+		logger.Out = ioutil.Discard
+		outbox = events.NewInMemoryLog(logger)
+		auditLog = audit.NewInMemoryStore()
+		p = NewPoster(logger, datastore.NewNoOpCache(logger), datastore.NewInMemoryDatastore(logger), validate.NewValidator(validate.DefaultRules()), outbox, auditLog)
+		customerID = "test-customer"
+	})
+
+	actions := func() []string {
+		entries, err := auditLog.Query(customerID, audit.Query{})
+		Expect(err).To(BeNil())
+		found := []string{}
+		for _, e := range entries {
+			found = append(found, e.Action)
+		}
+		return found
+	}
+
+	types := func() []string {
+		evs, err := outbox.Read(customerID, 0, 0)
+		Expect(err).To(BeNil())
+		found := []string{}
+		for _, e := range evs {
+			found = append(found, e.Type)
+		}
+		return found
+	}
+
+	Context("inserting and deleting a post", func() {
+		It("should validate, emit and record every write", func() {
+			inserted, err := p.Insert(customerID, &dao.Post{
+				URL:      " https://example.com/post ",
+				Captions: dao.ManualCaptions([]string{"caption"}),
+			})
+			Expect(err).To(BeNil())
+			Expect(inserted.URL).To(Equal("https://example.com/post"))
+
+			Expect(p.Delete(customerID, *inserted.ID, "alice")).To(BeNil())
+			_, err = p.Get(customerID, *inserted.ID)
+			Expect(err).ToNot(BeNil())
+
+			Expect(types()).To(Equal([]string{events.TypeCreated, events.TypeDeleted}))
+			Expect(actions()).To(Equal([]string{audit.ActionCreated, audit.ActionDeleted}))
+		})
+	})
+
+	Context("inserting an invalid post", func() {
+		It("should neither store, emit nor record it", func() {
+			_, err := p.Insert(customerID, &dao.Post{URL: "not a url"})
+			Expect(err).ToNot(BeNil())
+
+			posts, err := p.List(customerID, dao.ListOptions{})
+			Expect(err).To(BeNil())
+			Expect(posts).To(BeEmpty())
+			Expect(types()).To(BeEmpty())
+			Expect(actions()).To(BeEmpty())
+		})
+	})
+})
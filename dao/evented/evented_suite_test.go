@@ -0,0 +1,13 @@
+package evented
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEvented(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dao Evented Suite")
+}
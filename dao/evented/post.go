@@ -0,0 +1,208 @@
+package evented
+
+import (
+	"reflect"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/events"
+)
+
+// Poster implements the Poster interface by writing an event to the outbox for
+// every change the underlying Poster makes
+type Poster struct {
+	logger *log.Logger
+	next   dao.Poster
+	outbox events.Outbox
+}
+
+// NewPoster creates a new Poster with the supplied options
+func NewPoster(logger *log.Logger, next dao.Poster, outbox events.Outbox) *Poster {
+	return &Poster{
+		logger: logger,
+		next:   next,
+		outbox: outbox,
+	}
+}
+
+// Insert emits a created event for the new post
+func (d *Poster) Insert(customerID string, post *dao.Post) (*dao.Post, error) {
+	inserted, err := d.next.Insert(customerID, post)
+	if err != nil {
+		return nil, err
+	}
+	d.emit(events.NewEvent(events.TypeCreated, customerID, *inserted.ID, inserted.UpdatedBy, inserted))
+	return inserted, nil
+}
+
+// InsertBatch emits a created event for every inserted post
+func (d *Poster) InsertBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	results, err := d.next.InsertBatch(customerID, posts)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Err == nil {
+			d.emit(events.NewEvent(events.TypeCreated, customerID, *result.Post.ID, result.Post.UpdatedBy, result.Post))
+		}
+	}
+	return results, nil
+}
+
+// UpdateBatch emits the events Update would for every updated post
+func (d *Poster) UpdateBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	before := make([]*dao.Post, len(posts))
+	for i, post := range posts {
+		if post != nil && post.ID != nil {
+			before[i] = d.snapshot(customerID, *post.ID)
+		}
+	}
+
+	results, err := d.next.UpdateBatch(customerID, posts)
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		if result.Err == nil {
+			d.emitChanges(customerID, before[i], result.Post, result.Post.UpdatedBy)
+		}
+	}
+	return results, nil
+}
+
+// Get calls the underlying Poster, reads are not events
+func (d *Poster) Get(customerID string, postID bson.ObjectId) (*dao.Post, error) {
+	return d.next.Get(customerID, postID)
+}
+
+// GetByURL calls the underlying Poster, reads are not events
+func (d *Poster) GetByURL(customerID string, canonicalURL string) (*dao.Post, error) {
+	return d.next.GetByURL(customerID, canonicalURL)
+}
+
+// List calls the underlying Poster, reads are not events
+func (d *Poster) List(customerID string, opts dao.ListOptions) ([]*dao.Post, error) {
+	return d.next.List(customerID, opts)
+}
+
+// Revisions calls the underlying Poster, reads are not events
+func (d *Poster) Revisions(customerID string, postID bson.ObjectId) ([]*dao.Revision, error) {
+	return d.next.Revisions(customerID, postID)
+}
+
+// Update emits an event for each of the captions and the status that changed
+func (d *Poster) Update(customerID string, post *dao.Post) (*dao.Post, error) {
+	if post == nil || post.ID == nil {
+		return d.next.Update(customerID, post)
+	}
+
+	before := d.snapshot(customerID, *post.ID)
+	updated, err := d.next.Update(customerID, post)
+	if err != nil {
+		return nil, err
+	}
+	d.emitChanges(customerID, before, updated, updated.UpdatedBy)
+	return updated, nil
+}
+
+// Restore emits an event for each of the captions and the status that changed
+func (d *Poster) Restore(customerID string, postID bson.ObjectId, number int, author string) (*dao.Post, error) {
+	before := d.snapshot(customerID, postID)
+	restored, err := d.next.Restore(customerID, postID, number, author)
+	if err != nil {
+		return nil, err
+	}
+	d.emitChanges(customerID, before, restored, author)
+	return restored, nil
+}
+
+// Patch emits an event for the captions if they changed
+func (d *Poster) Patch(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+	before := d.snapshot(customerID, postID)
+	patched, err := d.next.Patch(customerID, postID, patch, author)
+	if err != nil {
+		return nil, err
+	}
+	d.emitChanges(customerID, before, patched, author)
+	return patched, nil
+}
+
+// Delete emits a deleted event
+func (d *Poster) Delete(customerID string, postID bson.ObjectId, author string) error {
+	if err := d.next.Delete(customerID, postID, author); err != nil {
+		return err
+	}
+	d.emit(events.NewEvent(events.TypeDeleted, customerID, postID, author, nil))
+	return nil
+}
+
+// Schedule calls the underlying Poster, scheduling changes neither the captions
+// nor the status
+func (d *Poster) Schedule(customerID string, post *dao.Post) (*dao.Post, error) {
+	return d.next.Schedule(customerID, post)
+}
+
+// ClaimDue emits a status_changed event for every claimed post
+func (d *Poster) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*dao.Post, error) {
+	posts, err := d.next.ClaimDue(now, lease, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		d.emit(events.NewEvent(events.TypeStatusChanged, post.CustID, *post.ID, dao.ActorScheduler, post))
+	}
+	return posts, nil
+}
+
+// CompletePublish emits a status_changed event for the published or failed post
+func (d *Poster) CompletePublish(customerID string, postID bson.ObjectId, publications []*dao.Publication) (*dao.Post, error) {
+	before := d.snapshot(customerID, postID)
+	post, err := d.next.CompletePublish(customerID, postID, publications)
+	if err != nil {
+		return nil, err
+	}
+	d.emitChanges(customerID, before, post, dao.ActorScheduler)
+	return post, nil
+}
+
+// snapshot copies the post as it is before a change. Without one, every field is
+// treated as changed
+func (d *Poster) snapshot(customerID string, postID bson.ObjectId) *dao.Post {
+	post, err := d.next.Get(customerID, postID)
+	if err != nil || post == nil {
+		return nil
+	}
+	before := *post
+	before.Captions = dao.CopyCaptions(post.Captions)
+	return &before
+}
+
+func (d *Poster) emitChanges(customerID string, before, after *dao.Post, actor string) {
+	if before == nil || !reflect.DeepEqual(normalize(before.Captions), normalize(after.Captions)) {
+		d.emit(events.NewEvent(events.TypeCaptionsUpdated, customerID, *after.ID, actor, after))
+	}
+	if before == nil || before.Status != after.Status {
+		d.emit(events.NewEvent(events.TypeStatusChanged, customerID, *after.ID, actor, after))
+	}
+}
+
+func (d *Poster) emit(e *events.Event) {
+	if _, err := d.outbox.Append(e); err != nil {
+		d.logger.WithFields(log.Fields{
+			"post_id": e.PostID.Hex(),
+			"type":    e.Type,
+			"error":   err.Error(),
+		}).Error("failed to append event to outbox")
+	}
+}
+
+// normalize treats nil and empty captions the same
+func normalize(captions []*dao.Caption) []*dao.Caption {
+	if len(captions) == 0 {
+		return nil
+	}
+	return captions
+}
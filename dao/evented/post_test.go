@@ -0,0 +1,272 @@
+package evented
+
+import (
+	"errors"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/events"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+)
+
+var _ = Describe("Poster", func() {
+	var (
+		logger   *log.Logger
+		p        *Poster
+		mockNext *mock_dao.MockPoster
+		mockCtrl *gomock.Controller
+		outbox   *events.InMemoryLog
+
+		customerID string
+		post       *dao.Post
+		postID     bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		mockNext = mock_dao.NewMockPoster(mockCtrl)
+		outbox = events.NewInMemoryLog(logger)
+		p = NewPoster(logger, mockNext, outbox)
+
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+		post = &dao.Post{
+			ID:        &postID,
+			URL:       "https://example.com/post",
+			Captions:  dao.ManualCaptions([]string{"caption1"}),
+			Status:    dao.StatusDraft,
+			UpdatedBy: "alice",
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	emitted := func() []string {
+		feed, err := outbox.Read(customerID, 0, 0)
+		Expect(err).To(BeNil())
+		types := []string{}
+		for _, e := range feed {
+			Expect(e.PostID).To(Equal(postID))
+			types = append(types, e.Type)
+		}
+		return types
+	}
+
+	Describe("Insert", func() {
+		Context("with datastore error", func() {
+			BeforeEach(func() {
+				mockNext.EXPECT().Insert(customerID, post).Return(nil, errors.New("test-error"))
+			})
+
+			It("should NOT emit an event", func() {
+				_, err := p.Insert(customerID, post)
+				Expect(err).NotTo(BeNil())
+				Expect(emitted()).To(BeEmpty())
+			})
+		})
+
+		Context("without datastore error", func() {
+			BeforeEach(func() {
+				mockNext.EXPECT().Insert(customerID, post).Return(post, nil)
+			})
+
+			It("should emit a created event with the post", func() {
+				retPost, err := p.Insert(customerID, post)
+				Expect(err).To(BeNil())
+				Expect(retPost).To(Equal(post))
+				Expect(emitted()).To(Equal([]string{events.TypeCreated}))
+
+				feed, _ := outbox.Read(customerID, 0, 0)
+				Expect(feed[0].Actor).To(Equal("alice"))
+				Expect(feed[0].Post).To(Equal(post))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		var updated *dao.Post
+
+		BeforeEach(func() {
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil)
+			copied := *post
+			updated = &copied
+		})
+
+		JustBeforeEach(func() {
+			mockNext.EXPECT().Update(customerID, updated).Return(updated, nil)
+			_, err := p.Update(customerID, updated)
+			Expect(err).To(BeNil())
+		})
+
+		Context("with new captions", func() {
+			BeforeEach(func() {
+				updated.Captions = dao.ManualCaptions([]string{"caption2"})
+			})
+
+			It("should emit a captions_updated event", func() {
+				Expect(emitted()).To(Equal([]string{events.TypeCaptionsUpdated}))
+			})
+		})
+
+		Context("with new status", func() {
+			BeforeEach(func() {
+				updated.Status = dao.StatusApproved
+			})
+
+			It("should emit a status_changed event", func() {
+				Expect(emitted()).To(Equal([]string{events.TypeStatusChanged}))
+			})
+		})
+
+		Context("with new captions and status", func() {
+			BeforeEach(func() {
+				updated.Captions = nil
+				updated.Status = dao.StatusApproved
+			})
+
+			It("should emit both events", func() {
+				Expect(emitted()).To(Equal([]string{events.TypeCaptionsUpdated, events.TypeStatusChanged}))
+			})
+		})
+
+		Context("without changes", func() {
+			It("should NOT emit an event", func() {
+				Expect(emitted()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Restore", func() {
+		BeforeEach(func() {
+			approved := *post
+			approved.Status = dao.StatusApproved
+			mockNext.EXPECT().Get(customerID, postID).Return(&approved, nil)
+			restored := *post
+			restored.Captions = dao.ManualCaptions([]string{"caption0"})
+			mockNext.EXPECT().Restore(customerID, postID, 1, "bob").Return(&restored, nil)
+		})
+
+		It("should emit an event for each change", func() {
+			_, err := p.Restore(customerID, postID, 1, "bob")
+			Expect(err).To(BeNil())
+			Expect(emitted()).To(Equal([]string{events.TypeCaptionsUpdated, events.TypeStatusChanged}))
+			feed, _ := outbox.Read(customerID, 0, 0)
+			Expect(feed[0].Actor).To(Equal("bob"))
+		})
+	})
+
+	Describe("Patch", func() {
+		BeforeEach(func() {
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil)
+			patched := *post
+			patched.Captions = dao.ManualCaptions([]string{"caption1", "caption2"})
+			mockNext.EXPECT().Patch(customerID, postID, gomock.Any(), "bob").Return(&patched, nil)
+		})
+
+		It("should emit an event for the captions", func() {
+			_, err := p.Patch(customerID, postID, func(post *dao.Post) (*dao.Post, error) { return post, nil }, "bob")
+			Expect(err).To(BeNil())
+			Expect(emitted()).To(Equal([]string{events.TypeCaptionsUpdated}))
+		})
+	})
+
+	Describe("Delete", func() {
+		Context("with datastore error", func() {
+			BeforeEach(func() {
+				mockNext.EXPECT().Delete(customerID, postID, "alice").Return(errors.New("test-error"))
+			})
+
+			It("should NOT emit an event", func() {
+				Expect(p.Delete(customerID, postID, "alice")).NotTo(BeNil())
+				Expect(emitted()).To(BeEmpty())
+			})
+		})
+
+		Context("without datastore error", func() {
+			BeforeEach(func() {
+				mockNext.EXPECT().Delete(customerID, postID, "alice").Return(nil)
+			})
+
+			It("should emit a deleted event without the post", func() {
+				Expect(p.Delete(customerID, postID, "alice")).To(BeNil())
+				Expect(emitted()).To(Equal([]string{events.TypeDeleted}))
+				feed, _ := outbox.Read(customerID, 0, 0)
+				Expect(feed[0].Post).To(BeNil())
+				Expect(feed[0].Actor).To(Equal("alice"))
+			})
+		})
+	})
+
+	Describe("Schedule", func() {
+		It("should NOT emit an event", func() {
+			mockNext.EXPECT().Schedule(customerID, post).Return(post, nil)
+			_, err := p.Schedule(customerID, post)
+			Expect(err).To(BeNil())
+			Expect(emitted()).To(BeEmpty())
+		})
+	})
+
+	Describe("ClaimDue", func() {
+		It("should emit a status_changed event for each claimed post", func() {
+			now := time.Now()
+			claimed := *post
+			claimed.CustID = customerID
+			claimed.Status = dao.StatusPublishing
+			mockNext.EXPECT().ClaimDue(now, time.Minute, 10).Return([]*dao.Post{&claimed}, nil)
+
+			_, err := p.ClaimDue(now, time.Minute, 10)
+			Expect(err).To(BeNil())
+			Expect(emitted()).To(Equal([]string{events.TypeStatusChanged}))
+			feed, _ := outbox.Read(customerID, 0, 0)
+			Expect(feed[0].Actor).To(Equal(dao.ActorScheduler))
+		})
+	})
+
+	Describe("CompletePublish", func() {
+		It("should emit a status_changed event", func() {
+			publishing := *post
+			publishing.Status = dao.StatusPublishing
+			mockNext.EXPECT().Get(customerID, postID).Return(&publishing, nil)
+			published := *post
+			published.Status = dao.StatusPublished
+			mockNext.EXPECT().CompletePublish(customerID, postID, nil).Return(&published, nil)
+
+			_, err := p.CompletePublish(customerID, postID, nil)
+			Expect(err).To(BeNil())
+			Expect(emitted()).To(Equal([]string{events.TypeStatusChanged}))
+		})
+	})
+
+	Describe("InsertBatch", func() {
+		It("should emit a created event for every inserted post", func() {
+			mockNext.EXPECT().InsertBatch(customerID, []*dao.Post{post, post}).Return([]*dao.BatchResult{{Post: post}, {Err: errors.New("test-error")}}, nil)
+			_, err := p.InsertBatch(customerID, []*dao.Post{post, post})
+			Expect(err).To(BeNil())
+			Expect(emitted()).To(Equal([]string{events.TypeCreated}))
+		})
+	})
+
+	Describe("UpdateBatch", func() {
+		It("should emit the changes of every updated post", func() {
+			copied := *post
+			updated := &copied
+			updated.Captions = dao.ManualCaptions([]string{"caption2"})
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil)
+			mockNext.EXPECT().UpdateBatch(customerID, []*dao.Post{updated}).Return([]*dao.BatchResult{{Post: updated}}, nil)
+			_, err := p.UpdateBatch(customerID, []*dao.Post{updated})
+			Expect(err).To(BeNil())
+			Expect(emitted()).To(Equal([]string{events.TypeCaptionsUpdated}))
+		})
+	})
+})
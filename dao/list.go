@@ -0,0 +1,105 @@
+package dao
+
+import (
+	"time"
+)
+
+// Times posts can be listed by
+const (
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+)
+
+// ListFilter selects the posts that are listed. Zero fields match every post
+type ListFilter struct {
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	CreatedBy     string
+	UpdatedBy     string
+}
+
+// ValidSort returns true if posts can be listed by the sort, an empty sort lists
+// them in id order
+func ValidSort(sort string) bool {
+	switch sort {
+	case "", SortCreatedAt, SortUpdatedAt:
+		return true
+	default:
+		return false
+	}
+}
+
+// Match returns true if the post passes every set field of the filter. The
+// after and before times are exclusive
+func (f ListFilter) Match(post *Post) bool {
+	created, updated := timeOf(post.CreatedAt), timeOf(post.UpdatedAt)
+	switch {
+	case !f.CreatedAfter.IsZero() && !created.After(f.CreatedAfter):
+		return false
+	case !f.CreatedBefore.IsZero() && !created.Before(f.CreatedBefore):
+		return false
+	case !f.UpdatedAfter.IsZero() && !updated.After(f.UpdatedAfter):
+		return false
+	case !f.UpdatedBefore.IsZero() && !updated.Before(f.UpdatedBefore):
+		return false
+	case f.CreatedBy != "" && post.CreatedBy != f.CreatedBy:
+		return false
+	case f.UpdatedBy != "" && post.UpdatedBy != f.UpdatedBy:
+		return false
+	}
+	return true
+}
+
+// SortTime returns the time the post is sorted by, the zero time when the posts
+// are listed in id order
+func (o ListOptions) SortTime(post *Post) time.Time {
+	switch o.Sort {
+	case SortCreatedAt:
+		return timeOf(post.CreatedAt)
+	case SortUpdatedAt:
+		return timeOf(post.UpdatedAt)
+	default:
+		return time.Time{}
+	}
+}
+
+// Less returns true if a is listed before b. Posts with the same sort time are
+// listed in id order
+func (o ListOptions) Less(a, b *Post) bool {
+	if o.Descending {
+		a, b = b, a
+	}
+	at, bt := o.SortTime(a), o.SortTime(b)
+	if !at.Equal(bt) {
+		return at.Before(bt)
+	}
+	return *a.ID < *b.ID
+}
+
+// Listed returns true if the post matches the filter and comes after the last
+// post of the previous page
+func (o ListOptions) Listed(post *Post) bool {
+	if !o.Filter.Match(post) {
+		return false
+	}
+	if o.After == "" {
+		return true
+	}
+	last := &Post{ID: &o.After}
+	switch o.Sort {
+	case SortCreatedAt:
+		last.CreatedAt = &o.AfterTime
+	case SortUpdatedAt:
+		last.UpdatedAt = &o.AfterTime
+	}
+	return o.Less(last, post)
+}
+
+func timeOf(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+)
+
+var _ = Describe("ListOptions", func() {
+	var (
+		base   time.Time
+		first  *Post
+		second *Post
+	)
+
+	newPost := func(created, updated time.Time, author string) *Post {
+		id := bson.NewObjectId()
+		return &Post{ID: &id, CreatedAt: &created, CreatedBy: author, UpdatedAt: &updated, UpdatedBy: author}
+	}
+
+	BeforeEach(func() {
+		base = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		first = newPost(base, base.Add(2*time.Hour), "alice")
+		second = newPost(base.Add(time.Hour), base.Add(time.Hour), "bob")
+	})
+
+	It("should list posts in id order by default", func() {
+		opts := ListOptions{}
+		Expect(opts.Less(first, second)).To(BeTrue())
+		Expect(opts.Less(second, first)).To(BeFalse())
+	})
+
+	It("should list posts by their sort time", func() {
+		cases := []struct {
+			opts        ListOptions
+			firstBefore bool
+		}{
+			{ListOptions{Sort: SortCreatedAt}, true},
+			{ListOptions{Sort: SortCreatedAt, Descending: true}, false},
+			{ListOptions{Sort: SortUpdatedAt}, false},
+			{ListOptions{Sort: SortUpdatedAt, Descending: true}, true},
+		}
+		for _, c := range cases {
+			Expect(c.opts.Less(first, second)).To(Equal(c.firstBefore), c.opts.Sort)
+			Expect(c.opts.Less(second, first)).To(Equal(!c.firstBefore), c.opts.Sort)
+		}
+	})
+
+	It("should list posts with the same sort time in id order", func() {
+		second.CreatedAt = first.CreatedAt
+		opts := ListOptions{Sort: SortCreatedAt}
+		Expect(opts.Less(first, second)).To(BeTrue())
+	})
+
+	It("should only list posts after the cursor", func() {
+		opts := ListOptions{Sort: SortUpdatedAt, After: *second.ID, AfterTime: *second.UpdatedAt}
+		Expect(opts.Listed(first)).To(BeTrue())
+		Expect(opts.Listed(second)).To(BeFalse())
+
+		opts = ListOptions{Sort: SortUpdatedAt, Descending: true, After: *first.ID, AfterTime: *first.UpdatedAt}
+		Expect(opts.Listed(first)).To(BeFalse())
+		Expect(opts.Listed(second)).To(BeTrue())
+	})
+
+	It("should filter posts", func() {
+		cases := []struct {
+			filter ListFilter
+			first  bool
+			second bool
+		}{
+			{ListFilter{}, true, true},
+			{ListFilter{CreatedAfter: base}, false, true},
+			{ListFilter{CreatedBefore: base.Add(time.Hour)}, true, false},
+			{ListFilter{UpdatedAfter: base.Add(time.Hour)}, true, false},
+			{ListFilter{UpdatedBefore: base.Add(2 * time.Hour)}, false, true},
+			{ListFilter{CreatedBy: "alice"}, true, false},
+			{ListFilter{UpdatedBy: "bob"}, false, true},
+		}
+		for i, c := range cases {
+			Expect(c.filter.Match(first)).To(Equal(c.first), "case %d", i)
+			Expect(c.filter.Match(second)).To(Equal(c.second), "case %d", i)
+		}
+	})
+})
@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"labix.org/v2/mgo/bson"
 
@@ -28,12 +30,78 @@ func (d *Poster) Insert(customerID string, post *dao.Post) (*dao.Post, error) {
 	return d.ds.Insert(customerID, post)
 }
 
+// InsertBatch handles batch insert requests using the underlying in memory datastore
+func (d *Poster) InsertBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	d.logger.Debug("in-memory insert batch")
+	return d.ds.InsertBatch(customerID, posts)
+}
+
+// UpdateBatch handles batch update requests using the underlying in memory datastore
+func (d *Poster) UpdateBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	d.logger.Debug("in-memory update batch")
+	return d.ds.UpdateBatch(customerID, posts)
+}
+
 // Get handles post get requests using the underlying in memory datastore
 func (d *Poster) Get(customerID string, postID bson.ObjectId) (*dao.Post, error) {
 	d.logger.Debug("in-memory get")
 	return d.ds.Get(customerID, postID)
 }
 
+// GetByURL handles post get by url requests using the underlying in memory datastore
+func (d *Poster) GetByURL(customerID string, canonicalURL string) (*dao.Post, error) {
+	d.logger.Debug("in-memory get by url")
+	return d.ds.GetByURL(customerID, canonicalURL)
+}
+
+// List handles post list requests using the underlying in memory datastore
+func (d *Poster) List(customerID string, opts dao.ListOptions) ([]*dao.Post, error) {
+	d.logger.Debug("in-memory list")
+	return d.ds.List(customerID, opts)
+}
+
+// Revisions handles post revisions requests using the underlying in memory datastore
+func (d *Poster) Revisions(customerID string, postID bson.ObjectId) ([]*dao.Revision, error) {
+	d.logger.Debug("in-memory revisions")
+	return d.ds.Revisions(customerID, postID)
+}
+
+// Restore handles post restore requests using the underlying in memory datastore
+func (d *Poster) Restore(customerID string, postID bson.ObjectId, number int, author string) (*dao.Post, error) {
+	d.logger.Debug("in-memory restore")
+	return d.ds.Restore(customerID, postID, number, author)
+}
+
+// Patch handles post patch requests using the underlying in memory datastore
+func (d *Poster) Patch(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+	d.logger.Debug("in-memory patch")
+	return d.ds.Patch(customerID, postID, patch, author)
+}
+
+// Delete handles post delete requests using the underlying in memory datastore
+func (d *Poster) Delete(customerID string, postID bson.ObjectId, author string) error {
+	d.logger.Debug("in-memory delete")
+	return d.ds.Delete(customerID, postID)
+}
+
+// Schedule handles post schedule requests using the underlying in memory datastore
+func (d *Poster) Schedule(customerID string, post *dao.Post) (*dao.Post, error) {
+	d.logger.Debug("in-memory schedule")
+	return d.ds.Schedule(customerID, post)
+}
+
+// ClaimDue handles claiming due posts using the underlying in memory datastore
+func (d *Poster) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*dao.Post, error) {
+	d.logger.Debug("in-memory claim due")
+	return d.ds.ClaimDue(now, lease, limit)
+}
+
+// CompletePublish handles recording publications using the underlying in memory datastore
+func (d *Poster) CompletePublish(customerID string, postID bson.ObjectId, publications []*dao.Publication) (*dao.Post, error) {
+	d.logger.Debug("in-memory complete publish")
+	return d.ds.CompletePublish(customerID, postID, publications)
+}
+
 // Update handles post update requests using the underlying in memory datastore
 func (d *Poster) Update(customerID string, postID *dao.Post) (*dao.Post, error) {
 	d.logger.Debug("in-memory update")
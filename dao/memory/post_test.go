@@ -3,6 +3,7 @@ package memory
 import (
 	"errors"
 	"io/ioutil"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
@@ -39,11 +40,11 @@ var _ = Describe("Poster", func() {
 			ID:     &postID,
 			CustID: customerID,
 			URL:    "test-url",
-			Captions: []string{
+			Captions: dao.ManualCaptions([]string{
 				"caption1",
 				"caption2",
 				"caption3",
-			},
+			}),
 		}
 	})
 
@@ -139,6 +140,50 @@ var _ = Describe("Poster", func() {
 		})
 	})
 
+	Describe("GetByURL", func() {
+		var (
+			retPost *dao.Post
+			err     error
+		)
+
+		JustBeforeEach(func() {
+			retPost, err = p.GetByURL(customerID, "https://example.com/post")
+		})
+
+		Context("with datastore error", func() {
+			var (
+				dsErr error
+			)
+			BeforeEach(func() {
+				dsErr = errors.New("test-error")
+				mockDs.EXPECT().GetByURL(customerID, "https://example.com/post").Return(nil, dsErr)
+			})
+
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err).To(Equal(dsErr))
+			})
+
+			It("should NOT return a post", func() {
+				Expect(retPost).To(BeNil())
+			})
+		})
+
+		Context("without datastore error", func() {
+			BeforeEach(func() {
+				mockDs.EXPECT().GetByURL(customerID, "https://example.com/post").Return(post, nil)
+			})
+
+			It("should NOT return an error", func() {
+				Expect(err).To(BeNil())
+			})
+
+			It("should return a post", func() {
+				Expect(retPost).To(Equal(post))
+			})
+		})
+	})
+
 	Describe("Update", func() {
 		var (
 			retPost *dao.Post
@@ -182,4 +227,99 @@ var _ = Describe("Poster", func() {
 			})
 		})
 	})
+
+	Describe("Revisions", func() {
+		It("should return the datastore revisions", func() {
+			revisions := []*dao.Revision{{Number: 1, PostID: postID}}
+			mockDs.EXPECT().Revisions(customerID, postID).Return(revisions, nil)
+			retRevisions, err := p.Revisions(customerID, postID)
+			Expect(err).To(BeNil())
+			Expect(retRevisions).To(Equal(revisions))
+		})
+	})
+
+	Describe("Restore", func() {
+		It("should return the restored post", func() {
+			mockDs.EXPECT().Restore(customerID, postID, 1, "author").Return(post, nil)
+			retPost, err := p.Restore(customerID, postID, 1, "author")
+			Expect(err).To(BeNil())
+			Expect(retPost).To(Equal(post))
+		})
+	})
+
+	Describe("Patch", func() {
+		It("should return the patched post", func() {
+			mockDs.EXPECT().Patch(customerID, postID, gomock.Any(), "author").Return(post, nil)
+			retPost, err := p.Patch(customerID, postID, func(post *dao.Post) (*dao.Post, error) { return post, nil }, "author")
+			Expect(err).To(BeNil())
+			Expect(retPost).To(Equal(post))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("should return the datastore error", func() {
+			dsErr := errors.New("test-error")
+			mockDs.EXPECT().Delete(customerID, postID).Return(dsErr)
+			Expect(p.Delete(customerID, postID, "alice")).To(Equal(dsErr))
+		})
+	})
+
+	Describe("Schedule", func() {
+		It("should return the scheduled post", func() {
+			mockDs.EXPECT().Schedule(customerID, post).Return(post, nil)
+			retPost, err := p.Schedule(customerID, post)
+			Expect(err).To(BeNil())
+			Expect(retPost).To(Equal(post))
+		})
+	})
+
+	Describe("ClaimDue", func() {
+		It("should return the claimed posts", func() {
+			now := time.Now()
+			mockDs.EXPECT().ClaimDue(now, time.Minute, 10).Return([]*dao.Post{post}, nil)
+			posts, err := p.ClaimDue(now, time.Minute, 10)
+			Expect(err).To(BeNil())
+			Expect(posts).To(Equal([]*dao.Post{post}))
+		})
+	})
+
+	Describe("CompletePublish", func() {
+		It("should return the published post", func() {
+			publications := []*dao.Publication{{Channel: "twitter"}}
+			mockDs.EXPECT().CompletePublish(customerID, postID, publications).Return(post, nil)
+			retPost, err := p.CompletePublish(customerID, postID, publications)
+			Expect(err).To(BeNil())
+			Expect(retPost).To(Equal(post))
+		})
+	})
+
+	Describe("InsertBatch", func() {
+		It("should return the datastore results", func() {
+			results := []*dao.BatchResult{{Post: post}}
+			mockDs.EXPECT().InsertBatch(customerID, []*dao.Post{post}).Return(results, nil)
+			retResults, err := p.InsertBatch(customerID, []*dao.Post{post})
+			Expect(err).To(BeNil())
+			Expect(retResults).To(Equal(results))
+		})
+	})
+
+	Describe("UpdateBatch", func() {
+		It("should return the datastore results", func() {
+			results := []*dao.BatchResult{{Post: post}}
+			mockDs.EXPECT().UpdateBatch(customerID, []*dao.Post{post}).Return(results, nil)
+			retResults, err := p.UpdateBatch(customerID, []*dao.Post{post})
+			Expect(err).To(BeNil())
+			Expect(retResults).To(Equal(results))
+		})
+	})
+
+	Describe("List", func() {
+		It("should return the datastore posts", func() {
+			opts := dao.ListOptions{After: postID, Limit: 10}
+			mockDs.EXPECT().List(customerID, opts).Return([]*dao.Post{post}, nil)
+			posts, err := p.List(customerID, opts)
+			Expect(err).To(BeNil())
+			Expect(posts).To(Equal([]*dao.Post{post}))
+		})
+	})
 })
@@ -1,20 +1,137 @@
 package dao
 
 import (
+	"time"
+
 	"labix.org/v2/mgo/bson"
 )
 
+// Statuses a post moves through
+const (
+	StatusDraft         = "draft"
+	StatusApproved      = "approved"
+	StatusPublishing    = "publishing"
+	StatusPublished     = "published"
+	StatusPublishFailed = "publish_failed"
+)
+
+// ActorScheduler is recorded as the author of changes made by the scheduler
+const ActorScheduler = "scheduler"
+
 // Post stores in the information about a url
 type Post struct {
-	ID       *bson.ObjectId `json:"id,omitempty"`
-	CustID   string         `json:"-"` // do not return when we marshal to json
-	URL      string         `json:"url"`
-	Captions []string       `json:"captions,omitempty"`
+	ID           *bson.ObjectId `json:"id,omitempty"`
+	CustID       string         `json:"-"` // do not return when we marshal to json
+	URL          string         `json:"url"`
+	CanonicalURL string         `json:"canonical_url,omitempty"`
+	Captions     []*Caption     `json:"captions,omitempty"`
+	// RejectedCaptions are the generated captions quality rules kept out of the
+	// post, they are set when the post is created
+	RejectedCaptions []*Rejection `json:"rejected_captions,omitempty"`
+	Status           string       `json:"status,omitempty"`
+	// CreatedAt, UpdatedAt and the authors are set by the datastore
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	// ScheduledAt is when an approved post is published to its Channels
+	ScheduledAt  *time.Time     `json:"scheduled_at,omitempty"`
+	Channels     []string       `json:"channels,omitempty"`
+	PublishedAt  *time.Time     `json:"published_at,omitempty"`
+	Publications []*Publication `json:"publications,omitempty"`
+	// ClaimedUntil is when the claim of a post being published runs out. It is
+	// only kept by the datastore, a post being published without one was
+	// claimed before a restart
+	ClaimedUntil *time.Time `json:"-"`
+}
+
+// Publication is the outcome of publishing a post to one channel
+type Publication struct {
+	Channel    string    `json:"channel"`
+	At         time.Time `json:"at"`
+	ExternalID string    `json:"external_id,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// BatchResult is the outcome of writing one post of a batch. Err is set when the
+// post was not written
+type BatchResult struct {
+	Post *Post
+	Err  error
+}
+
+// PatchFunc changes a copy of a stored post. It returns the post to store, or an
+// error to leave the stored post as it is
+type PatchFunc func(*Post) (*Post, error)
+
+// ListOptions selects a page of a customer's posts. Posts are listed in id order,
+// which is the order they were created in, unless Sort is set
+type ListOptions struct {
+	// After is the id of the last post of the previous page
+	After bson.ObjectId
+	// AfterTime is the Sort time of the last post of the previous page
+	AfterTime  time.Time
+	Limit      int
+	Sort       string
+	Descending bool
+	Filter     ListFilter
+}
+
+// ValidStatus returns true if the status is one a post can be in
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusDraft, StatusApproved, StatusPublishing, StatusPublished, StatusPublishFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanTransition returns true if a change of a post may move it from one status
+// to another. Drafts, and posts stored before they had a status, can be
+// approved, and so can posts that failed to publish, which publishes them
+// again. Every other status is only set by the scheduler
+func CanTransition(from, to string) bool {
+	switch from {
+	case "", StatusDraft, StatusPublishFailed:
+		return to == StatusApproved
+	default:
+		return false
+	}
 }
 
 // Poster defines the interface for persisting posts
 type Poster interface {
 	Insert(string, *Post) (*Post, error)
+	// InsertBatch inserts the posts, the results are in the same order. An error
+	// is only returned when none of the posts could be inserted
+	InsertBatch(string, []*Post) ([]*BatchResult, error)
 	Get(string, bson.ObjectId) (*Post, error)
 	Update(string, *Post) (*Post, error)
+	// UpdateBatch updates the posts like InsertBatch inserts them
+	UpdateBatch(string, []*Post) ([]*BatchResult, error)
+	GetByURL(string, string) (*Post, error)
+	// List returns a page of the customer's posts
+	List(string, ListOptions) ([]*Post, error)
+	Revisions(string, bson.ObjectId) ([]*Revision, error)
+	Restore(string, bson.ObjectId, int, string) (*Post, error)
+	// Patch applies the patch to the stored post atomically, no other change is
+	// made between reading and storing it. Only the captions and status are taken
+	// from the patched post
+	Patch(string, bson.ObjectId, PatchFunc, string) (*Post, error)
+	// Delete deletes the post and its revisions, the author is who deleted it
+	Delete(string, bson.ObjectId, string) error
+	// Schedule sets when and where the post is published, a nil ScheduledAt
+	// unschedules it
+	Schedule(string, *Post) (*Post, error)
+	// ClaimDue moves up to limit approved posts, of every customer, that are
+	// scheduled at or before the time to publishing and returns them. The move is
+	// a compare and set of the status, so a post is only claimed once while its
+	// claim holds, even by instances that share the store. A claim holds until
+	// lease has passed, after which a post that is still publishing is claimed
+	// again, so a publisher that stopped does not leave it publishing forever.
+	// Posts returned with an error were claimed all the same
+	ClaimDue(time.Time, time.Duration, int) ([]*Post, error)
+	// CompletePublish records the outcome of publishing a claimed post
+	CompletePublish(string, bson.ObjectId, []*Publication) (*Post, error)
 }
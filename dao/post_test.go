@@ -0,0 +1,30 @@
+package dao
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CanTransition", func() {
+	It("should only allow approving drafts and posts that failed to publish", func() {
+		cases := []struct {
+			from string
+			to   string
+			ok   bool
+		}{
+			{"", StatusApproved, true},
+			{StatusDraft, StatusApproved, true},
+			{StatusPublishFailed, StatusApproved, true},
+			{StatusApproved, StatusApproved, false},
+			{StatusPublishing, StatusApproved, false},
+			{StatusPublished, StatusApproved, false},
+			{StatusApproved, StatusDraft, false},
+			{StatusDraft, StatusPublishing, false},
+			{StatusDraft, StatusPublished, false},
+			{StatusPublishFailed, StatusPublished, false},
+		}
+		for _, c := range cases {
+			Expect(CanTransition(c.from, c.to)).To(Equal(c.ok), "%q to %q", c.from, c.to)
+		}
+	})
+})
@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"time"
+
+	"labix.org/v2/mgo/bson"
+)
+
+// Caption change operations
+const (
+	OpAdded   = "added"
+	OpRemoved = "removed"
+)
+
+// Revision is a version of a post's captions. Every insert, update and restore
+// creates one
+type Revision struct {
+	Number    int             `json:"number"`
+	PostID    bson.ObjectId   `json:"post_id"`
+	Author    string          `json:"author,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	Captions  []*Caption      `json:"captions"`
+	Status    string          `json:"status,omitempty"`
+	Diff      []CaptionChange `json:"diff"`
+}
+
+// CaptionChange is a caption added or removed compared to the previous revision.
+// Index is the position in the new captions for an added caption, and in the old
+// captions for a removed one
+type CaptionChange struct {
+	Op      string `json:"op"`
+	Index   int    `json:"index"`
+	Caption string `json:"caption"`
+}
+
+// DiffCaptions returns the captions removed from old and added in new, keeping
+// the longest run of captions common to both. Removals come before additions
+func DiffCaptions(old, new []string) []CaptionChange {
+	// lcs[i][j] is the length of the longest common subsequence of old[i:], new[j:]
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	changes := []CaptionChange{}
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			i++
+			j++
+		case i < len(old) && (j == len(new) || lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, CaptionChange{Op: OpRemoved, Index: i, Caption: old[i]})
+			i++
+		default:
+			changes = append(changes, CaptionChange{Op: OpAdded, Index: j, Caption: new[j]})
+			j++
+		}
+	}
+	return changes
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiffCaptions", func() {
+	It("should return no changes for the same captions", func() {
+		Expect(DiffCaptions([]string{"a", "b"}, []string{"a", "b"})).To(BeEmpty())
+	})
+
+	It("should add every caption to nothing", func() {
+		Expect(DiffCaptions(nil, []string{"a", "b"})).To(Equal([]CaptionChange{
+			{Op: OpAdded, Index: 0, Caption: "a"},
+			{Op: OpAdded, Index: 1, Caption: "b"},
+		}))
+	})
+
+	It("should remove every caption", func() {
+		Expect(DiffCaptions([]string{"a", "b"}, nil)).To(Equal([]CaptionChange{
+			{Op: OpRemoved, Index: 0, Caption: "a"},
+			{Op: OpRemoved, Index: 1, Caption: "b"},
+		}))
+	})
+
+	It("should keep the captions common to both", func() {
+		Expect(DiffCaptions([]string{"a", "b", "c", "d"}, []string{"a", "c", "e", "d"})).To(Equal([]CaptionChange{
+			{Op: OpRemoved, Index: 1, Caption: "b"},
+			{Op: OpAdded, Index: 2, Caption: "e"},
+		}))
+	})
+
+	It("should treat a reorder as a remove and an add", func() {
+		Expect(DiffCaptions([]string{"a", "b"}, []string{"b", "a"})).To(Equal([]CaptionChange{
+			{Op: OpRemoved, Index: 0, Caption: "a"},
+			{Op: OpAdded, Index: 1, Caption: "a"},
+		}))
+	})
+})
@@ -0,0 +1,192 @@
+package validated
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/validate"
+)
+
+// Poster implements the Poster interface by validating input before calling the
+// underlying Poster, so every caller gets the same rules as the http handlers
+type Poster struct {
+	logger    *log.Logger
+	next      dao.Poster
+	validator *validate.Validator
+}
+
+// NewPoster creates a new Poster with the supplied options
+func NewPoster(logger *log.Logger, next dao.Poster, validator *validate.Validator) *Poster {
+	return &Poster{
+		logger:    logger,
+		next:      next,
+		validator: validator,
+	}
+}
+
+// Insert validates and normalizes the url and captions before inserting
+func (d *Poster) Insert(customerID string, post *dao.Post) (*dao.Post, error) {
+	if post == nil {
+		return d.next.Insert(customerID, post)
+	}
+
+	input := *post
+	if err := d.validator.Post(&input); err != nil {
+		d.logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Info("invalid post")
+		return nil, err
+	}
+	return d.next.Insert(customerID, &input)
+}
+
+// InsertBatch validates every post like Insert. Invalid posts get their
+// validation error and only the valid ones are inserted
+func (d *Poster) InsertBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	return d.batch(customerID, posts, d.validator.Post, d.next.InsertBatch)
+}
+
+// UpdateBatch validates every post like Update. Invalid posts get their
+// validation error and only the valid ones are updated
+func (d *Poster) UpdateBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	return d.batch(customerID, posts, d.validator.Update, d.next.UpdateBatch)
+}
+
+func (d *Poster) batch(customerID string, posts []*dao.Post, validate func(*dao.Post) error, write func(string, []*dao.Post) ([]*dao.BatchResult, error)) ([]*dao.BatchResult, error) {
+	results := make([]*dao.BatchResult, len(posts))
+	valid := []*dao.Post{}
+	indexes := []int{}
+	for i, post := range posts {
+		if post == nil {
+			valid = append(valid, nil)
+			indexes = append(indexes, i)
+			continue
+		}
+		input := *post
+		if err := validate(&input); err != nil {
+			results[i] = &dao.BatchResult{Err: err}
+			continue
+		}
+		valid = append(valid, &input)
+		indexes = append(indexes, i)
+	}
+	if len(indexes) < len(posts) {
+		d.logger.WithFields(log.Fields{
+			"invalid": len(posts) - len(indexes),
+		}).Info("invalid posts in batch")
+	}
+
+	if len(valid) > 0 {
+		written, err := write(customerID, valid)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range indexes {
+			results[i] = written[j]
+		}
+	}
+	return results, nil
+}
+
+// Get calls the underlying Poster, there is nothing to validate
+func (d *Poster) Get(customerID string, postID bson.ObjectId) (*dao.Post, error) {
+	return d.next.Get(customerID, postID)
+}
+
+// GetByURL calls the underlying Poster, there is nothing to validate
+func (d *Poster) GetByURL(customerID string, canonicalURL string) (*dao.Post, error) {
+	return d.next.GetByURL(customerID, canonicalURL)
+}
+
+// List calls the underlying Poster, there is nothing to validate
+func (d *Poster) List(customerID string, opts dao.ListOptions) ([]*dao.Post, error) {
+	return d.next.List(customerID, opts)
+}
+
+// Revisions calls the underlying Poster, there is nothing to validate
+func (d *Poster) Revisions(customerID string, postID bson.ObjectId) ([]*dao.Revision, error) {
+	return d.next.Revisions(customerID, postID)
+}
+
+// Restore calls the underlying Poster, restored captions were validated when
+// they were first stored
+func (d *Poster) Restore(customerID string, postID bson.ObjectId, number int, author string) (*dao.Post, error) {
+	return d.next.Restore(customerID, postID, number, author)
+}
+
+// Patch validates and normalizes the captions of the patched post, the same way
+// Update does, before it is stored
+func (d *Poster) Patch(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+	if patch == nil {
+		return d.next.Patch(customerID, postID, patch, author)
+	}
+
+	return d.next.Patch(customerID, postID, func(post *dao.Post) (*dao.Post, error) {
+		patched, err := patch(post)
+		if err != nil {
+			return nil, err
+		}
+		if patched == nil {
+			return nil, nil
+		}
+		input := *patched
+		if err := d.validator.Update(&input); err != nil {
+			d.logger.WithFields(log.Fields{
+				"error": err.Error(),
+			}).Info("invalid patch")
+			return nil, err
+		}
+		return &input, nil
+	}, author)
+}
+
+// Delete calls the underlying Poster, there is nothing to validate
+func (d *Poster) Delete(customerID string, postID bson.ObjectId, author string) error {
+	return d.next.Delete(customerID, postID, author)
+}
+
+// Update validates and normalizes the captions before updating
+func (d *Poster) Update(customerID string, post *dao.Post) (*dao.Post, error) {
+	if post == nil {
+		return d.next.Update(customerID, post)
+	}
+
+	input := *post
+	if err := d.validator.Update(&input); err != nil {
+		d.logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Info("invalid post")
+		return nil, err
+	}
+	return d.next.Update(customerID, &input)
+}
+
+// Schedule validates and normalizes the time and channels before scheduling
+func (d *Poster) Schedule(customerID string, post *dao.Post) (*dao.Post, error) {
+	if post == nil {
+		return d.next.Schedule(customerID, post)
+	}
+
+	input := *post
+	if err := d.validator.Schedule(&input); err != nil {
+		d.logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Info("invalid schedule")
+		return nil, err
+	}
+	return d.next.Schedule(customerID, &input)
+}
+
+// ClaimDue calls the underlying Poster, there is nothing to validate
+func (d *Poster) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*dao.Post, error) {
+	return d.next.ClaimDue(now, lease, limit)
+}
+
+// CompletePublish calls the underlying Poster, publications are recorded by the
+// scheduler
+func (d *Poster) CompletePublish(customerID string, postID bson.ObjectId, publications []*dao.Publication) (*dao.Post, error) {
+	return d.next.CompletePublish(customerID, postID, publications)
+}
@@ -0,0 +1,302 @@
+package validated
+
+import (
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/validate"
+)
+
+var _ = Describe("Poster", func() {
+	var (
+		logger   *log.Logger
+		p        *Poster
+		mockNext *mock_dao.MockPoster
+		mockCtrl *gomock.Controller
+
+		customerID string
+		post       *dao.Post
+		postID     bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		mockNext = mock_dao.NewMockPoster(mockCtrl)
+		p = NewPoster(logger, mockNext, validate.NewValidator(validate.DefaultRules()))
+
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+		post = &dao.Post{
+			ID:       &postID,
+			URL:      " https://example.com/post ",
+			Captions: dao.ManualCaptions([]string{" caption1 ", "caption2"}),
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("Insert", func() {
+		var (
+			retPost *dao.Post
+			err     error
+		)
+
+		JustBeforeEach(func() {
+			retPost, err = p.Insert(customerID, post)
+		})
+
+		Context("with valid post", func() {
+			var (
+				expected *dao.Post
+			)
+
+			BeforeEach(func() {
+				expected = &dao.Post{
+					ID:       &postID,
+					URL:      "https://example.com/post",
+					Captions: dao.ManualCaptions([]string{"caption1", "caption2"}),
+				}
+				mockNext.EXPECT().Insert(customerID, expected).Return(expected, nil)
+			})
+
+			It("should insert the normalized post", func() {
+				Expect(err).To(BeNil())
+				Expect(retPost).To(Equal(expected))
+			})
+
+			It("should NOT modify the input", func() {
+				Expect(post.URL).To(Equal(" https://example.com/post "))
+			})
+		})
+
+		Context("with invalid post", func() {
+			BeforeEach(func() {
+				post.URL = "ftp://example.com"
+				post.Captions = dao.ManualCaptions([]string{""})
+			})
+
+			It("should return every invalid field", func() {
+				Expect(err).To(BeAssignableToTypeOf(&datastore.Validation{}))
+				Expect(err.Error()).To(Equal("validation failed: url: must be http or https; captions[0]: must not be empty"))
+			})
+
+			It("should NOT return a post", func() {
+				Expect(retPost).To(BeNil())
+			})
+		})
+	})
+
+	Describe("Get", func() {
+		BeforeEach(func() {
+			mockNext.EXPECT().Get(customerID, postID).Return(post, nil)
+		})
+
+		It("should call the underlying poster", func() {
+			retPost, err := p.Get(customerID, postID)
+			Expect(err).To(BeNil())
+			Expect(retPost).To(Equal(post))
+		})
+	})
+
+	Describe("Update", func() {
+		var (
+			retPost *dao.Post
+			err     error
+		)
+
+		JustBeforeEach(func() {
+			retPost, err = p.Update(customerID, post)
+		})
+
+		Context("with valid captions", func() {
+			BeforeEach(func() {
+				post.URL = ""
+				expected := &dao.Post{
+					ID:       &postID,
+					Captions: dao.ManualCaptions([]string{"caption1", "caption2"}),
+				}
+				mockNext.EXPECT().Update(customerID, expected).Return(expected, nil)
+			})
+
+			It("should update the normalized post", func() {
+				Expect(err).To(BeNil())
+				Expect(dao.CaptionTexts(retPost.Captions)).To(Equal([]string{"caption1", "caption2"}))
+			})
+		})
+
+		Context("with invalid captions", func() {
+			BeforeEach(func() {
+				post.Captions = dao.ManualCaptions([]string{"caption\x00"})
+			})
+
+			It("should return an error", func() {
+				Expect(err.Error()).To(Equal("validation failed: captions[0]: must not contain control characters"))
+				Expect(retPost).To(BeNil())
+			})
+		})
+
+		Context("with nil post", func() {
+			BeforeEach(func() {
+				post = nil
+				mockNext.EXPECT().Update(customerID, nil).Return(nil, datastore.NewInvalidArugmentError("must provide post"))
+			})
+
+			It("should return the underlying error", func() {
+				Expect(err.Error()).To(Equal("invalid must provide post"))
+			})
+		})
+	})
+
+	Describe("Patch", func() {
+		var (
+			captions []*dao.Caption
+			retPost  *dao.Post
+			err      error
+		)
+
+		BeforeEach(func() {
+			captions = dao.ManualCaptions([]string{" caption1 ", "caption2"})
+			mockNext.EXPECT().Patch(customerID, postID, gomock.Any(), "author").DoAndReturn(
+				func(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+					return patch(&dao.Post{ID: &postID, Captions: dao.ManualCaptions([]string{"caption0"})})
+				})
+		})
+
+		JustBeforeEach(func() {
+			retPost, err = p.Patch(customerID, postID, func(post *dao.Post) (*dao.Post, error) {
+				post.Captions = captions
+				return post, nil
+			}, "author")
+		})
+
+		It("should store the normalized captions", func() {
+			Expect(err).To(BeNil())
+			Expect(dao.CaptionTexts(retPost.Captions)).To(Equal([]string{"caption1", "caption2"}))
+		})
+
+		Context("with invalid captions", func() {
+			BeforeEach(func() {
+				captions = dao.ManualCaptions([]string{"caption\x00"})
+			})
+
+			It("should return an error", func() {
+				Expect(err.Error()).To(Equal("validation failed: captions[0]: must not contain control characters"))
+				Expect(retPost).To(BeNil())
+			})
+		})
+	})
+
+	Describe("Schedule", func() {
+		var (
+			scheduledAt time.Time
+			retPost     *dao.Post
+			err         error
+		)
+
+		BeforeEach(func() {
+			scheduledAt = time.Date(2020, 1, 2, 15, 0, 0, 0, time.UTC)
+			post = &dao.Post{
+				ID:          &postID,
+				ScheduledAt: &scheduledAt,
+				Channels:    []string{" Twitter "},
+			}
+		})
+
+		JustBeforeEach(func() {
+			retPost, err = p.Schedule(customerID, post)
+		})
+
+		Context("with valid channels", func() {
+			BeforeEach(func() {
+				expected := &dao.Post{
+					ID:          &postID,
+					ScheduledAt: &scheduledAt,
+					Channels:    []string{"twitter"},
+				}
+				mockNext.EXPECT().Schedule(customerID, expected).Return(expected, nil)
+			})
+
+			It("should schedule the normalized post", func() {
+				Expect(err).To(BeNil())
+				Expect(retPost.Channels).To(Equal([]string{"twitter"}))
+				Expect(post.Channels).To(Equal([]string{" Twitter "}))
+			})
+		})
+
+		Context("without channels", func() {
+			BeforeEach(func() {
+				post.Channels = nil
+			})
+
+			It("should return an error", func() {
+				Expect(err.Error()).To(Equal("validation failed: channels: must have at least one channel"))
+				Expect(retPost).To(BeNil())
+			})
+		})
+
+		Context("without scheduled_at", func() {
+			BeforeEach(func() {
+				post.ScheduledAt = nil
+				mockNext.EXPECT().Schedule(customerID, post).Return(post, nil)
+			})
+
+			It("should unschedule without checking the channels", func() {
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Describe("InsertBatch", func() {
+		var (
+			invalid    *dao.Post
+			results    []*dao.BatchResult
+			err        error
+			normalized *dao.Post
+		)
+
+		BeforeEach(func() {
+			invalid = &dao.Post{URL: "ftp://example.com"}
+			normalized = &dao.Post{
+				ID:       &postID,
+				URL:      "https://example.com/post",
+				Captions: dao.ManualCaptions([]string{"caption1", "caption2"}),
+			}
+			mockNext.EXPECT().InsertBatch(customerID, []*dao.Post{normalized}).Return([]*dao.BatchResult{{Post: normalized}}, nil)
+		})
+
+		JustBeforeEach(func() {
+			results, err = p.InsertBatch(customerID, []*dao.Post{invalid, post})
+		})
+
+		It("should only insert the valid posts", func() {
+			Expect(err).To(BeNil())
+			Expect(results).To(HaveLen(2))
+			Expect(results[0].Err.Error()).To(Equal("validation failed: url: must be http or https"))
+			Expect(results[1].Post).To(Equal(normalized))
+		})
+	})
+
+	Describe("UpdateBatch", func() {
+		Context("when every post is invalid", func() {
+			It("should NOT call the underlying poster", func() {
+				post.Captions = dao.ManualCaptions([]string{""})
+				results, err := p.UpdateBatch(customerID, []*dao.Post{post})
+				Expect(err).To(BeNil())
+				Expect(results[0].Err).To(BeAssignableToTypeOf(&datastore.Validation{}))
+			})
+		})
+	})
+})
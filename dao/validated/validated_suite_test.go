@@ -0,0 +1,13 @@
+package validated
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidated(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dao Validated Suite")
+}
@@ -1,6 +1,8 @@
 package datastore
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"labix.org/v2/mgo/bson"
 
@@ -37,8 +39,68 @@ func (c *NoOpCache) Update(customerID string, post *dao.Post) (*dao.Post, error)
 	return nil, nil
 }
 
+// GetByURL just logs that get by url was called
+func (c *NoOpCache) GetByURL(customerID string, canonicalURL string) (*dao.Post, error) {
+	c.logger.Info("calling cache get by url")
+	return nil, nil
+}
+
+// List just logs that list was called
+func (c *NoOpCache) List(customerID string, opts dao.ListOptions) ([]*dao.Post, error) {
+	c.logger.Info("calling cache list")
+	return nil, nil
+}
+
+// Revisions just logs that revisions was called
+func (c *NoOpCache) Revisions(customerID string, postID bson.ObjectId) ([]*dao.Revision, error) {
+	c.logger.Info("calling cache revisions")
+	return nil, nil
+}
+
+// Restore just logs that restore was called
+func (c *NoOpCache) Restore(customerID string, postID bson.ObjectId, number int, author string) (*dao.Post, error) {
+	c.logger.Info("calling cache restore")
+	return nil, nil
+}
+
+// Patch just logs that patch was called
+func (c *NoOpCache) Patch(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+	c.logger.Info("calling cache patch")
+	return nil, nil
+}
+
 // Delete just logs that delete was called
 func (c *NoOpCache) Delete(customerID string, postID bson.ObjectId) error {
 	c.logger.Info("calling cache delete")
 	return nil
 }
+
+// Schedule just logs that schedule was called
+func (c *NoOpCache) Schedule(customerID string, post *dao.Post) (*dao.Post, error) {
+	c.logger.Info("calling cache schedule")
+	return nil, nil
+}
+
+// ClaimDue just logs that claim due was called
+func (c *NoOpCache) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*dao.Post, error) {
+	c.logger.Info("calling cache claim due")
+	return nil, nil
+}
+
+// CompletePublish just logs that complete publish was called
+func (c *NoOpCache) CompletePublish(customerID string, postID bson.ObjectId, publications []*dao.Publication) (*dao.Post, error) {
+	c.logger.Info("calling cache complete publish")
+	return nil, nil
+}
+
+// InsertBatch just logs that insert batch was called
+func (c *NoOpCache) InsertBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	c.logger.Info("calling cache insert batch")
+	return nil, nil
+}
+
+// UpdateBatch just logs that update batch was called
+func (c *NoOpCache) UpdateBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	c.logger.Info("calling cache update batch")
+	return nil, nil
+}
@@ -2,8 +2,26 @@ package datastore
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
+// Stable, machine readable codes for each error type
+const (
+	CodeNotFound           = "not_found"
+	CodeInvalidArgument    = "invalid_argument"
+	CodeConflict           = "conflict"
+	CodePreconditionFailed = "precondition_failed"
+	CodeUnavailable        = "unavailable"
+	CodeQuotaExceeded      = "quota_exceeded"
+	CodeValidationFailed   = "validation_failed"
+)
+
+// Coder is implemented by errors that have a stable, machine readable code
+type Coder interface {
+	Code() string
+}
+
 // DSError is the base error
 type DSError struct {
 	msg string
@@ -15,6 +33,17 @@ type NotFound DSError
 // InvalidArugment represents an error where arugments supplied are invalid
 type InvalidArugment DSError
 
+// Conflict represents an error where the request conflicts with the current state
+// of a record
+type Conflict DSError
+
+// PreconditionFailed represents an error where a condition the caller required
+// of a record does not hold
+type PreconditionFailed DSError
+
+// Unavailable represents an error where a backend can not currently be reached
+type Unavailable DSError
+
 // NewNotFoundError returns a NotFound error with the supplied options
 func NewNotFoundError(msg string) *NotFound {
 	return &NotFound{
@@ -27,6 +56,11 @@ func (e *NotFound) Error() string {
 	return fmt.Sprintf("%s not found", e.msg)
 }
 
+// Code implements the Coder interface
+func (e *NotFound) Code() string {
+	return CodeNotFound
+}
+
 // NewInvalidArugmentError returns a NotFound error with the supplied options
 func NewInvalidArugmentError(msg string) *InvalidArugment {
 	return &InvalidArugment{
@@ -38,3 +72,125 @@ func NewInvalidArugmentError(msg string) *InvalidArugment {
 func (e *InvalidArugment) Error() string {
 	return fmt.Sprintf("invalid %s", e.msg)
 }
+
+// Code implements the Coder interface
+func (e *InvalidArugment) Code() string {
+	return CodeInvalidArgument
+}
+
+// NewConflictError returns a Conflict error with the supplied options
+func NewConflictError(msg string) *Conflict {
+	return &Conflict{
+		msg: msg,
+	}
+}
+
+// Error implements the Error interface
+func (e *Conflict) Error() string {
+	return fmt.Sprintf("conflict: %s", e.msg)
+}
+
+// Code implements the Coder interface
+func (e *Conflict) Code() string {
+	return CodeConflict
+}
+
+// NewPreconditionFailedError returns a PreconditionFailed error with the supplied options
+func NewPreconditionFailedError(msg string) *PreconditionFailed {
+	return &PreconditionFailed{
+		msg: msg,
+	}
+}
+
+// Error implements the Error interface
+func (e *PreconditionFailed) Error() string {
+	return fmt.Sprintf("precondition failed: %s", e.msg)
+}
+
+// Code implements the Coder interface
+func (e *PreconditionFailed) Code() string {
+	return CodePreconditionFailed
+}
+
+// NewUnavailableError returns an Unavailable error with the supplied options
+func NewUnavailableError(msg string) *Unavailable {
+	return &Unavailable{
+		msg: msg,
+	}
+}
+
+// Error implements the Error interface
+func (e *Unavailable) Error() string {
+	return fmt.Sprintf("%s unavailable", e.msg)
+}
+
+// Code implements the Coder interface
+func (e *Unavailable) Code() string {
+	return CodeUnavailable
+}
+
+// QuotaExceeded represents an error where the customer has used up a quota
+type QuotaExceeded struct {
+	msg      string
+	ResetsAt time.Time
+}
+
+// NewQuotaExceededError returns a QuotaExceeded error with the supplied options
+func NewQuotaExceededError(msg string, resetsAt time.Time) *QuotaExceeded {
+	return &QuotaExceeded{
+		msg:      msg,
+		ResetsAt: resetsAt,
+	}
+}
+
+// Error implements the Error interface
+func (e *QuotaExceeded) Error() string {
+	return fmt.Sprintf("quota exceeded: %s", e.msg)
+}
+
+// Code implements the Coder interface
+func (e *QuotaExceeded) Code() string {
+	return CodeQuotaExceeded
+}
+
+// FieldError describes why a single field failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validation represents an error where one or more fields are invalid
+type Validation struct {
+	Fields []FieldError
+}
+
+// NewValidationError returns a Validation error with the supplied options
+func NewValidationError(fields ...FieldError) *Validation {
+	return &Validation{
+		Fields: fields,
+	}
+}
+
+// Add records another invalid field
+func (e *Validation) Add(field, msg string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: msg})
+}
+
+// HasErrors returns true if any field was invalid
+func (e *Validation) HasErrors() bool {
+	return len(e.Fields) > 0
+}
+
+// Error implements the Error interface
+func (e *Validation) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(msgs, "; "))
+}
+
+// Code implements the Coder interface
+func (e *Validation) Code() string {
+	return CodeValidationFailed
+}
@@ -0,0 +1,510 @@
+package datastore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+// FileVersion is the version of the file format written by FileDatastore
+const FileVersion = 3
+
+// Operations of a file record
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// maxRecordSize is the longest line a file record may be
+const maxRecordSize = 16 << 20
+
+// fileHeader is the first line of a file
+type fileHeader struct {
+	Version int `json:"version"`
+}
+
+// fileRecord is a line of a file. A put replaces the post and its revisions, a
+// delete removes them
+type fileRecord struct {
+	Op         string          `json:"op"`
+	CustomerID string          `json:"customer_id"`
+	PostID     bson.ObjectId   `json:"post_id"`
+	Post       *dao.Post       `json:"post,omitempty"`
+	Revisions  []*dao.Revision `json:"revisions,omitempty"`
+}
+
+// migration upgrades a raw record of the previous version to the next one
+type migration func(record map[string]interface{}) error
+
+// migrations upgrade records one version at a time, migrations[v] upgrades a
+// record from version v to v+1
+var migrations = map[int]migration{
+	1: migrateTimestamps,
+	2: migrateCaptions,
+}
+
+// migrateTimestamps sets the created and updated times of a post from its
+// revisions, and who created it from the author of the first one. A post
+// without revisions was created when its id was
+func migrateTimestamps(record map[string]interface{}) error {
+	post, ok := record["post"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	revisions, _ := record["revisions"].([]interface{})
+	if len(revisions) == 0 {
+		id, _ := post["id"].(string)
+		if !bson.IsObjectIdHex(id) {
+			return fmt.Errorf("post %q has an invalid id", id)
+		}
+		created := bson.ObjectIdHex(id).Time().UTC().Format(time.RFC3339Nano)
+		post["created_at"], post["updated_at"] = created, created
+		return nil
+	}
+	first, _ := revisions[0].(map[string]interface{})
+	last, _ := revisions[len(revisions)-1].(map[string]interface{})
+	if first == nil || last == nil {
+		return fmt.Errorf("post %v has an invalid revision", post["id"])
+	}
+	post["created_at"], post["updated_at"] = first["created_at"], last["created_at"]
+	if author, ok := first["author"]; ok {
+		post["created_by"] = author
+	}
+	return nil
+}
+
+// migrateCaptions turns the caption texts of a post and its revisions into
+// manual captions. A caption of a revision gets the id of the post's caption
+// with the same text, so restoring the revision keeps it
+func migrateCaptions(record map[string]interface{}) error {
+	post, ok := record["post"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	ids := map[string]string{}
+	post["captions"] = captionObjects(post["captions"], ids)
+	revisions, _ := record["revisions"].([]interface{})
+	for _, r := range revisions {
+		revision, ok := r.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("post %v has an invalid revision", post["id"])
+		}
+		revision["captions"] = captionObjects(revision["captions"], ids)
+	}
+	return nil
+}
+
+// captionObjects returns the texts as manual captions. A text seen before gets
+// the same id, unless the captions already have a caption with it
+func captionObjects(value interface{}, ids map[string]string) interface{} {
+	texts, ok := value.([]interface{})
+	if !ok {
+		return value
+	}
+	used := map[string]bool{}
+	captions := make([]interface{}, len(texts))
+	for i, t := range texts {
+		text, _ := t.(string)
+		id, ok := ids[text]
+		if !ok || used[id] {
+			id = bson.NewObjectId().Hex()
+		}
+		if !ok {
+			ids[text] = id
+		}
+		used[id] = true
+		captions[i] = map[string]interface{}{"id": id, "text": text, "source": dao.SourceManual}
+	}
+	return captions
+}
+
+// FileDatastore implements the Datastore interface. Posts are served from
+// memory and every write is appended to a file as json lines before it is
+// stored in memory, and the file is replayed when it is opened. The file is
+// written while the lock of the memory datastore is held, so it always ends
+// with the latest version of a post. The file only grows until it is compacted
+type FileDatastore struct {
+	*InMemoryDatastore
+	logger *log.Logger
+	path   string
+	file   *os.File
+	lock   *os.File
+}
+
+// OpenFileDatastore opens the file at path, creating it if it does not exist. A
+// file is only opened by one process at a time, and a file of an older version
+// has to be migrated with MigrateFile first
+func OpenFileDatastore(logger *log.Logger, path string) (*FileDatastore, error) {
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+
+	d := &FileDatastore{
+		InMemoryDatastore: NewInMemoryDatastore(logger),
+		logger:            logger,
+		path:              path,
+		lock:              lock,
+	}
+	if err := d.open(); err != nil {
+		unlockFile(lock)
+		return nil, err
+	}
+	d.InMemoryDatastore.write = d.writeChanges
+	return d, nil
+}
+
+func (d *FileDatastore) open() error {
+	f, err := os.OpenFile(d.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if info.Size() == 0 {
+		if err := writeLine(f, &fileHeader{Version: FileVersion}); err != nil {
+			f.Close()
+			return err
+		}
+	} else if err := d.replay(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return err
+	}
+	d.file = f
+	return nil
+}
+
+// replay loads every record of the file. A last line that was only partly
+// written is ignored, and overwritten by the next write
+func (d *FileDatastore) replay(f *os.File) error {
+	r := bufio.NewReader(f)
+	version, offset, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	if version != FileVersion {
+		return fmt.Errorf("%s is version %d, migrate it to version %d", d.path, version, FileVersion)
+	}
+
+	records := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				d.logger.WithFields(log.Fields{
+					"path": d.path,
+				}).Warn("ignoring partly written record")
+				return f.Truncate(offset)
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		record := &fileRecord{}
+		if err := json.Unmarshal(line, record); err != nil {
+			return fmt.Errorf("%s: invalid record at byte %d: %v", d.path, offset, err)
+		}
+		d.replayRecord(record)
+		offset += int64(len(line))
+		records++
+	}
+
+	d.logger.WithFields(log.Fields{
+		"path":    d.path,
+		"records": records,
+	}).Info("replayed file")
+	return nil
+}
+
+func (d *FileDatastore) replayRecord(record *fileRecord) {
+	switch record.Op {
+	case opPut:
+		if record.Post == nil || record.Post.ID == nil {
+			return
+		}
+		d.InMemoryDatastore.put(record.CustomerID, record.Post, record.Revisions)
+	case opDelete:
+		d.InMemoryDatastore.Delete(record.CustomerID, record.PostID)
+	}
+}
+
+// Dump writes every post with its revisions to w, in the file format. The dump
+// is a compacted file, and can be loaded with Load
+func (d *FileDatastore) Dump(w io.Writer) error {
+	d.InMemoryDatastore.mu.Lock()
+	defer d.InMemoryDatastore.mu.Unlock()
+	return d.dump(w)
+}
+
+// dump writes every post by customer and id, it must be called with the lock of
+// the memory datastore held
+func (d *FileDatastore) dump(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if err := writeLine(bw, &fileHeader{Version: FileVersion}); err != nil {
+		return err
+	}
+	storeIDs := make([]string, 0, len(d.store))
+	for storeID := range d.store {
+		storeIDs = append(storeIDs, storeID)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool {
+		a, b := d.store[storeIDs[i]], d.store[storeIDs[j]]
+		if a.CustID != b.CustID {
+			return a.CustID < b.CustID
+		}
+		return *a.ID < *b.ID
+	})
+	for _, storeID := range storeIDs {
+		post := d.store[storeID]
+		record := &fileRecord{Op: opPut, CustomerID: post.CustID, PostID: *post.ID, Post: post, Revisions: d.revisions[storeID]}
+		if err := writeLine(bw, record); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Load stores every post of a dump, replacing posts with the same id, and
+// returns how many were loaded
+func (d *FileDatastore) Load(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+	version, _, err := readHeader(br)
+	if err != nil {
+		return 0, err
+	}
+	if version != FileVersion {
+		return 0, fmt.Errorf("dump is version %d, migrate it to version %d", version, FileVersion)
+	}
+
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(nil, maxRecordSize)
+	loaded := 0
+	for scanner.Scan() {
+		record := &fileRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return loaded, fmt.Errorf("invalid record %d: %v", loaded+1, err)
+		}
+		if record.Op != opPut || record.Post == nil || record.Post.ID == nil {
+			continue
+		}
+		if err := d.InMemoryDatastore.put(record.CustomerID, record.Post, record.Revisions); err != nil {
+			return loaded, err
+		}
+		loaded++
+	}
+	return loaded, scanner.Err()
+}
+
+// Compact rewrites the file with only the latest version of every post
+func (d *FileDatastore) Compact() error {
+	d.InMemoryDatastore.mu.Lock()
+	defer d.InMemoryDatastore.mu.Unlock()
+
+	tmp, err := os.OpenFile(d.path+".compact", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := d.dump(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	syncDir(filepath.Dir(d.path))
+
+	d.file.Close()
+	d.file = tmp
+	d.logger.WithFields(log.Fields{
+		"path": d.path,
+	}).Info("compacted file")
+	return nil
+}
+
+// Close closes the file and releases it for other processes
+func (d *FileDatastore) Close() error {
+	d.InMemoryDatastore.mu.Lock()
+	defer d.InMemoryDatastore.mu.Unlock()
+
+	err := d.file.Close()
+	unlockFile(d.lock)
+	return err
+}
+
+// writeChanges appends a record for every change to the file and syncs it. It
+// is called with the lock of the memory datastore held, and a failed write is
+// cut off the file again so the file only has the changes that were stored
+func (d *FileDatastore) writeChanges(changes []*change) error {
+	offset, err := d.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return d.writeFailed(err)
+	}
+	for _, c := range changes {
+		record := &fileRecord{Op: opDelete, CustomerID: c.customerID, PostID: c.postID}
+		if c.post != nil {
+			record = &fileRecord{Op: opPut, CustomerID: c.customerID, PostID: c.postID, Post: c.post, Revisions: c.revisions}
+		}
+		if err = writeLine(d.file, record); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = d.file.Sync()
+	}
+	if err != nil {
+		if terr := d.file.Truncate(offset); terr == nil {
+			d.file.Seek(offset, io.SeekStart)
+		}
+		return d.writeFailed(err)
+	}
+	return nil
+}
+
+func (d *FileDatastore) writeFailed(err error) error {
+	d.logger.WithFields(log.Fields{
+		"path":  d.path,
+		"error": err.Error(),
+	}).Error("failed to write record")
+	return NewUnavailableError("file datastore")
+}
+
+// MigrateFile upgrades the file at path to FileVersion. It returns the version
+// the file was at, the file is not changed if it is already current
+func MigrateFile(path string) (int, error) {
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlockFile(lock)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	version, _, err := readHeader(r)
+	if err != nil {
+		return 0, err
+	}
+	if version == FileVersion {
+		return version, nil
+	}
+	if version > FileVersion {
+		return version, fmt.Errorf("%s is version %d, newer than %d", path, version, FileVersion)
+	}
+
+	tmp, err := os.OpenFile(path+".migrate", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return version, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := bufio.NewWriter(tmp)
+	if err := writeLine(w, &fileHeader{Version: FileVersion}); err != nil {
+		return version, err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxRecordSize)
+	var invalid error
+	for scanner.Scan() {
+		// Only the last record may be invalid, it was partly written and is dropped
+		// like replay does
+		if invalid != nil {
+			return version, invalid
+		}
+		record := map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			invalid = fmt.Errorf("%s: invalid record: %v", path, err)
+			continue
+		}
+		for v := version; v < FileVersion; v++ {
+			migrate, ok := migrations[v]
+			if !ok {
+				return version, fmt.Errorf("no migration from version %d", v)
+			}
+			if err := migrate(record); err != nil {
+				return version, err
+			}
+		}
+		if err := writeLine(w, record); err != nil {
+			return version, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return version, err
+	}
+	if err := w.Flush(); err != nil {
+		return version, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return version, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return version, err
+	}
+	syncDir(filepath.Dir(path))
+	return version, nil
+}
+
+// readHeader returns the version of the file and the length of the header
+func readHeader(r *bufio.Reader) (version int, n int64, err error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return 0, 0, fmt.Errorf("missing header: %v", err)
+	}
+	header := &fileHeader{}
+	if err := json.Unmarshal(line, header); err != nil || header.Version < 1 {
+		return 0, 0, fmt.Errorf("invalid header")
+	}
+	return header.Version, int64(len(line)), nil
+}
+
+func writeLine(w io.Writer, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// syncDir makes a rename in the directory durable, it is best effort since not
+// every platform can sync a directory
+func syncDir(dir string) {
+	if f, err := os.Open(dir); err == nil {
+		f.Sync()
+		f.Close()
+	}
+}
@@ -0,0 +1,32 @@
+package datastore
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// errorSharingViolation is returned when a file is open in another process
+// that does not share it
+const errorSharingViolation syscall.Errno = 32
+
+// lockFile opens the file at path without sharing it, so a file datastore is
+// only opened by one process. The file is released when the process exits
+func lockFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err == errorSharingViolation {
+		return nil, fmt.Errorf("%s is in use by another process", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
+
+func unlockFile(f *os.File) {
+	f.Close()
+}
@@ -0,0 +1,336 @@
+package datastore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+var _ = Describe("FileDatastore", func() {
+	var (
+		logger     *log.Logger
+		dir        string
+		path       string
+		ds         *FileDatastore
+		customerID string
+	)
+
+	reopen := func() {
+		Expect(ds.Close()).To(Succeed())
+		var err error
+		ds, err = OpenFileDatastore(logger, path)
+		Expect(err).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		var err error
+		dir, err = ioutil.TempDir("", "file-datastore")
+		Expect(err).To(BeNil())
+		path = filepath.Join(dir, "posts.jsonl")
+		ds, err = OpenFileDatastore(logger, path)
+		Expect(err).To(BeNil())
+		customerID = "test-customer"
+	})
+
+	AfterEach(func() {
+		ds.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("should only be opened once", func() {
+		_, err := OpenFileDatastore(logger, path)
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("in use"))
+	})
+
+	It("should replay every write when opened", func() {
+		kept, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com/kept", Captions: dao.ManualCaptions([]string{"caption1"})})
+		Expect(err).To(BeNil())
+		_, err = ds.Update(customerID, &dao.Post{ID: kept.ID, Captions: dao.ManualCaptions([]string{"caption2"})})
+		Expect(err).To(BeNil())
+		deleted, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com/deleted"})
+		Expect(err).To(BeNil())
+		Expect(ds.Delete(customerID, *deleted.ID)).To(Succeed())
+
+		reopen()
+
+		post, err := ds.Get(customerID, *kept.ID)
+		Expect(err).To(BeNil())
+		Expect(dao.CaptionTexts(post.Captions)).To(Equal([]string{"caption2"}))
+		revisions, err := ds.Revisions(customerID, *kept.ID)
+		Expect(err).To(BeNil())
+		Expect(revisions).To(HaveLen(2))
+		byURL, err := ds.GetByURL(customerID, "https://example.com/kept")
+		Expect(err).To(BeNil())
+		Expect(byURL.ID).To(Equal(kept.ID))
+		_, err = ds.Get(customerID, *deleted.ID)
+		Expect(err).To(BeAssignableToTypeOf(&NotFound{}))
+	})
+
+	It("should keep the order, selection and pins of the captions", func() {
+		post, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com", Captions: dao.ManualCaptions([]string{"a", "b", "c"})})
+		Expect(err).To(BeNil())
+		patched, err := ds.Patch(customerID, *post.ID, func(p *dao.Post) (*dao.Post, error) {
+			p.Captions = []*dao.Caption{p.Captions[2], p.Captions[0], p.Captions[1]}
+			p.Captions[1].Selected = true
+			p.Captions[2].Pinned = true
+			return p, nil
+		}, "alice")
+		Expect(err).To(BeNil())
+		Expect(dao.CaptionTexts(patched.Captions)).To(Equal([]string{"b", "c", "a"}))
+
+		reopen()
+
+		stored, err := ds.Get(customerID, *post.ID)
+		Expect(err).To(BeNil())
+		Expect(stored.Captions).To(Equal(patched.Captions))
+		Expect(stored.Captions[0].Pinned).To(BeTrue())
+		Expect(stored.Captions[2].Selected).To(BeTrue())
+	})
+
+	It("should keep the quality findings and rejected captions", func() {
+		findings := []*dao.Finding{{Rule: "fragment", Action: "flag", Message: "does not end a sentence"}}
+		post, err := ds.Insert(customerID, &dao.Post{
+			URL:              "https://example.com",
+			Captions:         []*dao.Caption{{Text: "a", Source: dao.SourceGenerated, Score: 0.5, Findings: findings}},
+			RejectedCaptions: []*dao.Rejection{{Text: "b", Findings: findings}},
+		})
+		Expect(err).To(BeNil())
+		_, err = ds.Update(customerID, &dao.Post{ID: post.ID, Captions: dao.ManualCaptions([]string{"a", "c"})})
+		Expect(err).To(BeNil())
+
+		reopen()
+
+		stored, err := ds.Get(customerID, *post.ID)
+		Expect(err).To(BeNil())
+		Expect(stored.Captions[0].Findings).To(Equal(findings))
+		Expect(stored.Captions[1].Findings).To(BeNil())
+		Expect(stored.RejectedCaptions).To(Equal([]*dao.Rejection{{Text: "b", Findings: findings}}))
+	})
+
+	It("should ignore a partly written last record", func() {
+		post, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com"})
+		Expect(err).To(BeNil())
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		Expect(err).To(BeNil())
+		_, err = f.WriteString(`{"op":"put","customer_id":`)
+		Expect(err).To(BeNil())
+		Expect(f.Close()).To(Succeed())
+
+		reopen()
+
+		_, err = ds.Get(customerID, *post.ID)
+		Expect(err).To(BeNil())
+		_, err = ds.Insert(customerID, &dao.Post{URL: "https://example.com/next"})
+		Expect(err).To(BeNil())
+		reopen()
+		posts, err := ds.List(customerID, dao.ListOptions{})
+		Expect(err).To(BeNil())
+		Expect(posts).To(HaveLen(2))
+	})
+
+	It("should NOT store a write that can not be written to the file", func() {
+		post, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com", Captions: dao.ManualCaptions([]string{"caption1"})})
+		Expect(err).To(BeNil())
+		_, err = ds.Update(customerID, &dao.Post{ID: post.ID, Captions: post.Captions, Status: dao.StatusApproved})
+		Expect(err).To(BeNil())
+		due := time.Now().Add(-time.Minute)
+		_, err = ds.Schedule(customerID, &dao.Post{ID: post.ID, ScheduledAt: &due, Channels: []string{"twitter"}})
+		Expect(err).To(BeNil())
+
+		// Writes to a read only file fail
+		file := ds.file
+		readOnly, err := os.Open(path)
+		Expect(err).To(BeNil())
+		ds.file = readOnly
+
+		unavailable := NewUnavailableError("file datastore")
+		_, err = ds.Insert(customerID, &dao.Post{URL: "https://example.com/other"})
+		Expect(err).To(Equal(unavailable))
+		_, err = ds.Update(customerID, &dao.Post{ID: post.ID, Captions: dao.ManualCaptions([]string{"caption2"})})
+		Expect(err).To(Equal(unavailable))
+		_, err = ds.ClaimDue(time.Now(), time.Minute, 0)
+		Expect(err).To(Equal(unavailable))
+		Expect(ds.Delete(customerID, *post.ID)).To(Equal(unavailable))
+
+		stored, err := ds.Get(customerID, *post.ID)
+		Expect(err).To(BeNil())
+		Expect(dao.CaptionTexts(stored.Captions)).To(Equal([]string{"caption1"}))
+		Expect(stored.Status).To(Equal(dao.StatusApproved))
+		posts, err := ds.List(customerID, dao.ListOptions{})
+		Expect(err).To(BeNil())
+		Expect(posts).To(HaveLen(1))
+
+		ds.file = file
+		Expect(readOnly.Close()).To(Succeed())
+		claimed, err := ds.ClaimDue(time.Now(), time.Minute, 0)
+		Expect(err).To(BeNil())
+		Expect(claimed).To(HaveLen(1))
+		reopen()
+		stored, err = ds.Get(customerID, *post.ID)
+		Expect(err).To(BeNil())
+		Expect(stored.Status).To(Equal(dao.StatusPublishing))
+
+		// The claim was made before the restart, so it is claimed again
+		claimed, err = ds.ClaimDue(time.Now(), time.Minute, 0)
+		Expect(err).To(BeNil())
+		Expect(claimed).To(HaveLen(1))
+		Expect(claimed[0].ID).To(Equal(post.ID))
+	})
+
+	It("should NOT open a file of another version", func() {
+		Expect(ds.Close()).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(`{"version":99}`+"\n"), 0600)).To(Succeed())
+		var err error
+		_, err = OpenFileDatastore(logger, path)
+		Expect(err.Error()).To(ContainSubstring("is version 99"))
+		ds, err = OpenFileDatastore(logger, filepath.Join(dir, "other.jsonl"))
+		Expect(err).To(BeNil())
+	})
+
+	Describe("Compact", func() {
+		It("should keep only the latest version of every post", func() {
+			post, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com"})
+			Expect(err).To(BeNil())
+			for i := 0; i < 5; i++ {
+				_, err = ds.Update(customerID, &dao.Post{ID: post.ID, Captions: dao.ManualCaptions([]string{strings.Repeat("a", i+1)})})
+				Expect(err).To(BeNil())
+			}
+			before, err := ioutil.ReadFile(path)
+			Expect(err).To(BeNil())
+
+			Expect(ds.Compact()).To(Succeed())
+			after, err := ioutil.ReadFile(path)
+			Expect(err).To(BeNil())
+			Expect(len(after)).To(BeNumerically("<", len(before)))
+			Expect(strings.Count(string(after), "\n")).To(Equal(2))
+
+			_, err = ds.Update(customerID, &dao.Post{ID: post.ID, Captions: dao.ManualCaptions([]string{"latest"})})
+			Expect(err).To(BeNil())
+			reopen()
+			retPost, err := ds.Get(customerID, *post.ID)
+			Expect(err).To(BeNil())
+			Expect(dao.CaptionTexts(retPost.Captions)).To(Equal([]string{"latest"}))
+			revisions, err := ds.Revisions(customerID, *post.ID)
+			Expect(err).To(BeNil())
+			Expect(revisions).To(HaveLen(7))
+		})
+	})
+
+	Describe("Dump and Load", func() {
+		It("should load every post of the dump", func() {
+			post, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com"})
+			Expect(err).To(BeNil())
+			_, err = ds.Insert("other-customer", &dao.Post{URL: "https://example.com"})
+			Expect(err).To(BeNil())
+			dump := &bytes.Buffer{}
+			Expect(ds.Dump(dump)).To(Succeed())
+
+			other, err := OpenFileDatastore(logger, filepath.Join(dir, "restored.jsonl"))
+			Expect(err).To(BeNil())
+			defer other.Close()
+			loaded, err := other.Load(dump)
+			Expect(err).To(BeNil())
+			Expect(loaded).To(Equal(2))
+			retPost, err := other.Get(customerID, *post.ID)
+			Expect(err).To(BeNil())
+			Expect(retPost.URL).To(Equal("https://example.com"))
+		})
+	})
+
+	Describe("MigrateFile", func() {
+		BeforeEach(func() {
+			Expect(ds.Close()).To(Succeed())
+		})
+
+		It("should NOT change a current file", func() {
+			version, err := MigrateFile(path)
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal(FileVersion))
+			var openErr error
+			ds, openErr = OpenFileDatastore(logger, path)
+			Expect(openErr).To(BeNil())
+		})
+
+		It("should set the timestamps of a version 1 file from the revisions", func() {
+			revised := bson.NewObjectId()
+			bare := bson.NewObjectId()
+			lines := []string{
+				`{"version":1}`,
+				`{"op":"put","customer_id":"test-customer","post_id":"` + revised.Hex() + `","post":{"id":"` + revised.Hex() + `","url":"https://example.com","updated_by":"editor"},` +
+					`"revisions":[{"number":1,"post_id":"` + revised.Hex() + `","author":"writer","created_at":"2026-01-02T03:04:05Z","captions":null,"diff":null},` +
+					`{"number":2,"post_id":"` + revised.Hex() + `","author":"editor","created_at":"2026-02-03T04:05:06Z","captions":null,"diff":null}]}`,
+				`{"op":"put","customer_id":"test-customer","post_id":"` + bare.Hex() + `","post":{"id":"` + bare.Hex() + `","url":"https://example.org"}}`,
+			}
+			Expect(ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)).To(Succeed())
+
+			version, err := MigrateFile(path)
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal(1))
+			ds, err = OpenFileDatastore(logger, path)
+			Expect(err).To(BeNil())
+
+			post, err := ds.Get(customerID, revised)
+			Expect(err).To(BeNil())
+			Expect(*post.CreatedAt).To(Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+			Expect(*post.UpdatedAt).To(Equal(time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)))
+			Expect(post.CreatedBy).To(Equal("writer"))
+
+			post, err = ds.Get(customerID, bare)
+			Expect(err).To(BeNil())
+			Expect(post.CreatedAt.Equal(bare.Time())).To(BeTrue())
+			Expect(post.UpdatedAt).To(Equal(post.CreatedAt))
+		})
+
+		It("should turn the captions of a version 2 file into manual captions", func() {
+			id := bson.NewObjectId()
+			lines := []string{
+				`{"version":2}`,
+				`{"op":"put","customer_id":"test-customer","post_id":"` + id.Hex() + `","post":{"id":"` + id.Hex() + `","url":"https://example.com","captions":["b","a"],` +
+					`"created_at":"2026-01-02T03:04:05Z","updated_at":"2026-02-03T04:05:06Z"},` +
+					`"revisions":[{"number":1,"post_id":"` + id.Hex() + `","created_at":"2026-01-02T03:04:05Z","captions":["a","c"],"diff":null},` +
+					`{"number":2,"post_id":"` + id.Hex() + `","created_at":"2026-02-03T04:05:06Z","captions":["b","a"],"diff":null}]}`,
+			}
+			Expect(ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)).To(Succeed())
+
+			version, err := MigrateFile(path)
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal(2))
+			ds, err = OpenFileDatastore(logger, path)
+			Expect(err).To(BeNil())
+
+			post, err := ds.Get(customerID, id)
+			Expect(err).To(BeNil())
+			Expect(dao.CaptionTexts(post.Captions)).To(Equal([]string{"b", "a"}))
+			Expect(post.Captions[0].Source).To(Equal(dao.SourceManual))
+			Expect(post.Captions[0].ID).NotTo(Equal(post.Captions[1].ID))
+
+			revisions, err := ds.Revisions(customerID, id)
+			Expect(err).To(BeNil())
+			Expect(dao.CaptionTexts(revisions[0].Captions)).To(Equal([]string{"a", "c"}))
+			Expect(revisions[0].Captions[0].ID).To(Equal(post.Captions[1].ID))
+			Expect(revisions[1].Captions).To(Equal(post.Captions))
+		})
+
+		It("should NOT downgrade a newer file", func() {
+			Expect(ioutil.WriteFile(path, []byte(`{"version":99}`+"\n"), 0600)).To(Succeed())
+			_, err := MigrateFile(path)
+			Expect(err.Error()).To(ContainSubstring("newer"))
+			var openErr error
+			ds, openErr = OpenFileDatastore(logger, filepath.Join(dir, "other.jsonl"))
+			Expect(openErr).To(BeNil())
+		})
+	})
+})
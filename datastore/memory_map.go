@@ -2,44 +2,79 @@ package datastore
 
 import (
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"labix.org/v2/mgo/bson"
 
+	"github.com/bpross/cc-hw/canonical"
 	"github.com/bpross/cc-hw/dao"
 )
 
 // Datastore provides an interface for inserting, retrieving and updating information about posts
 type Datastore interface {
 	Insert(string, *dao.Post) (*dao.Post, error)
+	InsertBatch(string, []*dao.Post) ([]*dao.BatchResult, error)
 	Get(string, bson.ObjectId) (*dao.Post, error)
 	Update(string, *dao.Post) (*dao.Post, error)
+	UpdateBatch(string, []*dao.Post) ([]*dao.BatchResult, error)
 	Delete(string, bson.ObjectId) error
+	GetByURL(string, string) (*dao.Post, error)
+	List(string, dao.ListOptions) ([]*dao.Post, error)
+	Revisions(string, bson.ObjectId) ([]*dao.Revision, error)
+	Restore(string, bson.ObjectId, int, string) (*dao.Post, error)
+	Patch(string, bson.ObjectId, dao.PatchFunc, string) (*dao.Post, error)
+	Schedule(string, *dao.Post) (*dao.Post, error)
+	ClaimDue(time.Time, time.Duration, int) ([]*dao.Post, error)
+	CompletePublish(string, bson.ObjectId, []*dao.Publication) (*dao.Post, error)
 }
 
 // InMemoryDatastore implements the Datastore interface for in memory storage
 type InMemoryDatastore struct {
 	logger *log.Logger
+	mu     sync.Mutex
 	store  map[string]*dao.Post
+	// urls indexes post ids by tenant and canonical url, oldest first
+	urls map[string][]bson.ObjectId
+	// ids indexes the post ids of every tenant in id order, so a page of
+	// posts is found without sorting all of them
+	ids map[string][]bson.ObjectId
+	// revisions holds every version of a post by composite id, oldest first
+	revisions map[string][]*dao.Revision
+	// write is called with the changes of every write before they are stored,
+	// it is how a FileDatastore persists them. Nothing is stored if it fails
+	write func([]*change) error
+	now   func() time.Time
+}
+
+// change is a post as a write left it, with every revision of it. The post is
+// nil when it was deleted
+type change struct {
+	customerID string
+	postID     bson.ObjectId
+	post       *dao.Post
+	revisions  []*dao.Revision
 }
 
 // NewInMemoryDatastore creates a new InMemoryDatastore with the provided options
 func NewInMemoryDatastore(logger *log.Logger) *InMemoryDatastore {
 	m := make(map[string]*dao.Post)
 	return &InMemoryDatastore{
-		logger: logger,
-		store:  m,
+		logger:    logger,
+		store:     m,
+		urls:      make(map[string][]bson.ObjectId),
+		ids:       make(map[string][]bson.ObjectId),
+		revisions: make(map[string][]*dao.Revision),
+		now:       time.Now,
 	}
 }
 
 // Insert inserts a new post into the map, customerID is used to enforce tenancy
 func (d *InMemoryDatastore) Insert(customerID string, post *dao.Post) (*dao.Post, error) {
-	if post == nil {
-		return nil, NewInvalidArugmentError("must provide post")
-	}
-
-	if post.ID != nil {
-		return nil, NewInvalidArugmentError("cannot provide ID")
+	if err := checkInsert(post); err != nil {
+		return nil, err
 	}
 
 	if customerID == "" {
@@ -53,30 +88,80 @@ func (d *InMemoryDatastore) Insert(customerID string, post *dao.Post) (*dao.Post
 
 	logger.Info("inserting into memory map")
 
-	// Generate ID
-	id := bson.NewObjectId()
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	// Create new post
-	r := &dao.Post{
-		ID:       &id,
-		CustID:   customerID,
-		URL:      post.URL,
-		Captions: post.Captions,
+	r, err := d.insert(customerID, post)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create composite ID to enforce tenancy
-	storeID := createCompositeID(customerID, id)
-
-	// Store post
-	d.store[storeID] = r
-
 	logger.WithFields(log.Fields{
-		"post_id": id.Hex(),
+		"post_id": r.ID.Hex(),
 	}).Debug("successfully inserted post")
 
 	return r, nil
 }
 
+// InsertBatch inserts every post while holding the lock once. A post that can not
+// be inserted gets an error result and does not stop the others
+func (d *InMemoryDatastore) InsertBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"posts":      len(posts),
+	})
+
+	logger.Info("batch inserting into memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	results := make([]*dao.BatchResult, len(posts))
+	for i, post := range posts {
+		if err := checkInsert(post); err != nil {
+			results[i] = &dao.BatchResult{Err: err}
+			continue
+		}
+		inserted, err := d.insert(customerID, post)
+		results[i] = &dao.BatchResult{Post: inserted, Err: err}
+	}
+
+	logger.Debug("successfully batch inserted posts")
+	return results, nil
+}
+
+// insert stores a new post and returns a copy of it, it must be called with the
+// lock held
+func (d *InMemoryDatastore) insert(customerID string, post *dao.Post) (*dao.Post, error) {
+	// Generate ID
+	id := bson.NewObjectId()
+
+	// Create new post, urls that can not be canonicalized are not indexed
+	canonicalURL, _ := canonical.URL(post.URL)
+	r := &dao.Post{
+		ID:               &id,
+		CustID:           customerID,
+		URL:              post.URL,
+		CanonicalURL:     canonicalURL,
+		Captions:         dao.MergeCaptions(nil, post.Captions),
+		RejectedCaptions: dao.CopyRejections(post.RejectedCaptions),
+		Status:           dao.StatusDraft,
+		CreatedBy:        post.UpdatedBy,
+	}
+	d.touch(r, post.UpdatedBy)
+	r.CreatedAt = r.UpdatedAt
+
+	// Store post with its first revision
+	if err := d.commit(d.revise(r)); err != nil {
+		return nil, err
+	}
+	return copyPost(r), nil
+}
+
 // Get retrieves the postID from the map, tenancy is enforced with the customerID
 func (d *InMemoryDatastore) Get(customerID string, postID bson.ObjectId) (*dao.Post, error) {
 	if postID == "" {
@@ -97,6 +182,9 @@ func (d *InMemoryDatastore) Get(customerID string, postID bson.ObjectId) (*dao.P
 
 	logger.Info("retrieving from memory map")
 
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	// Find post in the datastore, if ok is false, the post DNE
 	r, ok := d.store[storeID]
 	if !ok {
@@ -104,13 +192,396 @@ func (d *InMemoryDatastore) Get(customerID string, postID bson.ObjectId) (*dao.P
 	}
 
 	logger.Debug("successfully retrieved")
-	return r, nil
+	return copyPost(r), nil
 }
 
 // Update stores the given post in the map
 func (d *InMemoryDatastore) Update(customerID string, post *dao.Post) (*dao.Post, error) {
+	if err := checkUpdate(post); err != nil {
+		return nil, err
+	}
+
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"postID":     post.ID.Hex(),
+	})
+
+	logger.Info("updating in memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	updated, err := d.update(customerID, post)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("successfully updated post")
+	return updated, nil
+}
+
+// UpdateBatch updates every post while holding the lock once. A post that can not
+// be updated gets an error result and does not stop the others
+func (d *InMemoryDatastore) UpdateBatch(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"posts":      len(posts),
+	})
+
+	logger.Info("batch updating in memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	results := make([]*dao.BatchResult, len(posts))
+	for i, post := range posts {
+		if err := checkUpdate(post); err != nil {
+			results[i] = &dao.BatchResult{Err: err}
+			continue
+		}
+		updated, err := d.update(customerID, post)
+		results[i] = &dao.BatchResult{Post: updated, Err: err}
+	}
+
+	logger.Debug("successfully batch updated posts")
+	return results, nil
+}
+
+// update copies the captions and status onto the stored post, it must be called
+// with the lock held
+func (d *InMemoryDatastore) update(customerID string, post *dao.Post) (*dao.Post, error) {
+	// Create composite id
+	storeID := createCompositeID(customerID, *post.ID)
+
+	// Find post in the datastore, if ok is false, the post DNE
+	prev, ok := d.store[storeID]
+	if !ok {
+		return nil, NewNotFoundError("post")
+	}
+	if prev.Status == dao.StatusPublishing {
+		return nil, NewPreconditionFailedError("post is being published")
+	}
+
+	if post.Status != "" && post.Status != prev.Status {
+		if err := checkTransition(prev.Status, post.Status); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only copy over captions and status, if one was provided
+	next := copyPost(prev)
+	captions := dao.MergeCaptions(prev.Captions, post.Captions)
+	unapprove(next, captions)
+	next.Captions = captions
+	if post.Status != "" {
+		next.Status = post.Status
+	}
+	d.touch(next, post.UpdatedBy)
+
+	// Store post and record the new version as a revision
+	if err := d.commit(d.revise(next)); err != nil {
+		return nil, err
+	}
+	return copyPost(next), nil
+}
+
+// Delete removes the post from the map, tenancy is enforced with the customerID
+func (d *InMemoryDatastore) Delete(customerID string, postID bson.ObjectId) error {
+	if postID == "" {
+		return NewInvalidArugmentError("postID")
+	}
+	if customerID == "" {
+		return NewInvalidArugmentError("customerID")
+	}
+
+	storeID := createCompositeID(customerID, postID)
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"postID":     postID.Hex(),
+	})
+	logger.Info("deleting from memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.store[storeID]; !ok {
+		return NewNotFoundError("post")
+	}
+	if err := d.commit(&change{customerID: customerID, postID: postID}); err != nil {
+		return err
+	}
+	logger.Debug("successfully deleted post")
+	return nil
+}
+
+// GetByURL retrieves the oldest post the customer has for the canonical url
+func (d *InMemoryDatastore) GetByURL(customerID string, canonicalURL string) (*dao.Post, error) {
+	if canonicalURL == "" {
+		return nil, NewInvalidArugmentError("url")
+	}
+
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"url":        canonicalURL,
+	})
+
+	logger.Info("retrieving by url from memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := d.urls[createURLID(customerID, canonicalURL)]
+	if len(ids) == 0 {
+		return nil, NewNotFoundError("post")
+	}
+
+	logger.Debug("successfully retrieved")
+	return copyPost(d.store[createCompositeID(customerID, ids[0])]), nil
+}
+
+// List returns up to opts.Limit of the customer's posts that match opts.Filter,
+// after opts.After in the order of opts.Sort
+func (d *InMemoryDatastore) List(customerID string, opts dao.ListOptions) ([]*dao.Post, error) {
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"after":      opts.After.Hex(),
+		"sort":       opts.Sort,
+	})
+
+	logger.Info("listing from memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var posts []*dao.Post
+	if opts.Sort == "" {
+		posts = d.listByID(customerID, opts)
+	} else {
+		posts = d.listBySortTime(customerID, opts)
+	}
+	for i, post := range posts {
+		posts[i] = copyPost(post)
+	}
+
+	logger.Debug("successfully listed")
+	return posts, nil
+}
+
+// listByID returns a page of posts in id order. The page starts after the last
+// post of the previous page in the id index, so only the posts of the page and
+// the ones the filter skips are read. It must be called with the lock held
+func (d *InMemoryDatastore) listByID(customerID string, opts dao.ListOptions) []*dao.Post {
+	// Walk the index from the first post of the page, backwards when descending
+	ids := d.ids[customerID]
+	i, step := 0, 1
+	if opts.Descending {
+		i, step = len(ids)-1, -1
+		if opts.After != "" {
+			i = sort.Search(len(ids), func(i int) bool { return ids[i] >= opts.After }) - 1
+		}
+	} else if opts.After != "" {
+		i = sort.Search(len(ids), func(i int) bool { return ids[i] > opts.After })
+	}
+
+	posts := []*dao.Post{}
+	for ; i >= 0 && i < len(ids); i += step {
+		if opts.Limit > 0 && len(posts) == opts.Limit {
+			break
+		}
+		post := d.store[createCompositeID(customerID, ids[i])]
+		if opts.Filter.Match(post) {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+// listBySortTime returns a page of posts sorted by the time of opts.Sort. It
+// must be called with the lock held
+func (d *InMemoryDatastore) listBySortTime(customerID string, opts dao.ListOptions) []*dao.Post {
+	posts := []*dao.Post{}
+	for _, id := range d.ids[customerID] {
+		post := d.store[createCompositeID(customerID, id)]
+		if opts.Listed(post) {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return opts.Less(posts[i], posts[j])
+	})
+	if opts.Limit > 0 && len(posts) > opts.Limit {
+		posts = posts[:opts.Limit]
+	}
+	return posts
+}
+
+// Revisions returns every version of the post, oldest first
+func (d *InMemoryDatastore) Revisions(customerID string, postID bson.ObjectId) ([]*dao.Revision, error) {
+	if postID == "" {
+		return nil, NewInvalidArugmentError("postID")
+	}
+
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	storeID := createCompositeID(customerID, postID)
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"postID":     postID.Hex(),
+	})
+
+	logger.Info("retrieving revisions from memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.store[storeID]; !ok {
+		return nil, NewNotFoundError("post")
+	}
+
+	revisions := make([]*dao.Revision, len(d.revisions[storeID]))
+	copy(revisions, d.revisions[storeID])
+
+	logger.Debug("successfully retrieved revisions")
+	return revisions, nil
+}
+
+// Restore sets the post's captions back to those of the revision. The post goes
+// back to draft, and the restore is stored as a new revision
+func (d *InMemoryDatastore) Restore(customerID string, postID bson.ObjectId, number int, author string) (*dao.Post, error) {
+	if postID == "" {
+		return nil, NewInvalidArugmentError("postID")
+	}
+
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	storeID := createCompositeID(customerID, postID)
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"postID":     postID.Hex(),
+		"revision":   number,
+	})
+
+	logger.Info("restoring revision in memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, ok := d.store[storeID]
+	if !ok {
+		return nil, NewNotFoundError("post")
+	}
+	if prev.Status == dao.StatusPublishing {
+		return nil, NewPreconditionFailedError("post is being published")
+	}
+	revisions := d.revisions[storeID]
+	if number < 1 || number > len(revisions) {
+		return nil, NewNotFoundError("revision")
+	}
+
+	restored := copyPost(prev)
+	restored.Captions = dao.MergeCaptions(prev.Captions, revisions[number-1].Captions)
+	restored.Status = dao.StatusDraft
+	d.touch(restored, author)
+	if err := d.commit(d.revise(restored)); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("successfully restored revision")
+	return copyPost(restored), nil
+}
+
+// Patch applies the patch to a copy of the post while holding the lock. The
+// captions and status of the patched post are stored as a new revision, its id,
+// customer and url must not have changed
+func (d *InMemoryDatastore) Patch(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+	if postID == "" {
+		return nil, NewInvalidArugmentError("postID")
+	}
+
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	if patch == nil {
+		return nil, NewInvalidArugmentError("must provide patch")
+	}
+
+	storeID := createCompositeID(customerID, postID)
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"postID":     postID.Hex(),
+	})
+
+	logger.Info("patching in memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, ok := d.store[storeID]
+	if !ok {
+		return nil, NewNotFoundError("post")
+	}
+	if prev.Status == dao.StatusPublishing {
+		return nil, NewPreconditionFailedError("post is being published")
+	}
+
+	patched, err := patch(copyPost(prev))
+	if err != nil {
+		return nil, err
+	}
+	if patched == nil || patched.ID == nil || *patched.ID != postID || patched.CustID != prev.CustID || patched.URL != prev.URL || patched.CanonicalURL != prev.CanonicalURL {
+		return nil, NewInvalidArugmentError("patch, only the captions and status can be changed")
+	}
+	if patched.Status != prev.Status {
+		if !dao.ValidStatus(patched.Status) {
+			return nil, NewInvalidArugmentError("status")
+		}
+		if err := checkTransition(prev.Status, patched.Status); err != nil {
+			return nil, err
+		}
+	}
+
+	next := copyPost(prev)
+	captions := dao.MergeCaptions(prev.Captions, patched.Captions)
+	unapprove(next, captions)
+	next.Captions = captions
+	if patched.Status != prev.Status {
+		next.Status = patched.Status
+	}
+	d.touch(next, author)
+	if err := d.commit(d.revise(next)); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("successfully patched post")
+	return copyPost(next), nil
+}
+
+// Schedule sets the time and channels the post is published at. Posts that are
+// being, or have been, published can not be scheduled
+func (d *InMemoryDatastore) Schedule(customerID string, post *dao.Post) (*dao.Post, error) {
 	if post == nil {
-		return nil, fmt.Errorf("must provide post")
+		return nil, NewInvalidArugmentError("must provide post")
 	}
 
 	if post.ID == nil {
@@ -126,30 +597,347 @@ func (d *InMemoryDatastore) Update(customerID string, post *dao.Post) (*dao.Post
 		"postID":     post.ID.Hex(),
 	})
 
-	logger.Info("updating in memory map")
+	logger.Info("scheduling in memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	// Create composite id
 	storeID := createCompositeID(customerID, *post.ID)
+	prev, ok := d.store[storeID]
+	if !ok {
+		return nil, NewNotFoundError("post")
+	}
+	switch prev.Status {
+	case dao.StatusPublishing:
+		return nil, NewPreconditionFailedError("post is being published")
+	case dao.StatusPublished:
+		return nil, NewPreconditionFailedError("post is already published")
+	}
+
+	scheduled := copyPost(prev)
+	if post.ScheduledAt == nil {
+		scheduled.ScheduledAt = nil
+		scheduled.Channels = nil
+	} else {
+		at := post.ScheduledAt.UTC()
+		scheduled.ScheduledAt = &at
+		scheduled.Channels = append([]string(nil), post.Channels...)
+	}
+	d.touch(scheduled, post.UpdatedBy)
+	// Scheduling does not change the captions, so it is not a revision
+	if err := d.commit(&change{customerID: customerID, postID: *post.ID, post: scheduled, revisions: d.revisions[storeID]}); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("successfully scheduled post")
+	return copyPost(scheduled), nil
+}
+
+// ClaimDue moves the approved posts that are due to publishing, earliest first,
+// and holds them until lease has passed. Posts still publishing once their
+// claim ran out are claimed again. The status is checked and changed while
+// holding the lock, so concurrent claims never return the same post
+func (d *InMemoryDatastore) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*dao.Post, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	due := []string{}
+	for storeID, post := range d.store {
+		if post.ScheduledAt == nil || post.ScheduledAt.After(now) {
+			continue
+		}
+		if post.Status == dao.StatusApproved || post.Status == dao.StatusPublishing && claimExpired(post, now) {
+			due = append(due, storeID)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return d.store[due[i]].ScheduledAt.Before(*d.store[due[j]].ScheduledAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	until := now.Add(lease)
+	changes := make([]*change, len(due))
+	claimed := make([]*dao.Post, len(due))
+	for i, storeID := range due {
+		post := copyPost(d.store[storeID])
+		if post.Status == dao.StatusPublishing {
+			d.logger.WithFields(log.Fields{
+				"customerID": post.CustID,
+				"postID":     post.ID.Hex(),
+			}).Warn("claim of post being published ran out, claiming it again")
+		}
+		post.Status = dao.StatusPublishing
+		post.ClaimedUntil = &until
+		d.touch(post, dao.ActorScheduler)
+		changes[i] = d.revise(post)
+		claimed[i] = copyPost(post)
+	}
+	if err := d.commit(changes...); err != nil {
+		return nil, err
+	}
+
+	if len(claimed) > 0 {
+		d.logger.WithFields(log.Fields{
+			"posts": len(claimed),
+		}).Info("claimed posts to publish")
+	}
+	return claimed, nil
+}
+
+// CompletePublish records the publications of a post being published. The post
+// is published if every channel succeeded
+func (d *InMemoryDatastore) CompletePublish(customerID string, postID bson.ObjectId, publications []*dao.Publication) (*dao.Post, error) {
+	if postID == "" {
+		return nil, NewInvalidArugmentError("postID")
+	}
+
+	if customerID == "" {
+		return nil, NewInvalidArugmentError("customerID")
+	}
+
+	storeID := createCompositeID(customerID, postID)
+	logger := d.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"postID":     postID.Hex(),
+	})
+
+	logger.Info("completing publish in memory map")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	// Find post in the datastore, if ok is false, the post DNE
 	prev, ok := d.store[storeID]
 	if !ok {
 		return nil, NewNotFoundError("post")
 	}
+	if prev.Status != dao.StatusPublishing {
+		return nil, NewPreconditionFailedError("post is not being published")
+	}
+
+	published := copyPost(prev)
+	published.Status = dao.StatusPublished
+	published.ClaimedUntil = nil
+	for _, p := range publications {
+		copied := *p
+		published.Publications = append(published.Publications, &copied)
+		if p.Error != "" {
+			published.Status = dao.StatusPublishFailed
+		}
+	}
+	d.touch(published, dao.ActorScheduler)
+	if published.Status == dao.StatusPublished {
+		published.PublishedAt = published.UpdatedAt
+	}
+	if err := d.commit(d.revise(published)); err != nil {
+		return nil, err
+	}
 
-	// Only copy over captions
-	prev.Captions = post.Captions
+	logger.WithFields(log.Fields{
+		"status": published.Status,
+	}).Debug("successfully completed publish")
+	return copyPost(published), nil
+}
 
-	// Store post
-	d.store[storeID] = prev
+// claimExpired returns true if the claim of a post being published ran out. A
+// claim made before a restart is not stored, so it has run out
+func claimExpired(post *dao.Post, now time.Time) bool {
+	return post.ClaimedUntil == nil || !post.ClaimedUntil.After(now)
+}
 
-	logger.Debug("successfully updated post")
-	return prev, nil
+// checkTransition returns a conflict unless a post may be moved between the
+// statuses
+func checkTransition(from, to string) error {
+	if !dao.CanTransition(from, to) {
+		return NewConflictError(fmt.Sprintf("post can not be %s when it is %s", to, from))
+	}
+	return nil
 }
 
-// Delete is not implemented for the in memory datastore
-func (d *InMemoryDatastore) Delete(customerID string, postID bson.ObjectId) error {
-	return fmt.Errorf("unimplemented")
+// unapprove moves an approved post back to draft when its captions change, so a
+// post is only published with the captions that were approved
+func unapprove(post *dao.Post, captions []*dao.Caption) {
+	if post.Status == dao.StatusApproved && !dao.EqualCaptions(post.Captions, captions) {
+		post.Status = dao.StatusDraft
+	}
+}
+
+// touch records the author as the last to change the post, now. It must be
+// called with the lock held
+func (d *InMemoryDatastore) touch(post *dao.Post, author string) {
+	now := d.now().UTC()
+	post.UpdatedAt = &now
+	post.UpdatedBy = author
+}
+
+// revise returns the change that stores the post with a new revision of it. It
+// must be called with the lock held
+func (d *InMemoryDatastore) revise(post *dao.Post) *change {
+	storeID := createCompositeID(post.CustID, *post.ID)
+	revisions := d.revisions[storeID]
+	var previous []*dao.Caption
+	if len(revisions) > 0 {
+		previous = revisions[len(revisions)-1].Captions
+	}
+
+	captions := dao.CopyCaptions(post.Captions)
+	revision := &dao.Revision{
+		Number:    len(revisions) + 1,
+		PostID:    *post.ID,
+		Author:    post.UpdatedBy,
+		CreatedAt: *post.UpdatedAt,
+		Captions:  captions,
+		Status:    post.Status,
+		Diff:      dao.DiffCaptions(dao.CaptionTexts(previous), dao.CaptionTexts(captions)),
+	}
+	// The stored revisions are not appended to in place, they are only replaced
+	// once the change is written
+	return &change{
+		customerID: post.CustID,
+		postID:     *post.ID,
+		post:       post,
+		revisions:  append(revisions[:len(revisions):len(revisions)], revision),
+	}
+}
+
+// commit writes the changes and then stores them, nothing is stored if they can
+// not be written. It must be called with the lock held
+func (d *InMemoryDatastore) commit(changes ...*change) error {
+	if d.write != nil {
+		if err := d.write(changes); err != nil {
+			return err
+		}
+	}
+	for _, c := range changes {
+		d.apply(c)
+	}
+	return nil
+}
+
+// apply stores a change, replacing the post and its revisions or removing them.
+// It must be called with the lock held
+func (d *InMemoryDatastore) apply(c *change) {
+	storeID := createCompositeID(c.customerID, c.postID)
+	prev, ok := d.store[storeID]
+	if c.post == nil {
+		if !ok {
+			return
+		}
+		delete(d.store, storeID)
+		delete(d.revisions, storeID)
+		if prev.CanonicalURL != "" {
+			d.removeURL(c.customerID, prev.CanonicalURL, c.postID)
+		}
+		d.removeID(c.customerID, c.postID)
+		return
+	}
+
+	if !ok {
+		d.addID(c.customerID, c.postID)
+	}
+
+	if !ok && c.post.CanonicalURL != "" {
+		urlID := createURLID(c.customerID, c.post.CanonicalURL)
+		ids := append(d.urls[urlID], c.postID)
+		sort.Slice(ids, func(i, j int) bool {
+			return ids[i] < ids[j]
+		})
+		d.urls[urlID] = ids
+	}
+	d.store[storeID] = c.post
+	d.revisions[storeID] = c.revisions
+}
+
+// put stores the post with its revisions as they are, replacing any post with
+// the same id. It is used to load posts that were stored before
+func (d *InMemoryDatastore) put(customerID string, post *dao.Post, revisions []*dao.Revision) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	post.CustID = customerID
+	return d.commit(&change{customerID: customerID, postID: *post.ID, post: post, revisions: revisions})
+}
+
+// addID adds the post id to the customer's id index, keeping it in id order
+func (d *InMemoryDatastore) addID(customerID string, postID bson.ObjectId) {
+	ids := d.ids[customerID]
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] > postID })
+	ids = append(ids, "")
+	copy(ids[i+1:], ids[i:])
+	ids[i] = postID
+	d.ids[customerID] = ids
+}
+
+// removeID removes the post id from the customer's id index
+func (d *InMemoryDatastore) removeID(customerID string, postID bson.ObjectId) {
+	ids := d.ids[customerID]
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] >= postID })
+	if i == len(ids) || ids[i] != postID {
+		return
+	}
+	ids = append(ids[:i], ids[i+1:]...)
+	if len(ids) == 0 {
+		delete(d.ids, customerID)
+		return
+	}
+	d.ids[customerID] = ids
+}
+
+func (d *InMemoryDatastore) removeURL(customerID, canonicalURL string, postID bson.ObjectId) {
+	urlID := createURLID(customerID, canonicalURL)
+	ids := d.urls[urlID]
+	for i, id := range ids {
+		if id == postID {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(d.urls, urlID)
+		return
+	}
+	d.urls[urlID] = ids
+}
+
+func checkInsert(post *dao.Post) error {
+	if post == nil {
+		return NewInvalidArugmentError("must provide post")
+	}
+
+	if post.ID != nil {
+		return NewInvalidArugmentError("cannot provide ID")
+	}
+	return nil
+}
+
+func checkUpdate(post *dao.Post) error {
+	if post == nil {
+		return NewInvalidArugmentError("must provide post")
+	}
+
+	if post.ID == nil {
+		return NewInvalidArugmentError("postID")
+	}
+
+	if post.Status != "" && !dao.ValidStatus(post.Status) {
+		return NewInvalidArugmentError("status")
+	}
+	return nil
+}
+
+// copyPost copies the post so it can be read without the lock
+func copyPost(post *dao.Post) *dao.Post {
+	copied := *post
+	copied.Captions = dao.CopyCaptions(post.Captions)
+	copied.RejectedCaptions = dao.CopyRejections(post.RejectedCaptions)
+	copied.Channels = append([]string(nil), post.Channels...)
+	copied.Publications = append([]*dao.Publication(nil), post.Publications...)
+	return &copied
+}
+
+func createURLID(customerID, canonicalURL string) string {
+	return fmt.Sprintf("%s:%s", customerID, canonicalURL)
 }
 
 func createCompositeID(customerID string, postID bson.ObjectId) string {
@@ -2,6 +2,8 @@ package datastore
 
 import (
 	"io/ioutil"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -17,12 +19,15 @@ var _ = Describe("InMemoryDatastore", func() {
 		ds         *InMemoryDatastore
 		post       *dao.Post
 		customerID string
+		now        time.Time
 	)
 
 	BeforeEach(func() {
 		logger = log.New()
 		logger.Out = ioutil.Discard
 		ds = NewInMemoryDatastore(logger)
+		now = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		ds.now = func() time.Time { return now }
 		customerID = "test-customer"
 	})
 
@@ -69,11 +74,11 @@ var _ = Describe("InMemoryDatastore", func() {
 			BeforeEach(func() {
 				post = &dao.Post{
 					URL: "test-url",
-					Captions: []string{
+					Captions: dao.ManualCaptions([]string{
 						"caption1",
 						"caption2",
 						"caption3",
-					},
+					}),
 				}
 			})
 
@@ -105,7 +110,18 @@ var _ = Describe("InMemoryDatastore", func() {
 					Expect(retPost.ID).NotTo(Equal(""))
 					Expect(retPost.CustID).To(Equal(customerID))
 					Expect(retPost.URL).To(Equal(post.URL))
-					Expect(retPost.Captions).To(Equal(post.Captions))
+					Expect(dao.CaptionTexts(retPost.Captions)).To(Equal(dao.CaptionTexts(post.Captions)))
+					for _, caption := range retPost.Captions {
+						Expect(caption.ID).NotTo(BeEmpty())
+						Expect(caption.Source).To(Equal(dao.SourceManual))
+					}
+					Expect(retPost.Status).To(Equal(dao.StatusDraft))
+				})
+
+				It("should record when and by whom the post was created", func() {
+					Expect(*retPost.CreatedAt).To(Equal(now))
+					Expect(*retPost.UpdatedAt).To(Equal(now))
+					Expect(retPost.CreatedBy).To(Equal(post.UpdatedBy))
 				})
 
 				It("should insert a post", func() {
@@ -191,11 +207,11 @@ var _ = Describe("InMemoryDatastore", func() {
 							ID:     &postID,
 							CustID: customerID,
 							URL:    "test-url",
-							Captions: []string{
+							Captions: dao.ManualCaptions([]string{
 								"caption1",
 								"caption2",
 								"caption3",
-							},
+							}),
 						}
 						ds.store[storeID] = post
 					})
@@ -212,6 +228,44 @@ var _ = Describe("InMemoryDatastore", func() {
 		})
 	})
 
+	Describe("returned posts", func() {
+		var stored *dao.Post
+
+		BeforeEach(func() {
+			var err error
+			stored, err = ds.Insert(customerID, &dao.Post{URL: "https://example.com/a", Captions: dao.ManualCaptions([]string{"caption1"})})
+			Expect(err).To(BeNil())
+		})
+
+		It("should NOT change the stored post when changed", func() {
+			stored.Captions[0].Text = "changed"
+			got, err := ds.Get(customerID, *stored.ID)
+			Expect(err).To(BeNil())
+			got.Status = dao.StatusPublished
+			byURL, err := ds.GetByURL(customerID, "https://example.com/a")
+			Expect(err).To(BeNil())
+			Expect(dao.CaptionTexts(byURL.Captions)).To(Equal([]string{"caption1"}))
+			Expect(byURL.Status).To(Equal(dao.StatusDraft))
+		})
+
+		It("should be safe to read while the post is updated", func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 100; i++ {
+					ds.Update(customerID, &dao.Post{ID: stored.ID, Captions: dao.ManualCaptions([]string{"caption2"})})
+				}
+			}()
+			for i := 0; i < 100; i++ {
+				got, err := ds.Get(customerID, *stored.ID)
+				Expect(err).To(BeNil())
+				got.Captions[0].Text = "changed"
+				Expect(got.UpdatedAt).NotTo(BeNil())
+			}
+			<-done
+		})
+	})
+
 	Describe("Update", func() {
 		var (
 			retPost *dao.Post
@@ -226,7 +280,7 @@ var _ = Describe("InMemoryDatastore", func() {
 		Context("without post", func() {
 			It("should return an error", func() {
 				Expect(err).NotTo(BeNil())
-				Expect(err.Error()).To(Equal("must provide post"))
+				Expect(err.Error()).To(Equal("invalid must provide post"))
 			})
 
 			It("should NOT return a post", func() {
@@ -239,11 +293,11 @@ var _ = Describe("InMemoryDatastore", func() {
 				post = &dao.Post{
 					CustID: customerID,
 					URL:    "test-url",
-					Captions: []string{
+					Captions: dao.ManualCaptions([]string{
 						"caption4",
 						"caption5",
 						"caption6",
-					},
+					}),
 				}
 			})
 
@@ -292,11 +346,11 @@ var _ = Describe("InMemoryDatastore", func() {
 							ID:     &postID,
 							CustID: customerID,
 							URL:    "test-url",
-							Captions: []string{
+							Captions: dao.ManualCaptions([]string{
 								"caption1",
 								"caption2",
 								"caption3",
-							},
+							}),
 						}
 						ds.store[storeID] = storedPost
 					})
@@ -306,6 +360,9 @@ var _ = Describe("InMemoryDatastore", func() {
 					})
 
 					It("should return a post", func() {
+						post.UpdatedAt = &now
+						Expect(dao.CaptionTexts(retPost.Captions)).To(Equal(dao.CaptionTexts(post.Captions)))
+						post.Captions = retPost.Captions
 						Expect(retPost).To(Equal(post))
 					})
 
@@ -313,6 +370,810 @@ var _ = Describe("InMemoryDatastore", func() {
 						storeID := createCompositeID(customerID, *retPost.ID)
 						Expect(ds.store).To(HaveKeyWithValue(storeID, retPost))
 					})
+
+					Context("with invalid status", func() {
+						BeforeEach(func() {
+							post.Status = "blah"
+						})
+
+						It("should return an error", func() {
+							Expect(err).NotTo(BeNil())
+							Expect(err.Error()).To(Equal("invalid status"))
+						})
+					})
+
+					Context("with status", func() {
+						BeforeEach(func() {
+							post.Status = dao.StatusApproved
+						})
+
+						It("should update the post status", func() {
+							Expect(err).To(BeNil())
+							Expect(retPost.Status).To(Equal(dao.StatusApproved))
+						})
+					})
+				})
+			})
+		})
+	})
+	Describe("InsertBatch", func() {
+		It("should return an error without customerID", func() {
+			results, err := ds.InsertBatch("", []*dao.Post{{URL: "https://example.com"}})
+			Expect(err.Error()).To(Equal("invalid customerID"))
+			Expect(results).To(BeNil())
+		})
+
+		It("should insert every valid post", func() {
+			id := bson.NewObjectId()
+			results, err := ds.InsertBatch(customerID, []*dao.Post{
+				{URL: "https://example.com/1"},
+				{ID: &id},
+				nil,
+				{URL: "https://example.com/2"},
+			})
+			Expect(err).To(BeNil())
+			Expect(results).To(HaveLen(4))
+			Expect(results[0].Post.URL).To(Equal("https://example.com/1"))
+			Expect(results[1].Err.Error()).To(Equal("invalid cannot provide ID"))
+			Expect(results[2].Err.Error()).To(Equal("invalid must provide post"))
+			Expect(results[3].Post.Status).To(Equal(dao.StatusDraft))
+			Expect(ds.store).To(HaveLen(2))
+		})
+	})
+
+	Describe("List", func() {
+		var ids []bson.ObjectId
+
+		BeforeEach(func() {
+			ids = []bson.ObjectId{}
+			for i := 0; i < 3; i++ {
+				inserted, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com"})
+				Expect(err).To(BeNil())
+				ids = append(ids, *inserted.ID)
+			}
+			_, err := ds.Insert("other-customer", &dao.Post{URL: "https://example.com"})
+			Expect(err).To(BeNil())
+		})
+
+		It("should return an error without customerID", func() {
+			_, err := ds.List("", dao.ListOptions{})
+			Expect(err.Error()).To(Equal("invalid customerID"))
+		})
+
+		It("should return a page of the customer's posts in id order", func() {
+			posts, err := ds.List(customerID, dao.ListOptions{Limit: 2})
+			Expect(err).To(BeNil())
+			Expect(posts).To(HaveLen(2))
+			Expect(*posts[0].ID).To(Equal(ids[0]))
+			Expect(*posts[1].ID).To(Equal(ids[1]))
+
+			posts, err = ds.List(customerID, dao.ListOptions{After: ids[1], Limit: 2})
+			Expect(err).To(BeNil())
+			Expect(posts).To(HaveLen(1))
+			Expect(*posts[0].ID).To(Equal(ids[2]))
+		})
+
+		It("should return a page of the customer's posts in descending id order", func() {
+			posts, err := ds.List(customerID, dao.ListOptions{Descending: true, Limit: 2})
+			Expect(err).To(BeNil())
+			Expect(posts).To(HaveLen(2))
+			Expect(*posts[0].ID).To(Equal(ids[2]))
+			Expect(*posts[1].ID).To(Equal(ids[1]))
+
+			posts, err = ds.List(customerID, dao.ListOptions{Descending: true, After: ids[1], Limit: 2})
+			Expect(err).To(BeNil())
+			Expect(posts).To(HaveLen(1))
+			Expect(*posts[0].ID).To(Equal(ids[0]))
+		})
+
+		It("should not return deleted posts", func() {
+			Expect(ds.Delete(customerID, ids[1])).To(Succeed())
+			posts, err := ds.List(customerID, dao.ListOptions{After: ids[0]})
+			Expect(err).To(BeNil())
+			Expect(posts).To(HaveLen(1))
+			Expect(*posts[0].ID).To(Equal(ids[2]))
+		})
+
+		Context("with posts updated at different times", func() {
+			BeforeEach(func() {
+				// The first post is updated last, by another author
+				now = now.Add(time.Hour)
+				_, err := ds.Update(customerID, &dao.Post{ID: &ids[0], UpdatedBy: "editor"})
+				Expect(err).To(BeNil())
+			})
+
+			It("should keep the created time and author", func() {
+				post, err := ds.Get(customerID, ids[0])
+				Expect(err).To(BeNil())
+				Expect(*post.CreatedAt).To(Equal(now.Add(-time.Hour)))
+				Expect(*post.UpdatedAt).To(Equal(now))
+				Expect(post.CreatedBy).To(Equal(""))
+				Expect(post.UpdatedBy).To(Equal("editor"))
+			})
+
+			It("should return a page of the customer's posts in the sort order", func() {
+				opts := dao.ListOptions{Sort: dao.SortUpdatedAt, Descending: true, Limit: 2}
+				posts, err := ds.List(customerID, opts)
+				Expect(err).To(BeNil())
+				Expect(posts).To(HaveLen(2))
+				Expect(*posts[0].ID).To(Equal(ids[0]))
+				Expect(*posts[1].ID).To(Equal(ids[2]))
+
+				opts.After, opts.AfterTime = *posts[1].ID, *posts[1].UpdatedAt
+				posts, err = ds.List(customerID, opts)
+				Expect(err).To(BeNil())
+				Expect(posts).To(HaveLen(1))
+				Expect(*posts[0].ID).To(Equal(ids[1]))
+			})
+
+			It("should only return the posts that match the filter", func() {
+				posts, err := ds.List(customerID, dao.ListOptions{Filter: dao.ListFilter{UpdatedBy: "editor"}})
+				Expect(err).To(BeNil())
+				Expect(posts).To(HaveLen(1))
+				Expect(*posts[0].ID).To(Equal(ids[0]))
+
+				posts, err = ds.List(customerID, dao.ListOptions{Filter: dao.ListFilter{UpdatedBefore: now}})
+				Expect(err).To(BeNil())
+				Expect(posts).To(HaveLen(2))
+			})
+		})
+	})
+
+	Describe("UpdateBatch", func() {
+		It("should update every post that exists", func() {
+			inserted, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com"})
+			Expect(err).To(BeNil())
+			missing := bson.NewObjectId()
+			results, err := ds.UpdateBatch(customerID, []*dao.Post{
+				{ID: &missing, Captions: dao.ManualCaptions([]string{"caption"})},
+				{ID: inserted.ID, Captions: dao.ManualCaptions([]string{"caption"})},
+			})
+			Expect(err).To(BeNil())
+			Expect(results[0].Err.Error()).To(Equal("post not found"))
+			Expect(dao.CaptionTexts(results[1].Post.Captions)).To(Equal([]string{"caption"}))
+		})
+	})
+
+	Describe("Delete", func() {
+		var (
+			err    error
+			postID bson.ObjectId
+		)
+
+		BeforeEach(func() {
+			postID = bson.NewObjectId()
+		})
+
+		JustBeforeEach(func() {
+			err = ds.Delete(customerID, postID)
+		})
+
+		Context("with post not found", func() {
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("post not found"))
+			})
+		})
+
+		Context("with another customer's post", func() {
+			BeforeEach(func() {
+				storeID := createCompositeID("other-customer", postID)
+				ds.store[storeID] = &dao.Post{ID: &postID, CustID: "other-customer"}
+			})
+
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("post not found"))
+			})
+		})
+
+		Context("with post found", func() {
+			BeforeEach(func() {
+				storeID := createCompositeID(customerID, postID)
+				ds.store[storeID] = &dao.Post{ID: &postID, CustID: customerID}
+			})
+
+			It("should delete the post", func() {
+				Expect(err).To(BeNil())
+				Expect(ds.store).To(BeEmpty())
+			})
+		})
+	})
+	Describe("GetByURL", func() {
+		var (
+			retPost      *dao.Post
+			err          error
+			canonicalURL string
+		)
+
+		BeforeEach(func() {
+			canonicalURL = "https://example.com/post"
+		})
+
+		JustBeforeEach(func() {
+			retPost, err = ds.GetByURL(customerID, canonicalURL)
+		})
+
+		Context("without url", func() {
+			BeforeEach(func() {
+				canonicalURL = ""
+			})
+
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("invalid url"))
+			})
+		})
+
+		Context("with post not found", func() {
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("post not found"))
+				Expect(retPost).To(BeNil())
+			})
+		})
+
+		Context("with posts for a variant of the url", func() {
+			var (
+				first *dao.Post
+			)
+
+			BeforeEach(func() {
+				first, _ = ds.Insert(customerID, &dao.Post{URL: "http://www.example.com/post/?utm_source=twitter"})
+				ds.Insert(customerID, &dao.Post{URL: "https://example.com/post"})
+				ds.Insert("other-customer", &dao.Post{URL: "https://example.com/post"})
+			})
+
+			It("should store the canonical url", func() {
+				Expect(first.CanonicalURL).To(Equal(canonicalURL))
+			})
+
+			It("should return the oldest post", func() {
+				Expect(err).To(BeNil())
+				Expect(retPost).To(Equal(first))
+			})
+
+			Context("with the oldest post deleted", func() {
+				BeforeEach(func() {
+					Expect(ds.Delete(customerID, *first.ID)).To(BeNil())
+				})
+
+				It("should return the next post", func() {
+					Expect(err).To(BeNil())
+					Expect(retPost.ID).NotTo(Equal(first.ID))
+					Expect(retPost.CustID).To(Equal(customerID))
+				})
+			})
+		})
+
+		Context("with another customer's post", func() {
+			BeforeEach(func() {
+				ds.Insert("other-customer", &dao.Post{URL: "https://example.com/post"})
+			})
+
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("post not found"))
+			})
+		})
+	})
+	Describe("changing the captions of an approved post", func() {
+		It("should move the post back to draft", func() {
+			cases := []struct {
+				name   string
+				change func(post *dao.Post) (*dao.Post, error)
+				status string
+			}{
+				{"an update", func(post *dao.Post) (*dao.Post, error) {
+					return ds.Update(customerID, &dao.Post{ID: post.ID, Captions: dao.ManualCaptions([]string{"changed"})})
+				}, dao.StatusDraft},
+				{"a batch update", func(post *dao.Post) (*dao.Post, error) {
+					results, err := ds.UpdateBatch(customerID, []*dao.Post{{ID: post.ID, Captions: dao.ManualCaptions([]string{"changed"})}})
+					Expect(err).To(BeNil())
+					return results[0].Post, results[0].Err
+				}, dao.StatusDraft},
+				{"an update with the same captions", func(post *dao.Post) (*dao.Post, error) {
+					return ds.Update(customerID, &dao.Post{ID: post.ID, Captions: post.Captions})
+				}, dao.StatusApproved},
+				{"a patch that selects a caption", func(post *dao.Post) (*dao.Post, error) {
+					return ds.Patch(customerID, *post.ID, func(stored *dao.Post) (*dao.Post, error) {
+						stored.Captions[1].Selected = true
+						return stored, nil
+					}, "alice")
+				}, dao.StatusDraft},
+				{"a patch that reorders the captions", func(post *dao.Post) (*dao.Post, error) {
+					return ds.Patch(customerID, *post.ID, func(stored *dao.Post) (*dao.Post, error) {
+						stored.Captions[0], stored.Captions[1] = stored.Captions[1], stored.Captions[0]
+						return stored, nil
+					}, "alice")
+				}, dao.StatusDraft},
+				{"a patch that changes nothing", func(post *dao.Post) (*dao.Post, error) {
+					return ds.Patch(customerID, *post.ID, func(stored *dao.Post) (*dao.Post, error) {
+						return stored, nil
+					}, "alice")
+				}, dao.StatusApproved},
+			}
+			for _, c := range cases {
+				inserted, err := ds.Insert(customerID, &dao.Post{URL: "https://example.com/post", Captions: dao.ManualCaptions([]string{"a", "b"})})
+				Expect(err).To(BeNil(), c.name)
+				approved, err := ds.Update(customerID, &dao.Post{ID: inserted.ID, Captions: inserted.Captions, Status: dao.StatusApproved})
+				Expect(err).To(BeNil(), c.name)
+				Expect(approved.Status).To(Equal(dao.StatusApproved), c.name)
+
+				changed, err := c.change(approved)
+				Expect(err).To(BeNil(), c.name)
+				Expect(changed.Status).To(Equal(c.status), c.name)
+			}
+		})
+	})
+
+	Describe("Revisions", func() {
+		var (
+			inserted  *dao.Post
+			revisions []*dao.Revision
+			err       error
+			now       time.Time
+		)
+
+		BeforeEach(func() {
+			now = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			ds.now = func() time.Time { return now }
+			inserted, _ = ds.Insert(customerID, &dao.Post{URL: "https://example.com/post", Captions: dao.ManualCaptions([]string{"a", "b"}), UpdatedBy: "alice"})
+			now = now.Add(time.Minute)
+			ds.Update(customerID, &dao.Post{ID: inserted.ID, Captions: dao.ManualCaptions([]string{"a", "c"}), UpdatedBy: "bob"})
+		})
+
+		JustBeforeEach(func() {
+			revisions, err = ds.Revisions(customerID, *inserted.ID)
+		})
+
+		It("should store a revision for the insert and every update", func() {
+			Expect(err).To(BeNil())
+			Expect(revisions).To(HaveLen(2))
+			Expect(dao.CaptionTexts(revisions[0].Captions)).To(Equal([]string{"a", "b"}))
+			Expect(dao.CaptionTexts(revisions[1].Captions)).To(Equal([]string{"a", "c"}))
+			// The caption that was kept keeps its id
+			Expect(revisions[1].Captions[0].ID).To(Equal(revisions[0].Captions[0].ID))
+			Expect(revisions).To(Equal([]*dao.Revision{
+				{
+					Number:    1,
+					PostID:    *inserted.ID,
+					Author:    "alice",
+					CreatedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+					Captions:  revisions[0].Captions,
+					Status:    dao.StatusDraft,
+					Diff: []dao.CaptionChange{
+						{Op: dao.OpAdded, Index: 0, Caption: "a"},
+						{Op: dao.OpAdded, Index: 1, Caption: "b"},
+					},
+				},
+				{
+					Number:    2,
+					PostID:    *inserted.ID,
+					Author:    "bob",
+					CreatedAt: time.Date(2020, 1, 2, 3, 5, 5, 0, time.UTC),
+					Captions:  revisions[1].Captions,
+					Status:    dao.StatusDraft,
+					Diff: []dao.CaptionChange{
+						{Op: dao.OpRemoved, Index: 1, Caption: "b"},
+						{Op: dao.OpAdded, Index: 1, Caption: "c"},
+					},
+				},
+			}))
+		})
+
+		Context("with another customer", func() {
+			BeforeEach(func() {
+				customerID = "other-customer"
+			})
+
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("post not found"))
+			})
+		})
+
+		Context("with the post deleted", func() {
+			BeforeEach(func() {
+				Expect(ds.Delete(customerID, *inserted.ID)).To(BeNil())
+			})
+
+			It("should return an error", func() {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(Equal("post not found"))
+			})
+		})
+
+		Describe("Restore", func() {
+			var (
+				restored   *dao.Post
+				restoreErr error
+				number     int
+			)
+
+			BeforeEach(func() {
+				number = 1
+				ds.Update(customerID, &dao.Post{ID: inserted.ID, Status: dao.StatusApproved, Captions: dao.ManualCaptions([]string{"a", "c"})})
+			})
+
+			JustBeforeEach(func() {
+				restored, restoreErr = ds.Restore(customerID, *inserted.ID, number, "carol")
+				revisions, _ = ds.Revisions(customerID, *inserted.ID)
+			})
+
+			It("should restore the captions and go back to draft", func() {
+				Expect(restoreErr).To(BeNil())
+				Expect(dao.CaptionTexts(restored.Captions)).To(Equal([]string{"a", "b"}))
+				Expect(restored.Status).To(Equal(dao.StatusDraft))
+				Expect(restored.UpdatedBy).To(Equal("carol"))
+			})
+
+			It("should store the restore as a revision", func() {
+				Expect(revisions).To(HaveLen(4))
+				last := revisions[3]
+				Expect(last.Number).To(Equal(4))
+				Expect(last.Author).To(Equal("carol"))
+				Expect(last.Diff).To(Equal([]dao.CaptionChange{
+					{Op: dao.OpRemoved, Index: 1, Caption: "c"},
+					{Op: dao.OpAdded, Index: 1, Caption: "b"},
+				}))
+			})
+
+			Context("with revision not found", func() {
+				BeforeEach(func() {
+					number = 5
+				})
+
+				It("should return an error", func() {
+					Expect(restoreErr).NotTo(BeNil())
+					Expect(restoreErr.Error()).To(Equal("revision not found"))
+				})
+			})
+		})
+
+		Describe("Patch", func() {
+			var (
+				patch    dao.PatchFunc
+				patched  *dao.Post
+				patchErr error
+			)
+
+			BeforeEach(func() {
+				patch = func(post *dao.Post) (*dao.Post, error) {
+					post.Captions = append(post.Captions, &dao.Caption{Text: "d"})
+					return post, nil
+				}
+			})
+
+			JustBeforeEach(func() {
+				patched, patchErr = ds.Patch(customerID, *inserted.ID, patch, "dave")
+				revisions, _ = ds.Revisions(customerID, *inserted.ID)
+			})
+
+			It("should store the patched captions as a revision", func() {
+				Expect(patchErr).To(BeNil())
+				Expect(dao.CaptionTexts(patched.Captions)).To(Equal([]string{"a", "c", "d"}))
+				Expect(patched.UpdatedBy).To(Equal("dave"))
+				Expect(revisions).To(HaveLen(3))
+				Expect(revisions[2].Diff).To(Equal([]dao.CaptionChange{{Op: dao.OpAdded, Index: 2, Caption: "d"}}))
+			})
+
+			Context("with a patch error", func() {
+				BeforeEach(func() {
+					patch = func(post *dao.Post) (*dao.Post, error) {
+						post.Captions = nil
+						return nil, NewConflictError("test")
+					}
+				})
+
+				It("should leave the post unchanged", func() {
+					Expect(patchErr).To(Equal(NewConflictError("test")))
+					post, _ := ds.Get(customerID, *inserted.ID)
+					Expect(dao.CaptionTexts(post.Captions)).To(Equal([]string{"a", "c"}))
+					Expect(revisions).To(HaveLen(2))
+				})
+			})
+
+			Context("with a patch that changes the url", func() {
+				BeforeEach(func() {
+					patch = func(post *dao.Post) (*dao.Post, error) {
+						post.URL = "https://example.com/other"
+						return post, nil
+					}
+				})
+
+				It("should return an error", func() {
+					Expect(patchErr).To(Equal(NewInvalidArugmentError("patch, only the captions and status can be changed")))
+					post, _ := ds.Get(customerID, *inserted.ID)
+					Expect(post.URL).To(Equal(inserted.URL))
+				})
+			})
+
+			Context("with a patch that changes the status", func() {
+				BeforeEach(func() {
+					patch = func(post *dao.Post) (*dao.Post, error) {
+						post.Status = dao.StatusApproved
+						return post, nil
+					}
+				})
+
+				It("should store the status", func() {
+					Expect(patchErr).To(BeNil())
+					Expect(patched.Status).To(Equal(dao.StatusApproved))
+					post, _ := ds.Get(customerID, *inserted.ID)
+					Expect(post.Status).To(Equal(dao.StatusApproved))
+					Expect(dao.CaptionTexts(post.Captions)).To(Equal([]string{"a", "c"}))
+				})
+			})
+
+			Context("with a status the post can not move to", func() {
+				setStatus := func(status string) {
+					ds.store[createCompositeID(customerID, *inserted.ID)].Status = status
+				}
+
+				It("should only approve drafts and posts that failed to publish", func() {
+					cases := []struct {
+						from string
+						to   string
+						err  error
+					}{
+						{dao.StatusDraft, dao.StatusApproved, nil},
+						{dao.StatusPublishFailed, dao.StatusApproved, nil},
+						{dao.StatusPublished, dao.StatusApproved, NewConflictError("post can not be approved when it is published")},
+						{dao.StatusPublishing, dao.StatusApproved, NewPreconditionFailedError("post is being published")},
+						{dao.StatusDraft, dao.StatusPublished, NewConflictError("post can not be published when it is draft")},
+						{dao.StatusDraft, dao.StatusPublishing, NewConflictError("post can not be publishing when it is draft")},
+						{dao.StatusApproved, dao.StatusDraft, NewConflictError("post can not be draft when it is approved")},
+						{dao.StatusPublishFailed, dao.StatusPublished, NewConflictError("post can not be published when it is publish_failed")},
+					}
+					for _, c := range cases {
+						setStatus(c.from)
+						to := c.to
+						post, err := ds.Patch(customerID, *inserted.ID, func(post *dao.Post) (*dao.Post, error) {
+							post.Status = to
+							return post, nil
+						}, "dave")
+						stored, _ := ds.Get(customerID, *inserted.ID)
+						if c.err != nil {
+							Expect(err).To(Equal(c.err), "%s to %s", c.from, c.to)
+							Expect(stored.Status).To(Equal(c.from), "%s to %s", c.from, c.to)
+							continue
+						}
+						Expect(err).To(BeNil(), "%s to %s", c.from, c.to)
+						Expect(post.Status).To(Equal(c.to))
+						Expect(stored.Status).To(Equal(c.to))
+					}
+				})
+
+				It("should not move the post to another status on update", func() {
+					setStatus(dao.StatusPublished)
+					_, err := ds.Update(customerID, &dao.Post{ID: inserted.ID, Status: dao.StatusApproved})
+					Expect(err).To(Equal(NewConflictError("post can not be approved when it is published")))
+				})
+			})
+
+			Context("with a patch that sets an unknown status", func() {
+				BeforeEach(func() {
+					patch = func(post *dao.Post) (*dao.Post, error) {
+						post.Status = "unknown"
+						return post, nil
+					}
+				})
+
+				It("should return an error", func() {
+					Expect(patchErr).To(Equal(NewInvalidArugmentError("status")))
+					post, _ := ds.Get(customerID, *inserted.ID)
+					Expect(post.Status).To(Equal(inserted.Status))
+				})
+			})
+
+			Context("with another customer", func() {
+				BeforeEach(func() {
+					customerID = "other-customer"
+				})
+
+				It("should return an error", func() {
+					Expect(patchErr).NotTo(BeNil())
+					Expect(patchErr.Error()).To(Equal("post not found"))
+				})
+			})
+		})
+	})
+	Describe("Scheduling", func() {
+		var (
+			inserted *dao.Post
+			now      time.Time
+			due      time.Time
+		)
+
+		BeforeEach(func() {
+			now = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+			ds.now = func() time.Time { return now }
+			due = now.Add(-time.Minute)
+
+			var err error
+			inserted, err = ds.Insert(customerID, &dao.Post{URL: "https://example.com/post", Captions: dao.ManualCaptions([]string{"a"})})
+			Expect(err).To(BeNil())
+		})
+
+		schedule := func(at time.Time) (*dao.Post, error) {
+			return ds.Schedule(customerID, &dao.Post{ID: inserted.ID, ScheduledAt: &at, Channels: []string{"twitter", "linkedin"}, UpdatedBy: "alice"})
+		}
+
+		approve := func() {
+			_, err := ds.Update(customerID, &dao.Post{ID: inserted.ID, Captions: dao.ManualCaptions([]string{"a"}), Status: dao.StatusApproved})
+			Expect(err).To(BeNil())
+		}
+
+		Describe("Schedule", func() {
+			It("should set the time and channels", func() {
+				at := time.Date(2020, 1, 2, 10, 0, 0, 0, time.FixedZone("EST", -5*3600))
+				scheduled, err := schedule(at)
+				Expect(err).To(BeNil())
+				Expect(*scheduled.ScheduledAt).To(Equal(at.UTC()))
+				Expect(scheduled.Channels).To(Equal([]string{"twitter", "linkedin"}))
+				Expect(scheduled.UpdatedBy).To(Equal("alice"))
+			})
+
+			It("should unschedule", func() {
+				schedule(due)
+				scheduled, err := ds.Schedule(customerID, &dao.Post{ID: inserted.ID})
+				Expect(err).To(BeNil())
+				Expect(scheduled.ScheduledAt).To(BeNil())
+				Expect(scheduled.Channels).To(BeNil())
+			})
+
+			It("should enforce tenancy", func() {
+				_, err := ds.Schedule("other-customer", &dao.Post{ID: inserted.ID})
+				Expect(err).To(Equal(NewNotFoundError("post")))
+			})
+		})
+
+		Describe("ClaimDue", func() {
+			It("should only claim approved posts that are due", func() {
+				schedule(due)
+				claimed, err := ds.ClaimDue(now, time.Minute, 0)
+				Expect(err).To(BeNil())
+				Expect(claimed).To(BeEmpty())
+
+				approve()
+				schedule(now.Add(time.Minute))
+				claimed, _ = ds.ClaimDue(now, time.Minute, 0)
+				Expect(claimed).To(BeEmpty())
+			})
+
+			It("should claim a post only once", func() {
+				approve()
+				schedule(due)
+				claimed, err := ds.ClaimDue(now, time.Minute, 0)
+				Expect(err).To(BeNil())
+				Expect(claimed).To(HaveLen(1))
+				Expect(claimed[0].CustID).To(Equal(customerID))
+				Expect(claimed[0].Status).To(Equal(dao.StatusPublishing))
+				Expect(claimed[0].UpdatedBy).To(Equal(dao.ActorScheduler))
+
+				claimed, _ = ds.ClaimDue(now, time.Minute, 0)
+				Expect(claimed).To(BeEmpty())
+			})
+
+			It("should claim a post again once its claim ran out", func() {
+				approve()
+				schedule(due)
+				claimed, _ := ds.ClaimDue(now, time.Minute, 0)
+				Expect(claimed).To(HaveLen(1))
+				Expect(*claimed[0].ClaimedUntil).To(Equal(now.Add(time.Minute)))
+
+				claimed, _ = ds.ClaimDue(now.Add(59*time.Second), time.Minute, 0)
+				Expect(claimed).To(BeEmpty())
+
+				later := now.Add(time.Minute)
+				claimed, err := ds.ClaimDue(later, time.Minute, 0)
+				Expect(err).To(BeNil())
+				Expect(claimed).To(HaveLen(1))
+				Expect(claimed[0].ID).To(Equal(inserted.ID))
+				Expect(claimed[0].Status).To(Equal(dao.StatusPublishing))
+				Expect(*claimed[0].ClaimedUntil).To(Equal(later.Add(time.Minute)))
+
+				completed, err := ds.CompletePublish(customerID, *inserted.ID, []*dao.Publication{{Channel: "twitter", At: later}, {Channel: "linkedin", At: later}})
+				Expect(err).To(BeNil())
+				Expect(completed.Status).To(Equal(dao.StatusPublished))
+				Expect(completed.ClaimedUntil).To(BeNil())
+			})
+
+			It("should claim a post only once when claimed concurrently", func() {
+				approve()
+				schedule(due)
+				claims := make(chan int, 8)
+				var wg sync.WaitGroup
+				for i := 0; i < cap(claims); i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						claimed, _ := ds.ClaimDue(now, time.Minute, 0)
+						claims <- len(claimed)
+					}()
+				}
+				wg.Wait()
+				close(claims)
+				total := 0
+				for n := range claims {
+					total += n
+				}
+				Expect(total).To(Equal(1))
+			})
+
+			It("should claim the earliest posts first", func() {
+				approve()
+				schedule(due)
+				later, _ := ds.Insert(customerID, &dao.Post{URL: "https://example.com/later"})
+				ds.Update(customerID, &dao.Post{ID: later.ID, Status: dao.StatusApproved})
+				at := due.Add(-time.Hour)
+				ds.Schedule(customerID, &dao.Post{ID: later.ID, ScheduledAt: &at, Channels: []string{"twitter"}})
+
+				claimed, _ := ds.ClaimDue(now, time.Minute, 1)
+				Expect(claimed).To(HaveLen(1))
+				Expect(claimed[0].ID).To(Equal(later.ID))
+			})
+
+			It("should block changes while publishing", func() {
+				approve()
+				schedule(due)
+				ds.ClaimDue(now, time.Minute, 0)
+
+				_, err := ds.Update(customerID, &dao.Post{ID: inserted.ID, Captions: dao.ManualCaptions([]string{"b"})})
+				Expect(err).To(Equal(NewPreconditionFailedError("post is being published")))
+				_, err = ds.Restore(customerID, *inserted.ID, 1, "alice")
+				Expect(err).To(Equal(NewPreconditionFailedError("post is being published")))
+				_, err = ds.Patch(customerID, *inserted.ID, func(post *dao.Post) (*dao.Post, error) { return post, nil }, "alice")
+				Expect(err).To(Equal(NewPreconditionFailedError("post is being published")))
+				_, err = schedule(due)
+				Expect(err).To(Equal(NewPreconditionFailedError("post is being published")))
+			})
+		})
+
+		Describe("CompletePublish", func() {
+			It("should require a claimed post", func() {
+				_, err := ds.CompletePublish(customerID, *inserted.ID, nil)
+				Expect(err).To(Equal(NewPreconditionFailedError("post is not being published")))
+			})
+
+			Context("with a claimed post", func() {
+				BeforeEach(func() {
+					approve()
+					schedule(due)
+					ds.ClaimDue(now, time.Minute, 0)
+				})
+
+				It("should publish the post when every channel succeeded", func() {
+					published, err := ds.CompletePublish(customerID, *inserted.ID, []*dao.Publication{
+						{Channel: "twitter", At: now, ExternalID: "1"},
+						{Channel: "linkedin", At: now},
+					})
+					Expect(err).To(BeNil())
+					Expect(published.Status).To(Equal(dao.StatusPublished))
+					Expect(*published.PublishedAt).To(Equal(now))
+					Expect(published.Publications).To(HaveLen(2))
+
+					_, err = schedule(due)
+					Expect(err).To(Equal(NewPreconditionFailedError("post is already published")))
+
+					revisions, _ := ds.Revisions(customerID, *inserted.ID)
+					Expect(revisions[len(revisions)-1].Status).To(Equal(dao.StatusPublished))
+				})
+
+				It("should fail the post when a channel failed", func() {
+					failed, err := ds.CompletePublish(customerID, *inserted.ID, []*dao.Publication{
+						{Channel: "twitter", At: now},
+						{Channel: "linkedin", At: now, Error: "returned status 500"},
+					})
+					Expect(err).To(BeNil())
+					Expect(failed.Status).To(Equal(dao.StatusPublishFailed))
+					Expect(failed.PublishedAt).To(BeNil())
+
+					// approving again publishes it on the next scan
+					approve()
+					claimed, _ := ds.ClaimDue(now, time.Minute, 0)
+					Expect(claimed).To(HaveLen(1))
 				})
 			})
 		})
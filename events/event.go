@@ -0,0 +1,48 @@
+package events
+
+import (
+	"time"
+
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+// Types of post events
+const (
+	TypeCreated         = "created"
+	TypeCaptionsUpdated = "captions_updated"
+	TypeStatusChanged   = "status_changed"
+	TypeDeleted         = "deleted"
+)
+
+// Event records a change to a post. Offset orders the events of a customer,
+// starting at 1
+type Event struct {
+	Offset     int64         `json:"offset"`
+	ID         string        `json:"id"`
+	Type       string        `json:"type"`
+	CustomerID string        `json:"-"`
+	PostID     bson.ObjectId `json:"post_id"`
+	Actor      string        `json:"actor,omitempty"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	// Post is the post after the change, it is not set for deleted events
+	Post *dao.Post `json:"post,omitempty"`
+}
+
+// NewEvent returns an event of the type for the post. The post is copied, so
+// later changes to it are not seen by subscribers
+func NewEvent(eventType, customerID string, postID bson.ObjectId, actor string, post *dao.Post) *Event {
+	e := &Event{
+		Type:       eventType,
+		CustomerID: customerID,
+		PostID:     postID,
+		Actor:      actor,
+	}
+	if post != nil {
+		p := *post
+		p.Captions = dao.CopyCaptions(post.Captions)
+		e.Post = &p
+	}
+	return e
+}
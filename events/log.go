@@ -0,0 +1,215 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+)
+
+const (
+	minRedeliveryBackoff = 10 * time.Millisecond
+	maxRedeliveryBackoff = time.Second
+)
+
+// Outbox receives the events of writes to posts
+type Outbox interface {
+	Append(*Event) (*Event, error)
+}
+
+// Log defines the interface for an ordered, per customer log of events that
+// consumers read from an offset
+type Log interface {
+	Outbox
+	Read(string, int64, int) ([]*Event, error)
+	Poll(context.Context, string, int64, int) ([]*Event, error)
+}
+
+// Handler handles an event delivered to a subscription. Returning an error
+// delivers the event again
+type Handler func(*Event) error
+
+// InMemoryLog implements the Log interface for in memory storage. It also
+// delivers every event, in order, to in process subscriptions
+type InMemoryLog struct {
+	logger     *log.Logger
+	mu         sync.Mutex
+	all        []*Event
+	byCustomer map[string][]*Event
+	// positions are the committed positions in all, by subscription name
+	positions map[string]int
+	// changed is closed and replaced on every append to wake up readers
+	changed chan struct{}
+	now     func() time.Time
+}
+
+// NewInMemoryLog creates an empty InMemoryLog
+func NewInMemoryLog(logger *log.Logger) *InMemoryLog {
+	return &InMemoryLog{
+		logger:     logger,
+		byCustomer: make(map[string][]*Event),
+		positions:  make(map[string]int),
+		changed:    make(chan struct{}),
+		now:        time.Now,
+	}
+}
+
+// Append assigns the event its id and the customer's next offset and stores it
+func (l *InMemoryLog) Append(e *Event) (*Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stored := *e
+	stored.ID = bson.NewObjectId().Hex()
+	stored.Offset = int64(len(l.byCustomer[e.CustomerID]) + 1)
+	if stored.OccurredAt.IsZero() {
+		stored.OccurredAt = l.now().UTC()
+	}
+	l.byCustomer[e.CustomerID] = append(l.byCustomer[e.CustomerID], &stored)
+	l.all = append(l.all, &stored)
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return &stored, nil
+}
+
+// Read returns up to limit of the customer's events with an offset after the
+// supplied one
+func (l *InMemoryLog) Read(customerID string, after int64, limit int) ([]*Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events, _ := l.read(customerID, after, limit)
+	return events, nil
+}
+
+// Poll is Read, but waits for an event if there is none yet. It returns no
+// events if the context is done first
+func (l *InMemoryLog) Poll(ctx context.Context, customerID string, after int64, limit int) ([]*Event, error) {
+	for {
+		l.mu.Lock()
+		events, changed := l.read(customerID, after, limit)
+		l.mu.Unlock()
+		if len(events) > 0 {
+			return events, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return []*Event{}, nil
+		case <-changed:
+		}
+	}
+}
+
+func (l *InMemoryLog) read(customerID string, after int64, limit int) ([]*Event, chan struct{}) {
+	customerEvents := l.byCustomer[customerID]
+	if after < 0 {
+		after = 0
+	}
+	if after >= int64(len(customerEvents)) {
+		return []*Event{}, l.changed
+	}
+
+	events := customerEvents[after:]
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return append([]*Event(nil), events...), l.changed
+}
+
+// Subscription delivers every customer's events to a Handler
+type Subscription struct {
+	name    string
+	log     *InMemoryLog
+	handler Handler
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Subscribe delivers events to the handler at least once and in order, starting
+// after the last event the subscription with the same name handled
+func (l *InMemoryLog) Subscribe(name string, handler Handler) *Subscription {
+	s := &Subscription{
+		name:    name,
+		log:     l,
+		handler: handler,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Close stops delivery and waits for the event being handled
+func (s *Subscription) Close() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Subscription) run() {
+	defer close(s.done)
+	logger := s.log.logger.WithFields(log.Fields{
+		"subscription": s.name,
+	})
+
+	for {
+		s.log.mu.Lock()
+		position := s.log.positions[s.name]
+		pending := append([]*Event(nil), s.log.all[position:]...)
+		changed := s.log.changed
+		s.log.mu.Unlock()
+
+		for _, e := range pending {
+			if !s.deliver(logger, e) {
+				return
+			}
+			s.log.mu.Lock()
+			position++
+			s.log.positions[s.name] = position
+			s.log.mu.Unlock()
+		}
+		if len(pending) > 0 {
+			continue
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-changed:
+		}
+	}
+}
+
+// deliver calls the handler until it succeeds. It returns false if the
+// subscription was closed first
+func (s *Subscription) deliver(logger *log.Entry, e *Event) bool {
+	backoff := minRedeliveryBackoff
+	for {
+		select {
+		case <-s.stop:
+			return false
+		default:
+		}
+
+		err := s.handler(e)
+		if err == nil {
+			return true
+		}
+		logger.WithFields(log.Fields{
+			"event_id": e.ID,
+			"error":    err.Error(),
+		}).Warn("failed to handle event, delivering again")
+
+		select {
+		case <-s.stop:
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRedeliveryBackoff {
+			backoff = maxRedeliveryBackoff
+		}
+	}
+}
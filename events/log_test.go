@@ -0,0 +1,206 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+var _ = Describe("InMemoryLog", func() {
+	var (
+		logger *log.Logger
+		l      *InMemoryLog
+		now    time.Time
+		postID bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		l = NewInMemoryLog(logger)
+		now = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		l.now = func() time.Time { return now }
+		postID = bson.NewObjectId()
+	})
+
+	appendEvent := func(customerID, eventType string) *Event {
+		e, err := l.Append(NewEvent(eventType, customerID, postID, "alice", nil))
+		Expect(err).To(BeNil())
+		return e
+	}
+
+	Describe("NewEvent", func() {
+		It("should copy the post", func() {
+			post := &dao.Post{ID: &postID, Captions: dao.ManualCaptions([]string{"caption1"})}
+			e := NewEvent(TypeCreated, "customer", postID, "alice", post)
+			post.Captions[0].Text = "changed"
+			Expect(dao.CaptionTexts(e.Post.Captions)).To(Equal([]string{"caption1"}))
+		})
+	})
+
+	Describe("Append", func() {
+		It("should assign offsets per customer", func() {
+			first := appendEvent("customer", TypeCreated)
+			other := appendEvent("other-customer", TypeCreated)
+			second := appendEvent("customer", TypeCaptionsUpdated)
+
+			Expect(first.Offset).To(Equal(int64(1)))
+			Expect(other.Offset).To(Equal(int64(1)))
+			Expect(second.Offset).To(Equal(int64(2)))
+			Expect(first.ID).NotTo(Equal(second.ID))
+			Expect(first.OccurredAt).To(Equal(now))
+		})
+	})
+
+	Describe("Read", func() {
+		BeforeEach(func() {
+			appendEvent("customer", TypeCreated)
+			appendEvent("other-customer", TypeCreated)
+			appendEvent("customer", TypeCaptionsUpdated)
+			appendEvent("customer", TypeStatusChanged)
+		})
+
+		It("should return the customer's events after the offset", func() {
+			events, err := l.Read("customer", 1, 0)
+			Expect(err).To(BeNil())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Type).To(Equal(TypeCaptionsUpdated))
+			Expect(events[1].Type).To(Equal(TypeStatusChanged))
+		})
+
+		It("should limit the events", func() {
+			events, _ := l.Read("customer", 0, 2)
+			Expect(events).To(HaveLen(2))
+			Expect(events[1].Offset).To(Equal(int64(2)))
+		})
+
+		It("should return no events past the end", func() {
+			events, err := l.Read("customer", 3, 0)
+			Expect(err).To(BeNil())
+			Expect(events).To(BeEmpty())
+		})
+	})
+
+	Describe("Poll", func() {
+		It("should return events that already exist", func() {
+			appendEvent("customer", TypeCreated)
+			events, err := l.Poll(context.Background(), "customer", 0, 10)
+			Expect(err).To(BeNil())
+			Expect(events).To(HaveLen(1))
+		})
+
+		It("should wait for the next event", func() {
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				l.Append(NewEvent(TypeCreated, "other-customer", postID, "", nil))
+				l.Append(NewEvent(TypeDeleted, "customer", postID, "", nil))
+			}()
+			events, err := l.Poll(context.Background(), "customer", 0, 10)
+			Expect(err).To(BeNil())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal(TypeDeleted))
+		})
+
+		It("should return no events when the context is done", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			events, err := l.Poll(ctx, "customer", 0, 10)
+			Expect(err).To(BeNil())
+			Expect(events).To(BeEmpty())
+		})
+	})
+
+	Describe("Subscribe", func() {
+		var (
+			mu       sync.Mutex
+			received []*Event
+		)
+
+		BeforeEach(func() {
+			received = nil
+		})
+
+		handled := func() []*Event {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]*Event(nil), received...)
+		}
+
+		It("should deliver every customer's events in order", func() {
+			appendEvent("customer", TypeCreated)
+			s := l.Subscribe("test", func(e *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, e)
+				return nil
+			})
+			defer s.Close()
+			appendEvent("other-customer", TypeCreated)
+			appendEvent("customer", TypeDeleted)
+
+			Eventually(handled).Should(HaveLen(3))
+			events := handled()
+			Expect(events[0].CustomerID).To(Equal("customer"))
+			Expect(events[1].CustomerID).To(Equal("other-customer"))
+			Expect(events[2].Type).To(Equal(TypeDeleted))
+		})
+
+		It("should deliver an event again until it is handled", func() {
+			failures := 2
+			s := l.Subscribe("test", func(e *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, e)
+				if failures > 0 {
+					failures--
+					return errors.New("test-error")
+				}
+				return nil
+			})
+			defer s.Close()
+			created := appendEvent("customer", TypeCreated)
+			appendEvent("customer", TypeDeleted)
+
+			Eventually(handled).Should(HaveLen(4))
+			events := handled()
+			Expect(events[0].ID).To(Equal(created.ID))
+			Expect(events[1].ID).To(Equal(created.ID))
+			Expect(events[2].ID).To(Equal(created.ID))
+			Expect(events[3].Type).To(Equal(TypeDeleted))
+		})
+
+		It("should resume a subscription after the last handled event", func() {
+			appendEvent("customer", TypeCreated)
+			s := l.Subscribe("test", func(e *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, e)
+				return nil
+			})
+			Eventually(handled).Should(HaveLen(1))
+			s.Close()
+
+			appendEvent("customer", TypeDeleted)
+			s = l.Subscribe("test", func(e *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, e)
+				return nil
+			})
+			defer s.Close()
+
+			Eventually(handled).Should(HaveLen(2))
+			Consistently(handled, 50*time.Millisecond).Should(HaveLen(2))
+			Expect(handled()[1].Type).To(Equal(TypeDeleted))
+		})
+	})
+})
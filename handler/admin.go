@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Compacter is implemented by datastores that can rewrite their storage to
+// reclaim space
+type Compacter interface {
+	Compact() error
+}
+
+// NewCompactHandler returns the admin handler for compacting the datastore
+func NewCompactHandler(ds Compacter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := ds.Compact(); err != nil {
+			setReturnError(err, c)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
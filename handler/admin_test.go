@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeCompacter struct {
+	err       error
+	compacted bool
+}
+
+func (f *fakeCompacter) Compact() error {
+	f.compacted = true
+	return f.err
+}
+
+var _ = Describe("CompactHandler", func() {
+	var (
+		compacter *fakeCompacter
+		recorder  *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		compacter = &fakeCompacter{}
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		router := gin.New()
+		router.POST("/datastore/compact", NewCompactHandler(compacter))
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/datastore/compact", nil))
+	})
+
+	It("should compact the datastore", func() {
+		Expect(recorder.Code).To(Equal(http.StatusNoContent))
+		Expect(compacter.compacted).To(BeTrue())
+	})
+
+	Context("with compact error", func() {
+		BeforeEach(func() {
+			compacter.err = errors.New("test-error")
+		})
+
+		It("should return StatusInternalServerError", func() {
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+})
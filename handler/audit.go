@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type auditResponse struct {
+	Entries []*auditEntryResponse `json:"entries"`
+	// Next is the after of the next page, it is not set on the last page
+	Next int64 `json:"next,omitempty"`
+}
+
+// auditEntryResponse is an entry with the posts in the representation of the
+// version. The hash of an entry covers the posts as they are stored
+type auditEntryResponse struct {
+	*audit.Entry
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// Auditor defines the interface to handle audit log requests
+type Auditor interface {
+	List(*gin.Context)
+}
+
+// DefaultAuditor implements the Auditor interface
+type DefaultAuditor struct {
+	store audit.Store
+}
+
+// NewDefaultAuditor returns a DefaultAuditor with the provided options
+func NewDefaultAuditor(store audit.Store) *DefaultAuditor {
+	return &DefaultAuditor{
+		store: store,
+	}
+}
+
+// List defines the handler for reading the customer's audit log, oldest first.
+// A page starts after the sequence in after, and the entries can be filtered by
+// actor, action, post and the time they occurred
+func (a *DefaultAuditor) List(c *gin.Context) {
+	after, err := queryInt(c, "after", 0)
+	if err != nil || after < 0 {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid after", nil)
+		return
+	}
+	limit, err := queryInt(c, "limit", defaultAuditLimit)
+	if err != nil || limit < 1 || limit > maxAuditLimit {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid limit", nil)
+		return
+	}
+	q := audit.Query{
+		After:  after,
+		Limit:  int(limit),
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		PostID: c.Query("post_id"),
+	}
+	for _, t := range []struct {
+		name  string
+		value *time.Time
+	}{
+		{"since", &q.Since},
+		{"until", &q.Until},
+	} {
+		v := c.Query(t.name)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid "+t.name, nil)
+			return
+		}
+		*t.value = parsed
+	}
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	entries, err := a.store.Query(customerID, q)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+
+	resp := auditResponse{Entries: make([]*auditEntryResponse, len(entries))}
+	for i, e := range entries {
+		resp.Entries[i] = &auditEntryResponse{Entry: e, Before: mapSnapshot(c, e.Before), After: mapSnapshot(c, e.After)}
+	}
+	if len(entries) == q.Limit {
+		resp.Next = entries[len(entries)-1].Sequence
+	}
+	c.PureJSON(http.StatusOK, resp)
+	return
+}
+
+// mapSnapshot returns the representation of the post of a snapshot. A snapshot
+// that can not be read as a post is returned as it was written
+func mapSnapshot(c *gin.Context, snapshot json.RawMessage) interface{} {
+	if len(snapshot) == 0 {
+		return nil
+	}
+	post, err := audit.DecodePost(snapshot)
+	if err != nil {
+		return snapshot
+	}
+	return mapPost(c, post)
+}
+
+// remoteIP returns the address the request came from. Headers like
+// X-Forwarded-For are set by the client, so they are not trusted
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// NewAuditRecorder returns middleware that records every request of a customer
+// in the audit log once it is handled: who made it, from where, the route and
+// the status of the response. It must come after authentication, requests
+// without a customer are not recorded
+func NewAuditRecorder(log audit.Appender) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		identity := getIdentity(c)
+		if identity == nil || identity.CustomerID == "" {
+			return
+		}
+		e := &audit.Entry{
+			CustomerID: identity.CustomerID,
+			Actor:      getActor(c),
+			Action:     audit.ActionRequest,
+			IP:         remoteIP(c.Request),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+		}
+		// Post routes name the post in the id parameter
+		if id := c.Param("id"); bson.IsObjectIdHex(id) && strings.Contains(c.FullPath(), "/post") {
+			e.PostID = id
+		}
+		if _, err := log.Append(e); err != nil {
+			c.Error(err)
+		}
+	}
+}
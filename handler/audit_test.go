@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("DefaultAuditor", func() {
+	var (
+		store      *audit.InMemoryStore
+		router     *gin.Engine
+		recorder   *httptest.ResponseRecorder
+		customerID string
+		postID     bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		store = audit.NewInMemoryStore()
+		recorder = httptest.NewRecorder()
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.Use(fakeAuthenticator, NewAuditRecorder(store))
+		router.GET("/audit", NewDefaultAuditor(store).List)
+		router.GET("/post/:id", func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		router.GET("/keys/:id", func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+	})
+
+	send := func(path, customer string) {
+		recorder = httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.1.2.3:4567"
+		if customer != "" {
+			req.Header.Add(customerIDHeader, customer)
+		}
+		router.ServeHTTP(recorder, req)
+	}
+
+	response := func() (entries []*audit.Entry, next int64) {
+		resp := struct {
+			Entries []*audit.Entry `json:"entries"`
+			Next    int64          `json:"next"`
+		}{}
+		ExpectWithOffset(1, json.Unmarshal(recorder.Body.Bytes(), &resp)).To(Succeed())
+		return resp.Entries, resp.Next
+	}
+
+	Describe("NewAuditRecorder", func() {
+		It("should record every request of a customer", func() {
+			send("/post/"+postID.Hex(), customerID)
+			send("/keys/"+postID.Hex(), customerID)
+			send("/post/"+postID.Hex(), "")
+
+			entries, err := store.Query(customerID, audit.Query{})
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Action).To(Equal(audit.ActionRequest))
+			Expect(entries[0].Actor).To(Equal(customerID))
+			Expect(entries[0].IP).To(Equal("10.1.2.3"))
+			Expect(entries[0].Method).To(Equal("GET"))
+			Expect(entries[0].Path).To(Equal("/post/" + postID.Hex()))
+			Expect(entries[0].Status).To(Equal(http.StatusNoContent))
+			Expect(entries[0].PostID).To(Equal(postID.Hex()))
+			Expect(entries[1].PostID).To(BeEmpty())
+		})
+
+		It("should record the address the request came from, not a forwarded one", func() {
+			recorder = httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/keys/1", nil)
+			req.RemoteAddr = "10.1.2.3:4567"
+			req.Header.Add(customerIDHeader, customerID)
+			req.Header.Add("X-Forwarded-For", "192.0.2.1")
+			req.Header.Add("X-Real-Ip", "192.0.2.2")
+			router.ServeHTTP(recorder, req)
+
+			entries, err := store.Query(customerID, audit.Query{})
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].IP).To(Equal("10.1.2.3"))
+		})
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			store.Append(&audit.Entry{CustomerID: customerID, Action: audit.ActionCreated, PostID: postID.Hex(), After: json.RawMessage(`{"id":"` + postID.Hex() + `","url":"https://example.com"}`)})
+			store.Append(&audit.Entry{CustomerID: "other-customer", Action: audit.ActionCreated})
+			store.Append(&audit.Entry{CustomerID: customerID, Action: audit.ActionDeleted, PostID: postID.Hex()})
+		})
+
+		It("should return the customer's entries", func() {
+			send("/audit", customerID)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			entries, next := response()
+			Expect(entries).To(HaveLen(2))
+			after, err := audit.DecodePost(entries[0].After)
+			Expect(err).To(BeNil())
+			Expect(after.URL).To(Equal("https://example.com"))
+			Expect(entries[1].PrevHash).To(Equal(entries[0].Hash))
+			Expect(next).To(BeZero())
+		})
+
+		It("should return a page of the entries that match", func() {
+			send("/audit?limit=1&action="+audit.ActionDeleted+"&post_id="+postID.Hex()+"&since=2020-01-01T00:00:00Z", customerID)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			entries, next := response()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Sequence).To(Equal(int64(2)))
+			Expect(next).To(Equal(int64(2)))
+		})
+
+		It("should reject invalid parameters", func() {
+			cases := map[string]string{
+				"/audit?after=-1":         "invalid after",
+				"/audit?limit=1001":       "invalid limit",
+				"/audit?since=yesterday":  "invalid since",
+				"/audit?until=2020-01-01": "invalid until",
+			}
+			for path, detail := range cases {
+				send(path, customerID)
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest), path)
+				expectProblem(recorder, datastore.CodeInvalidArgument, detail)
+			}
+		})
+	})
+})
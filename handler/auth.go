@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/auth"
+)
+
+const identityKey = "identity"
+
+// ActorAdmin is recorded as the author of changes made through admin routes
+const ActorAdmin = "admin"
+
+// NewAuthenticator returns middleware that authenticates requests with the first
+// Authenticator that recognizes the request's credentials. The tenant for the
+// request is derived from those credentials
+func NewAuthenticator(authenticators ...auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, a := range authenticators {
+			identity, err := a.Authenticate(c.Request)
+			if err != nil {
+				setReturnError(err, c)
+				c.Abort()
+				return
+			}
+			if identity != nil {
+				c.Set(identityKey, identity)
+				c.Next()
+				return
+			}
+		}
+
+		setReturnError(auth.NewUnauthorizedError("must include credentials in headers"), c)
+		c.Abort()
+	}
+}
+
+// RequireScope returns middleware that only allows callers granted the scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := getIdentity(c)
+		if identity == nil {
+			setReturnError(auth.NewUnauthorizedError("request is not authenticated"), c)
+			c.Abort()
+			return
+		}
+		if !identity.HasScope(scope) {
+			setReturnError(auth.NewForbiddenError("missing scope "+scope), c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// NewAdminAuthenticator returns middleware that only allows requests carrying the
+// configured admin key
+func NewAdminAuthenticator(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.Request.Header.Get(auth.APIKeyHeader)
+		if adminKey == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(adminKey)) != 1 {
+			setReturnError(auth.NewUnauthorizedError("invalid admin key"), c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// NewAdminCustomer returns middleware that lets admin routes act as the customer
+// in the customer_id path parameter, so the customer handlers can be reused.
+// Changes are made by ActorAdmin. It must come after NewAdminAuthenticator
+func NewAdminCustomer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(identityKey, &auth.Identity{
+			CustomerID: c.Param("customer_id"),
+			Subject:    ActorAdmin,
+			Scopes:     auth.AllScopes,
+		})
+		c.Next()
+	}
+}
+
+// getIdentity returns the identity set by the authentication middleware
+func getIdentity(c *gin.Context) *auth.Identity {
+	v, ok := c.Get(identityKey)
+	if !ok {
+		return nil
+	}
+	identity, _ := v.(*auth.Identity)
+	return identity
+}
+
+// getCustomerID returns the customerID of the authenticated caller. If there is
+// none, the response is set and an empty string is returned
+func getCustomerID(c *gin.Context) string {
+	identity := getIdentity(c)
+	if identity == nil || identity.CustomerID == "" {
+		setReturnError(auth.NewUnauthorizedError("request is not authenticated"), c)
+		return ""
+	}
+	return identity.CustomerID
+}
+
+// getActor returns who is making the request, recorded as the author of changes.
+// Tokens are identified by their subject and api keys by their id
+func getActor(c *gin.Context) string {
+	identity := getIdentity(c)
+	switch {
+	case identity == nil:
+		return ""
+	case identity.Subject != "":
+		return identity.Subject
+	case identity.KeyID != "":
+		return "key:" + identity.KeyID
+	default:
+		return identity.CustomerID
+	}
+}
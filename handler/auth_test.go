@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/auth"
+	mock_auth "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/auth"
+)
+
+var _ = Describe("Authenticators", func() {
+	var (
+		mockCtrl *gomock.Controller
+		mockKeys *mock_auth.MockKeyStore
+		router   *gin.Engine
+		recorder *httptest.ResponseRecorder
+		req      *http.Request
+		identity *auth.Identity
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKeys = mock_auth.NewMockKeyStore(mockCtrl)
+		recorder = httptest.NewRecorder()
+		identity = nil
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		whoami := func(c *gin.Context) {
+			identity = getIdentity(c)
+			c.Status(http.StatusOK)
+		}
+		authenticator := NewAuthenticator(auth.NewAPIKeyAuthenticator(mockKeys))
+		router.GET("/whoami", authenticator, whoami)
+		router.GET("/scoped", fakeAuthenticator, RequireScope(auth.ScopePostsApprove), whoami)
+		router.GET("/admin", NewAdminAuthenticator("admin-secret"), whoami)
+		router.GET("/admin/customers/:customer_id", NewAdminAuthenticator("admin-secret"), NewAdminCustomer(), whoami)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		router.ServeHTTP(recorder, req)
+	})
+
+	Describe("Authenticator", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/whoami", nil)
+		})
+
+		Context("without credentials in header", func() {
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+				Expect(recorder.Header().Get("WWW-Authenticate")).NotTo(BeEmpty())
+			})
+
+			It("should return a useful message", func() {
+				expectProblem(recorder, auth.CodeUnauthenticated, "unauthorized: must include credentials in headers")
+			})
+
+			It("should NOT call the handler", func() {
+				Expect(identity).To(BeNil())
+			})
+		})
+
+		Context("with invalid api key", func() {
+			BeforeEach(func() {
+				req.Header.Add(auth.APIKeyHeader, "bad-key")
+				mockKeys.EXPECT().Lookup("bad-key").Return(nil, auth.NewUnauthorizedError("invalid api key"))
+			})
+
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("should return a useful message", func() {
+				expectProblem(recorder, auth.CodeUnauthenticated, "unauthorized: invalid api key")
+			})
+		})
+
+		Context("with valid api key", func() {
+			BeforeEach(func() {
+				req.Header.Add(auth.APIKeyHeader, "good-key")
+				// the customer header must be ignored
+				req.Header.Add(customerIDHeader, "other-customer")
+				key := &auth.Key{ID: "key-id", CustID: "test-customer"}
+				mockKeys.EXPECT().Lookup("good-key").Return(key, nil)
+			})
+
+			It("should return StatusOK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+
+			It("should derive the identity from the key", func() {
+				Expect(identity.CustomerID).To(Equal("test-customer"))
+				Expect(identity.KeyID).To(Equal("key-id"))
+			})
+		})
+	})
+
+	Describe("RequireScope", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/scoped", nil)
+		})
+
+		Context("without identity", func() {
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("without scope", func() {
+			BeforeEach(func() {
+				router.GET("/unscoped", func(c *gin.Context) {
+					c.Set(identityKey, &auth.Identity{CustomerID: "test-customer", Scopes: []string{auth.ScopePostsRead}})
+				}, RequireScope(auth.ScopePostsApprove), func(c *gin.Context) {
+					c.Status(http.StatusOK)
+				})
+				req = httptest.NewRequest("GET", "/unscoped", nil)
+			})
+
+			It("should return StatusForbidden", func() {
+				Expect(recorder.Code).To(Equal(http.StatusForbidden))
+			})
+
+			It("should return a useful message", func() {
+				expectProblem(recorder, auth.CodeForbidden, "forbidden: missing scope posts:approve")
+			})
+		})
+
+		Context("with scope", func() {
+			BeforeEach(func() {
+				req.Header.Add(customerIDHeader, "test-customer")
+			})
+
+			It("should return StatusOK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("AdminAuthenticator", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/admin", nil)
+		})
+
+		Context("with wrong admin key", func() {
+			BeforeEach(func() {
+				req.Header.Add(auth.APIKeyHeader, "not-admin")
+			})
+
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("with admin key", func() {
+			BeforeEach(func() {
+				req.Header.Add(auth.APIKeyHeader, "admin-secret")
+			})
+
+			It("should return StatusOK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("AdminCustomer", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/admin/customers/test-customer", nil)
+			req.Header.Add(auth.APIKeyHeader, "admin-secret")
+		})
+
+		It("should act as the customer", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(identity.CustomerID).To(Equal("test-customer"))
+			Expect(identity.Subject).To(Equal(ActorAdmin))
+			Expect(identity.Scopes).To(Equal(auth.AllScopes))
+		})
+	})
+
+	Describe("getActor", func() {
+		var c *gin.Context
+
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/admin", nil)
+			c, _ = gin.CreateTestContext(httptest.NewRecorder())
+		})
+
+		It("should prefer the token subject", func() {
+			c.Set(identityKey, &auth.Identity{CustomerID: "customer", KeyID: "key-id", Subject: "alice"})
+			Expect(getActor(c)).To(Equal("alice"))
+		})
+
+		It("should use the key id for api keys", func() {
+			c.Set(identityKey, &auth.Identity{CustomerID: "customer", KeyID: "key-id"})
+			Expect(getActor(c)).To(Equal("key:key-id"))
+		})
+
+		It("should be empty without an identity", func() {
+			Expect(getActor(c)).To(Equal(""))
+		})
+	})
+})
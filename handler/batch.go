@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/canonical"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/validate"
+)
+
+const (
+	// maxBatchSize is the most items a batch request may have
+	maxBatchSize = 100
+	// batchConcurrency is how many captions are generated at once for a batch
+	batchConcurrency = 8
+)
+
+type batchCreateRequest struct {
+	Items []postRequest `json:"items"`
+}
+
+type batchUpdateItem struct {
+	ID       string   `json:"id"`
+	Captions []string `json:"captions,omitempty"`
+}
+
+type batchUpdateRequest struct {
+	Items []batchUpdateItem `json:"items"`
+}
+
+// batchResult is the outcome of a single item, Index is its position in the
+// request and Status the code it would have had on its own
+type batchResult struct {
+	Index    int         `json:"index"`
+	Status   int         `json:"status"`
+	Existing bool        `json:"existing,omitempty"`
+	Post     interface{} `json:"post,omitempty"`
+	Error    *Problem    `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []*batchResult `json:"results"`
+}
+
+// captioner generates the captions of the items of a batch. generate returns
+// the captions for a url, rendered with the template if its id is set, and the
+// generated captions that were rejected. It is called concurrently, and every
+// generation is reserved against the quota, unused reports a reservation that
+// has to be refunded. refund gives back the quota of generations that are not
+// used or whose posts were not stored
+type captioner interface {
+	generate(customerID, url, templateID string) (captions []*dao.Caption, rejected []*dao.Rejection, unused bool, err error)
+	refund(c *gin.Context, customerID string, n int)
+}
+
+// Actions returns a handler for custom methods, routed as /posts:action. The
+// action after the colon picks the handler, unknown actions are not found
+func Actions(actions map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		action := c.Param("action")
+		if strings.HasPrefix(action, ":") {
+			if h, ok := actions[action[1:]]; ok {
+				h(c)
+				return
+			}
+		}
+		setProblem(c, http.StatusNotFound, datastore.CodeNotFound, "action not found", nil)
+	}
+}
+
+// BatchCreate defines the handler for creating several posts with the captions
+// in the request
+func (p *DefaultPoster) BatchCreate(c *gin.Context) {
+	batchCreate(c, p.ds, p.validator, nil)
+}
+
+// BatchUpdate defines the handler for updating the captions of several posts
+func (p *DefaultPoster) BatchUpdate(c *gin.Context) {
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	req := &batchUpdateRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+	if !checkBatchSize(c, "items", len(req.Items)) {
+		return
+	}
+
+	actor := getActor(c)
+	results := make([]*batchResult, len(req.Items))
+	inputs := make([]*dao.Post, len(req.Items))
+	for i, item := range req.Items {
+		if !bson.IsObjectIdHex(item.ID) {
+			results[i] = errorResult(c, i, datastore.NewInvalidArugmentError("post id"))
+			continue
+		}
+		input := putRequestToPost(putRequest{Captions: item.Captions}, bson.ObjectIdHex(item.ID))
+		input.UpdatedBy = actor
+		if err := p.validator.Update(input); err != nil {
+			results[i] = errorResult(c, i, err)
+			continue
+		}
+		inputs[i] = input
+	}
+
+	if err := writeBatch(c, customerID, inputs, results, p.ds.UpdateBatch); err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, &batchResponse{Results: results})
+}
+
+// batchCreate defines the handler for creating several posts, captions are
+// generated for the items without any if gen is set
+func batchCreate(c *gin.Context, ds dao.Poster, validator *validate.Validator, gen captioner) {
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	req := &batchCreateRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+	if !checkBatchSize(c, "items", len(req.Items)) {
+		return
+	}
+
+	results, err := createPosts(c, ds, validator, gen, customerID, req.Items, c.Query(dedupeParam) == "true")
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, &batchResponse{Results: results})
+}
+
+// createPosts validates every item, looks up existing posts when asked to dedupe,
+// generates missing captions if gen is set and inserts the rest. Generations
+// whose posts are not inserted are refunded. It returns the result of every item
+func createPosts(c *gin.Context, ds dao.Poster, validator *validate.Validator, gen captioner, customerID string, items []postRequest, dedupe bool) ([]*batchResult, error) {
+	actor := getActor(c)
+	results := make([]*batchResult, len(items))
+	inputs := make([]*dao.Post, len(items))
+	for i, item := range items {
+		input := postRequestToPost(item)
+		input.UpdatedBy = actor
+		if err := validator.Post(input); err != nil {
+			results[i] = errorResult(c, i, err)
+			continue
+		}
+		inputs[i] = input
+	}
+
+	if dedupe {
+		findExistingBatch(c, ds, customerID, inputs, results)
+	}
+	if gen == nil {
+		if err := writeBatch(c, customerID, inputs, results, ds.InsertBatch); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	generated := generateBatch(c, customerID, items, inputs, results, gen)
+	err := writeBatch(c, customerID, inputs, results, ds.InsertBatch)
+	unused := 0
+	for i := range generated {
+		if generated[i] && (err != nil || results[i].Error != nil) {
+			unused++
+		}
+	}
+	if unused > 0 {
+		gen.refund(c, customerID, unused)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// findExistingBatch sets the result of every item the customer already has a post
+// for. An item with the same url as an earlier one in the batch is a conflict
+func findExistingBatch(c *gin.Context, ds dao.Poster, customerID string, inputs []*dao.Post, results []*batchResult) {
+	seen := map[string]int{}
+	for i, input := range inputs {
+		if results[i] != nil {
+			continue
+		}
+		canonicalURL, err := canonical.URL(input.URL)
+		if err != nil {
+			continue
+		}
+		if first, ok := seen[canonicalURL]; ok {
+			results[i] = errorResult(c, i, datastore.NewConflictError(fmt.Sprintf("same url as items[%d]", first)))
+			continue
+		}
+		seen[canonicalURL] = i
+
+		post, err := ds.GetByURL(customerID, canonicalURL)
+		if err != nil {
+			if _, notFound := err.(*datastore.NotFound); !notFound {
+				results[i] = errorResult(c, i, err)
+			}
+			continue
+		}
+		if post != nil {
+			results[i] = &batchResult{Index: i, Status: http.StatusOK, Existing: true, Post: mapPost(c, post)}
+		}
+	}
+}
+
+// generateBatch generates captions for the items without any, at most
+// batchConcurrency at a time, and refunds the reservations of generations that
+// came back without captions. It returns which items had captions generated
+func generateBatch(c *gin.Context, customerID string, items []postRequest, inputs []*dao.Post, results []*batchResult, gen captioner) []bool {
+	generated := make([]bool, len(inputs))
+	unused := make([]bool, len(inputs))
+	errs := make([]error, len(inputs))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		if results[i] != nil || len(input.Captions) > 0 {
+			continue
+		}
+		generated[i] = true
+		wg.Add(1)
+		go func(i int, input *dao.Post) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			input.Captions, input.RejectedCaptions, unused[i], errs[i] = gen.generate(customerID, input.URL, items[i].Template)
+		}(i, input)
+	}
+	wg.Wait()
+
+	refunds := 0
+	for i, err := range errs {
+		if unused[i] {
+			refunds++
+		}
+		if err != nil {
+			results[i] = errorResult(c, i, generatorError(c, err))
+			generated[i] = false
+		}
+	}
+	if refunds > 0 {
+		gen.refund(c, customerID, refunds)
+	}
+	return generated
+}
+
+// writeBatch writes the items that do not have a result yet and sets their
+// results
+func writeBatch(c *gin.Context, customerID string, inputs []*dao.Post, results []*batchResult, write func(string, []*dao.Post) ([]*dao.BatchResult, error)) error {
+	posts := []*dao.Post{}
+	indexes := []int{}
+	for i, input := range inputs {
+		if results[i] == nil {
+			posts = append(posts, input)
+			indexes = append(indexes, i)
+		}
+	}
+
+	if len(posts) > 0 {
+		written, err := write(customerID, posts)
+		if err != nil {
+			return err
+		}
+		for j, i := range indexes {
+			if written[j].Err != nil {
+				results[i] = errorResult(c, i, written[j].Err)
+				continue
+			}
+			results[i] = &batchResult{Index: i, Status: http.StatusOK, Post: mapPost(c, written[j].Post)}
+		}
+	}
+	return nil
+}
+
+func checkBatchSize(c *gin.Context, field string, n int) bool {
+	var message string
+	switch {
+	case n == 0:
+		message = "must have at least one item"
+	case n > maxBatchSize:
+		message = fmt.Sprintf("must have at most %d items", maxBatchSize)
+	default:
+		return true
+	}
+	setReturnError(datastore.NewValidationError(datastore.FieldError{Field: field, Message: message}), c)
+	return false
+}
+
+func errorResult(c *gin.Context, index int, err error) *batchResult {
+	problem := errorProblem(err, c)
+	return &batchResult{
+		Index:  index,
+		Status: problem.Status,
+		Error:  problem,
+	}
+}
@@ -0,0 +1,481 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	textapi "github.com/AYLIEN/aylien_textapi_go"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/caption"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	mock_caption "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/caption"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	mock_ratelimit "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/templates"
+	"github.com/bpross/cc-hw/validate"
+)
+
+// v1BatchResponse is a batchResponse as the /v1 routes represent it
+type v1BatchResponse struct {
+	Results []struct {
+		Index    int       `json:"index"`
+		Status   int       `json:"status"`
+		Existing bool      `json:"existing"`
+		Post     *dao.Post `json:"post"`
+		Error    *Problem  `json:"error"`
+	} `json:"results"`
+}
+
+var _ = Describe("Batch", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockPoster *mock_dao.MockPoster
+		router     *gin.Engine
+		customerID string
+		recorder   *httptest.ResponseRecorder
+		method     string
+		url        string
+		body       string
+		req        *http.Request
+		response   *v1BatchResponse
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPoster = mock_dao.NewMockPoster(mockCtrl)
+		router = setupRouter(NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules())))
+		customerID = "test-customer"
+		recorder = httptest.NewRecorder()
+		url = "/posts:batch"
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		req, err = http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).To(BeNil())
+		req.Header.Add(customerIDHeader, customerID)
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(recorder, req)
+
+		response = &v1BatchResponse{}
+		if recorder.Code == http.StatusOK {
+			Expect(json.Unmarshal(recorder.Body.Bytes(), response)).To(Succeed())
+		}
+	})
+
+	Describe("Actions", func() {
+		BeforeEach(func() {
+			method = "POST"
+			url = "/posts:unknown"
+			body = `{}`
+		})
+
+		It("should return StatusNotFound for an unknown action", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			expectProblem(recorder, datastore.CodeNotFound, "action not found")
+		})
+	})
+
+	Describe("BatchCreate", func() {
+		BeforeEach(func() {
+			method = "POST"
+		})
+
+		Context("without items", func() {
+			BeforeEach(func() {
+				body = `{"items":[]}`
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: items: must have at least one item")
+			})
+		})
+
+		Context("with too many items", func() {
+			BeforeEach(func() {
+				items := make([]string, maxBatchSize+1)
+				for i := range items {
+					items[i] = `{"url":"https://example.com"}`
+				}
+				body = `{"items":[` + strings.Join(items, ",") + `]}`
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: items: must have at most 100 items")
+			})
+		})
+
+		Context("with valid and invalid items", func() {
+			var postID bson.ObjectId
+
+			BeforeEach(func() {
+				postID = bson.NewObjectId()
+				body = `{"items":[{"url":"ftp://example.com"},{"url":"https://example.com/post","captions":["caption1"]}]}`
+			})
+
+			Context("with datastore error", func() {
+				BeforeEach(func() {
+					mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).Return(nil, errors.New("test-error"))
+				})
+
+				It("should return StatusInternalServerError", func() {
+					Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+				})
+			})
+
+			Context("without datastore error", func() {
+				BeforeEach(func() {
+					input := &dao.Post{URL: "https://example.com/post", Captions: dao.ManualCaptions([]string{"caption1"}), UpdatedBy: customerID}
+					created := &dao.Post{ID: &postID, URL: input.URL, Captions: input.Captions}
+					mockPoster.EXPECT().InsertBatch(customerID, []*dao.Post{input}).Return([]*dao.BatchResult{{Post: created}}, nil)
+				})
+
+				It("should return a result for every item", func() {
+					Expect(recorder.Code).To(Equal(http.StatusOK))
+					Expect(response.Results).To(HaveLen(2))
+					Expect(response.Results[0].Index).To(Equal(0))
+					Expect(response.Results[0].Status).To(Equal(http.StatusBadRequest))
+					Expect(response.Results[0].Error.Detail).To(Equal("validation failed: url: must be http or https"))
+					Expect(response.Results[1].Index).To(Equal(1))
+					Expect(response.Results[1].Status).To(Equal(http.StatusOK))
+					Expect(*response.Results[1].Post.ID).To(Equal(postID))
+				})
+			})
+		})
+
+		Context("with an item the datastore rejects", func() {
+			BeforeEach(func() {
+				body = `{"items":[{"url":"https://example.com/post"}]}`
+				mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).Return([]*dao.BatchResult{{Err: datastore.NewConflictError("test")}}, nil)
+			})
+
+			It("should return the status of the item", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(response.Results[0].Status).To(Equal(http.StatusConflict))
+				Expect(response.Results[0].Error.Code).To(Equal(datastore.CodeConflict))
+			})
+		})
+
+		Context("with dedupe", func() {
+			var existing *dao.Post
+
+			BeforeEach(func() {
+				url = "/posts:batch?dedupe=true"
+				postID := bson.NewObjectId()
+				existing = &dao.Post{ID: &postID, URL: "https://example.com/a"}
+				body = `{"items":[{"url":"https://example.com/a"},{"url":"https://example.com/b"},{"url":"https://EXAMPLE.com/b"}]}`
+				mockPoster.EXPECT().GetByURL(customerID, "https://example.com/a").Return(existing, nil)
+				mockPoster.EXPECT().GetByURL(customerID, "https://example.com/b").Return(nil, datastore.NewNotFoundError("post"))
+				mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(func(_ string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+					return []*dao.BatchResult{{Post: posts[0]}}, nil
+				})
+			})
+
+			It("should return existing posts and reject duplicate items", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(response.Results[0].Existing).To(BeTrue())
+				Expect(response.Results[0].Post.URL).To(Equal("https://example.com/a"))
+				Expect(response.Results[1].Status).To(Equal(http.StatusOK))
+				Expect(response.Results[1].Existing).To(BeFalse())
+				Expect(response.Results[2].Status).To(Equal(http.StatusConflict))
+				Expect(response.Results[2].Error.Detail).To(Equal("conflict: same url as items[1]"))
+			})
+		})
+	})
+
+	Describe("BatchUpdate", func() {
+		var postID bson.ObjectId
+
+		BeforeEach(func() {
+			method = "PUT"
+			postID = bson.NewObjectId()
+			body = fmt.Sprintf(`{"items":[{"id":"blah","captions":["caption"]},{"id":"%s","captions":[" caption "]}]}`, postID.Hex())
+			input := &dao.Post{ID: &postID, Captions: dao.ManualCaptions([]string{"caption"}), UpdatedBy: customerID}
+			mockPoster.EXPECT().UpdateBatch(customerID, []*dao.Post{input}).Return([]*dao.BatchResult{{Post: input}}, nil)
+		})
+
+		It("should update the items with valid ids", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(response.Results[0].Status).To(Equal(http.StatusBadRequest))
+			Expect(response.Results[0].Error.Detail).To(Equal("invalid post id"))
+			Expect(response.Results[1].Status).To(Equal(http.StatusOK))
+			Expect(dao.CaptionTexts(response.Results[1].Post.Captions)).To(Equal([]string{"caption"}))
+		})
+	})
+
+	Describe("CaptionGeneratorPoster BatchCreate", func() {
+		var (
+			mockGenerator *mock_caption.MockGenerator
+			mockQuota     *mock_ratelimit.MockQuota
+		)
+
+		BeforeEach(func() {
+			method = "POST"
+			mockGenerator = mock_caption.NewMockGenerator(mockCtrl)
+			mockQuota = mock_ratelimit.NewMockQuota(mockCtrl)
+			validator := validate.NewValidator(validate.DefaultRules())
+			base := NewDefaultPoster(mockPoster, validator)
+			router = setupRouter(NewCaptionGeneratorPoster(base, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(newQualityStore(), &quality.Config{}), newTemplateStore(), 3, mockQuota, validator))
+			body = `{"items":[{"url":"https://example.com/a"},{"url":"https://example.com/b","captions":["mine"]},{"url":"https://example.com/c"},{"url":"https://example.com/d"}]}`
+
+			mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil).Times(2)
+			mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, datastore.NewQuotaExceededError("monthly quota", time.Now().Add(time.Hour)))
+			mockGenerator.EXPECT().Create(gomock.Any(), 3).DoAndReturn(func(url string, _ int) ([]string, error) {
+				return []string{"generated " + url}, nil
+			})
+			mockGenerator.EXPECT().Create(gomock.Any(), 3).Return(nil, errors.New("test-error"))
+			mockQuota.EXPECT().Refund(customerID, 1).Return(nil)
+			mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(func(_ string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+				results := []*dao.BatchResult{}
+				for _, post := range posts {
+					results = append(results, &dao.BatchResult{Post: post})
+				}
+				return results, nil
+			})
+		})
+
+		It("should generate captions for the items without any", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			statuses := map[int]int{}
+			for _, result := range response.Results {
+				statuses[result.Status]++
+			}
+			Expect(statuses).To(Equal(map[int]int{
+				http.StatusOK:                 2,
+				http.StatusTooManyRequests:    1,
+				http.StatusServiceUnavailable: 1,
+			}))
+			Expect(dao.CaptionTexts(response.Results[1].Post.Captions)).To(Equal([]string{"mine"}))
+		})
+	})
+
+	Describe("CaptionGeneratorPoster BatchCreate with a quota of one generation", func() {
+		var quota *ratelimit.MonthlyQuota
+
+		BeforeEach(func() {
+			method = "POST"
+			mockGenerator := mock_caption.NewMockGenerator(mockCtrl)
+			quota = ratelimit.NewMonthlyQuota(1)
+			validator := validate.NewValidator(validate.DefaultRules())
+			base := NewDefaultPoster(mockPoster, validator)
+			router = setupRouter(NewCaptionGeneratorPoster(base, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(newQualityStore(), &quality.Config{}), newTemplateStore(), 3, quota, validator))
+			items := []string{}
+			for i := 0; i < batchConcurrency; i++ {
+				items = append(items, fmt.Sprintf(`{"url":"https://example.com/%d"}`, i))
+			}
+			body = `{"items":[` + strings.Join(items, ",") + `]}`
+
+			mockGenerator.EXPECT().Create(gomock.Any(), 3).DoAndReturn(func(url string, _ int) ([]string, error) {
+				return []string{"generated " + url}, nil
+			})
+			mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(func(_ string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+				Expect(posts).To(HaveLen(1))
+				return []*dao.BatchResult{{Post: posts[0]}}, nil
+			})
+		})
+
+		It("should generate only the items the quota has room for", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			statuses := map[int]int{}
+			for _, result := range response.Results {
+				statuses[result.Status]++
+			}
+			Expect(statuses).To(Equal(map[int]int{
+				http.StatusOK:              1,
+				http.StatusTooManyRequests: batchConcurrency - 1,
+			}))
+			usage, err := quota.Usage(customerID)
+			Expect(err).To(BeNil())
+			Expect(usage.Used).To(Equal(1))
+		})
+	})
+
+	Describe("CaptionGeneratorPoster BatchCreate with posts that are not stored", func() {
+		var mockQuota *mock_ratelimit.MockQuota
+
+		BeforeEach(func() {
+			method = "POST"
+			mockGenerator := mock_caption.NewMockGenerator(mockCtrl)
+			mockQuota = mock_ratelimit.NewMockQuota(mockCtrl)
+			validator := validate.NewValidator(validate.DefaultRules())
+			base := NewDefaultPoster(mockPoster, validator)
+			router = setupRouter(NewCaptionGeneratorPoster(base, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(newQualityStore(), &quality.Config{}), newTemplateStore(), 3, mockQuota, validator))
+			body = `{"items":[{"url":"https://example.com/a"},{"url":"https://example.com/b","captions":["mine"]},{"url":"https://example.com/c"}]}`
+
+			mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil).Times(2)
+			mockGenerator.EXPECT().Create(gomock.Any(), 3).DoAndReturn(func(url string, _ int) ([]string, error) {
+				return []string{"generated " + url}, nil
+			}).Times(2)
+		})
+
+		Context("with items the datastore rejects", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(func(_ string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+					return []*dao.BatchResult{
+						{Err: datastore.NewConflictError("post exists")},
+						{Err: datastore.NewConflictError("post exists")},
+						{Post: posts[2]},
+					}, nil
+				})
+				mockQuota.EXPECT().Refund(customerID, 1).Return(nil)
+			})
+
+			It("should refund the generations of the rejected items", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(response.Results[0].Status).To(Equal(http.StatusConflict))
+				Expect(response.Results[1].Status).To(Equal(http.StatusConflict))
+				Expect(response.Results[2].Status).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("with datastore error", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).Return(nil, datastore.NewUnavailableError("datastore"))
+				mockQuota.EXPECT().Refund(customerID, 2).Return(nil)
+			})
+
+			It("should refund every generation", func() {
+				Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+	})
+
+	Describe("CaptionGeneratorPoster BatchCreate with invalid generated captions", func() {
+		BeforeEach(func() {
+			method = "POST"
+			mockGenerator := mock_caption.NewMockGenerator(mockCtrl)
+			mockQuota := mock_ratelimit.NewMockQuota(mockCtrl)
+			validator := validate.NewValidator(validate.Rules{MaxURLLength: 100, MaxCaptions: 3, MaxCaptionLength: 20})
+			base := NewDefaultPoster(mockPoster, validator)
+			router = setupRouter(NewCaptionGeneratorPoster(base, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(newQualityStore(), &quality.Config{}), newTemplateStore(), 3, mockQuota, validator))
+			body = `{"items":[{"url":"https://example.com/a"},{"url":"https://example.com/b"}]}`
+
+			mockGenerator.EXPECT().Create("https://example.com/a", 3).Return([]string{"a caption that is far too long"}, nil)
+			mockGenerator.EXPECT().Create("https://example.com/b", 3).Return([]string{"short"}, nil)
+			mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil).Times(2)
+			mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(func(_ string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+				Expect(posts).To(HaveLen(1))
+				return []*dao.BatchResult{{Post: posts[0]}}, nil
+			})
+		})
+
+		It("should reject the item and still count its generation", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(response.Results[0].Status).To(Equal(http.StatusBadRequest))
+			Expect(response.Results[0].Error.Detail).To(Equal("validation failed: captions[0]: must be at most 20 characters"))
+			Expect(response.Results[1].Status).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("CaptionGeneratorPoster BatchCreate with templates", func() {
+		BeforeEach(func() {
+			method = "POST"
+			mockGenerator := mock_caption.NewMockGenerator(mockCtrl)
+			mockQuota := mock_ratelimit.NewMockQuota(mockCtrl)
+			templateStore := newTemplateStore()
+			tmpl, err := templateStore.Create(customerID, &templates.Template{Name: "house", Text: "{{summary}} {{short_url}}"})
+			Expect(err).To(BeNil())
+			validator := validate.NewValidator(validate.DefaultRules())
+			base := NewDefaultPoster(mockPoster, validator)
+			router = setupRouter(NewCaptionGeneratorPoster(base, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(newQualityStore(), &quality.Config{}), templateStore, 3, mockQuota, validator))
+			body = `{"items":[{"url":"https://example.com/a","template":"` + tmpl.ID.Hex() + `"},{"url":"https://example.com/b"},{"url":"https://example.com/c","template":"unknown"}]}`
+
+			mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil).Times(2)
+			mockGenerator.EXPECT().Create(gomock.Any(), 3).DoAndReturn(func(url string, _ int) ([]string, error) {
+				return []string{"Generated."}, nil
+			}).Times(2)
+			mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(func(_ string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+				results := []*dao.BatchResult{}
+				for _, post := range posts {
+					results = append(results, &dao.BatchResult{Post: post})
+				}
+				return results, nil
+			})
+		})
+
+		It("should render the captions of the items with the template they ask for", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(dao.CaptionTexts(response.Results[0].Post.Captions)).To(Equal([]string{"Generated. example.com/a"}))
+			Expect(dao.CaptionTexts(response.Results[1].Post.Captions)).To(Equal([]string{"Generated."}))
+			Expect(response.Results[2].Status).To(Equal(http.StatusBadRequest))
+			Expect(response.Results[2].Error.Detail).To(Equal("validation failed: template: must be a template id"))
+		})
+	})
+
+	Describe("CaptionGeneratorPoster BatchCreate with the aylien generator", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			method = "POST"
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.ParseForm()).To(Succeed())
+				w.Header().Set("Content-Type", "application/json")
+				Expect(json.NewEncoder(w).Encode(&textapi.SummarizeResponse{Sentences: []string{"Summary of " + r.PostForm.Get("url") + "."}})).To(Succeed())
+			}))
+			summarize := func(params *textapi.SummarizeParams) (*textapi.SummarizeResponse, error) {
+				resp, err := http.PostForm(server.URL, neturl.Values{"url": {params.URL}})
+				if err != nil {
+					return nil, err
+				}
+				defer resp.Body.Close()
+				summary := &textapi.SummarizeResponse{}
+				return summary, json.NewDecoder(resp.Body).Decode(summary)
+			}
+			generator := caption.NewAylienGenerator(log.New(), summarize)
+			validator := validate.NewValidator(validate.DefaultRules())
+			base := NewDefaultPoster(mockPoster, validator)
+			router = setupRouter(NewCaptionGeneratorPoster(base, mockPoster, generator, caption.DefaultScorer(), quality.NewChecker(newQualityStore(), &quality.Config{}), newTemplateStore(), 3, ratelimit.NewMonthlyQuota(100), validator))
+
+			// more items than batchConcurrency, several with the same url so
+			// the cache is read and written at the same time
+			items := []string{}
+			for i := 0; i < 4*batchConcurrency; i++ {
+				items = append(items, fmt.Sprintf(`{"url":"https://example.com/%d"}`, i%batchConcurrency))
+			}
+			body = `{"items":[` + strings.Join(items, ",") + `]}`
+
+			mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(func(_ string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+				results := []*dao.BatchResult{}
+				for _, post := range posts {
+					results = append(results, &dao.BatchResult{Post: post})
+				}
+				return results, nil
+			})
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should generate the captions of every item", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(response.Results).To(HaveLen(4 * batchConcurrency))
+			for i, result := range response.Results {
+				Expect(result.Status).To(Equal(http.StatusOK))
+				url := fmt.Sprintf("https://example.com/%d", i%batchConcurrency)
+				Expect(dao.CaptionTexts(result.Post.Captions)).To(Equal([]string{"Summary of " + url + "."}))
+			}
+		})
+	})
+})
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+type reorderRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// captionEdit changes a copy of the captions of a post, it returns the captions
+// to store
+type captionEdit func(captions []*dao.Caption) ([]*dao.Caption, error)
+
+// SelectCaption defines the handler for choosing the caption of a post, any
+// other selected caption is unselected
+func (p *DefaultPoster) SelectCaption(c *gin.Context) {
+	p.editCaption(c, func(captions []*dao.Caption, i int) ([]*dao.Caption, error) {
+		for j, caption := range captions {
+			caption.Selected = j == i
+		}
+		return captions, nil
+	})
+}
+
+// PinCaption defines the handler for pinning a caption, pinned captions always
+// come before the others
+func (p *DefaultPoster) PinCaption(c *gin.Context) {
+	p.editCaption(c, func(captions []*dao.Caption, i int) ([]*dao.Caption, error) {
+		captions[i].Pinned = true
+		return captions, nil
+	})
+}
+
+// UnpinCaption defines the handler for unpinning a caption, it stays ahead of
+// the captions that followed it
+func (p *DefaultPoster) UnpinCaption(c *gin.Context) {
+	p.editCaption(c, func(captions []*dao.Caption, i int) ([]*dao.Caption, error) {
+		captions[i].Pinned = false
+		return captions, nil
+	})
+}
+
+// DiscardCaption defines the handler for removing a caption from a post. A
+// pinned caption has to be unpinned first
+func (p *DefaultPoster) DiscardCaption(c *gin.Context) {
+	p.editCaption(c, func(captions []*dao.Caption, i int) ([]*dao.Caption, error) {
+		if captions[i].Pinned {
+			return nil, datastore.NewConflictError("caption is pinned")
+		}
+		return append(captions[:i], captions[i+1:]...), nil
+	})
+}
+
+// ReorderCaptions defines the handler for putting the captions of a post in the
+// order of the ids in the body, which must list every caption once. Pinned
+// captions still come first
+func (p *DefaultPoster) ReorderCaptions(c *gin.Context) {
+	req := &reorderRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+
+	p.editCaptions(c, func(captions []*dao.Caption) ([]*dao.Caption, error) {
+		invalid := datastore.NewValidationError(datastore.FieldError{Field: "ids", Message: "must list every caption of the post once"})
+		if len(req.IDs) != len(captions) {
+			return nil, invalid
+		}
+		ordered := make([]*dao.Caption, len(captions))
+		seen := map[string]bool{}
+		for i, id := range req.IDs {
+			j := dao.FindCaption(captions, id)
+			if j < 0 || seen[id] {
+				return nil, invalid
+			}
+			seen[id] = true
+			ordered[i] = captions[j]
+		}
+		return ordered, nil
+	})
+}
+
+// editCaption applies the edit to the caption in the caption_id parameter. A
+// caption the post does not have is not found
+func (p *DefaultPoster) editCaption(c *gin.Context, edit func(captions []*dao.Caption, i int) ([]*dao.Caption, error)) {
+	captionID := c.Param("caption_id")
+	p.editCaptions(c, func(captions []*dao.Caption) ([]*dao.Caption, error) {
+		i := dao.FindCaption(captions, captionID)
+		if i < 0 {
+			return nil, datastore.NewNotFoundError("caption")
+		}
+		return edit(captions, i)
+	})
+}
+
+// editCaptions applies the edit to the captions of the post in the id parameter
+// and responds with the post. The edit is applied in the datastore, so
+// concurrent changes are not lost
+func (p *DefaultPoster) editCaptions(c *gin.Context, edit captionEdit) {
+	urlID := c.Param("id")
+	// Check if id is valid
+	ok := validateID(c, urlID)
+	if !ok {
+		return
+	}
+
+	id := bson.ObjectIdHex(urlID)
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	post, err := p.ds.Patch(customerID, id, func(stored *dao.Post) (*dao.Post, error) {
+		captions, err := edit(stored.Captions)
+		if err != nil {
+			return nil, err
+		}
+		stored.Captions = captions
+		return stored, nil
+	}, getActor(c))
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, mapPost(c, post))
+	return
+}
@@ -1,16 +1,59 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"labix.org/v2/mgo/bson"
 
 	"github.com/bpross/cc-hw/caption"
 	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/templates"
+	"github.com/bpross/cc-hw/validate"
 )
 
 type GeneratePostRequest struct {
 	URL string `json:"url"`
+	// Template is the id of the template the captions are rendered with
+	Template string `json:"template,omitempty"`
+}
+
+// previewRequest renders the template with the id, or the text of a template
+// that is not stored. Captions are generated when none are given
+type previewRequest struct {
+	URL      string   `json:"url"`
+	Template string   `json:"template,omitempty"`
+	Text     string   `json:"text,omitempty"`
+	Captions []string `json:"captions,omitempty"`
+}
+
+// previewResponse is the captions of a post as a template would render them
+type previewResponse struct {
+	URL              string           `json:"url"`
+	Captions         []*dao.Caption   `json:"captions"`
+	RejectedCaptions []*dao.Rejection `json:"rejected_captions,omitempty"`
+}
+
+type warmRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// warmResult is the outcome of generating captions for one url of a warm request
+type warmResult struct {
+	Index  int      `json:"index"`
+	URL    string   `json:"url"`
+	Status int      `json:"status"`
+	Error  *Problem `json:"error,omitempty"`
+}
+
+type warmResponse struct {
+	Results []*warmResult `json:"results"`
 }
 
 // CaptionGeneratorPoster implements the Poster interface and generates captions
@@ -19,23 +62,39 @@ type CaptionGeneratorPoster struct {
 	Poster
 	ds               dao.Poster
 	captionGenerator caption.Generator
+	scorer           caption.Scorer
+	checker          *quality.Checker
+	templates        templates.Store
 	numCaptions      int
+	quota            ratelimit.Quota
+	validator        *validate.Validator
 }
 
-// NewCaptionGeneratorPoster returns a CaptionGeneratorPoster with the provided options
-func NewCaptionGeneratorPoster(base Poster, ds dao.Poster, g caption.Generator, numCaptions int) *CaptionGeneratorPoster {
+// NewCaptionGeneratorPoster returns a CaptionGeneratorPoster with the provided options.
+// Every generation is reserved against the customer's quota before the
+// generator is called, and refunded when no captions come back or its post
+// cannot be stored. The generated
+// captions are checked against the customer's quality rules and the captions
+// that pass are ranked by the scorer, and then rendered with the template the
+// request asks for
+func NewCaptionGeneratorPoster(base Poster, ds dao.Poster, g caption.Generator, scorer caption.Scorer, checker *quality.Checker, templateStore templates.Store, numCaptions int, quota ratelimit.Quota, validator *validate.Validator) *CaptionGeneratorPoster {
 	return &CaptionGeneratorPoster{
 		base,
 		ds,
 		g,
+		scorer,
+		checker,
+		templateStore,
 		numCaptions,
+		quota,
+		validator,
 	}
 }
 
 // Post defines the handler for POST requests. This generates captions and then saves
 func (p *CaptionGeneratorPoster) Post(c *gin.Context) {
-	// Get headers
-	customerID := getAndValidateHeaders(c)
+	// Get tenant
+	customerID := getCustomerID(c)
 	if customerID == "" {
 		return
 	}
@@ -43,30 +102,289 @@ func (p *CaptionGeneratorPoster) Post(c *gin.Context) {
 	// Hydrate post
 	req := &GeneratePostRequest{}
 	if err := c.BindJSON(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
 		return
 	}
 
-	// Generate captions
-	captions, err := p.captionGenerator.Create(req.URL, p.numCaptions)
+	// Reject bad urls before they cost a generation
+	verr := datastore.NewValidationError()
+	req.URL = p.validator.URL(verr, "url", req.URL)
+	if verr.HasErrors() {
+		setReturnError(verr, c)
+		return
+	}
+
+	// An existing post does not need another generation
+	existing, ok := findExisting(c, p.ds, customerID, req.URL)
+	if !ok || existing != nil {
+		return
+	}
+
+	// Generate captions, best first
+	captions, rejected, unused, err := p.generate(customerID, req.URL, req.Template)
+	if unused {
+		p.refund(c, customerID, 1)
+	}
 	if err != nil {
-		// log here
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "unable to generate captions"})
+		setReturnError(generatorError(c, err), c)
 		return
 	}
 
 	// Save post
 	input := generatePostRequestToPost(*req, captions)
+	input.RejectedCaptions = rejected
+	input.UpdatedBy = getActor(c)
 	post, err := p.ds.Insert(customerID, input)
 	if err != nil {
+		p.refund(c, customerID, 1)
 		setReturnError(err, c)
 		return
 	}
-	c.PureJSON(http.StatusOK, post)
+	c.PureJSON(http.StatusOK, mapPost(c, post))
 	return
 }
 
-func generatePostRequestToPost(req GeneratePostRequest, captions []string) *dao.Post {
+// BatchCreate defines the handler for creating several posts, generating
+// captions for the items without any. Every generation is counted against the
+// quota
+func (p *CaptionGeneratorPoster) BatchCreate(c *gin.Context) {
+	batchCreate(c, p.ds, p.validator, p)
+}
+
+// Import defines the handler for creating posts from a csv or json lines body.
+// With generate=true captions are generated for the rows without any
+func (p *CaptionGeneratorPoster) Import(c *gin.Context) {
+	importPosts(c, p.ds, p.validator, p)
+}
+
+// Warm defines the admin handler for generating the captions of urls ahead of
+// time, so the generator can answer from its cache. It is not counted against
+// any quota and nothing is stored as a post
+func (p *CaptionGeneratorPoster) Warm(c *gin.Context) {
+	req := &warmRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+	if !checkBatchSize(c, "urls", len(req.URLs)) {
+		return
+	}
+
+	results := make([]*warmResult, len(req.URLs))
+	errs := make([]error, len(req.URLs))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, url := range req.URLs {
+		verr := datastore.NewValidationError()
+		url = p.validator.URL(verr, "url", url)
+		results[i] = &warmResult{Index: i, URL: url, Status: http.StatusOK}
+		if verr.HasErrors() {
+			errs[i] = verr
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			_, errs[i] = p.captionGenerator.Create(url, p.numCaptions)
+		}(i, url)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			problem := errorProblem(generatorError(c, err), c)
+			results[i].Status, results[i].Error = problem.Status, problem
+		}
+	}
+	c.PureJSON(http.StatusOK, &warmResponse{Results: results})
+}
+
+// PreviewTemplate defines the handler for rendering a template without storing
+// anything. Captions are generated for the url when the request has none, and
+// the generation is counted against the quota
+func (p *CaptionGeneratorPoster) PreviewTemplate(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	req := &previewRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+
+	verr := datastore.NewValidationError()
+	req.URL = p.validator.URL(verr, "url", req.URL)
+	field, text := "text", req.Text
+	switch {
+	case req.Template != "" && req.Text != "":
+		verr.Add("text", "must not be set with template")
+	case req.Template != "":
+		field = "template"
+	default:
+		if err := templates.Check(req.Text); err != nil {
+			verr.Add("text", err.Error())
+		}
+	}
+	if verr.HasErrors() {
+		setReturnError(verr, c)
+		return
+	}
+	if req.Template != "" {
+		var err error
+		if text, err = p.templateText(customerID, req.Template); err != nil {
+			setReturnError(err, c)
+			return
+		}
+	}
+
+	resp := &previewResponse{URL: req.URL}
+	if len(req.Captions) > 0 {
+		input := &dao.Post{URL: req.URL, Captions: dao.ManualCaptions(req.Captions)}
+		if err := p.validator.Post(input); err != nil {
+			setReturnError(err, c)
+			return
+		}
+		rendered, err := p.render(field, text, req.URL, input.Captions)
+		if err != nil {
+			setReturnError(err, c)
+			return
+		}
+		resp.Captions = rendered
+	} else {
+		captions, rejected, unused, err := p.generateWith(customerID, req.URL, field, text)
+		if unused {
+			p.refund(c, customerID, 1)
+		}
+		if err != nil {
+			setReturnError(generatorError(c, err), c)
+			return
+		}
+		resp.Captions, resp.RejectedCaptions = captions, rejected
+	}
+	c.PureJSON(http.StatusOK, resp)
+}
+
+// generate generates the captions of the url, rendered with the customer's
+// template if its id is set. The template is looked up before the generation
+// is reserved against the quota
+func (p *CaptionGeneratorPoster) generate(customerID, url, templateID string) ([]*dao.Caption, []*dao.Rejection, bool, error) {
+	text, err := p.templateText(customerID, templateID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return p.generateWith(customerID, url, "template", text)
+}
+
+// generateWith generates captions, checks them against the customer's quality
+// rules and ranks the captions that pass. With a template text the captions are
+// ranked by their summary and rendered first, so the rules check the rendered
+// captions. Template errors are returned on field. The generation is reserved
+// against the quota before the generator is called, so concurrent requests
+// cannot overspend it. unused reports a reserved generation the caller has to
+// refund because the captions could not be made, a generation whose captions
+// fail to render or validate still counts
+func (p *CaptionGeneratorPoster) generateWith(customerID, url, field, text string) (captions []*dao.Caption, rejected []*dao.Rejection, unused bool, err error) {
+	if _, err := p.quota.Consume(customerID, 1); err != nil {
+		return nil, nil, false, err
+	}
+	candidates, err := p.captionGenerator.Create(url, p.numCaptions)
+	if err != nil {
+		return nil, nil, true, err
+	}
+
+	if text == "" {
+		if captions, rejected, err = p.checker.Check(customerID, url, candidates); err != nil {
+			return nil, nil, true, err
+		}
+		captions = caption.RankCaptions(p.scorer, url, captions)
+	} else {
+		ranked := caption.RankCaptions(p.scorer, url, generatedCaptions(candidates))
+		rendered, err := p.render(field, text, url, ranked)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if captions, rejected, err = p.checker.CheckCaptions(customerID, url, rendered); err != nil {
+			return nil, nil, true, err
+		}
+	}
+
+	verr := datastore.NewValidationError()
+	captions = p.validator.Captions(verr, "captions", captions)
+	if verr.HasErrors() {
+		return nil, nil, false, verr
+	}
+	return captions, rejected, false, nil
+}
+
+// refund gives back the quota of n generations whose posts were not stored. A
+// failed refund is only logged, the request already failed for another reason
+func (p *CaptionGeneratorPoster) refund(c *gin.Context, customerID string, n int) {
+	if err := p.quota.Refund(customerID, n); err != nil {
+		c.Error(err)
+	}
+}
+
+// render renders the captions with the template text and checks the rendered
+// captions are not longer than a caption may be. Errors are returned on field
+func (p *CaptionGeneratorPoster) render(field, text, url string, captions []*dao.Caption) ([]*dao.Caption, error) {
+	rendered, err := templates.Apply(field, text, url, captions)
+	if err != nil {
+		return nil, err
+	}
+	max := p.validator.MaxCaptionLength()
+	for _, c := range rendered {
+		if n := utf8.RuneCountInString(c.Text); n > max {
+			msg := fmt.Sprintf("renders a caption of %d characters, captions can have at most %d", n, max)
+			return nil, datastore.NewValidationError(datastore.FieldError{Field: field, Message: msg})
+		}
+	}
+	return rendered, nil
+}
+
+// generatedCaptions returns the generated captions with the texts
+func generatedCaptions(texts []string) []*dao.Caption {
+	captions := make([]*dao.Caption, len(texts))
+	for i, text := range texts {
+		captions[i] = &dao.Caption{Text: text, Source: dao.SourceGenerated}
+	}
+	return captions
+}
+
+// templateText returns the text of the customer's template, or an empty string
+// when id is not set
+func (p *CaptionGeneratorPoster) templateText(customerID, id string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+	if !bson.IsObjectIdHex(id) {
+		return "", datastore.NewValidationError(datastore.FieldError{Field: "template", Message: "must be a template id"})
+	}
+	t, err := p.templates.Get(customerID, bson.ObjectIdHex(id))
+	if err != nil {
+		if _, ok := err.(*datastore.NotFound); ok {
+			return "", datastore.NewValidationError(datastore.FieldError{Field: "template", Message: "must be the id of a template of the customer"})
+		}
+		return "", err
+	}
+	return t.Text, nil
+}
+
+// generatorError returns the error to respond with for a generator error.
+// Generators should return typed errors, anything else means the generator
+// could not be used
+func generatorError(c *gin.Context, err error) error {
+	if _, ok := err.(datastore.Coder); !ok {
+		c.Error(err)
+		return datastore.NewUnavailableError("caption generator")
+	}
+	return err
+}
+
+func generatePostRequestToPost(req GeneratePostRequest, captions []*dao.Caption) *dao.Post {
 	return &dao.Post{
 		URL:      req.URL,
 		Captions: captions,
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -15,10 +16,17 @@ import (
 	. "github.com/onsi/gomega"
 	"labix.org/v2/mgo/bson"
 
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/caption"
 	"github.com/bpross/cc-hw/dao"
 	"github.com/bpross/cc-hw/datastore"
 	mock_caption "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/caption"
 	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	mock_ratelimit "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/templates"
+	"github.com/bpross/cc-hw/validate"
 )
 
 var _ = Describe("CaptionGeneratorPoster", func() {
@@ -28,6 +36,9 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 		baseHandler   *DefaultPoster
 		handler       *CaptionGeneratorPoster
 		mockGenerator *mock_caption.MockGenerator
+		mockQuota     *mock_ratelimit.MockQuota
+		qualityStore  *quality.InMemoryStore
+		templateStore *templates.InMemoryStore
 		router        *gin.Engine
 		customerID    string
 		recorder      *httptest.ResponseRecorder
@@ -41,9 +52,12 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 		mockCtrl = gomock.NewController(GinkgoT())
 		mockPoster = mock_dao.NewMockPoster(mockCtrl)
 		mockGenerator = mock_caption.NewMockGenerator(mockCtrl)
-		baseHandler = NewDefaultPoster(mockPoster)
+		baseHandler = NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules()))
+		mockQuota = mock_ratelimit.NewMockQuota(mockCtrl)
 		numCaptions = 3
-		handler = NewCaptionGeneratorPoster(baseHandler, mockPoster, mockGenerator, numCaptions)
+		qualityStore = newQualityStore()
+		templateStore = newTemplateStore()
+		handler = NewCaptionGeneratorPoster(baseHandler, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(qualityStore, &quality.Config{}), templateStore, numCaptions, mockQuota, validate.NewValidator(validate.DefaultRules()))
 		router = setupRouter(handler)
 		customerID = "test-customer"
 		recorder = httptest.NewRecorder()
@@ -70,14 +84,12 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 				Expect(err).To(BeNil())
 			})
 
-			It("should return StatusBadRequest", func() {
-				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
 			})
 
 			It("should return a useful message", func() {
-				expected := `{"message":"must include customerID in headers"}`
-				actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-				Expect(actual).To(Equal(expected))
+				expectProblem(recorder, auth.CodeUnauthenticated, "unauthorized: request is not authenticated")
 			})
 		})
 
@@ -94,9 +106,60 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 				})
 
 				It("should return a useful message", func() {
-					expected := `{"message":"invalid request"}`
-					actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-					Expect(actual).To(Equal(expected))
+					expectProblem(recorder, datastore.CodeInvalidArgument, "invalid request")
+				})
+			})
+
+			Context("with invalid url", func() {
+				BeforeEach(func() {
+					req, err = http.NewRequest(method, url, strings.NewReader(`{"url":"file:///etc/passwd"}`))
+					Expect(err).To(BeNil())
+					req.Header.Add(customerIDHeader, customerID)
+					req.Header.Add("Content-Type", "application/json")
+				})
+
+				It("should return StatusBadRequest without using the quota", func() {
+					Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+					expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: url: must be http or https")
+				})
+			})
+
+			Context("with a template", func() {
+				var input *dao.Post
+				BeforeEach(func() {
+					tmpl, err := templateStore.Create(customerID, &templates.Template{Name: "house", Text: "📣 {{summary}} Read more: {{short_url}}"})
+					Expect(err).To(BeNil())
+					req, err = http.NewRequest(method, url, strings.NewReader(`{"url":"https://example.com/post","template":"`+tmpl.ID.Hex()+`"}`))
+					Expect(err).To(BeNil())
+					req.Header.Add(customerIDHeader, customerID)
+					req.Header.Add("Content-Type", "application/json")
+					mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+					mockGenerator.EXPECT().Create("https://example.com/post", numCaptions).Return([]string{"A caption."}, nil)
+					mockPoster.EXPECT().Insert(customerID, gomock.Any()).DoAndReturn(func(_ string, p *dao.Post) (*dao.Post, error) {
+						input = p
+						return p, nil
+					})
+				})
+
+				It("should store the captions rendered with the template", func() {
+					Expect(recorder.Code).To(Equal(http.StatusOK))
+					Expect(input.Captions).To(HaveLen(1))
+					Expect(input.Captions[0].Text).To(Equal("📣 A caption. Read more: example.com/post"))
+					Expect(input.Captions[0].Source).To(Equal(dao.SourceGenerated))
+				})
+			})
+
+			Context("with an unknown template", func() {
+				BeforeEach(func() {
+					req, err = http.NewRequest(method, url, strings.NewReader(`{"url":"https://example.com/post","template":"`+bson.NewObjectId().Hex()+`"}`))
+					Expect(err).To(BeNil())
+					req.Header.Add(customerIDHeader, customerID)
+					req.Header.Add("Content-Type", "application/json")
+				})
+
+				It("should return StatusBadRequest without using the quota", func() {
+					Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+					expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: template: must be the id of a template of the customer")
 				})
 			})
 
@@ -107,7 +170,7 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 				)
 				BeforeEach(func() {
 					post = dao.Post{
-						URL: "test-url",
+						URL: "https://example.com/post",
 					}
 					body, err = json.Marshal(post)
 					Expect(err).To(BeNil())
@@ -117,21 +180,87 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 					req.Header.Add("Content-Type", "application/json")
 				})
 
+				Context("with dedupe", func() {
+					BeforeEach(func() {
+						req.URL.RawQuery = "dedupe=true"
+					})
+
+					Context("with an existing post", func() {
+						var postID bson.ObjectId
+						BeforeEach(func() {
+							postID = bson.NewObjectId()
+							existing := &dao.Post{
+								ID:       &postID,
+								URL:      "https://example.com/post",
+								Captions: []*dao.Caption{{ID: "c1", Text: "caption1", Source: dao.SourceManual}},
+							}
+							mockPoster.EXPECT().GetByURL(customerID, "https://example.com/post").Return(existing, nil)
+						})
+
+						It("should return the existing post without generating", func() {
+							Expect(recorder.Code).To(Equal(http.StatusOK))
+							Expect(recorder.Header().Get("Existing-Post")).To(Equal("true"))
+							expected := fmt.Sprintf(`{"id":"%s","url":"https://example.com/post","captions":[{"id":"c1","text":"caption1","source":"manual","selected":false,"pinned":false}]}`, postID.Hex())
+							Expect(strings.TrimSuffix(recorder.Body.String(), "\n")).To(Equal(expected))
+						})
+					})
+
+					Context("with datastore error", func() {
+						BeforeEach(func() {
+							mockPoster.EXPECT().GetByURL(customerID, "https://example.com/post").Return(nil, datastore.NewUnavailableError("datastore"))
+						})
+
+						It("should return the error", func() {
+							Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+						})
+					})
+
+					Context("without an existing post", func() {
+						BeforeEach(func() {
+							mockPoster.EXPECT().GetByURL(customerID, "https://example.com/post").Return(nil, datastore.NewNotFoundError("post"))
+							mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, datastore.NewQuotaExceededError("10 generations for 2020-01", time.Now().Add(time.Hour)))
+						})
+
+						It("should go on to generate", func() {
+							Expect(recorder.Code).To(Equal(http.StatusTooManyRequests))
+							Expect(recorder.Header().Get("Existing-Post")).To(Equal(""))
+						})
+					})
+				})
+
+				Context("with quota exceeded", func() {
+					BeforeEach(func() {
+						mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, datastore.NewQuotaExceededError("10 generations for 2020-01", time.Now().Add(time.Hour)))
+					})
+
+					It("should return StatusTooManyRequests without generating", func() {
+						Expect(recorder.Code).To(Equal(http.StatusTooManyRequests))
+					})
+
+					It("should set Retry-After", func() {
+						Expect(recorder.Header().Get("Retry-After")).To(Equal("3600"))
+					})
+
+					It("should return a useful message", func() {
+						expectProblem(recorder, datastore.CodeQuotaExceeded, "quota exceeded: 10 generations for 2020-01")
+					})
+				})
+
 				Context("with generator error", func() {
 					var genErr error
 					BeforeEach(func() {
 						genErr = errors.New("generator error")
+						mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
 						mockGenerator.EXPECT().Create(post.URL, numCaptions).Return(nil, genErr)
+						mockQuota.EXPECT().Refund(customerID, 1).Return(nil)
 					})
 
-					It("should return StatusInternalServerError", func() {
-						Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+					It("should return StatusServiceUnavailable and refund the quota", func() {
+						Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
 					})
 
 					It("should return a useful message", func() {
-						expected := `{"message":"unable to generate captions"}`
-						actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-						Expect(actual).To(Equal(expected))
+						expectProblem(recorder, datastore.CodeUnavailable, "caption generator unavailable")
 					})
 				})
 
@@ -147,12 +276,18 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 							"caption3",
 						}
 						generatePost = dao.Post{
-							URL:      "test-url",
-							Captions: captions,
+							URL:       "https://example.com/post",
+							Captions:  caption.Rank(caption.DefaultScorer(), post.URL, captions),
+							UpdatedBy: customerID,
 						}
+						mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
 						mockGenerator.EXPECT().Create(post.URL, numCaptions).Return(captions, nil)
 					})
 					Context("with datastore error", func() {
+						BeforeEach(func() {
+							mockQuota.EXPECT().Refund(customerID, 1).Return(nil)
+						})
+
 						Context("with InvalidArugment error", func() {
 							BeforeEach(func() {
 								daoErr := datastore.NewInvalidArugmentError("test-error")
@@ -164,9 +299,7 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 							})
 
 							It("should return the datastore message", func() {
-								expected := `{"message":"invalid test-error"}`
-								actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-								Expect(actual).To(Equal(expected))
+								expectProblem(recorder, datastore.CodeInvalidArgument, "invalid test-error")
 							})
 						})
 
@@ -181,9 +314,7 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 							})
 
 							It("should return the datastore message", func() {
-								expected := `{"message":"test-error"}`
-								actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-								Expect(actual).To(Equal(expected))
+								expectProblem(recorder, codeInternal, "internal server error")
 							})
 						})
 					})
@@ -198,11 +329,10 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 							dsPost = &dao.Post{
 								ID:     &postID,
 								CustID: customerID,
-								URL:    "test-url",
-								Captions: []string{
-									"caption1",
-									"caption2",
-									"caption3",
+								URL:    "https://example.com/post",
+								Captions: []*dao.Caption{
+									{ID: "c1", Text: "caption1", Source: dao.SourceGenerated, Score: 0.5},
+									{ID: "c2", Text: "caption2", Source: dao.SourceGenerated, Score: 0.25},
 								},
 							}
 							mockPoster.EXPECT().Insert(customerID, &generatePost).Return(dsPost, nil)
@@ -213,12 +343,244 @@ var _ = Describe("CaptionGeneratorPoster", func() {
 						})
 
 						It("should return a post", func() {
-							expected := fmt.Sprintf(`{"id":"%s","url":"test-url","captions":["caption1","caption2","caption3"]}`, postID.Hex())
+							expected := fmt.Sprintf(`{"id":"%s","url":"https://example.com/post","captions":[{"id":"c1","text":"caption1","source":"generated","score":0.5,"selected":false,"pinned":false},{"id":"c2","text":"caption2","source":"generated","score":0.25,"selected":false,"pinned":false}]}`, postID.Hex())
 							actual := strings.TrimSuffix(recorder.Body.String(), "\n")
 							Expect(actual).To(Equal(expected))
 						})
 					})
 				})
+
+				Context("with quality rules", func() {
+					var input *dao.Post
+					BeforeEach(func() {
+						_, err := qualityStore.Put(customerID, &quality.Config{Rules: []*quality.RuleConfig{
+							{Type: quality.TypeBlocklist, Action: quality.ActionReject, Terms: []string{"cheap"}},
+							{Type: quality.TypeFragment, Action: quality.ActionFlag},
+						}})
+						Expect(err).To(BeNil())
+						mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+						mockGenerator.EXPECT().Create(post.URL, numCaptions).Return([]string{"So cheap.", "A whole sentence.", "no end"}, nil)
+						mockPoster.EXPECT().Insert(customerID, gomock.Any()).DoAndReturn(func(_ string, p *dao.Post) (*dao.Post, error) {
+							input = p
+							return p, nil
+						})
+					})
+
+					It("should store the captions that pass with their findings, and the rejected captions", func() {
+						Expect(recorder.Code).To(Equal(http.StatusOK))
+						Expect(input.Captions).To(HaveLen(2))
+						texts := []string{input.Captions[0].Text, input.Captions[1].Text}
+						Expect(texts).To(ConsistOf("A whole sentence.", "no end"))
+						for _, c := range input.Captions {
+							if c.Text == "no end" {
+								Expect(c.Findings).To(Equal([]*dao.Finding{{Rule: quality.TypeFragment, Action: quality.ActionFlag, Message: "does not start with a capital letter"}}))
+							} else {
+								Expect(c.Findings).To(BeNil())
+							}
+						}
+						Expect(input.RejectedCaptions).To(Equal([]*dao.Rejection{{Text: "So cheap.", Findings: []*dao.Finding{
+							{Rule: quality.TypeBlocklist, Action: quality.ActionReject, Message: `contains blocked term "cheap"`},
+						}}}))
+						Expect(recorder.Body.String()).To(ContainSubstring(`"rejected_captions":[{"text":"So cheap.","findings":[{"rule":"blocklist","action":"reject","message":"contains blocked term \"cheap\""}]}]`))
+					})
+				})
+			})
+		})
+	})
+
+	Describe("Post with a quota of one generation", func() {
+		It("should generate once for concurrent requests", func() {
+			quota := ratelimit.NewMonthlyQuota(1)
+			handler = NewCaptionGeneratorPoster(baseHandler, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(qualityStore, &quality.Config{}), templateStore, numCaptions, quota, validate.NewValidator(validate.DefaultRules()))
+			router = setupRouter(handler)
+			mockGenerator.EXPECT().Create("https://example.com/post", numCaptions).Return([]string{"caption1"}, nil)
+			mockPoster.EXPECT().Insert(customerID, gomock.Any()).DoAndReturn(func(_ string, p *dao.Post) (*dao.Post, error) {
+				return p, nil
+			})
+
+			codes := make(chan int, 5)
+			for i := 0; i < 5; i++ {
+				go func() {
+					defer GinkgoRecover()
+					req, err := http.NewRequest("POST", "/post", strings.NewReader(`{"url":"https://example.com/post"}`))
+					Expect(err).To(BeNil())
+					req.Header.Add(customerIDHeader, customerID)
+					req.Header.Add("Content-Type", "application/json")
+					recorder := httptest.NewRecorder()
+					router.ServeHTTP(recorder, req)
+					codes <- recorder.Code
+				}()
+			}
+			got := []int{}
+			for i := 0; i < 5; i++ {
+				got = append(got, <-codes)
+			}
+			Expect(got).To(ConsistOf(http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests))
+
+			usage, err := quota.Usage(customerID)
+			Expect(err).To(BeNil())
+			Expect(usage.Used).To(Equal(1))
+		})
+	})
+
+	Describe("PreviewTemplate", func() {
+		var body string
+
+		BeforeEach(func() {
+			router.POST("/templates/preview", handler.PreviewTemplate)
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			req, err = http.NewRequest("POST", "/templates/preview", strings.NewReader(body))
+			Expect(err).To(BeNil())
+			req.Header.Add(customerIDHeader, customerID)
+			req.Header.Add("Content-Type", "application/json")
+			router.ServeHTTP(recorder, req)
+		})
+
+		Context("with captions", func() {
+			BeforeEach(func() {
+				body = `{"url":"https://example.com/agency-domains","text":"{{summary}} {{hashtags}}","captions":["First.","Second."]}`
+			})
+
+			It("should render the captions without generating or storing anything", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				expected := `{"url":"https://example.com/agency-domains","captions":[{"id":"","text":"First. #agency #domains","source":"manual","selected":false,"pinned":false},{"id":"","text":"Second. #agency #domains","source":"manual","selected":false,"pinned":false}]}`
+				Expect(strings.TrimSuffix(recorder.Body.String(), "\n")).To(Equal(expected))
+			})
+		})
+
+		Context("without captions", func() {
+			BeforeEach(func() {
+				tmpl, err := templateStore.Create(customerID, &templates.Template{Name: "house", Text: "{{upper summary}}"})
+				Expect(err).To(BeNil())
+				body = `{"url":"https://example.com/post","template":"` + tmpl.ID.Hex() + `"}`
+				mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+				mockGenerator.EXPECT().Create("https://example.com/post", numCaptions).Return([]string{"A caption."}, nil)
+			})
+
+			It("should render generated captions with the stored template", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				resp := &previewResponse{}
+				Expect(json.Unmarshal(recorder.Body.Bytes(), resp)).To(Succeed())
+				Expect(resp.Captions).To(HaveLen(1))
+				Expect(resp.Captions[0].Text).To(Equal("A CAPTION."))
+				Expect(resp.Captions[0].Source).To(Equal(dao.SourceGenerated))
+			})
+		})
+
+		Context("with captions that render too long", func() {
+			BeforeEach(func() {
+				body = `{"url":"https://example.com/post","text":"{{summary}} {{summary}}","captions":["` + strings.Repeat("a", 200) + `"]}`
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: text: renders a caption of 401 characters, captions can have at most 280")
+			})
+		})
+
+		Context("with generated captions that render too long", func() {
+			BeforeEach(func() {
+				tmpl, err := templateStore.Create(customerID, &templates.Template{Name: "house", Text: "{{summary}} {{summary}}"})
+				Expect(err).To(BeNil())
+				body = `{"url":"https://example.com/post","template":"` + tmpl.ID.Hex() + `"}`
+				mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+				mockGenerator.EXPECT().Create("https://example.com/post", numCaptions).Return([]string{strings.Repeat("a", 200)}, nil)
+			})
+
+			It("should return StatusBadRequest and still count the generation", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: template: renders a caption of 401 characters, captions can have at most 280")
+			})
+		})
+
+		Context("with quality rules", func() {
+			BeforeEach(func() {
+				_, err := qualityStore.Put(customerID, &quality.Config{Rules: []*quality.RuleConfig{
+					{Type: quality.TypeBlocklist, Action: quality.ActionReject, Terms: []string{"cheap"}},
+				}})
+				Expect(err).To(BeNil())
+				body = `{"url":"https://example.com/post","text":"{{summary}} Cheap at {{domain}}."}`
+				mockGenerator.EXPECT().Create("https://example.com/post", numCaptions).Return([]string{"A caption."}, nil)
+				mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+			})
+
+			It("should check the rendered captions", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				resp := &previewResponse{}
+				Expect(json.Unmarshal(recorder.Body.Bytes(), resp)).To(Succeed())
+				Expect(resp.Captions).To(BeEmpty())
+				Expect(resp.RejectedCaptions).To(HaveLen(1))
+				Expect(resp.RejectedCaptions[0].Text).To(Equal("A caption. Cheap at example.com."))
+			})
+		})
+
+		Context("with an invalid template", func() {
+			BeforeEach(func() {
+				body = `{"url":"https://example.com/post","text":"{{printf \"%d\" 1}}","captions":["First."]}`
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, `validation failed: text: function "printf" is not allowed`)
+			})
+		})
+
+		Context("with both a template and text", func() {
+			BeforeEach(func() {
+				body = `{"url":"https://example.com/post","template":"` + bson.NewObjectId().Hex() + `","text":"{{summary}}"}`
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: text: must not be set with template")
+			})
+		})
+	})
+
+	Describe("Warm", func() {
+		var body string
+
+		BeforeEach(func() {
+			router.POST("/captions/warm", handler.Warm)
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			req, err = http.NewRequest("POST", "/captions/warm", strings.NewReader(body))
+			Expect(err).To(BeNil())
+			router.ServeHTTP(recorder, req)
+		})
+
+		Context("without urls", func() {
+			BeforeEach(func() {
+				body = `{"urls":[]}`
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with urls", func() {
+			BeforeEach(func() {
+				body = `{"urls":["https://example.com/a","ftp://example.com/b","https://example.com/c"]}`
+				mockGenerator.EXPECT().Create("https://example.com/a", numCaptions).Return([]string{"caption"}, nil)
+				mockGenerator.EXPECT().Create("https://example.com/c", numCaptions).Return(nil, errors.New("test-error"))
+			})
+
+			It("should generate captions without storing posts or using quota", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				resp := &warmResponse{}
+				Expect(json.Unmarshal(recorder.Body.Bytes(), resp)).To(Succeed())
+				Expect(resp.Results).To(HaveLen(3))
+				Expect(resp.Results[0].Status).To(Equal(http.StatusOK))
+				Expect(resp.Results[0].Error).To(BeNil())
+				Expect(resp.Results[1].Status).To(Equal(http.StatusBadRequest))
+				Expect(resp.Results[1].Error.Code).To(Equal(datastore.CodeValidationFailed))
+				Expect(resp.Results[2].Status).To(Equal(http.StatusServiceUnavailable))
 			})
 		})
 	})
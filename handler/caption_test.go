@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/validate"
+)
+
+var _ = Describe("Captions", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockPoster *mock_dao.MockPoster
+		router     *gin.Engine
+		recorder   *httptest.ResponseRecorder
+		customerID string
+		postID     bson.ObjectId
+		stored     *dao.Post
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPoster = mock_dao.NewMockPoster(mockCtrl)
+		router = setupRouter(NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules())))
+		recorder = httptest.NewRecorder()
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+		stored = &dao.Post{
+			ID:  &postID,
+			URL: "https://example.com/post",
+			Captions: []*dao.Caption{
+				{ID: "a", Text: "caption a", Source: dao.SourceGenerated, Score: 0.9, Selected: true},
+				{ID: "b", Text: "caption b", Source: dao.SourceGenerated, Score: 0.5},
+				{ID: "c", Text: "caption c", Source: dao.SourceManual, Pinned: true},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	// expectPatch makes the datastore apply the edit to a copy of the stored post
+	expectPatch := func() {
+		mockPoster.EXPECT().Patch(customerID, postID, gomock.Any(), customerID).DoAndReturn(
+			func(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+				copied := *stored
+				copied.Captions = dao.CopyCaptions(stored.Captions)
+				return patch(&copied)
+			})
+	}
+
+	do := func(method, path, body string) {
+		req := httptest.NewRequest(method, "/post/"+postID.Hex()+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(customerIDHeader, customerID)
+		router.ServeHTTP(recorder, req)
+	}
+
+	captions := func() []*dao.Caption {
+		post := &dao.Post{}
+		ExpectWithOffset(1, json.Unmarshal(recorder.Body.Bytes(), post)).To(Succeed())
+		return post.Captions
+	}
+
+	Describe("SelectCaption", func() {
+		It("should select only that caption", func() {
+			expectPatch()
+			do(http.MethodPost, "/captions/b/select", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			selected := []bool{}
+			for _, caption := range captions() {
+				selected = append(selected, caption.Selected)
+			}
+			Expect(selected).To(Equal([]bool{false, true, false}))
+		})
+
+		It("should return StatusNotFound for a caption the post does not have", func() {
+			expectPatch()
+			do(http.MethodPost, "/captions/z/select", "")
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			expectProblem(recorder, datastore.CodeNotFound, "caption not found")
+		})
+
+		It("should return StatusBadRequest for an invalid post id", func() {
+			req := httptest.NewRequest(http.MethodPost, "/post/blah/captions/a/select", nil)
+			req.Header.Set(customerIDHeader, customerID)
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("PinCaption", func() {
+		It("should pin the caption", func() {
+			expectPatch()
+			do(http.MethodPost, "/captions/a/pin", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(captions()[0].Pinned).To(BeTrue())
+		})
+	})
+
+	Describe("UnpinCaption", func() {
+		It("should unpin the caption", func() {
+			expectPatch()
+			do(http.MethodPost, "/captions/c/unpin", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(captions()[2].Pinned).To(BeFalse())
+		})
+	})
+
+	Describe("DiscardCaption", func() {
+		It("should remove the caption", func() {
+			expectPatch()
+			do(http.MethodDelete, "/captions/b", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(dao.CaptionTexts(captions())).To(Equal([]string{"caption a", "caption c"}))
+		})
+
+		It("should return StatusConflict for a pinned caption", func() {
+			expectPatch()
+			do(http.MethodDelete, "/captions/c", "")
+			Expect(recorder.Code).To(Equal(http.StatusConflict))
+			expectProblem(recorder, datastore.CodeConflict, "conflict: caption is pinned")
+		})
+
+		It("should return the datastore error", func() {
+			mockPoster.EXPECT().Patch(customerID, postID, gomock.Any(), customerID).Return(nil, datastore.NewNotFoundError("post"))
+			do(http.MethodDelete, "/captions/b", "")
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("ReorderCaptions", func() {
+		It("should put the captions in the order of the ids", func() {
+			expectPatch()
+			do(http.MethodPut, "/captions/order", `{"ids": ["c", "b", "a"]}`)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(dao.CaptionTexts(captions())).To(Equal([]string{"caption c", "caption b", "caption a"}))
+		})
+
+		It("should reject ids that do not list every caption once", func() {
+			cases := []string{
+				`{"ids": ["c", "b"]}`,
+				`{"ids": ["c", "b", "b"]}`,
+				`{"ids": ["c", "b", "z"]}`,
+			}
+			for _, body := range cases {
+				recorder = httptest.NewRecorder()
+				expectPatch()
+				do(http.MethodPut, "/captions/order", body)
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest), body)
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: ids: must list every caption of the post once")
+			}
+		})
+
+		It("should return StatusBadRequest for an invalid body", func() {
+			do(http.MethodPut, "/captions/order", `{"ids": "a"}`)
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
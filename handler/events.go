@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+)
+
+const (
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+)
+
+type eventsResponse struct {
+	Events     []*eventResponse `json:"events"`
+	NextOffset int64            `json:"next_offset"`
+}
+
+// eventResponse is an event with the post in the representation of the version
+type eventResponse struct {
+	*events.Event
+	Post interface{} `json:"post,omitempty"`
+}
+
+// EventFeed defines the interface to handle event feed requests
+type EventFeed interface {
+	Poll(*gin.Context)
+}
+
+// DefaultEventFeed implements the EventFeed interface
+type DefaultEventFeed struct {
+	log     events.Log
+	maxWait time.Duration
+}
+
+// NewDefaultEventFeed returns a DefaultEventFeed with the provided options. A
+// request never waits longer than maxWait for new events
+func NewDefaultEventFeed(log events.Log, maxWait time.Duration) *DefaultEventFeed {
+	return &DefaultEventFeed{
+		log:     log,
+		maxWait: maxWait,
+	}
+}
+
+// Poll defines the handler for reading the customer's events after an offset. It
+// waits up to wait seconds, by default the longest allowed, for new events
+func (f *DefaultEventFeed) Poll(c *gin.Context) {
+	after, err := queryInt(c, "after", 0)
+	if err != nil || after < 0 {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid after", nil)
+		return
+	}
+	limit, err := queryInt(c, "limit", defaultEventLimit)
+	if err != nil || limit < 1 || limit > maxEventLimit {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid limit", nil)
+		return
+	}
+	wait := f.maxWait
+	if seconds, err := queryInt(c, "wait", -1); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid wait", nil)
+		return
+	} else if seconds >= 0 && time.Duration(seconds)*time.Second < f.maxWait {
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	var feed []*events.Event
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		defer cancel()
+		feed, err = f.log.Poll(ctx, customerID, int64(after), int(limit))
+	} else {
+		feed, err = f.log.Read(customerID, int64(after), int(limit))
+	}
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+
+	next := int64(after)
+	if len(feed) > 0 {
+		next = feed[len(feed)-1].Offset
+	}
+	c.PureJSON(http.StatusOK, eventsResponse{
+		Events:     mapEvents(c, feed),
+		NextOffset: next,
+	})
+	return
+}
+
+// mapEvents returns the events with their posts in the representation of the
+// version, keeping a nil list nil
+func mapEvents(c *gin.Context, feed []*events.Event) []*eventResponse {
+	if feed == nil {
+		return nil
+	}
+	mapped := make([]*eventResponse, len(feed))
+	for i, e := range feed {
+		mapped[i] = &eventResponse{Event: e, Post: mapPost(c, e.Post)}
+	}
+	return mapped
+}
+
+// queryInt returns the query parameter as an int, or the default when it is not set
+func queryInt(c *gin.Context, name string, def int64) (int64, error) {
+	v := c.Query(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+)
+
+var _ = Describe("DefaultEventFeed", func() {
+	var (
+		eventLog   *events.InMemoryLog
+		handler    *DefaultEventFeed
+		router     *gin.Engine
+		recorder   *httptest.ResponseRecorder
+		req        *http.Request
+		customerID string
+		postID     bson.ObjectId
+		query      string
+	)
+
+	BeforeEach(func() {
+		logger := log.New()
+		logger.Out = ioutil.Discard
+		eventLog = events.NewInMemoryLog(logger)
+		handler = NewDefaultEventFeed(eventLog, 50*time.Millisecond)
+		recorder = httptest.NewRecorder()
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+		query = ""
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.GET("/events", fakeAuthenticator, handler.Poll)
+	})
+
+	JustBeforeEach(func() {
+		req = httptest.NewRequest("GET", "/events"+query, nil)
+		req.Header.Add(customerIDHeader, customerID)
+		router.ServeHTTP(recorder, req)
+	})
+
+	response := func() eventsResponse {
+		resp := eventsResponse{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &resp)).To(Succeed())
+		return resp
+	}
+
+	Context("with events", func() {
+		BeforeEach(func() {
+			eventLog.Append(events.NewEvent(events.TypeCreated, customerID, postID, "", nil))
+			eventLog.Append(events.NewEvent(events.TypeCreated, "other-customer", postID, "", nil))
+			eventLog.Append(events.NewEvent(events.TypeDeleted, customerID, postID, "", nil))
+			query = "?after=1"
+		})
+
+		It("should return the customer's events after the offset", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			resp := response()
+			Expect(resp.Events).To(HaveLen(1))
+			Expect(resp.Events[0].Type).To(Equal(events.TypeDeleted))
+			Expect(resp.Events[0].Offset).To(Equal(int64(2)))
+			Expect(resp.NextOffset).To(Equal(int64(2)))
+		})
+	})
+
+	Context("without new events", func() {
+		BeforeEach(func() {
+			eventLog.Append(events.NewEvent(events.TypeCreated, customerID, postID, "", nil))
+			query = "?after=1"
+		})
+
+		It("should wait and return no events with the same offset", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			resp := response()
+			Expect(resp.Events).To(BeEmpty())
+			Expect(resp.NextOffset).To(Equal(int64(1)))
+		})
+	})
+
+	Context("with an event while waiting", func() {
+		BeforeEach(func() {
+			handler.maxWait = 5 * time.Second
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				eventLog.Append(events.NewEvent(events.TypeCreated, customerID, postID, "", nil))
+			}()
+		})
+
+		It("should return the event", func() {
+			resp := response()
+			Expect(resp.Events).To(HaveLen(1))
+			Expect(resp.NextOffset).To(Equal(int64(1)))
+		})
+	})
+
+	Context("with wait=0", func() {
+		BeforeEach(func() {
+			handler.maxWait = time.Hour
+			query = "?wait=0"
+		})
+
+		It("should return without waiting", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(response().Events).To(BeEmpty())
+		})
+	})
+
+	Context("with invalid parameters", func() {
+		It("should return StatusBadRequest", func() {
+			for param, detail := range map[string]string{
+				"?after=-1":    "invalid after",
+				"?after=first": "invalid after",
+				"?limit=0":     "invalid limit",
+				"?limit=5000":  "invalid limit",
+				"?wait=soon":   "invalid wait",
+			} {
+				recorder = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", "/events"+param, nil)
+				req.Header.Add(customerIDHeader, customerID)
+				router.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest), param)
+				expectProblem(recorder, datastore.CodeInvalidArgument, detail)
+			}
+		})
+	})
+})
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// Formats posts are exported and imported in
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+
+	jsonlContentType = "application/x-ndjson"
+	// exportPageSize is how many posts are read from the datastore at a time
+	exportPageSize = 100
+)
+
+// csvHeader are the columns of an exported csv. Every caption has its own cell,
+// starting at the captions column
+var csvHeader = []string{"id", "url", "canonical_url", "status", "captions"}
+
+// postWriter writes posts in an export format
+type postWriter interface {
+	Write(*dao.Post) error
+	Flush() error
+}
+
+// Export defines the handler for streaming every post of the customer as csv or
+// json lines
+func (p *DefaultPoster) Export(c *gin.Context) {
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	format := c.DefaultQuery("format", formatCSV)
+	contentType := exportContentType(format)
+	if contentType == "" {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid format", nil)
+		return
+	}
+
+	posts, err := p.ds.List(customerID, dao.ListOptions{Limit: exportPageSize})
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="posts.`+format+`"`)
+	c.Status(http.StatusOK)
+	w, err := newPostWriter(format, c.Writer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// The response has started, so errors can only be logged and end the stream
+	for len(posts) > 0 {
+		for _, post := range posts {
+			if err := w.Write(post); err != nil {
+				c.Error(err)
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			c.Error(err)
+			return
+		}
+		c.Writer.Flush()
+
+		if len(posts) < exportPageSize {
+			return
+		}
+		posts, err = p.ds.List(customerID, dao.ListOptions{After: *posts[len(posts)-1].ID, Limit: exportPageSize})
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+}
+
+func exportContentType(format string) string {
+	switch format {
+	case formatCSV:
+		return "text/csv"
+	case formatJSONL:
+		return jsonlContentType
+	default:
+		return ""
+	}
+}
+
+func newPostWriter(format string, w io.Writer) (postWriter, error) {
+	if format == formatJSONL {
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return &jsonlPostWriter{encoder}, nil
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvPostWriter{cw}, nil
+}
+
+type jsonlPostWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlPostWriter) Write(post *dao.Post) error {
+	return w.encoder.Encode(post)
+}
+
+func (w *jsonlPostWriter) Flush() error {
+	return nil
+}
+
+type csvPostWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvPostWriter) Write(post *dao.Post) error {
+	record := []string{post.ID.Hex(), escapeCell(post.URL), escapeCell(post.CanonicalURL), post.Status}
+	for _, caption := range post.Captions {
+		record = append(record, escapeCell(caption.Text))
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvPostWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// escapeCell keeps spreadsheets from running a cell as a formula by quoting it
+// with a leading '. unescapeCell removes the quote on import
+func escapeCell(cell string) string {
+	if cell != "" && strings.ContainsAny(cell[:1], "=+-@\t\r") {
+		return "'" + cell
+	}
+	return cell
+}
+
+func unescapeCell(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsAny(cell[1:2], "=+-@\t\r") {
+		return cell[1:]
+	}
+	return cell
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/validate"
+)
+
+var _ = Describe("Export", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockPoster *mock_dao.MockPoster
+		router     *gin.Engine
+		customerID string
+		recorder   *httptest.ResponseRecorder
+		url        string
+		post       *dao.Post
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPoster = mock_dao.NewMockPoster(mockCtrl)
+		router = setupRouter(NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules())))
+		customerID = "test-customer"
+		recorder = httptest.NewRecorder()
+		url = "/export"
+
+		postID := bson.ObjectIdHex("5e154899cb80cb0001000003")
+		post = &dao.Post{
+			ID:           &postID,
+			URL:          "https://example.com/post",
+			CanonicalURL: "https://example.com/post",
+			Captions:     dao.ManualCaptions([]string{"caption1", "=SUM(A1:A2)"}),
+			Status:       dao.StatusDraft,
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).To(BeNil())
+		req.Header.Add(customerIDHeader, customerID)
+		router.ServeHTTP(recorder, req)
+	})
+
+	Context("with invalid format", func() {
+		BeforeEach(func() {
+			url = "/export?format=xml"
+		})
+
+		It("should return StatusBadRequest", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			expectProblem(recorder, datastore.CodeInvalidArgument, "invalid format")
+		})
+	})
+
+	Context("with datastore error", func() {
+		BeforeEach(func() {
+			mockPoster.EXPECT().List(customerID, dao.ListOptions{Limit: exportPageSize}).Return(nil, errors.New("test-error"))
+		})
+
+		It("should return StatusInternalServerError", func() {
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Context("with csv", func() {
+		BeforeEach(func() {
+			mockPoster.EXPECT().List(customerID, dao.ListOptions{Limit: exportPageSize}).Return([]*dao.Post{post}, nil)
+		})
+
+		It("should write a row for every post", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("text/csv"))
+			Expect(recorder.Header().Get("Content-Disposition")).To(Equal(`attachment; filename="posts.csv"`))
+			Expect(recorder.Body.String()).To(Equal("id,url,canonical_url,status,captions\n" +
+				"5e154899cb80cb0001000003,https://example.com/post,https://example.com/post,draft,caption1,'=SUM(A1:A2)\n"))
+		})
+	})
+
+	Context("with json lines", func() {
+		BeforeEach(func() {
+			url = "/export?format=jsonl"
+			mockPoster.EXPECT().List(customerID, dao.ListOptions{Limit: exportPageSize}).Return([]*dao.Post{post}, nil)
+		})
+
+		It("should write a line for every post", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal(jsonlContentType))
+			Expect(recorder.Body.String()).To(Equal(`{"id":"5e154899cb80cb0001000003","url":"https://example.com/post","canonical_url":"https://example.com/post","captions":[{"id":"","text":"caption1","source":"manual","selected":false,"pinned":false},{"id":"","text":"=SUM(A1:A2)","source":"manual","selected":false,"pinned":false}],"status":"draft"}` + "\n"))
+		})
+	})
+
+	Context("with more than a page of posts", func() {
+		BeforeEach(func() {
+			url = "/export?format=jsonl"
+			page := make([]*dao.Post, exportPageSize)
+			for i := range page {
+				id := bson.NewObjectId()
+				page[i] = &dao.Post{ID: &id, URL: fmt.Sprintf("https://example.com/%d", i)}
+			}
+			gomock.InOrder(
+				mockPoster.EXPECT().List(customerID, dao.ListOptions{Limit: exportPageSize}).Return(page, nil),
+				mockPoster.EXPECT().List(customerID, dao.ListOptions{After: *page[exportPageSize-1].ID, Limit: exportPageSize}).Return([]*dao.Post{post}, nil),
+			)
+		})
+
+		It("should read the next page after the last post", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(strings.Count(recorder.Body.String(), "\n")).To(Equal(exportPageSize + 1))
+		})
+	})
+})
@@ -1,14 +1,24 @@
 package handler
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/templates"
 )
 
+// customerIDHeader is only used by the tests to tell fakeAuthenticator who the caller is
+const customerIDHeader = "x-customer-id"
+
 func TestHandler(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Handler Suite")
@@ -17,8 +27,60 @@ func TestHandler(t *testing.T) {
 func setupRouter(p Poster) *gin.Engine {
 	gin.DefaultWriter = ioutil.Discard
 	r := gin.Default()
+	r.Use(fakeAuthenticator, NewVersion(V2Post, V2Revision))
+	r.GET("/posts", p.List)
 	r.GET("/post/:id", p.Get)
 	r.POST("/post", p.Post)
 	r.PUT("/post/:id", p.Put)
+	r.PATCH("/post/:id", p.Patch)
+	r.POST("/post/:id/approve", p.Approve)
+	r.GET("/post/:id/revisions", p.Revisions)
+	r.POST("/post/:id/revisions/:rev/restore", p.Restore)
+	r.DELETE("/post/:id", p.Delete)
+	r.PUT("/post/:id/schedule", p.Schedule)
+	r.DELETE("/post/:id/schedule", p.Unschedule)
+	r.POST("/post/:id/captions/:caption_id/select", p.SelectCaption)
+	r.POST("/post/:id/captions/:caption_id/pin", p.PinCaption)
+	r.POST("/post/:id/captions/:caption_id/unpin", p.UnpinCaption)
+	r.DELETE("/post/:id/captions/:caption_id", p.DiscardCaption)
+	r.PUT("/post/:id/captions/order", p.ReorderCaptions)
+	r.POST("/posts:action", Actions(map[string]gin.HandlerFunc{"batch": p.BatchCreate}))
+	r.PUT("/posts:action", Actions(map[string]gin.HandlerFunc{"batch": p.BatchUpdate}))
+	r.GET("/export", p.Export)
+	r.POST("/import", p.Import)
 	return r
 }
+
+// newQualityStore returns a quality.InMemoryStore that does not log
+func newQualityStore() *quality.InMemoryStore {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	return quality.NewInMemoryStore(logger)
+}
+
+// newTemplateStore returns a templates.InMemoryStore that does not log
+func newTemplateStore() *templates.InMemoryStore {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	return templates.NewInMemoryStore(logger)
+}
+
+// fakeAuthenticator trusts the customerIDHeader, so the handlers can be tested
+// without going through a KeyStore
+func fakeAuthenticator(c *gin.Context) {
+	if customerID := c.Request.Header.Get(customerIDHeader); customerID != "" {
+		c.Set(identityKey, &auth.Identity{CustomerID: customerID, Scopes: auth.AllScopes})
+	}
+	c.Next()
+}
+
+// expectProblem checks the response is a problem with the code and detail
+func expectProblem(recorder *httptest.ResponseRecorder, code, detail string) {
+	ExpectWithOffset(1, recorder.Header().Get("Content-Type")).To(HavePrefix(problemContentType))
+	problem := &Problem{}
+	ExpectWithOffset(1, json.Unmarshal(recorder.Body.Bytes(), problem)).To(Succeed())
+	ExpectWithOffset(1, problem.Status).To(Equal(recorder.Code))
+	ExpectWithOffset(1, problem.Title).NotTo(BeEmpty())
+	ExpectWithOffset(1, problem.Code).To(Equal(code))
+	ExpectWithOffset(1, problem.Detail).To(Equal(detail))
+}
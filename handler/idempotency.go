@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/idempotency"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+	maxIdempotencyKeyLen = 255
+)
+
+// bodyRecorder captures the response body while still writing it to the client
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *bodyRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// NewIdempotency returns middleware that replays the stored response when a
+// request is retried with the same Idempotency-Key. A retry with a different body
+// is rejected, and a retry that arrives while the first request is still running
+// waits up to wait for it before being rejected. It must run after authentication,
+// since keys are scoped to the customer
+func NewIdempotency(store idempotency.Store, wait time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Request.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			abortWithProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid idempotency key", nil)
+			return
+		}
+
+		customerID := getCustomerID(c)
+		if customerID == "" {
+			c.Abort()
+			return
+		}
+
+		fingerprint, err := requestFingerprint(c.Request)
+		if err != nil {
+			abortWithProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid request", nil)
+			return
+		}
+
+		record, started, err := store.Begin(customerID, key, fingerprint)
+		if err != nil {
+			setReturnError(err, c)
+			c.Abort()
+			return
+		}
+		if !started {
+			replayIdempotent(c, store, record, fingerprint, wait)
+			return
+		}
+
+		// Only successful responses are stored, any other releases the key so
+		// the request can be retried, e.g. once a quota resets
+		recorder := &bodyRecorder{c.Writer, &bytes.Buffer{}}
+		c.Writer = recorder
+		completed := false
+		defer func() {
+			if !completed {
+				store.Release(customerID, key)
+			}
+		}()
+
+		c.Next()
+
+		if recorder.Status() >= http.StatusOK && recorder.Status() < http.StatusMultipleChoices {
+			store.Complete(customerID, key, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+			completed = true
+		}
+	}
+}
+
+func replayIdempotent(c *gin.Context, store idempotency.Store, record *idempotency.Record, fingerprint string, wait time.Duration) {
+	if record.Fingerprint != fingerprint {
+		abortWithProblem(c, http.StatusUnprocessableEntity, codeIdempotencyKeyReused, "idempotency key was used with a different request", nil)
+		return
+	}
+
+	if !record.Done {
+		var err error
+		record, err = store.Wait(record.CustomerID, record.Key, wait)
+		if err != nil {
+			setReturnError(err, c)
+			c.Abort()
+			return
+		}
+		if record == nil || !record.Done {
+			abortWithProblem(c, http.StatusConflict, datastore.CodeConflict, "a request with this idempotency key is in progress", nil)
+			return
+		}
+	}
+
+	c.Header(replayedHeader, "true")
+	c.Data(record.StatusCode, record.ContentType, record.Body)
+	c.Abort()
+}
+
+// requestFingerprint hashes everything that identifies the request, the body is
+// restored so the handler can still read it
+func requestFingerprint(r *http.Request) (string, error) {
+	body := []byte{}
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/caption"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/idempotency"
+	mock_caption "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/caption"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	mock_ratelimit "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/validate"
+)
+
+var _ = Describe("Idempotency", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockPoster *mock_dao.MockPoster
+		store      *idempotency.InMemoryStore
+		router     *gin.Engine
+		customerID string
+		postID     bson.ObjectId
+		dsPost     *dao.Post
+	)
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/post", bytes.NewBufferString(body))
+		req.Header.Add(customerIDHeader, customerID)
+		req.Header.Add("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Add(idempotencyKeyHeader, key)
+		}
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	newRouter := func(p Poster) *gin.Engine {
+		gin.DefaultWriter = ioutil.Discard
+		r := gin.New()
+		r.Use(fakeAuthenticator)
+		r.POST("/post", NewIdempotency(store, 50*time.Millisecond), p.Post)
+		return r
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPoster = mock_dao.NewMockPoster(mockCtrl)
+		store = idempotency.NewInMemoryStore(time.Hour)
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+		dsPost = &dao.Post{
+			ID:       &postID,
+			CustID:   customerID,
+			URL:      "https://example.com/post",
+			Captions: dao.ManualCaptions([]string{"caption1"}),
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("with DefaultPoster", func() {
+		BeforeEach(func() {
+			router = newRouter(NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules())))
+		})
+
+		Context("without idempotency key", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Insert(customerID, gomock.Any()).Return(dsPost, nil).Times(2)
+			})
+
+			It("should insert every request", func() {
+				body := `{"url":"https://example.com/post","captions":["caption1"]}`
+				Expect(send("", body).Code).To(Equal(http.StatusOK))
+				Expect(send("", body).Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("with a retried request", func() {
+			var first, second *httptest.ResponseRecorder
+			BeforeEach(func() {
+				mockPoster.EXPECT().Insert(customerID, gomock.Any()).Return(dsPost, nil).Times(1)
+				body := `{"url":"https://example.com/post","captions":["caption1"]}`
+				first = send("key-1", body)
+				second = send("key-1", body)
+			})
+
+			It("should replay the first response", func() {
+				Expect(second.Code).To(Equal(first.Code))
+				Expect(second.Body.String()).To(Equal(first.Body.String()))
+				Expect(second.Header().Get("Content-Type")).To(Equal(first.Header().Get("Content-Type")))
+			})
+
+			It("should mark the response as replayed", func() {
+				Expect(first.Header().Get(replayedHeader)).To(BeEmpty())
+				Expect(second.Header().Get(replayedHeader)).To(Equal("true"))
+			})
+		})
+
+		Context("with a retried request and a different body", func() {
+			var second *httptest.ResponseRecorder
+			BeforeEach(func() {
+				mockPoster.EXPECT().Insert(customerID, gomock.Any()).Return(dsPost, nil).Times(1)
+				send("key-1", `{"url":"https://example.com/post"}`)
+				second = send("key-1", `{"url":"https://example.com/other"}`)
+			})
+
+			It("should return StatusUnprocessableEntity", func() {
+				Expect(second.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+
+			It("should return a useful message", func() {
+				expectProblem(second, codeIdempotencyKeyReused, "idempotency key was used with a different request")
+			})
+		})
+
+		Context("with the first request still in progress", func() {
+			var second *httptest.ResponseRecorder
+			BeforeEach(func() {
+				body := `{"url":"https://example.com/post"}`
+				req := httptest.NewRequest("POST", "/post", bytes.NewBufferString(body))
+				fingerprint, err := requestFingerprint(req)
+				Expect(err).To(BeNil())
+				_, _, err = store.Begin(customerID, "key-1", fingerprint)
+				Expect(err).To(BeNil())
+				second = send("key-1", body)
+			})
+
+			It("should return StatusConflict", func() {
+				Expect(second.Code).To(Equal(http.StatusConflict))
+			})
+		})
+
+		for _, tc := range []struct {
+			name   string
+			err    error
+			status int
+		}{
+			{"a server error", errors.New("test-error"), http.StatusInternalServerError},
+			{"an exceeded quota", datastore.NewQuotaExceededError("monthly quota", time.Now().Add(time.Hour)), http.StatusTooManyRequests},
+			{"a conflict", datastore.NewConflictError("post"), http.StatusConflict},
+		} {
+			tc := tc
+			Context("with "+tc.name, func() {
+				BeforeEach(func() {
+					gomock.InOrder(
+						mockPoster.EXPECT().Insert(customerID, gomock.Any()).Return(nil, tc.err),
+						mockPoster.EXPECT().Insert(customerID, gomock.Any()).Return(dsPost, nil),
+					)
+				})
+
+				It("should allow the request to be retried", func() {
+					body := `{"url":"https://example.com/post"}`
+					Expect(send("key-1", body).Code).To(Equal(tc.status))
+					Expect(send("key-1", body).Code).To(Equal(http.StatusOK))
+				})
+			})
+		}
+	})
+
+	Context("with CaptionGeneratorPoster", func() {
+		var (
+			mockGenerator *mock_caption.MockGenerator
+			mockQuota     *mock_ratelimit.MockQuota
+		)
+
+		BeforeEach(func() {
+			mockGenerator = mock_caption.NewMockGenerator(mockCtrl)
+			mockQuota = mock_ratelimit.NewMockQuota(mockCtrl)
+			base := NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules()))
+			router = newRouter(NewCaptionGeneratorPoster(base, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(newQualityStore(), &quality.Config{}), newTemplateStore(), 3, mockQuota, validate.NewValidator(validate.DefaultRules())))
+
+			mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil).Times(1)
+			mockGenerator.EXPECT().Create("https://example.com/post", 3).Return([]string{"caption1"}, nil).Times(1)
+			mockPoster.EXPECT().Insert(customerID, gomock.Any()).Return(dsPost, nil).Times(1)
+		})
+
+		It("should only generate captions once", func() {
+			body := `{"url":"https://example.com/post"}`
+			first := send("key-1", body)
+			second := send("key-1", body)
+			Expect(first.Code).To(Equal(http.StatusOK))
+			Expect(second.Body.String()).To(Equal(first.Body.String()))
+		})
+	})
+})
@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/validate"
+)
+
+const (
+	// generateParam asks POST /import to generate captions for rows without any
+	generateParam = "generate"
+	// maxImportErrors is the most row errors an import reports, the rest are
+	// only counted
+	maxImportErrors = 1000
+	// maxImportLine is the longest json line an import reads
+	maxImportLine = 1 << 20
+	// maxImportBody is the largest import body that is read
+	maxImportBody = 32 << 20
+)
+
+// importRow is a row of an import. Err is set when the row could not be parsed
+type importRow struct {
+	Line int
+	Post postRequest
+	Err  error
+}
+
+// rowReader reads an import one row at a time, it returns io.EOF after the last
+// row. Any other error stops the import
+type rowReader interface {
+	Read() (*importRow, error)
+}
+
+type importError struct {
+	Line  int      `json:"line"`
+	Error *Problem `json:"error"`
+}
+
+// importResponse counts the rows of an import. Error is set when the body could
+// not be read to the end, the counts are then of the rows before it
+type importResponse struct {
+	Created int            `json:"created"`
+	Failed  int            `json:"failed"`
+	Errors  []*importError `json:"errors"`
+	Error   *Problem       `json:"error,omitempty"`
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// Import defines the handler for creating posts from a csv or json lines body
+func (p *DefaultPoster) Import(c *gin.Context) {
+	importPosts(c, p.ds, p.validator, nil)
+}
+
+// importPosts reads the rows of the body and creates their posts maxBatchSize at
+// a time. Rows that fail are reported by line and do not stop the import, a body
+// that cannot be read to the end does, after the rows before it are created.
+// Captions are only generated when the request asks for it
+func importPosts(c *gin.Context, ds dao.Poster, validator *validate.Validator, gen captioner) {
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	if c.Query(generateParam) != "true" {
+		gen = nil
+	}
+
+	// A body that says it is too large is turned away before any post is
+	// created, one that does not stops the import once the limit is read
+	if c.Request.ContentLength > maxImportBody {
+		setProblem(c, http.StatusRequestEntityTooLarge, datastore.CodeInvalidArgument, fmt.Sprintf("body must be at most %d bytes", maxImportBody), nil)
+		return
+	}
+	body := &countingReader{r: http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBody)}
+
+	rows, err := newRowReader(c.DefaultQuery("format", formatCSV), body)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+
+	resp := &importResponse{Errors: []*importError{}}
+	fail := func(line int, problem *Problem) {
+		resp.Failed++
+		if len(resp.Errors) < maxImportErrors {
+			resp.Errors = append(resp.Errors, &importError{Line: line, Error: problem})
+		}
+	}
+
+	items := []postRequest{}
+	lines := []int{}
+	create := func() {
+		if len(items) == 0 {
+			return
+		}
+		results, err := createPosts(c, ds, validator, gen, customerID, items, false)
+		for i, line := range lines {
+			switch {
+			case err != nil:
+				fail(line, errorProblem(err, c))
+			case results[i].Error != nil:
+				fail(line, results[i].Error)
+			default:
+				resp.Created++
+			}
+		}
+		items, lines = nil, nil
+	}
+
+	for {
+		row, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Earlier batches are already created, so the rows read so far are
+			// too and the response counts them with the error
+			create()
+			if body.n >= maxImportBody {
+				resp.Error = newProblem(c, http.StatusRequestEntityTooLarge, datastore.CodeInvalidArgument, fmt.Sprintf("body must be at most %d bytes", maxImportBody), nil)
+			} else {
+				resp.Error = errorProblem(datastore.NewInvalidArugmentError("body: "+err.Error()), c)
+			}
+			c.PureJSON(resp.Error.Status, resp)
+			return
+		}
+		if row.Err != nil {
+			fail(row.Line, errorProblem(row.Err, c))
+			continue
+		}
+
+		items = append(items, row.Post)
+		lines = append(lines, row.Line)
+		if len(items) == maxBatchSize {
+			create()
+		}
+	}
+	create()
+
+	c.PureJSON(http.StatusOK, resp)
+}
+
+func newRowReader(format string, r io.Reader) (rowReader, error) {
+	switch format {
+	case formatCSV:
+		return newCSVRowReader(r)
+	case formatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxImportLine)
+		return &jsonlRowReader{scanner: scanner}, nil
+	default:
+		return nil, datastore.NewInvalidArugmentError("format")
+	}
+}
+
+// csvRowReader reads the csv written by an export. Only the url and captions
+// columns are read and the captions column may be left out. Rows are numbered
+// from 2, after the header
+type csvRowReader struct {
+	r        *csv.Reader
+	line     int
+	url      int
+	captions int
+}
+
+func newCSVRowReader(r io.Reader) (*csvRowReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, datastore.NewInvalidArugmentError("csv header")
+	}
+
+	rows := &csvRowReader{r: cr, line: 1, url: -1, captions: -1}
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "url":
+			rows.url = i
+		case "captions":
+			rows.captions = i
+		}
+	}
+	if rows.url < 0 {
+		return nil, datastore.NewValidationError(datastore.FieldError{Field: "url", Message: "csv header must have a url column"})
+	}
+	return rows, nil
+}
+
+func (r *csvRowReader) Read() (*importRow, error) {
+	record, err := r.r.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	r.line++
+	if err != nil {
+		if _, ok := err.(*csv.ParseError); ok {
+			return &importRow{Line: r.line, Err: datastore.NewInvalidArugmentError("row: " + err.Error())}, nil
+		}
+		return nil, err
+	}
+
+	row := &importRow{Line: r.line}
+	if r.url < len(record) {
+		row.Post.URL = unescapeCell(record[r.url])
+	}
+	if r.captions >= 0 && r.captions < len(record) {
+		captions := record[r.captions:]
+		// Spreadsheets pad rows to the longest one
+		for len(captions) > 0 && captions[len(captions)-1] == "" {
+			captions = captions[:len(captions)-1]
+		}
+		for _, caption := range captions {
+			row.Post.Captions = append(row.Post.Captions, unescapeCell(caption))
+		}
+	}
+	return row, nil
+}
+
+// jsonlRow is a line of a json lines import. Captions are texts, or the caption
+// objects of an export of which only the text is kept
+type jsonlRow struct {
+	URL      string            `json:"url"`
+	Captions []json.RawMessage `json:"captions"`
+}
+
+// captionText returns the text of a caption that is a string or an object
+func captionText(raw json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	caption := &dao.Caption{}
+	if err := json.Unmarshal(raw, caption); err != nil {
+		return "", err
+	}
+	return caption.Text, nil
+}
+
+// jsonlRowReader reads a post request from every line, blank lines are skipped
+type jsonlRowReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlRowReader) Read() (*importRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		row := &importRow{Line: r.line}
+		decoded := &jsonlRow{}
+		if err := json.Unmarshal([]byte(line), decoded); err != nil {
+			row.Err = datastore.NewInvalidArugmentError("json: " + err.Error())
+			return row, nil
+		}
+		row.Post.URL = decoded.URL
+		for _, raw := range decoded.Captions {
+			text, err := captionText(raw)
+			if err != nil {
+				row.Err = datastore.NewInvalidArugmentError("json: " + err.Error())
+				break
+			}
+			row.Post.Captions = append(row.Post.Captions, text)
+		}
+		return row, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/caption"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	mock_caption "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/caption"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	mock_ratelimit "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/validate"
+)
+
+var _ = Describe("Import", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockPoster *mock_dao.MockPoster
+		router     *gin.Engine
+		customerID string
+		recorder   *httptest.ResponseRecorder
+		url        string
+		body       string
+		chunked    bool
+		response   *importResponse
+	)
+
+	// inserted returns every post it is given
+	inserted := func(_ string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+		results := []*dao.BatchResult{}
+		for _, post := range posts {
+			results = append(results, &dao.BatchResult{Post: post})
+		}
+		return results, nil
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPoster = mock_dao.NewMockPoster(mockCtrl)
+		router = setupRouter(NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules())))
+		customerID = "test-customer"
+		recorder = httptest.NewRecorder()
+		url = "/import"
+		chunked = false
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("POST", url, strings.NewReader(body))
+		Expect(err).To(BeNil())
+		req.Header.Add(customerIDHeader, customerID)
+		if chunked {
+			req.ContentLength = -1
+		}
+		router.ServeHTTP(recorder, req)
+
+		response = &importResponse{}
+		if recorder.Header().Get("Content-Type") != problemContentType {
+			Expect(json.Unmarshal(recorder.Body.Bytes(), response)).To(Succeed())
+		}
+	})
+
+	Context("with invalid format", func() {
+		BeforeEach(func() {
+			url = "/import?format=xml"
+		})
+
+		It("should return StatusBadRequest", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			expectProblem(recorder, datastore.CodeInvalidArgument, "invalid format")
+		})
+	})
+
+	Context("with a body larger than an import can be", func() {
+		BeforeEach(func() {
+			body = "url\n" + strings.Repeat("a", maxImportBody)
+		})
+
+		It("should return StatusRequestEntityTooLarge", func() {
+			Expect(recorder.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			expectProblem(recorder, datastore.CodeInvalidArgument, "body must be at most 33554432 bytes")
+		})
+
+		Context("without a content length", func() {
+			BeforeEach(func() {
+				chunked = true
+			})
+
+			It("should stop reading at the limit", func() {
+				Expect(recorder.Code).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(response.Created).To(Equal(0))
+				Expect(response.Error.Code).To(Equal(datastore.CodeInvalidArgument))
+				Expect(response.Error.Detail).To(Equal("body must be at most 33554432 bytes"))
+			})
+		})
+
+		Context("that goes over the limit after the first batch", func() {
+			BeforeEach(func() {
+				url = "/import?format=jsonl"
+				chunked = true
+				lines := []string{}
+				for i := 0; i < maxBatchSize+50; i++ {
+					lines = append(lines, fmt.Sprintf(`{"url":"https://example.com/%d","captions":["caption"]}`, i))
+				}
+				// blank lines are skipped, so only the limit stops the import
+				blank := strings.Repeat(" ", 1023) + "\n"
+				body = strings.Join(lines, "\n") + "\n" + strings.Repeat(blank, maxImportBody/len(blank)+1) + `{"url":"https://example.com/last"}`
+				batches := []int{}
+				mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(func(customerID string, posts []*dao.Post) ([]*dao.BatchResult, error) {
+					batches = append(batches, len(posts))
+					Expect(batches).To(Equal([]int{maxBatchSize, 50}[:len(batches)]))
+					return inserted(customerID, posts)
+				}).Times(2)
+			})
+
+			It("should create the rows read before the limit and count them", func() {
+				Expect(recorder.Code).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(response.Created).To(Equal(maxBatchSize + 50))
+				Expect(response.Failed).To(Equal(0))
+				Expect(response.Error.Status).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(response.Error.Detail).To(Equal("body must be at most 33554432 bytes"))
+			})
+		})
+	})
+
+	Context("with csv", func() {
+		Context("without a url column", func() {
+			BeforeEach(func() {
+				body = "id,captions\n"
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: url: csv header must have a url column")
+			})
+		})
+
+		Context("with an export", func() {
+			BeforeEach(func() {
+				body = "id,url,canonical_url,status,captions\n" +
+					"5e154899cb80cb0001000003,https://example.com/a,https://example.com/a,draft,caption1,'=SUM(A1:A2),,\n" +
+					"5e154899cb80cb0001000004,ftp://example.com/b,,draft,caption1\n" +
+					"5e154899cb80cb0001000005,\"https://example.com/c,,draft\n"
+				expected := []*dao.Post{{
+					URL:       "https://example.com/a",
+					Captions:  dao.ManualCaptions([]string{"caption1", "=SUM(A1:A2)"}),
+					UpdatedBy: customerID,
+				}}
+				mockPoster.EXPECT().InsertBatch(customerID, expected).DoAndReturn(inserted)
+			})
+
+			It("should create the valid rows and report the others by line", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(response.Created).To(Equal(1))
+				Expect(response.Failed).To(Equal(2))
+				Expect(response.Errors[0].Line).To(Equal(4))
+				Expect(response.Errors[0].Error.Code).To(Equal(datastore.CodeInvalidArgument))
+				Expect(response.Errors[1].Line).To(Equal(3))
+				Expect(response.Errors[1].Error.Detail).To(Equal("validation failed: url: must be http or https"))
+			})
+		})
+	})
+
+	Context("with json lines", func() {
+		BeforeEach(func() {
+			url = "/import?format=jsonl"
+		})
+
+		Context("with more rows than a batch", func() {
+			BeforeEach(func() {
+				lines := []string{}
+				for i := 0; i < maxBatchSize+1; i++ {
+					lines = append(lines, fmt.Sprintf(`{"url":"https://example.com/%d","captions":["caption"]}`, i))
+				}
+				lines = append(lines, "", "{not json")
+				body = strings.Join(lines, "\n")
+				gomock.InOrder(
+					mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).DoAndReturn(inserted),
+					mockPoster.EXPECT().InsertBatch(customerID, gomock.Any()).Return(nil, errors.New("test-error")),
+				)
+			})
+
+			It("should create the posts a batch at a time", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(response.Created).To(Equal(maxBatchSize))
+				Expect(response.Failed).To(Equal(2))
+				Expect(response.Errors[0].Line).To(Equal(maxBatchSize + 3))
+				Expect(response.Errors[0].Error.Code).To(Equal(datastore.CodeInvalidArgument))
+				Expect(response.Errors[1].Line).To(Equal(maxBatchSize + 1))
+				Expect(response.Errors[1].Error.Status).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("with generate", func() {
+			var (
+				mockGenerator *mock_caption.MockGenerator
+				mockQuota     *mock_ratelimit.MockQuota
+			)
+
+			BeforeEach(func() {
+				url = "/import?format=jsonl&generate=true"
+				mockGenerator = mock_caption.NewMockGenerator(mockCtrl)
+				mockQuota = mock_ratelimit.NewMockQuota(mockCtrl)
+				validator := validate.NewValidator(validate.DefaultRules())
+				base := NewDefaultPoster(mockPoster, validator)
+				router = setupRouter(NewCaptionGeneratorPoster(base, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(newQualityStore(), &quality.Config{}), newTemplateStore(), 3, mockQuota, validator))
+				body = `{"url":"https://example.com/a"}` + "\n" + `{"url":"https://example.com/b","captions":[{"id":"c1","text":"mine","source":"manual"}]}`
+
+				mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+				mockGenerator.EXPECT().Create("https://example.com/a", 3).Return([]string{"generated"}, nil)
+				expected := []*dao.Post{
+					{URL: "https://example.com/a", Captions: caption.Rank(caption.DefaultScorer(), "https://example.com/a", []string{"generated"}), UpdatedBy: customerID},
+					{URL: "https://example.com/b", Captions: dao.ManualCaptions([]string{"mine"}), UpdatedBy: customerID},
+				}
+				mockPoster.EXPECT().InsertBatch(customerID, expected).DoAndReturn(inserted)
+			})
+
+			It("should generate captions for the rows without any", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(response.Created).To(Equal(2))
+				Expect(response.Errors).To(BeEmpty())
+			})
+		})
+	})
+})
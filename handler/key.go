@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/auth"
+)
+
+// keyResponse is returned whenever a new key is generated. This is the only time
+// the secret is returned to the caller
+type keyResponse struct {
+	*auth.Key
+	Secret string `json:"secret"`
+}
+
+// Keyer defines the interface to handle api key requests
+type Keyer interface {
+	Create(*gin.Context)
+	List(*gin.Context)
+	Rotate(*gin.Context)
+	Revoke(*gin.Context)
+	CreateForCustomer(*gin.Context)
+}
+
+// DefaultKeyer implements the Keyer interface
+type DefaultKeyer struct {
+	keys auth.KeyStore
+}
+
+// NewDefaultKeyer returns a DefaultKeyer with the provided options
+func NewDefaultKeyer(keys auth.KeyStore) *DefaultKeyer {
+	return &DefaultKeyer{
+		keys: keys,
+	}
+}
+
+// Create defines the handler for creating a new key for the authenticated customer
+func (k *DefaultKeyer) Create(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	k.create(c, customerID)
+}
+
+// List defines the handler for listing the authenticated customer's keys
+func (k *DefaultKeyer) List(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	keys, err := k.keys.List(customerID)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, keys)
+	return
+}
+
+// Rotate defines the handler for revoking a key and issuing its replacement
+func (k *DefaultKeyer) Rotate(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	key, secret, err := k.keys.Rotate(customerID, c.Param("id"))
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, &keyResponse{key, secret})
+	return
+}
+
+// Revoke defines the handler for revoking a key
+func (k *DefaultKeyer) Revoke(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	if err := k.keys.Revoke(customerID, c.Param("id")); err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	return
+}
+
+// CreateForCustomer defines the admin handler for creating a key for any customer.
+// This is how the first key for a customer is issued
+func (k *DefaultKeyer) CreateForCustomer(c *gin.Context) {
+	k.create(c, c.Param("customer_id"))
+}
+
+func (k *DefaultKeyer) create(c *gin.Context, customerID string) {
+	key, secret, err := k.keys.Create(customerID)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusCreated, &keyResponse{key, secret})
+	return
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/datastore"
+	mock_auth "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/auth"
+)
+
+var _ = Describe("DefaultKeyer", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockKeys   *mock_auth.MockKeyStore
+		handler    *DefaultKeyer
+		router     *gin.Engine
+		customerID string
+		recorder   *httptest.ResponseRecorder
+		req        *http.Request
+		key        *auth.Key
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKeys = mock_auth.NewMockKeyStore(mockCtrl)
+		handler = NewDefaultKeyer(mockKeys)
+		customerID = "test-customer"
+		recorder = httptest.NewRecorder()
+		key = &auth.Key{ID: "key-id", CustID: customerID, Prefix: "abcd1234"}
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.Use(fakeAuthenticator)
+		router.GET("/keys", handler.List)
+		router.POST("/keys", handler.Create)
+		router.POST("/keys/:id/rotate", handler.Rotate)
+		router.DELETE("/keys/:id", handler.Revoke)
+		router.POST("/admin/customers/:customer_id/keys", handler.CreateForCustomer)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		router.ServeHTTP(recorder, req)
+	})
+
+	Describe("Create", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("POST", "/keys", nil)
+		})
+
+		Context("without identity", func() {
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("with identity", func() {
+			BeforeEach(func() {
+				req.Header.Add(customerIDHeader, customerID)
+				mockKeys.EXPECT().Create(customerID).Return(key, "cck_secret", nil)
+			})
+
+			It("should return StatusCreated", func() {
+				Expect(recorder.Code).To(Equal(http.StatusCreated))
+			})
+
+			It("should return the key and secret", func() {
+				Expect(recorder.Body.String()).To(ContainSubstring(`"id":"key-id"`))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"secret":"cck_secret"`))
+			})
+		})
+	})
+
+	Describe("Rotate", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("POST", "/keys/key-id/rotate", nil)
+			req.Header.Add(customerIDHeader, customerID)
+		})
+
+		Context("with unknown key or another customer's key", func() {
+			BeforeEach(func() {
+				mockKeys.EXPECT().Rotate(customerID, "key-id").Return(nil, "", datastore.NewNotFoundError("key"))
+			})
+
+			It("should return StatusNotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				expectProblem(recorder, datastore.CodeNotFound, "key not found")
+			})
+		})
+
+		Context("with customer's key", func() {
+			BeforeEach(func() {
+				mockKeys.EXPECT().Rotate(customerID, "key-id").Return(key, "cck_secret", nil)
+			})
+
+			It("should return StatusOK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"secret":"cck_secret"`))
+			})
+		})
+	})
+
+	Describe("Revoke", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("DELETE", "/keys/key-id", nil)
+			req.Header.Add(customerIDHeader, customerID)
+			mockKeys.EXPECT().Revoke(customerID, "key-id").Return(nil)
+		})
+
+		It("should return StatusNoContent", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNoContent))
+		})
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/keys", nil)
+			req.Header.Add(customerIDHeader, customerID)
+			mockKeys.EXPECT().List(customerID).Return([]*auth.Key{key}, nil)
+		})
+
+		It("should return the keys without hashes", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(ContainSubstring(`"prefix":"abcd1234"`))
+			Expect(recorder.Body.String()).NotTo(ContainSubstring("secret"))
+		})
+	})
+
+	Describe("CreateForCustomer", func() {
+		BeforeEach(func() {
+			url := fmt.Sprintf("/admin/customers/%s/keys", "new-customer")
+			req = httptest.NewRequest("POST", url, nil)
+			mockKeys.EXPECT().Create("new-customer").Return(key, "cck_secret", nil)
+		})
+
+		It("should create a key for the customer in the path", func() {
+			Expect(recorder.Code).To(Equal(http.StatusCreated))
+		})
+	})
+})
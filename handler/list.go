@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// cursorSeparator separates the sort time from the post id in the cursor of a
+// list sorted by a time
+const cursorSeparator = "_"
+
+// readListOptions reads the page, sort and filter of a list request. ok is false
+// if the response has been set with an error
+func readListOptions(c *gin.Context) (opts dao.ListOptions, ok bool) {
+	invalid := func(name string) (dao.ListOptions, bool) {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid "+name, nil)
+		return dao.ListOptions{}, false
+	}
+
+	limit, err := queryInt(c, "limit", defaultListLimit)
+	if err != nil || limit < 1 || limit > maxListLimit {
+		return invalid("limit")
+	}
+	opts.Limit = int(limit)
+
+	opts.Sort = c.Query("sort")
+	if strings.HasPrefix(opts.Sort, "-") {
+		opts.Sort, opts.Descending = opts.Sort[1:], true
+	}
+	if !dao.ValidSort(opts.Sort) || (opts.Descending && opts.Sort == "") {
+		return invalid("sort")
+	}
+
+	if after := c.Query("after"); after != "" {
+		if opts.After, opts.AfterTime, ok = parseCursor(opts.Sort, after); !ok {
+			return invalid("after")
+		}
+	}
+
+	times := []struct {
+		name  string
+		value *time.Time
+	}{
+		{"created_after", &opts.Filter.CreatedAfter},
+		{"created_before", &opts.Filter.CreatedBefore},
+		{"updated_after", &opts.Filter.UpdatedAfter},
+		{"updated_before", &opts.Filter.UpdatedBefore},
+	}
+	for _, t := range times {
+		v := c.Query(t.name)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return invalid(t.name)
+		}
+		*t.value = parsed.UTC()
+	}
+	opts.Filter.CreatedBy = c.Query("created_by")
+	opts.Filter.UpdatedBy = c.Query("updated_by")
+	return opts, true
+}
+
+// listCursor returns the after of the page that follows the post. It is the post
+// id, prefixed by its sort time when the list is sorted by a time
+func listCursor(opts dao.ListOptions, post *dao.Post) string {
+	if opts.Sort == "" {
+		return post.ID.Hex()
+	}
+	return opts.SortTime(post).UTC().Format(time.RFC3339Nano) + cursorSeparator + post.ID.Hex()
+}
+
+// parseCursor parses the after of a list with the sort, ok is false if it is not
+// a cursor listCursor returns for the sort
+func parseCursor(sort, cursor string) (id bson.ObjectId, at time.Time, ok bool) {
+	if sort != "" {
+		i := strings.LastIndex(cursor, cursorSeparator)
+		if i < 0 {
+			return "", time.Time{}, false
+		}
+		var err error
+		if at, err = time.Parse(time.RFC3339Nano, cursor[:i]); err != nil {
+			return "", time.Time{}, false
+		}
+		cursor = cursor[i+1:]
+	}
+	if !bson.IsObjectIdHex(cursor) {
+		return "", time.Time{}, false
+	}
+	return bson.ObjectIdHex(cursor), at, true
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/openapi"
+)
+
+// NewOpenAPIHandler returns the handler serving the document
+func NewOpenAPIHandler(doc *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", doc.JSON())
+	}
+}
+
+// NewOpenAPIValidator returns middleware that rejects requests that do not match
+// their operation in the document with a validation problem. Requests the
+// document does not describe are let through. When validateResponses is set,
+// responses that do not match are added to the context errors, so they are
+// logged, but still sent
+func NewOpenAPIValidator(doc *openapi.Document, validateResponses bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := doc.Find(c.Request.Method, c.Request.URL.Path)
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+		if err := route.ValidateRequest(c.Request, params); err != nil {
+			setReturnError(err, c)
+			c.Abort()
+			return
+		}
+		if !validateResponses {
+			c.Next()
+			return
+		}
+
+		recorder := &bodyRecorder{c.Writer, &bytes.Buffer{}}
+		c.Writer = recorder
+		c.Next()
+
+		if err := route.ValidateResponse(recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			c.Error(err)
+		}
+	}
+}
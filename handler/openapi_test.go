@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/openapi"
+)
+
+var _ = Describe("OpenAPI", func() {
+	var (
+		router            *gin.Engine
+		validateResponses bool
+		errs              []*gin.Error
+		getResponse       string
+		received          string
+	)
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	BeforeEach(func() {
+		validateResponses = true
+		errs = nil
+		received = ""
+		getResponse = `{"id": "5e154899cb80cb0001000003", "url": "https://example.com"}`
+	})
+
+	JustBeforeEach(func() {
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.GET("/openapi.json", NewOpenAPIHandler(openapi.Default()))
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			errs = c.Errors
+		})
+		router.Use(NewOpenAPIValidator(openapi.Default(), validateResponses))
+		router.GET("/post/:id", func(c *gin.Context) {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(getResponse))
+		})
+		router.POST("/post", func(c *gin.Context) {
+			body, _ := ioutil.ReadAll(c.Request.Body)
+			received = string(body)
+			c.Status(http.StatusNoContent)
+		})
+		router.GET("/unknown", func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+	})
+
+	It("should serve the document", func() {
+		recorder := send(http.MethodGet, "/openapi.json", "")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("application/json"))
+		Expect(recorder.Body.String()).To(MatchJSON(openapi.Spec))
+	})
+
+	It("should reject requests that do not match", func() {
+		recorder := send(http.MethodPost, "/post?dedupe=maybe", `{"captions": "caption1"}`)
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: dedupe: must be a boolean; url: is required; captions: must be an array")
+		Expect(received).To(BeEmpty())
+	})
+
+	It("should pass the body of matching requests on", func() {
+		send(http.MethodPost, "/post", `{"url": "https://example.com"}`)
+		Expect(received).To(Equal(`{"url": "https://example.com"}`))
+	})
+
+	It("should let requests the document does not describe through", func() {
+		recorder := send(http.MethodGet, "/unknown", "")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("should accept responses that match", func() {
+		recorder := send(http.MethodGet, "/post/5e154899cb80cb0001000003", "")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(errs).To(BeEmpty())
+	})
+
+	Context("when a response does not match", func() {
+		BeforeEach(func() {
+			getResponse = `{"id": "5e154899cb80cb0001000003", "url": 1}`
+		})
+
+		It("should record an error and still send it", func() {
+			recorder := send(http.MethodGet, "/post/5e154899cb80cb0001000003", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal(getResponse))
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Error()).To(ContainSubstring("GET /post/{id}: status 200: validation failed: url: must be a string"))
+		})
+
+		Context("when response validation is off", func() {
+			BeforeEach(func() {
+				validateResponses = false
+			})
+
+			It("should not check it", func() {
+				send(http.MethodGet, "/post/5e154899cb80cb0001000003", "")
+				Expect(errs).To(BeEmpty())
+			})
+		})
+	})
+
+	It("should record undocumented statuses", func() {
+		send(http.MethodPost, "/post", `{"url": "https://example.com"}`)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Error()).To(ContainSubstring("POST /post: status 204 is not documented"))
+	})
+})
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/patch"
+)
+
+// captionsField is the only member of a post a patch can change
+const captionsField = "captions"
+
+// postFields are the members of a post as it is represented in json
+var postFields = jsonFields(reflect.TypeOf(dao.Post{}))
+
+// patchApplier applies the patch of a request to a post decoded from json
+type patchApplier func(interface{}) (interface{}, error)
+
+// readPatch reads the patch in the request body, by its content type. ok is
+// false if the response has been set with an error
+func readPatch(c *gin.Context) (apply patchApplier, ok bool) {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return nil, false
+	}
+
+	switch c.ContentType() {
+	case patch.MergePatchType:
+		var mergePatch interface{}
+		if err := json.Unmarshal(body, &mergePatch); err != nil {
+			setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+			return nil, false
+		}
+		return func(doc interface{}) (interface{}, error) {
+			return patch.Merge(doc, mergePatch), nil
+		}, true
+	case patch.JSONPatchType:
+		ops, err := patch.Decode(body)
+		if err != nil {
+			setReturnError(patchError(err), c)
+			return nil, false
+		}
+		return func(doc interface{}) (interface{}, error) {
+			patched, err := patch.Apply(doc, ops)
+			if err != nil {
+				return nil, patchError(err)
+			}
+			return patched, nil
+		}, true
+	}
+
+	detail := fmt.Sprintf("content type must be %s or %s", patch.MergePatchType, patch.JSONPatchType)
+	setProblem(c, http.StatusUnsupportedMediaType, datastore.CodeInvalidArgument, detail, nil)
+	return nil, false
+}
+
+// patchPost applies the patch to the post as it is represented in json. Every
+// member but the captions must be left as it was
+func patchPost(stored *dao.Post, apply patchApplier) (*dao.Post, error) {
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, err
+	}
+	// Captions are always there to patch, even when the post has none
+	if _, ok := doc[captionsField]; !ok {
+		doc[captionsField] = []interface{}{}
+	}
+
+	patched, err := apply(doc)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := patched.(map[string]interface{})
+	if !ok {
+		return nil, datastore.NewValidationError(datastore.FieldError{Field: "body", Message: "must leave the post an object"})
+	}
+
+	verr := datastore.NewValidationError()
+	for _, name := range memberNames(doc, fields) {
+		if name == captionsField || reflect.DeepEqual(doc[name], fields[name]) {
+			continue
+		}
+		if postFields[name] {
+			verr.Add(name, "can not be changed")
+		} else {
+			verr.Add(name, "is not allowed")
+		}
+	}
+	captions := patchedCaptions(verr, fields[captionsField])
+	if verr.HasErrors() {
+		return nil, verr
+	}
+
+	stored.Captions = captions
+	return stored, nil
+}
+
+// patchedCaptions returns the captions of a patched post, a removed or null
+// member removes every caption. A caption is a text or an object, of which only
+// the id, text, selected and pinned members are kept. The source and score come
+// from the stored caption with the id
+func patchedCaptions(verr *datastore.Validation, value interface{}) []*dao.Caption {
+	if value == nil {
+		return nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		verr.Add(captionsField, "must be an array")
+		return nil
+	}
+
+	captions := make([]*dao.Caption, len(items))
+	for i, item := range items {
+		name := fmt.Sprintf("%s[%d]", captionsField, i)
+		captions[i] = &dao.Caption{}
+		switch item := item.(type) {
+		case string:
+			captions[i].Text = item
+		case map[string]interface{}:
+			members := []struct {
+				name  string
+				value interface{}
+			}{
+				{"id", &captions[i].ID},
+				{"text", &captions[i].Text},
+				{"selected", &captions[i].Selected},
+				{"pinned", &captions[i].Pinned},
+			}
+			for _, m := range members {
+				member, ok := item[m.name]
+				if !ok || member == nil {
+					continue
+				}
+				switch target := m.value.(type) {
+				case *string:
+					if *target, ok = member.(string); !ok {
+						verr.Add(name+"."+m.name, "must be a string")
+					}
+				case *bool:
+					if *target, ok = member.(bool); !ok {
+						verr.Add(name+"."+m.name, "must be a boolean")
+					}
+				}
+			}
+		default:
+			verr.Add(name, "must be a string or an object")
+		}
+	}
+	return captions
+}
+
+// patchError returns the error of a JSON Patch that can not be applied. A test
+// that did not match is a conflict with the stored post
+func patchError(err error) error {
+	perr, ok := err.(*patch.Error)
+	if !ok {
+		return datastore.NewValidationError(datastore.FieldError{Field: "body", Message: err.Error()})
+	}
+	if perr.TestFailed {
+		return datastore.NewConflictError(perr.Error())
+	}
+	return datastore.NewValidationError(datastore.FieldError{Field: fmt.Sprintf("body[%d]", perr.Index), Message: perr.Message})
+}
+
+// memberNames returns the names of the members of either object, sorted
+func memberNames(a, b map[string]interface{}) []string {
+	names := []string{}
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonFields returns the json names of the fields of the struct type
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
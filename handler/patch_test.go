@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/dao/validated"
+	"github.com/bpross/cc-hw/datastore"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/patch"
+	"github.com/bpross/cc-hw/validate"
+)
+
+var _ = Describe("Patch", func() {
+	var (
+		mockCtrl    *gomock.Controller
+		mockPoster  *mock_dao.MockPoster
+		router      *gin.Engine
+		recorder    *httptest.ResponseRecorder
+		customerID  string
+		postID      bson.ObjectId
+		stored      *dao.Post
+		contentType string
+		body        string
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPoster = mock_dao.NewMockPoster(mockCtrl)
+		// Patches are validated by the poster the server wraps the datastore in
+		validator := validate.NewValidator(validate.DefaultRules())
+		router = setupRouter(NewDefaultPoster(validated.NewPoster(log.New(), mockPoster, validator), validator))
+		recorder = httptest.NewRecorder()
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+		stored = &dao.Post{
+			ID:           &postID,
+			CustID:       customerID,
+			URL:          "https://example.com/post",
+			CanonicalURL: "https://example.com/post",
+			Captions: []*dao.Caption{
+				{ID: "c1", Text: "caption1", Source: dao.SourceManual},
+				{ID: "c2", Text: "caption2", Source: dao.SourceGenerated, Score: 0.5},
+			},
+			Status: dao.StatusDraft,
+		}
+		contentType = patch.JSONPatchType
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	// expectPatch makes the datastore apply the patch to a copy of the stored post
+	expectPatch := func() {
+		mockPoster.EXPECT().Patch(customerID, postID, gomock.Any(), customerID).DoAndReturn(
+			func(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+				copied := *stored
+				copied.Captions = dao.CopyCaptions(stored.Captions)
+				return patch(&copied)
+			})
+	}
+
+	JustBeforeEach(func() {
+		req := httptest.NewRequest(http.MethodPatch, "/post/"+postID.Hex(), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(customerIDHeader, customerID)
+		router.ServeHTTP(recorder, req)
+	})
+
+	decoded := func() *dao.Post {
+		post := &dao.Post{}
+		ExpectWithOffset(2, json.Unmarshal(recorder.Body.Bytes(), post)).To(Succeed())
+		return post
+	}
+
+	captions := func() []string {
+		return dao.CaptionTexts(decoded().Captions)
+	}
+
+	Context("with a merge patch", func() {
+		BeforeEach(func() {
+			contentType = patch.MergePatchType
+			body = `{"captions": [" caption3 ", "caption1"]}`
+			expectPatch()
+		})
+
+		It("should replace the normalized captions", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(captions()).To(Equal([]string{"caption3", "caption1"}))
+		})
+	})
+
+	Context("with a merge patch of caption objects", func() {
+		BeforeEach(func() {
+			contentType = patch.MergePatchType
+			body = `{"captions": [{"id": "c2", "text": "caption2", "selected": true, "pinned": true, "source": "manual", "score": 1}, {"text": "caption3"}]}`
+			expectPatch()
+		})
+
+		It("should take the id, text, selection and pin of every caption", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(decoded().Captions).To(Equal([]*dao.Caption{
+				{ID: "c2", Text: "caption2", Selected: true, Pinned: true},
+				{Text: "caption3"},
+			}))
+		})
+	})
+
+	Context("with a merge patch that removes the captions", func() {
+		BeforeEach(func() {
+			contentType = patch.MergePatchType
+			body = `{"captions": null}`
+			expectPatch()
+		})
+
+		It("should remove every caption", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(captions()).To(BeEmpty())
+		})
+	})
+
+	Context("with a json patch of single captions", func() {
+		BeforeEach(func() {
+			body = `[
+				{"op": "test", "path": "/captions/1/text", "value": "caption2"},
+				{"op": "replace", "path": "/captions/1/text", "value": "updated"},
+				{"op": "add", "path": "/captions/-", "value": "caption3"},
+				{"op": "remove", "path": "/captions/0"}
+			]`
+			expectPatch()
+		})
+
+		It("should apply every operation", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(captions()).To(Equal([]string{"updated", "caption3"}))
+			Expect(decoded().Captions[0].ID).To(Equal("c2"))
+		})
+	})
+
+	Context("with a json patch of a post without captions", func() {
+		BeforeEach(func() {
+			stored.Captions = nil
+			body = `[{"op": "add", "path": "/captions/-", "value": "caption1"}]`
+			expectPatch()
+		})
+
+		It("should add the caption", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(captions()).To(Equal([]string{"caption1"}))
+		})
+	})
+
+	Context("with a json patch whose test fails", func() {
+		BeforeEach(func() {
+			body = `[{"op": "test", "path": "/captions/0/text", "value": "other"}, {"op": "remove", "path": "/captions/0"}]`
+			expectPatch()
+		})
+
+		It("should return StatusConflict", func() {
+			Expect(recorder.Code).To(Equal(http.StatusConflict))
+			expectProblem(recorder, datastore.CodeConflict, `conflict: operation 0: the value at "/captions/0/text" is not the tested value`)
+		})
+	})
+
+	Context("with a json patch of a caption that does not exist", func() {
+		BeforeEach(func() {
+			body = `[{"op": "replace", "path": "/captions/5", "value": "other"}]`
+			expectPatch()
+		})
+
+		It("should return StatusBadRequest", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			expectProblem(recorder, datastore.CodeValidationFailed, `validation failed: body[0]: path "/captions/5" does not exist`)
+		})
+	})
+
+	Context("with an invalid json patch", func() {
+		BeforeEach(func() {
+			body = `[{"op": "merge", "path": "/captions"}]`
+		})
+
+		It("should return StatusBadRequest without calling the datastore", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			expectProblem(recorder, datastore.CodeValidationFailed, `validation failed: body[0]: "op" must be one of add, remove, replace, move, copy, test`)
+		})
+	})
+
+	Context("with a patch of the immutable fields", func() {
+		BeforeEach(func() {
+			body = `[
+				{"op": "replace", "path": "/id", "value": "5e154899cb80cb0001000003"},
+				{"op": "replace", "path": "/url", "value": "https://example.com/other"},
+				{"op": "add", "path": "/status", "value": "approved"},
+				{"op": "add", "path": "/customer_id", "value": "other-customer"}
+			]`
+			expectPatch()
+		})
+
+		It("should reject every changed field", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			expectProblem(recorder, datastore.CodeValidationFailed,
+				"validation failed: customer_id: is not allowed; id: can not be changed; status: can not be changed; url: can not be changed")
+		})
+	})
+
+	Context("with invalid captions", func() {
+		BeforeEach(func() {
+			contentType = patch.MergePatchType
+			body = `{"captions": ["", 1, {"text": 2, "pinned": "yes"}]}`
+			expectPatch()
+		})
+
+		It("should return every invalid caption", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: captions[1]: must be a string or an object; captions[2].text: must be a string; captions[2].pinned: must be a boolean")
+		})
+	})
+
+	Context("with captions that do not validate", func() {
+		BeforeEach(func() {
+			contentType = patch.MergePatchType
+			body = `{"captions": [" "]}`
+			expectPatch()
+		})
+
+		It("should return StatusBadRequest", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: captions[0]: must not be empty")
+		})
+	})
+
+	Context("with another content type", func() {
+		BeforeEach(func() {
+			contentType = "application/json"
+			body = `{"captions": ["caption1"]}`
+		})
+
+		It("should return StatusUnsupportedMediaType", func() {
+			Expect(recorder.Code).To(Equal(http.StatusUnsupportedMediaType))
+			expectProblem(recorder, datastore.CodeInvalidArgument, "content type must be application/merge-patch+json or application/json-patch+json")
+		})
+	})
+
+	Context("with a datastore error", func() {
+		BeforeEach(func() {
+			body = `[]`
+			mockPoster.EXPECT().Patch(customerID, postID, gomock.Any(), customerID).Return(nil, datastore.NewNotFoundError("post"))
+		})
+
+		It("should return StatusNotFound", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
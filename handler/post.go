@@ -2,41 +2,86 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"labix.org/v2/mgo/bson"
 
+	"github.com/bpross/cc-hw/canonical"
 	"github.com/bpross/cc-hw/dao"
 	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/validate"
 )
 
-const customerIDHeader = "x-customer-id"
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+
+	// dedupeParam asks POST /post to return the post the customer already has for
+	// the url instead of creating another one
+	dedupeParam        = "dedupe"
+	existingPostHeader = "Existing-Post"
+)
 
 type postRequest struct {
 	URL      string   `json:"url"`
 	Captions []string `json:"captions,omitempty"`
+	// Template is the id of the template generated captions are rendered with
+	Template string `json:"template,omitempty"`
 }
 
 type putRequest struct {
 	Captions []string `json:"captions,omitempty"`
 }
 
+type listResponse struct {
+	Posts []interface{} `json:"posts"`
+	// Next is the after of the next page, it is empty on the last page
+	Next string `json:"next,omitempty"`
+}
+
+type scheduleRequest struct {
+	ScheduledAt *time.Time `json:"scheduled_at"`
+	Channels    []string   `json:"channels"`
+}
+
 // Poster defines the interface to handle post requests
 type Poster interface {
 	Get(*gin.Context)
+	List(*gin.Context)
 	Post(*gin.Context)
 	Put(*gin.Context)
+	Patch(*gin.Context)
+	SelectCaption(*gin.Context)
+	PinCaption(*gin.Context)
+	UnpinCaption(*gin.Context)
+	DiscardCaption(*gin.Context)
+	ReorderCaptions(*gin.Context)
+	Approve(*gin.Context)
+	Revisions(*gin.Context)
+	Restore(*gin.Context)
+	Delete(*gin.Context)
+	Schedule(*gin.Context)
+	Unschedule(*gin.Context)
+	BatchCreate(*gin.Context)
+	BatchUpdate(*gin.Context)
+	Export(*gin.Context)
+	Import(*gin.Context)
 }
 
 // DefaultPoster implements the Poster interface
 type DefaultPoster struct {
-	ds dao.Poster
+	ds        dao.Poster
+	validator *validate.Validator
 }
 
-// NewDefaultPoster returns a DefaultPoster with the provided options
-func NewDefaultPoster(ds dao.Poster) *DefaultPoster {
+// NewDefaultPoster returns a DefaultPoster with the provided options. Request
+// bodies are checked with the validator before the datastore is called
+func NewDefaultPoster(ds dao.Poster, validator *validate.Validator) *DefaultPoster {
 	return &DefaultPoster{
-		ds: ds,
+		ds:        ds,
+		validator: validator,
 	}
 }
 
@@ -51,8 +96,8 @@ func (p *DefaultPoster) Get(c *gin.Context) {
 
 	id := bson.ObjectIdHex(urlID)
 
-	// Get headers
-	customerID := getAndValidateHeaders(c)
+	// Get tenant
+	customerID := getCustomerID(c)
 	if customerID == "" {
 		return
 	}
@@ -62,31 +107,71 @@ func (p *DefaultPoster) Get(c *gin.Context) {
 		setReturnError(err, c)
 		return
 	}
-	c.PureJSON(http.StatusOK, post)
+	c.PureJSON(http.StatusOK, mapPost(c, post))
+	return
+}
+
+// List defines the handler for listing the customer's posts, oldest first unless
+// sort is set. A page starts after the cursor in after, and the posts can be
+// filtered by when and by whom they were created and last updated
+func (p *DefaultPoster) List(c *gin.Context) {
+	opts, ok := readListOptions(c)
+	if !ok {
+		return
+	}
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	posts, err := p.ds.List(customerID, opts)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+
+	resp := listResponse{Posts: mapPosts(c, posts)}
+	if len(posts) == opts.Limit {
+		resp.Next = listCursor(opts, posts[len(posts)-1])
+	}
+	c.PureJSON(http.StatusOK, resp)
 	return
 }
 
 // Post defines the handler for post POST requests
 func (p *DefaultPoster) Post(c *gin.Context) {
-	// Get headers
-	customerID := getAndValidateHeaders(c)
+	// Get tenant
+	customerID := getCustomerID(c)
 	if customerID == "" {
 		return
 	}
 	// Hydrate post
 	req := &postRequest{}
 	if err := c.BindJSON(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
 		return
 	}
 
 	input := postRequestToPost(*req)
+	input.UpdatedBy = getActor(c)
+	if err := p.validator.Post(input); err != nil {
+		setReturnError(err, c)
+		return
+	}
+
+	existing, ok := findExisting(c, p.ds, customerID, input.URL)
+	if !ok || existing != nil {
+		return
+	}
+
 	post, err := p.ds.Insert(customerID, input)
 	if err != nil {
 		setReturnError(err, c)
 		return
 	}
-	c.PureJSON(http.StatusOK, post)
+	c.PureJSON(http.StatusOK, mapPost(c, post))
 	return
 }
 
@@ -101,8 +186,8 @@ func (p *DefaultPoster) Put(c *gin.Context) {
 
 	id := bson.ObjectIdHex(urlID)
 
-	// Get headers
-	customerID := getAndValidateHeaders(c)
+	// Get tenant
+	customerID := getCustomerID(c)
 	if customerID == "" {
 		return
 	}
@@ -110,63 +195,296 @@ func (p *DefaultPoster) Put(c *gin.Context) {
 	// Hydrate post
 	req := &putRequest{}
 	if err := c.BindJSON(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
 		return
 	}
 
 	input := putRequestToPost(*req, id)
+	input.UpdatedBy = getActor(c)
+	if err := p.validator.Update(input); err != nil {
+		setReturnError(err, c)
+		return
+	}
 	post, err := p.ds.Update(customerID, input)
 	if err != nil {
 		setReturnError(err, c)
 		return
 	}
-	c.PureJSON(http.StatusOK, post)
+	c.PureJSON(http.StatusOK, mapPost(c, post))
 	return
 }
 
-func validateID(c *gin.Context, urlID string) bool {
-	ok := bson.IsObjectIdHex(urlID)
+// Patch defines the handler for post PATCH requests. The body is a JSON Merge
+// Patch or a JSON Patch of the post as it is stored, and only the captions can be
+// changed. The patch is applied in the datastore, so concurrent changes are not
+// lost
+func (p *DefaultPoster) Patch(c *gin.Context) {
+	urlID := c.Param("id")
+	// Check if id is valid
+	ok := validateID(c, urlID)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid post id"})
-		return false
+		return
 	}
-	return true
+
+	id := bson.ObjectIdHex(urlID)
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	apply, ok := readPatch(c)
+	if !ok {
+		return
+	}
+	// The patched captions are validated by the poster, see dao/validated
+	post, err := p.ds.Patch(customerID, id, func(stored *dao.Post) (*dao.Post, error) {
+		return patchPost(stored, apply)
+	}, getActor(c))
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, mapPost(c, post))
+	return
+}
+
+// Approve defines the handler for approving a post
+func (p *DefaultPoster) Approve(c *gin.Context) {
+	urlID := c.Param("id")
+	// Check if id is valid
+	ok := validateID(c, urlID)
+	if !ok {
+		return
+	}
+
+	id := bson.ObjectIdHex(urlID)
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	// Approve the captions as they are stored, a change made since they were
+	// read can not be approved by accident
+	post, err := p.ds.Patch(customerID, id, func(stored *dao.Post) (*dao.Post, error) {
+		stored.Status = dao.StatusApproved
+		return stored, nil
+	}, getActor(c))
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, mapPost(c, post))
+	return
+}
+
+// Revisions defines the handler for listing every version of a post
+func (p *DefaultPoster) Revisions(c *gin.Context) {
+	urlID := c.Param("id")
+	// Check if id is valid
+	ok := validateID(c, urlID)
+	if !ok {
+		return
+	}
+
+	id := bson.ObjectIdHex(urlID)
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	revisions, err := p.ds.Revisions(customerID, id)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, mapRevisions(c, revisions))
+	return
+}
+
+// Restore defines the handler for setting a post's captions back to a revision
+func (p *DefaultPoster) Restore(c *gin.Context) {
+	urlID := c.Param("id")
+	// Check if id is valid
+	ok := validateID(c, urlID)
+	if !ok {
+		return
+	}
+
+	id := bson.ObjectIdHex(urlID)
+
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || number < 1 {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid revision", nil)
+		return
+	}
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	post, err := p.ds.Restore(customerID, id, number, getActor(c))
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, mapPost(c, post))
+	return
+}
+
+// Delete defines the handler for deleting a post
+func (p *DefaultPoster) Delete(c *gin.Context) {
+	urlID := c.Param("id")
+	// Check if id is valid
+	ok := validateID(c, urlID)
+	if !ok {
+		return
+	}
+
+	id := bson.ObjectIdHex(urlID)
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	if err := p.ds.Delete(customerID, id, getActor(c)); err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	return
+}
+
+// findExisting returns the customer's post for the url when the request asked for
+// it with dedupe=true. If a post is found, it is written as the response. ok is
+// false if the response has been set with an error
+func findExisting(c *gin.Context, ds dao.Poster, customerID, url string) (post *dao.Post, ok bool) {
+	if c.Query(dedupeParam) != "true" {
+		return nil, true
+	}
+
+	canonicalURL, err := canonical.URL(url)
+	if err != nil {
+		return nil, true
+	}
+	post, err = ds.GetByURL(customerID, canonicalURL)
+	if err != nil {
+		if _, notFound := err.(*datastore.NotFound); notFound {
+			return nil, true
+		}
+		setReturnError(err, c)
+		return nil, false
+	}
+	if post == nil {
+		return nil, true
+	}
+
+	c.Header(existingPostHeader, "true")
+	c.PureJSON(http.StatusOK, mapPost(c, post))
+	return post, true
 }
 
-func getAndValidateHeaders(c *gin.Context) string {
-	// Get headers
-	customerID := c.Request.Header.Get(customerIDHeader)
+// Schedule defines the handler for setting when and where an approved post is
+// published
+func (p *DefaultPoster) Schedule(c *gin.Context) {
+	urlID := c.Param("id")
+	// Check if id is valid
+	ok := validateID(c, urlID)
+	if !ok {
+		return
+	}
+
+	id := bson.ObjectIdHex(urlID)
 
+	// Get tenant
+	customerID := getCustomerID(c)
 	if customerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "must include customerID in headers"})
+		return
 	}
-	return customerID
+	req := &scheduleRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+	if req.ScheduledAt == nil {
+		setReturnError(datastore.NewValidationError(datastore.FieldError{Field: "scheduled_at", Message: "is required"}), c)
+		return
+	}
+
+	input := &dao.Post{
+		ID:          &id,
+		ScheduledAt: req.ScheduledAt,
+		Channels:    req.Channels,
+		UpdatedBy:   getActor(c),
+	}
+	if err := p.validator.Schedule(input); err != nil {
+		setReturnError(err, c)
+		return
+	}
+
+	post, err := p.ds.Schedule(customerID, input)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, mapPost(c, post))
+	return
 }
 
-func setReturnError(dsErr error, c *gin.Context) {
-	switch dsErr.(type) {
-	case *datastore.InvalidArugment:
-		c.JSON(http.StatusBadRequest, gin.H{"message": dsErr.Error()})
+// Unschedule defines the handler for removing the schedule of a post
+func (p *DefaultPoster) Unschedule(c *gin.Context) {
+	urlID := c.Param("id")
+	// Check if id is valid
+	ok := validateID(c, urlID)
+	if !ok {
 		return
-	case *datastore.NotFound:
-		c.JSON(http.StatusNotFound, gin.H{"message": dsErr.Error()})
+	}
+
+	id := bson.ObjectIdHex(urlID)
+
+	// Get tenant
+	customerID := getCustomerID(c)
+	if customerID == "" {
 		return
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"message": dsErr.Error()})
+	}
+
+	post, err := p.ds.Schedule(customerID, &dao.Post{ID: &id, UpdatedBy: getActor(c)})
+	if err != nil {
+		setReturnError(err, c)
 		return
 	}
+	c.PureJSON(http.StatusOK, mapPost(c, post))
+	return
+}
+
+func validateID(c *gin.Context, urlID string) bool {
+	ok := bson.IsObjectIdHex(urlID)
+	if !ok {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid post id", nil)
+		return false
+	}
+	return true
 }
 
 func postRequestToPost(req postRequest) *dao.Post {
 	return &dao.Post{
 		URL:      req.URL,
-		Captions: req.Captions,
+		Captions: dao.ManualCaptions(req.Captions),
 	}
 }
 
 func putRequestToPost(req putRequest, id bson.ObjectId) *dao.Post {
 	return &dao.Post{
 		ID:       &id,
-		Captions: req.Captions,
+		Captions: dao.ManualCaptions(req.Captions),
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -15,11 +16,19 @@ import (
 	. "github.com/onsi/gomega"
 	"labix.org/v2/mgo/bson"
 
+	"github.com/bpross/cc-hw/auth"
 	"github.com/bpross/cc-hw/dao"
 	"github.com/bpross/cc-hw/datastore"
 	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/validate"
 )
 
+// v1ListResponse is a listResponse as the /v1 routes represent it
+type v1ListResponse struct {
+	Posts []*dao.Post `json:"posts"`
+	Next  string      `json:"next"`
+}
+
 var _ = Describe("DefaulPoster", func() {
 	var (
 		mockCtrl   *gomock.Controller
@@ -36,7 +45,7 @@ var _ = Describe("DefaulPoster", func() {
 	BeforeEach(func() {
 		mockCtrl = gomock.NewController(GinkgoT())
 		mockPoster = mock_dao.NewMockPoster(mockCtrl)
-		handler = NewDefaultPoster(mockPoster)
+		handler = NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules()))
 		router = setupRouter(handler)
 		customerID = "test-customer"
 		recorder = httptest.NewRecorder()
@@ -73,9 +82,7 @@ var _ = Describe("DefaulPoster", func() {
 			})
 
 			It("should return a useful message", func() {
-				expected := `{"message":"invalid post id"}`
-				actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-				Expect(actual).To(Equal(expected))
+				expectProblem(recorder, datastore.CodeInvalidArgument, "invalid post id")
 			})
 		})
 
@@ -90,14 +97,12 @@ var _ = Describe("DefaulPoster", func() {
 					Expect(err).To(BeNil())
 				})
 
-				It("should return StatusBadRequest", func() {
-					Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				It("should return StatusUnauthorized", func() {
+					Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
 				})
 
 				It("should return a useful message", func() {
-					expected := `{"message":"must include customerID in headers"}`
-					actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-					Expect(actual).To(Equal(expected))
+					expectProblem(recorder, auth.CodeUnauthenticated, "unauthorized: request is not authenticated")
 				})
 			})
 
@@ -120,9 +125,7 @@ var _ = Describe("DefaulPoster", func() {
 						})
 
 						It("should return the datastore message", func() {
-							expected := `{"message":"invalid test-error"}`
-							actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-							Expect(actual).To(Equal(expected))
+							expectProblem(recorder, datastore.CodeInvalidArgument, "invalid test-error")
 						})
 					})
 
@@ -137,9 +140,7 @@ var _ = Describe("DefaulPoster", func() {
 						})
 
 						It("should return the datastore message", func() {
-							expected := `{"message":"test-error not found"}`
-							actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-							Expect(actual).To(Equal(expected))
+							expectProblem(recorder, datastore.CodeNotFound, "test-error not found")
 						})
 					})
 
@@ -154,9 +155,7 @@ var _ = Describe("DefaulPoster", func() {
 						})
 
 						It("should return the datastore message", func() {
-							expected := `{"message":"test-error"}`
-							actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-							Expect(actual).To(Equal(expected))
+							expectProblem(recorder, codeInternal, "internal server error")
 						})
 					})
 				})
@@ -169,12 +168,12 @@ var _ = Describe("DefaulPoster", func() {
 						dsPost = &dao.Post{
 							ID:     &postID,
 							CustID: customerID,
-							URL:    "test-url",
-							Captions: []string{
+							URL:    "https://example.com/post",
+							Captions: dao.ManualCaptions([]string{
 								"caption1",
 								"caption2",
 								"caption3",
-							},
+							}),
 						}
 						mockPoster.EXPECT().Get(customerID, postID).Return(dsPost, nil)
 					})
@@ -184,7 +183,7 @@ var _ = Describe("DefaulPoster", func() {
 					})
 
 					It("should return a post", func() {
-						expected := fmt.Sprintf(`{"id":"%s","url":"test-url","captions":["caption1","caption2","caption3"]}`, postID.Hex())
+						expected := fmt.Sprintf(`{"id":"%s","url":"https://example.com/post","captions":[{"id":"","text":"caption1","source":"manual","selected":false,"pinned":false},{"id":"","text":"caption2","source":"manual","selected":false,"pinned":false},{"id":"","text":"caption3","source":"manual","selected":false,"pinned":false}]}`, postID.Hex())
 						actual := strings.TrimSuffix(recorder.Body.String(), "\n")
 						Expect(actual).To(Equal(expected))
 					})
@@ -193,6 +192,153 @@ var _ = Describe("DefaulPoster", func() {
 		})
 	})
 
+	Describe("List", func() {
+		var (
+			posts []*dao.Post
+			err   error
+		)
+
+		BeforeEach(func() {
+			url = "/posts"
+			posts = []*dao.Post{}
+			for i := 0; i < 2; i++ {
+				postID := bson.NewObjectId()
+				posts = append(posts, &dao.Post{ID: &postID, URL: fmt.Sprintf("https://example.com/%d", i)})
+			}
+		})
+
+		JustBeforeEach(func() {
+			req, err = http.NewRequest("GET", url, nil)
+			Expect(err).To(BeNil())
+			req.Header.Add(customerIDHeader, customerID)
+			router.ServeHTTP(recorder, req)
+		})
+
+		Context("with invalid after", func() {
+			BeforeEach(func() {
+				url = "/posts?after=blah"
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeInvalidArgument, "invalid after")
+			})
+		})
+
+		Context("with limit over the max", func() {
+			BeforeEach(func() {
+				url = fmt.Sprintf("/posts?limit=%d", maxListLimit+1)
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeInvalidArgument, "invalid limit")
+			})
+		})
+
+		Context("with a full page", func() {
+			var after bson.ObjectId
+
+			BeforeEach(func() {
+				after = bson.NewObjectId()
+				url = "/posts?limit=2&after=" + after.Hex()
+				mockPoster.EXPECT().List(customerID, dao.ListOptions{After: after, Limit: 2}).Return(posts, nil)
+			})
+
+			It("should return the next page", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				resp := &v1ListResponse{}
+				Expect(json.Unmarshal(recorder.Body.Bytes(), resp)).To(Succeed())
+				Expect(resp.Posts).To(Equal(posts))
+				Expect(resp.Next).To(Equal(posts[1].ID.Hex()))
+			})
+		})
+
+		invalidQueries := []struct {
+			query, detail string
+		}{
+			{"sort=url", "invalid sort"},
+			{"sort=-", "invalid sort"},
+			{"sort=created_at&after=" + bson.NewObjectId().Hex(), "invalid after"},
+			{"after=2026-10-01T12:00:00Z_" + bson.NewObjectId().Hex(), "invalid after"},
+			{"created_after=yesterday", "invalid created_after"},
+			{"updated_before=2026-10-01", "invalid updated_before"},
+		}
+		for _, q := range invalidQueries {
+			q := q
+			Context("with "+q.query, func() {
+				BeforeEach(func() {
+					url = "/posts?" + q.query
+				})
+
+				It("should return StatusBadRequest", func() {
+					Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+					expectProblem(recorder, datastore.CodeInvalidArgument, q.detail)
+				})
+			})
+		}
+
+		Context("with a sort and filters", func() {
+			var (
+				after     bson.ObjectId
+				afterTime time.Time
+			)
+
+			BeforeEach(func() {
+				after = bson.NewObjectId()
+				afterTime = time.Date(2026, 10, 1, 12, 0, 0, 500, time.UTC)
+				updated := time.Date(2026, 9, 30, 8, 0, 0, 0, time.UTC)
+				posts[1].UpdatedAt = &updated
+				url = "/posts?limit=2&sort=-updated_at&after=2026-10-01T12:00:00.0000005Z_" + after.Hex() +
+					"&created_after=2026-01-01T00:00:00Z&updated_before=2026-12-01T00:00:00%2B01:00&created_by=alice&updated_by=bob"
+				mockPoster.EXPECT().List(customerID, dao.ListOptions{
+					After:      after,
+					AfterTime:  afterTime,
+					Limit:      2,
+					Sort:       dao.SortUpdatedAt,
+					Descending: true,
+					Filter: dao.ListFilter{
+						CreatedAfter:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+						UpdatedBefore: time.Date(2026, 11, 30, 23, 0, 0, 0, time.UTC),
+						CreatedBy:     "alice",
+						UpdatedBy:     "bob",
+					},
+				}).Return(posts, nil)
+			})
+
+			It("should return the next page after the sort time of the last post", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				resp := &v1ListResponse{}
+				Expect(json.Unmarshal(recorder.Body.Bytes(), resp)).To(Succeed())
+				Expect(resp.Next).To(Equal("2026-09-30T08:00:00Z_" + posts[1].ID.Hex()))
+			})
+		})
+
+		Context("with the last page", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().List(customerID, dao.ListOptions{Limit: defaultListLimit}).Return(posts, nil)
+			})
+
+			It("should NOT return a next page", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				resp := &v1ListResponse{}
+				Expect(json.Unmarshal(recorder.Body.Bytes(), resp)).To(Succeed())
+				Expect(resp.Posts).To(HaveLen(2))
+				Expect(resp.Next).To(BeEmpty())
+			})
+		})
+
+		Context("with datastore error", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().List(customerID, dao.ListOptions{Limit: defaultListLimit}).Return(nil, errors.New("test-error"))
+			})
+
+			It("should return StatusInternalServerError", func() {
+				Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
 	Describe("Post", func() {
 		var err error
 		BeforeEach(func() {
@@ -210,14 +356,12 @@ var _ = Describe("DefaulPoster", func() {
 				Expect(err).To(BeNil())
 			})
 
-			It("should return StatusBadRequest", func() {
-				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
 			})
 
 			It("should return a useful message", func() {
-				expected := `{"message":"must include customerID in headers"}`
-				actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-				Expect(actual).To(Equal(expected))
+				expectProblem(recorder, auth.CodeUnauthenticated, "unauthorized: request is not authenticated")
 			})
 		})
 
@@ -234,9 +378,26 @@ var _ = Describe("DefaulPoster", func() {
 				})
 
 				It("should return a useful message", func() {
-					expected := `{"message":"invalid request"}`
-					actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-					Expect(actual).To(Equal(expected))
+					expectProblem(recorder, datastore.CodeInvalidArgument, "invalid request")
+				})
+			})
+
+			Context("with invalid fields", func() {
+				BeforeEach(func() {
+					body := `{"url":"example.com","captions":["ok","bad\u0007"]}`
+					req, err = http.NewRequest(method, url, strings.NewReader(body))
+					Expect(err).To(BeNil())
+					req.Header.Add(customerIDHeader, customerID)
+					req.Header.Add("Content-Type", "application/json")
+				})
+
+				It("should return StatusBadRequest", func() {
+					Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				})
+
+				It("should return every invalid field", func() {
+					expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: url: must be an absolute url; captions[1]: must not contain control characters")
+					Expect(recorder.Body.String()).To(ContainSubstring(`"errors":[{"field":"url","message":"must be an absolute url"},{"field":"captions[1]","message":"must not contain control characters"}]`))
 				})
 			})
 
@@ -247,21 +408,39 @@ var _ = Describe("DefaulPoster", func() {
 				)
 				BeforeEach(func() {
 					post = dao.Post{
-						URL: "test-url",
-						Captions: []string{
+						URL: "https://example.com/post",
+						Captions: dao.ManualCaptions([]string{
 							"caption1",
 							"caption2",
 							"caption3",
-						},
+						}),
 					}
-					body, err = json.Marshal(post)
+					body, err = json.Marshal(postRequest{URL: post.URL, Captions: dao.CaptionTexts(post.Captions)})
 					Expect(err).To(BeNil())
+					// the handler records the caller as the author
+					post.UpdatedBy = customerID
 					req, err = http.NewRequest(method, url, bytes.NewBuffer(body))
 					Expect(err).To(BeNil())
 					req.Header.Add(customerIDHeader, customerID)
 					req.Header.Add("Content-Type", "application/json")
 				})
 
+				Context("with dedupe and an existing post", func() {
+					var existingID bson.ObjectId
+					BeforeEach(func() {
+						req.URL.RawQuery = "dedupe=true"
+						existingID = bson.NewObjectId()
+						existing := &dao.Post{ID: &existingID, URL: "https://example.com/post"}
+						mockPoster.EXPECT().GetByURL(customerID, "https://example.com/post").Return(existing, nil)
+					})
+
+					It("should return the existing post", func() {
+						Expect(recorder.Code).To(Equal(http.StatusOK))
+						Expect(recorder.Header().Get("Existing-Post")).To(Equal("true"))
+						Expect(recorder.Body.String()).To(ContainSubstring(existingID.Hex()))
+					})
+				})
+
 				Context("with datastore error", func() {
 					Context("with InvalidArugment error", func() {
 						BeforeEach(func() {
@@ -274,9 +453,7 @@ var _ = Describe("DefaulPoster", func() {
 						})
 
 						It("should return the datastore message", func() {
-							expected := `{"message":"invalid test-error"}`
-							actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-							Expect(actual).To(Equal(expected))
+							expectProblem(recorder, datastore.CodeInvalidArgument, "invalid test-error")
 						})
 					})
 
@@ -291,9 +468,7 @@ var _ = Describe("DefaulPoster", func() {
 						})
 
 						It("should return the datastore message", func() {
-							expected := `{"message":"test-error"}`
-							actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-							Expect(actual).To(Equal(expected))
+							expectProblem(recorder, codeInternal, "internal server error")
 						})
 					})
 				})
@@ -308,12 +483,12 @@ var _ = Describe("DefaulPoster", func() {
 						dsPost = &dao.Post{
 							ID:     &postID,
 							CustID: customerID,
-							URL:    "test-url",
-							Captions: []string{
+							URL:    "https://example.com/post",
+							Captions: dao.ManualCaptions([]string{
 								"caption1",
 								"caption2",
 								"caption3",
-							},
+							}),
 						}
 						mockPoster.EXPECT().Insert(customerID, &post).Return(dsPost, nil)
 					})
@@ -323,7 +498,7 @@ var _ = Describe("DefaulPoster", func() {
 					})
 
 					It("should return a post", func() {
-						expected := fmt.Sprintf(`{"id":"%s","url":"test-url","captions":["caption1","caption2","caption3"]}`, postID.Hex())
+						expected := fmt.Sprintf(`{"id":"%s","url":"https://example.com/post","captions":[{"id":"","text":"caption1","source":"manual","selected":false,"pinned":false},{"id":"","text":"caption2","source":"manual","selected":false,"pinned":false},{"id":"","text":"caption3","source":"manual","selected":false,"pinned":false}]}`, postID.Hex())
 						actual := strings.TrimSuffix(recorder.Body.String(), "\n")
 						Expect(actual).To(Equal(expected))
 					})
@@ -358,9 +533,7 @@ var _ = Describe("DefaulPoster", func() {
 			})
 
 			It("should return a useful message", func() {
-				expected := `{"message":"invalid post id"}`
-				actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-				Expect(actual).To(Equal(expected))
+				expectProblem(recorder, datastore.CodeInvalidArgument, "invalid post id")
 			})
 		})
 
@@ -375,14 +548,12 @@ var _ = Describe("DefaulPoster", func() {
 					Expect(err).To(BeNil())
 				})
 
-				It("should return StatusBadRequest", func() {
-					Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				It("should return StatusUnauthorized", func() {
+					Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
 				})
 
 				It("should return a useful message", func() {
-					expected := `{"message":"must include customerID in headers"}`
-					actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-					Expect(actual).To(Equal(expected))
+					expectProblem(recorder, auth.CodeUnauthenticated, "unauthorized: request is not authenticated")
 				})
 			})
 
@@ -399,9 +570,7 @@ var _ = Describe("DefaulPoster", func() {
 					})
 
 					It("should return a useful message", func() {
-						expected := `{"message":"invalid request"}`
-						actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-						Expect(actual).To(Equal(expected))
+						expectProblem(recorder, datastore.CodeInvalidArgument, "invalid request")
 					})
 				})
 
@@ -413,14 +582,16 @@ var _ = Describe("DefaulPoster", func() {
 					BeforeEach(func() {
 						post = dao.Post{
 							ID: &postID,
-							Captions: []string{
+							Captions: dao.ManualCaptions([]string{
 								"caption1",
 								"caption2",
 								"caption3",
-							},
+							}),
 						}
-						body, err = json.Marshal(post)
+						body, err = json.Marshal(putRequest{Captions: dao.CaptionTexts(post.Captions)})
 						Expect(err).To(BeNil())
+						// the handler records the caller as the author
+						post.UpdatedBy = customerID
 						req, err = http.NewRequest(method, url, bytes.NewBuffer(body))
 						Expect(err).To(BeNil())
 						req.Header.Add(customerIDHeader, customerID)
@@ -439,9 +610,7 @@ var _ = Describe("DefaulPoster", func() {
 							})
 
 							It("should return the datastore message", func() {
-								expected := `{"message":"invalid test-error"}`
-								actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-								Expect(actual).To(Equal(expected))
+								expectProblem(recorder, datastore.CodeInvalidArgument, "invalid test-error")
 							})
 						})
 
@@ -456,9 +625,7 @@ var _ = Describe("DefaulPoster", func() {
 							})
 
 							It("should return the datastore message", func() {
-								expected := `{"message":"test-error"}`
-								actual := strings.TrimSuffix(recorder.Body.String(), "\n")
-								Expect(actual).To(Equal(expected))
+								expectProblem(recorder, codeInternal, "internal server error")
 							})
 						})
 					})
@@ -473,12 +640,12 @@ var _ = Describe("DefaulPoster", func() {
 							dsPost = &dao.Post{
 								ID:     &postID,
 								CustID: customerID,
-								URL:    "test-url",
-								Captions: []string{
+								URL:    "https://example.com/post",
+								Captions: dao.ManualCaptions([]string{
 									"caption1",
 									"caption2",
 									"caption3",
-								},
+								}),
 							}
 							mockPoster.EXPECT().Update(customerID, &post).Return(dsPost, nil)
 						})
@@ -488,7 +655,7 @@ var _ = Describe("DefaulPoster", func() {
 						})
 
 						It("should return a post", func() {
-							expected := fmt.Sprintf(`{"id":"%s","url":"test-url","captions":["caption1","caption2","caption3"]}`, postID.Hex())
+							expected := fmt.Sprintf(`{"id":"%s","url":"https://example.com/post","captions":[{"id":"","text":"caption1","source":"manual","selected":false,"pinned":false},{"id":"","text":"caption2","source":"manual","selected":false,"pinned":false},{"id":"","text":"caption3","source":"manual","selected":false,"pinned":false}]}`, postID.Hex())
 							actual := strings.TrimSuffix(recorder.Body.String(), "\n")
 							Expect(actual).To(Equal(expected))
 						})
@@ -497,4 +664,317 @@ var _ = Describe("DefaulPoster", func() {
 			})
 		})
 	})
+	Describe("Approve", func() {
+		var (
+			postID bson.ObjectId
+			err    error
+		)
+
+		BeforeEach(func() {
+			postID = bson.NewObjectId()
+			method = "POST"
+			url = "/post/" + postID.Hex() + "/approve"
+			req, err = http.NewRequest(method, url, nil)
+			Expect(err).To(BeNil())
+			req.Header.Add(customerIDHeader, customerID)
+		})
+
+		JustBeforeEach(func() {
+			router.ServeHTTP(recorder, req)
+		})
+
+		Context("with post not found", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Patch(customerID, postID, gomock.Any(), customerID).Return(nil, datastore.NewNotFoundError("post"))
+			})
+
+			It("should return StatusNotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("with post found", func() {
+			BeforeEach(func() {
+				dsPost := &dao.Post{
+					ID:       &postID,
+					CustID:   customerID,
+					URL:      "https://example.com/post",
+					Captions: dao.ManualCaptions([]string{"caption1"}),
+					Status:   dao.StatusDraft,
+				}
+				mockPoster.EXPECT().Patch(customerID, postID, gomock.Any(), customerID).DoAndReturn(
+					func(customerID string, postID bson.ObjectId, patch dao.PatchFunc, author string) (*dao.Post, error) {
+						copied := *dsPost
+						return patch(&copied)
+					})
+			})
+
+			It("should return StatusOK", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			})
+
+			It("should return the approved post", func() {
+				expected := fmt.Sprintf(`{"id":"%s","url":"https://example.com/post","captions":[{"id":"","text":"caption1","source":"manual","selected":false,"pinned":false}],"status":"approved"}`, postID.Hex())
+				actual := strings.TrimSuffix(recorder.Body.String(), "\n")
+				Expect(actual).To(Equal(expected))
+			})
+		})
+
+		Context("with a post that can not be approved", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Patch(customerID, postID, gomock.Any(), customerID).Return(nil, datastore.NewConflictError("post can not be approved when it is published"))
+			})
+
+			It("should return StatusConflict", func() {
+				Expect(recorder.Code).To(Equal(http.StatusConflict))
+				Expect(recorder.Body.String()).To(ContainSubstring("post can not be approved when it is published"))
+			})
+		})
+	})
+	Describe("Revisions", func() {
+		var (
+			postID bson.ObjectId
+			err    error
+		)
+
+		BeforeEach(func() {
+			postID = bson.NewObjectId()
+			req, err = http.NewRequest("GET", "/post/"+postID.Hex()+"/revisions", nil)
+			Expect(err).To(BeNil())
+			req.Header.Add(customerIDHeader, customerID)
+		})
+
+		JustBeforeEach(func() {
+			router.ServeHTTP(recorder, req)
+		})
+
+		Context("with post not found", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Revisions(customerID, postID).Return(nil, datastore.NewNotFoundError("post"))
+			})
+
+			It("should return StatusNotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				expectProblem(recorder, datastore.CodeNotFound, "post not found")
+			})
+		})
+
+		Context("with revisions", func() {
+			BeforeEach(func() {
+				revisions := []*dao.Revision{
+					{
+						Number:    1,
+						PostID:    postID,
+						Author:    customerID,
+						CreatedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+						Captions:  dao.ManualCaptions([]string{"caption1"}),
+						Status:    dao.StatusDraft,
+						Diff:      []dao.CaptionChange{{Op: dao.OpAdded, Index: 0, Caption: "caption1"}},
+					},
+				}
+				mockPoster.EXPECT().Revisions(customerID, postID).Return(revisions, nil)
+			})
+
+			It("should return the revisions", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				expected := fmt.Sprintf(`[{"number":1,"post_id":"%s","author":"%s","created_at":"2020-01-02T03:04:05Z","captions":[{"id":"","text":"caption1","source":"manual","selected":false,"pinned":false}],"status":"draft","diff":[{"op":"added","index":0,"caption":"caption1"}]}]`, postID.Hex(), customerID)
+				Expect(strings.TrimSuffix(recorder.Body.String(), "\n")).To(Equal(expected))
+			})
+		})
+	})
+
+	Describe("Restore", func() {
+		var (
+			postID bson.ObjectId
+			rev    string
+			err    error
+		)
+
+		BeforeEach(func() {
+			postID = bson.NewObjectId()
+			rev = "1"
+		})
+
+		JustBeforeEach(func() {
+			req, err = http.NewRequest("POST", "/post/"+postID.Hex()+"/revisions/"+rev+"/restore", nil)
+			Expect(err).To(BeNil())
+			req.Header.Add(customerIDHeader, customerID)
+			router.ServeHTTP(recorder, req)
+		})
+
+		Context("with invalid revision", func() {
+			BeforeEach(func() {
+				rev = "first"
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeInvalidArgument, "invalid revision")
+			})
+		})
+
+		Context("with revision not found", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Restore(customerID, postID, 1, customerID).Return(nil, datastore.NewNotFoundError("revision"))
+			})
+
+			It("should return StatusNotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				expectProblem(recorder, datastore.CodeNotFound, "revision not found")
+			})
+		})
+
+		Context("with revision found", func() {
+			BeforeEach(func() {
+				restored := &dao.Post{
+					ID:        &postID,
+					URL:       "https://example.com/post",
+					Captions:  dao.ManualCaptions([]string{"caption1"}),
+					Status:    dao.StatusDraft,
+					UpdatedBy: customerID,
+				}
+				mockPoster.EXPECT().Restore(customerID, postID, 1, customerID).Return(restored, nil)
+			})
+
+			It("should return the restored post", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				expected := fmt.Sprintf(`{"id":"%s","url":"https://example.com/post","captions":[{"id":"","text":"caption1","source":"manual","selected":false,"pinned":false}],"status":"draft","updated_by":"%s"}`, postID.Hex(), customerID)
+				Expect(strings.TrimSuffix(recorder.Body.String(), "\n")).To(Equal(expected))
+			})
+		})
+	})
+	Describe("Delete", func() {
+		var (
+			postID bson.ObjectId
+			err    error
+		)
+
+		BeforeEach(func() {
+			postID = bson.NewObjectId()
+			req, err = http.NewRequest("DELETE", "/post/"+postID.Hex(), nil)
+			Expect(err).To(BeNil())
+			req.Header.Add(customerIDHeader, customerID)
+		})
+
+		JustBeforeEach(func() {
+			router.ServeHTTP(recorder, req)
+		})
+
+		Context("with post not found", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Delete(customerID, postID, customerID).Return(datastore.NewNotFoundError("post"))
+			})
+
+			It("should return StatusNotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				expectProblem(recorder, datastore.CodeNotFound, "post not found")
+			})
+		})
+
+		Context("with post found", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Delete(customerID, postID, customerID).Return(nil)
+			})
+
+			It("should return StatusNoContent", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNoContent))
+				Expect(recorder.Body.String()).To(BeEmpty())
+			})
+		})
+	})
+	Describe("Schedule", func() {
+		var (
+			postID      bson.ObjectId
+			scheduledAt time.Time
+			body        string
+			err         error
+		)
+
+		BeforeEach(func() {
+			postID = bson.NewObjectId()
+			scheduledAt = time.Date(2020, 1, 2, 15, 0, 0, 0, time.UTC)
+			body = `{"scheduled_at": "2020-01-02T10:00:00-05:00", "channels": [" Twitter", "linkedin"]}`
+		})
+
+		JustBeforeEach(func() {
+			req, err = http.NewRequest("PUT", "/post/"+postID.Hex()+"/schedule", strings.NewReader(body))
+			Expect(err).To(BeNil())
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add(customerIDHeader, customerID)
+			router.ServeHTTP(recorder, req)
+		})
+
+		Context("without scheduled_at", func() {
+			BeforeEach(func() {
+				body = `{"channels": ["twitter"]}`
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: scheduled_at: is required")
+			})
+		})
+
+		Context("with invalid channels", func() {
+			BeforeEach(func() {
+				body = `{"scheduled_at": "2020-01-02T10:00:00Z", "channels": ["twitter", "Twitter", "face book"]}`
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed,
+					"validation failed: channels[1]: is a duplicate; channels[2]: must only contain letters, digits, - and _")
+			})
+		})
+
+		Context("with a published post", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Schedule(customerID, gomock.Any()).Return(nil, datastore.NewPreconditionFailedError("post is already published"))
+			})
+
+			It("should return StatusPreconditionFailed", func() {
+				Expect(recorder.Code).To(Equal(http.StatusPreconditionFailed))
+			})
+		})
+
+		Context("with a valid schedule", func() {
+			BeforeEach(func() {
+				mockPoster.EXPECT().Schedule(customerID, gomock.Any()).DoAndReturn(func(_ string, post *dao.Post) (*dao.Post, error) {
+					Expect(*post.ID).To(Equal(postID))
+					Expect(post.ScheduledAt.Equal(scheduledAt)).To(BeTrue())
+					Expect(post.Channels).To(Equal([]string{"twitter", "linkedin"}))
+					Expect(post.UpdatedBy).To(Equal(customerID))
+					return post, nil
+				})
+			})
+
+			It("should return the scheduled post", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"channels":["twitter","linkedin"]`))
+			})
+		})
+	})
+
+	Describe("Unschedule", func() {
+		var (
+			postID bson.ObjectId
+			err    error
+		)
+
+		BeforeEach(func() {
+			postID = bson.NewObjectId()
+			req, err = http.NewRequest("DELETE", "/post/"+postID.Hex()+"/schedule", nil)
+			Expect(err).To(BeNil())
+			req.Header.Add(customerIDHeader, customerID)
+			mockPoster.EXPECT().Schedule(customerID, &dao.Post{ID: &postID, UpdatedBy: customerID}).Return(&dao.Post{ID: &postID}, nil)
+		})
+
+		JustBeforeEach(func() {
+			router.ServeHTTP(recorder, req)
+		})
+
+		It("should remove the schedule", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+	})
 })
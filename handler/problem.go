@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+const problemContentType = "application/problem+json"
+
+// Codes for problems that do not come from a typed error
+const (
+	codeInternal             = "internal"
+	codeRateLimited          = "rate_limited"
+	codeIdempotencyKeyReused = "idempotency_key_reused"
+)
+
+// Problem is an RFC 7807 problem details response body. Code is a stable, machine
+// readable extension member clients can switch on
+type Problem struct {
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	Status   int                    `json:"status"`
+	Detail   string                 `json:"detail,omitempty"`
+	Instance string                 `json:"instance,omitempty"`
+	Code     string                 `json:"code"`
+	Errors   []datastore.FieldError `json:"errors,omitempty"`
+}
+
+// setProblem writes an application/problem+json response
+func setProblem(c *gin.Context, status int, code, detail string, fields []datastore.FieldError) {
+	writeProblem(c, newProblem(c, status, code, detail, fields))
+}
+
+func newProblem(c *gin.Context, status int, code, detail string, fields []datastore.FieldError) *Problem {
+	return &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+		Errors:   fields,
+	}
+}
+
+func writeProblem(c *gin.Context, problem *Problem) {
+	c.Header("Content-Type", problemContentType)
+	c.JSON(problem.Status, problem)
+}
+
+// abortWithProblem writes the problem and stops the rest of the handler chain
+func abortWithProblem(c *gin.Context, status int, code, detail string, fields []datastore.FieldError) {
+	setProblem(c, status, code, detail, fields)
+	c.Abort()
+}
+
+// setReturnError maps typed errors onto problem responses. Untyped errors are
+// not returned to the caller, since they may leak internal details
+func setReturnError(err error, c *gin.Context) {
+	switch e := err.(type) {
+	case *datastore.QuotaExceeded:
+		setRetryAfter(c, time.Until(e.ResetsAt))
+	case *auth.Unauthorized:
+		c.Header("WWW-Authenticate", `Bearer, ApiKey header="`+auth.APIKeyHeader+`"`)
+	}
+	writeProblem(c, errorProblem(err, c))
+}
+
+// errorProblem returns the problem for a typed error, it is used on its own for
+// the items of a batch response
+func errorProblem(err error, c *gin.Context) *Problem {
+	var fields []datastore.FieldError
+
+	switch e := err.(type) {
+	case *datastore.Validation:
+		fields = e.Fields
+	}
+
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		c.Error(err)
+		return newProblem(c, status, codeInternal, "internal server error", nil)
+	}
+	return newProblem(c, status, err.(datastore.Coder).Code(), err.Error(), fields)
+}
+
+func errorStatus(err error) int {
+	switch err.(type) {
+	case *datastore.InvalidArugment, *datastore.Validation:
+		return http.StatusBadRequest
+	case *datastore.NotFound:
+		return http.StatusNotFound
+	case *datastore.Conflict:
+		return http.StatusConflict
+	case *datastore.PreconditionFailed:
+		return http.StatusPreconditionFailed
+	case *datastore.Unavailable:
+		return http.StatusServiceUnavailable
+	case *datastore.QuotaExceeded:
+		return http.StatusTooManyRequests
+	case *auth.Unauthorized:
+		return http.StatusUnauthorized
+	case *auth.Forbidden:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("setReturnError", func() {
+	var (
+		router   *gin.Engine
+		recorder *httptest.ResponseRecorder
+		err      error
+	)
+
+	BeforeEach(func() {
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.GET("/error", func(c *gin.Context) {
+			setReturnError(err, c)
+		})
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/error", nil))
+	})
+
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{datastore.NewInvalidArugmentError("postID"), http.StatusBadRequest, datastore.CodeInvalidArgument},
+		{datastore.NewNotFoundError("post"), http.StatusNotFound, datastore.CodeNotFound},
+		{datastore.NewConflictError("post changed"), http.StatusConflict, datastore.CodeConflict},
+		{datastore.NewPreconditionFailedError("etag"), http.StatusPreconditionFailed, datastore.CodePreconditionFailed},
+		{datastore.NewUnavailableError("datastore"), http.StatusServiceUnavailable, datastore.CodeUnavailable},
+		{auth.NewUnauthorizedError("no key"), http.StatusUnauthorized, auth.CodeUnauthenticated},
+		{auth.NewForbiddenError("no scope"), http.StatusForbidden, auth.CodeForbidden},
+	}
+	for _, tc := range cases {
+		tc := tc
+		Context("with "+tc.code+" error", func() {
+			BeforeEach(func() {
+				err = tc.err
+			})
+
+			It("should map the error to a problem", func() {
+				Expect(recorder.Code).To(Equal(tc.status))
+				expectProblem(recorder, tc.code, tc.err.Error())
+			})
+		})
+	}
+
+	Context("with validation error", func() {
+		BeforeEach(func() {
+			verr := datastore.NewValidationError()
+			verr.Add("url", "must be absolute")
+			verr.Add("captions[0]", "too long")
+			err = verr
+		})
+
+		It("should return the invalid fields", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: url: must be absolute; captions[0]: too long")
+			Expect(recorder.Body.String()).To(ContainSubstring(`"errors":[{"field":"url","message":"must be absolute"},{"field":"captions[0]","message":"too long"}]`))
+		})
+	})
+
+	Context("with quota exceeded error", func() {
+		BeforeEach(func() {
+			err = datastore.NewQuotaExceededError("generations", time.Now().Add(time.Minute))
+		})
+
+		It("should set Retry-After", func() {
+			Expect(recorder.Code).To(Equal(http.StatusTooManyRequests))
+			Expect(recorder.Header().Get("Retry-After")).To(Equal("60"))
+		})
+	})
+
+	Context("with untyped error", func() {
+		BeforeEach(func() {
+			err = errors.New("connection refused at 10.0.0.1")
+		})
+
+		It("should NOT leak the error", func() {
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			expectProblem(recorder, codeInternal, "internal server error")
+		})
+	})
+})
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/quality"
+)
+
+// qualityRulesResponse is the rules generated captions are checked against.
+// Default is set when the customer has no rules of their own
+type qualityRulesResponse struct {
+	*quality.Config
+	Default bool `json:"default"`
+}
+
+// QualityRuler defines the interface to handle caption quality rule requests
+type QualityRuler interface {
+	Get(*gin.Context)
+	Put(*gin.Context)
+	Delete(*gin.Context)
+}
+
+// DefaultQualityRuler implements the QualityRuler interface
+type DefaultQualityRuler struct {
+	checker *quality.Checker
+	store   quality.Store
+}
+
+// NewDefaultQualityRuler returns a DefaultQualityRuler with the provided options.
+// The checker gives the rules of customers without rules in the store
+func NewDefaultQualityRuler(checker *quality.Checker, store quality.Store) *DefaultQualityRuler {
+	return &DefaultQualityRuler{
+		checker: checker,
+		store:   store,
+	}
+}
+
+// Get defines the handler for the rules the customer's generated captions are
+// checked against
+func (q *DefaultQualityRuler) Get(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	config, isDefault, err := q.checker.Config(customerID)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, &qualityRulesResponse{config, isDefault})
+	return
+}
+
+// Put defines the handler for replacing the customer's rules
+func (q *DefaultQualityRuler) Put(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	req := &quality.Config{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+
+	config, err := q.store.Put(customerID, req)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, &qualityRulesResponse{config, false})
+	return
+}
+
+// Delete defines the handler for removing the customer's rules, so the default
+// rules apply to them again
+func (q *DefaultQualityRuler) Delete(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	if err := q.store.Delete(customerID); err != nil {
+		if _, ok := err.(*datastore.NotFound); !ok {
+			setReturnError(err, c)
+			return
+		}
+	}
+	c.Status(http.StatusNoContent)
+	return
+}
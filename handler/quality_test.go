@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/quality"
+)
+
+var _ = Describe("DefaultQualityRuler", func() {
+	var (
+		store      *quality.InMemoryStore
+		handler    *DefaultQualityRuler
+		router     *gin.Engine
+		customerID string
+		recorder   *httptest.ResponseRecorder
+		req        *http.Request
+	)
+
+	BeforeEach(func() {
+		store = newQualityStore()
+		handler = NewDefaultQualityRuler(quality.NewChecker(store, quality.DefaultConfig()), store)
+		customerID = "test-customer"
+		recorder = httptest.NewRecorder()
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.Use(fakeAuthenticator)
+		router.GET("/quality/rules", handler.Get)
+		router.PUT("/quality/rules", handler.Put)
+		router.DELETE("/quality/rules", handler.Delete)
+	})
+
+	JustBeforeEach(func() {
+		router.ServeHTTP(recorder, req)
+	})
+
+	Describe("Get", func() {
+		Context("without customerID in header", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/quality/rules", nil)
+			})
+
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+				expectProblem(recorder, auth.CodeUnauthenticated, "unauthorized: request is not authenticated")
+			})
+		})
+
+		Context("without rules of the customer", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/quality/rules", nil)
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return the default rules", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				expected := `{"rules":[{"type":"profanity","action":"reject"},{"type":"fragment","action":"flag"}],"default":true}`
+				Expect(strings.TrimSuffix(recorder.Body.String(), "\n")).To(Equal(expected))
+			})
+		})
+
+		Context("with rules of the customer", func() {
+			BeforeEach(func() {
+				_, err := store.Put(customerID, &quality.Config{Rules: []*quality.RuleConfig{{Type: quality.TypeReadability, Action: quality.ActionFlag, MinScore: 0.5}}})
+				Expect(err).To(BeNil())
+				req = httptest.NewRequest("GET", "/quality/rules", nil)
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return the customer's rules", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				expected := `{"rules":[{"type":"readability","action":"flag","min_score":0.5}],"default":false}`
+				Expect(strings.TrimSuffix(recorder.Body.String(), "\n")).To(Equal(expected))
+			})
+		})
+	})
+
+	Describe("Put", func() {
+		Context("with json error", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("PUT", "/quality/rules", strings.NewReader(`{"rules":`))
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with invalid rules", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("PUT", "/quality/rules", strings.NewReader(`{"rules":[{"type":"brand_terms","action":"fix","terms":["Acme"]}]}`))
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return every invalid field without storing the rules", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: rules[0].action: brand_terms rules can not fix captions")
+				_, err := store.Get(customerID)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with valid rules", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("PUT", "/quality/rules", strings.NewReader(`{"rules":[{"type":"blocklist","action":"fix","terms":[" cheap "]}]}`))
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should store and return the trimmed rules", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				expected := `{"rules":[{"type":"blocklist","action":"fix","terms":["cheap"]}],"default":false}`
+				Expect(strings.TrimSuffix(recorder.Body.String(), "\n")).To(Equal(expected))
+				config, err := store.Get(customerID)
+				Expect(err).To(BeNil())
+				Expect(config.Rules[0].Terms).To(Equal([]string{"cheap"}))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("DELETE", "/quality/rules", nil)
+			req.Header.Add(customerIDHeader, customerID)
+		})
+
+		Context("with rules of the customer", func() {
+			BeforeEach(func() {
+				_, err := store.Put(customerID, quality.DefaultConfig())
+				Expect(err).To(BeNil())
+			})
+
+			It("should remove the rules", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNoContent))
+				_, err := store.Get(customerID)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("without rules of the customer", func() {
+			It("should return StatusNoContent", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNoContent))
+			})
+		})
+	})
+})
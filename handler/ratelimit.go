@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/ratelimit"
+)
+
+// NewRateLimiter returns middleware that rate limits requests per customer. It
+// must run after authentication, since the customer is the rate limit key
+func NewRateLimiter(limiter ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		customerID := getCustomerID(c)
+		if customerID == "" {
+			c.Abort()
+			return
+		}
+
+		res := limiter.Allow(customerID)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+		if !res.Allowed {
+			setRetryAfter(c, res.RetryAfter)
+			abortWithProblem(c, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounding up so
+// clients never retry too early
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_ratelimit "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/ratelimit"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var (
+		mockCtrl    *gomock.Controller
+		mockLimiter *mock_ratelimit.MockLimiter
+		router      *gin.Engine
+		recorder    *httptest.ResponseRecorder
+		req         *http.Request
+		customerID  string
+		reset       time.Time
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockLimiter = mock_ratelimit.NewMockLimiter(mockCtrl)
+		recorder = httptest.NewRecorder()
+		customerID = "test-customer"
+		reset = time.Unix(1577836800, 0)
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.GET("/limited", fakeAuthenticator, NewRateLimiter(mockLimiter), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		req = httptest.NewRequest("GET", "/limited", nil)
+		req.Header.Add(customerIDHeader, customerID)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		router.ServeHTTP(recorder, req)
+	})
+
+	Context("with request allowed", func() {
+		BeforeEach(func() {
+			mockLimiter.EXPECT().Allow(customerID).Return(ratelimit.Result{
+				Allowed:   true,
+				Limit:     10,
+				Remaining: 9,
+				Reset:     reset,
+			})
+		})
+
+		It("should return StatusOK", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should set the rate limit headers", func() {
+			Expect(recorder.Header().Get("X-RateLimit-Limit")).To(Equal("10"))
+			Expect(recorder.Header().Get("X-RateLimit-Remaining")).To(Equal("9"))
+			Expect(recorder.Header().Get("X-RateLimit-Reset")).To(Equal("1577836800"))
+		})
+	})
+
+	Context("with request denied", func() {
+		BeforeEach(func() {
+			mockLimiter.EXPECT().Allow(customerID).Return(ratelimit.Result{
+				Allowed:    false,
+				Limit:      10,
+				Remaining:  0,
+				RetryAfter: 1500 * time.Millisecond,
+				Reset:      reset,
+			})
+		})
+
+		It("should return StatusTooManyRequests", func() {
+			Expect(recorder.Code).To(Equal(http.StatusTooManyRequests))
+		})
+
+		It("should round Retry-After up", func() {
+			Expect(recorder.Header().Get("Retry-After")).To(Equal("2"))
+			Expect(recorder.Header().Get("X-RateLimit-Remaining")).To(Equal("0"))
+		})
+
+		It("should return a useful message", func() {
+			expectProblem(recorder, codeRateLimited, "rate limit exceeded")
+		})
+	})
+})
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/templates"
+)
+
+type templateRequest struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
+}
+
+// Templater defines the interface to handle caption template requests
+type Templater interface {
+	Create(*gin.Context)
+	List(*gin.Context)
+	Get(*gin.Context)
+	Update(*gin.Context)
+	Delete(*gin.Context)
+}
+
+// DefaultTemplater implements the Templater interface
+type DefaultTemplater struct {
+	store templates.Store
+}
+
+// NewDefaultTemplater returns a DefaultTemplater with the provided options
+func NewDefaultTemplater(store templates.Store) *DefaultTemplater {
+	return &DefaultTemplater{
+		store: store,
+	}
+}
+
+// Create defines the handler for adding a template for the customer's captions
+func (t *DefaultTemplater) Create(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	req := &templateRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+
+	created, err := t.store.Create(customerID, &templates.Template{Name: req.Name, Text: req.Text})
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusCreated, created)
+	return
+}
+
+// List defines the handler for listing the customer's templates
+func (t *DefaultTemplater) List(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	list, err := t.store.List(customerID)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, list)
+	return
+}
+
+// Get defines the handler for retrieving a template
+func (t *DefaultTemplater) Get(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	found, err := t.store.Get(customerID, id)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, found)
+	return
+}
+
+// Update defines the handler for changing the name and text of a template
+func (t *DefaultTemplater) Update(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	req := &templateRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+
+	updated, err := t.store.Update(customerID, &templates.Template{ID: id, Name: req.Name, Text: req.Text})
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, updated)
+	return
+}
+
+// Delete defines the handler for removing a template, posts rendered with it
+// keep their captions
+func (t *DefaultTemplater) Delete(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	if err := t.store.Delete(customerID, id); err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	return
+}
+
+func templateID(c *gin.Context) (bson.ObjectId, bool) {
+	id := c.Param("id")
+	if !bson.IsObjectIdHex(id) {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid template id", nil)
+		return "", false
+	}
+	return bson.ObjectIdHex(id), true
+}
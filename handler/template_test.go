@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/templates"
+)
+
+var _ = Describe("DefaultTemplater", func() {
+	var (
+		store      *templates.InMemoryStore
+		handler    *DefaultTemplater
+		router     *gin.Engine
+		customerID string
+		recorder   *httptest.ResponseRecorder
+		req        *http.Request
+		tmpl       *templates.Template
+	)
+
+	BeforeEach(func() {
+		store = newTemplateStore()
+		handler = NewDefaultTemplater(store)
+		customerID = "test-customer"
+		recorder = httptest.NewRecorder()
+		var err error
+		tmpl, err = store.Create(customerID, &templates.Template{Name: "house", Text: "{{summary}} {{short_url}}"})
+		Expect(err).To(BeNil())
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.Use(fakeAuthenticator)
+		router.GET("/templates", handler.List)
+		router.POST("/templates", handler.Create)
+		router.GET("/templates/:id", handler.Get)
+		router.PUT("/templates/:id", handler.Update)
+		router.DELETE("/templates/:id", handler.Delete)
+	})
+
+	JustBeforeEach(func() {
+		router.ServeHTTP(recorder, req)
+	})
+
+	Describe("Create", func() {
+		Context("without customerID in header", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("POST", "/templates", strings.NewReader(`{}`))
+			})
+
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+				expectProblem(recorder, auth.CodeUnauthenticated, "unauthorized: request is not authenticated")
+			})
+		})
+
+		Context("with an invalid template", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("POST", "/templates", strings.NewReader(`{"name":"house","text":"{{template \"x\"}}"}`))
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: text: must not call templates")
+			})
+		})
+
+		Context("with a template that ranges over a number", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("POST", "/templates", strings.NewReader(`{"name":"house","text":"{{range 2000000000}}x{{end}}"}`))
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: text: ranges must be over captions or keywords")
+			})
+		})
+
+		Context("with a valid template", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("POST", "/templates", strings.NewReader(`{"name":"short","text":"{{summary | truncate 100}}"}`))
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return the template", func() {
+				Expect(recorder.Code).To(Equal(http.StatusCreated))
+				created := &templates.Template{}
+				Expect(json.Unmarshal(recorder.Body.Bytes(), created)).To(Succeed())
+				Expect(created.ID).NotTo(BeEmpty())
+				Expect(created.Name).To(Equal("short"))
+				Expect(recorder.Body.String()).NotTo(ContainSubstring(customerID))
+			})
+		})
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/templates", nil)
+			req.Header.Add(customerIDHeader, customerID)
+		})
+
+		It("should return the customer's templates", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			list := []*templates.Template{}
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &list)).To(Succeed())
+			Expect(list).To(HaveLen(1))
+			Expect(list[0].ID).To(Equal(tmpl.ID))
+		})
+	})
+
+	Describe("Get", func() {
+		Context("with an invalid id", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/templates/nope", nil)
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeInvalidArgument, "invalid template id")
+			})
+		})
+
+		Context("with the template of another customer", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/templates/"+tmpl.ID.Hex(), nil)
+				req.Header.Add(customerIDHeader, "other-customer")
+			})
+
+			It("should return StatusNotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("PUT", "/templates/"+tmpl.ID.Hex(), strings.NewReader(`{"name":"renamed","text":"{{summary}}"}`))
+			req.Header.Add(customerIDHeader, customerID)
+		})
+
+		It("should return the updated template", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			updated, err := store.Get(customerID, tmpl.ID)
+			Expect(err).To(BeNil())
+			Expect(updated.Name).To(Equal("renamed"))
+			Expect(updated.Text).To(Equal("{{summary}}"))
+		})
+	})
+
+	Describe("Delete", func() {
+		Context("with an existing template", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("DELETE", "/templates/"+tmpl.ID.Hex(), nil)
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return StatusNoContent", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNoContent))
+			})
+		})
+
+		Context("with an unknown template", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("DELETE", "/templates/"+bson.NewObjectId().Hex(), nil)
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return StatusNotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/ratelimit"
+)
+
+// UsageReporter defines the interface to handle usage requests
+type UsageReporter interface {
+	Get(*gin.Context)
+	List(*gin.Context)
+}
+
+// DefaultUsageReporter implements the UsageReporter interface
+type DefaultUsageReporter struct {
+	quota ratelimit.Quota
+}
+
+// NewDefaultUsageReporter returns a DefaultUsageReporter with the provided options
+func NewDefaultUsageReporter(quota ratelimit.Quota) *DefaultUsageReporter {
+	return &DefaultUsageReporter{
+		quota: quota,
+	}
+}
+
+// Get defines the handler for the authenticated customer's current usage
+func (u *DefaultUsageReporter) Get(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	usage, err := u.quota.Usage(customerID)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, usage)
+	return
+}
+
+// List defines the admin handler for every customer's current usage
+func (u *DefaultUsageReporter) List(c *gin.Context) {
+	usages, err := u.quota.All()
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, usages)
+	return
+}
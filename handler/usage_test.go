@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_ratelimit "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/ratelimit"
+)
+
+var _ = Describe("DefaultUsageReporter", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockQuota  *mock_ratelimit.MockQuota
+		handler    *DefaultUsageReporter
+		router     *gin.Engine
+		recorder   *httptest.ResponseRecorder
+		req        *http.Request
+		customerID string
+		usage      ratelimit.Usage
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockQuota = mock_ratelimit.NewMockQuota(mockCtrl)
+		handler = NewDefaultUsageReporter(mockQuota)
+		recorder = httptest.NewRecorder()
+		customerID = "test-customer"
+		usage = ratelimit.Usage{
+			CustomerID: customerID,
+			Period:     "2020-01",
+			Used:       3,
+			Limit:      10,
+			ResetsAt:   time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		}
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.Use(fakeAuthenticator)
+		router.GET("/usage", handler.Get)
+		router.GET("/admin/usage", handler.List)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		router.ServeHTTP(recorder, req)
+	})
+
+	Describe("Get", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/usage", nil)
+			req.Header.Add(customerIDHeader, customerID)
+			mockQuota.EXPECT().Usage(customerID).Return(usage, nil)
+		})
+
+		It("should return the customer's usage", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			expected := `{"customer_id":"test-customer","period":"2020-01","used":3,"limit":10,"resets_at":"2020-02-01T00:00:00Z"}`
+			actual := strings.TrimSuffix(recorder.Body.String(), "\n")
+			Expect(actual).To(Equal(expected))
+		})
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/admin/usage", nil)
+			mockQuota.EXPECT().All().Return([]ratelimit.Usage{usage}, nil)
+		})
+
+		It("should return every customer's usage", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(HavePrefix(`[{"customer_id":"test-customer"`))
+		})
+	})
+})
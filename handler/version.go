@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+const (
+	postMapperKey     = "postMapper"
+	revisionMapperKey = "revisionMapper"
+)
+
+// PostMapper maps a post onto its representation in a version of the api.
+// Versions share the handlers and the DAO and only differ in what they respond
+// with
+type PostMapper func(*dao.Post) interface{}
+
+// RevisionMapper maps a revision onto its representation in a version of the api
+type RevisionMapper func(*dao.Revision) interface{}
+
+// v1Post is a post with the texts of its captions, as the /v1 routes responded
+// before captions were objects
+type v1Post struct {
+	*dao.Post
+	Captions []string `json:"captions,omitempty"`
+}
+
+// V1Post is the representation of the /v1 routes, the post as it is stored with
+// only the texts of its captions
+func V1Post(post *dao.Post) interface{} {
+	return &v1Post{Post: post, Captions: dao.CaptionTexts(post.Captions)}
+}
+
+// V2Post is the representation of the /v2 routes, the post as it is stored
+func V2Post(post *dao.Post) interface{} {
+	return post
+}
+
+// v1Revision is a revision with the texts of its captions
+type v1Revision struct {
+	*dao.Revision
+	Captions []string `json:"captions"`
+}
+
+// V1Revision is the representation of the /v1 routes, the revision as it is
+// stored with only the texts of its captions
+func V1Revision(revision *dao.Revision) interface{} {
+	return &v1Revision{Revision: revision, Captions: dao.CaptionTexts(revision.Captions)}
+}
+
+// V2Revision is the representation of the /v2 routes, the revision as it is
+// stored
+func V2Revision(revision *dao.Revision) interface{} {
+	return revision
+}
+
+// NewVersion returns middleware that makes the handlers after it respond with
+// the posts and revisions of the mappers
+func NewVersion(postMapper PostMapper, revisionMapper RevisionMapper) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(postMapperKey, postMapper)
+		c.Set(revisionMapperKey, revisionMapper)
+		c.Next()
+	}
+}
+
+// NewDeprecation returns middleware that marks the routes after it as
+// deprecated. Responses get the Deprecation and Sunset headers and link to the
+// same path under successorPrefix
+func NewDeprecation(sunset time.Time, successorPrefix string) gin.HandlerFunc {
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Sunset", sunsetHeader)
+		c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, c.Request.URL.Path))
+		c.Next()
+	}
+}
+
+// mapPost returns the representation of the post for the version of the route.
+// Routes without a version respond with V1Post
+func mapPost(c *gin.Context, post *dao.Post) interface{} {
+	if post == nil {
+		return nil
+	}
+	if v, ok := c.Get(postMapperKey); ok {
+		if mapper, ok := v.(PostMapper); ok {
+			return mapper(post)
+		}
+	}
+	return V1Post(post)
+}
+
+// mapPosts returns the representation of every post, keeping a nil list nil
+func mapPosts(c *gin.Context, posts []*dao.Post) []interface{} {
+	if posts == nil {
+		return nil
+	}
+	mapped := make([]interface{}, len(posts))
+	for i, post := range posts {
+		mapped[i] = mapPost(c, post)
+	}
+	return mapped
+}
+
+// mapRevisions returns the representation of every revision for the version of
+// the route, keeping a nil list nil. Routes without a version respond with
+// V1Revision
+func mapRevisions(c *gin.Context, revisions []*dao.Revision) []interface{} {
+	if revisions == nil {
+		return nil
+	}
+	mapper := RevisionMapper(V1Revision)
+	if v, ok := c.Get(revisionMapperKey); ok {
+		if m, ok := v.(RevisionMapper); ok {
+			mapper = m
+		}
+	}
+	mapped := make([]interface{}, len(revisions))
+	for i, revision := range revisions {
+		mapped[i] = mapper(revision)
+	}
+	return mapped
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/validate"
+)
+
+// testV3Post is a representation a later version could respond with
+type testV3Post struct {
+	ID       string `json:"id"`
+	Link     string `json:"link"`
+	Captions int    `json:"caption_count"`
+}
+
+func testV3Mapper(post *dao.Post) interface{} {
+	return &testV3Post{ID: post.ID.Hex(), Link: post.URL, Captions: len(post.Captions)}
+}
+
+var _ = Describe("Versions", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockPoster *mock_dao.MockPoster
+		router     *gin.Engine
+		customerID string
+		postID     bson.ObjectId
+		dsPost     *dao.Post
+		sunset     time.Time
+	)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Add(customerIDHeader, customerID)
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPoster = mock_dao.NewMockPoster(mockCtrl)
+		customerID = "test-customer"
+		postID = bson.ObjectIdHex("5e154899cb80cb0001000003")
+		dsPost = &dao.Post{ID: &postID, URL: "https://example.com/post", Captions: dao.ManualCaptions([]string{"caption1", "caption2"})}
+		sunset = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		p := NewDefaultPoster(mockPoster, validate.NewValidator(validate.DefaultRules()))
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.Use(fakeAuthenticator)
+		v1 := router.Group("/v1", NewVersion(V1Post, V1Revision))
+		v1.GET("/post/:id", p.Get)
+		v1.GET("/post/:id/revisions", p.Revisions)
+		v2 := router.Group("/v2", NewVersion(V2Post, V2Revision))
+		v2.GET("/post/:id", p.Get)
+		v2.GET("/post/:id/revisions", p.Revisions)
+		v3 := router.Group("/v3", NewVersion(testV3Mapper, V2Revision))
+		v3.GET("/post/:id", p.Get)
+		v3.GET("/posts", p.List)
+		legacy := router.Group("/", NewDeprecation(sunset, "/v1"), NewVersion(V1Post, V1Revision))
+		legacy.GET("/post/:id", p.Get)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("should respond with the texts of the captions on v1", func() {
+		mockPoster.EXPECT().Get(customerID, postID).Return(dsPost, nil)
+		recorder := get("/v1/post/" + postID.Hex())
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"id": "5e154899cb80cb0001000003", "url": "https://example.com/post", "captions": ["caption1", "caption2"]}`))
+		Expect(recorder.Header().Get("Deprecation")).To(BeEmpty())
+
+		post := struct {
+			Captions []string `json:"captions"`
+		}{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &post)).To(Succeed())
+		Expect(post.Captions).To(Equal([]string{"caption1", "caption2"}))
+	})
+
+	It("should respond with the post as it is stored on v2", func() {
+		mockPoster.EXPECT().Get(customerID, postID).Return(dsPost, nil)
+		recorder := get("/v2/post/" + postID.Hex())
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"id": "5e154899cb80cb0001000003", "url": "https://example.com/post", "captions": [
+			{"id": "", "text": "caption1", "source": "manual", "selected": false, "pinned": false},
+			{"id": "", "text": "caption2", "source": "manual", "selected": false, "pinned": false}
+		]}`))
+	})
+
+	It("should respond with the texts of the captions of revisions on v1 and the captions on v2", func() {
+		revisions := []*dao.Revision{{Number: 1, PostID: postID, CreatedAt: time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC), Captions: dsPost.Captions}}
+		mockPoster.EXPECT().Revisions(customerID, postID).Return(revisions, nil).Times(2)
+
+		recorder := get("/v1/post/" + postID.Hex() + "/revisions")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`[{"number": 1, "post_id": "5e154899cb80cb0001000003", "created_at": "2020-01-02T00:00:00Z", "captions": ["caption1", "caption2"], "diff": null}]`))
+
+		recorder = get("/v2/post/" + postID.Hex() + "/revisions")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`[{"number": 1, "post_id": "5e154899cb80cb0001000003", "created_at": "2020-01-02T00:00:00Z", "captions": [
+			{"id": "", "text": "caption1", "source": "manual", "selected": false, "pinned": false},
+			{"id": "", "text": "caption2", "source": "manual", "selected": false, "pinned": false}
+		], "diff": null}]`))
+	})
+
+	It("should respond with the representation of the mapper", func() {
+		mockPoster.EXPECT().Get(customerID, postID).Return(dsPost, nil)
+		recorder := get("/v3/post/" + postID.Hex())
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"id": "5e154899cb80cb0001000003", "link": "https://example.com/post", "caption_count": 2}`))
+	})
+
+	It("should map every post of a list", func() {
+		mockPoster.EXPECT().List(customerID, dao.ListOptions{Limit: defaultListLimit}).Return([]*dao.Post{dsPost}, nil)
+		recorder := get("/v3/posts")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"posts": [{"id": "5e154899cb80cb0001000003", "link": "https://example.com/post", "caption_count": 2}]}`))
+	})
+
+	It("should mark deprecated routes and link to their successor", func() {
+		mockPoster.EXPECT().Get(customerID, postID).Return(dsPost, nil)
+		recorder := get("/post/" + postID.Hex())
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"id": "5e154899cb80cb0001000003", "url": "https://example.com/post", "captions": ["caption1", "caption2"]}`))
+		Expect(recorder.Header().Get("Deprecation")).To(Equal("true"))
+		Expect(recorder.Header().Get("Sunset")).To(Equal("Fri, 01 Jan 2021 00:00:00 GMT"))
+		Expect(recorder.Header().Get("Link")).To(Equal(`</v1/post/5e154899cb80cb0001000003>; rel="successor-version"`))
+	})
+
+	It("should mark deprecated routes that fail", func() {
+		recorder := get("/post/invalid")
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		Expect(recorder.Header().Get("Deprecation")).To(Equal("true"))
+	})
+})
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/webhook"
+)
+
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+// webhookResponse is returned when a subscription is created. This is the only
+// time the signing secret is returned to the caller
+type webhookResponse struct {
+	*webhook.Subscription
+	Secret string `json:"secret"`
+}
+
+// Webhooker defines the interface to handle webhook subscription requests
+type Webhooker interface {
+	Create(*gin.Context)
+	List(*gin.Context)
+	Get(*gin.Context)
+	Update(*gin.Context)
+	Delete(*gin.Context)
+	Deliveries(*gin.Context)
+	Redeliver(*gin.Context)
+}
+
+// DefaultWebhooker implements the Webhooker interface
+type DefaultWebhooker struct {
+	store webhook.Store
+}
+
+// NewDefaultWebhooker returns a DefaultWebhooker with the provided options
+func NewDefaultWebhooker(store webhook.Store) *DefaultWebhooker {
+	return &DefaultWebhooker{
+		store: store,
+	}
+}
+
+// Create defines the handler for subscribing a url to the customer's post events
+func (w *DefaultWebhooker) Create(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	req := &webhookRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+
+	sub, err := w.store.Create(customerID, &webhook.Subscription{URL: req.URL, EventTypes: req.EventTypes})
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusCreated, &webhookResponse{sub, sub.Secret})
+	return
+}
+
+// List defines the handler for listing the customer's subscriptions
+func (w *DefaultWebhooker) List(c *gin.Context) {
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	subs, err := w.store.List(customerID)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, subs)
+	return
+}
+
+// Get defines the handler for retrieving a subscription
+func (w *DefaultWebhooker) Get(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	sub, err := w.store.Get(customerID, id)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, sub)
+	return
+}
+
+// Update defines the handler for changing the url and event types of a subscription
+func (w *DefaultWebhooker) Update(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	req := &webhookRequest{}
+	if err := c.BindJSON(req); err != nil {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+
+	sub, err := w.store.Update(customerID, &webhook.Subscription{ID: id, URL: req.URL, EventTypes: req.EventTypes})
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, sub)
+	return
+}
+
+// Delete defines the handler for removing a subscription
+func (w *DefaultWebhooker) Delete(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	if err := w.store.Delete(customerID, id); err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	return
+}
+
+// Deliveries defines the handler for the delivery log of a subscription, newest
+// first. ?status=dead lists the dead letters
+func (w *DefaultWebhooker) Deliveries(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", webhook.StatusPending, webhook.StatusSucceeded, webhook.StatusDead:
+	default:
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid status", nil)
+		return
+	}
+
+	deliveries, err := w.store.Deliveries(customerID, id, status)
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusOK, deliveries)
+	return
+}
+
+// Redeliver defines the handler for sending a dead delivery again
+func (w *DefaultWebhooker) Redeliver(c *gin.Context) {
+	deliveryID := c.Param("delivery_id")
+	if !bson.IsObjectIdHex(deliveryID) {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid delivery id", nil)
+		return
+	}
+	customerID := getCustomerID(c)
+	if customerID == "" {
+		return
+	}
+
+	delivery, err := w.store.Redeliver(customerID, bson.ObjectIdHex(deliveryID))
+	if err != nil {
+		setReturnError(err, c)
+		return
+	}
+	c.PureJSON(http.StatusAccepted, delivery)
+	return
+}
+
+func webhookID(c *gin.Context) (bson.ObjectId, bool) {
+	id := c.Param("id")
+	if !bson.IsObjectIdHex(id) {
+		setProblem(c, http.StatusBadRequest, datastore.CodeInvalidArgument, "invalid webhook id", nil)
+		return "", false
+	}
+	return bson.ObjectIdHex(id), true
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+	mock_webhook "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/webhook"
+	"github.com/bpross/cc-hw/webhook"
+)
+
+var _ = Describe("DefaultWebhooker", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockStore  *mock_webhook.MockStore
+		handler    *DefaultWebhooker
+		router     *gin.Engine
+		customerID string
+		recorder   *httptest.ResponseRecorder
+		req        *http.Request
+		sub        *webhook.Subscription
+		subID      bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockStore = mock_webhook.NewMockStore(mockCtrl)
+		handler = NewDefaultWebhooker(mockStore)
+		customerID = "test-customer"
+		recorder = httptest.NewRecorder()
+		subID = bson.NewObjectId()
+		sub = &webhook.Subscription{
+			ID:         subID,
+			CustID:     customerID,
+			URL:        "https://example.com/hook",
+			EventTypes: []string{events.TypeStatusChanged},
+			Secret:     "whsec_secret",
+		}
+
+		gin.DefaultWriter = ioutil.Discard
+		router = gin.New()
+		router.Use(fakeAuthenticator)
+		router.GET("/webhooks", handler.List)
+		router.POST("/webhooks", handler.Create)
+		router.GET("/webhooks/:id", handler.Get)
+		router.PUT("/webhooks/:id", handler.Update)
+		router.DELETE("/webhooks/:id", handler.Delete)
+		router.GET("/webhooks/:id/deliveries", handler.Deliveries)
+		router.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handler.Redeliver)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	JustBeforeEach(func() {
+		router.ServeHTTP(recorder, req)
+	})
+
+	jsonBody := func(v interface{}) *bytes.Reader {
+		b, err := json.Marshal(v)
+		Expect(err).To(BeNil())
+		return bytes.NewReader(b)
+	}
+
+	Describe("Create", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("POST", "/webhooks", jsonBody(webhookRequest{
+				URL:        sub.URL,
+				EventTypes: sub.EventTypes,
+			}))
+			req.Header.Add("Content-Type", "application/json")
+		})
+
+		Context("without identity", func() {
+			It("should return StatusUnauthorized", func() {
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("with an invalid subscription", func() {
+			BeforeEach(func() {
+				req.Header.Add(customerIDHeader, customerID)
+				mockStore.EXPECT().Create(customerID, gomock.Any()).Return(nil,
+					datastore.NewValidationError(datastore.FieldError{Field: "url", Message: "must be http or https"}))
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeValidationFailed, "validation failed: url: must be http or https")
+			})
+		})
+
+		Context("with a valid subscription", func() {
+			BeforeEach(func() {
+				req.Header.Add(customerIDHeader, customerID)
+				mockStore.EXPECT().Create(customerID, &webhook.Subscription{
+					URL:        sub.URL,
+					EventTypes: sub.EventTypes,
+				}).Return(sub, nil)
+			})
+
+			It("should return the subscription and secret", func() {
+				Expect(recorder.Code).To(Equal(http.StatusCreated))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"id":"` + subID.Hex() + `"`))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"secret":"whsec_secret"`))
+			})
+		})
+	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "/webhooks", nil)
+			req.Header.Add(customerIDHeader, customerID)
+			mockStore.EXPECT().List(customerID).Return([]*webhook.Subscription{sub}, nil)
+		})
+
+		It("should not return the secret", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(ContainSubstring(`"url":"https://example.com/hook"`))
+			Expect(recorder.Body.String()).NotTo(ContainSubstring("whsec_secret"))
+		})
+	})
+
+	Describe("Get", func() {
+		Context("with an invalid id", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/webhooks/abc", nil)
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeInvalidArgument, "invalid webhook id")
+			})
+		})
+
+		Context("with subscription not found", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/webhooks/"+subID.Hex(), nil)
+				req.Header.Add(customerIDHeader, customerID)
+				mockStore.EXPECT().Get(customerID, subID).Return(nil, datastore.NewNotFoundError("subscription"))
+			})
+
+			It("should return StatusNotFound", func() {
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				expectProblem(recorder, datastore.CodeNotFound, "subscription not found")
+			})
+		})
+
+		Context("with subscription found", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/webhooks/"+subID.Hex(), nil)
+				req.Header.Add(customerIDHeader, customerID)
+				mockStore.EXPECT().Get(customerID, subID).Return(sub, nil)
+			})
+
+			It("should return the subscription", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"event_types":["status_changed"]`))
+				Expect(recorder.Body.String()).NotTo(ContainSubstring("whsec_secret"))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("PUT", "/webhooks/"+subID.Hex(), jsonBody(webhookRequest{URL: "https://example.com/other"}))
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add(customerIDHeader, customerID)
+			updated := *sub
+			updated.URL = "https://example.com/other"
+			mockStore.EXPECT().Update(customerID, &webhook.Subscription{ID: subID, URL: "https://example.com/other"}).Return(&updated, nil)
+		})
+
+		It("should return the subscription", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(ContainSubstring(`"url":"https://example.com/other"`))
+		})
+	})
+
+	Describe("Delete", func() {
+		BeforeEach(func() {
+			req = httptest.NewRequest("DELETE", "/webhooks/"+subID.Hex(), nil)
+			req.Header.Add(customerIDHeader, customerID)
+			mockStore.EXPECT().Delete(customerID, subID).Return(nil)
+		})
+
+		It("should return StatusNoContent", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNoContent))
+		})
+	})
+
+	Describe("Deliveries", func() {
+		Context("with an invalid status", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/webhooks/"+subID.Hex()+"/deliveries?status=lost", nil)
+				req.Header.Add(customerIDHeader, customerID)
+			})
+
+			It("should return StatusBadRequest", func() {
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				expectProblem(recorder, datastore.CodeInvalidArgument, "invalid status")
+			})
+		})
+
+		Context("with the dead letters", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "/webhooks/"+subID.Hex()+"/deliveries?status=dead", nil)
+				req.Header.Add(customerIDHeader, customerID)
+				mockStore.EXPECT().Deliveries(customerID, subID, webhook.StatusDead).Return([]*webhook.Delivery{{
+					ID:             bson.NewObjectId(),
+					SubscriptionID: subID,
+					Status:         webhook.StatusDead,
+					Attempts:       []*webhook.Attempt{{StatusCode: 500, Error: "returned status 500"}},
+					Payload:        []byte("{}"),
+				}}, nil)
+			})
+
+			It("should return the deliveries", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"status":"dead"`))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"error":"returned status 500"`))
+			})
+		})
+	})
+
+	Describe("Redeliver", func() {
+		var deliveryID bson.ObjectId
+
+		BeforeEach(func() {
+			deliveryID = bson.NewObjectId()
+			req = httptest.NewRequest("POST", "/webhooks/"+subID.Hex()+"/deliveries/"+deliveryID.Hex()+"/redeliver", nil)
+			req.Header.Add(customerIDHeader, customerID)
+		})
+
+		Context("with a delivery that is not dead", func() {
+			BeforeEach(func() {
+				mockStore.EXPECT().Redeliver(customerID, deliveryID).Return(nil,
+					datastore.NewPreconditionFailedError("only dead deliveries can be redelivered"))
+			})
+
+			It("should return StatusPreconditionFailed", func() {
+				Expect(recorder.Code).To(Equal(http.StatusPreconditionFailed))
+			})
+		})
+
+		Context("with a dead delivery", func() {
+			BeforeEach(func() {
+				mockStore.EXPECT().Redeliver(customerID, deliveryID).Return(&webhook.Delivery{
+					ID:     bson.NewObjectId(),
+					Status: webhook.StatusPending,
+				}, nil)
+			})
+
+			It("should return StatusAccepted", func() {
+				Expect(recorder.Code).To(Equal(http.StatusAccepted))
+				Expect(recorder.Body.String()).To(ContainSubstring(`"status":"pending"`))
+			})
+		})
+	})
+})
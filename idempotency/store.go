@@ -0,0 +1,151 @@
+package idempotency
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Record stores the state of a request made with an idempotency key
+type Record struct {
+	CustomerID  string
+	Key         string
+	Fingerprint string
+	Done        bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+
+	done chan struct{} // closed when the request completes or is released
+}
+
+// Store defines the interface for saving the responses of idempotent requests
+type Store interface {
+	Begin(string, string, string) (*Record, bool, error)
+	Complete(string, string, int, string, []byte) error
+	Release(string, string) error
+	Wait(string, string, time.Duration) (*Record, error)
+}
+
+// InMemoryStore implements the Store interface for in memory storage
+type InMemoryStore struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	records map[string]*Record
+	now     func() time.Time
+}
+
+// NewInMemoryStore creates an InMemoryStore that keeps responses for ttl
+func NewInMemoryStore(ttl time.Duration) *InMemoryStore {
+	return &InMemoryStore{
+		ttl:     ttl,
+		records: make(map[string]*Record),
+		now:     time.Now,
+	}
+}
+
+// Begin claims the key for a new request. If the key was already claimed, the
+// existing record is returned and started is false
+func (s *InMemoryStore) Begin(customerID, key, fingerprint string) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purge()
+	id := compositeID(customerID, key)
+	if r, ok := s.records[id]; ok {
+		return r.copy(), false, nil
+	}
+
+	r := &Record{
+		CustomerID:  customerID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   s.now().Add(s.ttl),
+		done:        make(chan struct{}),
+	}
+	s.records[id] = r
+	return r.copy(), true, nil
+}
+
+// Complete stores the response for the key, so it can be replayed
+func (s *InMemoryStore) Complete(customerID, key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[compositeID(customerID, key)]
+	if !ok || r.Done {
+		return fmt.Errorf("idempotency key %s is not in progress", key)
+	}
+	r.Done = true
+	r.StatusCode = statusCode
+	r.ContentType = contentType
+	r.Body = body
+	r.ExpiresAt = s.now().Add(s.ttl)
+	close(r.done)
+	return nil
+}
+
+// Release removes an in progress key, so the request can be retried
+func (s *InMemoryStore) Release(customerID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := compositeID(customerID, key)
+	r, ok := s.records[id]
+	if !ok || r.Done {
+		return nil
+	}
+	delete(s.records, id)
+	close(r.done)
+	return nil
+}
+
+// Wait blocks until the request holding the key finishes or the timeout passes.
+// It returns the latest record, which is nil if the key was released
+func (s *InMemoryStore) Wait(customerID, key string, timeout time.Duration) (*Record, error) {
+	id := compositeID(customerID, key)
+
+	s.mu.Lock()
+	r, ok := s.records[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	select {
+	case <-r.done:
+	case <-time.After(timeout):
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok = s.records[id]
+	if !ok {
+		return nil, nil
+	}
+	return r.copy(), nil
+}
+
+// purge drops expired records, it must be called with the lock held. Requests
+// that never finished expire as well, so a lost request can not hold a key forever
+func (s *InMemoryStore) purge() {
+	now := s.now()
+	for id, r := range s.records {
+		if now.After(r.ExpiresAt) {
+			if !r.Done {
+				close(r.done)
+			}
+			delete(s.records, id)
+		}
+	}
+}
+
+func (r *Record) copy() *Record {
+	c := *r
+	return &c
+}
+
+func compositeID(customerID, key string) string {
+	return fmt.Sprintf("%s:%s", customerID, key)
+}
@@ -0,0 +1,117 @@
+package idempotency
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InMemoryStore", func() {
+	var (
+		store      *InMemoryStore
+		now        time.Time
+		customerID string
+		key        string
+	)
+
+	BeforeEach(func() {
+		now = time.Unix(1577836800, 0)
+		store = NewInMemoryStore(time.Hour)
+		store.now = func() time.Time { return now }
+		customerID = "test-customer"
+		key = "test-key"
+	})
+
+	Describe("Begin", func() {
+		It("should claim a new key", func() {
+			record, started, err := store.Begin(customerID, key, "fingerprint")
+			Expect(err).To(BeNil())
+			Expect(started).To(BeTrue())
+			Expect(record.Done).To(BeFalse())
+		})
+
+		It("should return the existing record for a claimed key", func() {
+			_, _, err := store.Begin(customerID, key, "fingerprint")
+			Expect(err).To(BeNil())
+
+			record, started, err := store.Begin(customerID, key, "other")
+			Expect(err).To(BeNil())
+			Expect(started).To(BeFalse())
+			Expect(record.Fingerprint).To(Equal("fingerprint"))
+		})
+
+		It("should scope keys to the customer", func() {
+			_, _, err := store.Begin(customerID, key, "fingerprint")
+			Expect(err).To(BeNil())
+
+			_, started, err := store.Begin("other-customer", key, "fingerprint")
+			Expect(err).To(BeNil())
+			Expect(started).To(BeTrue())
+		})
+
+		It("should reclaim an expired key", func() {
+			_, _, err := store.Begin(customerID, key, "fingerprint")
+			Expect(err).To(BeNil())
+			Expect(store.Complete(customerID, key, 200, "application/json", []byte("{}"))).To(Succeed())
+
+			now = now.Add(2 * time.Hour)
+			_, started, err := store.Begin(customerID, key, "fingerprint")
+			Expect(err).To(BeNil())
+			Expect(started).To(BeTrue())
+		})
+	})
+
+	Describe("Complete", func() {
+		It("should store the response", func() {
+			_, _, err := store.Begin(customerID, key, "fingerprint")
+			Expect(err).To(BeNil())
+			Expect(store.Complete(customerID, key, 200, "application/json", []byte("{}"))).To(Succeed())
+
+			record, started, err := store.Begin(customerID, key, "fingerprint")
+			Expect(err).To(BeNil())
+			Expect(started).To(BeFalse())
+			Expect(record.Done).To(BeTrue())
+			Expect(record.StatusCode).To(Equal(200))
+			Expect(record.Body).To(Equal([]byte("{}")))
+		})
+
+		It("should fail for an unknown key", func() {
+			Expect(store.Complete(customerID, key, 200, "", nil)).NotTo(Succeed())
+		})
+	})
+
+	Describe("Wait", func() {
+		BeforeEach(func() {
+			_, _, err := store.Begin(customerID, key, "fingerprint")
+			Expect(err).To(BeNil())
+		})
+
+		It("should return once the request completes", func() {
+			go func() {
+				defer GinkgoRecover()
+				Expect(store.Complete(customerID, key, 201, "", nil)).To(Succeed())
+			}()
+			record, err := store.Wait(customerID, key, time.Second)
+			Expect(err).To(BeNil())
+			Expect(record.Done).To(BeTrue())
+			Expect(record.StatusCode).To(Equal(201))
+		})
+
+		It("should return nil once the request is released", func() {
+			go func() {
+				defer GinkgoRecover()
+				Expect(store.Release(customerID, key)).To(Succeed())
+			}()
+			record, err := store.Wait(customerID, key, time.Second)
+			Expect(err).To(BeNil())
+			Expect(record).To(BeNil())
+		})
+
+		It("should return the in progress record after the timeout", func() {
+			record, err := store.Wait(customerID, key, 10*time.Millisecond)
+			Expect(err).To(BeNil())
+			Expect(record.Done).To(BeFalse())
+		})
+	})
+})
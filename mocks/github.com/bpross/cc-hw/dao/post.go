@@ -9,6 +9,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	bson "labix.org/v2/mgo/bson"
 	reflect "reflect"
+	time "time"
 )
 
 // MockPoster is a mock of Poster interface
@@ -49,6 +50,21 @@ func (mr *MockPosterMockRecorder) Insert(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockPoster)(nil).Insert), arg0, arg1)
 }
 
+// InsertBatch mocks base method
+func (m *MockPoster) InsertBatch(arg0 string, arg1 []*dao.Post) ([]*dao.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertBatch", arg0, arg1)
+	ret0, _ := ret[0].([]*dao.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertBatch indicates an expected call of InsertBatch
+func (mr *MockPosterMockRecorder) InsertBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertBatch", reflect.TypeOf((*MockPoster)(nil).InsertBatch), arg0, arg1)
+}
+
 // Get mocks base method
 func (m *MockPoster) Get(arg0 string, arg1 bson.ObjectId) (*dao.Post, error) {
 	m.ctrl.T.Helper()
@@ -78,3 +94,152 @@ func (mr *MockPosterMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockPoster)(nil).Update), arg0, arg1)
 }
+
+// UpdateBatch mocks base method
+func (m *MockPoster) UpdateBatch(arg0 string, arg1 []*dao.Post) ([]*dao.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBatch", arg0, arg1)
+	ret0, _ := ret[0].([]*dao.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateBatch indicates an expected call of UpdateBatch
+func (mr *MockPosterMockRecorder) UpdateBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBatch", reflect.TypeOf((*MockPoster)(nil).UpdateBatch), arg0, arg1)
+}
+
+// GetByURL mocks base method
+func (m *MockPoster) GetByURL(arg0, arg1 string) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByURL", arg0, arg1)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByURL indicates an expected call of GetByURL
+func (mr *MockPosterMockRecorder) GetByURL(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByURL", reflect.TypeOf((*MockPoster)(nil).GetByURL), arg0, arg1)
+}
+
+// List mocks base method
+func (m *MockPoster) List(arg0 string, arg1 dao.ListOptions) ([]*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockPosterMockRecorder) List(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockPoster)(nil).List), arg0, arg1)
+}
+
+// Revisions mocks base method
+func (m *MockPoster) Revisions(arg0 string, arg1 bson.ObjectId) ([]*dao.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revisions", arg0, arg1)
+	ret0, _ := ret[0].([]*dao.Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revisions indicates an expected call of Revisions
+func (mr *MockPosterMockRecorder) Revisions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revisions", reflect.TypeOf((*MockPoster)(nil).Revisions), arg0, arg1)
+}
+
+// Restore mocks base method
+func (m *MockPoster) Restore(arg0 string, arg1 bson.ObjectId, arg2 int, arg3 string) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore
+func (mr *MockPosterMockRecorder) Restore(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockPoster)(nil).Restore), arg0, arg1, arg2, arg3)
+}
+
+// Patch mocks base method
+func (m *MockPoster) Patch(arg0 string, arg1 bson.ObjectId, arg2 dao.PatchFunc, arg3 string) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch
+func (mr *MockPosterMockRecorder) Patch(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockPoster)(nil).Patch), arg0, arg1, arg2, arg3)
+}
+
+// Delete mocks base method
+func (m *MockPoster) Delete(arg0 string, arg1 bson.ObjectId, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockPosterMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPoster)(nil).Delete), arg0, arg1, arg2)
+}
+
+// Schedule mocks base method
+func (m *MockPoster) Schedule(arg0 string, arg1 *dao.Post) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Schedule", arg0, arg1)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Schedule indicates an expected call of Schedule
+func (mr *MockPosterMockRecorder) Schedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Schedule", reflect.TypeOf((*MockPoster)(nil).Schedule), arg0, arg1)
+}
+
+// ClaimDue mocks base method
+func (m *MockPoster) ClaimDue(arg0 time.Time, arg1 time.Duration, arg2 int) ([]*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDue", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDue indicates an expected call of ClaimDue
+func (mr *MockPosterMockRecorder) ClaimDue(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDue", reflect.TypeOf((*MockPoster)(nil).ClaimDue), arg0, arg1, arg2)
+}
+
+// CompletePublish mocks base method
+func (m *MockPoster) CompletePublish(arg0 string, arg1 bson.ObjectId, arg2 []*dao.Publication) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompletePublish", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompletePublish indicates an expected call of CompletePublish
+func (mr *MockPosterMockRecorder) CompletePublish(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompletePublish", reflect.TypeOf((*MockPoster)(nil).CompletePublish), arg0, arg1, arg2)
+}
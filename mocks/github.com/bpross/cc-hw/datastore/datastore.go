@@ -9,6 +9,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	bson "labix.org/v2/mgo/bson"
 	reflect "reflect"
+	time "time"
 )
 
 // MockDatastore is a mock of Datastore interface
@@ -49,6 +50,21 @@ func (mr *MockDatastoreMockRecorder) Insert(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockDatastore)(nil).Insert), arg0, arg1)
 }
 
+// InsertBatch mocks base method
+func (m *MockDatastore) InsertBatch(arg0 string, arg1 []*dao.Post) ([]*dao.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertBatch", arg0, arg1)
+	ret0, _ := ret[0].([]*dao.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertBatch indicates an expected call of InsertBatch
+func (mr *MockDatastoreMockRecorder) InsertBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertBatch", reflect.TypeOf((*MockDatastore)(nil).InsertBatch), arg0, arg1)
+}
+
 // Get mocks base method
 func (m *MockDatastore) Get(arg0 string, arg1 bson.ObjectId) (*dao.Post, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +95,21 @@ func (mr *MockDatastoreMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockDatastore)(nil).Update), arg0, arg1)
 }
 
+// UpdateBatch mocks base method
+func (m *MockDatastore) UpdateBatch(arg0 string, arg1 []*dao.Post) ([]*dao.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBatch", arg0, arg1)
+	ret0, _ := ret[0].([]*dao.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateBatch indicates an expected call of UpdateBatch
+func (mr *MockDatastoreMockRecorder) UpdateBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBatch", reflect.TypeOf((*MockDatastore)(nil).UpdateBatch), arg0, arg1)
+}
+
 // Delete mocks base method
 func (m *MockDatastore) Delete(arg0 string, arg1 bson.ObjectId) error {
 	m.ctrl.T.Helper()
@@ -92,3 +123,123 @@ func (mr *MockDatastoreMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDatastore)(nil).Delete), arg0, arg1)
 }
+
+// GetByURL mocks base method
+func (m *MockDatastore) GetByURL(arg0, arg1 string) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByURL", arg0, arg1)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByURL indicates an expected call of GetByURL
+func (mr *MockDatastoreMockRecorder) GetByURL(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByURL", reflect.TypeOf((*MockDatastore)(nil).GetByURL), arg0, arg1)
+}
+
+// List mocks base method
+func (m *MockDatastore) List(arg0 string, arg1 dao.ListOptions) ([]*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockDatastoreMockRecorder) List(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDatastore)(nil).List), arg0, arg1)
+}
+
+// Revisions mocks base method
+func (m *MockDatastore) Revisions(arg0 string, arg1 bson.ObjectId) ([]*dao.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revisions", arg0, arg1)
+	ret0, _ := ret[0].([]*dao.Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revisions indicates an expected call of Revisions
+func (mr *MockDatastoreMockRecorder) Revisions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revisions", reflect.TypeOf((*MockDatastore)(nil).Revisions), arg0, arg1)
+}
+
+// Restore mocks base method
+func (m *MockDatastore) Restore(arg0 string, arg1 bson.ObjectId, arg2 int, arg3 string) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore
+func (mr *MockDatastoreMockRecorder) Restore(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockDatastore)(nil).Restore), arg0, arg1, arg2, arg3)
+}
+
+// Patch mocks base method
+func (m *MockDatastore) Patch(arg0 string, arg1 bson.ObjectId, arg2 dao.PatchFunc, arg3 string) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch
+func (mr *MockDatastoreMockRecorder) Patch(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockDatastore)(nil).Patch), arg0, arg1, arg2, arg3)
+}
+
+// Schedule mocks base method
+func (m *MockDatastore) Schedule(arg0 string, arg1 *dao.Post) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Schedule", arg0, arg1)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Schedule indicates an expected call of Schedule
+func (mr *MockDatastoreMockRecorder) Schedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Schedule", reflect.TypeOf((*MockDatastore)(nil).Schedule), arg0, arg1)
+}
+
+// ClaimDue mocks base method
+func (m *MockDatastore) ClaimDue(arg0 time.Time, arg1 time.Duration, arg2 int) ([]*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDue", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDue indicates an expected call of ClaimDue
+func (mr *MockDatastoreMockRecorder) ClaimDue(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDue", reflect.TypeOf((*MockDatastore)(nil).ClaimDue), arg0, arg1, arg2)
+}
+
+// CompletePublish mocks base method
+func (m *MockDatastore) CompletePublish(arg0 string, arg1 bson.ObjectId, arg2 []*dao.Publication) (*dao.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompletePublish", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*dao.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompletePublish indicates an expected call of CompletePublish
+func (mr *MockDatastoreMockRecorder) CompletePublish(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompletePublish", reflect.TypeOf((*MockDatastore)(nil).CompletePublish), arg0, arg1, arg2)
+}
@@ -0,0 +1,288 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Document is the subset of an OpenAPI 3 document the validator understands:
+// paths, operations, parameters, json request and response bodies and the
+// schemas in components
+type Document struct {
+	OpenAPI string `json:"openapi"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Responses  map[string]*Response  `json:"responses"`
+		Parameters map[string]*Parameter `json:"parameters"`
+	} `json:"components"`
+
+	raw    []byte
+	routes []*route
+}
+
+// PathItem is the operations of a path
+type PathItem struct {
+	Get    *Operation `json:"get"`
+	Put    *Operation `json:"put"`
+	Post   *Operation `json:"post"`
+	Delete *Operation `json:"delete"`
+	Patch  *Operation `json:"patch"`
+}
+
+// Operation is one method of a path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is the body of a request by content type
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is a response by content type
+type Response struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*MediaType `json:"content"`
+}
+
+// MediaType is the schema of a content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// route matches request paths to the operations of a path template
+type route struct {
+	path     string
+	segments []string
+	item     *PathItem
+}
+
+// Route is the operation a request matched
+type Route struct {
+	Path      string
+	Method    string
+	Operation *Operation
+	doc       *Document
+}
+
+// Load parses the document and checks every reference can be resolved
+func Load(data []byte) (*Document, error) {
+	doc := &Document{raw: data}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("openapi version %q is not supported", doc.OpenAPI)
+	}
+
+	for _, path := range doc.sortedPaths() {
+		item := doc.Paths[path]
+		doc.routes = append(doc.routes, &route{path: path, segments: strings.Split(strings.Trim(path, "/"), "/"), item: item})
+		for method, op := range item.operations() {
+			if err := doc.resolveOperation(op); err != nil {
+				return nil, fmt.Errorf("%s %s: %v", method, path, err)
+			}
+		}
+	}
+	for name, schema := range doc.Components.Schemas {
+		if err := doc.resolveSchema(schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %v", name, err)
+		}
+	}
+	return doc, nil
+}
+
+// JSON returns the document as it was loaded
+func (d *Document) JSON() []byte {
+	return d.raw
+}
+
+// Operations returns every "METHOD /path" of the document, sorted
+func (d *Document) Operations() []string {
+	ops := []string{}
+	for path, item := range d.Paths {
+		for method := range item.operations() {
+			ops = append(ops, method+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// BasePath returns the path of the first server, the paths of the document are
+// relative to it
+func (d *Document) BasePath() string {
+	if len(d.Servers) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(d.Servers[0].URL, "/")
+}
+
+// Find returns the operation for the method and request path, or nil if the
+// document does not describe it. The path may start with the path of any
+// server or leave it out, so aliases of the routes outside of them are found
+// too. Literal paths win over templated ones
+func (d *Document) Find(method, path string) *Route {
+	for _, server := range d.Servers {
+		if base := strings.TrimSuffix(server.URL, "/"); base != "" && strings.HasPrefix(path, base+"/") {
+			path = strings.TrimPrefix(path, base)
+			break
+		}
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var best *route
+	bestLiterals := -1
+	for _, r := range d.routes {
+		if literals, ok := r.match(segments); ok && literals > bestLiterals {
+			best, bestLiterals = r, literals
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	op := best.item.operations()[method]
+	if op == nil {
+		return nil
+	}
+	return &Route{Path: best.path, Method: method, Operation: op, doc: d}
+}
+
+// match returns how many literal segments matched, templated segments match
+// any non-empty segment
+func (r *route) match(segments []string) (int, bool) {
+	if len(segments) != len(r.segments) {
+		return 0, false
+	}
+	literals := 0
+	for i, s := range r.segments {
+		switch {
+		case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+			if segments[i] == "" {
+				return 0, false
+			}
+		case s == segments[i]:
+			literals++
+		default:
+			return 0, false
+		}
+	}
+	return literals, true
+}
+
+func (p *PathItem) operations() map[string]*Operation {
+	ops := map[string]*Operation{}
+	for method, op := range map[string]*Operation{
+		http.MethodGet:    p.Get,
+		http.MethodPut:    p.Put,
+		http.MethodPost:   p.Post,
+		http.MethodDelete: p.Delete,
+		http.MethodPatch:  p.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+func (d *Document) sortedPaths() []string {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// resolveOperation replaces the references of the operation with what they
+// point to
+func (d *Document) resolveOperation(op *Operation) error {
+	for i, param := range op.Parameters {
+		if param.Ref != "" {
+			name := strings.TrimPrefix(param.Ref, "#/components/parameters/")
+			resolved, ok := d.Components.Parameters[name]
+			if !ok {
+				return fmt.Errorf("unknown reference %s", param.Ref)
+			}
+			op.Parameters[i] = resolved
+			param = resolved
+		}
+		if err := d.resolveSchema(param.Schema); err != nil {
+			return err
+		}
+	}
+	if op.RequestBody != nil {
+		for _, media := range op.RequestBody.Content {
+			if err := d.resolveSchema(media.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	for status, resp := range op.Responses {
+		if resp.Ref != "" {
+			name := strings.TrimPrefix(resp.Ref, "#/components/responses/")
+			resolved, ok := d.Components.Responses[name]
+			if !ok {
+				return fmt.Errorf("unknown reference %s", resp.Ref)
+			}
+			op.Responses[status] = resolved
+			resp = resolved
+		}
+		for _, media := range resp.Content {
+			if err := d.resolveSchema(media.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSchema links the schema references to the component schemas
+func (d *Document) resolveSchema(s *Schema) error {
+	if s == nil || s.resolved {
+		return nil
+	}
+	s.resolved = true
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		target, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("unknown reference %s", s.Ref)
+		}
+		s.target = target
+		return d.resolveSchema(target)
+	}
+	children := append([]*Schema{s.Items}, s.OneOf...)
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.Schema)
+	}
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	for _, child := range children {
+		if err := d.resolveSchema(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
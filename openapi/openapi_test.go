@@ -0,0 +1,292 @@
+package openapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+const testSpec = `{
+  "openapi": "3.0.3",
+  "servers": [{"url": "/v1"}, {"url": "/v2"}],
+  "paths": {
+    "/things": {
+      "get": {
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 10}},
+          {"name": "fresh", "in": "query", "schema": {"type": "boolean"}}
+        ],
+        "responses": {
+          "200": {"description": "ok", "content": {"application/json": {"schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Thing"}}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "post": {
+        "parameters": [{"name": "Idempotency-Key", "in": "header", "schema": {"type": "string", "maxLength": 3}}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Thing"}}}},
+        "responses": {"201": {"description": "created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Thing"}}}}}
+      }
+    },
+    "/things/{id}": {
+      "delete": {
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"204": {"description": "deleted"}}
+      }
+    },
+    "/things/mine": {
+      "get": {"responses": {"200": {"description": "ok", "content": {"text/csv": {}}}}}
+    },
+    "/things:batch": {
+      "post": {
+        "requestBody": {"content": {"*/*": {}}},
+        "responses": {"2XX": {"description": "ok"}}
+      }
+    }
+  },
+  "components": {
+    "responses": {
+      "Problem": {"description": "error", "content": {"application/problem+json": {"schema": {"type": "object", "required": ["code"], "properties": {"code": {"type": "string"}}}}}}
+    },
+    "schemas": {
+      "Thing": {
+        "type": "object",
+        "required": ["name"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string", "minLength": 1},
+          "kind": {"type": "string", "enum": ["a", "b"]},
+          "tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+          "at": {"type": "string", "format": "date-time"},
+          "size": {"type": "integer"},
+          "label": {"oneOf": [{"type": "string"}, {"type": "object", "required": ["text"], "properties": {"text": {"type": "string"}}}]},
+          "parent": {"$ref": "#/components/schemas/Thing"}
+        }
+      }
+    }
+  }
+}`
+
+var _ = Describe("Document", func() {
+	var doc *Document
+
+	BeforeEach(func() {
+		var err error
+		doc, err = Load([]byte(testSpec))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Load", func() {
+		It("should fail for invalid json", func() {
+			_, err := Load([]byte("{"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for other versions", func() {
+			_, err := Load([]byte(`{"swagger": "2.0"}`))
+			Expect(err).To(MatchError(ContainSubstring("not supported")))
+		})
+
+		It("should fail for unknown references", func() {
+			spec := strings.Replace(testSpec, "#/components/schemas/Thing", "#/components/schemas/Missing", 1)
+			_, err := Load([]byte(spec))
+			Expect(err).To(MatchError(ContainSubstring("unknown reference #/components/schemas/Missing")))
+		})
+
+		It("should load the spec of the server", func() {
+			Expect(func() { Default() }).NotTo(Panic())
+			Expect(Default().JSON()).To(MatchJSON(Spec))
+		})
+	})
+
+	Describe("Operations", func() {
+		It("should list every method and path", func() {
+			Expect(doc.Operations()).To(Equal([]string{
+				"DELETE /things/{id}",
+				"GET /things",
+				"GET /things/mine",
+				"POST /things",
+				"POST /things:batch",
+			}))
+		})
+	})
+
+	Describe("Find", func() {
+		It("should match templated segments", func() {
+			route := doc.Find(http.MethodDelete, "/things/123")
+			Expect(route).NotTo(BeNil())
+			Expect(route.Path).To(Equal("/things/{id}"))
+		})
+
+		It("should prefer literal segments", func() {
+			route := doc.Find(http.MethodGet, "/things/mine")
+			Expect(route).NotTo(BeNil())
+			Expect(route.Path).To(Equal("/things/mine"))
+		})
+
+		It("should match a custom method as a literal", func() {
+			route := doc.Find(http.MethodPost, "/things:batch")
+			Expect(route).NotTo(BeNil())
+			Expect(route.Path).To(Equal("/things:batch"))
+		})
+
+		It("should match paths with and without the base path", func() {
+			Expect(doc.BasePath()).To(Equal("/v1"))
+			route := doc.Find(http.MethodDelete, "/v1/things/123")
+			Expect(route).NotTo(BeNil())
+			Expect(route.Path).To(Equal("/things/{id}"))
+			Expect(doc.Find(http.MethodGet, "/v1")).To(BeNil())
+		})
+
+		It("should match paths under every server", func() {
+			route := doc.Find(http.MethodDelete, "/v2/things/123")
+			Expect(route).NotTo(BeNil())
+			Expect(route.Path).To(Equal("/things/{id}"))
+		})
+
+		It("should return nil for unknown paths and methods", func() {
+			Expect(doc.Find(http.MethodGet, "/others")).To(BeNil())
+			Expect(doc.Find(http.MethodPut, "/things")).To(BeNil())
+			Expect(doc.Find(http.MethodDelete, "/things/")).To(BeNil())
+		})
+	})
+
+	Describe("ValidateRequest", func() {
+		validate := func(method, target, contentType, body string, pathParams map[string]string) []datastore.FieldError {
+			req := httptest.NewRequest(method, target, strings.NewReader(body))
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
+			route := doc.Find(method, req.URL.Path)
+			Expect(route).NotTo(BeNil())
+			err := route.ValidateRequest(req, pathParams)
+			if err == nil {
+				return nil
+			}
+			Expect(err).To(BeAssignableToTypeOf(&datastore.Validation{}))
+			return err.(*datastore.Validation).Fields
+		}
+
+		It("should accept valid parameters", func() {
+			Expect(validate(http.MethodGet, "/things?limit=5&fresh=true", "", "", nil)).To(BeEmpty())
+			Expect(validate(http.MethodGet, "/things?limit=", "", "", nil)).To(BeEmpty())
+		})
+
+		It("should name invalid parameters", func() {
+			Expect(validate(http.MethodGet, "/things?limit=abc&fresh=maybe", "", "", nil)).To(ConsistOf(
+				datastore.FieldError{Field: "limit", Message: "must be an integer"},
+				datastore.FieldError{Field: "fresh", Message: "must be a boolean"},
+			))
+			Expect(validate(http.MethodGet, "/things?limit=11", "", "", nil)).To(ConsistOf(
+				datastore.FieldError{Field: "limit", Message: "must be at most 10"},
+			))
+		})
+
+		It("should require path parameters", func() {
+			Expect(validate(http.MethodDelete, "/things/1", "", "", nil)).To(ConsistOf(
+				datastore.FieldError{Field: "id", Message: "is required"},
+			))
+			Expect(validate(http.MethodDelete, "/things/1", "", "", map[string]string{"id": "1"})).To(BeEmpty())
+		})
+
+		It("should validate headers", func() {
+			req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(`{"name": "a"}`))
+			req.Header.Set("Idempotency-Key", "abcd")
+			err := doc.Find(http.MethodPost, "/things").ValidateRequest(req, nil)
+			Expect(err).To(MatchError(ContainSubstring("Idempotency-Key: must be at most 3 characters")))
+		})
+
+		It("should accept a valid body", func() {
+			body := `{"name": "a", "kind": "b", "tags": ["x"], "at": "2020-01-02T10:00:00Z", "size": 3, "parent": {"name": "p"}}`
+			Expect(validate(http.MethodPost, "/things", "application/json; charset=utf-8", body, nil)).To(BeEmpty())
+			Expect(validate(http.MethodPost, "/things", "", body, nil)).To(BeEmpty())
+		})
+
+		It("should name every invalid part of the body", func() {
+			body := `{"kind": "c", "tags": ["x", 1, "z"], "at": "yesterday", "size": 1.5, "parent": {"name": ""}, "extra": true}`
+			Expect(validate(http.MethodPost, "/things", "application/json", body, nil)).To(ConsistOf(
+				datastore.FieldError{Field: "name", Message: "is required"},
+				datastore.FieldError{Field: "kind", Message: "must be one of a, b"},
+				datastore.FieldError{Field: "tags", Message: "must have at most 2 items"},
+				datastore.FieldError{Field: "tags[1]", Message: "must be a string"},
+				datastore.FieldError{Field: "at", Message: "must be an RFC 3339 timestamp"},
+				datastore.FieldError{Field: "size", Message: "must be an integer"},
+				datastore.FieldError{Field: "parent.name", Message: "must be at least one character"},
+				datastore.FieldError{Field: "extra", Message: "is not allowed"},
+			))
+		})
+
+		It("should reject bodies that are not objects", func() {
+			Expect(validate(http.MethodPost, "/things", "application/json", `[]`, nil)).To(ConsistOf(
+				datastore.FieldError{Field: "body", Message: "must be an object"},
+			))
+			Expect(validate(http.MethodPost, "/things", "application/json", `{`, nil)).To(ConsistOf(
+				datastore.FieldError{Field: "body", Message: "must be valid json"},
+			))
+		})
+
+		It("should require a body", func() {
+			Expect(validate(http.MethodPost, "/things", "application/json", ``, nil)).To(ConsistOf(
+				datastore.FieldError{Field: "body", Message: "is required"},
+			))
+			Expect(validate(http.MethodPost, "/things", "application/json", ` `, nil)).To(ConsistOf(
+				datastore.FieldError{Field: "body", Message: "is required"},
+			))
+		})
+
+		It("should leave the body readable", func() {
+			req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(`{"name": "a"}`))
+			Expect(doc.Find(http.MethodPost, "/things").ValidateRequest(req, nil)).To(Succeed())
+			body, err := ioutil.ReadAll(req.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"name": "a"}`))
+		})
+
+		It("should reject unsupported content types", func() {
+			Expect(validate(http.MethodPost, "/things", "text/plain", `{"name": "a"}`, nil)).To(ConsistOf(
+				datastore.FieldError{Field: "body", Message: `content type "text/plain" is not supported`},
+			))
+		})
+
+		It("should accept any content type for */*", func() {
+			Expect(validate(http.MethodPost, "/things:batch", "application/json", "a\nb", nil)).To(BeEmpty())
+		})
+	})
+
+	Describe("ValidateResponse", func() {
+		It("should accept documented responses", func() {
+			route := doc.Find(http.MethodGet, "/things")
+			Expect(route.ValidateResponse(http.StatusOK, "application/json; charset=utf-8", []byte(`[{"name": "a"}]`))).To(Succeed())
+			Expect(route.ValidateResponse(http.StatusOK, "application/json", []byte(`null`))).To(Succeed())
+			Expect(route.ValidateResponse(http.StatusNotFound, "application/problem+json", []byte(`{"code": "not_found"}`))).To(Succeed())
+			Expect(doc.Find(http.MethodDelete, "/things/1").ValidateResponse(http.StatusNoContent, "", nil)).To(Succeed())
+			Expect(doc.Find(http.MethodGet, "/things/mine").ValidateResponse(http.StatusOK, "text/csv", []byte("a,b"))).To(Succeed())
+			Expect(doc.Find(http.MethodPost, "/things:batch").ValidateResponse(http.StatusAccepted, "", nil)).To(Succeed())
+		})
+
+		It("should reject bodies that do not match", func() {
+			route := doc.Find(http.MethodGet, "/things")
+			Expect(route.ValidateResponse(http.StatusOK, "application/json", []byte(`[{"size": 1}]`))).To(MatchError(ContainSubstring("[0].name: is required")))
+			Expect(route.ValidateResponse(http.StatusOK, "application/json", []byte(`[`))).To(MatchError(ContainSubstring("not valid json")))
+		})
+
+		It("should accept a value that matches one of the schemas of oneOf", func() {
+			route := doc.Find(http.MethodGet, "/things")
+			Expect(route.ValidateResponse(http.StatusOK, "application/json", []byte(`[{"name": "a", "label": "l"}, {"name": "b", "label": {"text": "l"}}]`))).To(Succeed())
+			Expect(route.ValidateResponse(http.StatusOK, "application/json", []byte(`[{"name": "a", "label": 1}]`))).To(MatchError(ContainSubstring("[0].label: must match one of 2 schemas")))
+			Expect(route.ValidateResponse(http.StatusOK, "application/json", []byte(`[{"name": "a", "label": {}}]`))).To(MatchError(ContainSubstring("[0].label: must match one of 2 schemas")))
+		})
+
+		It("should reject undocumented statuses and content types", func() {
+			Expect(doc.Find(http.MethodPost, "/things").ValidateResponse(http.StatusOK, "application/json", []byte(`{"name": "a"}`))).To(MatchError(ContainSubstring("status 200 is not documented")))
+			Expect(doc.Find(http.MethodGet, "/things").ValidateResponse(http.StatusAccepted, "", nil)).To(MatchError(ContainSubstring("status 202 is not documented")))
+			Expect(doc.Find(http.MethodGet, "/things").ValidateResponse(http.StatusOK, "text/html", []byte(`<p>`))).To(MatchError(ContainSubstring(`content type "text/html" is not documented`)))
+			Expect(doc.Find(http.MethodDelete, "/things/1").ValidateResponse(http.StatusNoContent, "text/plain", []byte(`gone`))).To(MatchError(ContainSubstring("should not have a body")))
+		})
+	})
+})
@@ -0,0 +1,239 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// Schema is the subset of a JSON schema the validator understands
+type Schema struct {
+	Ref                  string                `json:"$ref"`
+	Type                 string                `json:"type"`
+	Format               string                `json:"format"`
+	Nullable             bool                  `json:"nullable"`
+	Enum                 []interface{}         `json:"enum"`
+	Properties           map[string]*Schema    `json:"properties"`
+	Required             []string              `json:"required"`
+	AdditionalProperties *AdditionalProperties `json:"additionalProperties"`
+	Items                *Schema               `json:"items"`
+	MinItems             *int                  `json:"minItems"`
+	MaxItems             *int                  `json:"maxItems"`
+	MinLength            *int                  `json:"minLength"`
+	MaxLength            *int                  `json:"maxLength"`
+	Minimum              *float64              `json:"minimum"`
+	Maximum              *float64              `json:"maximum"`
+	OneOf                []*Schema             `json:"oneOf"`
+
+	target   *Schema
+	resolved bool
+}
+
+// AdditionalProperties is either a boolean or the schema of the properties that
+// are not listed
+type AdditionalProperties struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (a *AdditionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// Validate adds an error to verr for every part of value, as decoded by
+// encoding/json, that does not match the schema. field names the value
+func (s *Schema) Validate(verr *datastore.Validation, field string, value interface{}) {
+	if s == nil {
+		return
+	}
+	if s.target != nil {
+		s.target.Validate(verr, field, value)
+		return
+	}
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			verr.Add(field, "must not be null")
+		}
+		return
+	}
+	if len(s.OneOf) > 0 {
+		s.validateOneOf(verr, field, value)
+		return
+	}
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		verr.Add(field, fmt.Sprintf("must be one of %s", s.enumList()))
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			verr.Add(field, "must be an object")
+			return
+		}
+		s.validateObject(verr, field, obj)
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			verr.Add(field, "must be an array")
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			verr.Add(field, "must have at least "+count(*s.MinItems, "item"))
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			verr.Add(field, "must have at most "+count(*s.MaxItems, "item"))
+		}
+		for i, item := range arr {
+			s.Items.Validate(verr, fmt.Sprintf("%s[%d]", field, i), item)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			verr.Add(field, "must be a string")
+			return
+		}
+		s.validateString(verr, field, str)
+	case "integer", "number":
+		num, ok := value.(float64)
+		if !ok {
+			verr.Add(field, fmt.Sprintf("must be %s %s", article(s.Type), s.Type))
+			return
+		}
+		if s.Type == "integer" && num != math.Trunc(num) {
+			verr.Add(field, "must be an integer")
+			return
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			verr.Add(field, fmt.Sprintf("must be at least %v", *s.Minimum))
+		}
+		if s.Maximum != nil && num > *s.Maximum {
+			verr.Add(field, fmt.Sprintf("must be at most %v", *s.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			verr.Add(field, "must be a boolean")
+		}
+	}
+}
+
+// validateOneOf adds an error unless the value matches exactly one of the
+// schemas
+func (s *Schema) validateOneOf(verr *datastore.Validation, field string, value interface{}) {
+	matches := 0
+	for _, schema := range s.OneOf {
+		alternative := datastore.NewValidationError()
+		schema.Validate(alternative, field, value)
+		if !alternative.HasErrors() {
+			matches++
+		}
+	}
+	switch {
+	case matches == 0:
+		verr.Add(field, "must match one of "+count(len(s.OneOf), "schema"))
+	case matches > 1:
+		verr.Add(field, "must match only one of "+count(len(s.OneOf), "schema"))
+	}
+}
+
+func (s *Schema) validateObject(verr *datastore.Validation, field string, obj map[string]interface{}) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			verr.Add(join(field, name), "is required")
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if prop, ok := s.Properties[name]; ok {
+			prop.Validate(verr, join(field, name), obj[name])
+			continue
+		}
+		if s.AdditionalProperties == nil {
+			continue
+		}
+		if !s.AdditionalProperties.Allowed {
+			verr.Add(join(field, name), "is not allowed")
+			continue
+		}
+		s.AdditionalProperties.Schema.Validate(verr, join(field, name), obj[name])
+	}
+}
+
+func (s *Schema) validateString(verr *datastore.Validation, field, str string) {
+	length := len([]rune(str))
+	if s.MinLength != nil && length < *s.MinLength {
+		verr.Add(field, "must be at least "+count(*s.MinLength, "character"))
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		verr.Add(field, "must be at most "+count(*s.MaxLength, "character"))
+	}
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			verr.Add(field, "must be an RFC 3339 timestamp")
+		}
+	}
+}
+
+func (s *Schema) inEnum(value interface{}) bool {
+	for _, e := range s.Enum {
+		if e == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) enumList() string {
+	values := make([]string, len(s.Enum))
+	for i, e := range s.Enum {
+		values[i] = fmt.Sprint(e)
+	}
+	return strings.Join(values, ", ")
+}
+
+// resolve returns the schema a reference points to
+func (s *Schema) resolve() *Schema {
+	for s != nil && s.target != nil {
+		s = s.target
+	}
+	return s
+}
+
+// join returns the name of a property of field, the body itself has no name
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// count returns n and the noun, plural unless n is one
+func count(n int, noun string) string {
+	if n == 1 {
+		return "one " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func article(word string) string {
+	if strings.ContainsAny(word[:1], "aeiou") {
+		return "an"
+	}
+	return "a"
+}
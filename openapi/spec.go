@@ -0,0 +1,1116 @@
+package openapi
+
+import "sync"
+
+// Spec is the OpenAPI 3 document of the api served by cmd/server. The contract
+// test in cmd/server fails when it and the registered routes drift apart
+const Spec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "cc-hw",
+    "description": "Generates social media captions for urls and manages the resulting posts. Errors are application/problem+json documents with a stable code.",
+    "version": "1.0.0"
+  },
+  "servers": [
+    {"url": "/v1", "description": "Responds with the texts of captions. The unversioned paths are deprecated aliases of these, they respond with the Deprecation and Sunset headers"},
+    {"url": "/v2", "description": "Responds with captions as objects"}
+  ],
+  "security": [{"ApiKey": []}, {"Bearer": []}],
+  "paths": {
+    "/openapi.json": {
+      "get": {
+        "operationId": "getOpenAPI",
+        "summary": "This document",
+        "security": [],
+        "responses": {
+          "200": {"description": "The OpenAPI document", "content": {"application/json": {"schema": {"type": "object"}}}}
+        }
+      }
+    },
+    "/posts": {
+      "get": {
+        "operationId": "listPosts",
+        "summary": "List the customer's posts, oldest first unless sorted",
+        "parameters": [{"$ref": "#/components/parameters/After"}, {"$ref": "#/components/parameters/Limit"}, {"$ref": "#/components/parameters/Sort"}, {"$ref": "#/components/parameters/CreatedAfter"}, {"$ref": "#/components/parameters/CreatedBefore"}, {"$ref": "#/components/parameters/UpdatedAfter"}, {"$ref": "#/components/parameters/UpdatedBefore"}, {"$ref": "#/components/parameters/CreatedBy"}, {"$ref": "#/components/parameters/UpdatedBy"}],
+        "responses": {
+          "200": {"description": "A page of posts", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/PostPage"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post": {
+      "post": {
+        "operationId": "generatePost",
+        "summary": "Create a post with generated captions",
+        "description": "Counts against the monthly quota. Set dedupe=true to return the customer's existing post for the url instead, marked with the Existing-Post header.",
+        "parameters": [{"$ref": "#/components/parameters/IdempotencyKey"}, {"$ref": "#/components/parameters/Dedupe"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/PostRequest"}}}},
+        "responses": {
+          "200": {"description": "The created post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}": {
+      "get": {
+        "operationId": "getPost",
+        "summary": "Get a post",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "responses": {
+          "200": {"description": "The post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "put": {
+        "operationId": "updatePost",
+        "summary": "Replace the captions of a post",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/PutRequest"}}}},
+        "responses": {
+          "200": {"description": "The updated post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "patch": {
+        "operationId": "patchPost",
+        "summary": "Change the captions of a post with a JSON Merge Patch or a JSON Patch",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "requestBody": {"required": true, "content": {
+          "application/merge-patch+json": {"schema": {"$ref": "#/components/schemas/MergePatch"}},
+          "application/json-patch+json": {"schema": {"$ref": "#/components/schemas/JSONPatch"}}
+        }},
+        "responses": {
+          "200": {"description": "The patched post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "delete": {
+        "operationId": "deletePost",
+        "summary": "Delete a post and its revisions",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "responses": {
+          "204": {"description": "The post was deleted"},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/approve": {
+      "post": {
+        "operationId": "approvePost",
+        "summary": "Approve a draft post",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "responses": {
+          "200": {"description": "The approved post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/revisions": {
+      "get": {
+        "operationId": "listRevisions",
+        "summary": "List the revisions of a post, oldest first",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "responses": {
+          "200": {"description": "The revisions", "content": {"application/json": {"schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Revision"}}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/revisions/{rev}/restore": {
+      "post": {
+        "operationId": "restoreRevision",
+        "summary": "Restore the captions of a revision",
+        "parameters": [
+          {"$ref": "#/components/parameters/PostID"},
+          {"name": "rev", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 1}}
+        ],
+        "responses": {
+          "200": {"description": "The restored post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/schedule": {
+      "put": {
+        "operationId": "schedulePost",
+        "summary": "Schedule an approved post to be published",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ScheduleRequest"}}}},
+        "responses": {
+          "200": {"description": "The scheduled post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "delete": {
+        "operationId": "unschedulePost",
+        "summary": "Cancel the schedule of a post",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "responses": {
+          "200": {"description": "The unscheduled post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/captions/order": {
+      "put": {
+        "operationId": "reorderCaptions",
+        "summary": "Put the captions of a post in order, pinned captions stay first",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ReorderCaptionsRequest"}}}},
+        "responses": {
+          "200": {"description": "The post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/captions/{caption_id}": {
+      "delete": {
+        "operationId": "discardCaption",
+        "summary": "Remove a caption that is not pinned from a post",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}, {"$ref": "#/components/parameters/CaptionID"}],
+        "responses": {
+          "200": {"description": "The post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/captions/{caption_id}/select": {
+      "post": {
+        "operationId": "selectCaption",
+        "summary": "Select the caption that is published, unselecting any other",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}, {"$ref": "#/components/parameters/CaptionID"}],
+        "responses": {
+          "200": {"description": "The post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/captions/{caption_id}/pin": {
+      "post": {
+        "operationId": "pinCaption",
+        "summary": "Pin a caption ahead of the others",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}, {"$ref": "#/components/parameters/CaptionID"}],
+        "responses": {
+          "200": {"description": "The post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/post/{id}/captions/{caption_id}/unpin": {
+      "post": {
+        "operationId": "unpinCaption",
+        "summary": "Unpin a caption",
+        "parameters": [{"$ref": "#/components/parameters/PostID"}, {"$ref": "#/components/parameters/CaptionID"}],
+        "responses": {
+          "200": {"description": "The post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/posts:batch": {
+      "post": {
+        "operationId": "createPosts",
+        "summary": "Create up to 100 posts",
+        "description": "Items without captions have them generated. Every item has its own result, the request only fails as a whole when it is malformed.",
+        "parameters": [{"$ref": "#/components/parameters/IdempotencyKey"}, {"$ref": "#/components/parameters/Dedupe"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchCreateRequest"}}}},
+        "responses": {
+          "200": {"description": "A result per item", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchResponse"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "put": {
+        "operationId": "updatePosts",
+        "summary": "Replace the captions of up to 100 posts",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchUpdateRequest"}}}},
+        "responses": {
+          "200": {"description": "A result per item", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchResponse"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/export": {
+      "get": {
+        "operationId": "exportPosts",
+        "summary": "Stream every post of the customer",
+        "parameters": [{"$ref": "#/components/parameters/Format"}],
+        "responses": {
+          "200": {"description": "The posts", "content": {"text/csv": {}, "application/x-ndjson": {}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/import": {
+      "post": {
+        "operationId": "importPosts",
+        "summary": "Create a post for every line of a csv or json lines body",
+        "parameters": [
+          {"$ref": "#/components/parameters/Format"},
+          {"name": "generate", "in": "query", "schema": {"type": "boolean"}}
+        ],
+        "requestBody": {"required": true, "content": {"*/*": {}}},
+        "responses": {
+          "200": {"description": "How many posts were created and why lines failed", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ImportResponse"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/events": {
+      "get": {
+        "operationId": "pollEvents",
+        "summary": "Read the customer's events after an offset, waiting for new ones",
+        "parameters": [
+          {"name": "after", "in": "query", "schema": {"type": "integer", "minimum": 0}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 1000}},
+          {"name": "wait", "in": "query", "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "The events", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/EventsResponse"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/audit": {
+      "get": {
+        "operationId": "listAuditEntries",
+        "summary": "Read the customer's audit log, oldest first",
+        "parameters": [{"$ref": "#/components/parameters/AuditAfter"}, {"$ref": "#/components/parameters/AuditLimit"}, {"$ref": "#/components/parameters/AuditActor"}, {"$ref": "#/components/parameters/AuditAction"}, {"$ref": "#/components/parameters/AuditPostID"}, {"$ref": "#/components/parameters/AuditSince"}, {"$ref": "#/components/parameters/AuditUntil"}],
+        "responses": {
+          "200": {"description": "A page of audit entries", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/AuditPage"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/keys": {
+      "get": {
+        "operationId": "listKeys",
+        "summary": "List the customer's api keys",
+        "responses": {
+          "200": {"description": "The keys", "content": {"application/json": {"schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Key"}}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "post": {
+        "operationId": "createKey",
+        "summary": "Create an api key",
+        "responses": {
+          "201": {"description": "The key and its secret", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/KeyWithSecret"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/keys/{id}": {
+      "delete": {
+        "operationId": "revokeKey",
+        "summary": "Revoke an api key",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "responses": {
+          "204": {"description": "The key was revoked"},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/keys/{id}/rotate": {
+      "post": {
+        "operationId": "rotateKey",
+        "summary": "Replace an api key with a new one",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "responses": {
+          "200": {"description": "The new key and its secret", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/KeyWithSecret"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/webhooks": {
+      "get": {
+        "operationId": "listWebhooks",
+        "summary": "List the customer's webhook subscriptions",
+        "responses": {
+          "200": {"description": "The subscriptions", "content": {"application/json": {"schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Subscription"}}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "post": {
+        "operationId": "createWebhook",
+        "summary": "Subscribe a url to events",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/WebhookRequest"}}}},
+        "responses": {
+          "201": {"description": "The subscription and its signing secret", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SubscriptionWithSecret"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/webhooks/{id}": {
+      "get": {
+        "operationId": "getWebhook",
+        "summary": "Get a webhook subscription",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "responses": {
+          "200": {"description": "The subscription", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Subscription"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "put": {
+        "operationId": "updateWebhook",
+        "summary": "Replace the url and event types of a subscription",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/WebhookRequest"}}}},
+        "responses": {
+          "200": {"description": "The updated subscription", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Subscription"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteWebhook",
+        "summary": "Delete a webhook subscription",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "responses": {
+          "204": {"description": "The subscription was deleted"},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/webhooks/{id}/deliveries": {
+      "get": {
+        "operationId": "listDeliveries",
+        "summary": "List the deliveries of a subscription, oldest first",
+        "parameters": [
+          {"$ref": "#/components/parameters/ID"},
+          {"name": "status", "in": "query", "schema": {"type": "string", "enum": ["pending", "succeeded", "dead"]}}
+        ],
+        "responses": {
+          "200": {"description": "The deliveries", "content": {"application/json": {"schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Delivery"}}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/webhooks/{id}/deliveries/{delivery_id}/redeliver": {
+      "post": {
+        "operationId": "redeliver",
+        "summary": "Send a delivery again",
+        "parameters": [
+          {"$ref": "#/components/parameters/ID"},
+          {"name": "delivery_id", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "202": {"description": "The delivery was queued", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Delivery"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/usage": {
+      "get": {
+        "operationId": "getUsage",
+        "summary": "Get the customer's usage of the current period",
+        "responses": {
+          "200": {"description": "The usage", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Usage"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/quality/rules": {
+      "get": {
+        "operationId": "getQualityRules",
+        "summary": "Get the rules the customer's generated captions are checked against",
+        "responses": {
+          "200": {"description": "The rules", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/QualityRules"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "put": {
+        "operationId": "putQualityRules",
+        "summary": "Replace the customer's quality rules",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/QualityRulesRequest"}}}},
+        "responses": {
+          "200": {"description": "The stored rules", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/QualityRules"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteQualityRules",
+        "summary": "Remove the customer's quality rules, so the default rules apply again",
+        "responses": {
+          "204": {"description": "The default rules apply"},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/templates": {
+      "get": {
+        "operationId": "listTemplates",
+        "summary": "List the customer's caption templates",
+        "responses": {
+          "200": {"description": "The templates", "content": {"application/json": {"schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Template"}}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "post": {
+        "operationId": "createTemplate",
+        "summary": "Create a caption template",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TemplateRequest"}}}},
+        "responses": {
+          "201": {"description": "The template", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Template"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/templates/preview": {
+      "post": {
+        "operationId": "previewTemplate",
+        "summary": "Render a template without storing anything",
+        "description": "Captions are generated for the url when the request has none, which counts against the monthly quota.",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TemplatePreviewRequest"}}}},
+        "responses": {
+          "200": {"description": "The rendered captions", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TemplatePreview"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/templates/{id}": {
+      "get": {
+        "operationId": "getTemplate",
+        "summary": "Get a caption template",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "responses": {
+          "200": {"description": "The template", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Template"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "put": {
+        "operationId": "updateTemplate",
+        "summary": "Replace the name and text of a template",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TemplateRequest"}}}},
+        "responses": {
+          "200": {"description": "The updated template", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Template"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteTemplate",
+        "summary": "Delete a caption template",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "responses": {
+          "204": {"description": "The template was deleted"},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/admin/customers/{customer_id}/keys": {
+      "post": {
+        "operationId": "adminCreateKey",
+        "summary": "Create an api key for a customer",
+        "security": [{"AdminKey": []}],
+        "parameters": [{"$ref": "#/components/parameters/CustomerID"}],
+        "responses": {
+          "201": {"description": "The key and its secret", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/KeyWithSecret"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/admin/customers/{customer_id}/keys/{id}/rotate": {
+      "post": {
+        "operationId": "adminRotateKey",
+        "summary": "Replace an api key of a customer with a new one",
+        "security": [{"AdminKey": []}],
+        "parameters": [{"$ref": "#/components/parameters/CustomerID"}, {"$ref": "#/components/parameters/ID"}],
+        "responses": {
+          "200": {"description": "The new key and its secret", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/KeyWithSecret"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/admin/customers/{customer_id}/posts": {
+      "get": {
+        "operationId": "adminListPosts",
+        "summary": "List the posts of a customer, oldest first unless sorted",
+        "security": [{"AdminKey": []}],
+        "parameters": [{"$ref": "#/components/parameters/CustomerID"}, {"$ref": "#/components/parameters/After"}, {"$ref": "#/components/parameters/Limit"}, {"$ref": "#/components/parameters/Sort"}, {"$ref": "#/components/parameters/CreatedAfter"}, {"$ref": "#/components/parameters/CreatedBefore"}, {"$ref": "#/components/parameters/UpdatedAfter"}, {"$ref": "#/components/parameters/UpdatedBefore"}, {"$ref": "#/components/parameters/CreatedBy"}, {"$ref": "#/components/parameters/UpdatedBy"}],
+        "responses": {
+          "200": {"description": "A page of posts", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/PostPage"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/admin/customers/{customer_id}/audit": {
+      "get": {
+        "operationId": "adminListAuditEntries",
+        "summary": "Read the audit log of a customer, oldest first",
+        "security": [{"AdminKey": []}],
+        "parameters": [{"$ref": "#/components/parameters/CustomerID"}, {"$ref": "#/components/parameters/AuditAfter"}, {"$ref": "#/components/parameters/AuditLimit"}, {"$ref": "#/components/parameters/AuditActor"}, {"$ref": "#/components/parameters/AuditAction"}, {"$ref": "#/components/parameters/AuditPostID"}, {"$ref": "#/components/parameters/AuditSince"}, {"$ref": "#/components/parameters/AuditUntil"}],
+        "responses": {
+          "200": {"description": "A page of audit entries", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/AuditPage"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/admin/customers/{customer_id}/posts/{id}": {
+      "get": {
+        "operationId": "adminGetPost",
+        "summary": "Get a post of a customer",
+        "security": [{"AdminKey": []}],
+        "parameters": [{"$ref": "#/components/parameters/CustomerID"}, {"$ref": "#/components/parameters/PostID"}],
+        "responses": {
+          "200": {"description": "The post", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Post"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      },
+      "delete": {
+        "operationId": "adminDeletePost",
+        "summary": "Delete a post of a customer and its revisions",
+        "security": [{"AdminKey": []}],
+        "parameters": [{"$ref": "#/components/parameters/CustomerID"}, {"$ref": "#/components/parameters/PostID"}],
+        "responses": {
+          "204": {"description": "The post was deleted"},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/admin/usage": {
+      "get": {
+        "operationId": "adminListUsage",
+        "summary": "List the usage of every customer in the current period",
+        "security": [{"AdminKey": []}],
+        "responses": {
+          "200": {"description": "The usage by customer", "content": {"application/json": {"schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Usage"}}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/admin/captions/warm": {
+      "post": {
+        "operationId": "adminWarmCaptions",
+        "summary": "Generate captions for urls ahead of time so creating their posts is served from the cache",
+        "security": [{"AdminKey": []}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/WarmRequest"}}}},
+        "responses": {
+          "200": {"description": "A result per url", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/WarmResponse"}}}},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/admin/datastore/compact": {
+      "post": {
+        "operationId": "adminCompactDatastore",
+        "summary": "Rewrite the data file without its history. Only served when DATA_FILE is set",
+        "security": [{"AdminKey": []}],
+        "responses": {
+          "204": {"description": "The data file was compacted"},
+          "default": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "ApiKey": {"type": "apiKey", "in": "header", "name": "x-api-key"},
+      "Bearer": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+      "AdminKey": {"type": "apiKey", "in": "header", "name": "x-api-key", "description": "The ADMIN_API_KEY of the server"}
+    },
+    "parameters": {
+      "PostID": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+      "CaptionID": {"name": "caption_id", "in": "path", "required": true, "schema": {"type": "string"}},
+      "ID": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+      "CustomerID": {"name": "customer_id", "in": "path", "required": true, "schema": {"type": "string"}},
+      "After": {"name": "after", "in": "query", "description": "The next of the previous page", "schema": {"type": "string"}},
+      "Limit": {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 100}},
+      "Sort": {"name": "sort", "in": "query", "description": "The time to list posts by, descending with a leading -", "schema": {"type": "string", "enum": ["created_at", "-created_at", "updated_at", "-updated_at"]}},
+      "CreatedAfter": {"name": "created_after", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+      "CreatedBefore": {"name": "created_before", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+      "UpdatedAfter": {"name": "updated_after", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+      "UpdatedBefore": {"name": "updated_before", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+      "CreatedBy": {"name": "created_by", "in": "query", "schema": {"type": "string"}},
+      "UpdatedBy": {"name": "updated_by", "in": "query", "schema": {"type": "string"}},
+      "AuditAfter": {"name": "after", "in": "query", "description": "The sequence of the last entry of the previous page", "schema": {"type": "integer", "minimum": 0}},
+      "AuditLimit": {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 1000}},
+      "AuditActor": {"name": "actor", "in": "query", "schema": {"type": "string"}},
+      "AuditAction": {"name": "action", "in": "query", "schema": {"type": "string", "enum": ["request", "post.created", "post.updated", "post.patched", "post.restored", "post.deleted", "post.scheduled", "post.publish_claimed", "post.publish_completed"]}},
+      "AuditPostID": {"name": "post_id", "in": "query", "schema": {"type": "string"}},
+      "AuditSince": {"name": "since", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+      "AuditUntil": {"name": "until", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+      "Format": {"name": "format", "in": "query", "schema": {"type": "string", "enum": ["csv", "jsonl"]}},
+      "Dedupe": {"name": "dedupe", "in": "query", "schema": {"type": "boolean"}},
+      "IdempotencyKey": {"name": "Idempotency-Key", "in": "header", "description": "Replays the response of an earlier request with the same key", "schema": {"type": "string", "maxLength": 255}}
+    },
+    "responses": {
+      "Problem": {"description": "An error", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}
+    },
+    "schemas": {
+      "Problem": {
+        "type": "object",
+        "required": ["type", "title", "status", "code"],
+        "properties": {
+          "type": {"type": "string"},
+          "title": {"type": "string"},
+          "status": {"type": "integer"},
+          "detail": {"type": "string"},
+          "instance": {"type": "string"},
+          "code": {"type": "string"},
+          "errors": {"type": "array", "items": {"$ref": "#/components/schemas/FieldError"}}
+        }
+      },
+      "FieldError": {
+        "type": "object",
+        "required": ["field", "message"],
+        "properties": {
+          "field": {"type": "string"},
+          "message": {"type": "string"}
+        }
+      },
+      "Post": {
+        "type": "object",
+        "required": ["url"],
+        "properties": {
+          "id": {"type": "string"},
+          "url": {"type": "string"},
+          "canonical_url": {"type": "string"},
+          "captions": {"type": "array", "items": {"$ref": "#/components/schemas/PostCaption"}},
+          "rejected_captions": {"type": "array", "description": "Generated captions that broke a reject rule", "items": {"$ref": "#/components/schemas/Rejection"}},
+          "status": {"type": "string", "enum": ["draft", "approved", "publishing", "published", "publish_failed"]},
+          "created_at": {"type": "string", "format": "date-time"},
+          "created_by": {"type": "string"},
+          "updated_at": {"type": "string", "format": "date-time"},
+          "updated_by": {"type": "string"},
+          "scheduled_at": {"type": "string", "format": "date-time"},
+          "channels": {"type": "array", "items": {"type": "string"}},
+          "published_at": {"type": "string", "format": "date-time"},
+          "publications": {"type": "array", "items": {"$ref": "#/components/schemas/Publication"}}
+        }
+      },
+      "PostCaption": {
+        "description": "The text of the caption on /v1, the caption on /v2",
+        "oneOf": [{"type": "string"}, {"$ref": "#/components/schemas/Caption"}]
+      },
+      "Caption": {
+        "type": "object",
+        "required": ["id", "text", "source", "selected", "pinned"],
+        "properties": {
+          "id": {"type": "string"},
+          "text": {"type": "string"},
+          "source": {"type": "string", "enum": ["generated", "manual"]},
+          "score": {"type": "number", "description": "How well a generated caption ranked, missing for manual captions"},
+          "selected": {"type": "boolean"},
+          "pinned": {"type": "boolean"},
+          "findings": {"type": "array", "description": "The quality rules a generated caption broke and what was done about it", "items": {"$ref": "#/components/schemas/Finding"}}
+        }
+      },
+      "Finding": {
+        "type": "object",
+        "required": ["rule", "action", "message"],
+        "properties": {
+          "rule": {"type": "string"},
+          "action": {"type": "string", "enum": ["reject", "flag", "fix"]},
+          "message": {"type": "string"}
+        }
+      },
+      "Rejection": {
+        "type": "object",
+        "required": ["text", "findings"],
+        "properties": {
+          "text": {"type": "string"},
+          "findings": {"type": "array", "items": {"$ref": "#/components/schemas/Finding"}}
+        }
+      },
+      "Publication": {
+        "type": "object",
+        "required": ["channel", "at"],
+        "properties": {
+          "channel": {"type": "string"},
+          "at": {"type": "string", "format": "date-time"},
+          "external_id": {"type": "string"},
+          "error": {"type": "string"}
+        }
+      },
+      "PostPage": {
+        "type": "object",
+        "required": ["posts"],
+        "properties": {
+          "posts": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Post"}},
+          "next": {"type": "string", "description": "The after of the next page, missing on the last page"}
+        }
+      },
+      "PostRequest": {
+        "type": "object",
+        "required": ["url"],
+        "properties": {
+          "url": {"type": "string"},
+          "captions": {"type": "array", "nullable": true, "items": {"type": "string"}},
+          "template": {"type": "string", "description": "The id of a template the generated captions are rendered with"}
+        }
+      },
+      "PutRequest": {
+        "type": "object",
+        "properties": {
+          "captions": {"type": "array", "nullable": true, "items": {"type": "string"}}
+        }
+      },
+      "MergePatch": {
+        "type": "object",
+        "properties": {
+          "captions": {"type": "array", "nullable": true, "items": {"description": "The text of a caption, or a caption of which only the id, text, selected and pinned members are used"}}
+        }
+      },
+      "ReorderCaptionsRequest": {
+        "type": "object",
+        "required": ["ids"],
+        "properties": {
+          "ids": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "JSONPatch": {
+        "type": "array",
+        "items": {
+          "type": "object",
+          "required": ["op", "path"],
+          "properties": {
+            "op": {"type": "string", "enum": ["add", "remove", "replace", "move", "copy", "test"]},
+            "path": {"type": "string"},
+            "from": {"type": "string"},
+            "value": {}
+          }
+        }
+      },
+      "ScheduleRequest": {
+        "type": "object",
+        "properties": {
+          "scheduled_at": {"type": "string", "format": "date-time", "nullable": true},
+          "channels": {"type": "array", "nullable": true, "items": {"type": "string"}}
+        }
+      },
+      "BatchCreateRequest": {
+        "type": "object",
+        "required": ["items"],
+        "properties": {
+          "items": {"type": "array", "minItems": 1, "maxItems": 100, "items": {"$ref": "#/components/schemas/PostRequest"}}
+        }
+      },
+      "BatchUpdateRequest": {
+        "type": "object",
+        "required": ["items"],
+        "properties": {
+          "items": {
+            "type": "array",
+            "minItems": 1,
+            "maxItems": 100,
+            "items": {
+              "type": "object",
+              "required": ["id"],
+              "properties": {
+                "id": {"type": "string"},
+                "captions": {"type": "array", "nullable": true, "items": {"type": "string"}}
+              }
+            }
+          }
+        }
+      },
+      "BatchResponse": {
+        "type": "object",
+        "required": ["results"],
+        "properties": {
+          "results": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["index", "status"],
+              "properties": {
+                "index": {"type": "integer"},
+                "status": {"type": "integer"},
+                "existing": {"type": "boolean"},
+                "post": {"$ref": "#/components/schemas/Post"},
+                "error": {"$ref": "#/components/schemas/Problem"}
+              }
+            }
+          }
+        }
+      },
+      "Revision": {
+        "type": "object",
+        "required": ["number", "post_id", "created_at", "captions", "diff"],
+        "properties": {
+          "number": {"type": "integer"},
+          "post_id": {"type": "string"},
+          "author": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "captions": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/PostCaption"}},
+          "status": {"type": "string"},
+          "diff": {
+            "type": "array",
+            "nullable": true,
+            "items": {
+              "type": "object",
+              "required": ["op", "index", "caption"],
+              "properties": {
+                "op": {"type": "string"},
+                "index": {"type": "integer"},
+                "caption": {"type": "string"}
+              }
+            }
+          }
+        }
+      },
+      "ImportResponse": {
+        "type": "object",
+        "required": ["created", "failed", "errors"],
+        "properties": {
+          "created": {"type": "integer"},
+          "failed": {"type": "integer"},
+          "errors": {
+            "type": "array",
+            "nullable": true,
+            "items": {
+              "type": "object",
+              "required": ["line", "error"],
+              "properties": {
+                "line": {"type": "integer"},
+                "error": {"$ref": "#/components/schemas/Problem"}
+              }
+            }
+          }
+        }
+      },
+      "Event": {
+        "type": "object",
+        "required": ["offset", "id", "type", "post_id", "occurred_at"],
+        "properties": {
+          "offset": {"type": "integer"},
+          "id": {"type": "string"},
+          "type": {"type": "string", "enum": ["created", "captions_updated", "status_changed", "deleted"]},
+          "post_id": {"type": "string"},
+          "actor": {"type": "string"},
+          "occurred_at": {"type": "string", "format": "date-time"},
+          "post": {"$ref": "#/components/schemas/Post"}
+        }
+      },
+      "AuditEntry": {
+        "type": "object",
+        "required": ["sequence", "customer_id", "occurred_at", "action", "prev_hash", "hash"],
+        "properties": {
+          "sequence": {"type": "integer"},
+          "customer_id": {"type": "string"},
+          "occurred_at": {"type": "string", "format": "date-time"},
+          "actor": {"type": "string"},
+          "action": {"type": "string"},
+          "post_id": {"type": "string"},
+          "ip": {"type": "string"},
+          "method": {"type": "string"},
+          "path": {"type": "string"},
+          "status": {"type": "integer"},
+          "before": {"$ref": "#/components/schemas/Post"},
+          "after": {"$ref": "#/components/schemas/Post"},
+          "prev_hash": {"type": "string"},
+          "hash": {"type": "string"}
+        }
+      },
+      "AuditPage": {
+        "type": "object",
+        "required": ["entries"],
+        "properties": {
+          "entries": {"type": "array", "items": {"$ref": "#/components/schemas/AuditEntry"}},
+          "next": {"type": "integer", "description": "The after of the next page, missing on the last page"}
+        }
+      },
+      "EventsResponse": {
+        "type": "object",
+        "required": ["events", "next_offset"],
+        "properties": {
+          "events": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Event"}},
+          "next_offset": {"type": "integer"}
+        }
+      },
+      "Key": {
+        "type": "object",
+        "required": ["id", "prefix", "created_at"],
+        "properties": {
+          "id": {"type": "string"},
+          "prefix": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "revoked_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "KeyWithSecret": {
+        "type": "object",
+        "required": ["id", "prefix", "created_at", "secret"],
+        "properties": {
+          "id": {"type": "string"},
+          "prefix": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "revoked_at": {"type": "string", "format": "date-time"},
+          "secret": {"type": "string", "description": "Only returned when the key is created"}
+        }
+      },
+      "WebhookRequest": {
+        "type": "object",
+        "required": ["url", "event_types"],
+        "properties": {
+          "url": {"type": "string"},
+          "event_types": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Subscription": {
+        "type": "object",
+        "required": ["id", "url", "event_types", "created_at"],
+        "properties": {
+          "id": {"type": "string"},
+          "url": {"type": "string"},
+          "event_types": {"type": "array", "nullable": true, "items": {"type": "string"}},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "SubscriptionWithSecret": {
+        "type": "object",
+        "required": ["id", "url", "event_types", "created_at", "secret"],
+        "properties": {
+          "id": {"type": "string"},
+          "url": {"type": "string"},
+          "event_types": {"type": "array", "nullable": true, "items": {"type": "string"}},
+          "created_at": {"type": "string", "format": "date-time"},
+          "secret": {"type": "string", "description": "Signs the deliveries, only returned when the subscription is created"}
+        }
+      },
+      "Delivery": {
+        "type": "object",
+        "required": ["id", "subscription_id", "event_id", "event_type", "status", "attempts", "created_at"],
+        "properties": {
+          "id": {"type": "string"},
+          "subscription_id": {"type": "string"},
+          "event_id": {"type": "string"},
+          "event_type": {"type": "string"},
+          "status": {"type": "string", "enum": ["pending", "succeeded", "dead"]},
+          "attempts": {
+            "type": "array",
+            "nullable": true,
+            "items": {
+              "type": "object",
+              "required": ["at"],
+              "properties": {
+                "at": {"type": "string", "format": "date-time"},
+                "status_code": {"type": "integer"},
+                "error": {"type": "string"}
+              }
+            }
+          },
+          "next_attempt_at": {"type": "string", "format": "date-time"},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "Usage": {
+        "type": "object",
+        "required": ["customer_id", "period", "used", "limit", "resets_at"],
+        "properties": {
+          "customer_id": {"type": "string"},
+          "period": {"type": "string"},
+          "used": {"type": "integer"},
+          "limit": {"type": "integer"},
+          "resets_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "QualityRule": {
+        "type": "object",
+        "required": ["type", "action"],
+        "properties": {
+          "type": {"type": "string", "enum": ["profanity", "blocklist", "brand_terms", "readability", "fragment"]},
+          "action": {"type": "string", "enum": ["reject", "flag", "fix"]},
+          "terms": {"type": "array", "description": "The terms of blocklist and brand_terms rules", "items": {"type": "string"}},
+          "min_score": {"type": "number", "description": "The lowest readability score of readability rules"}
+        }
+      },
+      "QualityRulesRequest": {
+        "type": "object",
+        "required": ["rules"],
+        "properties": {
+          "rules": {"type": "array", "maxItems": 20, "items": {"$ref": "#/components/schemas/QualityRule"}}
+        }
+      },
+      "QualityRules": {
+        "type": "object",
+        "required": ["rules", "default"],
+        "properties": {
+          "rules": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/QualityRule"}},
+          "default": {"type": "boolean", "description": "Set when the customer has no rules of their own"}
+        }
+      },
+      "TemplateRequest": {
+        "type": "object",
+        "required": ["name", "text"],
+        "properties": {
+          "name": {"type": "string"},
+          "text": {"type": "string", "description": "A Go text/template, e.g. {{summary}} Read more: {{short_url}} {{hashtags}}"}
+        }
+      },
+      "Template": {
+        "type": "object",
+        "required": ["id", "name", "text", "created_at", "updated_at"],
+        "properties": {
+          "id": {"type": "string"},
+          "name": {"type": "string"},
+          "text": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "updated_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "TemplatePreviewRequest": {
+        "type": "object",
+        "required": ["url"],
+        "properties": {
+          "url": {"type": "string"},
+          "template": {"type": "string", "description": "The id of the template to render"},
+          "text": {"type": "string", "description": "The text of a template that is not stored, when template is not set"},
+          "captions": {"type": "array", "nullable": true, "items": {"type": "string"}}
+        }
+      },
+      "TemplatePreview": {
+        "type": "object",
+        "required": ["url", "captions"],
+        "properties": {
+          "url": {"type": "string"},
+          "captions": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Caption"}},
+          "rejected_captions": {"type": "array", "items": {"$ref": "#/components/schemas/Rejection"}}
+        }
+      },
+      "WarmRequest": {
+        "type": "object",
+        "required": ["urls"],
+        "properties": {
+          "urls": {"type": "array", "minItems": 1, "maxItems": 100, "items": {"type": "string"}}
+        }
+      },
+      "WarmResponse": {
+        "type": "object",
+        "required": ["results"],
+        "properties": {
+          "results": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["index", "url", "status"],
+              "properties": {
+                "index": {"type": "integer"},
+                "url": {"type": "string"},
+                "status": {"type": "integer"},
+                "error": {"$ref": "#/components/schemas/Problem"}
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+var (
+	defaultOnce sync.Once
+	defaultDoc  *Document
+)
+
+// Default returns the loaded Spec. It panics if Spec is invalid, which the tests
+// of this package rule out
+func Default() *Document {
+	defaultOnce.Do(func() {
+		doc, err := Load([]byte(Spec))
+		if err != nil {
+			panic(err)
+		}
+		defaultDoc = doc
+	})
+	return defaultDoc
+}
@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// ValidateRequest returns a *datastore.Validation naming every parameter and
+// part of the json body of the request that does not match the operation.
+// pathParams are the values of the templated path segments by name. A json body
+// is read and replaced, so handlers can still read it; other bodies are left
+// alone so they can be streamed
+func (r *Route) ValidateRequest(req *http.Request, pathParams map[string]string) error {
+	verr := datastore.NewValidationError()
+	query := req.URL.Query()
+	for _, param := range r.Operation.Parameters {
+		var value string
+		var ok bool
+		switch param.In {
+		case "path":
+			value, ok = pathParams[param.Name]
+		case "query":
+			value = query.Get(param.Name)
+			ok = value != ""
+		case "header":
+			value = req.Header.Get(param.Name)
+			ok = value != ""
+		default:
+			continue
+		}
+		if !ok {
+			if param.Required {
+				verr.Add(param.Name, "is required")
+			}
+			continue
+		}
+		param.Schema.Validate(verr, param.Name, coerce(param.Schema, value))
+	}
+
+	if rb := r.Operation.RequestBody; rb != nil {
+		if err := validateRequestBody(verr, rb, req); err != nil {
+			return err
+		}
+	}
+	if verr.HasErrors() {
+		return verr
+	}
+	return nil
+}
+
+func validateRequestBody(verr *datastore.Validation, rb *RequestBody, req *http.Request) error {
+	contentType := req.Header.Get("Content-Type")
+	if req.Body == nil || req.ContentLength == 0 {
+		if rb.Required {
+			verr.Add("body", "is required")
+		}
+		return nil
+	}
+	media, ok := mediaFor(rb.Content, contentType)
+	if !ok {
+		verr.Add("body", fmt.Sprintf("content type %q is not supported", contentType))
+		return nil
+	}
+	if !isJSON(media.mediaType) {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		if rb.Required {
+			verr.Add("body", "is required")
+		}
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		verr.Add("body", "must be valid json")
+		return nil
+	}
+	bodyErr := datastore.NewValidationError()
+	media.Schema.Validate(bodyErr, "", value)
+	for _, f := range bodyErr.Fields {
+		if f.Field == "" {
+			f.Field = "body"
+		}
+		verr.Add(f.Field, f.Message)
+	}
+	return nil
+}
+
+// ValidateResponse returns an error if the status is not documented for the
+// operation or the json body does not match the documented schema
+func (r *Route) ValidateResponse(status int, contentType string, body []byte) error {
+	resp := r.response(status)
+	if resp == nil {
+		return fmt.Errorf("%s %s: status %d is not documented", r.Method, r.Path, status)
+	}
+	if len(resp.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("%s %s: status %d should not have a body", r.Method, r.Path, status)
+		}
+		return nil
+	}
+	media, ok := mediaFor(resp.Content, contentType)
+	if !ok {
+		return fmt.Errorf("%s %s: content type %q is not documented for status %d", r.Method, r.Path, contentType, status)
+	}
+	if !isJSON(media.mediaType) {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s: status %d body is not valid json: %v", r.Method, r.Path, status, err)
+	}
+	verr := datastore.NewValidationError()
+	media.Schema.Validate(verr, "", value)
+	if verr.HasErrors() {
+		return fmt.Errorf("%s %s: status %d: %v", r.Method, r.Path, status, verr)
+	}
+	return nil
+}
+
+// response returns the response documented for the status, falling back to its
+// class (2XX). Errors fall back to default, a success has to be documented
+func (r *Route) response(status int) *Response {
+	code := strconv.Itoa(status)
+	keys := []string{code, code[:1] + "XX"}
+	if status >= http.StatusBadRequest {
+		keys = append(keys, "default")
+	}
+	for _, key := range keys {
+		if resp, ok := r.Operation.Responses[key]; ok {
+			return resp
+		}
+	}
+	return nil
+}
+
+type media struct {
+	*MediaType
+	mediaType string
+}
+
+// mediaFor returns the media type matching the content type, */* matches any.
+// A request without a content type matches an operation that accepts a single
+// one
+func mediaFor(content map[string]*MediaType, contentType string) (*media, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+	if mt, ok := content[mediaType]; ok {
+		return &media{MediaType: mt, mediaType: mediaType}, true
+	}
+	if mt, ok := content["*/*"]; ok {
+		return &media{MediaType: mt, mediaType: "*/*"}, true
+	}
+	if mediaType == "" && len(content) == 1 {
+		for name, mt := range content {
+			return &media{MediaType: mt, mediaType: name}, true
+		}
+	}
+	return nil, false
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// coerce converts a parameter to the type of its schema so it can be validated
+// like a json value. Values that do not convert are left as strings and fail
+// validation
+func coerce(s *Schema, value string) interface{} {
+	switch s.resolve().Type {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
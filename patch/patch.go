@@ -0,0 +1,360 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Content types of the two patch formats
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// JSON Patch operations
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// Operation is one operation of a JSON Patch, RFC 6902
+type Operation struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// Error is a JSON Patch that can not be applied. Index is the operation that
+// failed, TestFailed is set when it was a test whose value did not match
+type Error struct {
+	Index      int
+	Message    string
+	TestFailed bool
+}
+
+// Error implements the Error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Message)
+}
+
+// Merge applies a JSON Merge Patch, RFC 7396, to doc. Objects in the patch are
+// merged into the document, a null removes the member and anything else
+// replaces it. doc is not changed
+func Merge(doc, patch interface{}) interface{} {
+	fields, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	target, ok := doc.(map[string]interface{})
+	if !ok {
+		target = map[string]interface{}{}
+	}
+
+	merged := make(map[string]interface{}, len(target))
+	for name, value := range target {
+		merged[name] = value
+	}
+	for name, value := range fields {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = Merge(merged[name], value)
+	}
+	return merged
+}
+
+// Decode reads a JSON Patch, checking every operation has the members its op
+// needs
+func Decode(data []byte) ([]Operation, error) {
+	raw := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("a json patch must be an array of operations: %s", err.Error())
+	}
+
+	ops := make([]Operation, len(raw))
+	for i, members := range raw {
+		op := Operation{}
+		if err := decodeString(members, "op", &op.Op); err != nil {
+			return nil, &Error{Index: i, Message: err.Error()}
+		}
+		if err := decodeString(members, "path", &op.Path); err != nil {
+			return nil, &Error{Index: i, Message: err.Error()}
+		}
+		switch op.Op {
+		case OpAdd, OpReplace, OpTest:
+			value, ok := members["value"]
+			if !ok {
+				return nil, &Error{Index: i, Message: `"value" is required`}
+			}
+			if err := json.Unmarshal(value, &op.Value); err != nil {
+				return nil, &Error{Index: i, Message: err.Error()}
+			}
+		case OpMove, OpCopy:
+			if err := decodeString(members, "from", &op.From); err != nil {
+				return nil, &Error{Index: i, Message: err.Error()}
+			}
+		case OpRemove:
+		default:
+			return nil, &Error{Index: i, Message: fmt.Sprintf(`"op" must be one of %s`, strings.Join([]string{OpAdd, OpRemove, OpReplace, OpMove, OpCopy, OpTest}, ", "))}
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+// Apply applies the operations of a JSON Patch to doc in order. Either every
+// operation is applied or an *Error is returned, doc is not changed
+func Apply(doc interface{}, ops []Operation) (interface{}, error) {
+	doc = deepCopy(doc)
+	for i, op := range ops {
+		var err error
+		doc, err = apply(doc, op)
+		if err != nil {
+			if perr, ok := err.(*Error); ok {
+				perr.Index = i
+				return nil, perr
+			}
+			return nil, &Error{Index: i, Message: err.Error()}
+		}
+	}
+	return doc, nil
+}
+
+func apply(doc interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case OpAdd:
+		return add(doc, path, deepCopy(op.Value))
+	case OpRemove:
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		return remove(doc, path)
+	case OpReplace:
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return deepCopy(op.Value), nil
+		}
+		doc, err = remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(op.Value))
+	case OpMove:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("can not move %q into one of its children", op.From)
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case OpCopy:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value))
+	case OpTest:
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.Value) {
+			return nil, &Error{Message: fmt.Sprintf("the value at %q is not the tested value", op.Path), TestFailed: true}
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
+
+// parsePointer splits a JSON Pointer, RFC 6901, into its unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must be empty or start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// get returns the value at the path
+func get(doc interface{}, path []string) (interface{}, error) {
+	for i, token := range path {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, notFound(path[:i+1])
+			}
+			doc = value
+		case []interface{}:
+			index, err := parseIndex(token, len(container)-1)
+			if err != nil {
+				return nil, notFound(path[:i+1])
+			}
+			doc = container[index]
+		default:
+			return nil, notFound(path[:i+1])
+		}
+	}
+	return doc, nil
+}
+
+// add returns doc with the value added at the path. A member of an object is
+// set, and a value is inserted into an array before the index, or appended for -
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	return update(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			index := len(container)
+			if token != "-" {
+				var err error
+				if index, err = parseIndex(token, len(container)); err != nil {
+					return nil, err
+				}
+			}
+			added := make([]interface{}, 0, len(container)+1)
+			added = append(added, container[:index]...)
+			added = append(added, value)
+			return append(added, container[index:]...), nil
+		}
+		return nil, notFound(path)
+	}, value)
+}
+
+// remove returns doc without the value at the path
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("can not remove the whole document")
+	}
+	return update(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			index, err := parseIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(container[:index:index], container[index+1:]...), nil
+		}
+		return nil, notFound(path)
+	}, nil)
+}
+
+// update returns doc with the parent of the last token of the path replaced by
+// what change returns for it. An empty path replaces the whole document with
+// root
+func update(doc interface{}, path []string, change func(interface{}, string) (interface{}, error), root interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return root, nil
+	}
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+
+	child, err := get(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = update(child, path[1:], change, root)
+	if err != nil {
+		return nil, err
+	}
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		container[path[0]] = child
+	case []interface{}:
+		index, _ := parseIndex(path[0], len(container)-1)
+		container[index] = child
+	}
+	return doc, nil
+}
+
+// parseIndex returns the array index of the token, it must be at most max
+func parseIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || strconv.Itoa(index) != token {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("index %d is out of bounds", index)
+	}
+	return index, nil
+}
+
+func notFound(path []string) error {
+	escaped := make([]string, len(path))
+	for i, token := range path {
+		escaped[i] = strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+	}
+	return fmt.Errorf("path %q does not exist", "/"+strings.Join(escaped, "/"))
+}
+
+func decodeString(members map[string]json.RawMessage, name string, out *string) error {
+	raw, ok := members[name]
+	if !ok || bytes.Equal(raw, []byte("null")) {
+		return fmt.Errorf("%q is required", name)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%q must be a string", name)
+	}
+	return nil
+}
+
+// deepCopy copies the objects and arrays of a decoded json value
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for name, member := range v {
+			copied[name] = deepCopy(member)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+	return value
+}
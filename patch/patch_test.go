@@ -0,0 +1,116 @@
+package patch
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func decode(data string) interface{} {
+	var v interface{}
+	ExpectWithOffset(1, json.Unmarshal([]byte(data), &v)).To(Succeed())
+	return v
+}
+
+func encode(v interface{}) string {
+	data, err := json.Marshal(v)
+	ExpectWithOffset(1, err).To(BeNil())
+	return string(data)
+}
+
+var _ = Describe("Merge", func() {
+	It("should merge objects and remove null members", func() {
+		doc := decode(`{"title": "Goodbye!", "author": {"givenName": "John", "familyName": "Doe"}, "tags": ["example", "sample"], "content": "This will be unchanged"}`)
+		merged := Merge(doc, decode(`{"title": "Hello!", "phoneNumber": "+01-123-456-7890", "author": {"familyName": null}, "tags": ["example"]}`))
+		Expect(encode(merged)).To(MatchJSON(`{"title": "Hello!", "author": {"givenName": "John"}, "tags": ["example"], "content": "This will be unchanged", "phoneNumber": "+01-123-456-7890"}`))
+		Expect(encode(doc)).To(ContainSubstring(`"familyName":"Doe"`))
+	})
+
+	It("should replace the document with a patch that is not an object", func() {
+		Expect(Merge(decode(`{"a": "b"}`), decode(`["c"]`))).To(Equal(decode(`["c"]`)))
+		Expect(encode(Merge(decode(`["a"]`), decode(`{"a": "b"}`)))).To(MatchJSON(`{"a": "b"}`))
+	})
+})
+
+var _ = Describe("JSON Patch", func() {
+	apply := func(doc, ops string) (string, error) {
+		decoded, err := Decode([]byte(ops))
+		if err != nil {
+			return "", err
+		}
+		patched, err := Apply(decode(doc), decoded)
+		if err != nil {
+			return "", err
+		}
+		return encode(patched), nil
+	}
+
+	It("should apply the operations", func() {
+		cases := []struct {
+			name, doc, ops, expected string
+		}{
+			{"add a member", `{"foo": "bar"}`, `[{"op": "add", "path": "/baz", "value": "qux"}]`, `{"foo": "bar", "baz": "qux"}`},
+			{"insert into an array", `{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, `{"foo": ["bar", "qux", "baz"]}`},
+			{"append to an array", `{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/-", "value": "qux"}]`, `{"foo": ["bar", "qux"]}`},
+			{"remove a member", `{"baz": "qux", "foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `{"foo": "bar"}`},
+			{"remove an item", `{"foo": ["bar", "qux", "baz"]}`, `[{"op": "remove", "path": "/foo/1"}]`, `{"foo": ["bar", "baz"]}`},
+			{"replace an item", `{"foo": ["bar", "baz"]}`, `[{"op": "replace", "path": "/foo/0", "value": "qux"}]`, `{"foo": ["qux", "baz"]}`},
+			{"replace the document", `{"foo": "bar"}`, `[{"op": "replace", "path": "", "value": {"baz": null}}]`, `{"baz": null}`},
+			{"move a member", `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`, `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`, `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`},
+			{"move an item", `{"foo": ["all", "grass", "cows", "eat"]}`, `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`, `{"foo": ["all", "cows", "eat", "grass"]}`},
+			{"copy an item", `{"foo": ["a", "b"]}`, `[{"op": "copy", "from": "/foo/0", "path": "/foo/-"}]`, `{"foo": ["a", "b", "a"]}`},
+			{"test a value", `{"baz": "qux", "foo": ["a", 2, "c"]}`, `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`, `{"baz": "qux", "foo": ["a", 2, "c"]}`},
+			{"escaped paths", `{"a/b": 1, "m~n": 2}`, `[{"op": "replace", "path": "/a~1b", "value": 3}, {"op": "remove", "path": "/m~0n"}]`, `{"a/b": 3}`},
+			{"add a null value", `{}`, `[{"op": "add", "path": "/foo", "value": null}]`, `{"foo": null}`},
+		}
+		for _, c := range cases {
+			patched, err := apply(c.doc, c.ops)
+			Expect(err).To(BeNil(), c.name)
+			Expect(patched).To(MatchJSON(c.expected), c.name)
+		}
+	})
+
+	It("should reject operations that can not be applied", func() {
+		cases := []struct {
+			name, doc, ops string
+			index          int
+			message        string
+		}{
+			{"remove a missing member", `{"foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, 0, `path "/baz" does not exist`},
+			{"replace a missing item", `{"foo": ["bar"]}`, `[{"op": "test", "path": "/foo/0", "value": "bar"}, {"op": "replace", "path": "/foo/1", "value": "qux"}]`, 1, `path "/foo/1" does not exist`},
+			{"add past the end", `{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/2", "value": "qux"}]`, 0, `index 2 is out of bounds`},
+			{"add to a missing parent", `{}`, `[{"op": "add", "path": "/foo/bar", "value": "qux"}]`, 0, `path "/foo" does not exist`},
+			{"an index with a leading zero", `{"foo": ["bar", "baz"]}`, `[{"op": "remove", "path": "/foo/01"}]`, 0, `path "/foo/01" does not exist`},
+			{"a relative path", `{}`, `[{"op": "add", "path": "foo", "value": 1}]`, 0, `path "foo" must be empty or start with /`},
+			{"move into a child", `{"foo": {}}`, `[{"op": "move", "from": "/foo", "path": "/foo/bar"}]`, 0, `can not move "/foo" into one of its children`},
+			{"remove the document", `{}`, `[{"op": "remove", "path": ""}]`, 0, `can not remove the whole document`},
+			{"an unknown op", `{}`, `[{"op": "merge", "path": "/foo"}]`, 0, `"op" must be one of add, remove, replace, move, copy, test`},
+			{"a missing value", `{}`, `[{"op": "add", "path": "/foo"}]`, 0, `"value" is required`},
+			{"a missing from", `{}`, `[{"op": "add", "path": "/foo", "value": 1}, {"op": "copy", "path": "/foo"}]`, 1, `"from" is required`},
+		}
+		for _, c := range cases {
+			_, err := apply(c.doc, c.ops)
+			Expect(err).To(Equal(&Error{Index: c.index, Message: c.message}), c.name)
+		}
+	})
+
+	It("should fail a test whose value does not match", func() {
+		_, err := apply(`{"foo": ["bar"]}`, `[{"op": "test", "path": "/foo", "value": ["baz"]}]`)
+		Expect(err).To(Equal(&Error{Message: `the value at "/foo" is not the tested value`, TestFailed: true}))
+	})
+
+	It("should not change the document when an operation fails", func() {
+		doc := decode(`{"foo": ["bar"]}`)
+		ops, err := Decode([]byte(`[{"op": "add", "path": "/foo/-", "value": "baz"}, {"op": "remove", "path": "/qux"}]`))
+		Expect(err).To(BeNil())
+		_, err = Apply(doc, ops)
+		Expect(err).NotTo(BeNil())
+		Expect(encode(doc)).To(MatchJSON(`{"foo": ["bar"]}`))
+	})
+
+	It("should reject a patch that is not an array", func() {
+		_, err := Decode([]byte(`{"op": "add"}`))
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,938 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: post.proto
+
+package postpb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Post struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url                  string   `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	CanonicalUrl         string   `protobuf:"bytes,3,opt,name=canonical_url,json=canonicalUrl,proto3" json:"canonical_url,omitempty"`
+	Captions             []string `protobuf:"bytes,4,rep,name=captions,proto3" json:"captions,omitempty"`
+	Status               string   `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	UpdatedBy            string   `protobuf:"bytes,6,opt,name=updated_by,json=updatedBy,proto3" json:"updated_by,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Post) Reset()         { *m = Post{} }
+func (m *Post) String() string { return proto.CompactTextString(m) }
+func (*Post) ProtoMessage()    {}
+func (*Post) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{0}
+}
+
+func (m *Post) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Post.Unmarshal(m, b)
+}
+func (m *Post) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Post.Marshal(b, m, deterministic)
+}
+func (m *Post) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Post.Merge(m, src)
+}
+func (m *Post) XXX_Size() int {
+	return xxx_messageInfo_Post.Size(m)
+}
+func (m *Post) XXX_DiscardUnknown() {
+	xxx_messageInfo_Post.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Post proto.InternalMessageInfo
+
+func (m *Post) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Post) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *Post) GetCanonicalUrl() string {
+	if m != nil {
+		return m.CanonicalUrl
+	}
+	return ""
+}
+
+func (m *Post) GetCaptions() []string {
+	if m != nil {
+		return m.Captions
+	}
+	return nil
+}
+
+func (m *Post) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Post) GetUpdatedBy() string {
+	if m != nil {
+		return m.UpdatedBy
+	}
+	return ""
+}
+
+type CreateRequest struct {
+	Url                  string   `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Captions             []string `protobuf:"bytes,2,rep,name=captions,proto3" json:"captions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateRequest) Reset()         { *m = CreateRequest{} }
+func (m *CreateRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateRequest) ProtoMessage()    {}
+func (*CreateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{1}
+}
+
+func (m *CreateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateRequest.Unmarshal(m, b)
+}
+func (m *CreateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateRequest.Merge(m, src)
+}
+func (m *CreateRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateRequest.Size(m)
+}
+func (m *CreateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateRequest proto.InternalMessageInfo
+
+func (m *CreateRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *CreateRequest) GetCaptions() []string {
+	if m != nil {
+		return m.Captions
+	}
+	return nil
+}
+
+type GenerateRequest struct {
+	Url                  string   `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GenerateRequest) Reset()         { *m = GenerateRequest{} }
+func (m *GenerateRequest) String() string { return proto.CompactTextString(m) }
+func (*GenerateRequest) ProtoMessage()    {}
+func (*GenerateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{2}
+}
+
+func (m *GenerateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GenerateRequest.Unmarshal(m, b)
+}
+func (m *GenerateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GenerateRequest.Marshal(b, m, deterministic)
+}
+func (m *GenerateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GenerateRequest.Merge(m, src)
+}
+func (m *GenerateRequest) XXX_Size() int {
+	return xxx_messageInfo_GenerateRequest.Size(m)
+}
+func (m *GenerateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GenerateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GenerateRequest proto.InternalMessageInfo
+
+func (m *GenerateRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+type GetRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRequest) Reset()         { *m = GetRequest{} }
+func (m *GetRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRequest) ProtoMessage()    {}
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{3}
+}
+
+func (m *GetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRequest.Unmarshal(m, b)
+}
+func (m *GetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRequest.Marshal(b, m, deterministic)
+}
+func (m *GetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRequest.Merge(m, src)
+}
+func (m *GetRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRequest.Size(m)
+}
+func (m *GetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRequest proto.InternalMessageInfo
+
+func (m *GetRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type UpdateRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Captions             []string `protobuf:"bytes,2,rep,name=captions,proto3" json:"captions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateRequest) Reset()         { *m = UpdateRequest{} }
+func (m *UpdateRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateRequest) ProtoMessage()    {}
+func (*UpdateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{4}
+}
+
+func (m *UpdateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateRequest.Unmarshal(m, b)
+}
+func (m *UpdateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateRequest.Merge(m, src)
+}
+func (m *UpdateRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateRequest.Size(m)
+}
+func (m *UpdateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateRequest proto.InternalMessageInfo
+
+func (m *UpdateRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *UpdateRequest) GetCaptions() []string {
+	if m != nil {
+		return m.Captions
+	}
+	return nil
+}
+
+type DeleteRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
+func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteRequest) ProtoMessage()    {}
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{5}
+}
+
+func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
+}
+func (m *DeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteRequest.Merge(m, src)
+}
+func (m *DeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteRequest.Size(m)
+}
+func (m *DeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteRequest proto.InternalMessageInfo
+
+func (m *DeleteRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type ListRequest struct {
+	// after is the id of the post the page starts after.
+	After string `protobuf:"bytes,1,opt,name=after,proto3" json:"after,omitempty"`
+	// limit is at most 100, 20 when it is 0.
+	Limit                int32    `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRequest) Reset()         { *m = ListRequest{} }
+func (m *ListRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRequest) ProtoMessage()    {}
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{6}
+}
+
+func (m *ListRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRequest.Unmarshal(m, b)
+}
+func (m *ListRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRequest.Marshal(b, m, deterministic)
+}
+func (m *ListRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRequest.Merge(m, src)
+}
+func (m *ListRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRequest.Size(m)
+}
+func (m *ListRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRequest proto.InternalMessageInfo
+
+func (m *ListRequest) GetAfter() string {
+	if m != nil {
+		return m.After
+	}
+	return ""
+}
+
+func (m *ListRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListResponse struct {
+	Posts []*Post `protobuf:"bytes,1,rep,name=posts,proto3" json:"posts,omitempty"`
+	// next is the after of the next page, it is empty on the last page.
+	Next                 string   `protobuf:"bytes,2,opt,name=next,proto3" json:"next,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListResponse) Reset()         { *m = ListResponse{} }
+func (m *ListResponse) String() string { return proto.CompactTextString(m) }
+func (*ListResponse) ProtoMessage()    {}
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{7}
+}
+
+func (m *ListResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListResponse.Unmarshal(m, b)
+}
+func (m *ListResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListResponse.Marshal(b, m, deterministic)
+}
+func (m *ListResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListResponse.Merge(m, src)
+}
+func (m *ListResponse) XXX_Size() int {
+	return xxx_messageInfo_ListResponse.Size(m)
+}
+func (m *ListResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListResponse proto.InternalMessageInfo
+
+func (m *ListResponse) GetPosts() []*Post {
+	if m != nil {
+		return m.Posts
+	}
+	return nil
+}
+
+func (m *ListResponse) GetNext() string {
+	if m != nil {
+		return m.Next
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	// after is the offset of the last event seen, 0 for every event.
+	After                int64    `protobuf:"varint,1,opt,name=after,proto3" json:"after,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{8}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetAfter() int64 {
+	if m != nil {
+		return m.After
+	}
+	return 0
+}
+
+type Event struct {
+	Offset int64  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Id     string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// type is created, captions_updated, status_changed or deleted.
+	Type       string               `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	PostId     string               `protobuf:"bytes,4,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	Actor      string               `protobuf:"bytes,5,opt,name=actor,proto3" json:"actor,omitempty"`
+	OccurredAt *timestamp.Timestamp `protobuf:"bytes,6,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	// post is the post after the change, it is not set for deleted events.
+	Post                 *Post    `protobuf:"bytes,7,opt,name=post,proto3" json:"post,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+func (*Event) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e114ad14deab1dd1, []int{9}
+}
+
+func (m *Event) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Event.Unmarshal(m, b)
+}
+func (m *Event) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Event.Marshal(b, m, deterministic)
+}
+func (m *Event) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Event.Merge(m, src)
+}
+func (m *Event) XXX_Size() int {
+	return xxx_messageInfo_Event.Size(m)
+}
+func (m *Event) XXX_DiscardUnknown() {
+	xxx_messageInfo_Event.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Event proto.InternalMessageInfo
+
+func (m *Event) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *Event) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Event) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Event) GetPostId() string {
+	if m != nil {
+		return m.PostId
+	}
+	return ""
+}
+
+func (m *Event) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *Event) GetOccurredAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.OccurredAt
+	}
+	return nil
+}
+
+func (m *Event) GetPost() *Post {
+	if m != nil {
+		return m.Post
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Post)(nil), "cchw.post.v1.Post")
+	proto.RegisterType((*CreateRequest)(nil), "cchw.post.v1.CreateRequest")
+	proto.RegisterType((*GenerateRequest)(nil), "cchw.post.v1.GenerateRequest")
+	proto.RegisterType((*GetRequest)(nil), "cchw.post.v1.GetRequest")
+	proto.RegisterType((*UpdateRequest)(nil), "cchw.post.v1.UpdateRequest")
+	proto.RegisterType((*DeleteRequest)(nil), "cchw.post.v1.DeleteRequest")
+	proto.RegisterType((*ListRequest)(nil), "cchw.post.v1.ListRequest")
+	proto.RegisterType((*ListResponse)(nil), "cchw.post.v1.ListResponse")
+	proto.RegisterType((*WatchRequest)(nil), "cchw.post.v1.WatchRequest")
+	proto.RegisterType((*Event)(nil), "cchw.post.v1.Event")
+}
+
+func init() { proto.RegisterFile("post.proto", fileDescriptor_e114ad14deab1dd1) }
+
+var fileDescriptor_e114ad14deab1dd1 = []byte{
+	// 598 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x94, 0x5f, 0x6f, 0xd3, 0x3c,
+	0x14, 0xc6, 0x95, 0x26, 0xcd, 0xb6, 0xd3, 0xf6, 0x7d, 0x91, 0x41, 0x23, 0x64, 0x4c, 0xab, 0x32,
+	0xfe, 0xf4, 0x86, 0x14, 0xb6, 0xab, 0x31, 0xed, 0x82, 0xc1, 0x34, 0x21, 0xed, 0x02, 0x05, 0x26,
+	0x24, 0x6e, 0xaa, 0xc4, 0x71, 0xd7, 0x48, 0x69, 0x1c, 0xe2, 0x93, 0x8d, 0x7e, 0x1b, 0xbe, 0x02,
+	0x9f, 0x86, 0xaf, 0x83, 0x6c, 0x27, 0xa5, 0x09, 0xed, 0xae, 0xea, 0xe3, 0xe7, 0x3c, 0x3e, 0x4f,
+	0xed, 0x9f, 0x02, 0x90, 0x73, 0x81, 0x7e, 0x5e, 0x70, 0xe4, 0xa4, 0x4f, 0xe9, 0xec, 0xce, 0x57,
+	0x1b, 0xb7, 0x6f, 0xdc, 0xbd, 0x1b, 0xce, 0x6f, 0x52, 0x36, 0x56, 0x5a, 0x54, 0x4e, 0xc7, 0x6c,
+	0x9e, 0xe3, 0x42, 0xb7, 0xba, 0x07, 0x6d, 0x11, 0x93, 0x39, 0x13, 0x18, 0xce, 0x73, 0xdd, 0xe0,
+	0xfd, 0x34, 0xc0, 0xfa, 0xc4, 0x05, 0x92, 0xff, 0xa0, 0x93, 0xc4, 0x8e, 0x31, 0x34, 0x46, 0x3b,
+	0x41, 0x27, 0x89, 0xc9, 0x03, 0x30, 0xcb, 0x22, 0x75, 0x3a, 0x6a, 0x43, 0x2e, 0xc9, 0x21, 0x0c,
+	0x68, 0x98, 0xf1, 0x2c, 0xa1, 0x61, 0x3a, 0x91, 0x9a, 0xa9, 0xb4, 0xfe, 0x72, 0xf3, 0xba, 0x48,
+	0x89, 0x0b, 0xdb, 0x34, 0xcc, 0x31, 0xe1, 0x99, 0x70, 0xac, 0xa1, 0x39, 0xda, 0x09, 0x96, 0x35,
+	0xd9, 0x05, 0x5b, 0x60, 0x88, 0xa5, 0x70, 0xba, 0xca, 0x59, 0x55, 0x64, 0x1f, 0xa0, 0xcc, 0xe3,
+	0x10, 0x59, 0x3c, 0x89, 0x16, 0x8e, 0xad, 0xb4, 0x9d, 0x6a, 0xe7, 0x7c, 0xe1, 0x9d, 0xc1, 0xe0,
+	0x7d, 0xc1, 0x42, 0x64, 0x01, 0xfb, 0x5e, 0x32, 0x81, 0x75, 0x34, 0xe3, 0x6f, 0xb4, 0xd5, 0xa9,
+	0x9d, 0xe6, 0x54, 0xef, 0x10, 0xfe, 0xbf, 0x64, 0x19, 0x2b, 0xee, 0x3b, 0xc0, 0x7b, 0x0a, 0x70,
+	0xc9, 0xb0, 0xd6, 0x5b, 0x77, 0xe1, 0x9d, 0xc2, 0xe0, 0x5a, 0xc5, 0xd9, 0xd0, 0x70, 0xef, 0xfc,
+	0x03, 0x18, 0x7c, 0x60, 0x29, 0xdb, 0x68, 0xf6, 0x4e, 0xa0, 0x77, 0x95, 0x88, 0xe5, 0xf0, 0x47,
+	0xd0, 0x0d, 0xa7, 0xc8, 0x8a, 0xaa, 0x43, 0x17, 0x72, 0x37, 0x4d, 0xe6, 0x09, 0xaa, 0x07, 0xe9,
+	0x06, 0xba, 0xf0, 0xae, 0xa0, 0xaf, 0xad, 0x22, 0xe7, 0x99, 0x60, 0x64, 0x04, 0x5d, 0x89, 0x85,
+	0x70, 0x8c, 0xa1, 0x39, 0xea, 0x1d, 0x11, 0x7f, 0x95, 0x14, 0x5f, 0xbe, 0x73, 0xa0, 0x1b, 0x08,
+	0x01, 0x2b, 0x63, 0x3f, 0xb0, 0x7a, 0x5f, 0xb5, 0xf6, 0x9e, 0x41, 0xff, 0x6b, 0x88, 0x74, 0xb6,
+	0x36, 0x89, 0x59, 0x25, 0xf1, 0x7e, 0x1b, 0xd0, 0xbd, 0xb8, 0x65, 0x19, 0xca, 0xf7, 0xe4, 0xd3,
+	0xa9, 0x60, 0x58, 0x35, 0x54, 0x55, 0xf5, 0x07, 0x3b, 0xcb, 0xdb, 0x21, 0x60, 0xe1, 0x22, 0x67,
+	0x15, 0x2f, 0x6a, 0x4d, 0x1e, 0xc3, 0x96, 0x0c, 0x32, 0x49, 0x62, 0xc7, 0xd2, 0x30, 0xc8, 0xf2,
+	0x63, 0xac, 0x86, 0x52, 0xe4, 0x45, 0xc5, 0x88, 0x2e, 0xc8, 0x29, 0xf4, 0x38, 0xa5, 0x65, 0x51,
+	0xb0, 0x78, 0x12, 0xa2, 0x62, 0xa4, 0x77, 0xe4, 0xfa, 0x9a, 0x6e, 0xbf, 0xa6, 0xdb, 0xff, 0x52,
+	0xd3, 0x1d, 0x40, 0xdd, 0xfe, 0x0e, 0xc9, 0x0b, 0xb0, 0xe4, 0xe1, 0xce, 0xd6, 0xd0, 0xd8, 0x70,
+	0x29, 0x4a, 0x3f, 0xfa, 0x65, 0x42, 0x4f, 0x96, 0x9f, 0x59, 0x71, 0x9b, 0x50, 0x46, 0x4e, 0xc0,
+	0xd6, 0xe0, 0x91, 0xbd, 0xa6, 0xa7, 0x81, 0xa3, 0xbb, 0xe6, 0x40, 0x72, 0x06, 0xdb, 0x35, 0x74,
+	0x64, 0xbf, 0xa9, 0xb7, 0x60, 0x5c, 0x6b, 0x3f, 0x06, 0xf3, 0x92, 0x21, 0x71, 0xda, 0x4e, 0xbc,
+	0xcf, 0x74, 0x02, 0xb6, 0xa6, 0xb4, 0x1d, 0xb7, 0xc1, 0xee, 0x86, 0xb8, 0xb6, 0x66, 0xb4, 0x6d,
+	0x6d, 0x90, 0xeb, 0xee, 0xfe, 0x73, 0xe1, 0x17, 0xf2, 0x5b, 0x43, 0xce, 0xc0, 0x92, 0x18, 0x92,
+	0x27, 0x4d, 0xf3, 0x0a, 0xd5, 0xae, 0xbb, 0x4e, 0xaa, 0xa8, 0x7d, 0x0b, 0x5d, 0xc5, 0x1d, 0x69,
+	0x35, 0xad, 0xc2, 0xe8, 0x3e, 0x6c, 0x6a, 0x8a, 0xc0, 0xd7, 0xc6, 0xf9, 0xcb, 0x6f, 0xcf, 0x6f,
+	0x12, 0x9c, 0x95, 0x91, 0x4f, 0xf9, 0x7c, 0x1c, 0xe5, 0x05, 0x17, 0x62, 0x4c, 0xe9, 0xab, 0xd9,
+	0xdd, 0x58, 0xb6, 0xe6, 0xd1, 0xa9, 0xfe, 0x89, 0x6c, 0x95, 0xf9, 0xf8, 0xcf, 0x00, 0xb4, 0x6e,
+	0x7f, 0xf1, 0x49, 0x05, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// PostServiceClient is the client API for PostService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PostServiceClient interface {
+	// Create creates a post with the given captions.
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Post, error)
+	// Generate creates a post with generated captions, it counts against the quota.
+	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*Post, error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Post, error)
+	// Update replaces the captions of a post.
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*Post, error)
+	// Delete deletes a post and its revisions.
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// List returns a page of posts, oldest first.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Watch streams the changes to posts after an offset of the event log, until
+	// the call is cancelled.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (PostService_WatchClient, error)
+}
+
+type postServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewPostServiceClient(cc *grpc.ClientConn) PostServiceClient {
+	return &postServiceClient{cc}
+}
+
+func (c *postServiceClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Post, error) {
+	out := new(Post)
+	err := c.cc.Invoke(ctx, "/cchw.post.v1.PostService/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*Post, error) {
+	out := new(Post)
+	err := c.cc.Invoke(ctx, "/cchw.post.v1.PostService/Generate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Post, error) {
+	out := new(Post)
+	err := c.cc.Invoke(ctx, "/cchw.post.v1.PostService/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*Post, error) {
+	out := new(Post)
+	err := c.cc.Invoke(ctx, "/cchw.post.v1.PostService/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/cchw.post.v1.PostService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, "/cchw.post.v1.PostService/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (PostService_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_PostService_serviceDesc.Streams[0], "/cchw.post.v1.PostService/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &postServiceWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PostService_WatchClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type postServiceWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *postServiceWatchClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PostServiceServer is the server API for PostService service.
+type PostServiceServer interface {
+	// Create creates a post with the given captions.
+	Create(context.Context, *CreateRequest) (*Post, error)
+	// Generate creates a post with generated captions, it counts against the quota.
+	Generate(context.Context, *GenerateRequest) (*Post, error)
+	Get(context.Context, *GetRequest) (*Post, error)
+	// Update replaces the captions of a post.
+	Update(context.Context, *UpdateRequest) (*Post, error)
+	// Delete deletes a post and its revisions.
+	Delete(context.Context, *DeleteRequest) (*empty.Empty, error)
+	// List returns a page of posts, oldest first.
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Watch streams the changes to posts after an offset of the event log, until
+	// the call is cancelled.
+	Watch(*WatchRequest, PostService_WatchServer) error
+}
+
+// UnimplementedPostServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedPostServiceServer struct {
+}
+
+func (*UnimplementedPostServiceServer) Create(ctx context.Context, req *CreateRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (*UnimplementedPostServiceServer) Generate(ctx context.Context, req *GenerateRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+func (*UnimplementedPostServiceServer) Get(ctx context.Context, req *GetRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (*UnimplementedPostServiceServer) Update(ctx context.Context, req *UpdateRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (*UnimplementedPostServiceServer) Delete(ctx context.Context, req *DeleteRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (*UnimplementedPostServiceServer) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (*UnimplementedPostServiceServer) Watch(req *WatchRequest, srv PostService_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+
+func RegisterPostServiceServer(s *grpc.Server, srv PostServiceServer) {
+	s.RegisterService(&_PostService_serviceDesc, srv)
+}
+
+func _PostService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cchw.post.v1.PostService/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Generate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Generate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cchw.post.v1.PostService/Generate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Generate(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cchw.post.v1.PostService/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cchw.post.v1.PostService/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cchw.post.v1.PostService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cchw.post.v1.PostService/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PostServiceServer).Watch(m, &postServiceWatchServer{stream})
+}
+
+type PostService_WatchServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type postServiceWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *postServiceWatchServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _PostService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cchw.post.v1.PostService",
+	HandlerType: (*PostServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _PostService_Create_Handler,
+		},
+		{
+			MethodName: "Generate",
+			Handler:    _PostService_Generate_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _PostService_Get_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _PostService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _PostService_Delete_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _PostService_List_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _PostService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "post.proto",
+}
@@ -0,0 +1,155 @@
+package quality
+
+import (
+	"strings"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// configuredRule is a rule and what is done with the captions that break it
+type configuredRule struct {
+	name   string
+	action string
+	rule   Rule
+}
+
+// Engine checks captions against rules, in order
+type Engine struct {
+	rules []configuredRule
+}
+
+// NewEngine returns an Engine for the rules of the config, which is validated
+// first
+func NewEngine(config *Config) (*Engine, error) {
+	config = copyConfig(config)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	e := &Engine{}
+	for _, rc := range config.Rules {
+		var rule Rule
+		switch rc.Type {
+		case TypeProfanity:
+			rule = NewProfanityRule()
+		case TypeBlocklist:
+			rule = NewBlocklistRule(rc.Terms)
+		case TypeBrandTerms:
+			rule = NewBrandTermsRule(rc.Terms)
+		case TypeReadability:
+			rule = ReadabilityRule{MinScore: rc.MinScore}
+		case TypeFragment:
+			rule = FragmentRule{}
+		}
+		e.rules = append(e.rules, configuredRule{name: rc.Type, action: rc.Action, rule: rule})
+	}
+	return e, nil
+}
+
+// Check checks the candidate captions of the url. Captions a rule rejects are
+// returned as rejections with the findings up to that rule, the others as
+// generated captions with their findings, and the fixes of the rules that fix
+// them. A caption a fix leaves without words is rejected by that rule
+func (e *Engine) Check(url string, candidates []string) ([]*dao.Caption, []*dao.Rejection) {
+	captions := make([]*dao.Caption, len(candidates))
+	for i, candidate := range candidates {
+		captions[i] = &dao.Caption{Text: candidate, Source: dao.SourceGenerated}
+	}
+	return e.CheckCaptions(url, captions)
+}
+
+// CheckCaptions is Check for captions that already have a score or another
+// field set, the captions that pass keep them
+func (e *Engine) CheckCaptions(url string, candidates []*dao.Caption) ([]*dao.Caption, []*dao.Rejection) {
+	captions := []*dao.Caption{}
+	var rejections []*dao.Rejection
+	for _, candidate := range candidates {
+		text := candidate.Text
+		var findings []*dao.Finding
+		rejected := false
+		for _, r := range e.rules {
+			message := r.rule.Check(url, text)
+			if message == "" {
+				continue
+			}
+			finding := &dao.Finding{Rule: r.name, Action: r.action, Message: message}
+			if r.action == ActionFix {
+				text = strings.TrimSpace(r.rule.(Fixer).Fix(text))
+				if strings.IndexFunc(text, isWordRune) < 0 {
+					finding.Action = ActionReject
+				}
+			}
+			findings = append(findings, finding)
+			if finding.Action == ActionReject {
+				rejected = true
+				break
+			}
+		}
+		if rejected {
+			rejections = append(rejections, &dao.Rejection{Text: candidate.Text, Findings: findings})
+			continue
+		}
+		checked := *candidate
+		checked.Text, checked.Findings = text, findings
+		captions = append(captions, &checked)
+	}
+	return captions, rejections
+}
+
+// Checker checks generated captions against the rules of their customer.
+// Customers without rules of their own get the default rules
+type Checker struct {
+	store    Store
+	defaults *Config
+}
+
+// NewChecker returns a Checker with the provided options
+func NewChecker(store Store, defaults *Config) *Checker {
+	return &Checker{
+		store:    store,
+		defaults: copyConfig(defaults),
+	}
+}
+
+// Config returns the rules of the customer, isDefault is true when the customer
+// has none of their own
+func (c *Checker) Config(customerID string) (config *Config, isDefault bool, err error) {
+	config, err = c.store.Get(customerID)
+	if _, ok := err.(*datastore.NotFound); ok {
+		return copyConfig(c.defaults), true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return config, false, nil
+}
+
+// Check checks the candidate captions of the url against the rules of the
+// customer, see Engine.Check
+func (c *Checker) Check(customerID, url string, candidates []string) ([]*dao.Caption, []*dao.Rejection, error) {
+	engine, err := c.engine(customerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	captions, rejections := engine.Check(url, candidates)
+	return captions, rejections, nil
+}
+
+// CheckCaptions checks captions against the rules of the customer, see
+// Engine.CheckCaptions
+func (c *Checker) CheckCaptions(customerID, url string, candidates []*dao.Caption) ([]*dao.Caption, []*dao.Rejection, error) {
+	engine, err := c.engine(customerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	captions, rejections := engine.CheckCaptions(url, candidates)
+	return captions, rejections, nil
+}
+
+func (c *Checker) engine(customerID string) (*Engine, error) {
+	config, _, err := c.Config(customerID)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(config)
+}
@@ -0,0 +1,132 @@
+package quality
+
+import (
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("Config", func() {
+	It("should accept the default config", func() {
+		Expect(DefaultConfig().Validate()).To(Succeed())
+	})
+
+	It("should return every invalid rule", func() {
+		config := &Config{Rules: []*RuleConfig{
+			{Type: "spelling", Action: ActionFlag},
+			{Type: TypeBrandTerms, Action: ActionFix, Terms: []string{"Acme"}},
+			{Type: TypeBlocklist, Action: "warn", Terms: []string{" "}},
+			{Type: TypeReadability, Action: ActionFlag},
+			nil,
+			{Type: TypeBlocklist, Action: ActionReject},
+		}}
+		err := config.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*datastore.Validation).Fields).To(Equal([]datastore.FieldError{
+			{Field: "rules[0].type", Message: "must be one of profanity, blocklist, brand_terms, readability, fragment"},
+			{Field: "rules[1].action", Message: "brand_terms rules can not fix captions"},
+			{Field: "rules[2].action", Message: "must be reject, flag or fix"},
+			{Field: "rules[2].terms[0]", Message: "must not be empty"},
+			{Field: "rules[3].min_score", Message: "must be more than 0 and at most 1"},
+			{Field: "rules[4]", Message: "must not be null"},
+			{Field: "rules[5].terms", Message: "must not be empty"},
+		}))
+	})
+})
+
+var _ = Describe("Engine", func() {
+	const url = "https://example.com/post"
+
+	It("should reject, fix and flag captions in the order of the rules", func() {
+		engine, err := NewEngine(&Config{Rules: []*RuleConfig{
+			{Type: TypeProfanity, Action: ActionFix},
+			{Type: TypeBlocklist, Action: ActionReject, Terms: []string{"cheap"}},
+			{Type: TypeFragment, Action: ActionFlag},
+		}})
+		Expect(err).To(BeNil())
+
+		captions, rejections := engine.Check(url, []string{"Damn good post.", "So cheap", "a fragment"})
+		Expect(captions).To(Equal([]*dao.Caption{
+			{Text: "D*** good post.", Source: dao.SourceGenerated, Findings: []*dao.Finding{
+				{Rule: TypeProfanity, Action: ActionFix, Message: "contains profanity"},
+			}},
+			{Text: "a fragment", Source: dao.SourceGenerated, Findings: []*dao.Finding{
+				{Rule: TypeFragment, Action: ActionFlag, Message: "does not start with a capital letter"},
+			}},
+		}))
+		Expect(rejections).To(Equal([]*dao.Rejection{
+			{Text: "So cheap", Findings: []*dao.Finding{
+				{Rule: TypeBlocklist, Action: ActionReject, Message: `contains blocked term "cheap"`},
+			}},
+		}))
+	})
+
+	It("should reject a caption a fix leaves without words", func() {
+		engine, err := NewEngine(&Config{Rules: []*RuleConfig{{Type: TypeBlocklist, Action: ActionFix, Terms: []string{"cheap"}}}})
+		Expect(err).To(BeNil())
+		captions, rejections := engine.Check(url, []string{"Cheap!"})
+		Expect(captions).To(BeEmpty())
+		Expect(rejections[0].Findings[0].Action).To(Equal(ActionReject))
+	})
+
+	It("should keep the fields of checked captions", func() {
+		engine, err := NewEngine(&Config{Rules: []*RuleConfig{{Type: TypeProfanity, Action: ActionFix}}})
+		Expect(err).To(BeNil())
+		captions, rejections := engine.CheckCaptions(url, []*dao.Caption{{Text: "Damn good post.", Source: dao.SourceGenerated, Score: 0.5}})
+		Expect(rejections).To(BeEmpty())
+		Expect(captions).To(HaveLen(1))
+		Expect(captions[0].Text).To(Equal("D*** good post."))
+		Expect(captions[0].Score).To(Equal(0.5))
+		Expect(captions[0].Findings).To(HaveLen(1))
+	})
+
+	It("should NOT build an invalid config", func() {
+		_, err := NewEngine(&Config{Rules: []*RuleConfig{{Type: TypeFragment, Action: "warn"}}})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Checker", func() {
+	var (
+		store   *InMemoryStore
+		checker *Checker
+	)
+
+	BeforeEach(func() {
+		logger := log.New()
+		logger.Out = ioutil.Discard
+		store = NewInMemoryStore(logger)
+		checker = NewChecker(store, DefaultConfig())
+	})
+
+	It("should use the default rules for a customer without rules", func() {
+		config, isDefault, err := checker.Config("test-customer")
+		Expect(err).To(BeNil())
+		Expect(isDefault).To(BeTrue())
+		Expect(config).To(Equal(DefaultConfig()))
+
+		captions, rejections, err := checker.Check("test-customer", "https://example.com", []string{"Holy shit.", "no end"})
+		Expect(err).To(BeNil())
+		Expect(rejections).To(HaveLen(1))
+		Expect(captions[0].Findings).To(Equal([]*dao.Finding{{Rule: TypeFragment, Action: ActionFlag, Message: "does not start with a capital letter"}}))
+	})
+
+	It("should use the rules of the customer", func() {
+		_, err := store.Put("test-customer", &Config{Rules: []*RuleConfig{}})
+		Expect(err).To(BeNil())
+
+		_, isDefault, err := checker.Config("test-customer")
+		Expect(err).To(BeNil())
+		Expect(isDefault).To(BeFalse())
+
+		captions, rejections, err := checker.Check("test-customer", "https://example.com", []string{"Holy shit."})
+		Expect(err).To(BeNil())
+		Expect(rejections).To(BeEmpty())
+		Expect(captions[0].Findings).To(BeNil())
+	})
+})
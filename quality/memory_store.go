@@ -0,0 +1,78 @@
+package quality
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// Store defines the interface for keeping the rules of every customer. Get
+// returns a NotFound error for a customer without rules of their own
+type Store interface {
+	Get(string) (*Config, error)
+	Put(string, *Config) (*Config, error)
+	Delete(string) error
+}
+
+// InMemoryStore implements the Store interface for in memory storage
+type InMemoryStore struct {
+	logger  *log.Logger
+	mu      sync.Mutex
+	configs map[string]*Config
+}
+
+// NewInMemoryStore creates a new InMemoryStore with the provided options
+func NewInMemoryStore(logger *log.Logger) *InMemoryStore {
+	return &InMemoryStore{
+		logger:  logger,
+		configs: make(map[string]*Config),
+	}
+}
+
+// Get returns the customer's rules
+func (s *InMemoryStore) Get(customerID string) (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config, ok := s.configs[customerID]
+	if !ok {
+		return nil, datastore.NewNotFoundError("quality rules")
+	}
+	return copyConfig(config), nil
+}
+
+// Put validates and stores the customer's rules in place of any they had
+func (s *InMemoryStore) Put(customerID string, config *Config) (*Config, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+	if config == nil {
+		return nil, datastore.NewInvalidArugmentError("must provide config")
+	}
+	config = copyConfig(config)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs[customerID] = config
+
+	s.logger.WithFields(log.Fields{
+		"customerID": customerID,
+		"rules":      len(config.Rules),
+	}).Debug("stored quality rules")
+	return copyConfig(config), nil
+}
+
+// Delete removes the customer's rules, so the default rules apply to them again
+func (s *InMemoryStore) Delete(customerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.configs[customerID]; !ok {
+		return datastore.NewNotFoundError("quality rules")
+	}
+	delete(s.configs, customerID)
+	return nil
+}
@@ -0,0 +1,62 @@
+package quality
+
+import (
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("InMemoryStore", func() {
+	var (
+		store      *InMemoryStore
+		customerID string
+	)
+
+	BeforeEach(func() {
+		logger := log.New()
+		logger.Out = ioutil.Discard
+		store = NewInMemoryStore(logger)
+		customerID = "test-customer"
+	})
+
+	It("should return NotFound for a customer without rules", func() {
+		_, err := store.Get(customerID)
+		Expect(err).To(BeAssignableToTypeOf(&datastore.NotFound{}))
+	})
+
+	It("should store trimmed copies of the rules", func() {
+		config := &Config{Rules: []*RuleConfig{{Type: TypeBlocklist, Action: ActionReject, Terms: []string{" cheap "}}}}
+		stored, err := store.Put(customerID, config)
+		Expect(err).To(BeNil())
+		Expect(stored.Rules[0].Terms).To(Equal([]string{"cheap"}))
+		Expect(config.Rules[0].Terms).To(Equal([]string{" cheap "}))
+
+		stored.Rules[0].Terms[0] = "changed"
+		got, err := store.Get(customerID)
+		Expect(err).To(BeNil())
+		Expect(got.Rules[0].Terms).To(Equal([]string{"cheap"}))
+
+		_, err = store.Get("other-customer")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should NOT store invalid rules", func() {
+		_, err := store.Put(customerID, &Config{Rules: []*RuleConfig{{Type: TypeReadability, Action: ActionFix, MinScore: 0.5}}})
+		Expect(err).To(BeAssignableToTypeOf(&datastore.Validation{}))
+		_, err = store.Get(customerID)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should delete the rules", func() {
+		_, err := store.Put(customerID, DefaultConfig())
+		Expect(err).To(BeNil())
+		Expect(store.Delete(customerID)).To(Succeed())
+		_, err = store.Get(customerID)
+		Expect(err).To(HaveOccurred())
+		Expect(store.Delete(customerID)).To(BeAssignableToTypeOf(&datastore.NotFound{}))
+	})
+})
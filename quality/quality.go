@@ -0,0 +1,141 @@
+package quality
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// Actions a rule takes on a caption that breaks it
+const (
+	ActionReject = "reject"
+	ActionFlag   = "flag"
+	ActionFix    = "fix"
+)
+
+// Types of rules
+const (
+	TypeProfanity   = "profanity"
+	TypeBlocklist   = "blocklist"
+	TypeBrandTerms  = "brand_terms"
+	TypeReadability = "readability"
+	TypeFragment    = "fragment"
+)
+
+const (
+	maxRules      = 20
+	maxTerms      = 100
+	maxTermLength = 64
+)
+
+// types are the rules a config can have, and whether they can fix a caption
+var types = map[string]bool{
+	TypeProfanity:   true,
+	TypeBlocklist:   true,
+	TypeBrandTerms:  false,
+	TypeReadability: false,
+	TypeFragment:    true,
+}
+
+// RuleConfig configures one rule. Terms are only used by blocklist and
+// brand_terms rules, MinScore only by readability rules
+type RuleConfig struct {
+	Type     string   `json:"type"`
+	Action   string   `json:"action"`
+	Terms    []string `json:"terms,omitempty"`
+	MinScore float64  `json:"min_score,omitempty"`
+}
+
+// Config is the rules a customer's generated captions are checked against, in
+// order
+type Config struct {
+	Rules []*RuleConfig `json:"rules"`
+}
+
+// DefaultConfig returns the rules of customers without rules of their own. Only
+// profanity is rejected, fragments are flagged
+func DefaultConfig() *Config {
+	return &Config{Rules: []*RuleConfig{
+		{Type: TypeProfanity, Action: ActionReject},
+		{Type: TypeFragment, Action: ActionFlag},
+	}}
+}
+
+// Validate checks the config and trims its terms in place
+func (c *Config) Validate() error {
+	verr := datastore.NewValidationError()
+	if len(c.Rules) > maxRules {
+		verr.Add("rules", fmt.Sprintf("must have at most %d rules", maxRules))
+		return verr
+	}
+	for i, rule := range c.Rules {
+		name := fmt.Sprintf("rules[%d]", i)
+		if rule == nil {
+			verr.Add(name, "must not be null")
+			continue
+		}
+		fixable, ok := types[rule.Type]
+		if !ok {
+			verr.Add(name+".type", "must be one of profanity, blocklist, brand_terms, readability, fragment")
+			continue
+		}
+		switch rule.Action {
+		case ActionReject, ActionFlag:
+		case ActionFix:
+			if !fixable {
+				verr.Add(name+".action", fmt.Sprintf("%s rules can not fix captions", rule.Type))
+			}
+		default:
+			verr.Add(name+".action", "must be reject, flag or fix")
+		}
+
+		switch rule.Type {
+		case TypeBlocklist, TypeBrandTerms:
+			validateTerms(verr, name+".terms", rule.Terms)
+		case TypeReadability:
+			if rule.MinScore <= 0 || rule.MinScore > 1 {
+				verr.Add(name+".min_score", "must be more than 0 and at most 1")
+			}
+		}
+	}
+	if verr.HasErrors() {
+		return verr
+	}
+	return nil
+}
+
+// validateTerms checks the terms of a rule and trims them in place
+func validateTerms(verr *datastore.Validation, field string, terms []string) {
+	if len(terms) == 0 {
+		verr.Add(field, "must not be empty")
+		return
+	}
+	if len(terms) > maxTerms {
+		verr.Add(field, fmt.Sprintf("must have at most %d terms", maxTerms))
+		return
+	}
+	for i, term := range terms {
+		terms[i] = strings.TrimSpace(term)
+		name := fmt.Sprintf("%s[%d]", field, i)
+		if terms[i] == "" {
+			verr.Add(name, "must not be empty")
+		} else if len([]rune(terms[i])) > maxTermLength {
+			verr.Add(name, fmt.Sprintf("must be at most %d characters", maxTermLength))
+		}
+	}
+}
+
+// copyConfig copies the config so it does not change with the original
+func copyConfig(config *Config) *Config {
+	copied := &Config{Rules: make([]*RuleConfig, len(config.Rules))}
+	for i, rule := range config.Rules {
+		if rule == nil {
+			continue
+		}
+		r := *rule
+		r.Terms = append([]string(nil), rule.Terms...)
+		copied.Rules[i] = &r
+	}
+	return copied
+}
@@ -0,0 +1,297 @@
+package quality
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bpross/cc-hw/caption"
+)
+
+// Rule defines the interface for checking a caption of a url. Check returns why
+// the caption breaks the rule, or an empty string when it does not
+type Rule interface {
+	Check(url, caption string) string
+}
+
+// Fixer is a Rule that can fix the captions that break it
+type Fixer interface {
+	Rule
+	Fix(caption string) string
+}
+
+// defaultProfanity are the words the profanity rule looks for
+var defaultProfanity = []string{
+	"arse", "arsehole", "ass", "asshole", "bastard", "bitch", "bollocks", "bullshit",
+	"cock", "crap", "cunt", "damn", "dick", "dickhead", "fuck", "fucked", "fucker",
+	"fucking", "goddamn", "motherfucker", "piss", "pissed", "prick", "shit",
+	"shitty", "slut", "twat", "wanker", "whore", "wtf",
+}
+
+// ProfanityRule finds profanity in captions, and fixes it by masking every
+// letter of a word but the first
+type ProfanityRule struct {
+	words *matcher
+}
+
+// NewProfanityRule returns a ProfanityRule for the default list of words
+func NewProfanityRule() *ProfanityRule {
+	return &ProfanityRule{words: newMatcher(defaultProfanity)}
+}
+
+// Check implements the Rule interface
+func (r *ProfanityRule) Check(_, text string) string {
+	if found := r.words.find(text); len(found) > 0 {
+		return "contains profanity"
+	}
+	return ""
+}
+
+// Fix implements the Fixer interface
+func (r *ProfanityRule) Fix(text string) string {
+	return r.words.replace(text, func(word string) string {
+		first, size := utf8.DecodeRuneInString(word)
+		return string(first) + strings.Repeat("*", utf8.RuneCountInString(word[size:]))
+	})
+}
+
+// BlocklistRule finds the terms a customer bans, and fixes a caption by removing
+// them
+type BlocklistRule struct {
+	terms *matcher
+}
+
+// NewBlocklistRule returns a BlocklistRule for the terms
+func NewBlocklistRule(terms []string) *BlocklistRule {
+	return &BlocklistRule{terms: newMatcher(terms)}
+}
+
+// Check implements the Rule interface
+func (r *BlocklistRule) Check(_, text string) string {
+	found := r.terms.find(text)
+	if len(found) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("contains blocked %s %s", plural(len(found), "term", "terms"), quoteAll(found))
+}
+
+// Fix implements the Fixer interface
+func (r *BlocklistRule) Fix(text string) string {
+	removed := r.terms.replace(text, func(string) string { return "" })
+	// Removing a term leaves the space on both sides of it
+	removed = strings.Join(strings.Fields(removed), " ")
+	return strings.NewReplacer(" ,", ",", " .", ".", " !", "!", " ?", "?", " ;", ";", " :", ":").Replace(removed)
+}
+
+// BrandTermsRule requires captions to mention at least one of a customer's
+// brand terms
+type BrandTermsRule struct {
+	terms    *matcher
+	original []string
+}
+
+// NewBrandTermsRule returns a BrandTermsRule for the terms
+func NewBrandTermsRule(terms []string) *BrandTermsRule {
+	return &BrandTermsRule{terms: newMatcher(terms), original: terms}
+}
+
+// Check implements the Rule interface
+func (r *BrandTermsRule) Check(_, text string) string {
+	if len(r.terms.find(text)) > 0 {
+		return ""
+	}
+	if len(r.original) == 1 {
+		return fmt.Sprintf("does not mention %s", quoteAll(r.original))
+	}
+	return fmt.Sprintf("does not mention any of %s", quoteAll(r.original))
+}
+
+// ReadabilityRule requires captions to score at least MinScore with a
+// caption.ReadabilityScorer
+type ReadabilityRule struct {
+	MinScore float64
+}
+
+// Check implements the Rule interface
+func (r ReadabilityRule) Check(url, text string) string {
+	score := caption.ReadabilityScorer{}.Score(url, text)
+	if score >= r.MinScore {
+		return ""
+	}
+	return fmt.Sprintf("readability %.2f is below %.2f", score, r.MinScore)
+}
+
+// FragmentRule finds captions that are not whole sentences: they start with a
+// lower case letter, or do not end with a full stop, question or exclamation
+// mark. An ellipsis does not end a sentence
+type FragmentRule struct{}
+
+// Check implements the Rule interface
+func (FragmentRule) Check(_, text string) string {
+	switch {
+	case startsLower(text):
+		return "does not start with a capital letter"
+	case !endsSentence(text):
+		return "does not end a sentence"
+	}
+	return ""
+}
+
+// Fix implements the Fixer interface. A caption that does not end a sentence is
+// cut after its last whole sentence, or ended with a full stop when it has none
+func (FragmentRule) Fix(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return text
+	}
+	if startsLower(text) {
+		first, size := utf8.DecodeRuneInString(text)
+		text = string(unicode.ToUpper(first)) + text[size:]
+	}
+	if endsSentence(text) {
+		return text
+	}
+	text = strings.TrimRight(strings.TrimSuffix(text, "…"), ".")
+	if end := lastSentenceEnd(text); end > 0 {
+		return text[:end]
+	}
+	return strings.TrimRightFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) && !isClosing(r)
+	}) + "."
+}
+
+// startsLower returns true if the first letter of the text is lower case
+func startsLower(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			return unicode.IsLower(r)
+		}
+		if !unicode.IsPunct(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return false
+}
+
+// endsSentence returns true if the text ends with a full stop, question or
+// exclamation mark, before any closing quotes or brackets
+func endsSentence(text string) bool {
+	text = strings.TrimRightFunc(strings.TrimSpace(text), isClosing)
+	if strings.HasSuffix(text, "...") || strings.HasSuffix(text, "…") {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text)
+	return last == '.' || last == '!' || last == '?'
+}
+
+// lastSentenceEnd returns the index after the last sentence end of the text that
+// is followed by a space, or 0 when there is none
+func lastSentenceEnd(text string) int {
+	end := 0
+	for i, r := range text {
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		rest := text[i+1:]
+		j := len(rest) - len(strings.TrimLeftFunc(rest, isClosing))
+		next, _ := utf8.DecodeRuneInString(rest[j:])
+		if unicode.IsSpace(next) && !strings.HasSuffix(text[:i+1], "..") {
+			end = i + 1 + j
+		}
+	}
+	return end
+}
+
+func isClosing(r rune) bool {
+	return strings.ContainsRune(`"')]}’”`, r)
+}
+
+// matcher finds whole word occurrences of terms, ignoring case
+type matcher struct {
+	re *regexp.Regexp
+}
+
+// newMatcher returns a matcher for the terms. Longer terms are matched first, so
+// a term that starts another one does not hide it
+func newMatcher(terms []string) *matcher {
+	sorted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			sorted = append(sorted, regexp.QuoteMeta(term))
+		}
+	}
+	if len(sorted) == 0 {
+		return &matcher{}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	return &matcher{re: regexp.MustCompile(`(?i)(?:` + strings.Join(sorted, "|") + `)`)}
+}
+
+// find returns the distinct terms found in the text, as they are written there,
+// in the order they are first found
+func (m *matcher) find(text string) []string {
+	found := []string{}
+	seen := map[string]bool{}
+	for _, loc := range m.locate(text) {
+		term := text[loc[0]:loc[1]]
+		if key := strings.ToLower(term); !seen[key] {
+			seen[key] = true
+			found = append(found, term)
+		}
+	}
+	return found
+}
+
+// replace replaces every term found in the text with the result of with
+func (m *matcher) replace(text string, with func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range m.locate(text) {
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(with(text[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// locate returns the byte ranges of the terms in the text that are not part of
+// a longer word
+func (m *matcher) locate(text string) [][]int {
+	if m.re == nil {
+		return nil
+	}
+	locs := [][]int{}
+	for _, loc := range m.re.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(text[loc[1]:])
+		if loc[0] > 0 && isWordRune(before) || loc[1] < len(text) && isWordRune(after) {
+			continue
+		}
+		locs = append(locs, loc)
+	}
+	return locs
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// quoteAll quotes every term and joins them with commas
+func quoteAll(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = fmt.Sprintf("%q", term)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,92 @@
+package quality
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rules", func() {
+	const url = "https://example.com/post"
+
+	Describe("ProfanityRule", func() {
+		rule := NewProfanityRule()
+
+		It("should find whole words in any case", func() {
+			Expect(rule.Check(url, "What the Fuck is this?")).To(Equal("contains profanity"))
+			Expect(rule.Check(url, "A classic assessment of Scunthorpe.")).To(BeEmpty())
+		})
+
+		It("should mask every letter but the first", func() {
+			Expect(rule.Fix("This is shit, total bullshit.")).To(Equal("This is s***, total b*******."))
+		})
+	})
+
+	Describe("BlocklistRule", func() {
+		rule := NewBlocklistRule([]string{"cheap", "best ever"})
+
+		It("should name the blocked terms found", func() {
+			Expect(rule.Check(url, "The Best Ever deal, so cheap!")).To(Equal(`contains blocked terms "Best Ever", "cheap"`))
+			Expect(rule.Check(url, "So cheap. Cheap!")).To(Equal(`contains blocked term "cheap"`))
+			Expect(rule.Check(url, "Cheaply made.")).To(BeEmpty())
+		})
+
+		It("should remove the terms and the space around them", func() {
+			Expect(rule.Fix("The best ever deal, so cheap!")).To(Equal("The deal, so!"))
+		})
+	})
+
+	Describe("BrandTermsRule", func() {
+		It("should require one of the terms", func() {
+			rule := NewBrandTermsRule([]string{"Acme", "Acme Corp"})
+			Expect(rule.Check(url, "Built by acme corp.")).To(BeEmpty())
+			Expect(rule.Check(url, "Built by Acmes.")).To(Equal(`does not mention any of "Acme", "Acme Corp"`))
+			Expect(NewBrandTermsRule([]string{"Acme"}).Check(url, "Built.")).To(Equal(`does not mention "Acme"`))
+		})
+	})
+
+	Describe("ReadabilityRule", func() {
+		It("should require the minimum score", func() {
+			rule := ReadabilityRule{MinScore: 0.5}
+			Expect(rule.Check(url, "The cat sat on the mat.")).To(BeEmpty())
+			Expect(rule.Check(url, "Institutionalized organizations underestimate unprecedented considerations.")).To(HavePrefix("readability 0.00 is below 0.50"))
+		})
+	})
+
+	Describe("FragmentRule", func() {
+		rule := FragmentRule{}
+
+		It("should find captions that are not whole sentences", func() {
+			cases := []struct {
+				caption string
+				message string
+			}{
+				{"A whole sentence.", ""},
+				{`He said "go!"`, ""},
+				{"Is it (really)?", ""},
+				{"a lower case start.", "does not start with a capital letter"},
+				{"No end", "does not end a sentence"},
+				{"Trailing off...", "does not end a sentence"},
+				{"Trailing off…", "does not end a sentence"},
+			}
+			for _, c := range cases {
+				Expect(rule.Check(url, c.caption)).To(Equal(c.message), c.caption)
+			}
+		})
+
+		It("should cut after the last whole sentence, or end the caption", func() {
+			cases := []struct {
+				caption string
+				fixed   string
+			}{
+				{"first sentence. Second one that is cut", "First sentence."},
+				{"Is it? Yes! And then", "Is it? Yes!"},
+				{"No end,", "No end."},
+				{"Trailing off...", "Trailing off."},
+				{"A whole sentence.", "A whole sentence."},
+			}
+			for _, c := range cases {
+				Expect(rule.Fix(c.caption)).To(Equal(c.fixed), c.caption)
+			}
+		})
+	})
+})
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Result describes the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // how long until a request will be allowed, zero when allowed
+	Reset      time.Time     // when the bucket will be full again
+}
+
+// Limiter defines the interface for rate limiting requests by key
+type Limiter interface {
+	Allow(string) Result
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucketLimiter implements the Limiter interface with an in memory token
+// bucket per key. Buckets refill continuously at rate tokens per second up to burst
+type TokenBucketLimiter struct {
+	rate    float64
+	burst   int
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewTokenBucketLimiter creates a TokenBucketLimiter with the provided options
+func NewTokenBucketLimiter(rate float64, burst int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the key's bucket if one is available
+func (l *TokenBucketLimiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens: float64(l.burst),
+			last:   now,
+		}
+		l.buckets[key] = b
+	}
+
+	// Refill for the time that has passed since the last request
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(l.burst), b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+
+	res := Result{
+		Limit: l.burst,
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.timeFor(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = now.Add(l.timeFor(float64(l.burst) - b.tokens))
+	return res
+}
+
+// timeFor returns how long it takes to refill the number of tokens
+func (l *TokenBucketLimiter) timeFor(tokens float64) time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / l.rate * float64(time.Second)))
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TokenBucketLimiter", func() {
+	var (
+		limiter *TokenBucketLimiter
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Unix(1577836800, 0)
+		limiter = NewTokenBucketLimiter(1, 3)
+		limiter.now = func() time.Time { return now }
+	})
+
+	Context("with tokens in the bucket", func() {
+		It("should allow up to burst requests", func() {
+			for i := 2; i >= 0; i-- {
+				res := limiter.Allow("customer")
+				Expect(res.Allowed).To(BeTrue())
+				Expect(res.Limit).To(Equal(3))
+				Expect(res.Remaining).To(Equal(i))
+			}
+		})
+	})
+
+	Context("with an empty bucket", func() {
+		BeforeEach(func() {
+			for i := 0; i < 3; i++ {
+				limiter.Allow("customer")
+			}
+		})
+
+		It("should deny the request", func() {
+			res := limiter.Allow("customer")
+			Expect(res.Allowed).To(BeFalse())
+			Expect(res.Remaining).To(Equal(0))
+			Expect(res.RetryAfter).To(Equal(time.Second))
+			Expect(res.Reset).To(Equal(now.Add(3 * time.Second)))
+		})
+
+		It("should NOT limit other customers", func() {
+			Expect(limiter.Allow("other").Allowed).To(BeTrue())
+		})
+
+		It("should refill over time", func() {
+			now = now.Add(1500 * time.Millisecond)
+			Expect(limiter.Allow("customer").Allowed).To(BeTrue())
+			Expect(limiter.Allow("customer").Allowed).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// Usage describes how much of their quota a customer has used in the current period
+type Usage struct {
+	CustomerID string    `json:"customer_id"`
+	Period     string    `json:"period"`
+	Used       int       `json:"used"`
+	Limit      int       `json:"limit"`
+	ResetsAt   time.Time `json:"resets_at"`
+}
+
+// Remaining returns how much of the quota is left
+func (u Usage) Remaining() int {
+	if u.Used >= u.Limit {
+		return 0
+	}
+	return u.Limit - u.Used
+}
+
+// Quota defines the interface for tracking usage against a periodic quota
+type Quota interface {
+	Consume(string, int) (Usage, error)
+	Refund(string, int) error
+	Usage(string) (Usage, error)
+	All() ([]Usage, error)
+}
+
+// MonthlyQuota implements the Quota interface in memory, usage resets at the start
+// of every calendar month (UTC)
+type MonthlyQuota struct {
+	limit     int
+	overrides map[string]int
+	mu        sync.Mutex
+	used      map[string]map[string]int // period -> customerID -> used
+	now       func() time.Time
+}
+
+// NewMonthlyQuota creates a MonthlyQuota where every customer gets the same limit
+func NewMonthlyQuota(limit int) *MonthlyQuota {
+	return &MonthlyQuota{
+		limit:     limit,
+		overrides: make(map[string]int),
+		used:      make(map[string]map[string]int),
+		now:       time.Now,
+	}
+}
+
+// SetLimit overrides the limit for a single customer
+func (q *MonthlyQuota) SetLimit(customerID string, limit int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.overrides[customerID] = limit
+}
+
+// Consume records n units of usage. If that would go over the limit nothing is
+// recorded and a datastore.QuotaExceeded error is returned
+func (q *MonthlyQuota) Consume(customerID string, n int) (Usage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	usage := q.usage(customerID)
+	if usage.Used+n > usage.Limit {
+		return usage, exceeded(usage)
+	}
+
+	period := q.used[usage.Period]
+	if period == nil {
+		// Only the current period is ever needed, so drop the old ones
+		q.used = map[string]map[string]int{usage.Period: {}}
+		period = q.used[usage.Period]
+	}
+	period[customerID] += n
+	usage.Used += n
+	return usage, nil
+}
+
+// Refund gives back n units of usage in the current period, for work that was
+// consumed but did not succeed. Usage never goes below zero
+func (q *MonthlyQuota) Refund(customerID string, n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	used := q.used[period(q.now())]
+	if used[customerID] <= n {
+		delete(used, customerID)
+		return nil
+	}
+	used[customerID] -= n
+	return nil
+}
+
+// Usage returns the customer's usage for the current period
+func (q *MonthlyQuota) Usage(customerID string) (Usage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.usage(customerID), nil
+}
+
+// All returns the usage for every customer that has used part of their quota in
+// the current period
+func (q *MonthlyQuota) All() ([]Usage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	usages := []Usage{}
+	for customerID := range q.used[period(q.now())] {
+		usages = append(usages, q.usage(customerID))
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].CustomerID < usages[j].CustomerID
+	})
+	return usages, nil
+}
+
+func (q *MonthlyQuota) usage(customerID string) Usage {
+	now := q.now().UTC()
+	limit, ok := q.overrides[customerID]
+	if !ok {
+		limit = q.limit
+	}
+	p := period(now)
+	return Usage{
+		CustomerID: customerID,
+		Period:     p,
+		Used:       q.used[p][customerID],
+		Limit:      limit,
+		ResetsAt:   time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func exceeded(usage Usage) error {
+	msg := fmt.Sprintf("%d generations for %s", usage.Limit, usage.Period)
+	return datastore.NewQuotaExceededError(msg, usage.ResetsAt)
+}
+
+func period(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
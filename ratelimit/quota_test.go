@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("MonthlyQuota", func() {
+	var (
+		quota *MonthlyQuota
+		now   time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2020, 1, 31, 23, 0, 0, 0, time.UTC)
+		quota = NewMonthlyQuota(2)
+		quota.now = func() time.Time { return now }
+	})
+
+	Describe("Consume", func() {
+		It("should track usage until the limit", func() {
+			usage, err := quota.Consume("customer", 1)
+			Expect(err).To(BeNil())
+			Expect(usage.Used).To(Equal(1))
+			Expect(usage.Remaining()).To(Equal(1))
+
+			usage, err = quota.Consume("customer", 1)
+			Expect(err).To(BeNil())
+			Expect(usage.Used).To(Equal(2))
+
+			usage, err = quota.Consume("customer", 1)
+			Expect(err).To(BeAssignableToTypeOf(&datastore.QuotaExceeded{}))
+			Expect(err.Error()).To(Equal("quota exceeded: 2 generations for 2020-01"))
+			Expect(usage.Used).To(Equal(2))
+			Expect(usage.ResetsAt).To(Equal(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)))
+		})
+
+		It("should reset at the start of the month", func() {
+			_, err := quota.Consume("customer", 2)
+			Expect(err).To(BeNil())
+
+			now = now.Add(2 * time.Hour)
+			usage, err := quota.Consume("customer", 1)
+			Expect(err).To(BeNil())
+			Expect(usage.Period).To(Equal("2020-02"))
+			Expect(usage.Used).To(Equal(1))
+		})
+
+		It("should use per customer limits", func() {
+			quota.SetLimit("big-customer", 5)
+			usage, err := quota.Consume("big-customer", 5)
+			Expect(err).To(BeNil())
+			Expect(usage.Limit).To(Equal(5))
+		})
+	})
+
+	Describe("Refund", func() {
+		It("should give back usage without going below zero", func() {
+			_, err := quota.Consume("customer", 2)
+			Expect(err).To(BeNil())
+
+			Expect(quota.Refund("customer", 1)).To(Succeed())
+			usage, err := quota.Usage("customer")
+			Expect(err).To(BeNil())
+			Expect(usage.Used).To(Equal(1))
+
+			Expect(quota.Refund("customer", 2)).To(Succeed())
+			usage, err = quota.Usage("customer")
+			Expect(err).To(BeNil())
+			Expect(usage.Used).To(Equal(0))
+
+			all, err := quota.All()
+			Expect(err).To(BeNil())
+			Expect(all).To(BeEmpty())
+		})
+
+		It("should not refund a customer that has not used any", func() {
+			Expect(quota.Refund("customer", 1)).To(Succeed())
+			usage, err := quota.Usage("customer")
+			Expect(err).To(BeNil())
+			Expect(usage.Used).To(Equal(0))
+		})
+	})
+
+	Describe("All", func() {
+		It("should return usage for every customer in the period", func() {
+			_, err := quota.Consume("b", 1)
+			Expect(err).To(BeNil())
+			_, err = quota.Consume("a", 2)
+			Expect(err).To(BeNil())
+
+			usages, err := quota.All()
+			Expect(err).To(BeNil())
+			Expect(usages).To(HaveLen(2))
+			Expect(usages[0].CustomerID).To(Equal("a"))
+			Expect(usages[0].Used).To(Equal(2))
+			Expect(usages[1].CustomerID).To(Equal("b"))
+		})
+	})
+})
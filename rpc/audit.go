@@ -0,0 +1,106 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/audit"
+)
+
+// httpStatuses maps gRPC codes to the http status recorded for a call, so
+// entries of both apis read the same
+var httpStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.NotFound:           http.StatusNotFound,
+	codes.Aborted:            http.StatusConflict,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unimplemented:      http.StatusNotImplemented,
+}
+
+// AuditRecorder records every call of a customer in the audit log once it is
+// handled, like the REST api records requests. The path is the full method of
+// the call. It must come after the Authenticator, calls without a customer are
+// not recorded. A call is handled by then, so an entry that can not be appended
+// is only logged and the call returns what its handler did
+type AuditRecorder struct {
+	logger *log.Logger
+	log    audit.Appender
+}
+
+// NewAuditRecorder returns an AuditRecorder appending to the log
+func NewAuditRecorder(logger *log.Logger, log audit.Appender) *AuditRecorder {
+	return &AuditRecorder{
+		logger: logger,
+		log:    log,
+	}
+}
+
+// Unary is the interceptor for unary calls
+func (r *AuditRecorder) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	r.record(ctx, info.FullMethod, req, err)
+	return resp, err
+}
+
+// Stream is the interceptor for streaming calls, they are recorded when they end
+func (r *AuditRecorder) Stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, stream)
+	r.record(stream.Context(), info.FullMethod, nil, err)
+	return err
+}
+
+func (r *AuditRecorder) record(ctx context.Context, method string, req interface{}, err error) {
+	identity := getIdentity(ctx)
+	if identity == nil || identity.CustomerID == "" {
+		return
+	}
+	statusCode, ok := httpStatuses[status.Code(err)]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+	e := &audit.Entry{
+		CustomerID: identity.CustomerID,
+		Actor:      actor(ctx),
+		Action:     audit.ActionRequest,
+		IP:         peerIP(ctx),
+		Method:     http.MethodPost,
+		Path:       method,
+		Status:     statusCode,
+	}
+	// Calls on a post name it in the id field
+	if withID, ok := req.(interface{ GetId() string }); ok && bson.IsObjectIdHex(withID.GetId()) {
+		e.PostID = withID.GetId()
+	}
+	if _, err := r.log.Append(e); err != nil {
+		r.logger.WithFields(log.Fields{
+			"method": method,
+			"error":  err.Error(),
+		}).Error("failed to append audit entry")
+	}
+}
+
+// peerIP returns the address the call came from
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
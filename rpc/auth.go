@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/ratelimit"
+)
+
+// servicePrefix is the start of the full method names of the PostService
+const servicePrefix = "/cchw.post.v1.PostService/"
+
+// methodScopes is the scope a caller needs for each method
+var methodScopes = map[string]string{
+	servicePrefix + "Create":   auth.ScopePostsWrite,
+	servicePrefix + "Generate": auth.ScopePostsWrite,
+	servicePrefix + "Get":      auth.ScopePostsRead,
+	servicePrefix + "Update":   auth.ScopePostsWrite,
+	servicePrefix + "Delete":   auth.ScopePostsWrite,
+	servicePrefix + "List":     auth.ScopePostsRead,
+	servicePrefix + "Watch":    auth.ScopePostsRead,
+}
+
+type identityKey struct{}
+
+// Authenticator authenticates calls from their metadata with the same
+// authenticators as the REST api, checks the caller has the scope of the method
+// and rate limits them per customer
+type Authenticator struct {
+	logger         *log.Logger
+	limiter        ratelimit.Limiter
+	authenticators []auth.Authenticator
+}
+
+// NewAuthenticator returns an Authenticator trying the authenticators in order
+func NewAuthenticator(logger *log.Logger, limiter ratelimit.Limiter, authenticators ...auth.Authenticator) *Authenticator {
+	return &Authenticator{
+		logger:         logger,
+		limiter:        limiter,
+		authenticators: authenticators,
+	}
+}
+
+// Unary is the interceptor for unary calls
+func (a *Authenticator) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// Stream is the interceptor for streaming calls
+func (a *Authenticator) Stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticate returns the context with the identity of the caller
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	identity, err := a.identity(ctx)
+	if err != nil {
+		return nil, toStatus(a.logger, err)
+	}
+	scope, ok := methodScopes[method]
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "unknown method")
+	}
+	if !identity.HasScope(scope) {
+		return nil, toStatus(a.logger, auth.NewForbiddenError("missing scope "+scope))
+	}
+	if res := a.limiter.Allow(identity.CustomerID); !res.Allowed {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+// identity runs the authenticators on a request with the metadata as headers,
+// so api keys and tokens are sent the same way as to the REST api
+func (a *Authenticator) identity(ctx context.Context) (*auth.Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	req := &http.Request{Header: http.Header{}}
+	for name, values := range md {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	for _, authenticator := range a.authenticators {
+		identity, err := authenticator.Authenticate(req)
+		if err != nil {
+			return nil, err
+		}
+		if identity != nil {
+			return identity, nil
+		}
+	}
+	return nil, auth.NewUnauthorizedError("must include credentials in metadata")
+}
+
+// authenticatedStream replaces the context of a stream with the authenticated one
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// getIdentity returns the identity set by the Authenticator
+func getIdentity(ctx context.Context) *auth.Identity {
+	identity, _ := ctx.Value(identityKey{}).(*auth.Identity)
+	return identity
+}
+
+// customerID returns the customer of the caller
+func customerID(ctx context.Context) string {
+	if identity := getIdentity(ctx); identity != nil {
+		return identity.CustomerID
+	}
+	return ""
+}
+
+// actor returns who is making the change, in the same form as the REST api
+func actor(ctx context.Context) string {
+	identity := getIdentity(ctx)
+	switch {
+	case identity == nil:
+		return ""
+	case identity.Subject != "":
+		return identity.Subject
+	case identity.KeyID != "":
+		return "key:" + identity.KeyID
+	default:
+		return identity.CustomerID
+	}
+}
@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// errorCodes maps the codes of typed errors to gRPC codes
+var errorCodes = map[string]codes.Code{
+	datastore.CodeNotFound:           codes.NotFound,
+	datastore.CodeInvalidArgument:    codes.InvalidArgument,
+	datastore.CodeValidationFailed:   codes.InvalidArgument,
+	datastore.CodeConflict:           codes.Aborted,
+	datastore.CodePreconditionFailed: codes.FailedPrecondition,
+	datastore.CodeUnavailable:        codes.Unavailable,
+	datastore.CodeQuotaExceeded:      codes.ResourceExhausted,
+	auth.CodeUnauthenticated:         codes.Unauthenticated,
+	auth.CodeForbidden:               codes.PermissionDenied,
+}
+
+// toStatus returns the gRPC status error for an error. Validation errors carry
+// their fields as BadRequest details. Untyped errors are logged and returned as
+// Internal, so their message does not leak
+func toStatus(logger *log.Logger, err error) error {
+	coder, ok := err.(datastore.Coder)
+	if !ok {
+		logger.WithError(err).Error("rpc failed")
+		return status.Error(codes.Internal, "internal error")
+	}
+	code, ok := errorCodes[coder.Code()]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	st := status.New(code, err.Error())
+	if verr, ok := err.(*datastore.Validation); ok {
+		details := &errdetails.BadRequest{}
+		for _, field := range verr.Fields {
+			details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		if withDetails, err := st.WithDetails(details); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+func (s *PostServer) status(err error) error {
+	return toStatus(s.logger, err)
+}
@@ -0,0 +1,263 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/caption"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+	"github.com/bpross/cc-hw/postpb"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/validate"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+	// watchBatchSize is how many events are read from the log at a time
+	watchBatchSize = 100
+)
+
+// PostServer implements the postpb.PostServiceServer interface on top of the same
+// dao.Poster and caption.Generator as the REST handlers
+type PostServer struct {
+	logger           *log.Logger
+	ds               dao.Poster
+	captionGenerator caption.Generator
+	scorer           caption.Scorer
+	checker          *quality.Checker
+	numCaptions      int
+	quota            ratelimit.Quota
+	validator        *validate.Validator
+	log              events.Log
+}
+
+// NewPostServer returns a PostServer with the provided options. Generated posts
+// get numCaptions captions, checked against the customer's quality rules and
+// ranked by the scorer, and are counted against the quota. Captions are sent as
+// their texts, best first
+func NewPostServer(logger *log.Logger, ds dao.Poster, captionGenerator caption.Generator, scorer caption.Scorer, checker *quality.Checker, numCaptions int, quota ratelimit.Quota, validator *validate.Validator, log events.Log) *PostServer {
+	return &PostServer{
+		logger:           logger,
+		ds:               ds,
+		captionGenerator: captionGenerator,
+		scorer:           scorer,
+		checker:          checker,
+		numCaptions:      numCaptions,
+		quota:            quota,
+		validator:        validator,
+		log:              log,
+	}
+}
+
+// Create creates a post with the captions of the request
+func (s *PostServer) Create(ctx context.Context, req *postpb.CreateRequest) (*postpb.Post, error) {
+	input := &dao.Post{URL: req.Url, Captions: dao.ManualCaptions(req.Captions), UpdatedBy: actor(ctx)}
+	if err := s.validator.Post(input); err != nil {
+		return nil, s.status(err)
+	}
+	post, err := s.ds.Insert(customerID(ctx), input)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	return toPost(post), nil
+}
+
+// Generate creates a post with generated captions. The generation is reserved
+// against the quota before the provider is called, so concurrent requests
+// cannot overspend it, and refunded if no captions come back or the post is
+// not stored
+func (s *PostServer) Generate(ctx context.Context, req *postpb.GenerateRequest) (*postpb.Post, error) {
+	verr := datastore.NewValidationError()
+	url := s.validator.URL(verr, "url", req.Url)
+	if verr.HasErrors() {
+		return nil, s.status(verr)
+	}
+
+	if _, err := s.quota.Consume(customerID(ctx), 1); err != nil {
+		return nil, s.status(err)
+	}
+	candidates, err := s.captionGenerator.Create(url, s.numCaptions)
+	if err != nil {
+		s.refund(ctx)
+		if _, ok := err.(datastore.Coder); !ok {
+			s.logger.WithError(err).Error("caption generator failed")
+			err = datastore.NewUnavailableError("caption generator")
+		}
+		return nil, s.status(err)
+	}
+
+	captions, rejected, err := s.checker.Check(customerID(ctx), url, candidates)
+	if err != nil {
+		s.refund(ctx)
+		return nil, s.status(err)
+	}
+
+	post, err := s.ds.Insert(customerID(ctx), &dao.Post{
+		URL:              url,
+		Captions:         caption.RankCaptions(s.scorer, url, captions),
+		RejectedCaptions: rejected,
+		UpdatedBy:        actor(ctx),
+	})
+	if err != nil {
+		s.refund(ctx)
+		return nil, s.status(err)
+	}
+	return toPost(post), nil
+}
+
+// refund gives a reserved generation back to the customer of ctx
+func (s *PostServer) refund(ctx context.Context) {
+	if err := s.quota.Refund(customerID(ctx), 1); err != nil {
+		s.logger.WithError(err).Error("refunding quota failed")
+	}
+}
+
+// Get returns the post
+func (s *PostServer) Get(ctx context.Context, req *postpb.GetRequest) (*postpb.Post, error) {
+	id, err := postID(req.Id)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	post, err := s.ds.Get(customerID(ctx), id)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	return toPost(post), nil
+}
+
+// Update replaces the captions of the post
+func (s *PostServer) Update(ctx context.Context, req *postpb.UpdateRequest) (*postpb.Post, error) {
+	id, err := postID(req.Id)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	input := &dao.Post{ID: &id, Captions: dao.ManualCaptions(req.Captions), UpdatedBy: actor(ctx)}
+	if err := s.validator.Update(input); err != nil {
+		return nil, s.status(err)
+	}
+	post, err := s.ds.Update(customerID(ctx), input)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	return toPost(post), nil
+}
+
+// Delete deletes the post and its revisions
+func (s *PostServer) Delete(ctx context.Context, req *postpb.DeleteRequest) (*empty.Empty, error) {
+	id, err := postID(req.Id)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	if err := s.ds.Delete(customerID(ctx), id, actor(ctx)); err != nil {
+		return nil, s.status(err)
+	}
+	return &empty.Empty{}, nil
+}
+
+// List returns a page of posts, oldest first
+func (s *PostServer) List(ctx context.Context, req *postpb.ListRequest) (*postpb.ListResponse, error) {
+	opts := dao.ListOptions{Limit: int(req.Limit)}
+	if opts.Limit == 0 {
+		opts.Limit = defaultListLimit
+	}
+	if opts.Limit < 0 || opts.Limit > maxListLimit {
+		return nil, s.status(datastore.NewInvalidArugmentError("limit"))
+	}
+	if req.After != "" {
+		after, err := postID(req.After)
+		if err != nil {
+			return nil, s.status(datastore.NewInvalidArugmentError("after"))
+		}
+		opts.After = after
+	}
+
+	posts, err := s.ds.List(customerID(ctx), opts)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	resp := &postpb.ListResponse{Posts: make([]*postpb.Post, len(posts))}
+	for i, post := range posts {
+		resp.Posts[i] = toPost(post)
+	}
+	if len(posts) == opts.Limit {
+		resp.Next = posts[len(posts)-1].ID.Hex()
+	}
+	return resp, nil
+}
+
+// Watch sends the customer's events after the offset of the request as they are
+// appended to the log, until the call is cancelled
+func (s *PostServer) Watch(req *postpb.WatchRequest, stream postpb.PostService_WatchServer) error {
+	if req.After < 0 {
+		return s.status(datastore.NewInvalidArugmentError("after"))
+	}
+	ctx := stream.Context()
+	after := req.After
+	for {
+		feed, err := s.log.Poll(ctx, customerID(ctx), after, watchBatchSize)
+		if err != nil {
+			return s.status(err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		for _, e := range feed {
+			event, err := toEvent(e)
+			if err != nil {
+				return s.status(err)
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+			after = e.Offset
+		}
+	}
+}
+
+func postID(id string) (bson.ObjectId, error) {
+	if !bson.IsObjectIdHex(id) {
+		return "", datastore.NewInvalidArugmentError("post id")
+	}
+	return bson.ObjectIdHex(id), nil
+}
+
+func toPost(post *dao.Post) *postpb.Post {
+	if post == nil {
+		return nil
+	}
+	p := &postpb.Post{
+		Url:          post.URL,
+		CanonicalUrl: post.CanonicalURL,
+		Captions:     dao.CaptionTexts(post.Captions),
+		Status:       post.Status,
+		UpdatedBy:    post.UpdatedBy,
+	}
+	if post.ID != nil {
+		p.Id = post.ID.Hex()
+	}
+	return p
+}
+
+func toEvent(e *events.Event) (*postpb.Event, error) {
+	occurredAt, err := ptypes.TimestampProto(e.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+	return &postpb.Event{
+		Offset:     e.Offset,
+		Id:         e.ID,
+		Type:       e.Type,
+		PostId:     e.PostID.Hex(),
+		Actor:      e.Actor,
+		OccurredAt: occurredAt,
+		Post:       toPost(e.Post),
+	}, nil
+}
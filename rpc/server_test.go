@@ -0,0 +1,369 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/audit"
+	"github.com/bpross/cc-hw/auth"
+	"github.com/bpross/cc-hw/caption"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+	mock_caption "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/caption"
+	mock_dao "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/dao"
+	mock_ratelimit "github.com/bpross/cc-hw/mocks/github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/postpb"
+	"github.com/bpross/cc-hw/quality"
+	"github.com/bpross/cc-hw/ratelimit"
+	"github.com/bpross/cc-hw/validate"
+)
+
+// fakeAuthenticator trusts the x-customer-id metadata and grants the scopes in
+// x-scopes, or every scope
+type fakeAuthenticator struct{}
+
+func (fakeAuthenticator) Authenticate(r *http.Request) (*auth.Identity, error) {
+	customerID := r.Header.Get("x-customer-id")
+	if customerID == "" {
+		return nil, nil
+	}
+	scopes := auth.AllScopes
+	if s := r.Header.Get("x-scopes"); s != "" {
+		scopes = strings.Split(s, ",")
+	}
+	return &auth.Identity{CustomerID: customerID, Scopes: scopes}, nil
+}
+
+// brokenLog fails every append while err is set
+type brokenLog struct {
+	*audit.InMemoryStore
+	err error
+}
+
+func (l *brokenLog) Append(e *audit.Entry) (*audit.Entry, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.InMemoryStore.Append(e)
+}
+
+var _ = Describe("PostServer", func() {
+	var (
+		mockCtrl      *gomock.Controller
+		mockPoster    *mock_dao.MockPoster
+		mockGenerator *mock_caption.MockGenerator
+		mockQuota     *mock_ratelimit.MockQuota
+		mockLimiter   *mock_ratelimit.MockLimiter
+		eventLog      *events.InMemoryLog
+		auditLog      *brokenLog
+		server        *grpc.Server
+		conn          *grpc.ClientConn
+		client        postpb.PostServiceClient
+		ctx           context.Context
+		customerID    string
+		postID        bson.ObjectId
+	)
+
+	BeforeEach(func() {
+		logger := log.New()
+		logger.Out = ioutil.Discard
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPoster = mock_dao.NewMockPoster(mockCtrl)
+		mockGenerator = mock_caption.NewMockGenerator(mockCtrl)
+		mockQuota = mock_ratelimit.NewMockQuota(mockCtrl)
+		mockLimiter = mock_ratelimit.NewMockLimiter(mockCtrl)
+		eventLog = events.NewInMemoryLog(logger)
+
+		auditLog = &brokenLog{InMemoryStore: audit.NewInMemoryStore()}
+
+		authenticator := NewAuthenticator(logger, mockLimiter, fakeAuthenticator{})
+		recorder := NewAuditRecorder(logger, auditLog)
+		server = grpc.NewServer(grpc.UnaryInterceptor(ChainUnary(authenticator.Unary, recorder.Unary)), grpc.StreamInterceptor(ChainStream(authenticator.Stream, recorder.Stream)))
+		postpb.RegisterPostServiceServer(server, NewPostServer(logger, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(quality.NewInMemoryStore(logger), &quality.Config{}), 3, mockQuota, validate.NewValidator(validate.DefaultRules()), eventLog))
+		lis := bufconn.Listen(1 << 20)
+		go server.Serve(lis)
+
+		var err error
+		conn, err = grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return lis.Dial()
+		}))
+		Expect(err).To(BeNil())
+		client = postpb.NewPostServiceClient(conn)
+
+		customerID = "test-customer"
+		postID = bson.NewObjectId()
+		ctx = metadata.AppendToOutgoingContext(context.Background(), "x-customer-id", customerID)
+	})
+
+	AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		mockCtrl.Finish()
+	})
+
+	expectCode := func(err error, code codes.Code) {
+		ExpectWithOffset(1, status.Code(err)).To(Equal(code), "%v", err)
+	}
+
+	Describe("authentication", func() {
+		It("should reject calls without credentials", func() {
+			_, err := client.Get(context.Background(), &postpb.GetRequest{Id: postID.Hex()})
+			expectCode(err, codes.Unauthenticated)
+			Expect(auditLog.Query(customerID, audit.Query{})).To(BeEmpty())
+		})
+
+		It("should reject calls without the scope of the method", func() {
+			ctx = metadata.AppendToOutgoingContext(context.Background(), "x-customer-id", customerID, "x-scopes", auth.ScopePostsRead)
+			_, err := client.Delete(ctx, &postpb.DeleteRequest{Id: postID.Hex()})
+			expectCode(err, codes.PermissionDenied)
+		})
+
+		It("should rate limit calls", func() {
+			mockLimiter.EXPECT().Allow(customerID).Return(ratelimit.Result{Allowed: false})
+			_, err := client.Get(ctx, &postpb.GetRequest{Id: postID.Hex()})
+			expectCode(err, codes.ResourceExhausted)
+		})
+	})
+
+	Context("with an allowed caller", func() {
+		BeforeEach(func() {
+			mockLimiter.EXPECT().Allow(customerID).Return(ratelimit.Result{Allowed: true}).AnyTimes()
+		})
+
+		Describe("audit", func() {
+			It("should record every call of the customer", func() {
+				mockPoster.EXPECT().Get(customerID, postID).Return(&dao.Post{ID: &postID, URL: "https://example.com"}, nil)
+				_, err := client.Get(ctx, &postpb.GetRequest{Id: postID.Hex()})
+				Expect(err).To(BeNil())
+				mockPoster.EXPECT().Get(customerID, postID).Return(nil, datastore.NewNotFoundError("post"))
+				_, err = client.Get(ctx, &postpb.GetRequest{Id: postID.Hex()})
+				expectCode(err, codes.NotFound)
+
+				entries, err := auditLog.Query(customerID, audit.Query{})
+				Expect(err).To(BeNil())
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Action).To(Equal(audit.ActionRequest))
+				Expect(entries[0].Actor).To(Equal(customerID))
+				Expect(entries[0].Path).To(Equal("/cchw.post.v1.PostService/Get"))
+				Expect(entries[0].PostID).To(Equal(postID.Hex()))
+				Expect(entries[0].Status).To(Equal(http.StatusOK))
+				Expect(entries[1].Status).To(Equal(http.StatusNotFound))
+			})
+
+			It("should return the created post when the audit log can not be appended to", func() {
+				auditLog.err = datastore.NewUnavailableError("audit log")
+				mockPoster.EXPECT().Insert(customerID, gomock.Any()).Return(&dao.Post{ID: &postID, URL: "https://example.com", Captions: dao.ManualCaptions([]string{"caption1"})}, nil)
+				post, err := client.Create(ctx, &postpb.CreateRequest{Url: "https://example.com", Captions: []string{"caption1"}})
+				Expect(err).To(BeNil())
+				Expect(post.Id).To(Equal(postID.Hex()))
+			})
+		})
+
+		Describe("Create", func() {
+			It("should insert the post as the caller", func() {
+				expected := &dao.Post{URL: "https://example.com", Captions: dao.ManualCaptions([]string{"caption1"}), UpdatedBy: customerID}
+				mockPoster.EXPECT().Insert(customerID, expected).Return(&dao.Post{ID: &postID, URL: "https://example.com", Captions: dao.ManualCaptions([]string{"caption1"})}, nil)
+				post, err := client.Create(ctx, &postpb.CreateRequest{Url: "https://example.com", Captions: []string{"caption1"}})
+				Expect(err).To(BeNil())
+				Expect(post.Id).To(Equal(postID.Hex()))
+				Expect(post.Captions).To(Equal([]string{"caption1"}))
+			})
+
+			It("should return the invalid fields", func() {
+				_, err := client.Create(ctx, &postpb.CreateRequest{Url: "ftp://example.com"})
+				expectCode(err, codes.InvalidArgument)
+				details := status.Convert(err).Details()
+				Expect(details).To(HaveLen(1))
+				Expect(details[0].(*errdetails.BadRequest).FieldViolations[0].Field).To(Equal("url"))
+			})
+		})
+
+		Describe("Generate", func() {
+			var (
+				expected *dao.Post
+			)
+
+			BeforeEach(func() {
+				expected = &dao.Post{URL: "https://example.com", Captions: caption.Rank(caption.DefaultScorer(), "https://example.com", []string{"generated"}), UpdatedBy: customerID}
+			})
+
+			It("should generate captions within the quota", func() {
+				mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+				mockGenerator.EXPECT().Create("https://example.com", 3).Return([]string{"generated"}, nil)
+				mockPoster.EXPECT().Insert(customerID, expected).Return(&dao.Post{ID: &postID, URL: "https://example.com", Captions: dao.ManualCaptions([]string{"generated"})}, nil)
+				post, err := client.Generate(ctx, &postpb.GenerateRequest{Url: "https://example.com"})
+				Expect(err).To(BeNil())
+				Expect(post.Captions).To(Equal([]string{"generated"}))
+			})
+
+			It("should return ResourceExhausted without generating when the quota is spent", func() {
+				mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, datastore.NewQuotaExceededError("monthly quota", time.Now()))
+				_, err := client.Generate(ctx, &postpb.GenerateRequest{Url: "https://example.com"})
+				expectCode(err, codes.ResourceExhausted)
+			})
+
+			It("should return Unavailable and refund the quota when the generator fails", func() {
+				mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+				mockGenerator.EXPECT().Create("https://example.com", 3).Return(nil, errors.New("test-error"))
+				mockQuota.EXPECT().Refund(customerID, 1).Return(nil)
+				_, err := client.Generate(ctx, &postpb.GenerateRequest{Url: "https://example.com"})
+				expectCode(err, codes.Unavailable)
+			})
+
+			It("should refund the quota when the post is not stored", func() {
+				mockQuota.EXPECT().Consume(customerID, 1).Return(ratelimit.Usage{}, nil)
+				mockGenerator.EXPECT().Create("https://example.com", 3).Return([]string{"generated"}, nil)
+				mockPoster.EXPECT().Insert(customerID, expected).Return(nil, datastore.NewUnavailableError("datastore"))
+				mockQuota.EXPECT().Refund(customerID, 1).Return(nil)
+				_, err := client.Generate(ctx, &postpb.GenerateRequest{Url: "https://example.com"})
+				expectCode(err, codes.Unavailable)
+			})
+
+			It("should generate once for concurrent requests", func() {
+				quota := ratelimit.NewMonthlyQuota(1)
+				logger := log.New()
+				logger.Out = ioutil.Discard
+				postServer := NewPostServer(logger, mockPoster, mockGenerator, caption.DefaultScorer(), quality.NewChecker(quality.NewInMemoryStore(logger), &quality.Config{}), 3, quota, validate.NewValidator(validate.DefaultRules()), eventLog)
+				mockGenerator.EXPECT().Create("https://example.com", 3).Return([]string{"generated"}, nil)
+				mockPoster.EXPECT().Insert(customerID, expected).Return(&dao.Post{ID: &postID, URL: "https://example.com"}, nil)
+
+				callerCtx := context.WithValue(context.Background(), identityKey{}, &auth.Identity{CustomerID: customerID})
+				codesc := make(chan codes.Code, 5)
+				for i := 0; i < 5; i++ {
+					go func() {
+						defer GinkgoRecover()
+						_, err := postServer.Generate(callerCtx, &postpb.GenerateRequest{Url: "https://example.com"})
+						codesc <- status.Code(err)
+					}()
+				}
+				var got []codes.Code
+				for i := 0; i < 5; i++ {
+					got = append(got, <-codesc)
+				}
+				Expect(got).To(ConsistOf(codes.OK, codes.ResourceExhausted, codes.ResourceExhausted, codes.ResourceExhausted, codes.ResourceExhausted))
+
+				usage, err := quota.Usage(customerID)
+				Expect(err).To(BeNil())
+				Expect(usage.Used).To(Equal(1))
+			})
+		})
+
+		Describe("Get", func() {
+			It("should return InvalidArgument for an invalid id", func() {
+				_, err := client.Get(ctx, &postpb.GetRequest{Id: "blah"})
+				expectCode(err, codes.InvalidArgument)
+			})
+
+			It("should return NotFound", func() {
+				mockPoster.EXPECT().Get(customerID, postID).Return(nil, datastore.NewNotFoundError("post"))
+				_, err := client.Get(ctx, &postpb.GetRequest{Id: postID.Hex()})
+				expectCode(err, codes.NotFound)
+				Expect(status.Convert(err).Message()).To(Equal("post not found"))
+			})
+
+			It("should NOT leak untyped errors", func() {
+				mockPoster.EXPECT().Get(customerID, postID).Return(nil, errors.New("test-error"))
+				_, err := client.Get(ctx, &postpb.GetRequest{Id: postID.Hex()})
+				expectCode(err, codes.Internal)
+				Expect(status.Convert(err).Message()).To(Equal("internal error"))
+			})
+		})
+
+		Describe("Update", func() {
+			It("should replace the captions", func() {
+				expected := &dao.Post{ID: &postID, Captions: dao.ManualCaptions([]string{"caption2"}), UpdatedBy: customerID}
+				mockPoster.EXPECT().Update(customerID, expected).Return(&dao.Post{ID: &postID, Captions: dao.ManualCaptions([]string{"caption2"})}, nil)
+				post, err := client.Update(ctx, &postpb.UpdateRequest{Id: postID.Hex(), Captions: []string{"caption2"}})
+				Expect(err).To(BeNil())
+				Expect(post.Captions).To(Equal([]string{"caption2"}))
+			})
+
+			It("should return FailedPrecondition", func() {
+				mockPoster.EXPECT().Update(customerID, gomock.Any()).Return(nil, datastore.NewPreconditionFailedError("post is being published"))
+				_, err := client.Update(ctx, &postpb.UpdateRequest{Id: postID.Hex(), Captions: []string{"caption2"}})
+				expectCode(err, codes.FailedPrecondition)
+			})
+		})
+
+		Describe("Delete", func() {
+			It("should delete the post", func() {
+				mockPoster.EXPECT().Delete(customerID, postID, customerID).Return(nil)
+				_, err := client.Delete(ctx, &postpb.DeleteRequest{Id: postID.Hex()})
+				Expect(err).To(BeNil())
+			})
+		})
+
+		Describe("List", func() {
+			It("should return the next page when the page is full", func() {
+				after := bson.NewObjectId()
+				mockPoster.EXPECT().List(customerID, dao.ListOptions{After: after, Limit: 1}).Return([]*dao.Post{{ID: &postID}}, nil)
+				resp, err := client.List(ctx, &postpb.ListRequest{After: after.Hex(), Limit: 1})
+				Expect(err).To(BeNil())
+				Expect(resp.Posts).To(HaveLen(1))
+				Expect(resp.Next).To(Equal(postID.Hex()))
+			})
+
+			It("should use the default limit", func() {
+				mockPoster.EXPECT().List(customerID, dao.ListOptions{Limit: defaultListLimit}).Return([]*dao.Post{}, nil)
+				resp, err := client.List(ctx, &postpb.ListRequest{})
+				Expect(err).To(BeNil())
+				Expect(resp.Next).To(BeEmpty())
+			})
+
+			It("should return InvalidArgument for a limit over the max", func() {
+				_, err := client.List(ctx, &postpb.ListRequest{Limit: maxListLimit + 1})
+				expectCode(err, codes.InvalidArgument)
+			})
+		})
+
+		Describe("Watch", func() {
+			It("should stream the customer's events after the offset", func() {
+				_, err := eventLog.Append(events.NewEvent(events.TypeCreated, customerID, postID, customerID, &dao.Post{ID: &postID}))
+				Expect(err).To(BeNil())
+				_, err = eventLog.Append(events.NewEvent(events.TypeCreated, "other-customer", postID, "other-customer", &dao.Post{ID: &postID}))
+				Expect(err).To(BeNil())
+
+				watchCtx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				stream, err := client.Watch(watchCtx, &postpb.WatchRequest{})
+				Expect(err).To(BeNil())
+				event, err := stream.Recv()
+				Expect(err).To(BeNil())
+				Expect(event.Offset).To(Equal(int64(1)))
+				Expect(event.Type).To(Equal(events.TypeCreated))
+				Expect(event.Post.Id).To(Equal(postID.Hex()))
+
+				_, err = eventLog.Append(events.NewEvent(events.TypeDeleted, customerID, postID, customerID, nil))
+				Expect(err).To(BeNil())
+				event, err = stream.Recv()
+				Expect(err).To(BeNil())
+				Expect(event.Offset).To(Equal(int64(2)))
+				Expect(event.Type).To(Equal(events.TypeDeleted))
+				Expect(event.Post).To(BeNil())
+				Expect(event.OccurredAt).NotTo(BeNil())
+
+				cancel()
+				_, err = stream.Recv()
+				expectCode(err, codes.Canceled)
+			})
+		})
+	})
+})
@@ -0,0 +1,128 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/webhook"
+)
+
+const (
+	maxPublishResponseBytes = 64 << 10
+	// EventPublish is sent in the Webhook-Event header by WebhookPublisher
+	EventPublish = "publish"
+)
+
+// Publisher defines the interface for publishing a post to one of its channels.
+// It returns the id the channel gave the published post, if any
+type Publisher interface {
+	Publish(context.Context, *dao.Post, string) (string, error)
+}
+
+// LogPublisher implements the Publisher interface by logging the post, it is
+// used when no other publisher is configured
+type LogPublisher struct {
+	logger *log.Logger
+}
+
+// NewLogPublisher returns a LogPublisher with the provided options
+func NewLogPublisher(logger *log.Logger) *LogPublisher {
+	return &LogPublisher{
+		logger: logger,
+	}
+}
+
+// Publish logs the post and channel
+func (p *LogPublisher) Publish(ctx context.Context, post *dao.Post, channel string) (string, error) {
+	p.logger.WithFields(log.Fields{
+		"customerID": post.CustID,
+		"post_id":    post.ID.Hex(),
+		"channel":    channel,
+		"captions":   dao.CaptionTexts(post.Captions),
+	}).Info("publishing post")
+	return "", nil
+}
+
+// publishRequest is the body WebhookPublisher sends. Caption is the text of the
+// post's selected caption
+type publishRequest struct {
+	Channel string    `json:"channel"`
+	Caption string    `json:"caption,omitempty"`
+	Post    *dao.Post `json:"post"`
+}
+
+// publishResponse is the optional body a publish endpoint answers with
+type publishResponse struct {
+	ID string `json:"id"`
+}
+
+// WebhookPublisher implements the Publisher interface by posting the post to an
+// endpoint that publishes it, signed the same way as webhook deliveries
+type WebhookPublisher struct {
+	logger *log.Logger
+	url    string
+	secret string
+	client webhook.Doer
+	now    func() time.Time
+}
+
+// NewWebhookPublisher returns a WebhookPublisher that posts to the url
+func NewWebhookPublisher(logger *log.Logger, url, secret string, client webhook.Doer) *WebhookPublisher {
+	return &WebhookPublisher{
+		logger: logger,
+		url:    url,
+		secret: secret,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Publish posts the channel and post. Any response other than 2xx is an error, a
+// json body with an id is returned as the published post's id
+func (p *WebhookPublisher) Publish(ctx context.Context, post *dao.Post, channel string) (string, error) {
+	payload := &publishRequest{Channel: channel, Post: post}
+	if selected := dao.SelectedCaption(post.Captions); selected != nil {
+		payload.Caption = selected.Text
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	at := p.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.IDHeader, post.ID.Hex()+":"+channel)
+	req.Header.Set(webhook.EventHeader, EventPublish)
+	req.Header.Set(webhook.TimestampHeader, strconv.FormatInt(at.Unix(), 10))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(p.secret, at, body))
+
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxPublishResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	published := &publishResponse{}
+	if err := json.Unmarshal(respBody, published); err != nil {
+		return "", nil
+	}
+	return published.ID, nil
+}
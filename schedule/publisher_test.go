@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/webhook"
+)
+
+var _ = Describe("WebhookPublisher", func() {
+	var (
+		publisher *WebhookPublisher
+		server    *httptest.Server
+		status    int
+		response  string
+		received  *http.Request
+		body      []byte
+		post      *dao.Post
+		now       time.Time
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		response = `{"id": "tweet-1"}`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(status)
+			w.Write([]byte(response))
+		}))
+
+		logger := log.New()
+		logger.Out = ioutil.Discard
+		publisher = NewWebhookPublisher(logger, server.URL+"/publish", "whsec_test", server.Client())
+		now = time.Now()
+		publisher.now = func() time.Time { return now }
+
+		postID := bson.NewObjectId()
+		post = &dao.Post{ID: &postID, URL: "https://example.com/post", Captions: dao.ManualCaptions([]string{"a"})}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should post the signed channel and post", func() {
+		id, err := publisher.Publish(context.Background(), post, "twitter")
+		Expect(err).To(BeNil())
+		Expect(id).To(Equal("tweet-1"))
+
+		Expect(received.Method).To(Equal("POST"))
+		Expect(received.URL.Path).To(Equal("/publish"))
+		Expect(received.Header.Get(webhook.EventHeader)).To(Equal(EventPublish))
+		Expect(received.Header.Get(webhook.IDHeader)).To(Equal(post.ID.Hex() + ":twitter"))
+		Expect(webhook.Verify("whsec_test", received.Header.Get(webhook.TimestampHeader),
+			received.Header.Get(webhook.SignatureHeader), body, time.Minute, now)).To(Succeed())
+
+		sent := map[string]interface{}{}
+		Expect(json.Unmarshal(body, &sent)).To(Succeed())
+		Expect(sent["channel"]).To(Equal("twitter"))
+		Expect(sent["caption"]).To(Equal("a"))
+		Expect(sent["post"]).To(HaveKeyWithValue("url", "https://example.com/post"))
+	})
+
+	It("should accept a response without an id", func() {
+		response = ""
+		id, err := publisher.Publish(context.Background(), post, "twitter")
+		Expect(err).To(BeNil())
+		Expect(id).To(BeEmpty())
+	})
+
+	It("should return an error for other responses", func() {
+		status = http.StatusBadGateway
+		_, err := publisher.Publish(context.Background(), post, "twitter")
+		Expect(err).To(MatchError("returned status 502"))
+	})
+})
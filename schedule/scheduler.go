@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/dao"
+)
+
+const (
+	// DefaultInterval is how often the scheduler looks for due posts
+	DefaultInterval = 5 * time.Second
+	claimBatchSize  = 50
+	publishTimeout  = 10 * time.Second
+	maxErrorLength  = 256
+	// claimLease is how long a claimed post is held. It outlasts publishing to
+	// the most channels a post can have, after it a post that is still
+	// publishing, because its instance stopped, is claimed again
+	claimLease = 5 * time.Minute
+)
+
+// Scheduler publishes approved posts when they are due. Every post is claimed
+// before it is published, the datastore moves it from approved to publishing
+// only if it is still approved, so instances that share a datastore never
+// publish the same post twice while the claim holds. A post whose instance
+// stopped before it was published is claimed again once the claim runs out
+type Scheduler struct {
+	logger    *log.Logger
+	posts     dao.Poster
+	publisher Publisher
+	interval  time.Duration
+	now       func() time.Time
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewScheduler returns a Scheduler with the provided options
+func NewScheduler(logger *log.Logger, posts dao.Poster, publisher Publisher, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		logger:    logger,
+		posts:     posts,
+		publisher: publisher,
+		interval:  interval,
+		now:       time.Now,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start scans for due posts in the background until Close is called
+func (s *Scheduler) Start() {
+	go s.run()
+}
+
+// Close stops scanning and waits for the posts being published
+func (s *Scheduler) Close() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Scheduler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.publishDue()
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDue publishes the posts that are due and this instance claimed. It
+// returns how many posts were published or failed
+func (s *Scheduler) publishDue() int {
+	posts, err := s.posts.ClaimDue(s.now(), claimLease, claimBatchSize)
+	if err != nil {
+		// Posts returned with the error were claimed, so they are still published
+		s.logger.WithFields(log.Fields{
+			"error": err.Error(),
+			"count": len(posts),
+		}).Error("failed to claim due posts")
+	}
+	for _, post := range posts {
+		s.publish(post)
+	}
+	return len(posts)
+}
+
+// publish sends the claimed post to each of its channels and records the outcome.
+// A failed channel fails the post, it is not retried until it is approved again
+func (s *Scheduler) publish(post *dao.Post) {
+	logger := s.logger.WithFields(log.Fields{
+		"customerID": post.CustID,
+		"post_id":    post.ID.Hex(),
+	})
+
+	publications := make([]*dao.Publication, len(post.Channels))
+	for i, channel := range post.Channels {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		externalID, err := s.publisher.Publish(ctx, post, channel)
+		cancel()
+
+		publications[i] = &dao.Publication{
+			Channel:    channel,
+			At:         s.now().UTC(),
+			ExternalID: externalID,
+		}
+		if err != nil {
+			publications[i].Error = truncate(err.Error())
+			logger.WithFields(log.Fields{
+				"channel": channel,
+				"error":   err.Error(),
+			}).Warn("failed to publish post")
+		}
+	}
+
+	if _, err := s.posts.CompletePublish(post.CustID, *post.ID, publications); err != nil {
+		// The post stays publishing, so it is never published a second time
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Error("failed to record publish")
+		return
+	}
+	logger.Debug("published post")
+}
+
+func truncate(msg string) string {
+	if len(msg) > maxErrorLength {
+		return msg[:maxErrorLength]
+	}
+	return msg
+}
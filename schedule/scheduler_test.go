@@ -0,0 +1,167 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/dao/memory"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// recordingPublisher records every publish and fails the channels in failing
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []string
+	failing   map[string]bool
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, post *dao.Post, channel string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing[channel] {
+		return "", errors.New("channel is down")
+	}
+	p.published = append(p.published, post.ID.Hex()+":"+channel)
+	return "id-" + channel, nil
+}
+
+func (p *recordingPublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.published)
+}
+
+// unrecordedPoster claims posts but fails to record the claims
+type unrecordedPoster struct {
+	dao.Poster
+}
+
+func (p unrecordedPoster) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*dao.Post, error) {
+	posts, err := p.Poster.ClaimDue(now, lease, limit)
+	if err != nil {
+		return nil, err
+	}
+	return posts, errors.New("audit log is down")
+}
+
+var _ = Describe("Scheduler", func() {
+	var (
+		logger     *log.Logger
+		ds         *datastore.InMemoryDatastore
+		posts      dao.Poster
+		publisher  *recordingPublisher
+		scheduler  *Scheduler
+		now        time.Time
+		customerID string
+		post       *dao.Post
+	)
+
+	newScheduler := func() *Scheduler {
+		s := NewScheduler(logger, posts, publisher, time.Millisecond)
+		s.now = func() time.Time { return now }
+		return s
+	}
+
+	BeforeEach(func() {
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		ds = datastore.NewInMemoryDatastore(logger)
+		posts = memory.NewPoster(logger, ds)
+		publisher = &recordingPublisher{failing: map[string]bool{}}
+		now = time.Now()
+		scheduler = newScheduler()
+		customerID = "test-customer"
+
+		var err error
+		post, err = posts.Insert(customerID, &dao.Post{URL: "https://example.com/post", Captions: dao.ManualCaptions([]string{"a"})})
+		Expect(err).To(BeNil())
+		_, err = posts.Update(customerID, &dao.Post{ID: post.ID, Captions: dao.ManualCaptions([]string{"a"}), Status: dao.StatusApproved})
+		Expect(err).To(BeNil())
+		at := now.Add(-time.Second)
+		_, err = posts.Schedule(customerID, &dao.Post{ID: post.ID, ScheduledAt: &at, Channels: []string{"twitter", "linkedin"}})
+		Expect(err).To(BeNil())
+	})
+
+	current := func() *dao.Post {
+		p, err := posts.Get(customerID, *post.ID)
+		Expect(err).To(BeNil())
+		return p
+	}
+
+	It("should publish due posts to every channel", func() {
+		Expect(scheduler.publishDue()).To(Equal(1))
+		Expect(publisher.published).To(Equal([]string{post.ID.Hex() + ":twitter", post.ID.Hex() + ":linkedin"}))
+
+		published := current()
+		Expect(published.Status).To(Equal(dao.StatusPublished))
+		Expect(published.Publications).To(HaveLen(2))
+		Expect(published.Publications[0].ExternalID).To(Equal("id-twitter"))
+
+		Expect(scheduler.publishDue()).To(Equal(0))
+		Expect(publisher.count()).To(Equal(2))
+	})
+
+	It("should not publish posts that are not due", func() {
+		at := now.Add(time.Minute)
+		posts.Schedule(customerID, &dao.Post{ID: post.ID, ScheduledAt: &at, Channels: []string{"twitter"}})
+		Expect(scheduler.publishDue()).To(Equal(0))
+		Expect(current().Status).To(Equal(dao.StatusApproved))
+	})
+
+	It("should record a failed channel", func() {
+		publisher.failing["linkedin"] = true
+		Expect(scheduler.publishDue()).To(Equal(1))
+
+		failed := current()
+		Expect(failed.Status).To(Equal(dao.StatusPublishFailed))
+		Expect(failed.Publications[1].Channel).To(Equal("linkedin"))
+		Expect(failed.Publications[1].Error).To(Equal("channel is down"))
+	})
+
+	It("should publish a post whose instance stopped once its claim ran out", func() {
+		// Another instance claimed the post and stopped before publishing it
+		_, err := posts.ClaimDue(now, claimLease, 0)
+		Expect(err).To(BeNil())
+		Expect(current().Status).To(Equal(dao.StatusPublishing))
+
+		Expect(scheduler.publishDue()).To(Equal(0))
+		now = now.Add(claimLease)
+		Expect(scheduler.publishDue()).To(Equal(1))
+		Expect(current().Status).To(Equal(dao.StatusPublished))
+		Expect(publisher.count()).To(Equal(2))
+	})
+
+	It("should publish posts that were claimed with an error", func() {
+		posts = unrecordedPoster{posts}
+		scheduler = newScheduler()
+		Expect(scheduler.publishDue()).To(Equal(1))
+		Expect(current().Status).To(Equal(dao.StatusPublished))
+	})
+
+	It("should never publish a post twice from instances sharing the datastore", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(s *Scheduler) {
+				defer wg.Done()
+				s.publishDue()
+			}(newScheduler())
+		}
+		wg.Wait()
+		Expect(publisher.count()).To(Equal(2))
+	})
+
+	It("should publish in the background", func() {
+		scheduler.Start()
+		Eventually(publisher.count).Should(Equal(2))
+		scheduler.Close()
+	})
+})
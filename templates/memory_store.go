@@ -0,0 +1,146 @@
+package templates
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// InMemoryStore implements the Store interface for in memory storage
+type InMemoryStore struct {
+	logger    *log.Logger
+	mu        sync.Mutex
+	templates map[bson.ObjectId]*Template
+	now       func() time.Time
+}
+
+// NewInMemoryStore creates a new InMemoryStore with the provided options
+func NewInMemoryStore(logger *log.Logger) *InMemoryStore {
+	return &InMemoryStore{
+		logger:    logger,
+		templates: make(map[bson.ObjectId]*Template),
+		now:       time.Now,
+	}
+}
+
+// Create validates and stores a new template
+func (s *InMemoryStore) Create(customerID string, t *Template) (*Template, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+	if t == nil {
+		return nil, datastore.NewInvalidArugmentError("must provide template")
+	}
+	if err := Validate(t); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	created := &Template{
+		ID:        bson.NewObjectId(),
+		CustID:    customerID,
+		Name:      t.Name,
+		Text:      t.Text,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.templates[created.ID] = created
+
+	s.logger.WithFields(log.Fields{
+		"customerID":  customerID,
+		"template_id": created.ID.Hex(),
+	}).Debug("created caption template")
+	copied := *created
+	return &copied, nil
+}
+
+// Get returns the customer's template
+func (s *InMemoryStore) Get(customerID string, id bson.ObjectId) (*Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.get(customerID, id)
+	if err != nil {
+		return nil, err
+	}
+	copied := *t
+	return &copied, nil
+}
+
+// List returns all of the customer's templates, oldest first
+func (s *InMemoryStore) List(customerID string) ([]*Template, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	templates := []*Template{}
+	for _, t := range s.templates {
+		if t.CustID == customerID {
+			copied := *t
+			templates = append(templates, &copied)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].CreatedAt.Equal(templates[j].CreatedAt) {
+			return templates[i].ID < templates[j].ID
+		}
+		return templates[i].CreatedAt.Before(templates[j].CreatedAt)
+	})
+	return templates, nil
+}
+
+// Update replaces the name and text of the template
+func (s *InMemoryStore) Update(customerID string, t *Template) (*Template, error) {
+	if t == nil {
+		return nil, datastore.NewInvalidArugmentError("must provide template")
+	}
+	if err := Validate(t); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, err := s.get(customerID, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	prev.Name = t.Name
+	prev.Text = t.Text
+	prev.UpdatedAt = s.now().UTC()
+	copied := *prev
+	return &copied, nil
+}
+
+// Delete removes the template
+func (s *InMemoryStore) Delete(customerID string, id bson.ObjectId) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.get(customerID, id); err != nil {
+		return err
+	}
+	delete(s.templates, id)
+	return nil
+}
+
+// get must be called with the lock held
+func (s *InMemoryStore) get(customerID string, id bson.ObjectId) (*Template, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+	if id == "" {
+		return nil, datastore.NewInvalidArugmentError("templateID")
+	}
+	t, ok := s.templates[id]
+	if !ok || t.CustID != customerID {
+		return nil, datastore.NewNotFoundError("template")
+	}
+	return t, nil
+}
@@ -0,0 +1,68 @@
+package templates
+
+import (
+	"io/ioutil"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("InMemoryStore", func() {
+	var (
+		store      *InMemoryStore
+		customerID string
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		logger := log.New()
+		logger.Out = ioutil.Discard
+		store = NewInMemoryStore(logger)
+		customerID = "test-customer"
+		now = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		store.now = func() time.Time { return now }
+	})
+
+	It("should create, update and delete templates of the customer", func() {
+		created, err := store.Create(customerID, &Template{Name: " House style ", Text: "{{summary}} {{short_url}}"})
+		Expect(err).To(BeNil())
+		Expect(created.Name).To(Equal("House style"))
+		Expect(created.CreatedAt).To(Equal(now))
+
+		_, err = store.Get("other-customer", created.ID)
+		Expect(err).To(BeAssignableToTypeOf(&datastore.NotFound{}))
+
+		now = now.Add(time.Hour)
+		updated, err := store.Update(customerID, &Template{ID: created.ID, Name: "Renamed", Text: "{{summary}}"})
+		Expect(err).To(BeNil())
+		Expect(updated.Text).To(Equal("{{summary}}"))
+		Expect(updated.CreatedAt).To(Equal(created.CreatedAt))
+		Expect(updated.UpdatedAt).To(Equal(now))
+
+		list, err := store.List(customerID)
+		Expect(err).To(BeNil())
+		Expect(list).To(Equal([]*Template{updated}))
+
+		Expect(store.Delete(customerID, created.ID)).To(Succeed())
+		_, err = store.Get(customerID, created.ID)
+		Expect(err).To(BeAssignableToTypeOf(&datastore.NotFound{}))
+	})
+
+	It("should NOT store invalid templates", func() {
+		_, err := store.Create(customerID, &Template{Text: "{{printf \"%d\" 1}}"})
+		Expect(err).To(BeAssignableToTypeOf(&datastore.Validation{}))
+		Expect(err.(*datastore.Validation).Fields).To(HaveLen(2))
+
+		_, err = store.Create(customerID, &Template{Name: "test", Text: "{{range 2000000000}}x{{end}}"})
+		Expect(err).To(BeAssignableToTypeOf(&datastore.Validation{}))
+		Expect(err.(*datastore.Validation).Fields).To(Equal([]datastore.FieldError{{Field: "text", Message: "ranges must be over captions or keywords"}}))
+
+		_, err = store.Update(customerID, &Template{ID: bson.NewObjectId(), Name: "test", Text: "{{summary}}"})
+		Expect(err).To(BeAssignableToTypeOf(&datastore.NotFound{}))
+	})
+})
@@ -0,0 +1,285 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bpross/cc-hw/canonical"
+	"github.com/bpross/cc-hw/caption"
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+const (
+	// maxHashtags is how many of the url's keywords become hashtags
+	maxHashtags = 5
+	// maxOutputBytes stops a template that renders far more than a caption
+	maxOutputBytes = 4096
+	// maxRenderSteps is the most nodes a template may execute, counting the
+	// body of a range once for every item of its list
+	maxRenderSteps = 10000
+)
+
+// Vars are the article metadata and captions a template is rendered with. The
+// metadata is read from the article's url
+type Vars struct {
+	URL          string
+	CanonicalURL string
+	// ShortURL is the canonical url without its scheme
+	ShortURL string
+	Domain   string
+	// Title is the last segment of the url's path as words, e.g. "How to register
+	// a domain" for /2019/12/04/how-to-register-a-domain/
+	Title    string
+	Keywords []string
+	Hashtags string
+	Captions []string
+}
+
+// NewVars returns the variables of the article at rawURL with its captions, best
+// first
+func NewVars(rawURL string, captions []string) *Vars {
+	vars := &Vars{URL: rawURL, CanonicalURL: rawURL, Captions: captions}
+	if canonicalURL, err := canonical.URL(rawURL); err == nil {
+		vars.CanonicalURL = canonicalURL
+	}
+	vars.ShortURL = strings.TrimPrefix(strings.TrimPrefix(vars.CanonicalURL, "https://"), "http://")
+	if u, err := url.Parse(vars.CanonicalURL); err == nil {
+		vars.Domain = u.Hostname()
+		vars.Title = slugTitle(u.Path)
+	}
+	vars.Keywords = caption.Keywords(rawURL)
+	hashtags := []string{}
+	for _, keyword := range vars.Keywords {
+		if len(hashtags) == maxHashtags {
+			break
+		}
+		hashtags = append(hashtags, hashtag(keyword))
+	}
+	vars.Hashtags = strings.Join(hashtags, " ")
+	return vars
+}
+
+// Render renders the text of a template for one caption, summary is its text.
+// Runs of spaces are collapsed and the result is trimmed. Errors are returned as
+// a *datastore.Validation on field
+func Render(field, text string, vars *Vars, summary string) (string, error) {
+	t, err := parseText(text, funcs(vars, summary))
+	if err != nil {
+		return "", datastore.NewValidationError(datastore.FieldError{Field: field, Message: err.Error()})
+	}
+	if n := steps(t.Tree.Root, vars); n > maxRenderSteps {
+		return "", datastore.NewValidationError(datastore.FieldError{Field: field, Message: fmt.Sprintf("takes more than %d steps to render", maxRenderSteps)})
+	}
+	out := &limitedBuffer{max: maxOutputBytes}
+	if err := t.Execute(out, data(vars, summary)); err != nil {
+		return "", datastore.NewValidationError(datastore.FieldError{Field: field, Message: "can not be rendered: " + cleanError(err).Error()})
+	}
+	rendered := strings.TrimSpace(collapseSpaces(out.String()))
+	if rendered == "" {
+		return "", datastore.NewValidationError(datastore.FieldError{Field: field, Message: "renders an empty caption"})
+	}
+	return rendered, nil
+}
+
+// Apply renders the text of a template for every caption of the article at
+// rawURL. The rendered captions keep everything but their text
+func Apply(field, text, rawURL string, captions []*dao.Caption) ([]*dao.Caption, error) {
+	texts := make([]string, len(captions))
+	for i, c := range captions {
+		texts[i] = c.Text
+	}
+	vars := NewVars(rawURL, texts)
+
+	rendered := dao.CopyCaptions(captions)
+	for _, c := range rendered {
+		var err error
+		if c.Text, err = Render(field, text, vars, c.Text); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// funcs are the functions a template can call: a function for every variable,
+// so {{summary}} and {{.summary}} are the same, and helpers that can not
+// produce much more output than they are given
+func funcs(vars *Vars, summary string) template.FuncMap {
+	fns := template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"capitalize": capitalize,
+		"trim":       strings.TrimSpace,
+		"truncate":   truncate,
+		"join":       join,
+		"hashtag":    hashtag,
+		"default":    defaultString,
+	}
+	for name, value := range data(vars, summary) {
+		switch v := value.(type) {
+		case string:
+			fns[name] = func() string { return v }
+		case []string:
+			fns[name] = func() []string { return v }
+		}
+	}
+	return fns
+}
+
+// data is the dot a template is executed with
+func data(vars *Vars, summary string) map[string]interface{} {
+	return map[string]interface{}{
+		"summary":       summary,
+		"captions":      vars.Captions,
+		"url":           vars.URL,
+		"canonical_url": vars.CanonicalURL,
+		"short_url":     vars.ShortURL,
+		"domain":        vars.Domain,
+		"title":         vars.Title,
+		"keywords":      vars.Keywords,
+		"hashtags":      vars.Hashtags,
+	}
+}
+
+// capitalize upper cases the first letter of s
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// truncate cuts s to at most n characters, ending it with "…" when it was cut.
+// The cut is made at the last space when there is one
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 1 {
+		return ""
+	}
+	if len(runes) <= n {
+		return s
+	}
+	cut := string(runes[:n-1])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:") + "…"
+}
+
+func join(sep string, items []string) string {
+	return strings.Join(items, sep)
+}
+
+// hashtag makes a hashtag of the words of s, e.g. "#AgencyDomain" for "agency
+// domain"
+func hashtag(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	for i, word := range words {
+		if len(words) > 1 {
+			words[i] = capitalize(word)
+		}
+	}
+	return "#" + strings.Join(words, "")
+}
+
+// defaultString returns s, or def when s is empty
+func defaultString(def, s string) string {
+	if strings.TrimSpace(s) == "" {
+		return def
+	}
+	return s
+}
+
+// slugTitle returns the words of the last segment of the path, capitalized. A
+// segment without letters, like a date or an id, has no title
+func slugTitle(p string) string {
+	slug := path.Base(strings.TrimSuffix(p, "/"))
+	slug = strings.TrimSuffix(slug, path.Ext(slug))
+	if strings.IndexFunc(slug, unicode.IsLetter) < 0 {
+		return ""
+	}
+	words := strings.FieldsFunc(slug, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return capitalize(strings.Join(words, " "))
+}
+
+// steps returns how many nodes rendering node executes at most. Ranges are only
+// over listVars, so the length of their list is known before rendering
+func steps(node parse.Node, vars *Vars) int {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return 0
+		}
+		total := 0
+		for _, child := range n.Nodes {
+			total += steps(child, vars)
+		}
+		return total
+	case *parse.IfNode:
+		return 1 + steps(n.List, vars) + steps(n.ElseList, vars)
+	case *parse.WithNode:
+		return 1 + steps(n.List, vars) + steps(n.ElseList, vars)
+	case *parse.RangeNode:
+		items := 0
+		switch rangeList(n.Pipe) {
+		case "captions":
+			items = len(vars.Captions)
+		case "keywords":
+			items = len(vars.Keywords)
+		}
+		return 1 + items*steps(n.List, vars) + steps(n.ElseList, vars)
+	default:
+		return 1
+	}
+}
+
+// collapseSpaces replaces runs of spaces and tabs with one space, line breaks
+// are kept
+func collapseSpaces(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			if !space {
+				b.WriteRune(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// limitedBuffer fails writes past max bytes
+type limitedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("renders more than %d bytes", b.max)
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,109 @@
+package templates
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("Render", func() {
+	const articleURL = "https://www.example.com/2019/12/04/how-to-register-an-agency-domain/?utm_source=feed"
+
+	It("should read the metadata from the url", func() {
+		vars := NewVars(articleURL, []string{"First.", "Second."})
+		Expect(vars).To(Equal(&Vars{
+			URL:          articleURL,
+			CanonicalURL: "https://example.com/2019/12/04/how-to-register-an-agency-domain",
+			ShortURL:     "example.com/2019/12/04/how-to-register-an-agency-domain",
+			Domain:       "example.com",
+			Title:        "How to register an agency domain",
+			Keywords:     []string{"register", "agency", "domain"},
+			Hashtags:     "#register #agency #domain",
+			Captions:     []string{"First.", "Second."},
+		}))
+		Expect(NewVars("https://example.com/posts/12345", nil).Title).To(BeEmpty())
+	})
+
+	It("should render the variables and functions", func() {
+		vars := NewVars(articleURL, []string{"Register your domain.", "It is easy."})
+		cases := []struct {
+			text     string
+			rendered string
+		}{
+			{"📣 {{summary}} Read more: {{short_url}} {{hashtags}}", "📣 Register your domain. Read more: example.com/2019/12/04/how-to-register-an-agency-domain #register #agency #domain"},
+			{"{{.title}} on {{.domain}}", "How to register an agency domain on example.com"},
+			{"{{summary | truncate 12}}", "Register…"},
+			{"{{summary | upper}} {{title | lower}}", "REGISTER YOUR DOMAIN. how to register an agency domain"},
+			{"{{hashtag title}} {{join \", \" keywords}}", "#HowToRegisterAnAgencyDomain register, agency, domain"},
+			{"{{if eq summary (index captions 0)}}Top pick: {{end}}{{summary}}", "Top pick: Register your domain."},
+			{"{{range keywords}}#{{.}} {{end}}", "#register #agency #domain"},
+			{"{{range $i, $k := .keywords}}{{if $i}}, {{end}}{{$k}}{{end}}", "register, agency, domain"},
+			{"{{summary}}   \n{{default \"none\" \"\"}}", "Register your domain. \nnone"},
+		}
+		for _, c := range cases {
+			rendered, err := Render("template", c.text, vars, "Register your domain.")
+			Expect(err).To(BeNil(), c.text)
+			Expect(rendered).To(Equal(c.rendered), c.text)
+		}
+	})
+
+	It("should NOT allow unsafe templates", func() {
+		cases := []struct {
+			text    string
+			message string
+		}{
+			{"{{printf \"%099999999d\" 1}}", `function "printf" is not allowed`},
+			{"{{call .summary}}", `function "call" is not allowed`},
+			{"{{define \"x\"}}{{end}}{{template \"x\"}}", "must not define templates"},
+			{"{{template \"caption\"}}", "must not call templates"},
+			{"{{range captions}}{{range keywords}}{{end}}{{end}}", "ranges must not be nested"},
+			{"{{range 2000000000}}x{{end}}", "ranges must be over captions or keywords"},
+			{"{{range $i := 2000000000}}x{{end}}", "ranges must be over captions or keywords"},
+			{"{{range summary}}x{{end}}", "ranges must be over captions or keywords"},
+			{"{{range (index captions 0)}}x{{end}}", "ranges must be over captions or keywords"},
+			{"{{summary", "1: unclosed action"},
+			{"{{nope}}", `1: function "nope" not defined`},
+			{" ", "is required"},
+		}
+		for _, c := range cases {
+			err := Validate(&Template{Name: "test", Text: c.text})
+			Expect(err).To(HaveOccurred(), c.text)
+			Expect(err.(*datastore.Validation).Fields).To(Equal([]datastore.FieldError{{Field: "text", Message: c.message}}), c.text)
+		}
+	})
+
+	It("should return the errors of rendering on the field", func() {
+		vars := NewVars(articleURL, []string{"Only one."})
+		_, err := Render("template", "{{index captions 3}}", vars, "Only one.")
+		Expect(err).To(BeAssignableToTypeOf(&datastore.Validation{}))
+		Expect(err.(*datastore.Validation).Fields[0].Field).To(Equal("template"))
+		Expect(err.(*datastore.Validation).Fields[0].Message).To(HavePrefix("can not be rendered: "))
+
+		_, err = Render("template", "{{if false}}{{summary}}{{end}}", vars, "Only one.")
+		Expect(err.(*datastore.Validation).Fields[0].Message).To(Equal("renders an empty caption"))
+
+		_, err = Render("template", "{{range captions}}{{summary}}{{summary}}{{summary}}{{end}}", NewVars(articleURL, make([]string, 10)), string(make([]byte, 200)))
+		Expect(err.(*datastore.Validation).Fields[0].Message).To(Equal("can not be rendered: renders more than 4096 bytes"))
+
+		_, err = Render("template", "{{range captions}}"+strings.Repeat("{{if false}}{{end}}", 40)+"{{end}}", NewVars(articleURL, make([]string, 300)), "Only one.")
+		Expect(err.(*datastore.Validation).Fields[0].Message).To(Equal("takes more than 10000 steps to render"))
+	})
+
+	It("should apply the template to every caption", func() {
+		captions := []*dao.Caption{
+			{ID: "c1", Text: "First.", Source: dao.SourceGenerated, Score: 0.5},
+			{ID: "c2", Text: "Second.", Source: dao.SourceGenerated, Score: 0.25},
+		}
+		rendered, err := Apply("template", "{{summary}} {{short_url}}", "https://example.com/a", captions)
+		Expect(err).To(BeNil())
+		Expect(rendered).To(Equal([]*dao.Caption{
+			{ID: "c1", Text: "First. example.com/a", Source: dao.SourceGenerated, Score: 0.5},
+			{ID: "c2", Text: "Second. example.com/a", Source: dao.SourceGenerated, Score: 0.25},
+		}))
+		Expect(captions[0].Text).To(Equal("First."))
+	})
+})
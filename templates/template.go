@@ -0,0 +1,197 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+const (
+	maxNameLength = 100
+	maxTextLength = 1000
+	// templateName is the name templates are parsed with, it is cut from errors
+	templateName = "caption"
+)
+
+// listVars are the variables that are lists, the only things a template can
+// range over
+var listVars = map[string]bool{"captions": true, "keywords": true}
+
+// builtins are the text/template functions a template may call. The others
+// can print unbounded output (printf) or call functions out of the variables
+// (call), and the escapers are for html, not captions
+var builtins = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true, "index": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// Template renders generated captions in a customer's house style. Text is a
+// text/template, see Render for its variables and functions
+type Template struct {
+	ID        bson.ObjectId `json:"id"`
+	CustID    string        `json:"-"` // do not return when we marshal to json
+	Name      string        `json:"name"`
+	Text      string        `json:"text"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Store defines the interface for managing the templates of every customer
+type Store interface {
+	Create(string, *Template) (*Template, error)
+	Get(string, bson.ObjectId) (*Template, error)
+	List(string) ([]*Template, error)
+	Update(string, *Template) (*Template, error)
+	Delete(string, bson.ObjectId) error
+}
+
+// Validate checks the name and text of a template and trims its name in place
+func Validate(t *Template) error {
+	verr := datastore.NewValidationError()
+	t.Name = strings.TrimSpace(t.Name)
+	switch {
+	case t.Name == "":
+		verr.Add("name", "is required")
+	case utf8.RuneCountInString(t.Name) > maxNameLength:
+		verr.Add("name", fmt.Sprintf("must be at most %d characters", maxNameLength))
+	}
+	if err := Check(t.Text); err != nil {
+		verr.Add("text", err.Error())
+	}
+	if verr.HasErrors() {
+		return verr
+	}
+	return nil
+}
+
+// Check parses the text of a template and returns why it can not be used
+func Check(text string) error {
+	switch {
+	case strings.TrimSpace(text) == "":
+		return fmt.Errorf("is required")
+	case utf8.RuneCountInString(text) > maxTextLength:
+		return fmt.Errorf("must be at most %d characters", maxTextLength)
+	}
+	_, err := parseText(text, funcs(&Vars{}, ""))
+	return err
+}
+
+// parseText parses the text with the functions, and only allows the functions
+// of funcs and builtins. Templates can not define or call other templates, and
+// ranges can not be nested and only range over listVars, so rendering is
+// bounded by the size of the text and the variables
+func parseText(text string, fns template.FuncMap) (*template.Template, error) {
+	t, err := template.New(templateName).Option("missingkey=error").Funcs(fns).Parse(text)
+	if err != nil {
+		return nil, cleanError(err)
+	}
+	if len(t.Templates()) > 1 {
+		return nil, fmt.Errorf("must not define templates")
+	}
+	if err := checkNode(t.Tree.Root, fns, false); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// checkNode walks the parse tree under node and returns the first action a
+// template is not allowed to take
+func checkNode(node parse.Node, fns template.FuncMap, inRange bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkNode(child, fns, inRange); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkNode(n.Pipe, fns, inRange)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkNode(cmd, fns, inRange); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkNode(arg, fns, inRange); err != nil {
+				return err
+			}
+		}
+	case *parse.IdentifierNode:
+		if _, ok := fns[n.Ident]; !ok && !builtins[n.Ident] {
+			return fmt.Errorf("function %q is not allowed", n.Ident)
+		}
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode, fns, inRange)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode, fns, inRange)
+	case *parse.RangeNode:
+		if inRange {
+			return fmt.Errorf("ranges must not be nested")
+		}
+		if rangeList(n.Pipe) == "" {
+			return fmt.Errorf("ranges must be over captions or keywords")
+		}
+		return checkBranch(&n.BranchNode, fns, true)
+	case *parse.ChainNode:
+		return checkNode(n.Node, fns, inRange)
+	case *parse.TemplateNode:
+		return fmt.Errorf("must not call templates")
+	}
+	return nil
+}
+
+// rangeList returns the name of the list variable the pipeline of a range is,
+// e.g. captions for {{range $i, $c := .captions}}, or an empty string when it
+// is anything else, like a number
+func rangeList(pipe *parse.PipeNode) string {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return ""
+	}
+	var name string
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.IdentifierNode:
+		name = arg.Ident
+	case *parse.FieldNode:
+		if len(arg.Ident) == 1 {
+			name = arg.Ident[0]
+		}
+	case *parse.VariableNode:
+		if len(arg.Ident) == 2 && arg.Ident[0] == "$" {
+			name = arg.Ident[1]
+		}
+	}
+	if !listVars[name] {
+		return ""
+	}
+	return name
+}
+
+func checkBranch(n *parse.BranchNode, fns template.FuncMap, inRange bool) error {
+	if err := checkNode(n.Pipe, fns, inRange); err != nil {
+		return err
+	}
+	if err := checkNode(n.List, fns, inRange); err != nil {
+		return err
+	}
+	return checkNode(n.ElseList, fns, inRange)
+}
+
+// cleanError removes the template name text/template starts its errors with
+func cleanError(err error) error {
+	return fmt.Errorf("%s", strings.TrimPrefix(err.Error(), "template: "+templateName+":"))
+}
@@ -0,0 +1,60 @@
+// +build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/client"
+)
+
+// apiURL is where the api runs in docker compose
+const apiURL = "http://api:8080"
+
+// newClient returns a client using the api key
+func newClient(apiKey string) *client.Client {
+	return client.NewClient(apiURL, apiKey, nil, client.DefaultRetryPolicy())
+}
+
+// headerDoer adds its headers to every request, e.g. to spoof the customer
+type headerDoer map[string]string
+
+// Do sends the request with the extra headers
+func (h headerDoer) Do(req *http.Request) (*http.Response, error) {
+	for k, v := range h {
+		req.Header.Set(k, v)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// newSpoofingClient returns a client using the api key that also claims to
+// be another customer with the x-customer-id header
+func newSpoofingClient(apiKey, customerID string) *client.Client {
+	doer := headerDoer{"x-customer-id": customerID}
+	return client.NewClient(apiURL, apiKey, doer, client.DefaultRetryPolicy())
+}
+
+// createAPIKey uses the admin api to issue a key for the customer
+func createAPIKey(customerID string) string {
+	reqUrl := fmt.Sprintf("%s/v1/admin/customers/%s/keys", apiURL, customerID)
+	req, err := http.NewRequest("POST", reqUrl, nil)
+	Expect(err).To(BeNil())
+	req.Header.Add("x-api-key", os.Getenv("ADMIN_API_KEY"))
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	Expect(err).To(BeNil())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+	key := struct {
+		Secret string `json:"secret"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&key)
+	Expect(err).To(BeNil())
+	return key.Secret
+}
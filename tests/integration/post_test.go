@@ -3,64 +3,40 @@
 package integration
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
+	"context"
+	"errors"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"labix.org/v2/mgo/bson"
 
+	"github.com/bpross/cc-hw/client"
 	"github.com/bpross/cc-hw/dao"
-	"github.com/bpross/cc-hw/handler"
 )
 
 // This describes the tests enumerated in the design doc
 var _ = Describe("Post", func() {
 	var (
-		reqUrl   string
+		ctx      context.Context
+		c        *client.Client
 		url      string
-		resp     *http.Response
 		postBody *dao.Post
 		getBody  *dao.Post
+		err      error
 	)
 
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newClient(createAPIKey("1"))
+	})
+
 	Describe("Test Case 1", func() {
 		BeforeEach(func() {
-			// Make POST request
-			reqUrl = "http://api:8080/post"
 			url = "https://blog.cloudcampaign.io/2018/03/11/7-social-media-stats-you-can-leverage-to-land-more-clients/"
-			bodyStruct := handler.GeneratePostRequest{
-				URL: url,
-			}
-			body, err := json.Marshal(bodyStruct)
-			Expect(err).To(BeNil())
-			req, err := http.NewRequest("POST", reqUrl, bytes.NewBuffer(body))
-			Expect(err).To(BeNil())
-			req.Header.Add("x-customer-id", "1")
-			req.Header.Add("Content-Type", "application/json")
-			client := &http.Client{}
-			resp, err = client.Do(req)
-			Expect(err).To(BeNil())
-			respBody, err := ioutil.ReadAll(resp.Body)
-			Expect(err).To(BeNil())
-			postBody = &dao.Post{}
-			err = json.Unmarshal(respBody, postBody)
+			postBody, err = c.Generate(ctx, url)
 			Expect(err).To(BeNil())
 
-			// Make GET request
-			reqUrl = reqUrl + "/" + postBody.ID.Hex()
-			req, err = http.NewRequest("GET", reqUrl, nil)
-			req.Header.Add("x-customer-id", "1")
-			req.Header.Add("Content-Type", "application/json")
-			resp, err = client.Do(req)
-			Expect(err).To(BeNil())
-			respBody, err = ioutil.ReadAll(resp.Body)
-			Expect(err).To(BeNil())
-			getBody = &dao.Post{}
-			err = json.Unmarshal(respBody, getBody)
+			getBody, err = c.Get(ctx, postBody.ID.Hex())
 			Expect(err).To(BeNil())
 		})
 
@@ -71,42 +47,18 @@ var _ = Describe("Post", func() {
 
 	Describe("Test Case 2", func() {
 		BeforeEach(func() {
-			client := &http.Client{}
-			dneID := bson.NewObjectId()
-			reqUrl = fmt.Sprintf("http://api:8080/post/%s", dneID.Hex())
-			req, err := http.NewRequest("GET", reqUrl, nil)
-			req.Header.Add("x-customer-id", "1")
-			req.Header.Add("Content-Type", "application/json")
-			resp, err = client.Do(req)
-			Expect(err).To(BeNil())
+			_, err = c.Get(ctx, bson.NewObjectId().Hex())
 		})
 
 		It("should return 404", func() {
-			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+			Expect(errors.Is(err, client.ErrNotFound)).To(BeTrue())
 		})
 	})
 
 	Describe("Test Case 3", func() {
 		BeforeEach(func() {
-			// Make POST request
-			reqUrl = "http://api:8080/post"
 			url = "http://google.com"
-			bodyStruct := handler.GeneratePostRequest{
-				URL: url,
-			}
-			body, err := json.Marshal(bodyStruct)
-			Expect(err).To(BeNil())
-			req, err := http.NewRequest("POST", reqUrl, bytes.NewBuffer(body))
-			Expect(err).To(BeNil())
-			req.Header.Add("x-customer-id", "1")
-			req.Header.Add("Content-Type", "application/json")
-			client := &http.Client{}
-			resp, err = client.Do(req)
-			Expect(err).To(BeNil())
-			respBody, err := ioutil.ReadAll(resp.Body)
-			Expect(err).To(BeNil())
-			postBody = &dao.Post{}
-			err = json.Unmarshal(respBody, postBody)
+			postBody, err = c.Generate(ctx, url)
 			Expect(err).To(BeNil())
 		})
 
@@ -118,79 +70,79 @@ var _ = Describe("Post", func() {
 	Describe("Test Case 4", func() {
 		var newCaptions []string
 		BeforeEach(func() {
-			// Make POST request
-			reqUrl = "http://api:8080/post"
 			url = "https://blog.cloudcampaign.io/2018/03/11/7-social-media-stats-you-can-leverage-to-land-more-clients/"
-			bodyStruct := handler.GeneratePostRequest{
-				URL: url,
-			}
-			body, err := json.Marshal(bodyStruct)
-			Expect(err).To(BeNil())
-			req, err := http.NewRequest("POST", reqUrl, bytes.NewBuffer(body))
-			Expect(err).To(BeNil())
-			req.Header.Add("x-customer-id", "1")
-			req.Header.Add("Content-Type", "application/json")
-			client := &http.Client{}
-			resp, err = client.Do(req)
-			Expect(err).To(BeNil())
-			respBody, err := ioutil.ReadAll(resp.Body)
-			Expect(err).To(BeNil())
-			postBody = &dao.Post{}
-			err = json.Unmarshal(respBody, postBody)
+			postBody, err = c.Generate(ctx, url)
 			Expect(err).To(BeNil())
 
-			// Make PUT request
-			reqUrl = reqUrl + "/" + postBody.ID.Hex()
 			newCaptions = []string{
 				"test1",
 			}
-			updateStruct := dao.Post{
-				Captions: newCaptions,
-			}
-			body, err = json.Marshal(updateStruct)
+			_, err = c.Update(ctx, postBody.ID.Hex(), newCaptions)
 			Expect(err).To(BeNil())
-			req, err = http.NewRequest("PUT", reqUrl, bytes.NewBuffer(body))
-			Expect(err).To(BeNil())
-			req.Header.Add("x-customer-id", "1")
-			req.Header.Add("Content-Type", "application/json")
-			resp, err = client.Do(req)
-			Expect(err).To(BeNil())
-			Expect(resp.StatusCode).To(Equal(http.StatusOK))
 
 			firstID := postBody.ID.Hex()
 
 			// Make second POST request
-			reqUrl = "http://api:8080/post"
-			url = "https://blog.cloudcampaign.io/2018/03/11/7-social-media-stats-you-can-leverage-to-land-more-clients/"
-			bodyStruct = handler.GeneratePostRequest{
-				URL: url,
-			}
-			body, err = json.Marshal(bodyStruct)
-			Expect(err).To(BeNil())
-			req, err = http.NewRequest("POST", reqUrl, bytes.NewBuffer(body))
-			Expect(err).To(BeNil())
-			req.Header.Add("x-customer-id", "1")
-			req.Header.Add("Content-Type", "application/json")
-			resp, err = client.Do(req)
+			_, err = c.Generate(ctx, url)
 			Expect(err).To(BeNil())
-			Expect(resp.StatusCode).To(Equal(http.StatusOK))
-
-			// Make GET request
-			reqUrl = reqUrl + "/" + firstID
-			req, err = http.NewRequest("GET", reqUrl, nil)
-			req.Header.Add("x-customer-id", "1")
-			req.Header.Add("Content-Type", "application/json")
-			resp, err = client.Do(req)
-			Expect(err).To(BeNil())
-			respBody, err = ioutil.ReadAll(resp.Body)
-			Expect(err).To(BeNil())
-			getBody = &dao.Post{}
-			err = json.Unmarshal(respBody, getBody)
+
+			getBody, err = c.Get(ctx, firstID)
 			Expect(err).To(BeNil())
 		})
 
 		It("should have the captions from PUT request", func() {
-			Expect(getBody.Captions).To(Equal(newCaptions))
+			Expect(dao.CaptionTexts(getBody.Captions)).To(Equal(newCaptions))
+		})
+	})
+
+	Describe("Test Case 5", func() {
+		BeforeEach(func() {
+			// Create as customer 1
+			postBody, err = c.Create(ctx, "http://google.com", []string{"test1"})
+			Expect(err).To(BeNil())
+
+			// Get as customer 2, claiming to be customer 1
+			_, err = newSpoofingClient(createAPIKey("2"), "1").Get(ctx, postBody.ID.Hex())
+		})
+
+		It("should not return another customer's post", func() {
+			Expect(errors.Is(err, client.ErrNotFound)).To(BeTrue())
+		})
+	})
+
+	Describe("Test Case 6", func() {
+		BeforeEach(func() {
+			_, err = newSpoofingClient("", "1").Get(ctx, bson.NewObjectId().Hex())
+		})
+
+		It("should return 401 without an api key even with a customer header", func() {
+			Expect(errors.Is(err, client.ErrUnauthenticated)).To(BeTrue())
+		})
+	})
+
+	Describe("Test Case 7", func() {
+		var created []string
+
+		BeforeEach(func() {
+			c = newClient(createAPIKey(bson.NewObjectId().Hex()))
+			created = []string{}
+			for _, url := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"} {
+				postBody, err = c.Create(ctx, url, []string{"test1"})
+				Expect(err).To(BeNil())
+				created = append(created, postBody.ID.Hex())
+			}
+			Expect(c.Delete(ctx, created[1])).To(Succeed())
+			created = append(created[:1], created[2])
+		})
+
+		It("should list the posts a page at a time", func() {
+			listed := []string{}
+			it := c.Posts(ctx, 1)
+			for it.Next() {
+				listed = append(listed, it.Post().ID.Hex())
+			}
+			Expect(it.Err()).To(BeNil())
+			Expect(listed).To(Equal(created))
 		})
 	})
 })
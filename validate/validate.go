@@ -0,0 +1,286 @@
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// Default limits used by DefaultRules
+const (
+	DefaultMaxURLLength     = 2048
+	DefaultMaxCaptions      = 10
+	DefaultMaxCaptionLength = 280
+
+	maxChannels      = 10
+	maxChannelLength = 32
+)
+
+// Rules configures what a Validator accepts
+type Rules struct {
+	MaxURLLength     int
+	MaxCaptions      int
+	MaxCaptionLength int
+	// AllowedHosts, when not empty, is the only set of hosts a URL may point at.
+	// A host matches itself and all of its subdomains
+	AllowedHosts []string
+	// DeniedHosts are never accepted, even if they are allowed
+	DeniedHosts []string
+}
+
+// DefaultRules returns the default limits with no host lists
+func DefaultRules() Rules {
+	return Rules{
+		MaxURLLength:     DefaultMaxURLLength,
+		MaxCaptions:      DefaultMaxCaptions,
+		MaxCaptionLength: DefaultMaxCaptionLength,
+	}
+}
+
+// Validator checks and normalizes post input
+type Validator struct {
+	rules Rules
+}
+
+// NewValidator returns a Validator with the provided rules
+func NewValidator(rules Rules) *Validator {
+	rules.AllowedHosts = normalizeHosts(rules.AllowedHosts)
+	rules.DeniedHosts = normalizeHosts(rules.DeniedHosts)
+	return &Validator{
+		rules: rules,
+	}
+}
+
+// URL validates the url and returns it normalized. Violations are added to
+// verr under field
+func (v *Validator) URL(verr *datastore.Validation, field, raw string) string {
+	if !utf8.ValidString(raw) {
+		verr.Add(field, "must be valid utf-8")
+		return raw
+	}
+	raw = norm.NFC.String(strings.TrimSpace(raw))
+	if raw == "" {
+		verr.Add(field, "is required")
+		return raw
+	}
+	if utf8.RuneCountInString(raw) > v.rules.MaxURLLength {
+		verr.Add(field, fmt.Sprintf("must be at most %d characters", v.rules.MaxURLLength))
+		return raw
+	}
+	if hasControl(raw, false) {
+		verr.Add(field, "must not contain control characters")
+		return raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() {
+		verr.Add(field, "must be an absolute url")
+		return raw
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		verr.Add(field, "must be http or https")
+		return raw
+	}
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		verr.Add(field, "must have a host")
+		return raw
+	}
+	if !v.hostAllowed(host) {
+		verr.Add(field, fmt.Sprintf("host %s is not allowed", host))
+		return raw
+	}
+	return u.String()
+}
+
+// MaxCaptionLength returns the most characters a caption may have
+func (v *Validator) MaxCaptionLength() int {
+	return v.rules.MaxCaptionLength
+}
+
+// Captions validates the captions and returns them normalized. Violations are
+// added to verr under field, and field[i] for a single caption
+func (v *Validator) Captions(verr *datastore.Validation, field string, captions []*dao.Caption) []*dao.Caption {
+	if captions == nil {
+		return nil
+	}
+	if len(captions) > v.rules.MaxCaptions {
+		verr.Add(field, fmt.Sprintf("must have at most %d captions", v.rules.MaxCaptions))
+		return captions
+	}
+
+	normalized := make([]*dao.Caption, len(captions))
+	selected := 0
+	for i, caption := range captions {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		if caption == nil {
+			normalized[i] = &dao.Caption{}
+			verr.Add(name, "must not be null")
+			continue
+		}
+		c := *caption
+		normalized[i] = &c
+		if c.Selected {
+			selected++
+		}
+		if c.Source != "" && !dao.ValidSource(c.Source) {
+			verr.Add(name+".source", fmt.Sprintf("must be %s or %s", dao.SourceGenerated, dao.SourceManual))
+		}
+		if !utf8.ValidString(c.Text) {
+			verr.Add(name, "must be valid utf-8")
+			continue
+		}
+		c.Text = norm.NFC.String(strings.TrimSpace(c.Text))
+		switch {
+		case c.Text == "":
+			verr.Add(name, "must not be empty")
+		case utf8.RuneCountInString(c.Text) > v.rules.MaxCaptionLength:
+			verr.Add(name, fmt.Sprintf("must be at most %d characters", v.rules.MaxCaptionLength))
+		case hasControl(c.Text, true):
+			verr.Add(name, "must not contain control characters")
+		}
+	}
+	if selected > 1 {
+		verr.Add(field, "must have at most one selected caption")
+	}
+	return normalized
+}
+
+// Post validates and normalizes the url and captions of the post in place. It
+// returns a *datastore.Validation listing every invalid field
+func (v *Validator) Post(post *dao.Post) error {
+	verr := datastore.NewValidationError()
+	post.URL = v.URL(verr, "url", post.URL)
+	post.Captions = v.Captions(verr, "captions", post.Captions)
+	if verr.HasErrors() {
+		return verr
+	}
+	return nil
+}
+
+// Update validates and normalizes the captions of the post in place. The url of
+// a post can not be updated, so it is not checked
+func (v *Validator) Update(post *dao.Post) error {
+	verr := datastore.NewValidationError()
+	post.Captions = v.Captions(verr, "captions", post.Captions)
+	if verr.HasErrors() {
+		return verr
+	}
+	return nil
+}
+
+// Schedule validates and normalizes the channels of a scheduled post in place. A
+// post without a ScheduledAt is being unscheduled, so nothing is checked
+func (v *Validator) Schedule(post *dao.Post) error {
+	if post.ScheduledAt == nil {
+		return nil
+	}
+
+	verr := datastore.NewValidationError()
+	if post.ScheduledAt.IsZero() {
+		verr.Add("scheduled_at", "is required")
+	}
+	post.Channels = v.Channels(verr, "channels", post.Channels)
+	if verr.HasErrors() {
+		return verr
+	}
+	return nil
+}
+
+// Channels trims and lower cases the channels, and adds an error for a missing
+// or duplicate channel, or one that is not made of letters, digits, - and _
+func (v *Validator) Channels(verr *datastore.Validation, field string, channels []string) []string {
+	if len(channels) == 0 {
+		verr.Add(field, "must have at least one channel")
+		return channels
+	}
+	if len(channels) > maxChannels {
+		verr.Add(field, fmt.Sprintf("must have at most %d channels", maxChannels))
+	}
+
+	normalized := make([]string, len(channels))
+	seen := map[string]bool{}
+	for i, channel := range channels {
+		channelField := fmt.Sprintf("%s[%d]", field, i)
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		normalized[i] = channel
+		switch {
+		case channel == "":
+			verr.Add(channelField, "must not be empty")
+		case len(channel) > maxChannelLength:
+			verr.Add(channelField, fmt.Sprintf("must be at most %d characters", maxChannelLength))
+		case !validChannel(channel):
+			verr.Add(channelField, "must only contain letters, digits, - and _")
+		case seen[channel]:
+			verr.Add(channelField, "is a duplicate")
+		}
+		seen[channel] = true
+	}
+	return normalized
+}
+
+func (v *Validator) hostAllowed(host string) bool {
+	for _, denied := range v.rules.DeniedHosts {
+		if matchHost(host, denied) {
+			return false
+		}
+	}
+	if len(v.rules.AllowedHosts) == 0 {
+		return true
+	}
+	for _, allowed := range v.rules.AllowedHosts {
+		if matchHost(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost returns true if host is pattern or one of its subdomains
+func matchHost(host, pattern string) bool {
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+func normalizeHosts(hosts []string) []string {
+	normalized := []string{}
+	for _, h := range hosts {
+		if h = normalizeHost(h); h != "" {
+			normalized = append(normalized, h)
+		}
+	}
+	return normalized
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// hasControl returns true if s has a control character. Captions may span
+// several lines, so newlines can be allowed
+func hasControl(s string, allowNewline bool) bool {
+	for _, r := range s {
+		if allowNewline && r == '\n' {
+			continue
+		}
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return true
+		}
+	}
+	return false
+}
+
+func validChannel(channel string) bool {
+	for _, r := range channel {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
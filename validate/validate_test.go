@@ -0,0 +1,209 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/datastore"
+)
+
+var _ = Describe("Validator", func() {
+	var (
+		rules Rules
+		v     *Validator
+		verr  *datastore.Validation
+	)
+
+	BeforeEach(func() {
+		rules = DefaultRules()
+		verr = datastore.NewValidationError()
+	})
+
+	JustBeforeEach(func() {
+		v = NewValidator(rules)
+	})
+
+	Describe("URL", func() {
+		It("should accept an absolute http(s) url", func() {
+			Expect(v.URL(verr, "url", " https://example.com/a?b=c ")).To(Equal("https://example.com/a?b=c"))
+			Expect(v.URL(verr, "url", "http://example.com")).To(Equal("http://example.com"))
+			Expect(verr.HasErrors()).To(BeFalse())
+		})
+
+		It("should normalize unicode", func() {
+			Expect(v.URL(verr, "url", "https://example.com/café")).To(Equal("https://example.com/caf%C3%A9"))
+			Expect(verr.HasErrors()).To(BeFalse())
+		})
+
+		It("should reject invalid urls", func() {
+			cases := map[string]string{
+				"":                            "is required",
+				"example.com/post":            "must be an absolute url",
+				"/post":                       "must be an absolute url",
+				"ftp://example.com":           "must be http or https",
+				"javascript:alert(1)":         "must be http or https",
+				"https:///post":               "must have a host",
+				"https://exa\x00mple.com":     "must not contain control characters",
+				"https://example.com/\u2028a": "must not contain control characters",
+				"https://example.com/" + strings.Repeat("a", DefaultMaxURLLength): "must be at most 2048 characters",
+			}
+			for raw, msg := range cases {
+				verr = datastore.NewValidationError()
+				v.URL(verr, "url", raw)
+				Expect(verr.Fields).To(Equal([]datastore.FieldError{{Field: "url", Message: msg}}), raw)
+			}
+		})
+
+		Context("with allowed hosts", func() {
+			BeforeEach(func() {
+				rules.AllowedHosts = []string{"Example.com."}
+			})
+
+			It("should accept the host and its subdomains", func() {
+				v.URL(verr, "url", "https://example.com/a")
+				v.URL(verr, "url", "https://blog.EXAMPLE.com/a")
+				Expect(verr.HasErrors()).To(BeFalse())
+			})
+
+			It("should reject other hosts", func() {
+				v.URL(verr, "url", "https://notexample.com/a")
+				Expect(verr.Error()).To(Equal("validation failed: url: host notexample.com is not allowed"))
+			})
+		})
+
+		Context("with denied hosts", func() {
+			BeforeEach(func() {
+				rules.AllowedHosts = []string{"example.com"}
+				rules.DeniedHosts = []string{"internal.example.com"}
+			})
+
+			It("should reject the host even if it is allowed", func() {
+				v.URL(verr, "url", "https://api.internal.example.com/a")
+				Expect(verr.Error()).To(Equal("validation failed: url: host api.internal.example.com is not allowed"))
+			})
+		})
+	})
+
+	Describe("Captions", func() {
+		It("should accept nil captions", func() {
+			Expect(v.Captions(verr, "captions", nil)).To(BeNil())
+			Expect(verr.HasErrors()).To(BeFalse())
+		})
+
+		It("should trim and normalize captions", func() {
+			captions := v.Captions(verr, "captions", dao.ManualCaptions([]string{" line1\nline2 ", "café"}))
+			Expect(dao.CaptionTexts(captions)).To(Equal([]string{"line1\nline2", "café"}))
+			Expect(verr.HasErrors()).To(BeFalse())
+		})
+
+		It("should limit the number of captions", func() {
+			v.Captions(verr, "captions", dao.ManualCaptions(make([]string, DefaultMaxCaptions+1)))
+			Expect(verr.Error()).To(Equal("validation failed: captions: must have at most 10 captions"))
+		})
+
+		It("should report every invalid caption", func() {
+			v.Captions(verr, "captions", dao.ManualCaptions([]string{
+				"ok",
+				" ",
+				strings.Repeat("é", DefaultMaxCaptionLength+1),
+				"tab\there",
+				"bad\xffutf8",
+			}))
+			Expect(verr.Fields).To(Equal([]datastore.FieldError{
+				{Field: "captions[1]", Message: "must not be empty"},
+				{Field: "captions[2]", Message: "must be at most 280 characters"},
+				{Field: "captions[3]", Message: "must not contain control characters"},
+				{Field: "captions[4]", Message: "must be valid utf-8"},
+			}))
+		})
+
+		It("should count characters, not bytes", func() {
+			v.Captions(verr, "captions", dao.ManualCaptions([]string{strings.Repeat("é", DefaultMaxCaptionLength)}))
+			Expect(verr.HasErrors()).To(BeFalse())
+		})
+
+		It("should not change the captions it was given", func() {
+			captions := dao.ManualCaptions([]string{" a "})
+			v.Captions(verr, "captions", captions)
+			Expect(captions[0].Text).To(Equal(" a "))
+		})
+
+		It("should only allow one selected caption", func() {
+			captions := dao.ManualCaptions([]string{"a", "b"})
+			captions[0].Selected, captions[1].Selected = true, true
+			v.Captions(verr, "captions", captions)
+			Expect(verr.Error()).To(Equal("validation failed: captions: must have at most one selected caption"))
+		})
+
+		It("should reject an unknown source", func() {
+			v.Captions(verr, "captions", []*dao.Caption{{Text: "a", Source: "copied"}, nil})
+			Expect(verr.Fields).To(Equal([]datastore.FieldError{
+				{Field: "captions[0].source", Message: "must be generated or manual"},
+				{Field: "captions[1]", Message: "must not be null"},
+			}))
+		})
+	})
+
+	Describe("Post", func() {
+		It("should normalize the post in place", func() {
+			post := &dao.Post{URL: " https://example.com ", Captions: dao.ManualCaptions([]string{" a "})}
+			Expect(v.Post(post)).To(BeNil())
+			Expect(post.URL).To(Equal("https://example.com"))
+			Expect(dao.CaptionTexts(post.Captions)).To(Equal([]string{"a"}))
+		})
+
+		It("should return every invalid field", func() {
+			err := v.Post(&dao.Post{Captions: dao.ManualCaptions([]string{""})})
+			Expect(err).To(BeAssignableToTypeOf(&datastore.Validation{}))
+			Expect(err.Error()).To(Equal("validation failed: url: is required; captions[0]: must not be empty"))
+		})
+	})
+
+	Describe("Update", func() {
+		It("should not check the url", func() {
+			Expect(v.Update(&dao.Post{Captions: dao.ManualCaptions([]string{"a"})})).To(BeNil())
+		})
+
+		It("should return invalid captions", func() {
+			err := v.Update(&dao.Post{Captions: dao.ManualCaptions([]string{"bell\x07"})})
+			Expect(err.Error()).To(Equal("validation failed: captions[0]: must not contain control characters"))
+		})
+	})
+	Describe("Schedule", func() {
+		It("should not check an unscheduled post", func() {
+			Expect(v.Schedule(&dao.Post{Channels: []string{""}})).To(BeNil())
+		})
+
+		It("should normalize the channels in place", func() {
+			at := time.Now()
+			post := &dao.Post{ScheduledAt: &at, Channels: []string{" Twitter", "linked_in-2"}}
+			Expect(v.Schedule(post)).To(BeNil())
+			Expect(post.Channels).To(Equal([]string{"twitter", "linked_in-2"}))
+		})
+
+		It("should return every invalid field", func() {
+			at := time.Time{}
+			err := v.Schedule(&dao.Post{ScheduledAt: &at, Channels: []string{"", "a.b", strings.Repeat("a", 33), "x", "X"}})
+			Expect(err.Error()).To(Equal("validation failed: scheduled_at: is required; " +
+				"channels[0]: must not be empty; " +
+				"channels[1]: must only contain letters, digits, - and _; " +
+				"channels[2]: must be at most 32 characters; " +
+				"channels[4]: is a duplicate"))
+		})
+
+		It("should limit the number of channels", func() {
+			at := time.Now()
+			channels := []string{}
+			for i := 0; i < 11; i++ {
+				channels = append(channels, fmt.Sprintf("c%d", i))
+			}
+			err := v.Schedule(&dao.Post{ScheduledAt: &at, Channels: channels})
+			Expect(err.Error()).To(Equal("validation failed: channels: must have at most 10 channels"))
+		})
+	})
+})
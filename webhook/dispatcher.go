@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bpross/cc-hw/events"
+)
+
+// Defaults used by DefaultRetryPolicy
+const (
+	DefaultMaxAttempts    = 8
+	DefaultInitialBackoff = 30 * time.Second
+	DefaultMaxBackoff     = time.Hour
+	DefaultTimeout        = 10 * time.Second
+
+	pollInterval     = time.Second
+	claimBatchSize   = 50
+	maxResponseBytes = 64 << 10
+	userAgent        = "cc-hw-webhooks/1"
+)
+
+// RetryPolicy configures how often a delivery is tried. After a failed attempt
+// the next one waits InitialBackoff, doubling every time up to MaxBackoff. A
+// delivery that fails MaxAttempts times is dead
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+}
+
+// DefaultRetryPolicy tries a delivery for about a day and a half
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    DefaultMaxAttempts,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+		Timeout:        DefaultTimeout,
+	}
+}
+
+// backoff returns how long to wait after the number of failed attempts
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// Doer sends http requests, it is satisfied by *http.Client
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Dispatcher turns post events into deliveries for the matching subscriptions and
+// sends them, retrying failures until they succeed or are dead
+type Dispatcher struct {
+	logger *log.Logger
+	store  Store
+	client Doer
+	policy RetryPolicy
+	now    func() time.Time
+	// wake is signalled when new deliveries are added
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewDispatcher returns a Dispatcher with the provided options. The client should
+// not be able to reach internal hosts, since subscription urls come from customers
+func NewDispatcher(logger *log.Logger, store Store, client Doer, policy RetryPolicy) *Dispatcher {
+	return &Dispatcher{
+		logger: logger,
+		store:  store,
+		client: client,
+		policy: policy,
+		now:    time.Now,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Handle creates a delivery of the event for every subscription that receives it.
+// It is meant to be subscribed to the event log, an error has the event handled again
+func (d *Dispatcher) Handle(e *events.Event) error {
+	subs, err := d.store.Matching(e.CustomerID, e.Type)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		_, err := d.store.AddDelivery(&Delivery{
+			CustID:         e.CustomerID,
+			SubscriptionID: sub.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start sends due deliveries in the background until Close is called
+func (d *Dispatcher) Start() {
+	go d.run()
+}
+
+// Close stops sending and waits for the attempts in flight
+func (d *Dispatcher) Close() {
+	close(d.stop)
+	<-d.done
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue()
+		select {
+		case <-d.stop:
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue attempts every due delivery, and returns how many were attempted
+func (d *Dispatcher) deliverDue() int {
+	// The lease has to outlast an attempt, or the delivery could be claimed again
+	deliveries, err := d.store.Claim(d.now(), 2*d.policy.Timeout, claimBatchSize)
+	if err != nil {
+		d.logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Error("failed to claim webhook deliveries")
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *Delivery) {
+			defer wg.Done()
+			d.attempt(delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+// attempt sends the delivery once and stores the outcome
+func (d *Dispatcher) attempt(delivery *Delivery) {
+	logger := d.logger.WithFields(log.Fields{
+		"delivery_id":     delivery.ID.Hex(),
+		"subscription_id": delivery.SubscriptionID.Hex(),
+		"event_id":        delivery.EventID,
+	})
+
+	sub, err := d.store.Get(delivery.CustID, delivery.SubscriptionID)
+	if err != nil {
+		// The subscription was deleted after the delivery was claimed
+		logger.Debug("dropping delivery without subscription")
+		return
+	}
+
+	at := d.now().UTC()
+	statusCode, err := d.send(sub, delivery, at)
+	result := &Attempt{At: at, StatusCode: statusCode}
+	delivery.Attempts = append(delivery.Attempts, result)
+
+	switch {
+	case err == nil:
+		delivery.Status = StatusSucceeded
+		delivery.NextAttemptAt = nil
+		logger.Debug("delivered webhook")
+	case len(delivery.Attempts) >= d.policy.MaxAttempts:
+		result.Error = truncate(err.Error())
+		delivery.Status = StatusDead
+		delivery.NextAttemptAt = nil
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Warn("webhook delivery is dead")
+	default:
+		result.Error = truncate(err.Error())
+		next := at.Add(d.policy.backoff(len(delivery.Attempts)))
+		delivery.NextAttemptAt = &next
+		logger.WithFields(log.Fields{
+			"error":   err.Error(),
+			"attempt": len(delivery.Attempts),
+		}).Info("webhook delivery failed, retrying")
+	}
+
+	if err := d.store.SaveDelivery(delivery); err != nil {
+		logger.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Warn("failed to save webhook delivery")
+	}
+}
+
+// send posts the signed payload, any response other than 2xx is an error
+func (d *Dispatcher) send(sub *Subscription, delivery *Delivery, at time.Time) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(IDHeader, delivery.ID.Hex())
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(at.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, at, delivery.Payload))
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.policy.Timeout)
+	defer cancel()
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func truncate(msg string) string {
+	if len(msg) > maxDeliveryErrLen {
+		return msg[:maxDeliveryErrLen]
+	}
+	return msg
+}
@@ -0,0 +1,246 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/dao"
+	"github.com/bpross/cc-hw/events"
+)
+
+// receiver records the deliveries sent to a httptest server
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+var _ = Describe("Dispatcher", func() {
+	var (
+		logger     *log.Logger
+		store      *InMemoryStore
+		dispatcher *Dispatcher
+		recv       *receiver
+		server     *httptest.Server
+		now        time.Time
+		customerID string
+		sub        *Subscription
+		event      *events.Event
+	)
+
+	BeforeEach(func() {
+		logger = log.New()
+		logger.Out = ioutil.Discard
+		store = NewInMemoryStore(logger)
+		recv = &receiver{status: http.StatusOK}
+		server = httptest.NewServer(recv)
+
+		now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		store.now = func() time.Time { return now }
+		dispatcher = NewDispatcher(logger, store, server.Client(), RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Minute,
+			MaxBackoff:     90 * time.Second,
+			Timeout:        time.Second,
+		})
+		dispatcher.now = func() time.Time { return now }
+
+		customerID = "test-customer"
+		var err error
+		sub, err = store.Create(customerID, &Subscription{
+			URL:        server.URL + "/hook",
+			EventTypes: []string{events.TypeStatusChanged},
+		})
+		Expect(err).To(BeNil())
+
+		postID := bson.NewObjectId()
+		event = events.NewEvent(events.TypeStatusChanged, customerID, postID, "alice", &dao.Post{
+			ID:     &postID,
+			URL:    "https://example.com/post",
+			Status: dao.StatusApproved,
+		})
+		event.ID = "event-id"
+		event.Offset = 1
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	deliveries := func() []*Delivery {
+		d, err := store.Deliveries(customerID, sub.ID, "")
+		Expect(err).To(BeNil())
+		return d
+	}
+
+	Describe("Handle", func() {
+		It("should only create deliveries for matching subscriptions", func() {
+			event.Type = events.TypeCreated
+			Expect(dispatcher.Handle(event)).To(Succeed())
+			Expect(deliveries()).To(BeEmpty())
+		})
+
+		It("should only create deliveries for the customer", func() {
+			event.CustomerID = "other-customer"
+			Expect(dispatcher.Handle(event)).To(Succeed())
+			Expect(deliveries()).To(BeEmpty())
+		})
+
+		It("should create a pending delivery with the event", func() {
+			Expect(dispatcher.Handle(event)).To(Succeed())
+			d := deliveries()
+			Expect(d).To(HaveLen(1))
+			Expect(d[0].Status).To(Equal(StatusPending))
+			Expect(d[0].EventID).To(Equal("event-id"))
+
+			payload := map[string]interface{}{}
+			Expect(json.Unmarshal(d[0].Payload, &payload)).To(Succeed())
+			Expect(payload["type"]).To(Equal(events.TypeStatusChanged))
+			Expect(payload["actor"]).To(Equal("alice"))
+			Expect(payload).NotTo(HaveKey("customer_id"))
+		})
+	})
+
+	Describe("deliverDue", func() {
+		BeforeEach(func() {
+			Expect(dispatcher.Handle(event)).To(Succeed())
+		})
+
+		Context("with a receiver that succeeds", func() {
+			It("should send a signed delivery", func() {
+				Expect(dispatcher.deliverDue()).To(Equal(1))
+				Expect(recv.count()).To(Equal(1))
+
+				req := recv.requests[0]
+				d := deliveries()[0]
+				Expect(req.Method).To(Equal("POST"))
+				Expect(req.URL.Path).To(Equal("/hook"))
+				Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+				Expect(req.Header.Get(IDHeader)).To(Equal(d.ID.Hex()))
+				Expect(req.Header.Get(EventHeader)).To(Equal(events.TypeStatusChanged))
+				Expect(recv.bodies[0]).To(Equal(d.Payload))
+				Expect(Verify(sub.Secret, req.Header.Get(TimestampHeader), req.Header.Get(SignatureHeader), recv.bodies[0], time.Minute, now)).To(Succeed())
+			})
+
+			It("should mark the delivery succeeded", func() {
+				dispatcher.deliverDue()
+				d := deliveries()[0]
+				Expect(d.Status).To(Equal(StatusSucceeded))
+				Expect(d.NextAttemptAt).To(BeNil())
+				Expect(d.Attempts).To(Equal([]*Attempt{{At: now, StatusCode: http.StatusOK}}))
+				Expect(dispatcher.deliverDue()).To(Equal(0))
+			})
+		})
+
+		Context("with a receiver that fails", func() {
+			BeforeEach(func() {
+				recv.status = http.StatusInternalServerError
+			})
+
+			It("should retry with exponential backoff", func() {
+				Expect(dispatcher.deliverDue()).To(Equal(1))
+				d := deliveries()[0]
+				Expect(d.Status).To(Equal(StatusPending))
+				Expect(d.Attempts[0].StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(d.Attempts[0].Error).To(Equal("returned status 500"))
+				Expect(*d.NextAttemptAt).To(Equal(now.Add(time.Minute)))
+
+				// not due yet
+				now = now.Add(59 * time.Second)
+				Expect(dispatcher.deliverDue()).To(Equal(0))
+
+				now = now.Add(time.Second)
+				Expect(dispatcher.deliverDue()).To(Equal(1))
+				d = deliveries()[0]
+				Expect(d.Attempts).To(HaveLen(2))
+				// doubled, then capped by the max backoff
+				Expect(*d.NextAttemptAt).To(Equal(now.Add(90 * time.Second)))
+			})
+
+			It("should end in the dead letters after the last attempt", func() {
+				for i := 0; i < 3; i++ {
+					Expect(dispatcher.deliverDue()).To(Equal(1))
+					now = now.Add(time.Hour)
+				}
+				Expect(dispatcher.deliverDue()).To(Equal(0))
+				Expect(recv.count()).To(Equal(3))
+
+				dead, err := store.Deliveries(customerID, sub.ID, StatusDead)
+				Expect(err).To(BeNil())
+				Expect(dead).To(HaveLen(1))
+				Expect(dead[0].Attempts).To(HaveLen(3))
+				Expect(dead[0].NextAttemptAt).To(BeNil())
+
+				// redelivering starts over
+				recv.status = http.StatusNoContent
+				_, err = store.Redeliver(customerID, dead[0].ID)
+				Expect(err).To(BeNil())
+				Expect(dispatcher.deliverDue()).To(Equal(1))
+				succeeded, _ := store.Deliveries(customerID, sub.ID, StatusSucceeded)
+				Expect(succeeded).To(HaveLen(1))
+			})
+		})
+
+		Context("with a receiver that can not be reached", func() {
+			BeforeEach(func() {
+				server.Close()
+			})
+
+			It("should record the error", func() {
+				dispatcher.deliverDue()
+				d := deliveries()[0]
+				Expect(d.Status).To(Equal(StatusPending))
+				Expect(d.Attempts[0].StatusCode).To(BeZero())
+				Expect(d.Attempts[0].Error).NotTo(BeEmpty())
+			})
+		})
+
+		Context("with a deleted subscription", func() {
+			It("should drop the delivery", func() {
+				Expect(store.Delete(customerID, sub.ID)).To(Succeed())
+				Expect(dispatcher.deliverDue()).To(Equal(0))
+				Expect(recv.count()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("Start", func() {
+		It("should deliver events from the log", func() {
+			dispatcher.now = time.Now
+			store.now = time.Now
+			eventLog := events.NewInMemoryLog(logger)
+			subscription := eventLog.Subscribe("webhooks", dispatcher.Handle)
+			dispatcher.Start()
+			defer dispatcher.Close()
+			defer subscription.Close()
+
+			eventLog.Append(events.NewEvent(events.TypeStatusChanged, customerID, bson.NewObjectId(), "", nil))
+			Eventually(recv.count).Should(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,295 @@
+package webhook
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+)
+
+// InMemoryStore implements the Store interface for in memory storage
+type InMemoryStore struct {
+	logger        *log.Logger
+	mu            sync.Mutex
+	subscriptions map[bson.ObjectId]*Subscription
+	deliveries    map[bson.ObjectId]*Delivery
+	now           func() time.Time
+}
+
+// NewInMemoryStore creates a new InMemoryStore with the provided options
+func NewInMemoryStore(logger *log.Logger) *InMemoryStore {
+	return &InMemoryStore{
+		logger:        logger,
+		subscriptions: make(map[bson.ObjectId]*Subscription),
+		deliveries:    make(map[bson.ObjectId]*Delivery),
+		now:           time.Now,
+	}
+}
+
+// Create validates and stores a new subscription with a generated secret
+func (s *InMemoryStore) Create(customerID string, sub *Subscription) (*Subscription, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+	if sub == nil {
+		return nil, datastore.NewInvalidArugmentError("must provide subscription")
+	}
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	created := &Subscription{
+		ID:         bson.NewObjectId(),
+		CustID:     customerID,
+		URL:        sub.URL,
+		EventTypes: append([]string{}, sub.EventTypes...),
+		Secret:     secret,
+		CreatedAt:  s.now().UTC(),
+	}
+	s.subscriptions[created.ID] = created
+
+	s.logger.WithFields(log.Fields{
+		"customerID":      customerID,
+		"subscription_id": created.ID.Hex(),
+	}).Debug("created webhook subscription")
+	copied := *created
+	return &copied, nil
+}
+
+// Get returns the customer's subscription
+func (s *InMemoryStore) Get(customerID string, id bson.ObjectId) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, err := s.get(customerID, id)
+	if err != nil {
+		return nil, err
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+// List returns all of the customer's subscriptions, oldest first
+func (s *InMemoryStore) List(customerID string) ([]*Subscription, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list(customerID, ""), nil
+}
+
+// Update replaces the url and event types of the subscription, the secret is kept
+func (s *InMemoryStore) Update(customerID string, sub *Subscription) (*Subscription, error) {
+	if sub == nil {
+		return nil, datastore.NewInvalidArugmentError("must provide subscription")
+	}
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, err := s.get(customerID, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	prev.URL = sub.URL
+	prev.EventTypes = append([]string{}, sub.EventTypes...)
+	copied := *prev
+	return &copied, nil
+}
+
+// Delete removes the subscription and stops its pending deliveries
+func (s *InMemoryStore) Delete(customerID string, id bson.ObjectId) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.get(customerID, id); err != nil {
+		return err
+	}
+	delete(s.subscriptions, id)
+	for deliveryID, d := range s.deliveries {
+		if d.SubscriptionID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// Matching returns the customer's subscriptions that receive the event type
+func (s *InMemoryStore) Matching(customerID, eventType string) ([]*Subscription, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list(customerID, eventType), nil
+}
+
+// AddDelivery stores a new pending delivery that is due right away
+func (s *InMemoryStore) AddDelivery(d *Delivery) (*Delivery, error) {
+	if d == nil {
+		return nil, datastore.NewInvalidArugmentError("must provide delivery")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.get(d.CustID, d.SubscriptionID); err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	created := *d
+	created.ID = bson.NewObjectId()
+	created.Status = StatusPending
+	created.Attempts = []*Attempt{}
+	created.NextAttemptAt = &now
+	created.CreatedAt = now
+	s.deliveries[created.ID] = &created
+
+	copied := created
+	return &copied, nil
+}
+
+// SaveDelivery stores the status and attempts of a delivery. A delivery removed
+// along with its subscription is not found
+func (s *InMemoryStore) SaveDelivery(d *Delivery) error {
+	if d == nil {
+		return datastore.NewInvalidArugmentError("must provide delivery")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.deliveries[d.ID]
+	if !ok {
+		return datastore.NewNotFoundError("delivery")
+	}
+	prev.Status = d.Status
+	prev.Attempts = append([]*Attempt{}, d.Attempts...)
+	prev.NextAttemptAt = d.NextAttemptAt
+	return nil
+}
+
+// Deliveries lists the subscription's deliveries, newest first
+func (s *InMemoryStore) Deliveries(customerID string, subscriptionID bson.ObjectId, status string) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.get(customerID, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	deliveries := []*Delivery{}
+	for _, d := range s.deliveries {
+		if d.SubscriptionID == subscriptionID && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, copyDelivery(d))
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].ID > deliveries[j].ID
+		}
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return deliveries, nil
+}
+
+// Redeliver copies a dead delivery into a new pending one, the dead delivery is
+// kept in the log
+func (s *InMemoryStore) Redeliver(customerID string, deliveryID bson.ObjectId) (*Delivery, error) {
+	s.mu.Lock()
+	d, ok := s.deliveries[deliveryID]
+	if !ok || d.CustID != customerID {
+		s.mu.Unlock()
+		return nil, datastore.NewNotFoundError("delivery")
+	}
+	if d.Status != StatusDead {
+		s.mu.Unlock()
+		return nil, datastore.NewPreconditionFailedError("only dead deliveries can be redelivered")
+	}
+	retry := &Delivery{
+		CustID:         d.CustID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+	}
+	s.mu.Unlock()
+	return s.AddDelivery(retry)
+}
+
+// Claim returns the pending deliveries that are due, oldest first, and moves
+// their next attempt past the lease
+func (s *InMemoryStore) Claim(now time.Time, lease time.Duration, limit int) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := []*Delivery{}
+	for _, d := range s.deliveries {
+		if d.Status == StatusPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	leased := now.Add(lease)
+	claimed := make([]*Delivery, len(due))
+	for i, d := range due {
+		d.NextAttemptAt = &leased
+		claimed[i] = copyDelivery(d)
+	}
+	return claimed, nil
+}
+
+// get must be called with the lock held
+func (s *InMemoryStore) get(customerID string, id bson.ObjectId) (*Subscription, error) {
+	if customerID == "" {
+		return nil, datastore.NewInvalidArugmentError("customerID")
+	}
+	if id == "" {
+		return nil, datastore.NewInvalidArugmentError("subscriptionID")
+	}
+	sub, ok := s.subscriptions[id]
+	if !ok || sub.CustID != customerID {
+		return nil, datastore.NewNotFoundError("subscription")
+	}
+	return sub, nil
+}
+
+// list must be called with the lock held
+func (s *InMemoryStore) list(customerID, eventType string) []*Subscription {
+	subs := []*Subscription{}
+	for _, sub := range s.subscriptions {
+		if sub.CustID == customerID && (eventType == "" || sub.Matches(eventType)) {
+			copied := *sub
+			subs = append(subs, &copied)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].ID < subs[j].ID
+		}
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs
+}
+
+func copyDelivery(d *Delivery) *Delivery {
+	copied := *d
+	copied.Attempts = append([]*Attempt{}, d.Attempts...)
+	return &copied
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signature", func() {
+	var (
+		secret    string
+		body      []byte
+		at        time.Time
+		timestamp string
+		signature string
+	)
+
+	BeforeEach(func() {
+		secret = "whsec_test"
+		body = []byte(`{"type":"created"}`)
+		at = time.Unix(1577836800, 0)
+		timestamp = strconv.FormatInt(at.Unix(), 10)
+		signature = Sign(secret, at, body)
+	})
+
+	It("should sign the timestamp and body", func() {
+		// echo -n '1577836800.{"type":"created"}' | openssl dgst -sha256 -hmac whsec_test
+		Expect(signature).To(Equal("v1=40bd6a99af7b4e3b08f710e4e88735f6f09333cf9e04856186872d5f6ab13838"))
+	})
+
+	It("should verify the signature", func() {
+		Expect(Verify(secret, timestamp, signature, body, time.Minute, at.Add(30*time.Second))).To(Succeed())
+	})
+
+	It("should verify any of several signatures", func() {
+		Expect(Verify(secret, timestamp, "v0=abc, "+signature, body, time.Minute, at)).To(Succeed())
+	})
+
+	It("should reject a different body", func() {
+		Expect(Verify(secret, timestamp, signature, []byte(`{"type":"deleted"}`), time.Minute, at)).To(Equal(ErrInvalidSignature))
+	})
+
+	It("should reject a different secret", func() {
+		Expect(Verify("whsec_other", timestamp, signature, body, time.Minute, at)).To(Equal(ErrInvalidSignature))
+	})
+
+	It("should reject a different timestamp", func() {
+		Expect(Verify(secret, strconv.FormatInt(at.Unix()+1, 10), signature, body, time.Minute, at)).To(Equal(ErrInvalidSignature))
+	})
+
+	It("should reject an old timestamp", func() {
+		Expect(Verify(secret, timestamp, signature, body, time.Minute, at.Add(2*time.Minute))).To(Equal(ErrExpiredTimestamp))
+	})
+
+	It("should reject malformed headers", func() {
+		Expect(Verify(secret, "yesterday", signature, body, time.Minute, at)).To(Equal(ErrInvalidSignature))
+		Expect(Verify(secret, timestamp, "v1=zz", body, time.Minute, at)).To(Equal(ErrInvalidSignature))
+		Expect(Verify(secret, timestamp, "", body, time.Minute, at)).To(Equal(ErrInvalidSignature))
+	})
+})
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"labix.org/v2/mgo/bson"
+
+	"github.com/bpross/cc-hw/datastore"
+	"github.com/bpross/cc-hw/events"
+)
+
+const (
+	secretPrefix      = "whsec_"
+	secretBytes       = 24
+	maxURLLength      = 2048
+	maxDeliveryErrLen = 256
+)
+
+// Statuses of a delivery
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// eventTypes are the events a subscription can filter on
+var eventTypes = []string{
+	events.TypeCreated,
+	events.TypeCaptionsUpdated,
+	events.TypeStatusChanged,
+	events.TypeDeleted,
+}
+
+// Subscription sends a customer's post events to their url. An empty EventTypes
+// receives every event
+type Subscription struct {
+	ID         bson.ObjectId `json:"id"`
+	CustID     string        `json:"-"` // do not return when we marshal to json
+	URL        string        `json:"url"`
+	EventTypes []string      `json:"event_types"`
+	Secret     string        `json:"-"` // only returned when the subscription is created
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// Matches returns true if the subscription receives events of the type
+func (s *Subscription) Matches(eventType string) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Attempt records a single try to deliver an event
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Delivery tracks sending one event to one subscription. Payload is fixed when
+// the delivery is created, so every attempt sends the same body
+type Delivery struct {
+	ID             bson.ObjectId `json:"id"`
+	CustID         string        `json:"-"` // do not return when we marshal to json
+	SubscriptionID bson.ObjectId `json:"subscription_id"`
+	EventID        string        `json:"event_id"`
+	EventType      string        `json:"event_type"`
+	Status         string        `json:"status"`
+	Attempts       []*Attempt    `json:"attempts"`
+	NextAttemptAt  *time.Time    `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	Payload        []byte        `json:"-"`
+}
+
+// Store defines the interface for managing subscriptions and their deliveries
+type Store interface {
+	Create(string, *Subscription) (*Subscription, error)
+	Get(string, bson.ObjectId) (*Subscription, error)
+	List(string) ([]*Subscription, error)
+	Update(string, *Subscription) (*Subscription, error)
+	Delete(string, bson.ObjectId) error
+	// Matching returns the customer's subscriptions that receive the event type
+	Matching(string, string) ([]*Subscription, error)
+
+	AddDelivery(*Delivery) (*Delivery, error)
+	SaveDelivery(*Delivery) error
+	// Deliveries lists a subscription's deliveries, newest first, optionally
+	// only those with the status
+	Deliveries(string, bson.ObjectId, string) ([]*Delivery, error)
+	// Redeliver queues a dead delivery to be sent again as a new delivery
+	Redeliver(string, bson.ObjectId) (*Delivery, error)
+	// Claim returns up to limit pending deliveries that are due, and holds them
+	// until lease has passed so they are not claimed twice
+	Claim(time.Time, time.Duration, int) ([]*Delivery, error)
+}
+
+// validateSubscription checks the url and event types of a subscription
+func validateSubscription(s *Subscription) error {
+	verr := datastore.NewValidationError()
+	u, err := url.Parse(s.URL)
+	switch {
+	case s.URL == "":
+		verr.Add("url", "is required")
+	case len(s.URL) > maxURLLength:
+		verr.Add("url", fmt.Sprintf("must be at most %d characters", maxURLLength))
+	case err != nil || !u.IsAbs() || u.Host == "":
+		verr.Add("url", "must be an absolute url")
+	case u.Scheme != "http" && u.Scheme != "https":
+		verr.Add("url", "must be http or https")
+	case u.User != nil:
+		verr.Add("url", "must not contain credentials")
+	}
+
+	seen := map[string]bool{}
+	for i, t := range s.EventTypes {
+		field := fmt.Sprintf("event_types[%d]", i)
+		if !validEventType(t) {
+			verr.Add(field, "must be one of "+strings.Join(eventTypes, ", "))
+		} else if seen[t] {
+			verr.Add(field, "is a duplicate")
+		}
+		seen[t] = true
+	}
+
+	if verr.HasErrors() {
+		return verr
+	}
+	return nil
+}
+
+func validEventType(t string) bool {
+	for _, valid := range eventTypes {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}